@@ -28,6 +28,24 @@ type DatabaseConfig struct {
 	Type string `mapstructure:"type"`
 	// Embedded-only configuration
 	LibSQLDataDir string `mapstructure:"libsql_data_dir"` // Directory for database files
+	// QueryTimeout bounds each database transaction and query; negative disables the bound
+	QueryTimeout time.Duration `mapstructure:"query_timeout"`
+	// AuthToken authenticates against a remote libsql server; BuildDSN adds it as the authToken parameter
+	AuthToken string `mapstructure:"auth_token"`
+	// Pragmas overrides allowlisted PRAGMAs for every memory database, e.g. journal_mode: WAL
+	Pragmas map[string]string `mapstructure:"pragmas"`
+
+	// PRAGMA settings applied to each memory database connection
+	SyncMode           string `mapstructure:"sync_mode"`            // NORMAL, FULL, OFF
+	CacheSize          int    `mapstructure:"cache_size"`           // Pages, negative for KB
+	TempStore          string `mapstructure:"temp_store"`           // MEMORY, FILE, DEFAULT
+	JournalMode        string `mapstructure:"journal_mode"`         // WAL, DELETE, TRUNCATE, PERSIST, MEMORY, OFF
+	MmapSize           int64  `mapstructure:"mmap_size"`            // Memory-mapped I/O limit in bytes; negative disables
+	WALAutocheckpoint  int    `mapstructure:"wal_autocheckpoint"`   // Pages between automatic WAL checkpoints
+	BusyTimeoutMs      int    `mapstructure:"busy_timeout_ms"`      // Lock wait in milliseconds
+	DisableForeignKeys bool   `mapstructure:"disable_foreign_keys"` // Foreign key enforcement is on unless disabled
+	// ProjectPragmas overrides allowlisted PRAGMAs per project, keyed by project name
+	ProjectPragmas map[string]map[string]string `mapstructure:"project_pragmas"`
 }
 
 // VVFSConfig stores vvfs specific configurations.
@@ -40,11 +58,17 @@ type VVFSConfig struct {
 
 // EmbeddingConfig stores embedding model configurations.
 type EmbeddingConfig struct {
-	Provider  string `mapstructure:"provider"`   // "hugot", "onnx", etc.
-	ModelPath string `mapstructure:"model_path"` // Path or HF repo ID
-	Dims      int    `mapstructure:"dims"`       // Target embedding dimensions
-	Pooling   string `mapstructure:"pooling"`    // "mean", "cls"
-	BatchSize int    `mapstructure:"batch_size"` // Batch size for inference
+	Provider     string `mapstructure:"provider"`      // "hugot", "onnx", etc.
+	ModelPath    string `mapstructure:"model_path"`    // Path or HF repo ID
+	Dims         int    `mapstructure:"dims"`          // Target embedding dimensions
+	Pooling      string `mapstructure:"pooling"`       // "mean", "cls", "last_token", "weighted_mean"
+	DimsPolicy   string `mapstructure:"dims_policy"`   // "strict", "truncate", "pad": handling of vectors whose length is not Dims
+	BatchSize    int    `mapstructure:"batch_size"`    // Batch size for inference
+	ModelVersion string `mapstructure:"model_version"` // Provenance tag stored with each vector; derived from the model when empty
+
+	// Prefixes for query and document embeddings; empty uses the model family's default
+	QueryInstruction    string `mapstructure:"query_instruction"`
+	DocumentInstruction string `mapstructure:"document_instruction"`
 }
 
 // LLMConfig stores language model configurations.
@@ -56,6 +80,14 @@ type LLMConfig struct {
 	TopP              float32 `mapstructure:"top_p"`              // Nucleus sampling
 	MinP              float32 `mapstructure:"min_p"`              // Minimum probability
 	RepetitionPenalty float32 `mapstructure:"repetition_penalty"` // Repetition penalty
+
+	// Circuit breaker for local model providers
+	BreakerThreshold int           `mapstructure:"breaker_threshold"` // Failures before a provider rejects calls
+	BreakerCooldown  time.Duration `mapstructure:"breaker_cooldown"`  // How long an open breaker rejects calls
+
+	// Per-call timeouts for local model providers
+	GenerateTimeout time.Duration `mapstructure:"generate_timeout"` // Bound on a single text generation call
+	EmbedTimeout    time.Duration `mapstructure:"embed_timeout"`    // Bound on a single embedding call
 }
 
 // ONNXConfig stores ONNX runtime configurations.
@@ -81,11 +113,18 @@ type HarnessConfig struct {
 	RateLimitEnabled    bool          `mapstructure:"rate_limit_enabled"`     // Enable rate limiting
 	RateLimitCapacity   int           `mapstructure:"rate_limit_capacity"`    // Token bucket capacity
 	RateLimitRefillRate time.Duration `mapstructure:"rate_limit_refill_rate"` // Refill rate
+	RateLimitWait       bool          `mapstructure:"rate_limit_wait"`        // Wait for a token up to the request deadline instead of failing
 
 	// Policies
-	MaxToolDepth  int `mapstructure:"max_tool_depth"`  // Maximum recursive tool calls
-	MaxIterations int `mapstructure:"max_iterations"`  // Maximum orchestration iterations
-	MaxOutputSize int `mapstructure:"max_output_size"` // Maximum output size in bytes
+	MaxToolDepth            int `mapstructure:"max_tool_depth"`            // Maximum recursive tool calls
+	MaxIterations           int `mapstructure:"max_iterations"`            // Maximum orchestration iterations
+	MaxOutputSize           int `mapstructure:"max_output_size"`           // Maximum output size in bytes
+	MaxConversationMessages int `mapstructure:"max_conversation_messages"` // Messages kept before the oldest are summarized; 0 disables
+	MaxToolCalls            int `mapstructure:"max_tool_calls"`            // Best proposed tool calls run per turn; 0 runs all
+
+	// Prompt budget
+	ContextSize    int `mapstructure:"context_size"`    // Model context window in tokens; prompts are trimmed to fit, 0 disables
+	ReservedTokens int `mapstructure:"reserved_tokens"` // Tokens held back from context_size for the completion; 0 reserves llm.max_new_tokens
 
 	// Safety and validation
 	EnableGuardrails bool     `mapstructure:"enable_guardrails"` // Enable safety checks
@@ -95,8 +134,21 @@ type HarnessConfig struct {
 	// Telemetry
 	EnableTracing bool `mapstructure:"enable_tracing"` // Enable structured logging/tracing
 
+	// Audit
+	AuditEnabled      bool `mapstructure:"audit_enabled"`       // Record every tool invocation in the database
+	AuditStoreResults bool `mapstructure:"audit_store_results"` // Keep redacted tool results in audit records, not only their hashes
+
 	// Performance
-	ToolConcurrency int `mapstructure:"tool_concurrency"` // Max concurrent tool executions
+	ToolConcurrency    int `mapstructure:"tool_concurrency"`      // Max concurrent tool executions
+	MaxToolResultBytes int `mapstructure:"max_tool_result_bytes"` // Per-tool-result cap in the prompt; larger results are truncated
+
+	// Provider fallback
+	ProviderChain   []string      `mapstructure:"provider_chain"`   // Ordered provider names to try, e.g. ["gguf", "remote"]
+	ProviderTimeout time.Duration `mapstructure:"provider_timeout"` // Per-provider attempt timeout before failing over
+
+	// Tool declarations
+	ToolSchemaFormat string `mapstructure:"tool_schema_format"` // "openai" or "inline"; empty uses the primary provider's declared format
+	ToolResultFormat string `mapstructure:"tool_result_format"` // "json", "text" or "xml" rendering of structured tool outputs
 }
 
 // MemoryConfig stores memory system configurations.
@@ -110,8 +162,23 @@ type MemoryConfig struct {
 	TimeDecay bool    `mapstructure:"time_decay"` // Enable time-based decay
 	Rerank    bool    `mapstructure:"rerank"`     // Enable reranking
 
+	// Autocut bounds
+	AutocutMinResults     int     `mapstructure:"autocut_min_results"`      // Results autocut always keeps
+	AutocutMaxCutFraction float64 `mapstructure:"autocut_max_cut_fraction"` // Largest share of results autocut may drop (0 = no cap)
+
+	// Candidate overfetch for fusion
+	Overfetch    int `mapstructure:"overfetch"`     // Candidates fetched per source as a multiple of K
+	MaxOverfetch int `mapstructure:"max_overfetch"` // Cap on the multiple when adaptive overfetch re-queries
+
+	// Time decay shape (applies when TimeDecay is enabled)
+	TimeDecayFunc     string        `mapstructure:"time_decay_func"`      // "exponential", "linear", "step"
+	TimeDecayHalfLife time.Duration `mapstructure:"time_decay_half_life"` // Exponential half-life; overrides Lambda when set
+	TimeDecayCutoff   time.Duration `mapstructure:"time_decay_cutoff"`    // Age where linear decay reaches zero or step decay drops
+
 	// Vector index settings
-	VectorIndex string `mapstructure:"vector_index"` // "flat", "hnsw", "leann", "external"
+	VectorIndex         string `mapstructure:"vector_index"`          // "flat", "hnsw", "leann", "external"
+	IndexPath           string `mapstructure:"index_path"`            // File used to persist in-memory index state on flush
+	LoadExistingVectors bool   `mapstructure:"load_existing_vectors"` // Load stored embeddings into in-memory indexes during warm-up
 
 	// HNSW settings (for hnsw index)
 	HNSWM              int `mapstructure:"hnsw_m"`               // Max connections per node (16-64)
@@ -139,6 +206,11 @@ type MemoryConfig struct {
 	GraphDepth        int    `mapstructure:"graph_depth"`         // Max graph traversal depth (1-3)
 	GraphCenterPolicy string `mapstructure:"graph_center_policy"` // "top_entity", "explicit"
 	GraphRerankOnly   bool   `mapstructure:"graph_rerank_only"`   // Use graph only for reranking
+	GraphExplainPaths bool   `mapstructure:"graph_explain_paths"` // Record the traversed path on graph search results
+	// Exponent applied to the product of edge weights along a path when scoring graph results; 0 ignores weights
+	GraphEdgeWeightFactor float64 `mapstructure:"graph_edge_weight_factor"`
+	// Entity mentions (proper nouns, acronyms, identifiers) a query needs before the router adds graph search; 0 means 1
+	GraphMinEntities int `mapstructure:"graph_min_entities"`
 
 	// Knowledge extraction settings
 	ExtractorProvider    string        `mapstructure:"extractor_provider"`    // "openai", "gemini", "ollama"
@@ -149,6 +221,15 @@ type MemoryConfig struct {
 	MaxLatency      time.Duration `mapstructure:"max_latency"`       // Max retrieval latency budget
 	IngestBatchSize int           `mapstructure:"ingest_batch_size"` // Batch size for parallel ingest
 	CacheCapacity   int           `mapstructure:"cache_capacity"`    // Cache capacity for embeddings/summaries
+	DedupThreshold  float64       `mapstructure:"dedup_threshold"`   // Cosine similarity at which an ingested item is skipped as a duplicate; 0 disables
+
+	// Document chunking for IngestDocument
+	ChunkSize    int `mapstructure:"chunk_size"`    // Target tokens per chunk
+	ChunkOverlap int `mapstructure:"chunk_overlap"` // Tokens shared between consecutive chunks
+
+	// Failed ingest retries
+	IngestMaxAttempts  int           `mapstructure:"ingest_max_attempts"`  // Attempts before a failed ingest is marked permanently failed
+	IngestRetryBackoff time.Duration `mapstructure:"ingest_retry_backoff"` // Delay before the first retry; doubles per attempt
 
 	// Observability
 	EnableMetrics bool `mapstructure:"enable_metrics"` // Enable detailed metrics collection
@@ -170,127 +251,172 @@ func LoadConfig(configPath string) (*Config, error) {
 		viper.SetConfigType("yaml")
 	}
 
-	// Set default values
-	viper.SetDefault("vvfs.targetDir", ".")
-	viper.SetDefault("vvfs.cacheDir", internal.DefaultCacheDir)
-	viper.SetDefault("vvfs.database.dsn", internal.DefaultDatabaseDSN)
-	viper.SetDefault("vvfs.database.type", internal.DefaultDatabaseType)
-	viper.SetDefault("vvfs.organizeTimeoutMinutes", 10)
+	setDefaults(viper.GetViper())
+
+	viper.AutomaticEnv()
+	// Replace dots with underscores in env var names e.g. genkit.plugins.googleAI.apiKey becomes GENKIT_PLUGINS_GOOGLEAI_APIKEY
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			// TODO: Create default config file if not found
+			// Config file not found; defaults will be used. This is not an error for the application to halt on.
+			// It's good practice to log this situation if a logger is available here.
+			// fmt.Printf("Warning: Config file not found at expected locations. Using default values. Searched: %s\n", viper.ConfigFileUsed())
+		} else {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+
+	err := viper.Unmarshal(&AppConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode into struct: %w", err)
+	}
+
+	return &AppConfig, nil
+}
+
+// setDefaults registers every default value on v.
+// DumpDefaults reads the same registrations, so new keys only need adding here.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("vvfs.targetDir", ".")
+	v.SetDefault("vvfs.cacheDir", internal.DefaultCacheDir)
+	v.SetDefault("vvfs.database.dsn", internal.DefaultDatabaseDSN)
+	v.SetDefault("vvfs.database.type", internal.DefaultDatabaseType)
+	v.SetDefault("vvfs.organizeTimeoutMinutes", 10)
 
 	// LibSQL embedded defaults only
-	viper.SetDefault("vvfs.database.libsql_data_dir", internal.DefaultDatabaseDir)
-	viper.SetDefault("vvfs.organizeTimeoutMinutes", 10)
+	v.SetDefault("vvfs.database.libsql_data_dir", internal.DefaultDatabaseDir)
+	v.SetDefault("vvfs.database.query_timeout", 30*time.Second)
+	v.SetDefault("vvfs.database.sync_mode", "NORMAL")
+	v.SetDefault("vvfs.database.cache_size", -64000) // 64MB
+	v.SetDefault("vvfs.database.temp_store", "MEMORY")
+	v.SetDefault("vvfs.database.journal_mode", "WAL")
+	v.SetDefault("vvfs.database.mmap_size", 268435456) // 256MB
+	v.SetDefault("vvfs.database.wal_autocheckpoint", 1000)
+	v.SetDefault("vvfs.database.busy_timeout_ms", 5000)
+	v.SetDefault("vvfs.database.disable_foreign_keys", false)
+	v.SetDefault("vvfs.organizeTimeoutMinutes", 10)
 
 	// Embedding defaults
-	viper.SetDefault("embedding.provider", "hugot")
-	viper.SetDefault("embedding.model_path", "google/gemma-3-1b") // EmbeddingGemma when available
-	viper.SetDefault("embedding.dims", 768)
-	viper.SetDefault("embedding.pooling", "mean")
-	viper.SetDefault("embedding.batch_size", 32)
+	v.SetDefault("embedding.provider", "hugot")
+	v.SetDefault("embedding.model_path", "google/gemma-3-1b") // EmbeddingGemma when available
+	v.SetDefault("embedding.dims", 768)
+	v.SetDefault("embedding.pooling", "mean")
+	v.SetDefault("embedding.dims_policy", "strict")
+	v.SetDefault("embedding.batch_size", 32)
 
 	// LLM defaults (Gemma 270M)
-	viper.SetDefault("llm.provider", "hugot")
-	viper.SetDefault("llm.model_path", "onnx-community/gemma-3-270m-it-ONNX")
-	viper.SetDefault("llm.max_new_tokens", 512)
-	viper.SetDefault("llm.temperature", 0.3)
-	viper.SetDefault("llm.top_p", 0.9)
-	viper.SetDefault("llm.min_p", 0.15)
-	viper.SetDefault("llm.repetition_penalty", 1.05)
+	v.SetDefault("llm.provider", "hugot")
+	v.SetDefault("llm.model_path", "onnx-community/gemma-3-270m-it-ONNX")
+	v.SetDefault("llm.max_new_tokens", 512)
+	v.SetDefault("llm.temperature", 0.3)
+	v.SetDefault("llm.top_p", 0.9)
+	v.SetDefault("llm.min_p", 0.15)
+	v.SetDefault("llm.repetition_penalty", 1.05)
+	v.SetDefault("llm.breaker_threshold", 5)
+	v.SetDefault("llm.breaker_cooldown", "60s")
+	v.SetDefault("llm.generate_timeout", "30s")
+	v.SetDefault("llm.embed_timeout", "10s")
 
 	// ONNX defaults (optimized for performance)
-	viper.SetDefault("onnx.backend", "ort")
-	viper.SetDefault("onnx.ep", "cpu")
-	viper.SetDefault("onnx.inter_op_threads", 1)  // Optimized for multi-goroutine fan-out
-	viper.SetDefault("onnx.intra_op_threads", 1)  // Optimized for multi-goroutine fan-out
-	viper.SetDefault("onnx.cpu_mem_arena", false) // Disable for high-throughput
-	viper.SetDefault("onnx.mem_pattern", false)   // Disable for high-throughput
+	v.SetDefault("onnx.backend", "ort")
+	v.SetDefault("onnx.ep", "cpu")
+	v.SetDefault("onnx.inter_op_threads", 1)  // Optimized for multi-goroutine fan-out
+	v.SetDefault("onnx.intra_op_threads", 1)  // Optimized for multi-goroutine fan-out
+	v.SetDefault("onnx.cpu_mem_arena", false) // Disable for high-throughput
+	v.SetDefault("onnx.mem_pattern", false)   // Disable for high-throughput
 
 	// Harness defaults (production-optimized)
-	viper.SetDefault("harness.cache_enabled", true)
-	viper.SetDefault("harness.cache_capacity", 1000)
-	viper.SetDefault("harness.cache_ttl_seconds", 3600) // 1 hour
-	viper.SetDefault("harness.rate_limit_enabled", true)
-	viper.SetDefault("harness.rate_limit_capacity", 10)
-	viper.SetDefault("harness.rate_limit_refill_rate", "1s")
-	viper.SetDefault("harness.max_tool_depth", 3)
-	viper.SetDefault("harness.max_iterations", 10)
-	viper.SetDefault("harness.max_output_size", 10000) // 10KB
-	viper.SetDefault("harness.enable_guardrails", true)
-	viper.SetDefault("harness.blocked_words", []string{"password", "secret", "key", "token", "credential"})
-	viper.SetDefault("harness.allowed_tools", []string{}) // Empty means allow all by default
-	viper.SetDefault("harness.enable_tracing", true)
-	viper.SetDefault("harness.tool_concurrency", 5)
+	v.SetDefault("harness.cache_enabled", true)
+	v.SetDefault("harness.cache_capacity", 1000)
+	v.SetDefault("harness.cache_ttl_seconds", 3600) // 1 hour
+	v.SetDefault("harness.rate_limit_enabled", true)
+	v.SetDefault("harness.rate_limit_capacity", 10)
+	v.SetDefault("harness.rate_limit_refill_rate", "1s")
+	v.SetDefault("harness.rate_limit_wait", false)
+	v.SetDefault("harness.max_tool_depth", 3)
+	v.SetDefault("harness.max_tool_calls", 0) // Run every proposed call
+	v.SetDefault("harness.max_iterations", 10)
+	v.SetDefault("harness.max_output_size", 10000) // 10KB
+	v.SetDefault("harness.max_conversation_messages", 0)
+	v.SetDefault("harness.context_size", 0)    // No prompt trimming
+	v.SetDefault("harness.reserved_tokens", 0) // Reserve llm.max_new_tokens
+	v.SetDefault("harness.enable_guardrails", true)
+	v.SetDefault("harness.blocked_words", []string{"password", "secret", "key", "token", "credential"})
+	v.SetDefault("harness.allowed_tools", []string{}) // Empty means allow all by default
+	v.SetDefault("harness.enable_tracing", true)
+	v.SetDefault("harness.audit_enabled", false)
+	v.SetDefault("harness.audit_store_results", false) // Hashes only
+	v.SetDefault("harness.tool_concurrency", 5)
+	v.SetDefault("harness.max_tool_result_bytes", 16384) // 16KB
+	v.SetDefault("harness.provider_chain", []string{})   // Empty means a single injected provider
+	v.SetDefault("harness.provider_timeout", "60s")
+	v.SetDefault("harness.tool_schema_format", "") // Empty defers to the provider
+	v.SetDefault("harness.tool_result_format", "json")
 
 	// Memory defaults (retrieval-optimized)
-	viper.SetDefault("memory.alpha", 0.5)     // Balanced fusion
-	viper.SetDefault("memory.k", 8)           // Top-8 results
-	viper.SetDefault("memory.threshold", 0.8) // Cosine similarity threshold
-	viper.SetDefault("memory.lambda", 0.1)    // Gentle time decay
-	viper.SetDefault("memory.autocut", true)
-	viper.SetDefault("memory.time_decay", true)
-	viper.SetDefault("memory.rerank", false) // Disabled by default for performance
-
-	viper.SetDefault("memory.vector_index", "flat") // Start with simple flat index
+	v.SetDefault("memory.alpha", 0.5)     // Balanced fusion
+	v.SetDefault("memory.k", 8)           // Top-8 results
+	v.SetDefault("memory.threshold", 0.8) // Cosine similarity threshold
+	v.SetDefault("memory.lambda", 0.1)    // Gentle time decay
+	v.SetDefault("memory.autocut", true)
+	v.SetDefault("memory.autocut_min_results", 3)
+	v.SetDefault("memory.autocut_max_cut_fraction", 0.0)
+	v.SetDefault("memory.time_decay", true)
+	v.SetDefault("memory.time_decay_func", "exponential")
+	v.SetDefault("memory.rerank", false) // Disabled by default for performance
+	v.SetDefault("memory.overfetch", 2)
+	v.SetDefault("memory.max_overfetch", 8)
+
+	v.SetDefault("memory.vector_index", "flat") // Start with simple flat index
+	v.SetDefault("memory.load_existing_vectors", true)
 
 	// HNSW defaults (tuned for 768-dim embeddings)
-	viper.SetDefault("memory.hnsw_m", 32)
-	viper.SetDefault("memory.hnsw_ef_construction", 128)
-	viper.SetDefault("memory.hnsw_ef_search", 64)
+	v.SetDefault("memory.hnsw_m", 32)
+	v.SetDefault("memory.hnsw_ef_construction", 128)
+	v.SetDefault("memory.hnsw_ef_search", 64)
 
 	// LEANN defaults (experimental low-storage mode)
-	viper.SetDefault("memory.leann_enabled", false)
-	viper.SetDefault("memory.leann_pq_dim", 64) // PQ subvector dim
-	viper.SetDefault("memory.leann_batch_size", 64)
-	viper.SetDefault("memory.leann_storage_budget", 0.05) // 5% of raw data
+	v.SetDefault("memory.leann_enabled", false)
+	v.SetDefault("memory.leann_pq_dim", 64) // PQ subvector dim
+	v.SetDefault("memory.leann_batch_size", 64)
+	v.SetDefault("memory.leann_storage_budget", 0.05) // 5% of raw data
 
 	// Ensemble defaults (off by default for simplicity)
-	viper.SetDefault("memory.ensemble_enabled", false)
-	viper.SetDefault("memory.ensemble_strategy", "weighted_rrf")
-	viper.SetDefault("memory.router_mode", "rules")
+	v.SetDefault("memory.ensemble_enabled", false)
+	v.SetDefault("memory.ensemble_strategy", "weighted_rrf")
+	v.SetDefault("memory.router_mode", "rules")
 
-	viper.SetDefault("memory.weights_bm25", 0.35)
-	viper.SetDefault("memory.weights_vector", 0.55)
-	viper.SetDefault("memory.weights_graph", 0.10)
+	v.SetDefault("memory.weights_bm25", 0.35)
+	v.SetDefault("memory.weights_vector", 0.55)
+	v.SetDefault("memory.weights_graph", 0.10)
 
 	// Graph defaults (off by default)
-	viper.SetDefault("memory.graph_enabled", false)
-	viper.SetDefault("memory.graph_depth", 2)
-	viper.SetDefault("memory.graph_center_policy", "top_entity")
-	viper.SetDefault("memory.graph_rerank_only", true) // Use only for reranking
+	v.SetDefault("memory.graph_enabled", false)
+	v.SetDefault("memory.graph_depth", 2)
+	v.SetDefault("memory.graph_center_policy", "top_entity")
+	v.SetDefault("memory.graph_rerank_only", true) // Use only for reranking
+	v.SetDefault("memory.graph_explain_paths", false)
+	v.SetDefault("memory.graph_edge_weight_factor", 1.0)
+	v.SetDefault("memory.graph_min_entities", 1)
 
 	// Knowledge extraction defaults
-	viper.SetDefault("memory.extractor_provider", "openai") // Requires API key
-	viper.SetDefault("memory.extractor_concurrency", 3)
-	viper.SetDefault("memory.extractor_timeout", "30s")
+	v.SetDefault("memory.extractor_provider", "openai") // Requires API key
+	v.SetDefault("memory.extractor_concurrency", 3)
+	v.SetDefault("memory.extractor_timeout", "30s")
 
 	// Performance defaults
-	viper.SetDefault("memory.max_latency", "200ms")
-	viper.SetDefault("memory.ingest_batch_size", 32)
-	viper.SetDefault("memory.cache_capacity", 1000)
+	v.SetDefault("memory.max_latency", "200ms")
+	v.SetDefault("memory.ingest_batch_size", 32)
+	v.SetDefault("memory.cache_capacity", 1000)
+	v.SetDefault("memory.dedup_threshold", 0.0) // Dedup off by default
+	v.SetDefault("memory.chunk_size", 256)
+	v.SetDefault("memory.chunk_overlap", 32)
+	v.SetDefault("memory.ingest_max_attempts", 5)
+	v.SetDefault("memory.ingest_retry_backoff", "30s")
 
 	// Observability defaults
-	viper.SetDefault("memory.enable_metrics", true)
-	viper.SetDefault("memory.enable_tracing", true)
-
-	viper.AutomaticEnv()
-	// Replace dots with underscores in env var names e.g. genkit.plugins.googleAI.apiKey becomes GENKIT_PLUGINS_GOOGLEAI_APIKEY
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// TODO: Create default config file if not found
-			// Config file not found; defaults will be used. This is not an error for the application to halt on.
-			// It's good practice to log this situation if a logger is available here.
-			// fmt.Printf("Warning: Config file not found at expected locations. Using default values. Searched: %s\n", viper.ConfigFileUsed())
-		} else {
-			return nil, fmt.Errorf("failed to read config file: %w", err)
-		}
-	}
-
-	err := viper.Unmarshal(&AppConfig)
-	if err != nil {
-		return nil, fmt.Errorf("unable to decode into struct: %w", err)
-	}
-
-	return &AppConfig, nil
+	v.SetDefault("memory.enable_metrics", true)
+	v.SetDefault("memory.enable_tracing", true)
 }
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// projectDBFile is the database file name inside each project's directory.
+const projectDBFile = "libsql.db"
+
+// remoteSchemes are the URL schemes ParseFileDSN reports as served by a libsql server.
+var remoteSchemes = map[string]bool{
+	"libsql": true,
+	"http":   true,
+	"https":  true,
+	"ws":     true,
+	"wss":    true,
+}
+
+// BuildDSN returns the connection string for the configured database.
+// An empty projectName uses DSN; otherwise the project's own database file under
+// LibSQLDataDir is used. file: DSNs are re-escaped as file: URIs and keep their
+// query parameters. Any other DSN is passed through for the driver to resolve,
+// with AuthToken set as the authToken parameter.
+func (c DatabaseConfig) BuildDSN(projectName string) (string, error) {
+	if projectName != "" {
+		path, err := c.ProjectDBPath(projectName)
+		if err != nil {
+			return "", err
+		}
+		return FileDSN(path, nil), nil
+	}
+
+	if c.DSN == "" {
+		return "", fmt.Errorf("database DSN is empty")
+	}
+
+	if strings.HasPrefix(c.DSN, "file:") {
+		path, params, _ := ParseFileDSN(c.DSN)
+		return FileDSN(path, params), nil
+	}
+
+	if c.AuthToken == "" {
+		return c.DSN, nil
+	}
+	u, err := url.Parse(c.DSN)
+	if err != nil {
+		separator := "?"
+		if strings.Contains(c.DSN, "?") {
+			separator = "&"
+		}
+		return c.DSN + separator + "authToken=" + url.QueryEscape(c.AuthToken), nil
+	}
+	query := u.Query()
+	query.Set("authToken", c.AuthToken)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// ProjectDBPath returns the database file of a project under LibSQLDataDir.
+// The project name must be a single path element.
+func (c DatabaseConfig) ProjectDBPath(projectName string) (string, error) {
+	if projectName == "" || projectName == "." || projectName == ".." ||
+		strings.ContainsAny(projectName, `/\`) {
+		return "", fmt.Errorf("invalid project name %q", projectName)
+	}
+	if c.LibSQLDataDir == "" {
+		return "", fmt.Errorf("libsql data directory is not configured")
+	}
+	return filepath.Join(c.LibSQLDataDir, projectName, projectDBFile), nil
+}
+
+// FileDSN builds a file: URI for path, escaping characters such as spaces, '?'
+// and '#' so they are not mistaken for URI syntax.
+func FileDSN(path string, params url.Values) string {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath()
+	if encoded := params.Encode(); encoded != "" {
+		dsn += "?" + encoded
+	}
+	return dsn
+}
+
+// ParseFileDSN splits a local DSN into its unescaped file path and query parameters.
+// Both file: URIs and bare paths are accepted; ok is false for remote DSNs.
+func ParseFileDSN(dsn string) (path string, params url.Values, ok bool) {
+	if isRemoteDSN(dsn) {
+		return "", nil, false
+	}
+
+	path, rawQuery, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		path = unescaped
+	}
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		params = url.Values{}
+	}
+	return path, params, true
+}
+
+// isRemoteDSN reports whether dsn names a libsql server.
+func isRemoteDSN(dsn string) bool {
+	scheme, _, found := strings.Cut(dsn, "://")
+	return found && remoteSchemes[strings.ToLower(scheme)]
+}
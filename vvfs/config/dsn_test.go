@@ -0,0 +1,112 @@
+package config
+
+import (
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildDSN_FilePathWithSpaces(t *testing.T) {
+	cfg := DatabaseConfig{DSN: "file:/var/lib/my data/vvfs#1.db"}
+
+	dsn, err := cfg.BuildDSN("")
+	require.NoError(t, err)
+	assert.Equal(t, "file:/var/lib/my%20data/vvfs%231.db", dsn)
+
+	path, _, ok := ParseFileDSN(dsn)
+	require.True(t, ok)
+	assert.Equal(t, "/var/lib/my data/vvfs#1.db", path)
+}
+
+func TestBuildDSN_FileKeepsQueryParams(t *testing.T) {
+	cfg := DatabaseConfig{
+		DSN:     "file::memory:?cache=shared",
+		Pragmas: map[string]string{"journal_mode": "WAL"},
+	}
+
+	dsn, err := cfg.BuildDSN("")
+	require.NoError(t, err)
+	assert.Equal(t, "file::memory:?cache=shared", dsn, "pragmas are applied as PRAGMA statements, not DSN parameters")
+}
+
+func TestBuildDSN_BareDSNPassedThrough(t *testing.T) {
+	dsn, err := DatabaseConfig{DSN: "libsql.db"}.BuildDSN("")
+	require.NoError(t, err)
+	assert.Equal(t, "libsql.db", dsn)
+
+	// Like any non-file: DSN, a bare one carries the auth token
+	dsn, err = DatabaseConfig{DSN: "libsql.db", AuthToken: "secret"}.BuildDSN("")
+	require.NoError(t, err)
+	assert.Equal(t, "libsql.db?authToken=secret", dsn)
+}
+
+func TestBuildDSN_ProjectDatabase(t *testing.T) {
+	dataDir := filepath.Join("/srv", "vvfs data")
+	cfg := DatabaseConfig{DSN: "libsql://db.example.com", LibSQLDataDir: dataDir, AuthToken: "secret"}
+
+	dsn, err := cfg.BuildDSN("notes app")
+	require.NoError(t, err)
+	assert.Equal(t, "file:/srv/vvfs%20data/notes%20app/libsql.db", dsn)
+
+	for _, name := range []string{"..", "a/b", `a\b`} {
+		_, err := cfg.BuildDSN(name)
+		assert.Error(t, err, name)
+	}
+
+	_, err = DatabaseConfig{}.BuildDSN("notes")
+	assert.Error(t, err)
+}
+
+func TestBuildDSN_RemoteKeepsQueryParams(t *testing.T) {
+	cfg := DatabaseConfig{DSN: "libsql://db.example.com:8080/main?tls=0&timeout=5s"}
+
+	dsn, err := cfg.BuildDSN("")
+	require.NoError(t, err)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "libsql", u.Scheme)
+	assert.Equal(t, "db.example.com:8080", u.Host)
+	assert.Equal(t, "/main", u.Path)
+	assert.Equal(t, url.Values{"tls": {"0"}, "timeout": {"5s"}}, u.Query())
+}
+
+func TestBuildDSN_AuthTokenInjection(t *testing.T) {
+	cfg := DatabaseConfig{
+		DSN:       "https://db.example.com/?authToken=stale&tls=1",
+		AuthToken: "tok+en/with=chars&more",
+	}
+
+	dsn, err := cfg.BuildDSN("")
+	require.NoError(t, err)
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	assert.Equal(t, "tok+en/with=chars&more", u.Query().Get("authToken"))
+	assert.Equal(t, "1", u.Query().Get("tls"))
+	assert.Len(t, u.Query()["authToken"], 1)
+
+	// Local databases never carry the token
+	local := DatabaseConfig{DSN: "file:./libsql.db", AuthToken: "secret"}
+	dsn, err = local.BuildDSN("")
+	require.NoError(t, err)
+	assert.Equal(t, "file:./libsql.db", dsn)
+}
+
+func TestBuildDSN_EmptyDSN(t *testing.T) {
+	_, err := DatabaseConfig{}.BuildDSN("")
+	assert.Error(t, err)
+}
+
+func TestParseFileDSN_Remote(t *testing.T) {
+	_, _, ok := ParseFileDSN("wss://db.example.com")
+	assert.False(t, ok)
+
+	path, params, ok := ParseFileDSN("test.db")
+	require.True(t, ok)
+	assert.Equal(t, "test.db", path)
+	assert.Empty(t, params)
+}
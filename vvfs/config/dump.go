@@ -0,0 +1,191 @@
+package config
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// configSource is parsed for the field comments used to annotate DumpDefaults.
+//
+//go:embed config.go
+var configSource []byte
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// DumpDefaults renders the full default configuration as annotated YAML.
+// Every mapstructure key of Config is emitted with its registered default (or
+// its zero value when none is registered), its Go type and the field's comment,
+// so the output can seed a config file or serve as a reference for reviewing one.
+func DumpDefaults() ([]byte, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	comments, err := fieldComments()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Default vvfs configuration (generated by config.DumpDefaults)\n")
+	writeSection(&buf, reflect.TypeOf(Config{}), "", 0, v, comments)
+	return buf.Bytes(), nil
+}
+
+// Keys returns every dotted mapstructure key of Config in sorted order.
+func Keys() []string {
+	var keys []string
+	walkKeys(reflect.TypeOf(Config{}), "", func(key string, _ reflect.StructField) {
+		keys = append(keys, key)
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+// walkKeys calls fn for every leaf field below t, descending into nested structs.
+func walkKeys(t reflect.Type, prefix string, fn func(key string, field reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := mapstructureName(field)
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		if isSection(field.Type) {
+			walkKeys(field.Type, key+".", fn)
+			continue
+		}
+		fn(key, field)
+	}
+}
+
+// writeSection emits the fields of t as YAML at the given indent level.
+func writeSection(buf *bytes.Buffer, t reflect.Type, prefix string, indent int, v *viper.Viper, comments map[string]string) {
+	pad := strings.Repeat("  ", indent)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := mapstructureName(field)
+		if name == "" {
+			continue
+		}
+		key := prefix + name
+		comment := comments[t.Name()+"."+field.Name]
+
+		if isSection(field.Type) {
+			if comment != "" {
+				fmt.Fprintf(buf, "%s# %s\n", pad, comment)
+			}
+			fmt.Fprintf(buf, "%s%s:\n", pad, name)
+			writeSection(buf, field.Type, key+".", indent+1, v, comments)
+			continue
+		}
+
+		annotation := field.Type.String()
+		value := v.Get(key)
+		if value == nil {
+			value = reflect.Zero(field.Type).Interface()
+			annotation += ", no default"
+		}
+		if comment != "" {
+			annotation += "; " + comment
+		}
+		fmt.Fprintf(buf, "%s%s: %s # %s\n", pad, name, yamlValue(value, field.Type), annotation)
+	}
+}
+
+// isSection reports whether a field type is rendered as a nested mapping.
+func isSection(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != durationType
+}
+
+// mapstructureName returns the key a field is decoded from, or "" if it is skipped.
+func mapstructureName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// yamlValue formats a default as a YAML scalar, flow sequence or flow mapping.
+func yamlValue(value any, t reflect.Type) string {
+	if t == durationType {
+		if d, ok := value.(time.Duration); ok {
+			return strconv.Quote(d.String())
+		}
+		return strconv.Quote(fmt.Sprint(value))
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return strconv.Quote(rv.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = yamlValue(rv.Index(i).Interface(), t.Elem())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		items := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			items = append(items, fmt.Sprint(key.Interface())+": "+yamlValue(rv.MapIndex(key).Interface(), t.Elem()))
+		}
+		sort.Strings(items)
+		return "{" + strings.Join(items, ", ") + "}"
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// fieldComments maps "Type.Field" to the field's line or doc comment in config.go.
+func fieldComments() (map[string]string, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "config.go", configSource, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config source: %w", err)
+	}
+
+	comments := make(map[string]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			for _, field := range structType.Fields.List {
+				text := field.Comment.Text()
+				if text == "" {
+					text = field.Doc.Text()
+				}
+				text = strings.Join(strings.Fields(text), " ")
+				for _, name := range field.Names {
+					comments[typeSpec.Name.Name+"."+name.Name] = text
+				}
+			}
+		}
+	}
+	return comments, nil
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectTagKeys lists dotted mapstructure keys independently of the dump code
+func collectTagKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+		if field.Type.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Duration(0)) {
+			keys = append(keys, collectTagKeys(field.Type, key+".")...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestDumpDefaultsIncludesEveryKey(t *testing.T) {
+	dump, err := DumpDefaults()
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(bytes.NewReader(dump)))
+
+	keys := collectTagKeys(reflect.TypeOf(Config{}), "")
+	require.NotEmpty(t, keys)
+	assert.ElementsMatch(t, keys, Keys())
+	for _, key := range keys {
+		assert.True(t, v.IsSet(key), "dump is missing %s", key)
+	}
+}
+
+func TestDumpDefaultsMatchesRegisteredDefaults(t *testing.T) {
+	dump, err := DumpDefaults()
+	require.NoError(t, err)
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(bytes.NewReader(dump)))
+
+	defaults := viper.New()
+	setDefaults(defaults)
+	for _, key := range defaults.AllKeys() {
+		assert.Equal(t, defaults.GetString(key), v.GetString(key), "default for %s", key)
+	}
+	assert.Equal(t, 30*time.Second, v.GetDuration("memory.extractor_timeout"))
+	assert.Equal(t, []string{"password", "secret", "key", "token", "credential"}, v.GetStringSlice("harness.blocked_words"))
+
+	// Field comments annotate the output
+	assert.Contains(t, string(dump), "Top-k results to return")
+}
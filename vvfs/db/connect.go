@@ -5,8 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
 
 // LibSQLEmbeddedConfig holds configuration for embedded libsql connections
@@ -37,8 +40,13 @@ func ConnectToDBWithConfig(config *LibSQLEmbeddedConfig) (*sql.DB, error) {
 	}
 
 	// Embedded mode with enhanced pragmas
-	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_synchronous=NORMAL&_cache_size=-64000&_temp_store=memory",
-		config.DatabasePath)
+	dsn := appconfig.FileDSN(config.DatabasePath, url.Values{
+		"_foreign_keys": {"1"},
+		"_journal_mode": {"WAL"},
+		"_synchronous":  {"NORMAL"},
+		"_cache_size":   {"-64000"},
+		"_temp_store":   {"memory"},
+	})
 
 	slog.Info("Connecting to embedded libsql", "dsn", dsn)
 
@@ -0,0 +1,27 @@
+//go:build libsql_decltype
+
+package customlibsql
+
+/*
+#include "libsql.h"
+
+// Added by build/patches/libsql-decltype.diff; upstream libsql.h does not declare it
+int libsql_column_decltype(libsql_stmt_t stmt, int col, const char **out_decltype, const char **out_err_msg);
+*/
+import "C"
+
+// declTypes reads the declared type of each result column so Rows.Next can return
+// time.Time and bool values. Columns without a declared type (expressions) are "".
+// Requires libsql artifacts built with build/patches/libsql-decltype.diff.
+func (s *Statement) declTypes(count int) []string {
+	declTypes := make([]string, count)
+	for i := range declTypes {
+		var declType *C.char
+		if C.libsql_column_decltype(s.stmt, C.int(i), &declType, nil) != 0 || declType == nil {
+			continue
+		}
+		declTypes[i] = C.GoString(declType)
+		C.libsql_free_string(declType)
+	}
+	return declTypes
+}
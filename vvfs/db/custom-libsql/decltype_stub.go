@@ -0,0 +1,10 @@
+//go:build !libsql_decltype
+
+package customlibsql
+
+// declTypes reports no declared types, so values are returned as libsql stores them.
+// Build with the libsql_decltype tag against patched artifacts to map DATETIME and
+// BOOLEAN columns to time.Time and bool.
+func (s *Statement) declTypes(count int) []string {
+	return make([]string, count)
+}
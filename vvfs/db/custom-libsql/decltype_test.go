@@ -0,0 +1,76 @@
+//go:build libsql_decltype
+
+package customlibsql
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRows_DatetimeAndBooleanColumns(t *testing.T) {
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "types.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE events (
+		id INTEGER PRIMARY KEY,
+		happened_at DATETIME NOT NULL,
+		updated_at TIMESTAMP,
+		active BOOLEAN NOT NULL,
+		note TEXT
+	)`)
+	require.NoError(t, err)
+
+	at := time.Date(2024, 3, 9, 14, 30, 15, 123456789, time.FixedZone("CET", 3600))
+	_, err = db.Exec(`INSERT INTO events (id, happened_at, updated_at, active, note) VALUES (?, ?, ?, ?, ?)`,
+		1, at, nil, true, "2024-03-09")
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO events (id, happened_at, updated_at, active, note) VALUES (2, '2023-12-31 23:59:59', 1700000000, 0, NULL)`)
+	require.NoError(t, err)
+
+	rows, err := db.Query(`SELECT happened_at, updated_at, active, note FROM events ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"DATETIME", "TIMESTAMP", "BOOLEAN", "TEXT"}, []string{
+		columnTypes[0].DatabaseTypeName(), columnTypes[1].DatabaseTypeName(),
+		columnTypes[2].DatabaseTypeName(), columnTypes[3].DatabaseTypeName(),
+	})
+
+	// Native Go types come back through interface{} destinations
+	require.True(t, rows.Next())
+	var happened, updated, active, note interface{}
+	require.NoError(t, rows.Scan(&happened, &updated, &active, &note))
+	require.IsType(t, time.Time{}, happened)
+	assert.True(t, at.Equal(happened.(time.Time)))
+	assert.Nil(t, updated)
+	assert.Equal(t, true, active)
+	assert.Equal(t, "2024-03-09", note, "TEXT columns are not parsed as times")
+
+	// ... and scan directly into typed destinations
+	require.True(t, rows.Next())
+	var happenedAt time.Time
+	var updatedAt *time.Time
+	var isActive bool
+	var missing *string
+	require.NoError(t, rows.Scan(&happenedAt, &updatedAt, &isActive, &missing))
+	assert.Equal(t, time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC), happenedAt)
+	require.NotNil(t, updatedAt)
+	assert.Equal(t, time.Unix(1700000000, 0).UTC(), *updatedAt)
+	assert.False(t, isActive)
+	assert.Nil(t, missing)
+
+	assert.False(t, rows.Next())
+	require.NoError(t, rows.Err())
+
+	// Expressions have no declared type and keep their raw representation
+	var count interface{}
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM events WHERE active`).Scan(&count))
+	assert.Equal(t, int64(1), count)
+}
@@ -51,9 +51,12 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 	"time"
 	"unsafe"
 )
@@ -61,6 +64,14 @@ import (
 // Connection represents a libSQL database connection
 type Connection struct {
 	conn C.libsql_connection_t
+
+	// The C API cannot interrupt a running statement, so a call abandoned on context
+	// cancellation keeps running in the background. Until it returns, frees of the
+	// statement, rows and connection are queued in deferred instead of running.
+	mu       sync.Mutex
+	bad      bool          // a call was abandoned; database/sql must discard the connection
+	pending  chan struct{} // closed when the abandoned call returns
+	deferred []func()
 }
 
 // Open creates a new libSQL database connection
@@ -76,15 +87,7 @@ type Connector struct {
 
 // Connect creates a new database connection
 func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
-	// Extract filename from DSN (basic parsing for file: DSN)
-	dsn := c.dsn
-	var filename string
-
-	if len(dsn) > 5 && dsn[:5] == "file:" {
-		filename = dsn[5:]
-	} else {
-		filename = dsn
-	}
+	filename := dsnFilename(c.dsn)
 
 	// Convert to C string
 	cFilename := C.CString(filename)
@@ -221,6 +224,9 @@ func (d *Driver) Open(dsn string) (driver.Conn, error) {
 
 // Prepare prepares a statement
 func (c *Connection) Prepare(query string) (driver.Stmt, error) {
+	if !c.IsValid() {
+		return nil, driver.ErrBadConn
+	}
 	cQuery := C.CString(query)
 	defer C.free(unsafe.Pointer(cQuery))
 
@@ -236,13 +242,77 @@ func (c *Connection) Prepare(query string) (driver.Stmt, error) {
 
 // Close closes the connection
 func (c *Connection) Close() error {
-	if c.conn != nil {
-		C.libsql_disconnect(c.conn)
-		c.conn = nil
-	}
+	c.release(func() {
+		if c.conn != nil {
+			C.libsql_disconnect(c.conn)
+			c.conn = nil
+		}
+	})
 	return nil
 }
 
+// IsValid reports whether the connection can be reused; it implements driver.Validator so
+// database/sql drops connections with an abandoned call still running.
+func (c *Connection) IsValid() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.bad
+}
+
+// call runs fn and waits for it until ctx is done. On cancellation fn is left running,
+// the connection is marked bad and ctx.Err() is returned; a cancelled write may still
+// complete.
+func (c *Connection) call(ctx context.Context, fn func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		fn()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	c.mu.Lock()
+	c.bad = true
+	c.pending = done
+	c.mu.Unlock()
+	go func() {
+		<-done
+		c.mu.Lock()
+		deferred := c.deferred
+		c.deferred = nil
+		c.pending = nil
+		c.mu.Unlock()
+		for _, free := range deferred {
+			free()
+		}
+	}()
+	return ctx.Err()
+}
+
+// release runs free now, or after the abandoned call returns if one is still running.
+func (c *Connection) release(free func()) {
+	c.mu.Lock()
+	if c.pending != nil {
+		c.deferred = append(c.deferred, free)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	free()
+}
+
 // Begin starts a transaction
 func (c *Connection) Begin() (driver.Tx, error) {
 	return &Transaction{conn: c}, nil
@@ -256,10 +326,12 @@ type Statement struct {
 }
 
 func (s *Statement) Close() error {
-	if s.stmt != nil {
-		C.libsql_free_stmt(s.stmt)
-		s.stmt = nil
-	}
+	s.conn.release(func() {
+		if s.stmt != nil {
+			C.libsql_free_stmt(s.stmt)
+			s.stmt = nil
+		}
+	})
 	return nil
 }
 
@@ -272,27 +344,8 @@ func (s *Statement) Exec(args []driver.Value) (driver.Result, error) {
 	C.libsql_reset_stmt(s.stmt, nil)
 
 	// Bind parameters
-	for i, arg := range args {
-		var result C.int
-		switch v := arg.(type) {
-		case int64:
-			result = C.libsql_bind_int(s.stmt, C.int(i+1), C.longlong(v), nil)
-		case float64:
-			result = C.libsql_bind_float(s.stmt, C.int(i+1), C.double(v), nil)
-		case string:
-			cStr := C.CString(v)
-			defer C.free(unsafe.Pointer(cStr))
-			result = C.libsql_bind_string(s.stmt, C.int(i+1), cStr, nil)
-		case []byte:
-			result = C.libsql_bind_blob(s.stmt, C.int(i+1), (*C.uchar)(unsafe.Pointer(&v[0])), C.int(len(v)), nil)
-		case nil:
-			result = C.libsql_bind_null(s.stmt, C.int(i+1), nil)
-		default:
-			return nil, fmt.Errorf("unsupported parameter type: %T", v)
-		}
-		if result != 0 {
-			return nil, fmt.Errorf("failed to bind parameter %d: %d", i+1, int(result))
-		}
+	if err := s.bind(args); err != nil {
+		return nil, err
 	}
 
 	// Execute
@@ -313,41 +366,104 @@ func (s *Statement) Query(args []driver.Value) (driver.Rows, error) {
 	C.libsql_reset_stmt(s.stmt, nil)
 
 	// Bind parameters (same as Exec)
+	if err := s.bind(args); err != nil {
+		return nil, err
+	}
+
+	// Execute query and get rows via libsql_query_stmt
+	var rows C.libsql_rows_t
+	var outErr *C.char
+	rc := C.libsql_query_stmt(s.stmt, &rows, (**C.char)(unsafe.Pointer(&outErr)))
+	if rc != 0 {
+		if outErr != nil {
+			return nil, fmt.Errorf("query failed: %s", C.GoString(outErr))
+		}
+		return nil, fmt.Errorf("query failed: code %d", int(rc))
+	}
+
+	r := &Rows{rows: rows, conn: s.conn}
+	r.declTypes = s.declTypes(len(r.Columns()))
+	r.kinds = make([]columnKind, len(r.declTypes))
+	for i, declType := range r.declTypes {
+		r.kinds[i] = declaredKind(declType)
+	}
+	return r, nil
+}
+
+// ExecContext executes the statement and returns ctx.Err() as soon as ctx is done, leaving
+// the connection bad so database/sql discards it (see Connection.call).
+func (s *Statement) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values, err := positionalValues(args)
+	if err != nil {
+		return nil, err
+	}
+	var res driver.Result
+	if callErr := s.conn.call(ctx, func() { res, err = s.Exec(values) }); callErr != nil {
+		return nil, callErr
+	}
+	return res, err
+}
+
+// QueryContext runs the query under ctx like ExecContext; the returned rows fetch each row
+// under ctx too, so a long scan is abandoned once ctx is cancelled.
+func (s *Statement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := positionalValues(args)
+	if err != nil {
+		return nil, err
+	}
+	var rows driver.Rows
+	if callErr := s.conn.call(ctx, func() { rows, err = s.Query(values) }); callErr != nil {
+		return nil, callErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := rows.(*Rows)
+	r.ctx = ctx
+	return r, nil
+}
+
+// positionalValues converts named values to positional ones; named parameters are unsupported.
+func positionalValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("named parameter %q is not supported", arg.Name)
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+// bind binds args to the statement's positional parameters
+func (s *Statement) bind(args []driver.Value) error {
 	for i, arg := range args {
 		var result C.int
-		switch v := arg.(type) {
+		switch v := bindableValue(arg).(type) {
 		case int64:
 			result = C.libsql_bind_int(s.stmt, C.int(i+1), C.longlong(v), nil)
 		case float64:
 			result = C.libsql_bind_float(s.stmt, C.int(i+1), C.double(v), nil)
 		case string:
 			cStr := C.CString(v)
-			defer C.free(unsafe.Pointer(cStr))
 			result = C.libsql_bind_string(s.stmt, C.int(i+1), cStr, nil)
+			C.free(unsafe.Pointer(cStr))
 		case []byte:
-			result = C.libsql_bind_blob(s.stmt, C.int(i+1), (*C.uchar)(unsafe.Pointer(&v[0])), C.int(len(v)), nil)
+			if len(v) == 0 {
+				result = C.libsql_bind_blob(s.stmt, C.int(i+1), nil, 0, nil)
+			} else {
+				result = C.libsql_bind_blob(s.stmt, C.int(i+1), (*C.uchar)(unsafe.Pointer(&v[0])), C.int(len(v)), nil)
+			}
 		case nil:
 			result = C.libsql_bind_null(s.stmt, C.int(i+1), nil)
 		default:
-			return nil, fmt.Errorf("unsupported parameter type: %T", v)
+			return fmt.Errorf("unsupported parameter type: %T", v)
 		}
 		if result != 0 {
-			return nil, fmt.Errorf("failed to bind parameter %d: %d", i+1, int(result))
+			return fmt.Errorf("failed to bind parameter %d: %d", i+1, int(result))
 		}
 	}
-
-	// Execute query and get rows via libsql_query_stmt
-	var rows C.libsql_rows_t
-	var outErr *C.char
-	rc := C.libsql_query_stmt(s.stmt, &rows, (**C.char)(unsafe.Pointer(&outErr)))
-	if rc != 0 {
-		if outErr != nil {
-			return nil, fmt.Errorf("query failed: %s", C.GoString(outErr))
-		}
-		return nil, fmt.Errorf("query failed: code %d", int(rc))
-	}
-
-	return &Rows{rows: rows}, nil
+	return nil
 }
 
 // Result implementation
@@ -366,8 +482,12 @@ func (r *Result) RowsAffected() (int64, error) {
 
 // Rows implementation
 type Rows struct {
-	rows    C.libsql_rows_t
-	columns []string
+	rows      C.libsql_rows_t
+	columns   []string
+	declTypes []string     // declared column types, "" for expressions
+	kinds     []columnKind // per-column conversion derived from declTypes
+	conn      *Connection
+	ctx       context.Context // bounds each row fetch when set
 }
 
 func (r *Rows) Columns() []string {
@@ -383,12 +503,37 @@ func (r *Rows) Columns() []string {
 	return r.columns
 }
 
+// ColumnTypeDatabaseTypeName returns the column's declared type in upper case, or ""
+// for expressions without one.
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < 0 || index >= len(r.declTypes) {
+		return ""
+	}
+	return strings.ToUpper(r.declTypes[index])
+}
+
 func (r *Rows) Close() error {
-	C.libsql_free_rows(r.rows)
+	r.conn.release(func() {
+		C.libsql_free_rows(r.rows)
+	})
 	return nil
 }
 
 func (r *Rows) Next(dest []driver.Value) error {
+	if r.ctx == nil {
+		return r.next(dest)
+	}
+	// Fetch into a scratch slice so an abandoned fetch cannot write into dest late
+	row := make([]driver.Value, len(dest))
+	var err error
+	if callErr := r.conn.call(r.ctx, func() { err = r.next(row) }); callErr != nil {
+		return callErr
+	}
+	copy(dest, row)
+	return err
+}
+
+func (r *Rows) next(dest []driver.Value) error {
 	var outRow C.libsql_row_t
 	var outErr *C.char
 	rc := C.libsql_next_row(r.rows, &outRow, (**C.char)(unsafe.Pointer(&outErr)))
@@ -423,6 +568,9 @@ func (r *Rows) Next(dest []driver.Value) error {
 		default:
 			dest[i] = nil
 		}
+		if i < len(r.kinds) {
+			dest[i] = convertValue(r.kinds[i], dest[i])
+		}
 	}
 
 	return nil
@@ -460,8 +608,27 @@ func GetLibSQLPath() string {
 
 // NewEmbeddedConnector creates a connector for embedded use (compatibility wrapper)
 func NewEmbeddedConnector(dbPath string) *Connector {
-	dsn := fmt.Sprintf("file:%s", dbPath)
-	return &Connector{dsn: dsn}
+	return &Connector{dsn: fileDSN(dbPath)}
+}
+
+// fileDSN builds a file: DSN for path, escaping characters such as '?' and '#' so
+// dsnFilename returns the path unchanged.
+func fileDSN(path string) string {
+	return "file:" + (&url.URL{Path: path}).EscapedPath()
+}
+
+// dsnFilename extracts the filename libsql_open_file expects. file: DSNs are unescaped
+// and lose their query parameters; any other DSN is used as the filename itself.
+func dsnFilename(dsn string) string {
+	rest, ok := strings.CutPrefix(dsn, "file:")
+	if !ok {
+		return dsn
+	}
+	path, _, _ := strings.Cut(rest, "?")
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		return unescaped
+	}
+	return path
 }
 
 // stripExt removes file extension from filename
@@ -496,7 +663,7 @@ func CallExtensionInit(path string, initName string) (int, string) {
 // DebugLoadExtension opens an embedded connection and attempts to call libsql_load_extension on the provided shared object path and optional entrypoint.
 // Returns rc (int), message string (if any), and error for Go-level failures.
 func DebugLoadExtension(dbPath string, soPath string, entry string) (int, string, error) {
-	connector := &Connector{dsn: fmt.Sprintf("file:%s", dbPath)}
+	connector := &Connector{dsn: fileDSN(dbPath)}
 	drvConn, err := connector.Connect(context.Background())
 	if err != nil {
 		return -1, "", fmt.Errorf("connect failed: %w", err)
@@ -0,0 +1,97 @@
+package customlibsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertValue(t *testing.T) {
+	ts := time.Date(2024, 3, 9, 14, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		declType string
+		in       driver.Value
+		want     driver.Value
+	}{
+		{name: "datetime text", declType: "DATETIME", in: "2024-03-09 14:30:15", want: ts},
+		{name: "timestamp iso with zone", declType: "timestamp", in: "2024-03-09T16:30:15+02:00", want: ts},
+		{name: "timestamp utc suffix", declType: "TIMESTAMP", in: "2024-03-09T14:30:15Z", want: ts},
+		{name: "date only", declType: "DATE", in: "2024-03-09", want: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "unix seconds", declType: "DATETIME", in: ts.Unix(), want: ts},
+		{name: "unparseable text kept", declType: "DATETIME", in: "yesterday", want: "yesterday"},
+		{name: "boolean true", declType: "BOOLEAN", in: int64(1), want: true},
+		{name: "boolean false", declType: "bool", in: int64(0), want: false},
+		{name: "boolean text", declType: "BOOLEAN", in: "true", want: true},
+		{name: "null stays nil", declType: "BOOLEAN", in: nil, want: nil},
+		{name: "plain integer", declType: "INTEGER", in: int64(1), want: int64(1)},
+		{name: "no declared type", declType: "", in: "2024-03-09", want: "2024-03-09"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := convertValue(declaredKind(tt.declType), tt.in)
+			if want, ok := tt.want.(time.Time); ok {
+				require.IsType(t, time.Time{}, got)
+				assert.True(t, want.Equal(got.(time.Time)), "got %v, want %v", got, want)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatement_QueryContextAbandonsOnCancel(t *testing.T) {
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "cancel.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Counting this many generated rows takes far longer than the deadline
+	start := time.Now()
+	var n int64
+	err = db.QueryRowContext(ctx, `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c LIMIT 100000000)
+		SELECT count(*) FROM c`).Scan(&n)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 2*time.Second, "cancellation should not wait for the query")
+
+	// The connection running the abandoned query is discarded, so the pool opens a fresh one
+	var one int64
+	require.NoError(t, db.QueryRowContext(context.Background(), `SELECT 1`).Scan(&one))
+	assert.Equal(t, int64(1), one)
+}
+
+func TestDSNFilename(t *testing.T) {
+	assert.Equal(t, "/data/my data/v?1#2.db", dsnFilename(fileDSN("/data/my data/v?1#2.db")))
+	assert.Equal(t, "./libsql.db", dsnFilename("file:./libsql.db?_journal_mode=WAL"))
+	assert.Equal(t, "libsql.db", dsnFilename("libsql.db"), "bare DSNs are used as the filename")
+}
+
+func TestConnector_OpensEscapedPath(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "my data")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, "notes#1.db")
+
+	db := sql.OpenDB(NewEmbeddedConnector(path))
+	defer db.Close()
+	_, err := db.Exec(`CREATE TABLE notes (id INTEGER PRIMARY KEY)`)
+	require.NoError(t, err)
+
+	// The database lives at the unescaped path, not at its URI spelling
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(root, "my%20data"))
+	assert.True(t, os.IsNotExist(err))
+}
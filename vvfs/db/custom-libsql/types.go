@@ -0,0 +1,107 @@
+package customlibsql
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// columnKind classifies a column by its declared type for value conversion
+type columnKind int
+
+const (
+	kindPlain columnKind = iota
+	kindDatetime
+	kindBoolean
+)
+
+// timestampFormat is used when binding time.Time values; it sorts lexically and
+// round-trips through parseTimestamp
+const timestampFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// timestampFormats are the TEXT layouts recognized in datetime columns, most specific first
+var timestampFormats = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// declaredKind maps a column's declared type (e.g. "DATETIME", "boolean") to a column kind
+func declaredKind(declType string) columnKind {
+	switch strings.ToUpper(strings.TrimSpace(declType)) {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return kindDatetime
+	case "BOOL", "BOOLEAN":
+		return kindBoolean
+	default:
+		return kindPlain
+	}
+}
+
+// convertValue converts a raw libSQL value to the Go type expected for the column kind.
+// Values that cannot be converted (e.g. unparseable timestamps) are returned unchanged.
+func convertValue(kind columnKind, v driver.Value) driver.Value {
+	switch kind {
+	case kindDatetime:
+		switch val := v.(type) {
+		case string:
+			if t, ok := parseTimestamp(val); ok {
+				return t
+			}
+		case int64:
+			// Integer timestamps are Unix seconds
+			return time.Unix(val, 0).UTC()
+		case float64:
+			sec := int64(val)
+			return time.Unix(sec, int64((val-float64(sec))*1e9)).UTC()
+		}
+	case kindBoolean:
+		switch val := v.(type) {
+		case int64:
+			return val != 0
+		case float64:
+			return val != 0
+		case string:
+			switch strings.ToLower(strings.TrimSpace(val)) {
+			case "1", "true", "t", "yes", "y":
+				return true
+			case "0", "false", "f", "no", "n":
+				return false
+			}
+		}
+	}
+	return v
+}
+
+// parseTimestamp parses SQLite TEXT timestamps, normalizing to UTC
+func parseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "Z")
+	for _, layout := range timestampFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// bindableValue converts driver values with no native libSQL type: booleans become
+// 0/1 integers and times become UTC timestamp strings
+func bindableValue(v driver.Value) driver.Value {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return int64(1)
+		}
+		return int64(0)
+	case time.Time:
+		return val.UTC().Format(timestampFormat)
+	default:
+		return v
+	}
+}
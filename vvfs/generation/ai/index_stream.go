@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/trees"
+)
+
+// IndexProgress reports one file handled by IndexTreeStream
+type IndexProgress struct {
+	Path      string          `json:"path"`       // file just handled
+	FileNode  *trees.FileNode `json:"file"`       // nil when the file could not be read
+	Embedding []float32       `json:"-"`          // nil when Err is set
+	Err       error           `json:"-"`          // per-file failure; indexing continues
+	FilesDone int             `json:"files_done"` // files handled so far, including failures
+	FilesSeen int             `json:"files_seen"` // files discovered so far
+}
+
+// IndexTreeStream walks root and embeds every regular file in batches, emitting one progress
+// event per file. Per-file failures are reported on the event and do not stop the walk.
+// Cancelling ctx stops the walk before the next file or batch; both channels are closed
+// when indexing ends, and the error channel yields at most one error (the walk or ctx error)
+func (s *Service) IndexTreeStream(ctx context.Context, root string) (<-chan IndexProgress, <-chan error) {
+	progress := make(chan IndexProgress, s.ingestBatchSize)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(progress)
+		if err := s.indexTree(ctx, root, progress); err != nil {
+			errc <- err
+		}
+	}()
+
+	return progress, errc
+}
+
+// indexTree runs the walk behind IndexTreeStream
+func (s *Service) indexTree(ctx context.Context, root string, progress chan<- IndexProgress) error {
+	var (
+		pending []*trees.FileNode
+		done    int
+		seen    int
+	)
+
+	emit := func(event IndexProgress) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		done++
+		event.FilesDone, event.FilesSeen = done, seen
+		select {
+		case progress <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		texts := make([]string, len(pending))
+		for i, fileNode := range pending {
+			texts[i] = s.generateFileRepresentation(fileNode)
+		}
+		embeddings, errs := s.embedBatch(ctx, texts)
+		for i, fileNode := range pending {
+			event := IndexProgress{Path: fileNode.Path, FileNode: fileNode, Embedding: embeddings[i]}
+			if errs[i] != nil {
+				event.Embedding = nil
+				event.Err = fmt.Errorf("%s: failed to generate embedding: %w", fileNode.Path, errs[i])
+			}
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+		pending = pending[:0]
+		return nil
+	}
+
+	walkErr := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			if path == root {
+				return err
+			}
+			seen++
+			return emit(IndexProgress{Path: path, Err: fmt.Errorf("%s: failed to read: %w", path, err)})
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		seen++
+		info, err := entry.Info()
+		if err != nil {
+			return emit(IndexProgress{Path: path, Err: fmt.Errorf("%s: failed to stat: %w", path, err)})
+		}
+		pending = append(pending, &trees.FileNode{
+			Path:      path,
+			Name:      entry.Name(),
+			Extension: strings.ToLower(filepath.Ext(entry.Name())),
+			Metadata:  trees.NewMetadata(info),
+		})
+		if len(pending) >= s.ingestBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if walkErr != nil {
+		return fmt.Errorf("failed to index %s: %w", root, walkErr)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("failed to index %s: %w", root, err)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/trees"
+)
+
+// Move is a single planned file relocation
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// OrganizationPlan is a machine-executable organization proposal
+type OrganizationPlan struct {
+	Moves       []Move   `json:"moves"`
+	Directories []string `json:"directories"` // Directories that must be created, parents first
+	Description string   `json:"description"`
+}
+
+// contentTypeFolders maps detected content types to default folder names
+var contentTypeFolders = map[string]string{
+	"text":       "Documents",
+	"document":   "Documents",
+	"image":      "Images",
+	"video":      "Videos",
+	"audio":      "Audio",
+	"structured": "Data",
+	"code":       "Code",
+	"unknown":    "Other",
+}
+
+// PlanOrganization builds a move plan for the given files without touching the filesystem.
+// Model-suggested groupings are used when available; otherwise files are grouped by content type.
+func (s *Service) PlanOrganization(ctx context.Context, files []*trees.FileNode) (*OrganizationPlan, error) {
+	plan := &OrganizationPlan{
+		Moves:       make([]Move, 0, len(files)),
+		Directories: make([]string, 0),
+	}
+	if len(files) == 0 {
+		return plan, nil
+	}
+
+	groups, description := s.suggestFileGroups(ctx, files)
+	plan.Description = description
+
+	plannedTargets := make(map[string]bool)
+	plannedDirs := make(map[string]bool)
+
+	for _, file := range files {
+		if file == nil || file.Path == "" {
+			continue
+		}
+
+		name := file.Name
+		if name == "" {
+			name = filepath.Base(file.Path)
+		}
+
+		folder, ok := groups[name]
+		if !ok {
+			folder = contentTypeFolders[s.detectContentType(file)]
+		}
+
+		from := filepath.Clean(file.Path)
+		targetDir := filepath.Join(filepath.Dir(from), folder)
+		to := filepath.Join(targetDir, name)
+
+		// Already organized
+		if to == from {
+			continue
+		}
+
+		// Never plan two files onto the same destination or over an existing file
+		if plannedTargets[to] {
+			log.Printf("Warning: Skipping move of %s, destination %s already planned", from, to)
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			log.Printf("Warning: Skipping move of %s, destination %s already exists", from, to)
+			continue
+		}
+		plannedTargets[to] = true
+
+		if !plannedDirs[targetDir] {
+			plannedDirs[targetDir] = true
+			if _, err := os.Stat(targetDir); os.IsNotExist(err) {
+				plan.Directories = append(plan.Directories, targetDir)
+			}
+		}
+
+		plan.Moves = append(plan.Moves, Move{From: from, To: to})
+	}
+
+	// Parents sort before their children
+	sort.Strings(plan.Directories)
+
+	if plan.Description == "" {
+		plan.Description = fmt.Sprintf("Group %d files into %d folders by content type", len(plan.Moves), len(plannedDirs))
+	}
+
+	return plan, nil
+}
+
+// suggestFileGroups asks the chat model for a file→folder grouping, returning nil on failure
+func (s *Service) suggestFileGroups(ctx context.Context, files []*trees.FileNode) (map[string]string, string) {
+	var fileDescriptions strings.Builder
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		fileDescriptions.WriteString(fmt.Sprintf("- %s (%s)\n", file.Name, s.detectContentType(file)))
+	}
+
+	prompt := fmt.Sprintf(`Group these files into folders:
+
+Files:
+%s
+
+Respond with JSON only:
+{
+  "file_groups": {"Documents": ["file1.txt", "file2.pdf"]},
+  "description": "One sentence explaining the grouping"
+}`, fileDescriptions.String())
+
+	response, err := s.modelManager.GenerateText(ctx, prompt)
+	if err != nil {
+		log.Printf("Warning: Model grouping unavailable, falling back to content types: %v", err)
+		return nil, ""
+	}
+
+	data, ok := harness.ExtractJSON(response)
+	if !ok {
+		return nil, ""
+	}
+
+	var parsed struct {
+		FileGroups  map[string][]string `json:"file_groups"`
+		Description string              `json:"description"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		log.Printf("Warning: Failed to parse model grouping: %v", err)
+		return nil, ""
+	}
+
+	groups := make(map[string]string)
+	for folder, names := range parsed.FileGroups {
+		// Reject folder names that would escape the file's directory
+		folder = filepath.Clean(strings.TrimSuffix(folder, "/"))
+		if folder == "." || strings.HasPrefix(folder, "..") || filepath.IsAbs(folder) {
+			continue
+		}
+		for _, name := range names {
+			groups[name] = folder
+		}
+	}
+
+	return groups, parsed.Description
+}
+
+// ApplyPlan executes an organization plan. With dryRun set, the plan is only logged.
+// Moves are applied in order; if any step fails, completed moves are reverted and
+// newly created directories removed before the error is returned.
+func (s *Service) ApplyPlan(ctx context.Context, plan *OrganizationPlan, dryRun bool) error {
+	if plan == nil {
+		return fmt.Errorf("plan cannot be nil")
+	}
+	for i, move := range plan.Moves {
+		if move.From == "" || move.To == "" {
+			return fmt.Errorf("invalid move at index %d: source and destination are required", i)
+		}
+	}
+
+	if dryRun {
+		for _, dir := range plan.Directories {
+			log.Printf("[dry-run] mkdir %s", dir)
+		}
+		for _, move := range plan.Moves {
+			log.Printf("[dry-run] move %s -> %s", move.From, move.To)
+		}
+		return nil
+	}
+
+	var createdDirs []string
+	var completed []Move
+
+	rollback := func(cause error) error {
+		for i := len(completed) - 1; i >= 0; i-- {
+			if err := os.Rename(completed[i].To, completed[i].From); err != nil {
+				log.Printf("Warning: Rollback failed to restore %s: %v", completed[i].From, err)
+			}
+		}
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			if err := os.Remove(createdDirs[i]); err != nil {
+				log.Printf("Warning: Rollback failed to remove directory %s: %v", createdDirs[i], err)
+			}
+		}
+		return cause
+	}
+
+	for _, dir := range plan.Directories {
+		created, err := mkdirAllTracked(dir)
+		createdDirs = append(createdDirs, created...)
+		if err != nil {
+			return rollback(fmt.Errorf("failed to create directory %s: %w", dir, err))
+		}
+	}
+
+	for _, move := range plan.Moves {
+		if err := ctx.Err(); err != nil {
+			return rollback(err)
+		}
+		if _, err := os.Stat(move.To); err == nil {
+			return rollback(fmt.Errorf("destination already exists: %s", move.To))
+		}
+		if err := os.Rename(move.From, move.To); err != nil {
+			return rollback(fmt.Errorf("failed to move %s to %s: %w", move.From, move.To, err))
+		}
+		completed = append(completed, move)
+	}
+
+	return nil
+}
+
+// mkdirAllTracked behaves like os.MkdirAll but returns every directory it
+// created, parents first, so a rollback can remove intermediate parents too
+func mkdirAllTracked(dir string) ([]string, error) {
+	var missing []string
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		if _, err := os.Stat(current); err == nil {
+			break
+		}
+		missing = append(missing, current)
+		if parent := filepath.Dir(current); parent == current {
+			break
+		}
+	}
+
+	created := make([]string, 0, len(missing))
+	for i := len(missing) - 1; i >= 0; i-- {
+		if err := os.Mkdir(missing[i], 0o755); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return created, err
+		}
+		created = append(created, missing[i])
+	}
+	return created, nil
+}
@@ -2,19 +2,56 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"sync"
 
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/filesystem"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness"
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/models"
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/trees"
 )
 
+const (
+	// defaultIngestBatchSize matches the memory system's default ingest batch size
+	defaultIngestBatchSize = 32
+	// defaultSummaryConcurrency bounds concurrent summary generation in AnalyzeFiles
+	defaultSummaryConcurrency = 4
+)
+
 // Service provides AI capabilities integrated with File4You's filesystem
 type Service struct {
 	modelManager *models.ModelManager
+	models       analysisModels
 	filesystem   *filesystem.FileSystem
+	similarIndex SimilarFileIndex
+
+	ingestBatchSize    int
+	summaryConcurrency int
+}
+
+// analysisModels is the subset of the model manager used by file analysis
+type analysisModels interface {
+	GenerateText(ctx context.Context, prompt string, options ...interface{}) (string, error)
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// SimilarFileIndex is the vector store queried by FindSimilarFiles
+type SimilarFileIndex interface {
+	// SearchSimilarFiles returns up to limit files nearest to embedding
+	SearchSimilarFiles(ctx context.Context, embedding []float32, limit int) ([]SimilarFile, error)
+}
+
+// SimilarFile is a file matched by similarity search
+type SimilarFile struct {
+	FileNode   *trees.FileNode `json:"file"`
+	Similarity float64         `json:"similarity"` // cosine similarity, 1 is identical
 }
 
 // Config holds configuration for the AI service
@@ -23,6 +60,10 @@ type Config struct {
 	EnableContentAnalysis  bool
 	EnableSemanticSearch   bool
 	EnableAutoOrganization bool
+
+	// Batch analysis settings (zero selects the defaults)
+	IngestBatchSize    int // files per batched embedding call
+	SummaryConcurrency int // concurrent summary generations
 }
 
 // NewService creates a new AI service
@@ -36,15 +77,60 @@ func NewService(fs *filesystem.FileSystem, config *Config) (*Service, error) {
 		}
 	}
 
-	modelManager, err := models.NewModelManager(config.ModelManagerConfig)
+	managerConfig := config.ModelManagerConfig
+	if managerConfig == nil {
+		managerConfig = models.DefaultModelManagerConfig()
+	}
+
+	// Circuit breaker policy comes from the application config unless set explicitly
+	if managerConfig.BreakerThreshold == 0 {
+		managerConfig.BreakerThreshold = appconfig.AppConfig.LLM.BreakerThreshold
+	}
+	if managerConfig.BreakerCooldown == 0 {
+		managerConfig.BreakerCooldown = appconfig.AppConfig.LLM.BreakerCooldown
+	}
+
+	// Likewise the per-call request timeouts
+	if managerConfig.GenerateTimeout == 0 {
+		managerConfig.GenerateTimeout = appconfig.AppConfig.LLM.GenerateTimeout
+	}
+	if managerConfig.EmbedTimeout == 0 {
+		managerConfig.EmbedTimeout = appconfig.AppConfig.LLM.EmbedTimeout
+	}
+
+	// And the sampling settings for generation
+	if managerConfig.MinP == 0 {
+		managerConfig.MinP = appconfig.AppConfig.LLM.MinP
+	}
+	if managerConfig.RepetitionPenalty == 0 {
+		managerConfig.RepetitionPenalty = appconfig.AppConfig.LLM.RepetitionPenalty
+	}
+
+	modelManager, err := models.NewModelManager(managerConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model manager: %w", err)
 	}
 
-	return &Service{
-		modelManager: modelManager,
-		filesystem:   fs,
-	}, nil
+	service := &Service{
+		modelManager:       modelManager,
+		models:             modelManager,
+		filesystem:         fs,
+		ingestBatchSize:    config.IngestBatchSize,
+		summaryConcurrency: config.SummaryConcurrency,
+	}
+	if service.ingestBatchSize <= 0 {
+		service.ingestBatchSize = defaultIngestBatchSize
+	}
+	if service.summaryConcurrency <= 0 {
+		service.summaryConcurrency = defaultSummaryConcurrency
+	}
+
+	return service, nil
+}
+
+// SetSimilarFileIndex sets the vector index used to find similar files
+func (s *Service) SetSimilarFileIndex(index SimilarFileIndex) {
+	s.similarIndex = index
 }
 
 // AnalyzeFileContent analyzes file content using AI models
@@ -55,11 +141,100 @@ func (s *Service) AnalyzeFileContent(ctx context.Context, fileNode *trees.FileNo
 	}
 
 	// Generate embedding for the file
-	embedding, err := s.modelManager.GenerateEmbedding(ctx, s.generateFileRepresentation(fileNode))
+	embedding, err := s.models.GenerateEmbedding(ctx, s.generateFileRepresentation(fileNode))
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
 
+	return s.buildAnalysis(ctx, fileNode, embedding), nil
+}
+
+// AnalyzeFiles analyzes files with batched embedding calls and concurrent summaries.
+// Results keep the input order; a file that fails leaves a nil entry and its error is
+// joined into the returned error without aborting the rest of the batch.
+func (s *Service) AnalyzeFiles(ctx context.Context, files []*trees.FileNode) ([]*FileAnalysis, error) {
+	analyses := make([]*FileAnalysis, len(files))
+	fileErrs := make([]error, len(files))
+
+	// Build representations for every valid file
+	indexes := make([]int, 0, len(files))
+	texts := make([]string, 0, len(files))
+	for i, fileNode := range files {
+		if fileNode == nil {
+			fileErrs[i] = fmt.Errorf("file %d: file node cannot be nil", i)
+			continue
+		}
+		indexes = append(indexes, i)
+		texts = append(texts, s.generateFileRepresentation(fileNode))
+	}
+
+	// Embed in batches; a failing file only fails itself
+	embeddings := make([][]float32, len(files))
+	for start := 0; start < len(texts); start += s.ingestBatchSize {
+		end := min(start+s.ingestBatchSize, len(texts))
+		batch, errs := s.embedBatch(ctx, texts[start:end])
+		for j := range batch {
+			i := indexes[start+j]
+			if errs[j] != nil {
+				fileErrs[i] = fmt.Errorf("%s: failed to generate embedding: %w", files[i].Path, errs[j])
+				continue
+			}
+			embeddings[i] = batch[j]
+		}
+	}
+
+	// Summarize embedded files with bounded concurrency
+	sem := make(chan struct{}, s.summaryConcurrency)
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		if fileErrs[i] != nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			analyses[i] = s.buildAnalysis(ctx, files[i], embeddings[i])
+		}(i)
+	}
+	wg.Wait()
+
+	return analyses, errors.Join(fileErrs...)
+}
+
+// embedBatch embeds texts in one call, retrying text by text when the batch fails so a
+// single bad input only fails itself. The results and errors align with texts
+func (s *Service) embedBatch(ctx context.Context, texts []string) ([][]float32, []error) {
+	embeddings := make([][]float32, len(texts))
+	errs := make([]error, len(texts))
+
+	batch, err := s.models.GenerateEmbeddings(ctx, texts)
+	if err == nil && len(batch) != len(texts) {
+		err = fmt.Errorf("expected %d embeddings, got %d", len(texts), len(batch))
+	}
+	if err == nil {
+		copy(embeddings, batch)
+		return embeddings, errs
+	}
+
+	for j := range texts {
+		single, err := s.models.GenerateEmbeddings(ctx, texts[j:j+1])
+		if err == nil && len(single) != 1 {
+			err = fmt.Errorf("expected 1 embedding, got %d", len(single))
+		}
+		if err != nil {
+			errs[j] = err
+			continue
+		}
+		embeddings[j] = single[0]
+	}
+	return embeddings, errs
+}
+
+// buildAnalysis summarizes an embedded file and extracts its key information
+func (s *Service) buildAnalysis(ctx context.Context, fileNode *trees.FileNode, embedding []float32) *FileAnalysis {
 	// Generate content summary using chat model
 	summary, err := s.generateContentSummary(ctx, fileNode)
 	if err != nil {
@@ -67,8 +242,7 @@ func (s *Service) AnalyzeFileContent(ctx context.Context, fileNode *trees.FileNo
 		summary = "Content analysis unavailable"
 	}
 
-	// Extract key information
-	analysis := &FileAnalysis{
+	return &FileAnalysis{
 		FileNode:    fileNode,
 		Embedding:   embedding,
 		Summary:     summary,
@@ -76,8 +250,6 @@ func (s *Service) AnalyzeFileContent(ctx context.Context, fileNode *trees.FileNo
 		Keywords:    s.extractKeywords(fileNode),
 		Metadata:    s.extractMetadata(fileNode),
 	}
-
-	return analysis, nil
 }
 
 // generateFileRepresentation creates a text representation for embedding
@@ -119,7 +291,7 @@ Content:
 
 Summary (2-3 sentences):`, fileNode.Name, s.detectContentType(fileNode), content)
 
-	summary, err := s.modelManager.GenerateText(ctx, prompt)
+	summary, err := s.models.GenerateText(ctx, prompt)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate content summary: %w", err)
 	}
@@ -220,6 +392,22 @@ func (s *Service) extractEXIFData(filePath string) string {
 
 // FindSimilarFiles finds files similar to the given file using embeddings
 func (s *Service) FindSimilarFiles(ctx context.Context, fileNode *trees.FileNode, limit int) ([]*trees.FileNode, error) {
+	matches, err := s.FindSimilarFilesWithScores(ctx, fileNode, limit, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	similarFiles := make([]*trees.FileNode, len(matches))
+	for i, match := range matches {
+		similarFiles[i] = match.FileNode
+	}
+	return similarFiles, nil
+}
+
+// FindSimilarFilesWithScores finds files similar to the given file, dropping matches whose
+// similarity is below minSimilarity. Results are sorted by descending similarity and may be
+// fewer than limit once weak matches are filtered out.
+func (s *Service) FindSimilarFilesWithScores(ctx context.Context, fileNode *trees.FileNode, limit int, minSimilarity float64) ([]SimilarFile, error) {
 	if fileNode == nil {
 		return nil, fmt.Errorf("file node cannot be nil")
 	}
@@ -228,33 +416,56 @@ func (s *Service) FindSimilarFiles(ctx context.Context, fileNode *trees.FileNode
 		return nil, fmt.Errorf("limit must be positive, got %d", limit)
 	}
 
+	if !(minSimilarity >= 0 && minSimilarity <= 1) {
+		return nil, fmt.Errorf("minimum similarity must be within [0, 1], got %v", minSimilarity)
+	}
+
 	// Generate embedding for the target file
-	_, err := s.modelManager.GenerateEmbedding(ctx, s.generateFileRepresentation(fileNode))
+	embedding, err := s.models.GenerateEmbedding(ctx, s.generateFileRepresentation(fileNode))
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate target embedding: %w", err)
 	}
 
-	// Search for similar files using the vector database
-	// This would integrate with the existing vector search
-	similarFiles := make([]*trees.FileNode, 0, limit)
+	if s.similarIndex == nil {
+		log.Printf("No similar file index configured, skipping search for %s", fileNode.Path)
+		return []SimilarFile{}, nil
+	}
 
-	// For now, return empty slice - integration would be with the database layer
-	log.Printf("Would search for %d files similar to %s", limit, fileNode.Path)
+	// Search for similar files using the vector index
+	candidates, err := s.similarIndex.SearchSimilarFiles(ctx, embedding, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search similar files: %w", err)
+	}
+
+	similarFiles := make([]SimilarFile, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.FileNode == nil || candidate.Similarity < minSimilarity {
+			continue
+		}
+		similarFiles = append(similarFiles, candidate)
+	}
+	sort.SliceStable(similarFiles, func(i, j int) bool {
+		return similarFiles[i].Similarity > similarFiles[j].Similarity
+	})
+	if len(similarFiles) > limit {
+		similarFiles = similarFiles[:limit]
+	}
 
 	return similarFiles, nil
 }
 
 // SuggestOrganization suggests how to organize files using AI
 func (s *Service) SuggestOrganization(ctx context.Context, files []*trees.FileNode) (*OrganizationSuggestion, error) {
-	// Analyze all files
-	analyses := make([]*FileAnalysis, len(files))
-	for i, file := range files {
-		analysis, err := s.AnalyzeFileContent(ctx, file)
-		if err != nil {
-			log.Printf("Warning: Failed to analyze file %s: %v", file.Path, err)
-			continue
+	// Analyze all files, skipping any that fail
+	results, err := s.AnalyzeFiles(ctx, files)
+	if err != nil {
+		log.Printf("Warning: Failed to analyze some files: %v", err)
+	}
+	analyses := make([]*FileAnalysis, 0, len(results))
+	for _, analysis := range results {
+		if analysis != nil {
+			analyses = append(analyses, analysis)
 		}
-		analyses[i] = analysis
 	}
 
 	// Create organization prompt
@@ -303,9 +514,26 @@ Output in structured format:
 	return prompt
 }
 
-// parseOrganizationSuggestions parses AI-generated organization suggestions
+// organizationSuggestionPayload is the structured format requested by createOrganizationPrompt
+type organizationSuggestionPayload struct {
+	FolderStructure   []string            `json:"folder_structure"`
+	FileGroups        map[string][]string `json:"file_groups"`
+	NamingConventions string              `json:"naming_conventions"`
+	SpecialHandling   string              `json:"special_handling"`
+}
+
+// parseOrganizationSuggestions parses AI-generated organization suggestions, preferring the
+// structured JSON payload and falling back to one suggestion per line of prose
 func (s *Service) parseOrganizationSuggestions(suggestion string) []string {
-	// Simple parsing - in production, this would parse structured JSON
+	if data, ok := harness.ExtractJSON(suggestion); ok {
+		var payload organizationSuggestionPayload
+		if err := json.Unmarshal(data, &payload); err == nil {
+			if suggestions := payload.suggestions(); len(suggestions) > 0 {
+				return suggestions
+			}
+		}
+	}
+
 	lines := strings.Split(suggestion, "\n")
 	suggestions := make([]string, 0, len(lines))
 
@@ -319,6 +547,35 @@ func (s *Service) parseOrganizationSuggestions(suggestion string) []string {
 	return suggestions
 }
 
+// suggestions flattens the payload into readable suggestions with groups in sorted order
+func (p organizationSuggestionPayload) suggestions() []string {
+	var suggestions []string
+	for _, folder := range p.FolderStructure {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			suggestions = append(suggestions, "Create folder: "+folder)
+		}
+	}
+
+	groups := make([]string, 0, len(p.FileGroups))
+	for group := range p.FileGroups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		if files := p.FileGroups[group]; len(files) > 0 {
+			suggestions = append(suggestions, fmt.Sprintf("Group into %s: %s", group, strings.Join(files, ", ")))
+		}
+	}
+
+	if naming := strings.TrimSpace(p.NamingConventions); naming != "" {
+		suggestions = append(suggestions, "Naming: "+naming)
+	}
+	if special := strings.TrimSpace(p.SpecialHandling); special != "" {
+		suggestions = append(suggestions, "Special handling: "+special)
+	}
+	return suggestions
+}
+
 // FileAnalysis represents AI analysis of a file
 type FileAnalysis struct {
 	FileNode    *trees.FileNode        `json:"file"`
@@ -336,6 +593,11 @@ type OrganizationSuggestion struct {
 	Suggestions []string `json:"suggestions"`
 }
 
+// AnalyzeImage captions and tags the image at imagePath with the vision model
+func (s *Service) AnalyzeImage(ctx context.Context, imagePath string) (*models.ImageAnalysis, error) {
+	return s.modelManager.AnalyzeImage(ctx, imagePath)
+}
+
 // GetHealthSummary returns health status of all providers
 func (s *Service) GetHealthSummary() map[string]*models.ModelHealth {
 	return s.modelManager.GetHealthSummary()
@@ -353,10 +615,13 @@ func (s *Service) Close() error {
 
 // ModelManagerInterface defines the interface for model management
 type ModelManagerInterface interface {
-	GenerateText(ctx context.Context, prompt string) (string, error)
+	GenerateText(ctx context.Context, prompt string, options ...interface{}) (string, error)
 	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
 	AnalyzeImage(ctx context.Context, imagePath string) (*models.ImageAnalysis, error)
 	GetHealthSummary() map[string]*models.ModelHealth
 	GetModelInfo() map[string]interface{}
 	Close() error
 }
+
+// Ensure ModelManager implements ModelManagerInterface
+var _ ModelManagerInterface = (*models.ModelManager)(nil)
@@ -1,9 +1,15 @@
 package ai
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -246,3 +252,338 @@ func TestModelHealth(t *testing.T) {
 	modelInfo := aiService.modelManager.GetModelInfo()
 	assert.NotNil(t, modelInfo)
 }
+
+// stubVisionAnalyzer stands in for the vision provider
+type stubVisionAnalyzer struct{}
+
+func (stubVisionAnalyzer) AnalyzeImage(ctx context.Context, imageData []byte) (*models.ImageAnalysis, error) {
+	return &models.ImageAnalysis{
+		Caption: "a red square",
+		Tags:    []string{"red", "square"},
+		Objects: []models.DetectedObject{{Label: "square", Confidence: 0.95, Bounds: image.Rect(0, 0, 16, 16)}},
+	}, nil
+}
+
+func TestAnalyzeImage(t *testing.T) {
+	aiService, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	// The model manager satisfies the interface, including the typed AnalyzeImage
+	var manager ModelManagerInterface = aiService.modelManager
+	assert.NotNil(t, manager)
+
+	var encoded bytes.Buffer
+	require.NoError(t, png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 16, 16))))
+	imagePath := filepath.Join(t.TempDir(), "square.png")
+	require.NoError(t, os.WriteFile(imagePath, encoded.Bytes(), 0644))
+
+	aiService.modelManager.SetImageAnalyzer(stubVisionAnalyzer{})
+	analysis, err := aiService.AnalyzeImage(context.Background(), imagePath)
+	require.NoError(t, err)
+	assert.Equal(t, "a red square", analysis.Caption)
+	assert.Equal(t, []string{"red", "square"}, analysis.Tags)
+	require.Len(t, analysis.Objects, 1)
+	assert.Equal(t, "square", analysis.Objects[0].Label)
+	assert.Equal(t, 16, analysis.Width)
+	assert.Equal(t, 16, analysis.Height)
+	assert.Equal(t, "png", analysis.Format)
+}
+
+func TestPlanOrganization(t *testing.T) {
+	aiService, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	files := []*trees.FileNode{
+		{Path: filepath.Join(root, "report.txt"), Name: "report.txt", Extension: ".txt"},
+		{Path: filepath.Join(root, "photo.jpg"), Name: "photo.jpg", Extension: ".jpg"},
+		{Path: filepath.Join(root, "main.go"), Name: "main.go", Extension: ".go"},
+	}
+	for _, f := range files {
+		require.NoError(t, os.WriteFile(f.Path, []byte(f.Name), 0644))
+	}
+
+	plan, err := aiService.PlanOrganization(ctx, files)
+	require.NoError(t, err)
+	require.Len(t, plan.Moves, len(files))
+
+	targets := make(map[string]bool)
+	for _, move := range plan.Moves {
+		assert.NotEmpty(t, move.From)
+		assert.NotEmpty(t, move.To)
+		assert.NotEqual(t, move.From, move.To)
+		assert.False(t, targets[move.To], "duplicate destination %s", move.To)
+		targets[move.To] = true
+		assert.Contains(t, plan.Directories, filepath.Dir(move.To))
+	}
+
+	// Planning must not touch the filesystem
+	for _, dir := range plan.Directories {
+		_, err := os.Stat(dir)
+		assert.True(t, os.IsNotExist(err))
+	}
+}
+
+func TestApplyPlanDryRun(t *testing.T) {
+	aiService, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	root := t.TempDir()
+	src := filepath.Join(root, "notes.md")
+	require.NoError(t, os.WriteFile(src, []byte("# notes"), 0644))
+
+	plan, err := aiService.PlanOrganization(ctx, []*trees.FileNode{
+		{Path: src, Name: "notes.md", Extension: ".md"},
+	})
+	require.NoError(t, err)
+	require.Len(t, plan.Moves, 1)
+
+	require.NoError(t, aiService.ApplyPlan(ctx, plan, true))
+
+	_, err = os.Stat(src)
+	assert.NoError(t, err)
+	_, err = os.Stat(plan.Moves[0].To)
+	assert.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Applying for real moves the file
+	require.NoError(t, aiService.ApplyPlan(ctx, plan, false))
+	_, err = os.Stat(plan.Moves[0].To)
+	assert.NoError(t, err)
+}
+
+func TestApplyPlanRollbackRemovesNestedDirectories(t *testing.T) {
+	aiService := &Service{}
+
+	ctx := context.Background()
+	root := t.TempDir()
+	src := filepath.Join(root, "notes.md")
+	require.NoError(t, os.WriteFile(src, []byte("# notes"), 0644))
+
+	// Only the leaf is listed; its parents are created implicitly
+	target := filepath.Join(root, "archive", "2024", "notes")
+	plan := &OrganizationPlan{
+		Directories: []string{target},
+		Moves: []Move{
+			{From: src, To: filepath.Join(target, "notes.md")},
+			{From: filepath.Join(root, "missing.md"), To: filepath.Join(target, "missing.md")},
+		},
+	}
+
+	err := aiService.ApplyPlan(ctx, plan, false)
+	require.Error(t, err)
+
+	// The completed move is reverted
+	_, err = os.Stat(src)
+	assert.NoError(t, err)
+
+	// Every directory the plan created is gone, not just the leaf
+	_, err = os.Stat(filepath.Join(root, "archive"))
+	assert.True(t, os.IsNotExist(err))
+	entries, err := os.ReadDir(root)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// fakeAnalysisModels embeds deterministically and fails any text mentioning "corrupt"
+type fakeAnalysisModels struct {
+	mu         sync.Mutex
+	batchSizes []int
+	onBatch    func() // called after each GenerateEmbeddings call is recorded
+}
+
+func (f *fakeAnalysisModels) GenerateText(ctx context.Context, prompt string, options ...interface{}) (string, error) {
+	return "summary", nil
+}
+
+func (f *fakeAnalysisModels) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := f.GenerateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (f *fakeAnalysisModels) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	f.mu.Lock()
+	f.batchSizes = append(f.batchSizes, len(texts))
+	f.mu.Unlock()
+	if f.onBatch != nil {
+		f.onBatch()
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.Contains(text, "corrupt") {
+			return nil, fmt.Errorf("cannot embed %q", text)
+		}
+		embeddings[i] = []float32{float32(len(text))}
+	}
+	return embeddings, nil
+}
+
+func TestAnalyzeFiles(t *testing.T) {
+	fake := &fakeAnalysisModels{}
+	aiService := &Service{models: fake, ingestBatchSize: 2, summaryConcurrency: 2}
+
+	names := []string{"a.txt", "b.md", "corrupt.go", "d.json", "e.png"}
+	files := make([]*trees.FileNode, len(names))
+	for i, name := range names {
+		files[i] = &trees.FileNode{Path: "workspace/" + name, Name: name, Extension: filepath.Ext(name)}
+	}
+
+	analyses, err := aiService.AnalyzeFiles(context.Background(), files)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "workspace/corrupt.go")
+	require.Len(t, analyses, len(files))
+
+	for i, analysis := range analyses {
+		if names[i] == "corrupt.go" {
+			assert.Nil(t, analysis)
+			continue
+		}
+		require.NotNil(t, analysis, names[i])
+		assert.Same(t, files[i], analysis.FileNode)
+		assert.NotEmpty(t, analysis.Embedding)
+		assert.Equal(t, "summary", analysis.Summary)
+	}
+
+	// Two full batches, the failing batch retried per file, then the tail batch
+	assert.Equal(t, []int{2, 2, 1, 1, 1}, fake.batchSizes)
+}
+
+// writeIndexTree creates n small files spread over nested directories under a temp root
+func writeIndexTree(t *testing.T, n int) string {
+	root := t.TempDir()
+	for i := 0; i < n; i++ {
+		path := filepath.Join(root, fmt.Sprintf("dir%d", i%3), fmt.Sprintf("file%d.txt", i))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf("contents %d", i)), 0644))
+	}
+	return root
+}
+
+func TestIndexTreeStream(t *testing.T) {
+	root := writeIndexTree(t, 7)
+	fake := &fakeAnalysisModels{}
+	aiService := &Service{models: fake, ingestBatchSize: 3}
+
+	progress, errc := aiService.IndexTreeStream(context.Background(), root)
+
+	var events []IndexProgress
+	for event := range progress {
+		events = append(events, event)
+	}
+	require.NoError(t, <-errc)
+
+	require.Len(t, events, 7)
+	paths := make(map[string]bool)
+	for i, event := range events {
+		assert.NoError(t, event.Err)
+		assert.NotEmpty(t, event.Embedding)
+		assert.Equal(t, i+1, event.FilesDone)
+		paths[event.Path] = true
+	}
+	assert.Len(t, paths, 7)
+	assert.Equal(t, 7, events[len(events)-1].FilesSeen)
+	assert.Equal(t, []int{3, 3, 1}, fake.batchSizes)
+}
+
+func TestIndexTreeStream_CancelStopsWalk(t *testing.T) {
+	root := writeIndexTree(t, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel while the first batch is being embedded
+	fake := &fakeAnalysisModels{onBatch: cancel}
+	aiService := &Service{models: fake, ingestBatchSize: 2}
+
+	progress, errc := aiService.IndexTreeStream(ctx, root)
+
+	var events []IndexProgress
+	for event := range progress {
+		events = append(events, event)
+	}
+	err := <-errc
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Empty(t, events)
+	assert.Equal(t, []int{2}, fake.batchSizes)
+}
+
+// fakeSimilarIndex returns canned matches in the order given, ignoring the query
+type fakeSimilarIndex struct {
+	matches []SimilarFile
+	limit   int
+}
+
+func (f *fakeSimilarIndex) SearchSimilarFiles(ctx context.Context, embedding []float32, limit int) ([]SimilarFile, error) {
+	f.limit = limit
+	return f.matches, nil
+}
+
+func TestFindSimilarFilesWithScores(t *testing.T) {
+	file := func(name string) *trees.FileNode {
+		return &trees.FileNode{Path: "workspace/" + name, Name: name}
+	}
+	index := &fakeSimilarIndex{matches: []SimilarFile{
+		{FileNode: file("weak.txt"), Similarity: 0.31},
+		{FileNode: file("close.txt"), Similarity: 0.82},
+		{FileNode: file("exact.txt"), Similarity: 0.97},
+		{FileNode: file("edge.txt"), Similarity: 0.5},
+		{FileNode: file("noise.txt"), Similarity: 0.12},
+	}}
+	aiService := &Service{models: &fakeAnalysisModels{}}
+	aiService.SetSimilarFileIndex(index)
+	ctx := context.Background()
+	target := file("report.txt")
+
+	matches, err := aiService.FindSimilarFilesWithScores(ctx, target, 5, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 5, index.limit)
+
+	var names []string
+	for _, match := range matches {
+		assert.GreaterOrEqual(t, match.Similarity, 0.5)
+		names = append(names, match.FileNode.Name)
+	}
+	assert.Equal(t, []string{"exact.txt", "close.txt", "edge.txt"}, names)
+	assert.Equal(t, 0.97, matches[0].Similarity)
+
+	// The plain variant keeps every match, most similar first
+	nodes, err := aiService.FindSimilarFiles(ctx, target, 2)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "exact.txt", nodes[0].Name)
+	assert.Equal(t, "close.txt", nodes[1].Name)
+
+	for _, threshold := range []float64{-0.1, 1.5} {
+		_, err := aiService.FindSimilarFilesWithScores(ctx, target, 5, threshold)
+		assert.Error(t, err, "threshold %v", threshold)
+	}
+}
+
+func TestParseOrganizationSuggestions(t *testing.T) {
+	aiService := &Service{}
+
+	structured := "Here is my plan:\n```json\n" + `{
+  "folder_structure": ["Documents/", "Images/"],
+  "file_groups": {"Images": ["a.png"], "Documents": ["notes {draft}.txt"]},
+  "naming_conventions": "Use dates",
+  "special_handling": ""
+}` + "\n```\nHope this helps."
+	assert.Equal(t, []string{
+		"Create folder: Documents/",
+		"Create folder: Images/",
+		"Group into Documents: notes {draft}.txt",
+		"Group into Images: a.png",
+		"Naming: Use dates",
+	}, aiService.parseOrganizationSuggestions(structured))
+
+	prose := "Move photos into Images\n\nKeep reports together"
+	assert.Equal(t, []string{"Move photos into Images", "Keep reports together"}, aiService.parseOrganizationSuggestions(prose))
+}
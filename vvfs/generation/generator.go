@@ -2,6 +2,7 @@ package generation
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/spf13/viper"
 )
@@ -32,13 +33,25 @@ type GenerationRequest struct {
 	MinP              float32   `json:"min_p"`              // Minimum probability
 	RepetitionPenalty float32   `json:"repetition_penalty"` // Repetition penalty
 	Stream            bool      `json:"stream"`             // Whether to stream the response
+
+	ResponseFormat ResponseFormat  `json:"response_format,omitempty"` // Output format; empty means text
+	JSONSchema     json.RawMessage `json:"json_schema,omitempty"`     // Schema the output must satisfy when ResponseFormat is json_schema
 }
 
+// ResponseFormat selects the shape of the generated output
+type ResponseFormat string
+
+const (
+	ResponseFormatText       ResponseFormat = "text"        // Free-form text
+	ResponseFormatJSONSchema ResponseFormat = "json_schema" // JSON validated against GenerationRequest.JSONSchema
+)
+
 // GenerationResponse represents the response from text generation
 type GenerationResponse struct {
 	Text     string    `json:"text"`            // Generated text
 	Messages []Message `json:"messages"`        // Full conversation including generation
 	Usage    *Usage    `json:"usage,omitempty"` // Token usage information
+	Err      error     `json:"-"`               // Set on a streamed response when generation failed
 }
 
 // Usage represents token usage statistics
@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// LibSQLAuditSink implements AuditSink using a LibSQL tool_invocations table.
+type LibSQLAuditSink struct {
+	db *sql.DB
+}
+
+// NewLibSQLAuditSink returns a sink writing to the tool_invocations table, which is
+// created by the memory database migrations. It fails if the table is missing.
+func NewLibSQLAuditSink(ctx context.Context, db *sql.DB) (*LibSQLAuditSink, error) {
+	var name string
+	err := db.QueryRowContext(ctx,
+		"SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'tool_invocations'").Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("tool_invocations table not found; run the memory database migrations")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tool_invocations table: %w", err)
+	}
+	return &LibSQLAuditSink{db: db}, nil
+}
+
+// RecordToolInvocation inserts one audit record.
+func (s *LibSQLAuditSink) RecordToolInvocation(ctx context.Context, invocation ports.ToolInvocation) error {
+	query := `
+		INSERT INTO tool_invocations (id, conversation_id, tool, args, result, result_hash, error, started_at, duration_ns)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := s.db.ExecContext(ctx, query,
+		invocation.ID,
+		invocation.ConversationID,
+		invocation.Tool,
+		invocation.Args,
+		invocation.Result,
+		invocation.ResultHash,
+		invocation.Error,
+		invocation.StartedAt.UnixNano(),
+		int64(invocation.Duration),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record tool invocation: %w", err)
+	}
+
+	return nil
+}
+
+// ListToolInvocations returns the records matching filter, newest first.
+func (s *LibSQLAuditSink) ListToolInvocations(ctx context.Context, filter ports.AuditFilter) ([]ports.ToolInvocation, error) {
+	var where []string
+	var args []any
+	if filter.ConversationID != "" {
+		where = append(where, "conversation_id = ?")
+		args = append(args, filter.ConversationID)
+	}
+	if filter.Tool != "" {
+		where = append(where, "tool = ?")
+		args = append(args, filter.Tool)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "started_at < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+
+	query := `
+		SELECT id, conversation_id, tool, args, result, result_hash, error, started_at, duration_ns
+		FROM tool_invocations
+	`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tool invocations: %w", err)
+	}
+	defer rows.Close()
+
+	var invocations []ports.ToolInvocation
+	for rows.Next() {
+		var inv ports.ToolInvocation
+		var startedAt, duration int64
+		if err := rows.Scan(&inv.ID, &inv.ConversationID, &inv.Tool, &inv.Args, &inv.Result,
+			&inv.ResultHash, &inv.Error, &startedAt, &duration); err != nil {
+			return nil, fmt.Errorf("failed to scan tool invocation: %w", err)
+		}
+		inv.StartedAt = time.Unix(0, startedAt)
+		inv.Duration = time.Duration(duration)
+		invocations = append(invocations, inv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tool invocations: %w", err)
+	}
+	return invocations, nil
+}
+
+// Ensure LibSQLAuditSink implements AuditSink.
+var _ ports.AuditSink = (*LibSQLAuditSink)(nil)
@@ -0,0 +1,82 @@
+package adapters
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "github.com/tursodatabase/go-libsql"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// openMigratedDB opens a libsql database with the memory migrations applied.
+func openMigratedDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("libsql", "file:"+filepath.Join(t.TempDir(), "audit.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectTurso, db, os.DirFS("../../../memory/migrations"))
+	require.NoError(t, err)
+	_, err = provider.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func TestNewLibSQLAuditSink_RequiresMigration(t *testing.T) {
+	db, err := sql.Open("libsql", "file:"+filepath.Join(t.TempDir(), "empty.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewLibSQLAuditSink(context.Background(), db)
+	assert.ErrorContains(t, err, "tool_invocations table not found")
+}
+
+func TestLibSQLAuditSink_RecordAndList(t *testing.T) {
+	ctx := context.Background()
+	sink, err := NewLibSQLAuditSink(ctx, openMigratedDB(t))
+	require.NoError(t, err)
+
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []ports.ToolInvocation{
+		{ID: "1", ConversationID: "conv-a", Tool: "fs_read", Args: `{"path":"a.txt"}`, Result: "hello", ResultHash: "h1", StartedAt: base, Duration: 5 * time.Millisecond},
+		{ID: "2", ConversationID: "conv-a", Tool: "fs_search", Args: `{}`, ResultHash: "h2", Error: "boom", StartedAt: base.Add(time.Minute), Duration: time.Second},
+		{ID: "3", ConversationID: "conv-b", Tool: "fs_read", Args: `{}`, ResultHash: "h3", StartedAt: base.Add(2 * time.Minute)},
+	}
+	for _, record := range records {
+		require.NoError(t, sink.RecordToolInvocation(ctx, record))
+	}
+
+	ids := func(filter ports.AuditFilter) []string {
+		invocations, err := sink.ListToolInvocations(ctx, filter)
+		require.NoError(t, err)
+		out := make([]string, len(invocations))
+		for i, inv := range invocations {
+			out[i] = inv.ID
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"3", "2", "1"}, ids(ports.AuditFilter{}), "newest first")
+	assert.Equal(t, []string{"2", "1"}, ids(ports.AuditFilter{ConversationID: "conv-a"}))
+	assert.Equal(t, []string{"3", "1"}, ids(ports.AuditFilter{Tool: "fs_read"}))
+	assert.Equal(t, []string{"1"}, ids(ports.AuditFilter{ConversationID: "conv-a", Tool: "fs_read"}))
+	assert.Equal(t, []string{"2"}, ids(ports.AuditFilter{Since: base.Add(time.Minute), Until: base.Add(2 * time.Minute)}))
+	assert.Equal(t, []string{"3"}, ids(ports.AuditFilter{Limit: 1}))
+	assert.Empty(t, ids(ports.AuditFilter{Tool: "missing"}))
+
+	// Every field round-trips
+	invocations, err := sink.ListToolInvocations(ctx, ports.AuditFilter{ConversationID: "conv-a", Tool: "fs_search"})
+	require.NoError(t, err)
+	require.Len(t, invocations, 1)
+	got := invocations[0]
+	assert.True(t, records[1].StartedAt.Equal(got.StartedAt))
+	got.StartedAt = records[1].StartedAt
+	assert.Equal(t, records[1], got)
+}
@@ -0,0 +1,130 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// NamedProvider pairs a provider with the name used in traces and config.
+type NamedProvider struct {
+	Name     string
+	Provider ports.Provider
+}
+
+// FallbackProvider implements the Provider interface over an ordered chain of providers,
+// moving to the next one when a call errors or exceeds the per-attempt timeout.
+type FallbackProvider struct {
+	providers []NamedProvider
+	timeout   time.Duration // per-attempt timeout for Complete; zero means none
+	tracer    ports.Tracer  // optional
+}
+
+// NewFallbackProvider creates a new fallback provider chain.
+func NewFallbackProvider(providers []NamedProvider, timeout time.Duration, tracer ports.Tracer) *FallbackProvider {
+	return &FallbackProvider{
+		providers: providers,
+		timeout:   timeout,
+		tracer:    tracer,
+	}
+}
+
+// Capabilities reports what every provider in the chain supports, since any of them may
+// serve a call, and the smallest known context window.
+func (f *FallbackProvider) Capabilities() ports.ProviderCapabilities {
+	if len(f.providers) == 0 {
+		return ports.ProviderCapabilities{}
+	}
+
+	caps := ports.ProviderCapabilities{NativeToolCalls: true, Streaming: true, JSONMode: true}
+	for _, p := range f.providers {
+		provided := ports.CapabilitiesOf(p.Provider)
+		caps.NativeToolCalls = caps.NativeToolCalls && provided.NativeToolCalls
+		caps.Streaming = caps.Streaming && provided.Streaming
+		caps.JSONMode = caps.JSONMode && provided.JSONMode
+		if provided.ContextWindow > 0 && (caps.ContextWindow == 0 || provided.ContextWindow < caps.ContextWindow) {
+			caps.ContextWindow = provided.ContextWindow
+		}
+	}
+	return caps
+}
+
+// Complete tries each provider in order and returns the first successful completion.
+func (f *FallbackProvider) Complete(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+	if len(f.providers) == 0 {
+		return ports.Completion{}, fmt.Errorf("no providers configured")
+	}
+
+	var errs []error
+	for i, p := range f.providers {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if f.timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, f.timeout)
+		}
+		completion, err := p.Provider.Complete(attemptCtx, in, opts)
+		cancel()
+
+		if err == nil {
+			f.event(ctx, "provider_selected", map[string]any{"provider": p.Name, "attempt": i + 1})
+			return completion, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		// The caller gave up; further attempts would fail the same way
+		if ctx.Err() != nil {
+			return ports.Completion{}, fmt.Errorf("provider call cancelled: %w", ctx.Err())
+		}
+		f.failover(ctx, i, err)
+	}
+
+	return ports.Completion{}, fmt.Errorf("all %d providers failed: %w", len(f.providers), errors.Join(errs...))
+}
+
+// Stream tries each provider in order until one opens a stream.
+// Failover only covers stream setup; errors after the first chunk are not retried.
+func (f *FallbackProvider) Stream(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+	if len(f.providers) == 0 {
+		return nil, fmt.Errorf("no providers configured")
+	}
+
+	var errs []error
+	for i, p := range f.providers {
+		ch, err := p.Provider.Stream(ctx, in, opts)
+		if err == nil {
+			f.event(ctx, "provider_selected", map[string]any{"provider": p.Name, "attempt": i + 1, "stream": true})
+			return ch, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("provider stream cancelled: %w", ctx.Err())
+		}
+		f.failover(ctx, i, err)
+	}
+
+	return nil, fmt.Errorf("all %d providers failed: %w", len(f.providers), errors.Join(errs...))
+}
+
+// failover records that provider i failed and which provider (if any) is tried next.
+func (f *FallbackProvider) failover(ctx context.Context, i int, err error) {
+	attrs := map[string]any{
+		"provider": f.providers[i].Name,
+		"error":    err.Error(),
+	}
+	if i+1 < len(f.providers) {
+		attrs["next_provider"] = f.providers[i+1].Name
+	}
+	f.event(ctx, "provider_failover", attrs)
+}
+
+func (f *FallbackProvider) event(ctx context.Context, name string, attrs map[string]any) {
+	if f.tracer != nil {
+		f.tracer.Event(ctx, name, attrs)
+	}
+}
+
+// Ensure FallbackProvider implements the Provider interface.
+var _ ports.Provider = (*FallbackProvider)(nil)
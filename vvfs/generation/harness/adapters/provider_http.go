@@ -0,0 +1,401 @@
+package adapters
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// HTTPProviderConfig configures an HTTPProvider.
+type HTTPProviderConfig struct {
+	// BaseURL is the API root, e.g. "http://localhost:8000/v1"; "/chat/completions" is appended.
+	BaseURL string
+	// Model is sent as the request's "model" field.
+	Model string
+	// APIKey is sent as a bearer token when set. Most local servers need none.
+	APIKey string
+	// Client performs the requests; defaults to a client without its own timeout.
+	Client *http.Client
+	// Timeout bounds each non-streaming request unless Options.TimeoutMs is set. Streams
+	// are bounded by the caller's context and Options.TimeoutMs only.
+	Timeout time.Duration
+	// ContextWindow is the served model's context size in tokens, reported through
+	// Capabilities. Zero leaves it unknown.
+	ContextWindow int
+}
+
+// HTTPProvider implements the Provider interface against an OpenAI-compatible
+// /chat/completions endpoint such as vLLM, Ollama or LM Studio.
+//
+// Tool results are sent as user messages, since PromptMessage does not carry the call IDs
+// that OpenAI "tool" messages require.
+type HTTPProvider struct {
+	endpoint string
+	model    string
+	apiKey   string
+	client   *http.Client
+	timeout  time.Duration
+	window   int
+}
+
+// NewHTTPProvider creates a provider for the endpoint described by cfg.
+func NewHTTPProvider(cfg HTTPProviderConfig) (*HTTPProvider, error) {
+	baseURL := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &HTTPProvider{
+		endpoint: baseURL + "/chat/completions",
+		model:    cfg.Model,
+		apiKey:   cfg.APIKey,
+		client:   client,
+		timeout:  cfg.Timeout,
+		window:   cfg.ContextWindow,
+	}, nil
+}
+
+// ToolSchemaFormat reports that tools are declared in the OpenAI "tools" array.
+func (p *HTTPProvider) ToolSchemaFormat() string { return "openai" }
+
+// Capabilities reports native tool calls, streaming and JSON mode, which OpenAI-compatible
+// servers provide, and the configured context window.
+func (p *HTTPProvider) Capabilities() ports.ProviderCapabilities {
+	return ports.ProviderCapabilities{NativeToolCalls: true, Streaming: true, JSONMode: true, ContextWindow: p.window}
+}
+
+// chatMessage is one entry of the request's "messages" array.
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the /chat/completions request body.
+type chatRequest struct {
+	Model             string          `json:"model,omitempty"`
+	Messages          []chatMessage   `json:"messages"`
+	Tools             json.RawMessage `json:"tools,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"`
+	MaxTokens         int             `json:"max_tokens,omitempty"`
+	Temperature       float32         `json:"temperature,omitempty"`
+	TopP              float32         `json:"top_p,omitempty"`
+	MinP              float32         `json:"min_p,omitempty"`
+	RepetitionPenalty float32         `json:"repetition_penalty,omitempty"`
+	Seed              int             `json:"seed,omitempty"`
+	Stop              []string        `json:"stop,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+	ResponseFormat    any             `json:"response_format,omitempty"`
+}
+
+// chatToolCall is a tool call in a response message or stream delta.
+type chatToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// chatUsage is the response's token accounting.
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// chatResponse is a /chat/completions response or, with Delta set, a stream event.
+type chatResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content   string         `json:"content"`
+			ToolCalls []chatToolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+}
+
+// Complete sends a non-streaming chat completion request.
+func (p *HTTPProvider) Complete(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+	ctx, cancel := p.callContext(ctx, opts, p.timeout)
+	defer cancel()
+
+	resp, err := p.post(ctx, in, opts, false)
+	if err != nil {
+		return ports.Completion{}, err
+	}
+	defer resp.Body.Close()
+
+	var decoded chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return ports.Completion{}, fmt.Errorf("failed to decode chat completion: %w", err)
+	}
+	if len(decoded.Choices) == 0 {
+		return ports.Completion{}, fmt.Errorf("chat completion has no choices")
+	}
+
+	message := decoded.Choices[0].Message
+	completion := ports.Completion{
+		Text:  message.Content,
+		Raw:   decoded,
+		Usage: convertUsage(decoded.Usage),
+	}
+	for _, call := range message.ToolCalls {
+		completion.ToolCalls = append(completion.ToolCalls, convertToolCall(call))
+	}
+	return completion, nil
+}
+
+// Stream sends a streaming chat completion request and relays its server-sent events.
+// Tool call fragments are assembled and delivered with the final chunk. The channel is
+// closed without a final chunk if the stream breaks off.
+func (p *HTTPProvider) Stream(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+	ctx, cancel := p.callContext(ctx, opts, 0)
+
+	resp, err := p.post(ctx, in, opts, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := make(chan ports.CompletionChunk, 16)
+	go func() {
+		defer close(ch)
+		defer cancel()
+		defer resp.Body.Close()
+
+		send := func(chunk ports.CompletionChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		calls := make(map[int]*chatToolCall)
+		var usage *ports.Usage
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue // blank separators, comments and other SSE fields
+			}
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				break
+			}
+
+			var event chatResponse
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return
+			}
+			if event.Usage != nil {
+				usage = convertUsage(event.Usage)
+			}
+			for _, choice := range event.Choices {
+				for _, fragment := range choice.Delta.ToolCalls {
+					call, ok := calls[fragment.Index]
+					if !ok {
+						call = &chatToolCall{Index: fragment.Index}
+						calls[fragment.Index] = call
+					}
+					call.Function.Name += fragment.Function.Name
+					call.Function.Arguments += fragment.Function.Arguments
+				}
+				if choice.Delta.Content != "" && !send(ports.CompletionChunk{DeltaText: choice.Delta.Content}) {
+					return
+				}
+			}
+		}
+		if scanner.Err() != nil {
+			return
+		}
+
+		final := ports.CompletionChunk{Done: true, Usage: usage}
+		indexes := make([]int, 0, len(calls))
+		for index := range calls {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		for _, index := range indexes {
+			final.ToolCalls = append(final.ToolCalls, convertToolCall(*calls[index]))
+		}
+		send(final)
+	}()
+	return ch, nil
+}
+
+// callContext applies Options.TimeoutMs, or fallback when it is unset.
+func (p *HTTPProvider) callContext(ctx context.Context, opts ports.Options, fallback time.Duration) (context.Context, context.CancelFunc) {
+	timeout := fallback
+	if opts.TimeoutMs > 0 {
+		timeout = time.Duration(opts.TimeoutMs) * time.Millisecond
+	}
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// post sends the request and returns the response once it has a success status.
+func (p *HTTPProvider) post(ctx context.Context, in ports.PromptInput, opts ports.Options, stream bool) (*http.Response, error) {
+	body, err := json.Marshal(p.buildRequest(in, opts, stream))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send chat request: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("chat request failed with status %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+// buildRequest maps a prompt and options onto a chat completion request. Context snippets
+// are appended to the system message.
+func (p *HTTPProvider) buildRequest(in ports.PromptInput, opts ports.Options, stream bool) chatRequest {
+	req := chatRequest{
+		Model:             p.model,
+		MaxTokens:         opts.MaxNewTokens,
+		Temperature:       opts.Temperature,
+		TopP:              opts.TopP,
+		MinP:              opts.MinP,
+		RepetitionPenalty: opts.RepetitionPenalty,
+		Seed:              opts.Seed,
+		Stop:              opts.Stop,
+		Stream:            stream,
+	}
+	if opts.JSONMode {
+		req.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+
+	system := in.System
+	if len(in.Context) > 0 {
+		if system != "" {
+			system += "\n\n"
+		}
+		system += "Context:\n" + strings.Join(in.Context, "\n\n")
+	}
+	if system != "" {
+		req.Messages = append(req.Messages, chatMessage{Role: "system", Content: system})
+	}
+	for _, msg := range in.Messages {
+		switch msg.Role {
+		case "developer":
+			req.Messages = append(req.Messages, chatMessage{Role: "system", Content: msg.Content})
+		case "tool":
+			req.Messages = append(req.Messages, chatMessage{Role: "user", Content: "Tool result:\n" + msg.Content})
+		default:
+			req.Messages = append(req.Messages, chatMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+
+	req.Tools = in.ToolSchema
+	if len(req.Tools) == 0 && len(in.Tools) > 0 {
+		req.Tools = openAITools(in.Tools)
+	}
+	if len(req.Tools) > 0 {
+		switch opts.ToolChoice {
+		case "":
+		case "auto", "none", "required":
+			req.ToolChoice = opts.ToolChoice
+		default:
+			req.ToolChoice = map[string]any{"type": "function", "function": map[string]string{"name": opts.ToolChoice}}
+		}
+	}
+	return req
+}
+
+// openAITools serializes specs as an OpenAI "tools" array for prompts built without a
+// wire-format tool schema formatter.
+func openAITools(specs []ports.ToolSpec) json.RawMessage {
+	type function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		Parameters  json.RawMessage `json:"parameters"`
+	}
+	type tool struct {
+		Type     string   `json:"type"`
+		Function function `json:"function"`
+	}
+
+	tools := make([]tool, len(specs))
+	for i, spec := range specs {
+		params := json.RawMessage(`{"type":"object","properties":{}}`)
+		if json.Valid(spec.JSONSchema) {
+			params = spec.JSONSchema
+		}
+		tools[i] = tool{Type: "function", Function: function{Name: spec.Name, Description: spec.Description, Parameters: params}}
+	}
+	data, _ := json.Marshal(tools)
+	return data
+}
+
+// convertToolCall maps a response tool call onto a ToolCall. Arguments that are not valid
+// JSON are passed on as a JSON string so tool schema validation can reject them.
+func convertToolCall(call chatToolCall) ports.ToolCall {
+	args := strings.TrimSpace(call.Function.Arguments)
+	switch {
+	case args == "":
+		args = "{}"
+	case !json.Valid([]byte(args)):
+		quoted, _ := json.Marshal(args)
+		args = string(quoted)
+	}
+	return ports.ToolCall{Name: call.Function.Name, Args: json.RawMessage(args)}
+}
+
+// convertUsage maps response usage onto Usage.
+func convertUsage(usage *chatUsage) *ports.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &ports.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+var (
+	_ ports.Provider                 = (*HTTPProvider)(nil)
+	_ ports.ToolSchemaFormatProvider = (*HTTPProvider)(nil)
+)
@@ -2,6 +2,7 @@ package adapters
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -13,7 +14,8 @@ type TokenBucket struct {
 	mu         sync.Mutex
 	buckets    map[string]*bucket
 	capacity   int           // max tokens per bucket
-	refillRate time.Duration // time between token refills
+	refillRate time.Duration // time between token refills; zero only returns tokens on release
+	released   chan struct{} // closed and replaced on every release to wake waiters
 }
 
 // bucket represents a single token bucket for a key.
@@ -28,6 +30,7 @@ func NewTokenBucket(capacity int, refillRate time.Duration) *TokenBucket {
 		buckets:    make(map[string]*bucket),
 		capacity:   capacity,
 		refillRate: refillRate,
+		released:   make(chan struct{}),
 	}
 }
 
@@ -36,6 +39,50 @@ func (tb *TokenBucket) Acquire(ctx context.Context, key string) (release func(),
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 
+	if release, _ = tb.tryAcquire(key); release == nil {
+		return nil, ErrRateLimitExceeded
+	}
+	return release, nil
+}
+
+// AcquireWait acquires a token for the given key, waiting for the next refill or release
+// when the bucket is empty. It fails with ErrRateLimitExceeded once ctx is done.
+func (tb *TokenBucket) AcquireWait(ctx context.Context, key string) (release func(), err error) {
+	for {
+		tb.mu.Lock()
+		release, wait := tb.tryAcquire(key)
+		released := tb.released
+		tb.mu.Unlock()
+		if release != nil {
+			return release, nil
+		}
+
+		var timer *time.Timer
+		var refilled <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			refilled = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			err = fmt.Errorf("%w: %w", ErrRateLimitExceeded, ctx.Err())
+		case <-refilled:
+		case <-released:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tryAcquire consumes a token for key if one is available. Otherwise it returns a nil
+// release and the time until the next refill, or zero when tokens only return on release.
+// tb.mu must be held.
+func (tb *TokenBucket) tryAcquire(key string) (release func(), wait time.Duration) {
 	b, exists := tb.buckets[key]
 	if !exists {
 		b = &bucket{
@@ -46,16 +93,21 @@ func (tb *TokenBucket) Acquire(ctx context.Context, key string) (release func(),
 	}
 
 	// Refill tokens based on elapsed time
-	elapsed := time.Since(b.lastRefill)
-	tokensToAdd := int(elapsed / tb.refillRate)
-	if tokensToAdd > 0 {
-		b.tokens = min(b.tokens+tokensToAdd, tb.capacity)
-		b.lastRefill = b.lastRefill.Add(time.Duration(tokensToAdd) * tb.refillRate)
+	if tb.refillRate > 0 {
+		elapsed := time.Since(b.lastRefill)
+		tokensToAdd := int(elapsed / tb.refillRate)
+		if tokensToAdd > 0 {
+			b.tokens = min(b.tokens+tokensToAdd, tb.capacity)
+			b.lastRefill = b.lastRefill.Add(time.Duration(tokensToAdd) * tb.refillRate)
+		}
 	}
 
 	// Check if we have a token available
 	if b.tokens <= 0 {
-		return nil, ErrRateLimitExceeded
+		if tb.refillRate > 0 {
+			wait = time.Until(b.lastRefill.Add(tb.refillRate))
+		}
+		return nil, wait
 	}
 
 	// Consume a token
@@ -68,9 +120,11 @@ func (tb *TokenBucket) Acquire(ctx context.Context, key string) (release func(),
 		if b, exists := tb.buckets[key]; exists {
 			b.tokens = min(b.tokens+1, tb.capacity)
 		}
+		close(tb.released)
+		tb.released = make(chan struct{})
 	}
 
-	return release, nil
+	return release, 0
 }
 
 // ErrRateLimitExceeded is returned when the rate limit is exceeded.
@@ -91,5 +145,8 @@ func min(a, b int) int {
 	return b
 }
 
-// Ensure TokenBucket implements the RateLimiter interface.
-var _ ports.RateLimiter = (*TokenBucket)(nil)
+// Ensure TokenBucket implements the RateLimiter interfaces.
+var (
+	_ ports.RateLimiter        = (*TokenBucket)(nil)
+	_ ports.WaitingRateLimiter = (*TokenBucket)(nil)
+)
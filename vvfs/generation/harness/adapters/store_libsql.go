@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
@@ -91,12 +92,59 @@ func (s *LibSQLConversationStore) AppendToolArtifact(ctx context.Context, conver
 	// Create a tool turn
 	toolTurn := ports.Turn{
 		Role:      "tool",
-		Content:   fmt.Sprintf("Tool %s executed: %s", name, string(payload)),
+		Content:   artifactPrefix(name) + string(payload),
 		CreatedAt: time.Now(),
 	}
 
 	return s.SaveTurn(ctx, conversationID, toolTurn)
 }
 
-// Ensure LibSQLConversationStore implements the ConversationStore interface.
-var _ ports.ConversationStore = (*LibSQLConversationStore)(nil)
+// LoadToolArtifact returns the most recent artifact named name in the conversation.
+func (s *LibSQLConversationStore) LoadToolArtifact(ctx context.Context, conversationID, name string) ([]byte, error) {
+	query := `
+		SELECT turn_data FROM conversation_turns
+		WHERE conversation_id = ?
+		ORDER BY created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query turns: %w", err)
+	}
+	defer rows.Close()
+
+	prefix := artifactPrefix(name)
+	for rows.Next() {
+		var turnJSON string
+		if err := rows.Scan(&turnJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan turn: %w", err)
+		}
+
+		var turn ports.Turn
+		if err := json.Unmarshal([]byte(turnJSON), &turn); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal turn: %w", err)
+		}
+		if turn.Role != "tool" {
+			continue
+		}
+		if payload, ok := strings.CutPrefix(turn.Content, prefix); ok {
+			return []byte(payload), nil
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating turns: %w", err)
+	}
+	return nil, ports.ErrArtifactNotFound
+}
+
+// artifactPrefix is the text an artifact turn's content starts with.
+func artifactPrefix(name string) string {
+	return fmt.Sprintf("Tool %s executed: ", name)
+}
+
+// Ensure LibSQLConversationStore implements the store interfaces.
+var (
+	_ ports.ConversationStore = (*LibSQLConversationStore)(nil)
+	_ ports.ArtifactReader    = (*LibSQLConversationStore)(nil)
+)
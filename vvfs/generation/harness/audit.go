@@ -0,0 +1,71 @@
+package harness
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// toolAuditor writes a redacted audit record of every tool call to a sink.
+type toolAuditor struct {
+	sink         ports.AuditSink
+	guardrails   *Guardrails  // redacts arguments, results and errors
+	storeResults bool         // keep redacted results, not only their hashes
+	tracer       ports.Tracer // optional, reports records that could not be written
+}
+
+// SetAuditSink makes the orchestrator record every tool invocation in sink.
+// Arguments, results and errors are redacted with guardrails; a nil guardrails uses
+// NewGuardrails. Results are kept only as hashes unless storeResults is set.
+// A nil sink disables auditing.
+func (o *HarnessOrchestrator) SetAuditSink(sink ports.AuditSink, guardrails *Guardrails, storeResults bool) {
+	if sink == nil {
+		o.auditor = nil
+		return
+	}
+	if guardrails == nil {
+		guardrails = NewGuardrails()
+	}
+	o.auditor = &toolAuditor{sink: sink, guardrails: guardrails, storeResults: storeResults, tracer: o.tracer}
+}
+
+// record writes the audit record of call, which began at begin and produced res.
+// Calls are recorded whether or not they succeeded, including unknown and disallowed tools.
+func (a *toolAuditor) record(ctx context.Context, call ports.ToolCall, begin time.Time, res toolResult) {
+	invocation := ports.ToolInvocation{
+		ID:        newAuditID(),
+		Tool:      call.Name,
+		Args:      a.guardrails.RedactArgs(call.Args),
+		StartedAt: begin,
+		Duration:  time.Since(begin),
+	}
+	invocation.ConversationID, _ = ports.ConversationIDFromContext(ctx)
+	if res.err != nil {
+		invocation.Error = a.guardrails.SanitizeOutput(res.err.Error())
+	} else {
+		sum := sha256.Sum256([]byte(res.content))
+		invocation.ResultHash = hex.EncodeToString(sum[:])
+		if a.storeResults {
+			invocation.Result = a.guardrails.SanitizeOutput(res.content)
+		}
+	}
+
+	// The audit write must not fail the tool call it describes
+	if err := a.sink.RecordToolInvocation(ctx, invocation); err != nil && a.tracer != nil {
+		a.tracer.Event(ctx, "audit_error", map[string]any{"tool": call.Name, "error": err.Error()})
+	}
+}
+
+// newAuditID returns a random identifier for an audit record.
+func newAuditID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,31 @@
+package harness
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned (wrapped) by the orchestrator. Match them with errors.Is.
+var (
+	ErrMaxIterations  = errors.New("max iterations exceeded")
+	ErrMaxToolDepth   = errors.New("max tool depth exceeded")
+	ErrRateLimited    = errors.New("rate limit exceeded")
+	ErrProviderFailed = errors.New("provider call failed")
+	ErrUnknownTool    = errors.New("unknown tool")
+	ErrToolNotAllowed = errors.New("tool not allowed")
+	// ErrStreamingUnsupported is returned by StreamOrchestrate when the provider's
+	// capabilities report no streaming support.
+	ErrStreamingUnsupported = errors.New("provider does not support streaming")
+)
+
+// ErrToolFailed reports a failed tool invocation. Match it with errors.As.
+type ErrToolFailed struct {
+	Name string // tool that failed
+	Err  error  // underlying cause
+}
+
+func (e *ErrToolFailed) Error() string {
+	return fmt.Sprintf("tool %s failed: %v", e.Name, e.Err)
+}
+
+func (e *ErrToolFailed) Unwrap() error { return e.Err }
@@ -14,8 +14,10 @@ import (
 // Factory creates and wires harness components from configuration.
 type Factory struct {
 	harnessConfig *config.HarnessConfig
-	db            *sql.DB // Optional, for conversation store
+	llmConfig     *config.LLMConfig // Optional, source of provider sampling options
+	db            *sql.DB           // Optional, for conversation store
 	logger        zerolog.Logger
+	providers     map[string]ports.Provider // Named providers available to the fallback chain
 }
 
 // NewFactory creates a new harness factory.
@@ -24,6 +26,31 @@ func NewFactory(harnessConfig *config.HarnessConfig, db *sql.DB, logger zerolog.
 		harnessConfig: harnessConfig,
 		db:            db,
 		logger:        logger,
+		providers:     make(map[string]ports.Provider),
+	}
+}
+
+// RegisterProvider makes a provider available under name for HarnessConfig.ProviderChain.
+func (f *Factory) RegisterProvider(name string, provider ports.Provider) {
+	f.providers[name] = provider
+}
+
+// SetLLMConfig makes orchestrators created by this factory use the configured sampling options.
+func (f *Factory) SetLLMConfig(llmConfig *config.LLMConfig) {
+	f.llmConfig = llmConfig
+}
+
+// OptionsFromLLMConfig maps LLM sampling settings onto provider options.
+func OptionsFromLLMConfig(llmConfig *config.LLMConfig) ports.Options {
+	if llmConfig == nil {
+		return ports.Options{}
+	}
+	return ports.Options{
+		MaxNewTokens:      llmConfig.MaxNewTokens,
+		Temperature:       llmConfig.Temperature,
+		TopP:              llmConfig.TopP,
+		MinP:              llmConfig.MinP,
+		RepetitionPenalty: llmConfig.RepetitionPenalty,
 	}
 }
 
@@ -34,9 +61,16 @@ func (f *Factory) CreateOrchestrator() (*HarnessOrchestrator, error) {
 	limiter := f.createRateLimiter()
 	tracer := f.createTracer()
 	store := f.createStore()
+	provider := f.createProvider(tracer)
 
 	// Create core components
-	builder := NewPromptBuilder()
+	defaultOptions := OptionsFromLLMConfig(f.llmConfig)
+	reserved := f.harnessConfig.ReservedTokens
+	if reserved == 0 && f.harnessConfig.ContextSize > 0 {
+		reserved = defaultOptions.MaxNewTokens
+	}
+	builder := NewBudgetedPromptBuilder(f.harnessConfig.ContextSize, reserved, nil)
+	builder.ToolFormatter = f.createToolSchemaFormatter()
 	assembler := NewContextAssembler(
 		Budget{
 			MaxContextTokens: 4000,
@@ -47,7 +81,7 @@ func (f *Factory) CreateOrchestrator() (*HarnessOrchestrator, error) {
 
 	// Create orchestrator
 	orchestrator := NewHarnessOrchestrator(
-		nil, // Provider must be injected separately (inference-specific)
+		provider, // nil unless providers were registered (inference-specific)
 		builder,
 		assembler,
 		store,
@@ -55,6 +89,18 @@ func (f *Factory) CreateOrchestrator() (*HarnessOrchestrator, error) {
 		limiter,
 		tracer,
 	)
+	orchestrator.SetDefaultOptions(defaultOptions)
+	if f.harnessConfig.EnableGuardrails {
+		orchestrator.SetAllowedTools(f.harnessConfig.AllowedTools)
+	}
+	if sink := f.createAuditSink(); sink != nil {
+		orchestrator.SetAuditSink(sink, f.CreateGuardrails(), f.harnessConfig.AuditStoreResults)
+	}
+	if format := f.harnessConfig.ToolResultFormat; format != "" {
+		if err := orchestrator.SetToolResultFormat(format); err != nil {
+			f.logger.Warn().Str("format", format).Msg("Unknown tool result format, rendering tool outputs as JSON")
+		}
+	}
 
 	return orchestrator, nil
 }
@@ -89,6 +135,81 @@ func (f *Factory) createTracer() ports.Tracer {
 	return adapters.NewZerologTracer(f.logger)
 }
 
+// createProvider resolves the configured provider chain against registered providers.
+// A chain of one returns that provider directly; longer chains fail over in order.
+func (f *Factory) createProvider(tracer ports.Tracer) ports.Provider {
+	chain := f.harnessConfig.ProviderChain
+	if len(chain) == 0 {
+		// Without a chain, a single registered provider is used as-is
+		if len(f.providers) == 1 {
+			for _, provider := range f.providers {
+				return provider
+			}
+		}
+		return nil
+	}
+
+	named := make([]adapters.NamedProvider, 0, len(chain))
+	for _, name := range chain {
+		provider, ok := f.providers[name]
+		if !ok {
+			f.logger.Warn().Str("provider", name).Msg("Provider in chain is not registered, skipping")
+			continue
+		}
+		named = append(named, adapters.NamedProvider{Name: name, Provider: provider})
+	}
+
+	switch len(named) {
+	case 0:
+		return nil
+	case 1:
+		return named[0].Provider
+	default:
+		return adapters.NewFallbackProvider(named, f.harnessConfig.ProviderTimeout, tracer)
+	}
+}
+
+// primaryProvider returns the provider tried first: the head of the configured chain,
+// or the only registered provider when there is no chain.
+func (f *Factory) primaryProvider() ports.Provider {
+	if len(f.harnessConfig.ProviderChain) == 0 {
+		if len(f.providers) == 1 {
+			for _, provider := range f.providers {
+				return provider
+			}
+		}
+		return nil
+	}
+	for _, name := range f.harnessConfig.ProviderChain {
+		if provider, ok := f.providers[name]; ok {
+			return provider
+		}
+	}
+	return nil
+}
+
+// createToolSchemaFormatter selects how tool declarations are serialized: the configured
+// ToolSchemaFormat wins, otherwise the format declared by the primary provider. Without
+// either, tool specs are passed to the provider unformatted.
+func (f *Factory) createToolSchemaFormatter() ToolSchemaFormatter {
+	format := f.harnessConfig.ToolSchemaFormat
+	if format == "" {
+		if declared, ok := f.primaryProvider().(ports.ToolSchemaFormatProvider); ok {
+			format = declared.ToolSchemaFormat()
+		}
+	}
+	if format == "" {
+		return nil
+	}
+
+	formatter, ok := ToolSchemaFormatterFor(format)
+	if !ok {
+		f.logger.Warn().Str("format", format).Msg("Unknown tool schema format, passing tool specs unformatted")
+		return nil
+	}
+	return formatter
+}
+
 // CreateStore creates a conversation store adapter from config.
 func (f *Factory) createStore() ports.ConversationStore {
 	if f.db == nil {
@@ -98,6 +219,25 @@ func (f *Factory) createStore() ports.ConversationStore {
 	return adapters.NewLibSQLConversationStore(f.db)
 }
 
+// createAuditSink creates the tool invocation audit log from config.
+// Auditing needs a database; without one it stays disabled.
+func (f *Factory) createAuditSink() ports.AuditSink {
+	if !f.harnessConfig.AuditEnabled {
+		return nil
+	}
+	if f.db == nil {
+		f.logger.Warn().Msg("Tool audit enabled without a database, audit log disabled")
+		return nil
+	}
+
+	sink, err := adapters.NewLibSQLAuditSink(context.Background(), f.db)
+	if err != nil {
+		f.logger.Warn().Err(err).Msg("Failed to initialize tool audit log, audit log disabled")
+		return nil
+	}
+	return sink
+}
+
 // CreateGuardrails creates guardrails from config.
 func (f *Factory) CreateGuardrails() *Guardrails {
 	guardrails := NewGuardrails()
@@ -121,13 +261,18 @@ func (f *Factory) CreateGuardrails() *Guardrails {
 // CreatePolicy creates a policy from config with validation.
 func (f *Factory) CreatePolicy() *Policy {
 	policy := &Policy{
-		MaxToolDepth:      f.harnessConfig.MaxToolDepth,
-		MaxIterations:     f.harnessConfig.MaxIterations,
-		ToolTimeout:       30 * time.Second,
-		RequireJSONOutput: false,
-		Deterministic:     false,
-		RetryCount:        2,
-		RetryBackoff:      100 * time.Millisecond,
+		MaxToolDepth:       f.harnessConfig.MaxToolDepth,
+		MaxIterations:      f.harnessConfig.MaxIterations,
+		ToolTimeout:        30 * time.Second,
+		RequireJSONOutput:  false,
+		Deterministic:      false,
+		RetryCount:         2,
+		RetryBackoff:       100 * time.Millisecond,
+		MaxToolResultBytes: f.harnessConfig.MaxToolResultBytes,
+
+		MaxConversationMessages: f.harnessConfig.MaxConversationMessages,
+		MaxToolCalls:            f.harnessConfig.MaxToolCalls,
+		RateLimitWait:           f.harnessConfig.RateLimitWait,
 	}
 
 	// Validate and clamp policy values
@@ -91,8 +91,15 @@ func (g *Guardrails) ValidateOutput(output string) error {
 	return nil
 }
 
-// ValidateJSONOutput validates JSON output against a schema if provided.
+// ValidateJSONOutput validates JSON output against a schema if provided. Output that is
+// not valid JSON on its own is validated using the first JSON value embedded in it, so
+// prose or code fences around the payload do not fail validation.
 func (g *Guardrails) ValidateJSONOutput(data json.RawMessage, schema []byte) error {
+	if !json.Valid(data) {
+		if extracted, ok := ExtractJSON(string(data)); ok {
+			data = extracted
+		}
+	}
 	return g.jsonValidator.Validate(data, schema)
 }
 
@@ -108,6 +115,64 @@ func (g *Guardrails) SanitizeOutput(output string) string {
 	return sanitized
 }
 
+// RedactArgs returns tool arguments with sensitive values masked for audit logs.
+// Values whose field name or text contains a blocked word are replaced outright and
+// other strings go through the output filters. Arguments that are not valid JSON are
+// treated as a single string.
+func (g *Guardrails) RedactArgs(args json.RawMessage) string {
+	var decoded any
+	if err := json.Unmarshal(args, &decoded); err != nil {
+		return g.redactString(string(args))
+	}
+
+	redacted, err := json.Marshal(g.redactValue(decoded))
+	if err != nil {
+		return "[REDACTED]"
+	}
+	return string(redacted)
+}
+
+func (g *Guardrails) redactValue(v any) any {
+	switch val := v.(type) {
+	case string:
+		return g.redactString(val)
+	case map[string]any:
+		for k, item := range val {
+			if g.containsBlockedWord(k) {
+				val[k] = "[REDACTED]"
+				continue
+			}
+			val[k] = g.redactValue(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = g.redactValue(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+func (g *Guardrails) redactString(s string) string {
+	if g.containsBlockedWord(s) {
+		return "[REDACTED]"
+	}
+	return g.SanitizeOutput(s)
+}
+
+// containsBlockedWord reports whether s contains any blocked word, ignoring case.
+func (g *Guardrails) containsBlockedWord(s string) bool {
+	lower := strings.ToLower(s)
+	for _, word := range g.blockedWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
 // JSONValidator handles JSON schema validation.
 type JSONValidator struct{}
 
@@ -167,7 +232,7 @@ func NewPolicyValidator() *PolicyValidator {
 // ValidateDepth checks if tool depth is within limits.
 func (v *PolicyValidator) ValidateDepth(currentDepth int) error {
 	if currentDepth > v.maxToolDepth {
-		return fmt.Errorf("tool depth %d exceeds maximum %d", currentDepth, v.maxToolDepth)
+		return fmt.Errorf("%w: depth %d exceeds maximum %d", ErrMaxToolDepth, currentDepth, v.maxToolDepth)
 	}
 	return nil
 }
@@ -175,7 +240,7 @@ func (v *PolicyValidator) ValidateDepth(currentDepth int) error {
 // ValidateIteration checks if iteration count is within limits.
 func (v *PolicyValidator) ValidateIteration(currentIteration int) error {
 	if currentIteration > v.maxIterations {
-		return fmt.Errorf("iteration %d exceeds maximum %d", currentIteration, v.maxIterations)
+		return fmt.Errorf("%w: iteration %d exceeds maximum %d", ErrMaxIterations, currentIteration, v.maxIterations)
 	}
 	return nil
 }
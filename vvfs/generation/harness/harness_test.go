@@ -1,10 +1,23 @@
 package harness
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -100,6 +113,39 @@ func (s *stubConversationStore) AppendToolArtifact(ctx context.Context, conversa
 // Ensure stubConversationStore implements the ConversationStore interface.
 var _ ports.ConversationStore = (*stubConversationStore)(nil)
 
+// recordingTracer implements Tracer and records emitted events for assertions.
+type recordingTracer struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	name  string
+	attrs map[string]any
+}
+
+func (r *recordingTracer) StartSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, func(err error)) {
+	return ctx, func(err error) {}
+}
+
+func (r *recordingTracer) Event(ctx context.Context, name string, attrs map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{name: name, attrs: attrs})
+}
+
+func (r *recordingTracer) find(name string) []recordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []recordedEvent
+	for _, e := range r.events {
+		if e.name == name {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
 // TestPromptBuilder_Build tests prompt construction.
 func TestPromptBuilder_Build(t *testing.T) {
 	builder := NewPromptBuilder()
@@ -123,6 +169,42 @@ func TestPromptBuilder_Build(t *testing.T) {
 	assert.Equal(t, "value", input.Meta["test"])
 }
 
+// TestPromptBuilder_TrimsToContextSize tests that over-budget prompts drop the oldest turns.
+func TestPromptBuilder_TrimsToContextSize(t *testing.T) {
+	// One token per word keeps the arithmetic readable
+	countWords := func(s string) int { return len(strings.Fields(s)) }
+	builder := NewBudgetedPromptBuilder(120, 20, countWords)
+
+	system := "You are a helpful assistant"
+	var messages []ports.PromptMessage
+	for i := 0; i < 20; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		messages = append(messages, ports.PromptMessage{
+			Role:    role,
+			Content: fmt.Sprintf("turn %d with some filler words to use budget", i),
+		})
+	}
+	messages = append(messages, ports.PromptMessage{Role: "user", Content: "latest question"})
+
+	input := builder.Build(system, messages, nil, nil, nil)
+
+	assert.Equal(t, system, input.System)
+	assert.Equal(t, "latest question", input.Messages[len(input.Messages)-1].Content)
+	assert.Less(t, len(input.Messages), len(messages))
+	assert.LessOrEqual(t, builder.EstimateTokens(input), 100)
+	assert.NotEqual(t, "turn 0 with some filler words to use budget", input.Messages[1].Content)
+	assert.Contains(t, input.Messages[0].Content, "earlier messages omitted")
+	assert.NotEmpty(t, input.Meta["trimmed_messages"])
+	assert.NotEqual(t, "0", input.Meta["trimmed_tokens"])
+
+	// Within budget nothing changes
+	_, report := builder.Fit(ports.PromptInput{System: system, Messages: messages[len(messages)-1:]})
+	assert.False(t, report.Trimmed())
+}
+
 // TestContextAssembler_Pack tests context packing with token budgeting.
 func TestContextAssembler_Pack(t *testing.T) {
 	assembler := NewContextAssembler(
@@ -162,6 +244,14 @@ func TestOutputParser_ParseToolCalls(t *testing.T) {
 	assert.Len(t, calls2, 1)
 	assert.Equal(t, "test_tool", calls2[0].Name)
 
+	// Test OpenAI format with string-encoded arguments
+	text4 := `Calling: {"tool_calls": [{"function": {"name": "read_file", "arguments": "{\"path\": \"a/{b}.txt\"}"}}]}`
+	calls4 := parser.ParseToolCalls(text4)
+	if assert.Len(t, calls4, 1) {
+		assert.Equal(t, "read_file", calls4[0].Name)
+		assert.JSONEq(t, `{"path": "a/{b}.txt"}`, string(calls4[0].Args))
+	}
+
 	// Test no tool calls
 	text3 := "Just a normal response"
 	calls3 := parser.ParseToolCalls(text3)
@@ -255,6 +345,49 @@ func TestTokenBucket_BasicRateLimiting(t *testing.T) {
 	release3()
 }
 
+// TestTokenBucket_AcquireWait tests that waiting acquisition rides out bursts until the deadline.
+func TestTokenBucket_AcquireWait(t *testing.T) {
+	// A burst beyond capacity succeeds once the bucket refills within the deadline
+	limiter := adapters.NewTokenBucket(2, 50*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		_, err := limiter.Acquire(context.Background(), "burst")
+		assert.NoError(t, err)
+	}
+	_, err := limiter.Acquire(context.Background(), "burst")
+	assert.ErrorIs(t, err, adapters.ErrRateLimitExceeded)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	release, err := limiter.AcquireWait(ctx, "burst")
+	if assert.NoError(t, err) {
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "should have waited for the refill")
+		release()
+	}
+
+	// The deadline passing before the next refill fails the wait
+	limiter = adapters.NewTokenBucket(1, time.Hour)
+	_, err = limiter.Acquire(context.Background(), "slow")
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err = limiter.AcquireWait(ctx, "slow")
+	assert.ErrorIs(t, err, adapters.ErrRateLimitExceeded)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Without refills a released token wakes the waiter
+	limiter = adapters.NewTokenBucket(1, 0)
+	held, err := limiter.Acquire(context.Background(), "release")
+	assert.NoError(t, err)
+	time.AfterFunc(20*time.Millisecond, held)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err = limiter.AcquireWait(ctx, "release")
+	if assert.NoError(t, err) {
+		release()
+	}
+}
+
 // TestHarnessOrchestrator_SimpleConversation tests basic orchestration without tools.
 func TestHarnessOrchestrator_SimpleConversation(t *testing.T) {
 	// Setup stub provider
@@ -300,6 +433,173 @@ func TestHarnessOrchestrator_SimpleConversation(t *testing.T) {
 	assert.Equal(t, 15, resp.Usage.TotalTokens)
 }
 
+// TestHarnessOrchestrator_BypassCache tests that BypassCache forces a fresh run and refreshes the cache.
+func TestHarnessOrchestrator_BypassCache(t *testing.T) {
+	calls := 0
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			calls++
+			return ports.Completion{Text: fmt.Sprintf("response %d", calls)}, nil
+		},
+	}
+	tracer := &recordingTracer{}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, adapters.NewLRUCache(100), adapters.NewTokenBucket(10, time.Second), tracer)
+
+	newRequest := func(policy *Policy) *Request {
+		return &Request{
+			Conversation: &Conversation{ID: "cache-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hello"}}},
+			System:       "You are a helpful assistant",
+			Policy:       policy,
+		}
+	}
+
+	// Warm the cache
+	resp, err := orchestrator.Orchestrate(context.Background(), newRequest(DefaultPolicy()))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 1", resp.Text)
+
+	resp, err = orchestrator.Orchestrate(context.Background(), newRequest(DefaultPolicy()))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 1", resp.Text)
+	assert.Equal(t, 1, calls)
+
+	// Bypass re-invokes the provider despite the warm cache
+	bypass := DefaultPolicy()
+	bypass.BypassCache = true
+	resp, err = orchestrator.Orchestrate(context.Background(), newRequest(bypass))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 2", resp.Text)
+	assert.Equal(t, 2, calls)
+	assert.Len(t, tracer.find("cache_bypass"), 1)
+
+	// The fresh result was written back
+	resp, err = orchestrator.Orchestrate(context.Background(), newRequest(DefaultPolicy()))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 2", resp.Text)
+	assert.Equal(t, 2, calls)
+
+	// Write-only mode also skips the lookup and repopulates
+	writeOnly := DefaultPolicy()
+	writeOnly.CacheWriteOnly = true
+	resp, err = orchestrator.Orchestrate(context.Background(), newRequest(writeOnly))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 3", resp.Text)
+	resp, err = orchestrator.Orchestrate(context.Background(), newRequest(DefaultPolicy()))
+	assert.NoError(t, err)
+	assert.Equal(t, "response 3", resp.Text)
+}
+
+// TestPolicy_WithDefaults tests that partial policies keep defaults for unset fields.
+func TestPolicy_WithDefaults(t *testing.T) {
+	def := DefaultPolicy()
+
+	merged := (&Policy{MaxToolDepth: 1}).WithDefaults()
+	assert.Equal(t, 1, merged.MaxToolDepth)
+	assert.Equal(t, def.MaxIterations, merged.MaxIterations)
+	assert.Equal(t, def.ToolTimeout, merged.ToolTimeout)
+	assert.Equal(t, def.RetryCount, merged.RetryCount)
+	assert.Equal(t, def.RetryBackoff, merged.RetryBackoff)
+
+	// ExplicitZero is kept as a real zero instead of being defaulted
+	merged = (&Policy{RetryCount: ExplicitZero, ToolTimeout: ExplicitZero, BypassCache: true}).WithDefaults()
+	assert.Equal(t, 0, merged.RetryCount)
+	assert.Equal(t, time.Duration(0), merged.ToolTimeout)
+	assert.Equal(t, def.MaxToolDepth, merged.MaxToolDepth)
+	assert.True(t, merged.BypassCache)
+
+	// nil yields the defaults and the receiver is never mutated
+	assert.Equal(t, def, (*Policy)(nil).WithDefaults())
+	partial := &Policy{MaxIterations: 5}
+	_ = partial.WithDefaults()
+	assert.Equal(t, &Policy{MaxIterations: 5}, partial)
+
+	// Orchestrate applies the merge, so a depth-only override still allows iterations
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			return ports.Completion{Text: "done"}, nil
+		},
+	}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	req := &Request{
+		Conversation: &Conversation{ID: "partial-policy", Messages: []ports.PromptMessage{{Role: "user", Content: "Hi"}}},
+		Policy:       &Policy{MaxToolDepth: 1},
+	}
+	resp, err := orchestrator.Orchestrate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp.Text)
+	assert.Equal(t, def.MaxIterations, req.Policy.MaxIterations)
+}
+
+// TestHarnessOrchestrator_TypedErrors tests that each failure path is matchable with errors.Is/errors.As.
+func TestHarnessOrchestrator_TypedErrors(t *testing.T) {
+	toolCallProvider := func(name string) *StubProvider {
+		return &StubProvider{
+			completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+				return ports.Completion{ToolCalls: []ports.ToolCall{{Name: name, Args: json.RawMessage(`{}`)}}}, nil
+			},
+		}
+	}
+	run := func(provider ports.Provider, limiter ports.RateLimiter, policy *Policy) error {
+		orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, limiter, &noOpTracer{})
+		_, err := orchestrator.Orchestrate(context.Background(), &Request{
+			Conversation: &Conversation{ID: "errors-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hi"}}},
+			Tools:        []ports.Tool{&StubTool{name: "echo", schema: `{}`, result: "ok"}},
+			Policy:       policy,
+		})
+		return err
+	}
+
+	t.Run("rate limited", func(t *testing.T) {
+		limiter := adapters.NewTokenBucket(1, time.Hour)
+		release, err := limiter.Acquire(context.Background(), "orchestrate")
+		assert.NoError(t, err)
+		defer release()
+
+		err = run(&StubProvider{}, limiter, DefaultPolicy())
+		assert.ErrorIs(t, err, ErrRateLimited)
+	})
+
+	t.Run("max iterations", func(t *testing.T) {
+		policy := DefaultPolicy()
+		policy.MaxIterations = 2
+		policy.MaxToolDepth = 10
+		err := run(toolCallProvider("echo"), &noOpRateLimiter{}, policy)
+		assert.ErrorIs(t, err, ErrMaxIterations)
+		assert.Contains(t, err.Error(), "max iterations exceeded")
+	})
+
+	t.Run("max tool depth", func(t *testing.T) {
+		policy := DefaultPolicy()
+		policy.MaxToolDepth = 1
+		err := run(toolCallProvider("echo"), &noOpRateLimiter{}, policy)
+		assert.ErrorIs(t, err, ErrMaxToolDepth)
+	})
+
+	t.Run("tool failed", func(t *testing.T) {
+		err := run(toolCallProvider("missing"), &noOpRateLimiter{}, DefaultPolicy())
+		var toolErr *ErrToolFailed
+		if assert.ErrorAs(t, err, &toolErr) {
+			assert.Equal(t, "missing", toolErr.Name)
+		}
+		assert.ErrorIs(t, err, ErrUnknownTool)
+	})
+
+	t.Run("provider failed", func(t *testing.T) {
+		cause := errors.New("backend down")
+		provider := &StubProvider{
+			completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+				return ports.Completion{}, cause
+			},
+		}
+		err := run(provider, &noOpRateLimiter{}, DefaultPolicy())
+		assert.ErrorIs(t, err, ErrProviderFailed)
+		assert.ErrorIs(t, err, cause)
+	})
+}
+
 // TestHarnessOrchestrator_WithTools tests orchestration with tool calls.
 func TestHarnessOrchestrator_WithTools(t *testing.T) {
 	provider := &StubProvider{
@@ -345,6 +645,166 @@ func TestHarnessOrchestrator_WithTools(t *testing.T) {
 	assert.Equal(t, 15, resp.Usage.TotalTokens)
 }
 
+// capabilityProvider is a StubProvider declaring its capabilities.
+type capabilityProvider struct {
+	StubProvider
+	caps ports.ProviderCapabilities
+}
+
+func (p *capabilityProvider) Capabilities() ports.ProviderCapabilities { return p.caps }
+
+// TestHarnessOrchestrator_AdaptsToProviderCapabilities tests that tool declarations, JSON
+// mode, prompt budget and streaming follow what the provider reports it supports.
+func TestHarnessOrchestrator_AdaptsToProviderCapabilities(t *testing.T) {
+	tool := &StubTool{name: "lookup", schema: `{"type":"object"}`, result: "found"}
+	var prompts []ports.PromptInput
+	var options []ports.Options
+	newOrchestrator := func(caps *ports.ProviderCapabilities) *HarnessOrchestrator {
+		prompts, options = nil, nil
+		stub := StubProvider{
+			completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+				prompts = append(prompts, in)
+				options = append(options, opts)
+				if len(prompts) == 1 && len(in.Tools) == 0 && strings.Contains(in.System, "lookup") {
+					// A model without native tool calls answers in the documented text form
+					return ports.Completion{Text: `[{"name": "lookup", "arguments": {}}]`}, nil
+				}
+				return ports.Completion{Text: "done"}, nil
+			},
+			streamFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+				t.Error("Stream must not be called")
+				return nil, errors.New("unexpected stream")
+			},
+		}
+		var provider ports.Provider = &stub
+		if caps != nil {
+			provider = &capabilityProvider{StubProvider: stub, caps: *caps}
+		}
+		return NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	}
+	newRequest := func(messages ...string) *Request {
+		conv := &Conversation{ID: "caps-conv"}
+		for _, content := range messages {
+			conv.Messages = append(conv.Messages, ports.PromptMessage{Role: "user", Content: content})
+		}
+		return &Request{
+			Conversation: conv,
+			System:       "Be brief.",
+			Tools:        []ports.Tool{tool},
+			Policy:       &Policy{RequireJSONOutput: true},
+			Options:      &ports.Options{MaxNewTokens: 16},
+		}
+	}
+
+	t.Run("native provider", func(t *testing.T) {
+		orchestrator := newOrchestrator(nil)
+		_, err := orchestrator.Orchestrate(context.Background(), newRequest("Hi"))
+		assert.NoError(t, err)
+		if !assert.Len(t, prompts, 1) {
+			return
+		}
+		assert.Len(t, prompts[0].Tools, 1)
+		assert.Equal(t, "Be brief.", prompts[0].System)
+		assert.True(t, options[0].JSONMode)
+	})
+
+	t.Run("bare local model", func(t *testing.T) {
+		orchestrator := newOrchestrator(&ports.ProviderCapabilities{})
+		resp, err := orchestrator.Orchestrate(context.Background(), newRequest("Hi"))
+		assert.NoError(t, err)
+		assert.Equal(t, "done", resp.Text)
+
+		// Tools are described in the system prompt and the text-form call is executed
+		if !assert.Len(t, prompts, 2) {
+			return
+		}
+		assert.Empty(t, prompts[0].Tools)
+		assert.Contains(t, prompts[0].System, "Be brief.")
+		assert.Contains(t, prompts[0].System, "- lookup")
+		assert.Contains(t, prompts[1].Messages[len(prompts[1].Messages)-1].Content, "found")
+		assert.False(t, options[0].JSONMode)
+
+		// The shared builder is left untouched
+		assert.Nil(t, orchestrator.builder.ToolFormatter)
+
+		respCh, errCh := orchestrator.StreamOrchestrate(context.Background(), newRequest("Hi"))
+		for range respCh {
+			t.Error("no response expected from a non-streaming provider")
+		}
+		assert.ErrorIs(t, <-errCh, ErrStreamingUnsupported)
+	})
+
+	t.Run("context window", func(t *testing.T) {
+		orchestrator := newOrchestrator(&ports.ProviderCapabilities{NativeToolCalls: true, ContextWindow: 120})
+		long := strings.Repeat("word ", 40)
+		_, err := orchestrator.Orchestrate(context.Background(), newRequest(long, long, long, "latest"))
+		assert.NoError(t, err)
+		if !assert.Len(t, prompts, 1) {
+			return
+		}
+		assert.NotEmpty(t, prompts[0].Meta["trimmed_messages"])
+		assert.Equal(t, "latest", prompts[0].Messages[len(prompts[0].Messages)-1].Content)
+		assert.Zero(t, orchestrator.builder.ContextSize)
+	})
+}
+
+// TestHarnessOrchestrator_RequestAllowedTools tests that a request allowlist narrows the
+// global one: hidden tools are left out of the prompt and calls to them are rejected.
+func TestHarnessOrchestrator_RequestAllowedTools(t *testing.T) {
+	var offered [][]string
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			names := make([]string, len(in.Tools))
+			for i, spec := range in.Tools {
+				names[i] = spec.Name
+			}
+			offered = append(offered, names)
+			return ports.Completion{ToolCalls: []ports.ToolCall{{Name: "preview", Args: json.RawMessage(`{}`)}}}, nil
+		},
+	}
+
+	factory := NewFactory(&config.HarnessConfig{
+		MaxToolDepth:     3,
+		MaxIterations:    5,
+		EnableGuardrails: true,
+		AllowedTools:     []string{"search", "preview"},
+	}, nil, zerolog.New(zerolog.Nop()))
+	factory.RegisterProvider("local", provider)
+	orchestrator, err := factory.CreateOrchestrator()
+	assert.NoError(t, err)
+
+	tools := []ports.Tool{
+		&StubTool{name: "search", schema: `{}`, result: "found"},
+		&StubTool{name: "preview", schema: `{}`, result: "contents"},
+		&StubTool{name: "exec", schema: `{}`, result: "ran"},
+	}
+	_, err = orchestrator.Orchestrate(context.Background(), &Request{
+		Conversation: &Conversation{ID: "narrow-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hi"}}},
+		Tools:        tools,
+		AllowedTools: []string{"search", "exec"},
+	})
+	var toolErr *ErrToolFailed
+	if assert.ErrorAs(t, err, &toolErr) {
+		assert.Equal(t, "preview", toolErr.Name)
+	}
+	assert.ErrorIs(t, err, ErrToolNotAllowed)
+
+	// Without a request allowlist the global one alone applies
+	_, err = orchestrator.Orchestrate(context.Background(), &Request{
+		Conversation: &Conversation{ID: "global-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hi"}}},
+		Tools:        tools,
+		Policy:       &Policy{MaxToolDepth: 1},
+	})
+	assert.ErrorIs(t, err, ErrMaxToolDepth)
+
+	if assert.Len(t, offered, 3) {
+		// exec is outside the global allowlist and preview outside the request's
+		assert.Equal(t, []string{"search"}, offered[0])
+		assert.Equal(t, []string{"search", "preview"}, offered[2])
+	}
+}
+
 // Benchmark tests for performance validation
 func BenchmarkPromptBuilder_Build(b *testing.B) {
 	builder := NewPromptBuilder()
@@ -593,6 +1053,1832 @@ func TestFactory_Wiring(t *testing.T) {
 	assert.Equal(t, 5, policy.MaxIterations)
 }
 
+// TestFallbackProvider_FailsOver tests that the next provider is used when the first errors.
+func TestFallbackProvider_FailsOver(t *testing.T) {
+	local := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			return ports.Completion{}, fmt.Errorf("model overloaded")
+		},
+	}
+	remote := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			return ports.Completion{Text: "remote completion"}, nil
+		},
+	}
+	tracer := &recordingTracer{}
+
+	provider := adapters.NewFallbackProvider([]adapters.NamedProvider{
+		{Name: "local", Provider: local},
+		{Name: "remote", Provider: remote},
+	}, time.Second, tracer)
+
+	completion, err := provider.Complete(context.Background(), ports.PromptInput{}, ports.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "remote completion", completion.Text)
+
+	failovers := tracer.find("provider_failover")
+	if assert.Len(t, failovers, 1) {
+		assert.Equal(t, "local", failovers[0].attrs["provider"])
+		assert.Equal(t, "remote", failovers[0].attrs["next_provider"])
+		assert.Contains(t, failovers[0].attrs["error"], "model overloaded")
+	}
+	selected := tracer.find("provider_selected")
+	if assert.Len(t, selected, 1) {
+		assert.Equal(t, "remote", selected[0].attrs["provider"])
+	}
+
+	// Every provider failing surfaces all errors
+	failing := adapters.NewFallbackProvider([]adapters.NamedProvider{{Name: "local", Provider: local}}, 0, nil)
+	_, err = failing.Complete(context.Background(), ports.PromptInput{}, ports.Options{})
+	assert.ErrorContains(t, err, "all 1 providers failed")
+}
+
+// TestFactory_ProviderChain tests that the factory builds a fallback chain from config.
+func TestFactory_ProviderChain(t *testing.T) {
+	cfg := &config.HarnessConfig{
+		MaxToolDepth:  3,
+		MaxIterations: 5,
+		ProviderChain: []string{"gguf", "missing", "remote"},
+	}
+	factory := NewFactory(cfg, nil, zerolog.New(zerolog.Nop()))
+	factory.RegisterProvider("gguf", &StubProvider{})
+	factory.RegisterProvider("remote", &StubProvider{})
+
+	provider := factory.createProvider(&noOpTracer{})
+	assert.IsType(t, &adapters.FallbackProvider{}, provider)
+
+	orchestrator, err := factory.CreateOrchestrator()
+	assert.NoError(t, err)
+	assert.IsType(t, &adapters.FallbackProvider{}, orchestrator.provider)
+}
+
+// formatDeclaringProvider is a StubProvider that declares its tool schema format.
+type formatDeclaringProvider struct {
+	StubProvider
+	format string
+}
+
+func (p *formatDeclaringProvider) ToolSchemaFormat() string { return p.format }
+
+// TestFactory_ToolSchemaFormat tests that the formatter follows config, then the primary provider.
+func TestFactory_ToolSchemaFormat(t *testing.T) {
+	cfg := &config.HarnessConfig{MaxToolDepth: 3, MaxIterations: 5, ProviderChain: []string{"remote", "gguf"}}
+	factory := NewFactory(cfg, nil, zerolog.New(zerolog.Nop()))
+	factory.RegisterProvider("remote", &formatDeclaringProvider{format: "openai"})
+	factory.RegisterProvider("gguf", &formatDeclaringProvider{format: "inline"})
+
+	orchestrator, err := factory.CreateOrchestrator()
+	assert.NoError(t, err)
+	assert.IsType(t, OpenAIToolSchemaFormatter{}, orchestrator.builder.ToolFormatter)
+
+	cfg.ToolSchemaFormat = "inline"
+	orchestrator, err = factory.CreateOrchestrator()
+	assert.NoError(t, err)
+	assert.IsType(t, InlineToolSchemaFormatter{}, orchestrator.builder.ToolFormatter)
+
+	// Providers without a declared format get plain specs
+	plain := NewFactory(&config.HarnessConfig{MaxToolDepth: 3, MaxIterations: 5}, nil, zerolog.New(zerolog.Nop()))
+	plain.RegisterProvider("local", &StubProvider{})
+	orchestrator, err = plain.CreateOrchestrator()
+	assert.NoError(t, err)
+	assert.Nil(t, orchestrator.builder.ToolFormatter)
+}
+
+func TestFactory_PromptBudget(t *testing.T) {
+	cfg := &config.HarnessConfig{MaxToolDepth: 3, MaxIterations: 5, ContextSize: 2048, ReservedTokens: 256}
+	factory := NewFactory(cfg, nil, zerolog.New(zerolog.Nop()))
+	factory.RegisterProvider("local", &StubProvider{})
+
+	orchestrator, err := factory.CreateOrchestrator()
+	assert.NoError(t, err)
+	assert.Equal(t, 2048, orchestrator.builder.ContextSize)
+	assert.Equal(t, 256, orchestrator.builder.ReservedTokens)
+
+	// The configured budget trims real prompts
+	messages := make([]ports.PromptMessage, 0, 200)
+	for i := 0; i < 200; i++ {
+		messages = append(messages, ports.PromptMessage{Role: "user", Content: strings.Repeat("word ", 40)})
+	}
+	input := orchestrator.builder.Build("system", messages, nil, nil, nil)
+	assert.NotEmpty(t, input.Meta["trimmed_messages"])
+}
+
+// TestHarnessOrchestrator_ProviderOptions tests that configured sampling options reach the provider.
+func TestHarnessOrchestrator_ProviderOptions(t *testing.T) {
+	var received []ports.Options
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			received = append(received, opts)
+			return ports.Completion{Text: "ok"}, nil
+		},
+	}
+
+	factory := NewFactory(&config.HarnessConfig{MaxToolDepth: 3, MaxIterations: 5}, nil, zerolog.New(zerolog.Nop()))
+	factory.RegisterProvider("local", provider)
+	factory.SetLLMConfig(&config.LLMConfig{
+		MaxNewTokens:      512,
+		Temperature:       0.3,
+		TopP:              0.8,
+		MinP:              0.15,
+		RepetitionPenalty: 1.05,
+	})
+	orchestrator, err := factory.CreateOrchestrator()
+	assert.NoError(t, err)
+
+	newRequest := func(opts *ports.Options) *Request {
+		return &Request{
+			Conversation: &Conversation{ID: "options-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hi"}}},
+			Options:      opts,
+		}
+	}
+
+	_, err = orchestrator.Orchestrate(context.Background(), newRequest(nil))
+	assert.NoError(t, err)
+	_, err = orchestrator.Orchestrate(context.Background(), newRequest(&ports.Options{Temperature: 0.1, Stop: []string{"END"}}))
+	assert.NoError(t, err)
+
+	if !assert.Len(t, received, 2) {
+		return
+	}
+	assert.Equal(t, ports.Options{MaxNewTokens: 512, Temperature: 0.3, TopP: 0.8, MinP: 0.15, RepetitionPenalty: 1.05}, received[0])
+	// Request overrides win field by field; the rest stay as configured
+	assert.Equal(t, float32(0.1), received[1].Temperature)
+	assert.Equal(t, []string{"END"}, received[1].Stop)
+	assert.Equal(t, 512, received[1].MaxNewTokens)
+	assert.Equal(t, float32(1.05), received[1].RepetitionPenalty)
+
+	// Unset config keeps the previous defaults
+	bare := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	bare.SetDefaultOptions(OptionsFromLLMConfig(&config.LLMConfig{TopP: 0.5}))
+	_, err = bare.Orchestrate(context.Background(), newRequest(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, ports.Options{MaxNewTokens: 1024, Temperature: 0.7, TopP: 0.5}, received[2])
+}
+
+// timedTool records when it was invoked.
+type timedTool struct {
+	StubTool
+	started chan time.Time
+}
+
+func (t *timedTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	t.started <- time.Now()
+	return t.result, nil
+}
+
+// TestStreamOrchestrate_OverlapsToolsWithStream tests that a tool call completed mid-stream
+// starts executing before the stream finishes.
+func TestStreamOrchestrate_OverlapsToolsWithStream(t *testing.T) {
+	tool := &timedTool{StubTool: StubTool{name: "lookup", schema: `{}`, result: "found"}, started: make(chan time.Time, 1)}
+
+	var streamDone time.Time
+	var toolStarted time.Time
+	streams := 0
+	provider := &StubProvider{
+		streamFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+			streams++
+			ch := make(chan ports.CompletionChunk)
+			if streams > 1 {
+				go func() {
+					defer close(ch)
+					ch <- ports.CompletionChunk{DeltaText: "answer", Done: true}
+				}()
+				return ch, nil
+			}
+			go func() {
+				defer close(ch)
+				ch <- ports.CompletionChunk{ToolCalls: []ports.ToolCall{{Name: "lookup", Args: json.RawMessage(`{}`)}}}
+				// Keep generating until the tool has started (or give up after a second)
+				select {
+				case toolStarted = <-tool.started:
+				case <-time.After(time.Second):
+				}
+				ch <- ports.CompletionChunk{DeltaText: "still thinking"}
+				streamDone = time.Now()
+				ch <- ports.CompletionChunk{Done: true}
+			}()
+			return ch, nil
+		},
+	}
+
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	req := &Request{
+		Conversation: &Conversation{ID: "overlap-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Look it up"}}},
+		Tools:        []ports.Tool{tool},
+	}
+
+	respCh, errCh := orchestrator.StreamOrchestrate(context.Background(), req)
+	var responses []*Response
+	for resp := range respCh {
+		responses = append(responses, resp)
+	}
+	assert.NoError(t, <-errCh)
+
+	if !assert.False(t, toolStarted.IsZero(), "tool did not start while the stream was open") {
+		return
+	}
+	assert.True(t, toolStarted.Before(streamDone))
+	if !assert.Len(t, responses, 2) {
+		return
+	}
+	assert.Equal(t, "answer", responses[1].Text)
+
+	// Tool result was joined into the conversation before the next prompt
+	last := req.Conversation.Messages[len(req.Conversation.Messages)-1]
+	assert.Equal(t, "tool", last.Role)
+	assert.Equal(t, "found", last.Content)
+}
+
+// TestExecuteTools_MoreCallsThanSlots tests that results keep call order beyond the concurrency bound.
+func TestExecuteTools_MoreCallsThanSlots(t *testing.T) {
+	orchestrator := &HarnessOrchestrator{}
+	req := &Request{Tools: []ports.Tool{&StubTool{name: "echo", schema: `{}`, result: "ok"}}}
+	calls := make([]ports.ToolCall, maxConcurrentTools*2+1)
+	for i := range calls {
+		calls[i] = ports.ToolCall{Name: "echo", Args: json.RawMessage(`{}`)}
+	}
+
+	outputs, err := orchestrator.executeTools(context.Background(), req, calls)
+	assert.NoError(t, err)
+	assert.Len(t, outputs, len(calls))
+}
+
+// TestExecuteTools_TruncatesLargeResults tests that oversized results are capped in context
+// and persisted in full as a tool artifact.
+func TestExecuteTools_TruncatesLargeResults(t *testing.T) {
+	store := &stubConversationStore{}
+	orchestrator := NewHarnessOrchestrator(&StubProvider{}, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		store, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+
+	large := `{"entries":"` + strings.Repeat("x", 5000) + `"}`
+	req := &Request{
+		Conversation: &Conversation{ID: "truncate-conv"},
+		Tools: []ports.Tool{
+			&StubTool{name: "fs_metadata", schema: `{}`, result: large},
+			&StubTool{name: "echo", schema: `{}`, result: "small"},
+		},
+		Policy: (&Policy{MaxToolResultBytes: 1024}).WithDefaults(),
+	}
+	calls := []ports.ToolCall{
+		{Name: "fs_metadata", Args: json.RawMessage(`{}`)},
+		{Name: "echo", Args: json.RawMessage(`{}`)},
+	}
+
+	outputs, err := orchestrator.executeTools(context.Background(), req, calls)
+	assert.NoError(t, err)
+	if !assert.Len(t, outputs, 2) {
+		return
+	}
+	assert.Equal(t, large[:1024]+fmt.Sprintf("...[truncated %d bytes]", len(large)-1024), outputs[0])
+	assert.Equal(t, "small", outputs[1])
+
+	// Only the truncated result is archived, in full
+	artifacts := store.turns["truncate-conv"]
+	if assert.Len(t, artifacts, 1) {
+		assert.Equal(t, large, artifacts[0].Content)
+	}
+
+	// ExplicitZero disables the cap
+	store.turns = nil
+	req.Policy = (&Policy{MaxToolResultBytes: ExplicitZero}).WithDefaults()
+	outputs, err = orchestrator.executeTools(context.Background(), req, calls[:1])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{large}, outputs)
+	assert.Empty(t, store.turns)
+}
+
+// failingTool implements Tool and always fails after an optional delay.
+type failingTool struct {
+	name  string
+	delay time.Duration
+}
+
+func (t *failingTool) Name() string   { return t.name }
+func (t *failingTool) Schema() []byte { return []byte(`{}`) }
+func (t *failingTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	time.Sleep(t.delay)
+	return nil, errors.New("tool exploded")
+}
+
+// artifactStore keeps tool artifacts per conversation so they can be read back.
+type artifactStore struct {
+	noOpStore
+	mu        sync.Mutex
+	artifacts map[string]map[string][]byte
+}
+
+func (s *artifactStore) AppendToolArtifact(ctx context.Context, conversationID, name string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.artifacts == nil {
+		s.artifacts = make(map[string]map[string][]byte)
+	}
+	if s.artifacts[conversationID] == nil {
+		s.artifacts[conversationID] = make(map[string][]byte)
+	}
+	s.artifacts[conversationID][name] = payload
+	return nil
+}
+
+func (s *artifactStore) LoadToolArtifact(ctx context.Context, conversationID, name string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	payload, ok := s.artifacts[conversationID][name]
+	if !ok {
+		return nil, ports.ErrArtifactNotFound
+	}
+	return payload, nil
+}
+
+// TestExecuteTools_FetchTruncatedArtifact tests that a truncated result names its artifact and
+// the full output can be fetched back with fetch_artifact, but only from the same conversation.
+func TestExecuteTools_FetchTruncatedArtifact(t *testing.T) {
+	store := &artifactStore{}
+	orchestrator := NewHarnessOrchestrator(&StubProvider{}, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		store, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+
+	large := `{"entries":"` + strings.Repeat("y", 5000) + `"}`
+	req := &Request{
+		Conversation: &Conversation{ID: "artifact-conv"},
+		Tools: []ports.Tool{
+			&StubTool{name: "fs_metadata", schema: `{}`, result: large},
+			tools.NewFetchArtifactTool(store),
+		},
+		Policy: (&Policy{MaxToolResultBytes: 1024}).WithDefaults(),
+	}
+
+	outputs, err := orchestrator.executeTools(context.Background(), req, []ports.ToolCall{{Name: "fs_metadata", Args: json.RawMessage(`{}`)}})
+	assert.NoError(t, err)
+	if !assert.Len(t, outputs, 1) {
+		return
+	}
+	assert.True(t, strings.HasPrefix(outputs[0], large[:1024]+fmt.Sprintf("...[truncated %d bytes]", len(large)-1024)))
+
+	// The marker tells the model which artifact to fetch
+	match := regexp.MustCompile(`fetch_artifact name="([^"]+)"`).FindStringSubmatch(outputs[0])
+	if !assert.Len(t, match, 2) {
+		return
+	}
+	name := match[1]
+	assert.True(t, strings.HasPrefix(name, "fs_metadata-"))
+
+	fetch := ports.ToolCall{Name: "fetch_artifact", Args: json.RawMessage(fmt.Sprintf(`{"name": %q}`, name))}
+	outputs, err = orchestrator.executeTools(context.Background(), req, []ports.ToolCall{fetch})
+	assert.NoError(t, err)
+	if assert.Len(t, outputs, 1) {
+		var page tools.ArtifactPage
+		assert.NoError(t, json.Unmarshal([]byte(outputs[0]), &page))
+		assert.Equal(t, large, page.Content)
+		assert.Equal(t, len(large), page.TotalBytes)
+		assert.Zero(t, page.NextOffset)
+	}
+
+	// A byte range pages through the artifact
+	ranged := ports.ToolCall{Name: "fetch_artifact", Args: json.RawMessage(fmt.Sprintf(`{"name": %q, "offset": 2, "limit": 8}`, name))}
+	outputs, err = orchestrator.executeTools(context.Background(), req, []ports.ToolCall{ranged})
+	assert.NoError(t, err)
+	if assert.Len(t, outputs, 1) {
+		var page tools.ArtifactPage
+		assert.NoError(t, json.Unmarshal([]byte(outputs[0]), &page))
+		assert.Equal(t, large[2:10], page.Content)
+		assert.Equal(t, 10, page.NextOffset)
+	}
+
+	// Another conversation cannot see the artifact
+	other := *req
+	other.Conversation = &Conversation{ID: "other-conv"}
+	_, err = orchestrator.executeTools(context.Background(), &other, []ports.ToolCall{fetch})
+	assert.ErrorContains(t, err, "no artifact named")
+}
+
+// recordingObserver implements ToolCallObserver and counts forwarded samples.
+type recordingObserver struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (r *recordingObserver) ObserveToolCall(name string, duration time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.calls == nil {
+		r.calls = make(map[string]int)
+	}
+	r.calls[name]++
+}
+
+// TestExecuteTools_RecordsToolMetrics tests per-tool counters and latency samples.
+func TestExecuteTools_RecordsToolMetrics(t *testing.T) {
+	orchestrator := NewHarnessOrchestrator(&StubProvider{}, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	observer := &recordingObserver{}
+	orchestrator.ToolMetrics().SetObserver(observer)
+
+	req := &Request{Tools: []ports.Tool{
+		&StubTool{name: "echo", schema: `{}`, result: "ok"},
+		&failingTool{name: "flaky", delay: 30 * time.Millisecond},
+	}}
+	calls := []ports.ToolCall{
+		{Name: "echo", Args: json.RawMessage(`{}`)},
+		{Name: "echo", Args: json.RawMessage(`{}`)},
+		{Name: "flaky", Args: json.RawMessage(`{}`)},
+		{Name: "missing", Args: json.RawMessage(`{}`)},
+	}
+
+	_, err := orchestrator.executeTools(context.Background(), req, calls)
+	assert.Error(t, err)
+
+	sum := func(buckets []int64) int64 {
+		var n int64
+		for _, b := range buckets {
+			n += b
+		}
+		return n
+	}
+
+	echo, ok := orchestrator.ToolMetrics().Stats("echo")
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(2), echo.Calls)
+		assert.Equal(t, int64(2), echo.Successes)
+		assert.Equal(t, int64(0), echo.Failures)
+		assert.Equal(t, int64(2), sum(echo.Buckets))
+	}
+
+	flaky, ok := orchestrator.ToolMetrics().Stats("flaky")
+	if assert.True(t, ok) {
+		assert.Equal(t, int64(1), flaky.Calls)
+		assert.Equal(t, int64(0), flaky.Successes)
+		assert.Equal(t, int64(1), flaky.Failures)
+		assert.GreaterOrEqual(t, flaky.MaxLatency, 30*time.Millisecond)
+		assert.Equal(t, flaky.MaxLatency, flaky.MeanLatency())
+		// 30ms is past the 5ms and 25ms bounds
+		assert.Zero(t, flaky.Buckets[0]+flaky.Buckets[1])
+		assert.Equal(t, int64(1), sum(flaky.Buckets))
+	}
+
+	// Unknown tools are not recorded
+	_, ok = orchestrator.ToolMetrics().Stats("missing")
+	assert.False(t, ok)
+	assert.Len(t, orchestrator.ToolMetrics().Snapshot(), 2)
+	assert.Equal(t, map[string]int{"echo": 2, "flaky": 1}, observer.calls)
+}
+
+// memoryAuditSink implements AuditSink in memory.
+type memoryAuditSink struct {
+	mu      sync.Mutex
+	records []ports.ToolInvocation
+}
+
+func (s *memoryAuditSink) RecordToolInvocation(ctx context.Context, invocation ports.ToolInvocation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, invocation)
+	return nil
+}
+
+func (s *memoryAuditSink) ListToolInvocations(ctx context.Context, filter ports.AuditFilter) ([]ports.ToolInvocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var matched []ports.ToolInvocation
+	for _, inv := range s.records {
+		if (filter.ConversationID == "" || inv.ConversationID == filter.ConversationID) &&
+			(filter.Tool == "" || inv.Tool == filter.Tool) {
+			matched = append(matched, inv)
+		}
+	}
+	return matched, nil
+}
+
+// TestExecuteTools_AuditsInvocations tests that every tool call produces a redacted audit record.
+func TestExecuteTools_AuditsInvocations(t *testing.T) {
+	orchestrator := NewHarnessOrchestrator(&StubProvider{}, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	sink := &memoryAuditSink{}
+	orchestrator.SetAuditSink(sink, nil, false)
+
+	req := &Request{
+		Conversation: &Conversation{ID: "audit-conv"},
+		Tools:        []ports.Tool{&StubTool{name: "echo", schema: `{}`, result: "ok"}},
+	}
+	calls := []ports.ToolCall{
+		{Name: "echo", Args: json.RawMessage(`{"query":"quarterly notes","api_key":"abc123","note":"my password is hunter2"}`)},
+		{Name: "missing", Args: json.RawMessage(`{}`)},
+	}
+
+	before := time.Now()
+	_, err := orchestrator.executeTools(context.Background(), req, calls)
+	assert.ErrorIs(t, err, ErrUnknownTool)
+
+	echo, err := sink.ListToolInvocations(context.Background(), ports.AuditFilter{ConversationID: "audit-conv", Tool: "echo"})
+	assert.NoError(t, err)
+	if assert.Len(t, echo, 1) {
+		record := echo[0]
+		assert.NotEmpty(t, record.ID)
+		assert.Equal(t, "audit-conv", record.ConversationID)
+		assert.JSONEq(t, `{"query":"quarterly notes","api_key":"[REDACTED]","note":"[REDACTED]"}`, record.Args)
+		sum := sha256.Sum256([]byte("ok"))
+		assert.Equal(t, hex.EncodeToString(sum[:]), record.ResultHash)
+		assert.Empty(t, record.Result, "results are kept as hashes by default")
+		assert.Empty(t, record.Error)
+		assert.False(t, record.StartedAt.Before(before))
+		assert.GreaterOrEqual(t, record.Duration, time.Duration(0))
+	}
+
+	missing, err := sink.ListToolInvocations(context.Background(), ports.AuditFilter{Tool: "missing"})
+	assert.NoError(t, err)
+	if assert.Len(t, missing, 1) {
+		assert.Contains(t, missing[0].Error, "unknown tool")
+		assert.Empty(t, missing[0].ResultHash)
+	}
+
+	// Stored results are redacted too
+	orchestrator.SetAuditSink(sink, nil, true)
+	req.Tools = []ports.Tool{&StubTool{name: "leak", schema: `{}`, result: "secret: s3cr3t"}}
+	_, err = orchestrator.executeTools(context.Background(), req, []ports.ToolCall{{Name: "leak", Args: json.RawMessage(`{}`)}})
+	assert.NoError(t, err)
+	leak, err := sink.ListToolInvocations(context.Background(), ports.AuditFilter{Tool: "leak"})
+	assert.NoError(t, err)
+	if assert.Len(t, leak, 1) {
+		assert.Equal(t, "[REDACTED]", leak[0].Result)
+	}
+}
+
+// concatSummarizer implements Summarizer by joining message contents.
+type concatSummarizer struct {
+	calls int
+}
+
+func (s *concatSummarizer) Summarize(ctx context.Context, messages []ports.PromptMessage) (string, error) {
+	s.calls++
+	parts := make([]string, len(messages))
+	for i, msg := range messages {
+		parts[i] = strings.TrimPrefix(msg.Content, summaryPrefix)
+	}
+	return strings.Join(parts, " | "), nil
+}
+
+// TestHarnessOrchestrator_ConversationRollover tests that a long tool loop keeps the conversation
+// bounded and carries older turns forward through the summary.
+func TestHarnessOrchestrator_ConversationRollover(t *testing.T) {
+	const toolRounds = 8
+	const limit = 4
+
+	maxPromptMessages := 0
+	calls := 0
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			calls++
+			maxPromptMessages = max(maxPromptMessages, len(in.Messages))
+			if calls <= toolRounds {
+				return ports.Completion{
+					Text:      fmt.Sprintf("round %d", calls),
+					ToolCalls: []ports.ToolCall{{Name: "echo", Args: json.RawMessage(`{}`)}},
+				}, nil
+			}
+			return ports.Completion{Text: "done"}, nil
+		},
+	}
+	store := &stubConversationStore{}
+	summarizer := &concatSummarizer{}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		store, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	orchestrator.SetSummarizer(summarizer)
+
+	req := &Request{
+		Conversation: &Conversation{ID: "rollover-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "find the config file"}}},
+		Tools:        []ports.Tool{&StubTool{name: "echo", schema: `{}`, result: "ok"}},
+		Policy:       &Policy{MaxIterations: toolRounds + 1, MaxToolDepth: toolRounds, MaxConversationMessages: limit},
+	}
+
+	resp, err := orchestrator.Orchestrate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp.Text)
+
+	// Every prompt and the final conversation stay within the limit
+	assert.LessOrEqual(t, maxPromptMessages, limit)
+	assert.Len(t, req.Conversation.Messages, limit)
+	assert.Greater(t, summarizer.calls, 1)
+
+	// The original request survives every rollover inside the summary
+	summary := req.Conversation.Messages[0]
+	assert.Equal(t, "system", summary.Role)
+	assert.True(t, strings.HasPrefix(summary.Content, summaryPrefix))
+	assert.Contains(t, summary.Content, "find the config file")
+	assert.Contains(t, summary.Content, "round 1")
+
+	// Each summary is persisted, followed by the final assistant turn
+	turns := store.turns["rollover-conv"]
+	if assert.Len(t, turns, summarizer.calls+1) {
+		assert.Equal(t, "system", turns[0].Role)
+		assert.Equal(t, summary.Content, turns[len(turns)-2].Content)
+		assert.Equal(t, "assistant", turns[len(turns)-1].Role)
+	}
+}
+
+// shortSummarizer implements Summarizer with a one-line summary.
+type shortSummarizer struct {
+	calls int
+}
+
+func (s *shortSummarizer) Summarize(ctx context.Context, messages []ports.PromptMessage) (string, error) {
+	s.calls++
+	return fmt.Sprintf("%d messages looked up", len(messages)), nil
+}
+
+// TestHarnessOrchestrator_ShedsToolResultsToFitContextWindow tests that a long tool loop sheds the
+// oldest tool results so every prompt fits the context window with the system prompt and question intact.
+func TestHarnessOrchestrator_ShedsToolResultsToFitContextWindow(t *testing.T) {
+	const toolRounds = 10
+	const system = "You are a careful file assistant."
+	const question = "find the config file"
+
+	run := func(t *testing.T, summarizer ports.Summarizer) (*Request, *recordingTracer) {
+		builder := NewBudgetedPromptBuilder(300, 50, nil)
+		calls := 0
+		provider := &StubProvider{
+			completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+				calls++
+				assert.LessOrEqual(t, builder.EstimateTokens(in), 250, "prompt %d", calls)
+				assert.Equal(t, system, in.System)
+				if assert.NotEmpty(t, in.Messages) {
+					assert.Equal(t, ports.PromptMessage{Role: "user", Content: question}, in.Messages[0])
+				}
+				if calls > 1 {
+					assert.Equal(t, "tool", in.Messages[len(in.Messages)-1].Role, "latest result kept in prompt %d", calls)
+				}
+				if calls <= toolRounds {
+					return ports.Completion{
+						Text:      fmt.Sprintf("round %d", calls),
+						ToolCalls: []ports.ToolCall{{Name: "lookup", Args: json.RawMessage(`{}`)}},
+					}, nil
+				}
+				return ports.Completion{Text: "done"}, nil
+			},
+		}
+		tracer := &recordingTracer{}
+		orchestrator := NewHarnessOrchestrator(provider, builder, NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, tracer)
+		if summarizer != nil {
+			orchestrator.SetSummarizer(summarizer)
+		}
+
+		req := &Request{
+			System:       system,
+			Conversation: &Conversation{ID: "shed-conv", Messages: []ports.PromptMessage{{Role: "user", Content: question}}},
+			Tools:        []ports.Tool{&StubTool{name: "lookup", schema: `{}`, result: strings.Repeat("x", 200)}},
+			Policy:       &Policy{MaxIterations: toolRounds + 1, MaxToolDepth: toolRounds},
+		}
+		resp, err := orchestrator.Orchestrate(context.Background(), req)
+		assert.NoError(t, err)
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, "done", resp.Text)
+		}
+		assert.Equal(t, toolRounds+1, calls)
+		return req, tracer
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		req, tracer := run(t, nil)
+
+		// A single note counts every dropped result
+		var notes []string
+		for _, msg := range req.Conversation.Messages {
+			if _, synthetic := shedToolResultCount(msg); synthetic {
+				notes = append(notes, msg.Content)
+			}
+		}
+		events := tracer.find("tool_results_shed")
+		if assert.Len(t, notes, 1) && assert.NotEmpty(t, events) {
+			last := events[len(events)-1].attrs
+			assert.Equal(t, fmt.Sprintf(toolResultsNote, last["tool_results"]), notes[0])
+			assert.Equal(t, false, last["summarized"])
+			assert.LessOrEqual(t, last["tokens_after"], last["budget"])
+		}
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		summarizer := &shortSummarizer{}
+		req, tracer := run(t, summarizer)
+
+		assert.Greater(t, summarizer.calls, 0)
+		events := tracer.find("tool_results_shed")
+		if assert.Len(t, events, summarizer.calls) {
+			assert.Equal(t, true, events[0].attrs["summarized"])
+		}
+		var summaries int
+		for _, msg := range req.Conversation.Messages {
+			if strings.HasPrefix(msg.Content, toolResultsSummaryPrefix) {
+				summaries++
+			}
+		}
+		assert.Equal(t, 1, summaries)
+	})
+}
+
+// stoppingProvider emits tokens one at a time and halts after the first stop sequence,
+// including the sequence itself as many backends do. It records the options it received.
+type stoppingProvider struct {
+	tokens   []string
+	received []ports.Options
+}
+
+func (p *stoppingProvider) generate(opts ports.Options) []string {
+	p.received = append(p.received, opts)
+	var text string
+	for i, token := range p.tokens {
+		text += token
+		for _, stop := range opts.Stop {
+			if strings.Contains(text, stop) {
+				return p.tokens[:i+1]
+			}
+		}
+	}
+	return p.tokens
+}
+
+func (p *stoppingProvider) Complete(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+	return ports.Completion{Text: strings.Join(p.generate(opts), "")}, nil
+}
+
+func (p *stoppingProvider) Stream(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+	tokens := p.generate(opts)
+	ch := make(chan ports.CompletionChunk, len(tokens))
+	for i, token := range tokens {
+		ch <- ports.CompletionChunk{DeltaText: token, Done: i == len(tokens)-1}
+	}
+	close(ch)
+	return ch, nil
+}
+
+// TestHarnessOrchestrator_StopSequences tests that generation halts at a request stop sequence,
+// the sequence is stripped, and the per-request token cap reaches the provider.
+func TestHarnessOrchestrator_StopSequences(t *testing.T) {
+	tokens := []string{`{"answer": 42}`, "<|e", "nd|>", " trailing chatter"}
+	newRequest := func() *Request {
+		return &Request{
+			Conversation:  &Conversation{ID: "stop-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Answer in JSON"}}},
+			StopSequences: []string{"<|end|>"},
+			MaxNewTokens:  64,
+		}
+	}
+	newOrchestrator := func(provider ports.Provider) *HarnessOrchestrator {
+		return NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	}
+
+	t.Run("complete", func(t *testing.T) {
+		provider := &stoppingProvider{tokens: tokens}
+		resp, err := newOrchestrator(provider).Orchestrate(context.Background(), newRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, `{"answer": 42}`, resp.Text)
+		if assert.Len(t, provider.received, 1) {
+			assert.Equal(t, []string{"<|end|>"}, provider.received[0].Stop)
+			assert.Equal(t, 64, provider.received[0].MaxNewTokens)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		provider := &stoppingProvider{tokens: tokens}
+		respCh, errCh := newOrchestrator(provider).StreamOrchestrate(context.Background(), newRequest())
+		var text string
+		for resp := range respCh {
+			text = resp.Text
+		}
+		assert.NoError(t, <-errCh)
+		assert.Equal(t, `{"answer": 42}`, text)
+	})
+
+	t.Run("unset keeps defaults", func(t *testing.T) {
+		provider := &stoppingProvider{tokens: tokens}
+		req := newRequest()
+		req.StopSequences, req.MaxNewTokens = nil, 0
+		resp, err := newOrchestrator(provider).Orchestrate(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Join(tokens, ""), resp.Text)
+		assert.Nil(t, provider.received[0].Stop)
+		assert.Equal(t, DefaultOptions().MaxNewTokens, provider.received[0].MaxNewTokens)
+	})
+
+	t.Run("aggregator ignores text after the stop", func(t *testing.T) {
+		aggregator := newStreamingAggregator("STOP")
+		for _, delta := range []string{"keep ", "this ST", "OP drop", " and this"} {
+			aggregator.addChunk(ports.CompletionChunk{DeltaText: delta})
+		}
+		assert.Equal(t, "keep this ", aggregator.finalize().Text)
+	})
+}
+
+func TestRecordingTracer_RecordsToolRun(t *testing.T) {
+	calls := 0
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			calls++
+			switch calls {
+			case 1:
+				return ports.Completion{Text: "searching", ToolCalls: []ports.ToolCall{{Name: "search", Args: json.RawMessage(`{"q":"config"}`)}}}, nil
+			case 2:
+				return ports.Completion{Text: "fetching", ToolCalls: []ports.ToolCall{{Name: "fetch", Args: json.RawMessage(`{"id":7}`)}}}, nil
+			default:
+				return ports.Completion{Text: "done"}, nil
+			}
+		},
+	}
+
+	tracer := NewRecordingTracer(nil)
+	tracePath := filepath.Join(t.TempDir(), "trace.json")
+	tracer.PersistTo(tracePath)
+
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, tracer)
+	resp, err := orchestrator.Orchestrate(context.Background(), &Request{
+		Conversation: &Conversation{ID: "trace-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Find the config"}}},
+		Tools: []ports.Tool{
+			&StubTool{name: "search", schema: `{}`, result: "config.yaml api_key=sk-live-123"},
+			&StubTool{name: "fetch", schema: `{}`, result: "contents"},
+		},
+		Policy: &Policy{MaxToolDepth: 3, MaxIterations: 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "done", resp.Text)
+
+	// Provider calls and tool invocations are recorded in execution order
+	timeline := tracer.Timeline()
+	var steps []string
+	for i, entry := range timeline {
+		assert.Equal(t, i+1, entry.Seq)
+		if entry.Kind != TraceSpanStart {
+			continue
+		}
+		switch entry.Name {
+		case "provider_call":
+			steps = append(steps, "provider")
+		case "tool_call":
+			steps = append(steps, "tool:"+entry.Attrs["tool"].(string))
+		}
+	}
+	assert.Equal(t, []string{"provider", "tool:search", "provider", "tool:fetch", "provider"}, steps)
+
+	// Payloads are captured and secrets are redacted
+	var results, completions []TraceEntry
+	for _, entry := range timeline {
+		switch entry.Name {
+		case "tool_result":
+			results = append(results, entry)
+		case "provider_completion":
+			completions = append(completions, entry)
+		}
+	}
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, "config.yaml [REDACTED]", results[0].Attrs["result"])
+		assert.Equal(t, "contents", results[1].Attrs["result"])
+	}
+	if assert.Len(t, completions, 3) {
+		assert.Equal(t, "searching", completions[0].Attrs["text"])
+		assert.Equal(t, "done", completions[2].Attrs["text"])
+	}
+
+	// The persisted JSON replays to the same timeline
+	var buf bytes.Buffer
+	assert.NoError(t, tracer.WriteJSON(&buf))
+	assert.NotContains(t, buf.String(), "sk-live-123")
+	replayed, err := ReadTraceTimeline(&buf)
+	assert.NoError(t, err)
+	assert.Len(t, replayed, len(timeline))
+
+	file, err := os.Open(tracePath)
+	if assert.NoError(t, err) {
+		defer file.Close()
+		persisted, err := ReadTraceTimeline(file)
+		assert.NoError(t, err)
+		assert.Len(t, persisted, len(timeline))
+	}
+}
+
+// TestReplayProvider_ReplaysRecordedRun records a tool-calling run and replays it without the live provider.
+func TestReplayProvider_ReplaysRecordedRun(t *testing.T) {
+	calls := 0
+	live := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			calls++
+			switch calls {
+			case 1:
+				return ports.Completion{Text: "searching", ToolCalls: []ports.ToolCall{{Name: "search", Args: json.RawMessage(`{"q":"config"}`)}}}, nil
+			default:
+				return ports.Completion{Text: "found it"}, nil
+			}
+		},
+	}
+	newRequest := func(question string) *Request {
+		return &Request{
+			Conversation: &Conversation{ID: "replay-conv", Messages: []ports.PromptMessage{{Role: "user", Content: question}}},
+			Tools:        []ports.Tool{&StubTool{name: "search", schema: `{}`, result: "config.yaml"}},
+			Policy:       &Policy{MaxToolDepth: 3, MaxIterations: 5},
+		}
+	}
+	newOrchestrator := func(provider ports.Provider, tracer ports.Tracer) *HarnessOrchestrator {
+		return NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, tracer)
+	}
+
+	// Record the live run
+	tracer := NewRecordingTracer(nil)
+	tracePath := filepath.Join(t.TempDir(), "run.json")
+	tracer.PersistTo(tracePath)
+	recorded, err := newOrchestrator(live, tracer).Orchestrate(context.Background(), newRequest("Find the config"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Replay the persisted run against a fresh orchestrator
+	replay, err := LoadReplayProvider(tracePath)
+	if !assert.NoError(t, err) {
+		return
+	}
+	replayTracer := NewRecordingTracer(nil)
+	replayed, err := newOrchestrator(replay, replayTracer).Orchestrate(context.Background(), newRequest("Find the config"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls, "replay must not call the live provider")
+	assert.Equal(t, recorded.Text, replayed.Text)
+	assert.Equal(t, recorded.ToolCalls, replayed.ToolCalls)
+	assert.Equal(t, 0, replay.Unserved())
+
+	// The replayed run takes the same steps
+	steps := func(timeline []TraceEntry) []string {
+		var names []string
+		for _, entry := range timeline {
+			if entry.Kind == TraceSpanStart {
+				names = append(names, entry.Name)
+			}
+		}
+		return names
+	}
+	assert.Equal(t, steps(tracer.Timeline()), steps(replayTracer.Timeline()))
+
+	// A prompt that was never recorded fails loudly
+	_, err = newOrchestrator(replay, NewRecordingTracer(nil)).Orchestrate(context.Background(), newRequest("Something else"))
+	assert.ErrorIs(t, err, ErrNoRecordedResponse)
+	assert.ErrorIs(t, err, ErrProviderFailed)
+
+	// Timelines recorded without prompt hashes cannot be replayed
+	_, err = NewReplayProvider([]TraceEntry{{Kind: TraceSpanStart, Name: "provider_call", SpanID: 1}})
+	assert.Error(t, err)
+}
+
+// countingTool counts its invocations.
+type countingTool struct {
+	StubTool
+	calls atomic.Int32
+}
+
+func (t *countingTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	t.calls.Add(1)
+	return t.result, nil
+}
+
+// TestHarnessOrchestrator_IdempotencyKey tests that keyed duplicates replay the recorded response.
+func TestHarnessOrchestrator_IdempotencyKey(t *testing.T) {
+	var completions atomic.Int32
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			n := completions.Add(1)
+			if in.Messages[len(in.Messages)-1].Role != "tool" {
+				return ports.Completion{Text: "charging", ToolCalls: []ports.ToolCall{{Name: "charge", Args: json.RawMessage(`{"amount":10}`)}}}, nil
+			}
+			return ports.Completion{Text: fmt.Sprintf("charged (completion %d)", n), Usage: &ports.Usage{TotalTokens: 7}}, nil
+		},
+	}
+	tool := &countingTool{StubTool: StubTool{name: "charge", schema: `{}`, result: "ok"}}
+	store := &testConversationStore{}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		store, adapters.NewLRUCache(100), &noOpRateLimiter{}, &noOpTracer{})
+
+	newRequest := func(key string) *Request {
+		return &Request{
+			Conversation:   &Conversation{ID: "pay-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Charge $10"}}},
+			Tools:          []ports.Tool{tool},
+			Policy:         &Policy{BypassCache: true}, // the response cache must not be what dedupes
+			IdempotencyKey: key,
+		}
+	}
+
+	first, err := orchestrator.Orchestrate(context.Background(), newRequest("payment-1"))
+	assert.NoError(t, err)
+	second, err := orchestrator.Orchestrate(context.Background(), newRequest("payment-1"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, "charged (completion 2)", second.Text)
+	assert.Equal(t, int32(1), tool.calls.Load())
+	assert.Len(t, store.turns["pay-conv"], 1)
+
+	// A different key runs again
+	third, err := orchestrator.Orchestrate(context.Background(), newRequest("payment-2"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Text, third.Text)
+	assert.Equal(t, int32(2), tool.calls.Load())
+
+	// Concurrent duplicates share a single run
+	var wg sync.WaitGroup
+	responses := make([]*Response, 5)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := orchestrator.Orchestrate(context.Background(), newRequest("payment-3"))
+			assert.NoError(t, err)
+			responses[i] = resp
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), tool.calls.Load())
+	for _, resp := range responses[1:] {
+		assert.Equal(t, responses[0], resp)
+	}
+}
+
+// TestHarnessOrchestrator_IdempotencyWithoutCache tests that keyed duplicates are deduped when
+// the response cache is disabled, until the idempotency TTL expires.
+func TestHarnessOrchestrator_IdempotencyWithoutCache(t *testing.T) {
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			if in.Messages[len(in.Messages)-1].Role != "tool" {
+				return ports.Completion{Text: "charging", ToolCalls: []ports.ToolCall{{Name: "charge", Args: json.RawMessage(`{"amount":10}`)}}}, nil
+			}
+			return ports.Completion{Text: "charged"}, nil
+		},
+	}
+	tool := &countingTool{StubTool: StubTool{name: "charge", schema: `{}`, result: "ok"}}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&testConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+	now := time.Now()
+	orchestrator.idempotency.now = func() time.Time { return now }
+
+	newRequest := func() *Request {
+		return &Request{
+			Conversation:   &Conversation{ID: "pay-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Charge $10"}}},
+			Tools:          []ports.Tool{tool},
+			Policy:         &Policy{IdempotencyTTL: time.Hour},
+			IdempotencyKey: "payment-1",
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := orchestrator.Orchestrate(context.Background(), newRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, "charged", resp.Text)
+	}
+	assert.Equal(t, int32(1), tool.calls.Load())
+
+	// Once the TTL has passed the key runs again
+	now = now.Add(time.Hour)
+	_, err := orchestrator.Orchestrate(context.Background(), newRequest())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), tool.calls.Load())
+}
+
+// budgetTool declares its own timeout and reports the budget it was given.
+type budgetTool struct {
+	StubTool
+	timeout time.Duration
+	block   bool // wait for the deadline instead of returning at once
+}
+
+func (t *budgetTool) Timeout() time.Duration { return t.timeout }
+
+func (t *budgetTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return "no deadline", nil
+	}
+	if t.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return time.Until(deadline).String(), nil
+}
+
+// TestExecuteTools_PerToolTimeout tests that tool-declared timeouts override the policy default.
+func TestExecuteTools_PerToolTimeout(t *testing.T) {
+	quick := &budgetTool{StubTool: StubTool{name: "metadata_lookup", schema: `{}`}, timeout: 50 * time.Millisecond}
+	slow := &budgetTool{StubTool: StubTool{name: "web_search", schema: `{}`}, timeout: 10 * time.Minute}
+	plain := &budgetTool{StubTool: StubTool{name: "plain", schema: `{}`}} // no declared timeout
+	expiring := &budgetTool{StubTool: StubTool{name: "expiring", schema: `{}`}, timeout: 10 * time.Millisecond, block: true}
+
+	orchestrator := &HarnessOrchestrator{}
+	req := &Request{
+		Tools:  []ports.Tool{quick, slow, plain, expiring},
+		Policy: (&Policy{ToolTimeout: 2 * time.Second}).WithDefaults(),
+	}
+	dispatcher := orchestrator.newToolDispatcher(context.Background(), req)
+	for _, name := range []string{"metadata_lookup", "web_search", "plain", "expiring"} {
+		dispatcher.start(ports.ToolCall{Name: name, Args: json.RawMessage(`{}`)})
+	}
+	_, err := dispatcher.wait()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	budget := func(i int) time.Duration {
+		d, err := time.ParseDuration(dispatcher.results[i].content)
+		assert.NoError(t, err, dispatcher.results[i].content)
+		return d
+	}
+	assert.LessOrEqual(t, budget(0), 50*time.Millisecond)
+	assert.Greater(t, budget(1), 9*time.Minute)
+	assert.LessOrEqual(t, budget(2), 2*time.Second)
+	assert.Greater(t, budget(2), time.Second)
+
+	var toolErr *ErrToolFailed
+	assert.ErrorAs(t, dispatcher.results[3].err, &toolErr)
+	assert.Equal(t, "expiring", toolErr.Name)
+
+	// ExplicitZero disables the policy deadline but declared timeouts still apply
+	req.Policy = (&Policy{ToolTimeout: ExplicitZero}).WithDefaults()
+	dispatcher = orchestrator.newToolDispatcher(context.Background(), req)
+	dispatcher.start(ports.ToolCall{Name: "plain", Args: json.RawMessage(`{}`)})
+	dispatcher.start(ports.ToolCall{Name: "metadata_lookup", Args: json.RawMessage(`{}`)})
+	outputs, err := dispatcher.wait()
+	assert.NoError(t, err)
+	assert.Equal(t, "no deadline", outputs[0])
+	assert.LessOrEqual(t, budget(1), 50*time.Millisecond)
+}
+
+// TestExtractJSON tests pulling the first JSON value out of messy model output.
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		found bool
+	}{
+		{name: "bare object", input: `{"a": 1}`, want: `{"a": 1}`, found: true},
+		{name: "prose wrapped", input: `Sure! Here it is: {"a": 1} Let me know.`, want: `{"a": 1}`, found: true},
+		{name: "fenced", input: "```json\n[1, 2, 3]\n```", want: `[1, 2, 3]`, found: true},
+		{name: "nested objects", input: `result: {"a": {"b": [{"c": 2}]}, "d": []} done`, want: `{"a": {"b": [{"c": 2}]}, "d": []}`, found: true},
+		{name: "braces in strings", input: `x {"text": "a } b { c ]"} y`, want: `{"text": "a } b { c ]"}`, found: true},
+		{name: "escaped quotes", input: `{"q": "say \"}\" now"}`, want: `{"q": "say \"}\" now"}`, found: true},
+		{name: "skips bracketed prose", input: `See [note] then {"ok": true}`, want: `{"ok": true}`, found: true},
+		{name: "first of several", input: `{"first": 1} and {"second": 2}`, want: `{"first": 1}`, found: true},
+		{name: "unterminated", input: `{"a": 1`, found: false},
+		{name: "no json", input: "just prose", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExtractJSON(tt.input)
+			assert.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Equal(t, tt.want, string(got))
+			}
+		})
+	}
+}
+
+// TestGuardrails_ValidateJSONOutputExtractsPayload tests schema validation of prose-wrapped JSON.
+func TestGuardrails_ValidateJSONOutputExtractsPayload(t *testing.T) {
+	guardrails := NewGuardrails()
+	schema := []byte(`{"type": "object", "required": ["name"]}`)
+
+	assert.NoError(t, guardrails.ValidateJSONOutput(json.RawMessage("Result:\n```json\n{\"name\": \"x\"}\n```"), schema))
+	assert.Error(t, guardrails.ValidateJSONOutput(json.RawMessage(`Result: {"other": 1}`), schema))
+}
+
+// TestStreamingAggregator_IncrementalToolCalls streams a large response in small chunks and
+// checks every call is detected once, as soon as it closes, with each byte scanned once.
+func TestStreamingAggregator_IncrementalToolCalls(t *testing.T) {
+	var text strings.Builder
+	var closes []int // offset just past each call's closing bracket
+	var want []string
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&text, "Step %d: thinking about {braces} and \"quotes\" in prose. ", i)
+		switch i % 3 {
+		case 0:
+			fmt.Fprintf(&text, `[{"name": "search", "arguments": {"i": %d, "q": "a } b { c"}}]`, i)
+		case 1:
+			fmt.Fprintf(&text, `lookup({"i": %d, "nested": {"path": "x\"}y"}})`, i)
+		case 2:
+			fmt.Fprintf(&text, `{"tool_calls": [{"function": {"name": "fetch", "arguments": "{\"i\": %d}"}}]}`, i)
+		}
+		closes = append(closes, strings.LastIndexAny(text.String(), "]}")+1)
+		want = append(want, fmt.Sprintf("%d", i))
+		text.WriteString(" done.\n")
+	}
+	full := text.String()
+
+	aggregator := newStreamingAggregator()
+	var got []ports.ToolCall
+	for offset := 0; offset < len(full); offset += 7 {
+		end := min(offset+7, len(full))
+		aggregator.addChunk(ports.CompletionChunk{DeltaText: full[offset:end]})
+		got = append(got, aggregator.getEarlyToolCalls()...)
+
+		expected := 0
+		for _, closeAt := range closes {
+			if closeAt <= end {
+				expected++
+			}
+		}
+		if !assert.Len(t, got, expected, "calls detected after %d bytes", end) {
+			return
+		}
+	}
+	completion := aggregator.finalize()
+	assert.Empty(t, aggregator.getEarlyToolCalls(), "finalize must not re-emit calls")
+
+	if !assert.Len(t, completion.ToolCalls, len(want)) {
+		return
+	}
+	names := []string{"search", "lookup", "fetch"}
+	for i, call := range completion.ToolCalls {
+		assert.Equal(t, names[i%3], call.Name)
+		var args struct {
+			I int `json:"i"`
+		}
+		assert.NoError(t, json.Unmarshal(call.Args, &args))
+		assert.Equal(t, want[i], fmt.Sprintf("%d", args.I))
+	}
+	assert.Equal(t, full, completion.Text)
+
+	// Every byte is examined exactly once regardless of chunking
+	assert.Equal(t, len(full), aggregator.scanner.scanned)
+}
+
+// TestStreamingAggregator_StopSpanningChunks checks a stop sequence split across chunks
+// truncates the text and suppresses calls after it.
+func TestStreamingAggregator_StopSpanningChunks(t *testing.T) {
+	aggregator := newStreamingAggregator("<|end|>")
+	for _, chunk := range []string{"answer tool({\"a\": 1}) then <|e", "nd|> ignored({\"b\": 2})"} {
+		aggregator.addChunk(ports.CompletionChunk{DeltaText: chunk})
+	}
+	completion := aggregator.finalize()
+	assert.Equal(t, "answer tool({\"a\": 1}) then ", completion.Text)
+	if assert.Len(t, completion.ToolCalls, 1) {
+		assert.Equal(t, "tool", completion.ToolCalls[0].Name)
+	}
+}
+
+// TestStreamingAggregator_Reset tests that reset drops all state from the previous call.
+func TestStreamingAggregator_Reset(t *testing.T) {
+	aggregator := newStreamingAggregator()
+	aggregator.addChunk(ports.CompletionChunk{
+		DeltaText: `first lookup({"q": 1})`,
+		Usage:     &ports.Usage{TotalTokens: 7},
+	})
+	aggregator.finalize()
+
+	aggregator.reset("<|end|>")
+	assert.Empty(t, aggregator.getText())
+	assert.Empty(t, aggregator.getToolCalls())
+	assert.Empty(t, aggregator.getEarlyToolCalls())
+	assert.Nil(t, aggregator.getUsage())
+
+	aggregator.addChunk(ports.CompletionChunk{DeltaText: `second search({"q": 2})<|end|> dropped`})
+	completion := aggregator.finalize()
+	assert.Equal(t, `second search({"q": 2})`, completion.Text)
+	if assert.Len(t, completion.ToolCalls, 1) {
+		assert.Equal(t, "search", completion.ToolCalls[0].Name)
+	}
+}
+
+// sampleToolSpecs is a small tool set for the schema formatter tests.
+func sampleToolSpecs() []ports.ToolSpec {
+	return []ports.ToolSpec{
+		{
+			Name:        "search_files",
+			Description: "Find files by name",
+			JSONSchema:  []byte(`{"type": "object", "properties": {"query": {"type": "string"}}, "required": ["query"]}`),
+		},
+		{Name: "list_roots"},
+	}
+}
+
+// fileInfo is a structured tool output used by the tool result format tests.
+type fileInfo struct {
+	XMLName xml.Name `json:"-" xml:"tool_result"`
+	Path    string   `json:"path" xml:"path"`
+	Size    int      `json:"size" xml:"size"`
+	Tags    []string `json:"tags" xml:"tags>item"`
+}
+
+// structTool returns a fileInfo, optionally in its own declared result format.
+type structTool struct {
+	StubTool
+	output fileInfo
+	format string
+}
+
+func (t *structTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	return t.output, nil
+}
+
+func (t *structTool) ResultFormat() string { return t.format }
+
+// TestHarnessOrchestrator_ToolResultFormat tests that a struct tool output is rendered in
+// the configured format, or the tool's own, and reaches the conversation intact.
+func TestHarnessOrchestrator_ToolResultFormat(t *testing.T) {
+	output := fileInfo{Path: "docs/a & b.md", Size: 42, Tags: []string{"draft", "go"}}
+	run := func(t *testing.T, format, toolFormat string) string {
+		var prompts []ports.PromptInput
+		provider := &StubProvider{completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			prompts = append(prompts, in)
+			if len(prompts) > 1 {
+				return ports.Completion{Text: "done"}, nil
+			}
+			return ports.Completion{ToolCalls: []ports.ToolCall{{Name: "stat", Args: json.RawMessage(`{}`)}}}, nil
+		}}
+		orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &recordingTracer{})
+		if format != "" {
+			assert.NoError(t, orchestrator.SetToolResultFormat(format))
+		}
+
+		tool := &structTool{StubTool: StubTool{name: "stat", schema: `{"type": "object"}`}, output: output, format: toolFormat}
+		_, err := orchestrator.Orchestrate(context.Background(), &Request{
+			Conversation: &Conversation{ID: "format-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Stat it"}}},
+			Tools:        []ports.Tool{tool},
+		})
+		assert.NoError(t, err)
+		if !assert.Len(t, prompts, 2) {
+			return ""
+		}
+		last := prompts[1].Messages[len(prompts[1].Messages)-1]
+		assert.Equal(t, "tool", last.Role)
+		return last.Content
+	}
+
+	t.Run("json is the default", func(t *testing.T) {
+		content := run(t, "", "")
+		assert.Equal(t, `{"path":"docs/a \u0026 b.md","size":42,"tags":["draft","go"]}`, content)
+		var decoded fileInfo
+		assert.NoError(t, json.Unmarshal([]byte(content), &decoded))
+		assert.Equal(t, output, decoded)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		assert.Equal(t, "path: docs/a & b.md\nsize: 42\ntags:\n  - draft\n  - go", run(t, "text", ""))
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		content := run(t, "XML", "")
+		assert.Equal(t, `<tool_result name="stat"><path>docs/a &amp; b.md</path><size>42</size>`+
+			`<tags><item>draft</item><item>go</item></tags></tool_result>`, content)
+		var decoded fileInfo
+		assert.NoError(t, xml.Unmarshal([]byte(content), &decoded))
+		decoded.XMLName = xml.Name{}
+		assert.Equal(t, output, decoded)
+	})
+
+	t.Run("tool override", func(t *testing.T) {
+		assert.Equal(t, "path: docs/a & b.md\nsize: 42\ntags:\n  - draft\n  - go", run(t, "xml", "text"))
+		// An unknown declared format falls back to the orchestrator's
+		assert.Contains(t, run(t, "xml", "yaml"), `<tool_result name="stat">`)
+	})
+
+	orchestrator := NewHarnessOrchestrator(nil, nil, nil, nil, nil, nil, nil)
+	assert.Error(t, orchestrator.SetToolResultFormat("yaml"))
+}
+
+// TestToolSchemaFormatters tests the structure each formatter emits for a sample tool set.
+func TestToolSchemaFormatters(t *testing.T) {
+	t.Run("openai", func(t *testing.T) {
+		formatter, ok := ToolSchemaFormatterFor("openai")
+		assert.True(t, ok)
+		assert.Equal(t, ToolSchemaWire, formatter.Placement())
+
+		out, err := formatter.Format(sampleToolSpecs())
+		assert.NoError(t, err)
+
+		var tools []struct {
+			Type     string `json:"type"`
+			Function struct {
+				Name        string          `json:"name"`
+				Description string          `json:"description"`
+				Parameters  json.RawMessage `json:"parameters"`
+			} `json:"function"`
+		}
+		assert.NoError(t, json.Unmarshal(out, &tools))
+		if assert.Len(t, tools, 2) {
+			assert.Equal(t, "function", tools[0].Type)
+			assert.Equal(t, "search_files", tools[0].Function.Name)
+			assert.Equal(t, "Find files by name", tools[0].Function.Description)
+			assert.JSONEq(t, `{"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`, string(tools[0].Function.Parameters))
+			assert.Equal(t, "list_roots", tools[1].Function.Name)
+			assert.JSONEq(t, `{"type":"object","properties":{}}`, string(tools[1].Function.Parameters))
+		}
+	})
+
+	t.Run("inline", func(t *testing.T) {
+		formatter, ok := ToolSchemaFormatterFor("inline")
+		assert.True(t, ok)
+		assert.Equal(t, ToolSchemaInline, formatter.Placement())
+
+		out, err := formatter.Format(sampleToolSpecs())
+		assert.NoError(t, err)
+		text := string(out)
+		assert.Contains(t, text, `- search_files: Find files by name
+  Parameters: {"type":"object","properties":{"query":{"type":"string"}},"required":["query"]}`)
+		assert.Contains(t, text, "- list_roots\n  Parameters: {\"type\":\"object\",\"properties\":{}}")
+		assert.Contains(t, text, `[{"name": "<tool name>", "arguments": {<parameters>}}]`)
+	})
+
+	t.Run("invalid schema", func(t *testing.T) {
+		specs := []ports.ToolSpec{{Name: "broken", JSONSchema: []byte(`{"type":`)}}
+		for _, formatter := range []ToolSchemaFormatter{OpenAIToolSchemaFormatter{}, InlineToolSchemaFormatter{}} {
+			_, err := formatter.Format(specs)
+			assert.ErrorContains(t, err, `tool "broken" has an invalid JSON schema`)
+		}
+	})
+
+	_, ok := ToolSchemaFormatterFor("xml")
+	assert.False(t, ok)
+}
+
+// TestPromptBuilder_ToolFormatter tests that the builder places formatted tools in the prompt.
+func TestPromptBuilder_ToolFormatter(t *testing.T) {
+	messages := func() []ports.PromptMessage { return []ports.PromptMessage{{Role: "user", Content: "Find the report"}} }
+
+	builder := &PromptBuilder{ToolFormatter: OpenAIToolSchemaFormatter{}}
+	wire := builder.Build("You are helpful.", messages(), nil, sampleToolSpecs(), nil)
+	assert.Equal(t, "You are helpful.", wire.System)
+	assert.Len(t, wire.Tools, 2)
+	assert.True(t, json.Valid(wire.ToolSchema))
+	assert.Equal(t, builder.count(wire.System)+builder.count(wire.Messages[0].Content)+messageOverheadTokens+builder.count(string(wire.ToolSchema)),
+		builder.EstimateTokens(wire), "the serialized schema is counted instead of the specs")
+
+	builder.ToolFormatter = InlineToolSchemaFormatter{}
+	inline := builder.Build("You are helpful.", messages(), nil, sampleToolSpecs(), nil)
+	assert.True(t, strings.HasPrefix(inline.System, "You are helpful.\n\nYou can call the following tools:"))
+	assert.Contains(t, inline.System, "- search_files: Find files by name")
+	assert.Empty(t, inline.Tools)
+	assert.Nil(t, inline.ToolSchema)
+
+	// Formatting failures leave the specs in place
+	broken := builder.Build("", messages(), nil, []ports.ToolSpec{{Name: "broken", JSONSchema: []byte("{")}}, nil)
+	assert.Len(t, broken.Tools, 1)
+	assert.Contains(t, broken.Meta["tool_schema_error"], "invalid JSON schema")
+}
+
+// TestHarnessOrchestrator_MaxToolCalls tests that only the best proposed tool calls run.
+func TestHarnessOrchestrator_MaxToolCalls(t *testing.T) {
+	newTools := func() []*countingTool {
+		var tools []*countingTool
+		for _, name := range []string{"alpha", "beta", "gamma"} {
+			tools = append(tools, &countingTool{StubTool: StubTool{name: name, schema: `{"type": "object"}`, result: name + " done"}})
+		}
+		return tools
+	}
+	run := func(t *testing.T, provider ports.Provider, policy *Policy, stream bool) ([]*countingTool, *recordingTracer) {
+		tools := newTools()
+		req := &Request{
+			Conversation: &Conversation{ID: "nbest-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Look it up"}}},
+			Tools:        []ports.Tool{tools[0], tools[1], tools[2]},
+			Policy:       policy,
+		}
+		tracer := &recordingTracer{}
+		orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+			&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, tracer)
+
+		if stream {
+			respCh, errCh := orchestrator.StreamOrchestrate(context.Background(), req)
+			for range respCh {
+			}
+			assert.NoError(t, <-errCh)
+		} else {
+			_, err := orchestrator.Orchestrate(context.Background(), req)
+			assert.NoError(t, err)
+		}
+		return tools, tracer
+	}
+	callCounts := func(tools []*countingTool) []int32 {
+		return []int32{tools[0].calls.Load(), tools[1].calls.Load(), tools[2].calls.Load()}
+	}
+
+	// Three structured calls on the first turn, then a final answer
+	structured := func() ports.Provider {
+		turn := 0
+		return &StubProvider{completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			turn++
+			if turn > 1 {
+				return ports.Completion{Text: "final"}, nil
+			}
+			return ports.Completion{ToolCalls: []ports.ToolCall{
+				{Name: "alpha", Args: json.RawMessage(`{}`)},
+				{Name: "beta", Args: json.RawMessage(`{}`)},
+				{Name: "gamma", Args: json.RawMessage(`{}`)},
+			}}, nil
+		}}
+	}
+
+	t.Run("default runs every call", func(t *testing.T) {
+		tools, _ := run(t, structured(), nil, false)
+		assert.Equal(t, []int32{1, 1, 1}, callCounts(tools))
+	})
+
+	t.Run("top-1 by order", func(t *testing.T) {
+		tools, tracer := run(t, structured(), &Policy{MaxToolCalls: 1}, false)
+		assert.Equal(t, []int32{1, 0, 0}, callCounts(tools))
+		if discarded := tracer.find("tool_calls_discarded"); assert.Len(t, discarded, 1) {
+			assert.Equal(t, []string{"beta", "gamma"}, discarded[0].attrs["discarded"])
+		}
+	})
+
+	proposals := `[{"name": "alpha", "arguments": {}, "confidence": 0.2}, ` +
+		`{"name": "beta", "arguments": {}, "confidence": 0.9}, ` +
+		`{"name": "gamma", "arguments": {}, "confidence": 0.5}]`
+
+	t.Run("top-1 by parsed confidence", func(t *testing.T) {
+		turn := 0
+		provider := &StubProvider{completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			turn++
+			if turn > 1 {
+				return ports.Completion{Text: "final"}, nil
+			}
+			return ports.Completion{Text: "Candidates: " + proposals}, nil
+		}}
+		tools, _ := run(t, provider, &Policy{MaxToolCalls: 1}, false)
+		assert.Equal(t, []int32{0, 1, 0}, callCounts(tools))
+	})
+
+	t.Run("stream defers dispatch until every proposal is in", func(t *testing.T) {
+		turn := 0
+		provider := &StubProvider{streamFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+			turn++
+			text := "final"
+			if turn == 1 {
+				text = proposals
+			}
+			ch := make(chan ports.CompletionChunk, len(text))
+			for i := 0; i < len(text); i += 8 {
+				ch <- ports.CompletionChunk{DeltaText: text[i:min(i+8, len(text))]}
+			}
+			ch <- ports.CompletionChunk{Done: true}
+			close(ch)
+			return ch, nil
+		}}
+		tools, _ := run(t, provider, &Policy{MaxToolCalls: 2}, true)
+		assert.Equal(t, []int32{0, 1, 1}, callCounts(tools))
+	})
+}
+
+// TestHarnessOrchestrator_DryRunTools tests that dry-run mode surfaces planned tool calls
+// without invoking any tool, and that a model that never stops calling tools still terminates.
+func TestHarnessOrchestrator_DryRunTools(t *testing.T) {
+	var prompts []ports.PromptInput
+	call := ports.ToolCall{Name: "alpha", Args: json.RawMessage(`{"q": "x"}`)}
+	provider := &StubProvider{
+		completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+			prompts = append(prompts, in)
+			return ports.Completion{Text: "calling", ToolCalls: []ports.ToolCall{call}}, nil
+		},
+		streamFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+			prompts = append(prompts, in)
+			ch := make(chan ports.CompletionChunk, 1)
+			ch <- ports.CompletionChunk{DeltaText: "calling", ToolCalls: []ports.ToolCall{call}, Done: true}
+			close(ch)
+			return ch, nil
+		},
+	}
+	newRequest := func(tool ports.Tool) *Request {
+		return &Request{
+			Conversation: &Conversation{ID: "dry-run-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Plan it"}}},
+			Tools:        []ports.Tool{tool},
+			Policy:       &Policy{DryRunTools: true, MaxToolDepth: 2},
+		}
+	}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &recordingTracer{})
+
+	t.Run("orchestrate", func(t *testing.T) {
+		prompts = nil
+		tool := &countingTool{StubTool: StubTool{name: "alpha", schema: `{"type": "object"}`, result: "executed"}}
+		resp, err := orchestrator.Orchestrate(context.Background(), newRequest(tool))
+		assert.NoError(t, err)
+		assert.Zero(t, tool.calls.Load())
+		if assert.NotNil(t, resp) {
+			assert.Equal(t, []ports.ToolCall{call, call, call}, resp.ToolCalls)
+		}
+
+		// Each planned call is answered with a synthetic result; the depth limit ends the run
+		assert.Len(t, prompts, 3)
+		last := prompts[len(prompts)-1].Messages
+		assert.Equal(t, ports.PromptMessage{Role: "tool", Content: "alpha: not executed (dry-run)"}, last[len(last)-1])
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		prompts = nil
+		tool := &countingTool{StubTool: StubTool{name: "alpha", schema: `{"type": "object"}`, result: "executed"}}
+		respCh, errCh := orchestrator.StreamOrchestrate(context.Background(), newRequest(tool))
+		var surfaced []ports.ToolCall
+		for resp := range respCh {
+			surfaced = append(surfaced, resp.ToolCalls...)
+		}
+		assert.NoError(t, <-errCh)
+		assert.Zero(t, tool.calls.Load())
+		assert.Equal(t, []ports.ToolCall{call, call, call}, surfaced)
+		assert.Len(t, prompts, 3)
+	})
+}
+
+// TestHarnessOrchestrator_RateLimitWait tests that the policy chooses between waiting for a
+// rate limit permit and failing immediately.
+func TestHarnessOrchestrator_RateLimitWait(t *testing.T) {
+	limiter := adapters.NewTokenBucket(1, 200*time.Millisecond)
+	provider := &StubProvider{completionFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+		return ports.Completion{Text: "done"}, nil
+	}}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, limiter, &noOpTracer{})
+	newRequest := func(policy *Policy) *Request {
+		return &Request{
+			Conversation: &Conversation{ID: "rate-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Hello"}}},
+			Policy:       policy,
+		}
+	}
+
+	// Drain the bucket so the next orchestration finds it empty
+	_, err := limiter.Acquire(context.Background(), "orchestrate")
+	assert.NoError(t, err)
+
+	_, err = orchestrator.Orchestrate(context.Background(), newRequest(&Policy{}))
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := orchestrator.Orchestrate(ctx, newRequest(&Policy{RateLimitWait: true}))
+	if assert.NoError(t, err) {
+		assert.Equal(t, "done", resp.Text)
+	}
+}
+
+// TestHarnessOrchestrator_StreamIterationsAggregatedIndependently tests that each streamed
+// provider call is aggregated on its own, so a later turn never repeats an earlier one.
+func TestHarnessOrchestrator_StreamIterationsAggregatedIndependently(t *testing.T) {
+	turn := 0
+	provider := &StubProvider{streamFunc: func(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+		turn++
+		ch := make(chan ports.CompletionChunk, 2)
+		if turn == 1 {
+			ch <- ports.CompletionChunk{DeltaText: "Checking the index. "}
+			ch <- ports.CompletionChunk{
+				ToolCalls: []ports.ToolCall{{Name: "lookup", Args: json.RawMessage(`{}`)}},
+				Usage:     &ports.Usage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14},
+				Done:      true,
+			}
+		} else {
+			ch <- ports.CompletionChunk{DeltaText: "Found it."}
+			ch <- ports.CompletionChunk{Usage: &ports.Usage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}, Done: true}
+		}
+		close(ch)
+		return ch, nil
+	}}
+	orchestrator := NewHarnessOrchestrator(provider, NewPromptBuilder(), NewContextAssembler(Budget{MaxContextTokens: 1000}, nil),
+		&stubConversationStore{}, &noOpCache{}, &noOpRateLimiter{}, &noOpTracer{})
+
+	req := &Request{
+		Conversation: &Conversation{ID: "stream-iter-conv", Messages: []ports.PromptMessage{{Role: "user", Content: "Where is it?"}}},
+		Tools:        []ports.Tool{&StubTool{name: "lookup", schema: `{"type": "object"}`, result: "in the index"}},
+	}
+	respCh, errCh := orchestrator.StreamOrchestrate(context.Background(), req)
+	var responses []*Response
+	for resp := range respCh {
+		responses = append(responses, resp)
+	}
+	assert.NoError(t, <-errCh)
+
+	if assert.Len(t, responses, 2) {
+		assert.Equal(t, "Checking the index. ", responses[0].Text)
+		assert.Len(t, responses[0].ToolCalls, 1)
+
+		assert.Equal(t, "Found it.", responses[1].Text)
+		assert.NotContains(t, responses[1].Text, "Checking")
+		assert.Empty(t, responses[1].ToolCalls)
+		if assert.NotNil(t, responses[1].Usage) {
+			assert.Equal(t, 23, responses[1].Usage.TotalTokens)
+		}
+	}
+}
+
+// TestHTTPProvider tests request shaping and response parsing against a mock
+// OpenAI-compatible server, for both plain and streamed completions.
+func TestHTTPProvider(t *testing.T) {
+	var requests []map[string]any
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		var decoded map[string]any
+		assert.NoError(t, json.Unmarshal(body, &decoded))
+		requests = append(requests, decoded)
+		headers = append(headers, r.Header.Clone())
+
+		if decoded["stream"] == true {
+			w.Header().Set("Content-Type", "text/event-stream")
+			events := []string{
+				`{"choices":[{"delta":{"role":"assistant","content":"Hel"}}]}`,
+				`{"choices":[{"delta":{"content":"lo"}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"search","arguments":"{\"q\":"}}]}}]}`,
+				`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go\"}"}}]},"finish_reason":"tool_calls"}]}`,
+				`{"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+				`[DONE]`,
+			}
+			for _, event := range events {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			return
+		}
+		if decoded["model"] == "broken" {
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Hello",`+
+			`"tool_calls":[{"id":"c1","type":"function","function":{"name":"search","arguments":"{\"q\":\"go\"}"}}]},`+
+			`"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`)
+	}))
+	defer server.Close()
+
+	provider, err := adapters.NewHTTPProvider(adapters.HTTPProviderConfig{BaseURL: server.URL + "/v1/", Model: "local-model", APIKey: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, ToolSchemaFormatOpenAI, provider.ToolSchemaFormat())
+
+	in := ports.PromptInput{
+		System:   "Be brief.",
+		Messages: []ports.PromptMessage{{Role: "user", Content: "Find go"}, {Role: "tool", Content: "3 hits"}},
+		Context:  []string{"Go is a language."},
+		Tools:    []ports.ToolSpec{{Name: "search", Description: "Search docs", JSONSchema: []byte(`{"type":"object"}`)}},
+	}
+	opts := ports.Options{MaxNewTokens: 64, Temperature: 0.5, Seed: 7, Stop: []string{"END"}, ToolChoice: "search"}
+	wantCall := ports.ToolCall{Name: "search", Args: json.RawMessage(`{"q":"go"}`)}
+	wantUsage := &ports.Usage{PromptTokens: 7, CompletionTokens: 3, TotalTokens: 10}
+
+	t.Run("complete", func(t *testing.T) {
+		completion, err := provider.Complete(context.Background(), in, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, "Hello", completion.Text)
+		assert.Equal(t, []ports.ToolCall{wantCall}, completion.ToolCalls)
+		assert.Equal(t, wantUsage, completion.Usage)
+
+		req := requests[len(requests)-1]
+		assert.Equal(t, "Bearer secret", headers[len(headers)-1].Get("Authorization"))
+		assert.Equal(t, "local-model", req["model"])
+		assert.Equal(t, []any{
+			map[string]any{"role": "system", "content": "Be brief.\n\nContext:\nGo is a language."},
+			map[string]any{"role": "user", "content": "Find go"},
+			map[string]any{"role": "user", "content": "Tool result:\n3 hits"},
+		}, req["messages"])
+		assert.Equal(t, []any{map[string]any{"type": "function", "function": map[string]any{
+			"name": "search", "description": "Search docs", "parameters": map[string]any{"type": "object"},
+		}}}, req["tools"])
+		assert.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "search"}}, req["tool_choice"])
+		assert.Equal(t, float64(64), req["max_tokens"])
+		assert.Equal(t, 0.5, req["temperature"])
+		assert.Equal(t, float64(7), req["seed"])
+		assert.Equal(t, []any{"END"}, req["stop"])
+		assert.Nil(t, req["stream"])
+		assert.Nil(t, req["top_p"], "unset options are left to the server")
+		assert.Nil(t, req["response_format"])
+	})
+
+	t.Run("json mode", func(t *testing.T) {
+		assert.Equal(t, ports.ProviderCapabilities{NativeToolCalls: true, Streaming: true, JSONMode: true}, provider.Capabilities())
+		jsonOpts := opts
+		jsonOpts.JSONMode = true
+		_, err := provider.Complete(context.Background(), in, jsonOpts)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"type": "json_object"}, requests[len(requests)-1]["response_format"])
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		ch, err := provider.Stream(context.Background(), in, opts)
+		assert.NoError(t, err)
+		var text strings.Builder
+		var final ports.CompletionChunk
+		for chunk := range ch {
+			text.WriteString(chunk.DeltaText)
+			if chunk.Done {
+				final = chunk
+			}
+		}
+		assert.Equal(t, "Hello", text.String())
+		assert.True(t, final.Done)
+		assert.Equal(t, []ports.ToolCall{wantCall}, final.ToolCalls)
+		assert.Equal(t, wantUsage, final.Usage)
+		assert.Equal(t, true, requests[len(requests)-1]["stream"])
+		assert.Equal(t, "text/event-stream", headers[len(headers)-1].Get("Accept"))
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		broken, err := adapters.NewHTTPProvider(adapters.HTTPProviderConfig{BaseURL: server.URL + "/v1", Model: "broken"})
+		assert.NoError(t, err)
+		_, err = broken.Complete(context.Background(), in, opts)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "503")
+			assert.Contains(t, err.Error(), "model not loaded")
+		}
+		assert.Empty(t, headers[len(headers)-1].Get("Authorization"))
+	})
+
+	_, err = adapters.NewHTTPProvider(adapters.HTTPProviderConfig{})
+	assert.Error(t, err)
+}
+
 // TestStreamingAggregator tests the streaming aggregator functionality.
 func TestStreamingAggregator(t *testing.T) {
 	aggregator := newStreamingAggregator()
@@ -0,0 +1,146 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// idempotencyKeyPrefix namespaces recorded idempotent results.
+const idempotencyKeyPrefix = "idempotency:"
+
+// idempotencyStore keeps recorded idempotent results until their TTL expires. It is kept
+// apart from the response cache, which may be disabled or evict entries early, so a
+// duplicate request can never silently re-run side-effecting tools. The zero value is ready to use.
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+	now     func() time.Time // clock, overridable in tests
+}
+
+// idempotencyEntry is a serialized response and when it stops being replayed.
+type idempotencyEntry struct {
+	data    []byte
+	expires time.Time // zero never expires
+}
+
+// get returns the unexpired result recorded under key.
+func (s *idempotencyStore) get(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !entry.expires.IsZero() && !s.clock().Before(entry.expires) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// put records data under key for ttl (non-positive keeps it indefinitely) and drops expired entries.
+func (s *idempotencyStore) put(key string, data []byte, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock()
+	if s.entries == nil {
+		s.entries = make(map[string]idempotencyEntry)
+	}
+	for k, entry := range s.entries {
+		if !entry.expires.IsZero() && !now.Before(entry.expires) {
+			delete(s.entries, k)
+		}
+	}
+	entry := idempotencyEntry{data: data}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	s.entries[key] = entry
+}
+
+func (s *idempotencyStore) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// idempotentCall is a keyed run in progress; done is closed once resp and err are set.
+type idempotentCall struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// orchestrateOnce returns the result recorded for req.IdempotencyKey, or runs the request
+// and records its result. Concurrent duplicates wait for the run already in progress.
+// Failed runs are not recorded, so a retry with the same key runs again.
+func (o *HarnessOrchestrator) orchestrateOnce(ctx context.Context, req *Request) (*Response, error) {
+	key := idempotencyKeyPrefix + req.IdempotencyKey
+	if resp, ok := o.replayIdempotent(ctx, key); ok {
+		return resp, nil
+	}
+
+	o.idempotencyMu.Lock()
+	if call, ok := o.inflight[key]; ok {
+		o.idempotencyMu.Unlock()
+		o.tracer.Event(ctx, "idempotent_join", map[string]any{"key": req.IdempotencyKey})
+		select {
+		case <-call.done:
+			return call.resp, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	// A run may have finished between the lookup above and taking the lock
+	if resp, ok := o.replayIdempotent(ctx, key); ok {
+		o.idempotencyMu.Unlock()
+		return resp, nil
+	}
+	call := &idempotentCall{done: make(chan struct{})}
+	if o.inflight == nil {
+		o.inflight = make(map[string]*idempotentCall)
+	}
+	o.inflight[key] = call
+	o.idempotencyMu.Unlock()
+
+	// Record before releasing the key so no duplicate can slip in between
+	call.resp, call.err = o.orchestrate(ctx, req)
+	if call.err == nil {
+		o.recordIdempotent(ctx, key, call.resp, req.Policy.IdempotencyTTL)
+	}
+
+	o.idempotencyMu.Lock()
+	delete(o.inflight, key)
+	o.idempotencyMu.Unlock()
+	close(call.done)
+
+	return call.resp, call.err
+}
+
+// replayIdempotent returns the response recorded under key, if any.
+func (o *HarnessOrchestrator) replayIdempotent(ctx context.Context, key string) (*Response, bool) {
+	data, ok := o.idempotency.get(key)
+	if !ok {
+		return nil, false
+	}
+	resp, err := o.parseCachedResponse(data)
+	if err != nil {
+		o.tracer.Event(ctx, "idempotency_error", map[string]any{"error": err.Error(), "key": key})
+		return nil, false
+	}
+	o.tracer.Event(ctx, "idempotent_replay", map[string]any{"key": key})
+	return resp, true
+}
+
+// recordIdempotent stores resp under key for ttl.
+func (o *HarnessOrchestrator) recordIdempotent(ctx context.Context, key string, resp *Response, ttl time.Duration) {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		o.tracer.Event(ctx, "idempotency_error", map[string]any{"error": err.Error(), "key": key})
+		return
+	}
+	o.idempotency.put(key, data, ttl)
+}
@@ -0,0 +1,66 @@
+package harness
+
+import "encoding/json"
+
+// ExtractJSON returns the first balanced JSON object or array embedded in s, such as a
+// value wrapped in prose or a markdown code fence. Braces inside JSON strings, including
+// escaped quotes, do not affect nesting. Candidates that balance but are not valid JSON
+// are skipped, so bracketed prose before the real payload is tolerated. The second
+// result reports whether a value was found.
+func ExtractJSON(s string) ([]byte, bool) {
+	for start := 0; start < len(s); start++ {
+		if s[start] != '{' && s[start] != '[' {
+			continue
+		}
+		end, ok := matchJSONValue(s, start)
+		if !ok {
+			continue
+		}
+		if candidate := []byte(s[start:end]); json.Valid(candidate) {
+			return candidate, true
+		}
+	}
+	return nil, false
+}
+
+// matchJSONValue scans from the opening bracket at start and returns the index just past
+// its matching closing bracket. It reports false when the brackets are mismatched or the
+// value is unterminated.
+func matchJSONValue(s string, start int) (int, bool) {
+	stack := make([]byte, 0, 8)
+	inString := false
+	escaped := false
+
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{':
+			stack = append(stack, '}')
+		case '[':
+			stack = append(stack, ']')
+		case '}', ']':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return 0, false
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,132 @@
+package harness
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ToolLatencyBuckets are the upper bounds of the per-tool latency histogram.
+// Samples slower than the last bound land in an overflow bucket.
+var ToolLatencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	25 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	30 * time.Second,
+}
+
+// ToolCallObserver receives every tool call recorded by ToolMetrics.
+// Implement it to forward samples to an external backend, e.g. a Prometheus
+// counter and histogram labelled by tool name.
+type ToolCallObserver interface {
+	ObserveToolCall(name string, duration time.Duration, err error)
+}
+
+// ToolStats is a snapshot of the metrics recorded for one tool.
+type ToolStats struct {
+	Calls        int64
+	Successes    int64
+	Failures     int64
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	// Buckets counts samples per ToolLatencyBuckets bound; the extra last entry counts overflow.
+	Buckets []int64
+}
+
+// MeanLatency returns the average call duration, or zero before any call.
+func (s ToolStats) MeanLatency() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Calls)
+}
+
+// ToolMetrics aggregates call counts and latency histograms per tool name.
+// It is safe for concurrent use.
+type ToolMetrics struct {
+	mu       sync.Mutex
+	tools    map[string]*ToolStats
+	observer ToolCallObserver
+}
+
+// NewToolMetrics creates an empty collector.
+func NewToolMetrics() *ToolMetrics {
+	return &ToolMetrics{tools: make(map[string]*ToolStats)}
+}
+
+// SetObserver registers an observer that is called for every recorded tool call.
+func (m *ToolMetrics) SetObserver(observer ToolCallObserver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observer = observer
+}
+
+// Observe records one tool call; a non-nil err counts as a failure.
+func (m *ToolMetrics) Observe(name string, duration time.Duration, err error) {
+	m.mu.Lock()
+	stats, ok := m.tools[name]
+	if !ok {
+		stats = &ToolStats{Buckets: make([]int64, len(ToolLatencyBuckets)+1)}
+		m.tools[name] = stats
+	}
+
+	stats.Calls++
+	if err != nil {
+		stats.Failures++
+	} else {
+		stats.Successes++
+	}
+	stats.TotalLatency += duration
+	if duration > stats.MaxLatency {
+		stats.MaxLatency = duration
+	}
+	bucket := sort.Search(len(ToolLatencyBuckets), func(i int) bool { return duration <= ToolLatencyBuckets[i] })
+	stats.Buckets[bucket]++
+
+	observer := m.observer
+	m.mu.Unlock()
+
+	if observer != nil {
+		observer.ObserveToolCall(name, duration, err)
+	}
+}
+
+// Stats returns a snapshot for one tool and whether it has been called.
+func (m *ToolMetrics) Stats(name string) (ToolStats, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.tools[name]
+	if !ok {
+		return ToolStats{}, false
+	}
+	return stats.clone(), true
+}
+
+// Snapshot returns a copy of the stats of every tool called so far.
+func (m *ToolMetrics) Snapshot() map[string]ToolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]ToolStats, len(m.tools))
+	for name, stats := range m.tools {
+		snapshot[name] = stats.clone()
+	}
+	return snapshot
+}
+
+// Reset clears all recorded stats.
+func (m *ToolMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tools = make(map[string]*ToolStats)
+}
+
+func (s *ToolStats) clone() ToolStats {
+	c := *s
+	c.Buckets = append([]int64(nil), s.Buckets...)
+	return c
+}
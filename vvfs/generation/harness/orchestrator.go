@@ -2,10 +2,16 @@ package harness
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
 )
@@ -23,9 +29,26 @@ type Request struct {
 	Context      []string
 	Tools        []ports.Tool
 	Policy       *Policy
+	Options      *ports.Options // sampling overrides; zero fields keep the orchestrator defaults
+	// StopSequences end each completion at the first match, which is stripped from the text.
+	// When set they replace Options.Stop.
+	StopSequences []string
+	// MaxNewTokens caps each completion; when set it replaces Options.MaxNewTokens.
+	MaxNewTokens int
+	// IdempotencyKey dedupes repeated submissions: a successful result is recorded under the
+	// key for Policy.IdempotencyTTL and returned to later requests with the same key without
+	// re-running tools or persisting turns again.
+	IdempotencyKey string
+	// AllowedTools further restricts Tools for this request. The effective tool set is the
+	// intersection of Tools, the orchestrator's global allowlist and this list; tools outside
+	// it are left out of the prompt and calls to them fail with ErrToolNotAllowed.
+	// Empty applies no request-level restriction.
+	AllowedTools []string
 }
 
 // Policy controls orchestration behavior.
+// Zero-valued numeric fields are unset and take the DefaultPolicy value (see WithDefaults);
+// use ExplicitZero to request a real zero, e.g. RetryCount: ExplicitZero for no retries.
 type Policy struct {
 	MaxToolDepth      int           // max recursive tool calls
 	MaxIterations     int           // safeguard against infinite loops
@@ -34,21 +57,136 @@ type Policy struct {
 	Deterministic     bool          // seed for reproducible results
 	RetryCount        int           // provider call retries
 	RetryBackoff      time.Duration // base delay between retries
+	BypassCache       bool          // drop any cached response, run fresh, then cache the new result
+	CacheWriteOnly    bool          // skip cache lookups but keep populating the cache
+	// MaxToolResultBytes caps each tool result placed in the prompt; the full result is
+	// stored as a tool artifact. ExplicitZero disables the cap.
+	MaxToolResultBytes int
+	// MaxConversationMessages bounds Conversation.Messages; once exceeded the oldest messages
+	// are rolled into a single summary message by the orchestrator's Summarizer. Zero disables.
+	MaxConversationMessages int
+	// IdempotencyTTL is how long a result is kept for requests with an IdempotencyKey.
+	IdempotencyTTL time.Duration
+	// MaxToolCalls runs only the best N tool calls proposed in a turn; the rest are discarded
+	// and traced. Calls are ranked by Confidence when the model reports one, otherwise by
+	// order. Zero runs every call.
+	MaxToolCalls int
+	// RateLimitWait waits for a rate limit permit, up to the context deadline, instead of
+	// failing as soon as the limiter is exhausted. Limiters that cannot wait fail as usual.
+	RateLimitWait bool
+	// DryRunTools plans tool calls without executing them: each call is answered with a
+	// synthetic "not executed (dry-run)" result and every planned call is returned in
+	// Response.ToolCalls. Reaching MaxToolDepth ends the run instead of failing it.
+	DryRunTools bool
 }
 
 // DefaultPolicy returns sensible defaults.
 func DefaultPolicy() *Policy {
 	return &Policy{
-		MaxToolDepth:      3,
-		MaxIterations:     10,
-		ToolTimeout:       30 * time.Second,
-		RequireJSONOutput: false,
-		Deterministic:     false,
-		RetryCount:        2,
-		RetryBackoff:      100 * time.Millisecond,
+		MaxToolDepth:       3,
+		MaxIterations:      10,
+		ToolTimeout:        30 * time.Second,
+		RequireJSONOutput:  false,
+		Deterministic:      false,
+		RetryCount:         2,
+		RetryBackoff:       100 * time.Millisecond,
+		MaxToolResultBytes: 16 * 1024,
+		IdempotencyTTL:     24 * time.Hour,
 	}
 }
 
+// ExplicitZero marks a numeric Policy field as intentionally zero rather than unset.
+const ExplicitZero = -1
+
+// WithDefaults returns a copy of p with unset fields filled from DefaultPolicy.
+// Fields set to ExplicitZero (or any negative value) resolve to zero. A nil policy
+// yields DefaultPolicy.
+func (p *Policy) WithDefaults() *Policy {
+	def := DefaultPolicy()
+	if p == nil {
+		return def
+	}
+
+	merged := *p
+	merged.MaxToolDepth = mergeInt(p.MaxToolDepth, def.MaxToolDepth)
+	merged.MaxIterations = mergeInt(p.MaxIterations, def.MaxIterations)
+	merged.RetryCount = mergeInt(p.RetryCount, def.RetryCount)
+	merged.MaxToolResultBytes = mergeInt(p.MaxToolResultBytes, def.MaxToolResultBytes)
+	merged.MaxConversationMessages = mergeInt(p.MaxConversationMessages, def.MaxConversationMessages)
+	merged.MaxToolCalls = mergeInt(p.MaxToolCalls, def.MaxToolCalls)
+	merged.ToolTimeout = mergeDuration(p.ToolTimeout, def.ToolTimeout)
+	merged.RetryBackoff = mergeDuration(p.RetryBackoff, def.RetryBackoff)
+	merged.IdempotencyTTL = mergeDuration(p.IdempotencyTTL, def.IdempotencyTTL)
+	return &merged
+}
+
+func mergeInt(v, def int) int {
+	switch {
+	case v < 0:
+		return 0
+	case v == 0:
+		return def
+	default:
+		return v
+	}
+}
+
+func mergeDuration(v, def time.Duration) time.Duration {
+	switch {
+	case v < 0:
+		return 0
+	case v == 0:
+		return def
+	default:
+		return v
+	}
+}
+
+// DefaultOptions returns the sampling options used when neither config nor request set them.
+func DefaultOptions() ports.Options {
+	return ports.Options{
+		MaxNewTokens: 1024,
+		Temperature:  0.7,
+		TopP:         0.9,
+	}
+}
+
+// mergeOptions returns base with every non-zero field of override applied on top.
+func mergeOptions(base ports.Options, override *ports.Options) ports.Options {
+	if override == nil {
+		return base
+	}
+	merged := base
+	if override.MaxNewTokens != 0 {
+		merged.MaxNewTokens = override.MaxNewTokens
+	}
+	if override.Temperature != 0 {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != 0 {
+		merged.TopP = override.TopP
+	}
+	if override.MinP != 0 {
+		merged.MinP = override.MinP
+	}
+	if override.RepetitionPenalty != 0 {
+		merged.RepetitionPenalty = override.RepetitionPenalty
+	}
+	if override.Seed != 0 {
+		merged.Seed = override.Seed
+	}
+	if override.Stop != nil {
+		merged.Stop = override.Stop
+	}
+	if override.ToolChoice != "" {
+		merged.ToolChoice = override.ToolChoice
+	}
+	if override.TimeoutMs != 0 {
+		merged.TimeoutMs = override.TimeoutMs
+	}
+	return merged
+}
+
 // Response is the final output of the orchestrator.
 type Response struct {
 	Text      string
@@ -58,13 +196,23 @@ type Response struct {
 
 // HarnessOrchestrator coordinates the full tool-calling loop.
 type HarnessOrchestrator struct {
-	provider  ports.Provider
-	builder   *PromptBuilder
-	assembler *ContextAssembler
-	store     ports.ConversationStore
-	cache     ports.Cache
-	limiter   ports.RateLimiter
-	tracer    ports.Tracer
+	provider   ports.Provider
+	builder    *PromptBuilder
+	assembler  *ContextAssembler
+	store      ports.ConversationStore
+	cache      ports.Cache
+	limiter    ports.RateLimiter
+	tracer     ports.Tracer
+	options    ports.Options    // default sampling options for every provider call
+	metrics    *ToolMetrics     // per-tool call counts and latency
+	summarizer ports.Summarizer // optional, rolls over conversations past Policy.MaxConversationMessages
+	resultFmt  ToolResultFormat // how non-string tool outputs are rendered into the conversation
+	allowlist  map[string]bool  // global tool allowlist; empty allows every tool
+	auditor    *toolAuditor     // optional, records every tool invocation
+
+	idempotencyMu sync.Mutex
+	inflight      map[string]*idempotentCall // keyed runs in progress, joined by concurrent duplicates
+	idempotency   idempotencyStore           // recorded keyed results, independent of the response cache
 }
 
 // NewHarnessOrchestrator creates a new orchestrator with dependencies.
@@ -85,19 +233,89 @@ func NewHarnessOrchestrator(
 		cache:     cache,
 		limiter:   limiter,
 		tracer:    tracer,
+		options:   DefaultOptions(),
+		metrics:   NewToolMetrics(),
+		resultFmt: ToolResultJSON,
 	}
 }
 
+// SetAllowedTools sets the global tool allowlist applied to every request.
+// An empty list allows every tool a request provides.
+func (o *HarnessOrchestrator) SetAllowedTools(names []string) {
+	o.allowlist = make(map[string]bool, len(names))
+	for _, name := range names {
+		o.allowlist[name] = true
+	}
+}
+
+// SetSummarizer sets the summarizer used to roll over conversations that exceed
+// Policy.MaxConversationMessages. Without one, conversations are never compacted.
+func (o *HarnessOrchestrator) SetSummarizer(summarizer ports.Summarizer) {
+	o.summarizer = summarizer
+}
+
+// ToolMetrics returns the per-tool call and latency metrics recorded by this orchestrator.
+func (o *HarnessOrchestrator) ToolMetrics() *ToolMetrics {
+	return o.metrics
+}
+
+// SetToolResultFormat sets how non-string tool outputs are rendered into the conversation:
+// "json" (the default), "text" or "xml". Tools implementing ports.ResultFormatTool override it.
+func (o *HarnessOrchestrator) SetToolResultFormat(format string) error {
+	resolved, ok := ToolResultFormatFor(format)
+	if !ok {
+		return fmt.Errorf("unknown tool result format %q", format)
+	}
+	o.resultFmt = resolved
+	return nil
+}
+
+// SetDefaultOptions sets the sampling options sent to the provider; zero fields keep DefaultOptions.
+func (o *HarnessOrchestrator) SetDefaultOptions(opts ports.Options) {
+	o.options = mergeOptions(DefaultOptions(), &opts)
+}
+
+// providerOptions resolves the options for one provider call.
+func (o *HarnessOrchestrator) providerOptions(req *Request, iteration int) ports.Options {
+	opts := mergeOptions(o.options, req.Options)
+	if len(req.StopSequences) > 0 {
+		opts.Stop = req.StopSequences
+	}
+	if req.MaxNewTokens > 0 {
+		opts.MaxNewTokens = req.MaxNewTokens
+	}
+	if req.Policy.Deterministic && iteration == 1 {
+		opts.Seed = 42
+	}
+	// Providers without JSON mode rely on the request's own instructions instead
+	opts.JSONMode = req.Policy.RequireJSONOutput && ports.CapabilitiesOf(o.provider).JSONMode
+	return opts
+}
+
+// acquirePermit takes a rate limit permit, waiting for one when the policy allows it.
+func (o *HarnessOrchestrator) acquirePermit(ctx context.Context, policy *Policy) (func(), error) {
+	if waiter, ok := o.limiter.(ports.WaitingRateLimiter); ok && policy.RateLimitWait {
+		return waiter.AcquireWait(ctx, "orchestrate")
+	}
+	return o.limiter.Acquire(ctx, "orchestrate")
+}
+
 // Orchestrate runs the full tool-calling loop to completion.
+// Requests with an IdempotencyKey run at most once per key; see Request.IdempotencyKey.
 func (o *HarnessOrchestrator) Orchestrate(ctx context.Context, req *Request) (*Response, error) {
-	if req.Policy == nil {
-		req.Policy = DefaultPolicy()
+	req.Policy = req.Policy.WithDefaults()
+	if req.IdempotencyKey != "" {
+		return o.orchestrateOnce(ctx, req)
 	}
+	return o.orchestrate(ctx, req)
+}
 
+// orchestrate runs one orchestration with a resolved policy.
+func (o *HarnessOrchestrator) orchestrate(ctx context.Context, req *Request) (*Response, error) {
 	// Acquire rate limit permit
-	release, err := o.limiter.Acquire(ctx, "orchestrate")
+	release, err := o.acquirePermit(ctx, req.Policy)
 	if err != nil {
-		return nil, fmt.Errorf("rate limit exceeded: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrRateLimited, err)
 	}
 	defer release()
 
@@ -108,19 +326,26 @@ func (o *HarnessOrchestrator) Orchestrate(ctx context.Context, req *Request) (*R
 	})
 	defer finish(nil)
 
-	// Try cache first
+	// Try cache first unless the policy forces a fresh run
 	cacheKey := o.buildCacheKey(req)
-	if cached, ok := o.cache.Get(ctx, cacheKey); ok {
-		o.tracer.Event(ctx, "cache_hit", map[string]any{"key": cacheKey})
-		return o.parseCachedResponse(cached)
+	switch {
+	case req.Policy.BypassCache:
+		o.tracer.Event(ctx, "cache_bypass", map[string]any{"key": cacheKey, "mode": "bypass"})
+		if err := o.cache.Delete(ctx, cacheKey); err != nil {
+			o.tracer.Event(ctx, "cache_error", map[string]any{"error": err.Error()})
+		}
+	case req.Policy.CacheWriteOnly:
+		o.tracer.Event(ctx, "cache_bypass", map[string]any{"key": cacheKey, "mode": "write_only"})
+	default:
+		if cached, ok := o.cache.Get(ctx, cacheKey); ok {
+			o.tracer.Event(ctx, "cache_hit", map[string]any{"key": cacheKey})
+			return o.parseCachedResponse(cached)
+		}
 	}
 
 	// Build initial prompt
-	toolSpecs := o.buildToolSpecs(req.Tools)
-	prompt := o.builder.Build(req.System, req.Conversation.Messages, req.Context, toolSpecs, map[string]string{
-		"conversation_id": req.Conversation.ID,
-		"tool_count":      fmt.Sprintf("%d", len(req.Tools)),
-	})
+	o.compactConversation(ctx, req)
+	prompt := o.buildInitialPrompt(req)
 
 	// Run orchestration loop
 	result, err := o.runLoop(ctx, req, prompt)
@@ -147,51 +372,82 @@ func (o *HarnessOrchestrator) Orchestrate(ctx context.Context, req *Request) (*R
 }
 
 // StreamOrchestrate provides streaming orchestration with early tool-call emission.
+// It fails with ErrStreamingUnsupported when the provider cannot stream.
 func (o *HarnessOrchestrator) StreamOrchestrate(ctx context.Context, req *Request) (<-chan *Response, <-chan error) {
 	respCh := make(chan *Response, 10)
 	errCh := make(chan error, 1)
+	req.Policy = req.Policy.WithDefaults()
 
 	go func() {
 		defer close(respCh)
 		defer close(errCh)
 
-		// Run orchestration loop with streaming aggregator
-		aggregator := newStreamingAggregator()
+		if !ports.CapabilitiesOf(o.provider).Streaming {
+			errCh <- ErrStreamingUnsupported
+			return
+		}
 
+		o.compactConversation(ctx, req)
 		currentPrompt := o.buildInitialPrompt(req)
 		iteration := 0
 		depth := 0
+		aggregator := newStreamingAggregator()
 
 		for {
 			iteration++
 			if iteration > req.Policy.MaxIterations {
-				errCh <- fmt.Errorf("max iterations exceeded: %d", req.Policy.MaxIterations)
+				errCh <- fmt.Errorf("%w: %d", ErrMaxIterations, req.Policy.MaxIterations)
 				return
 			}
 
 			// Build provider options
-			opts := ports.Options{
-				MaxNewTokens: 1024,
-				Temperature:  0.7,
-				TopP:         0.9,
-				Seed:         0,
-			}
-			if req.Policy.Deterministic && iteration == 1 {
-				opts.Seed = 42
-			}
+			opts := o.providerOptions(req, iteration)
 
 			// Call provider with streaming
-			streamCh, err := o.provider.Stream(ctx, currentPrompt, opts)
+			callCtx, spanFinish := o.tracer.StartSpan(ctx, "provider_call", map[string]any{
+				"iteration":   iteration,
+				"depth":       depth,
+				"stream":      true,
+				"system":      currentPrompt.System,
+				"messages":    currentPrompt.Messages,
+				"prompt_hash": PromptHash(currentPrompt),
+			})
+			streamCh, err := o.provider.Stream(callCtx, currentPrompt, opts)
 			if err != nil {
-				errCh <- fmt.Errorf("provider stream failed: %w", err)
+				spanFinish(err)
+				errCh <- fmt.Errorf("%w (stream): %w", ErrProviderFailed, err)
 				return
 			}
 
-			// Process stream chunks
-			o.processStream(ctx, streamCh, aggregator)
+			// Process stream chunks, starting tools as soon as their calls are complete
+			// unless this turn would exceed the tool depth. Each call is aggregated on its own.
+			aggregator.reset(opts.Stop...)
+			var dispatcher *toolDispatcher
+			if depth < req.Policy.MaxToolDepth && !req.Policy.DryRunTools {
+				dispatcher = o.newToolDispatcher(ctx, req)
+			}
+			// Selecting the best calls needs every proposal, so it defers dispatch to the end
+			early := dispatcher
+			if req.Policy.MaxToolCalls > 0 {
+				early = nil
+			}
+			o.processStream(callCtx, streamCh, aggregator, early)
 
 			// Check for tool calls in aggregated content
 			toolCalls := aggregator.getToolCalls()
+			if req.Policy.MaxToolCalls > 0 {
+				toolCalls = o.selectToolCalls(callCtx, toolCalls, req.Policy.MaxToolCalls)
+				if dispatcher != nil {
+					for _, call := range toolCalls {
+						dispatcher.start(call)
+					}
+				}
+			}
+			o.tracer.Event(callCtx, "provider_completion", map[string]any{
+				"text":       aggregator.getText(),
+				"tool_calls": toolCalls,
+			})
+			spanFinish(nil)
 			if len(toolCalls) > 0 {
 				// Emit early tool calls for immediate execution
 				respCh <- &Response{
@@ -200,18 +456,28 @@ func (o *HarnessOrchestrator) StreamOrchestrate(ctx context.Context, req *Reques
 					Usage:     aggregator.getUsage(),
 				}
 
+				// A dry run ends at the depth limit; the calls were already emitted above
+				if req.Policy.DryRunTools && depth >= req.Policy.MaxToolDepth {
+					return
+				}
+
 				// Validate tool depth only if we're going to execute tools
 				if depth >= req.Policy.MaxToolDepth {
-					errCh <- fmt.Errorf("max tool depth exceeded: %d", req.Policy.MaxToolDepth)
+					errCh <- fmt.Errorf("%w: %d", ErrMaxToolDepth, req.Policy.MaxToolDepth)
 					return
 				}
 				depth++
 
-				// Execute tools
-				toolResults, err := o.executeTools(ctx, req.Tools, toolCalls)
-				if err != nil {
-					errCh <- fmt.Errorf("tool execution failed: %w", err)
-					return
+				// Join tools started mid-stream
+				var toolResults []string
+				if req.Policy.DryRunTools {
+					toolResults = o.dryRunTools(ctx, toolCalls)
+				} else {
+					toolResults, err = dispatcher.wait()
+					if err != nil {
+						errCh <- fmt.Errorf("tool execution failed: %w", err)
+						return
+					}
 				}
 
 				// Append to conversation and continue loop
@@ -223,9 +489,11 @@ func (o *HarnessOrchestrator) StreamOrchestrate(ctx context.Context, req *Reques
 						ports.PromptMessage{Role: "tool", Content: result},
 					)
 				}
+				o.compactConversation(ctx, req)
+				o.shedToolResults(ctx, req)
 
 				// Rebuild prompt for next iteration
-				currentPrompt = o.builder.Build(req.System, req.Conversation.Messages, req.Context, o.buildToolSpecs(req.Tools), nil)
+				currentPrompt = o.rebuildPrompt(req)
 				continue
 			}
 
@@ -242,74 +510,125 @@ func (o *HarnessOrchestrator) StreamOrchestrate(ctx context.Context, req *Reques
 	return respCh, errCh
 }
 
-// processStream aggregates streaming chunks into a completion.
-func (o *HarnessOrchestrator) processStream(ctx context.Context, streamCh <-chan ports.CompletionChunk, aggregator *streamingAggregator) ports.Completion {
+// processStream aggregates streaming chunks into a completion. Each tool call is handed to
+// the dispatcher (when non-nil) as soon as it is complete, so tools run while the model
+// keeps streaming.
+func (o *HarnessOrchestrator) processStream(ctx context.Context, streamCh <-chan ports.CompletionChunk, aggregator *streamingAggregator, dispatcher *toolDispatcher) ports.Completion {
+	dispatch := func() {
+		if dispatcher == nil {
+			return
+		}
+		for _, call := range aggregator.getEarlyToolCalls() {
+			o.tracer.Event(ctx, "tool_dispatched", map[string]any{"tool": call.Name, "early": !aggregator.done})
+			dispatcher.start(call)
+		}
+	}
+
 	for chunk := range streamCh {
 		aggregator.addChunk(chunk)
-
-		// Check if we have early tool calls to emit
-		if toolCalls := aggregator.getEarlyToolCalls(); len(toolCalls) > 0 {
-			// This would be handled by the streaming aggregator to emit early
-			// For now, we'll continue accumulating until the end
-		}
+		dispatch()
 	}
 
-	return aggregator.finalize()
+	completion := aggregator.finalize()
+	dispatch() // calls only recognized by the final parse
+	return completion
 }
 
 // buildInitialPrompt builds the initial prompt for orchestration.
 func (o *HarnessOrchestrator) buildInitialPrompt(req *Request) ports.PromptInput {
-	toolSpecs := o.buildToolSpecs(req.Tools)
-	return o.builder.Build(req.System, req.Conversation.Messages, req.Context, toolSpecs, map[string]string{
+	toolSpecs := o.buildToolSpecs(o.permittedTools(req))
+	return o.promptBuilder(req).Build(req.System, req.Conversation.Messages, req.Context, toolSpecs, map[string]string{
 		"conversation_id": req.Conversation.ID,
-		"tool_count":      fmt.Sprintf("%d", len(req.Tools)),
+		"tool_count":      fmt.Sprintf("%d", len(toolSpecs)),
 	})
 }
 
+// rebuildPrompt builds the prompt for the next iteration from the updated conversation.
+func (o *HarnessOrchestrator) rebuildPrompt(req *Request) ports.PromptInput {
+	return o.promptBuilder(req).Build(req.System, req.Conversation.Messages, req.Context, o.buildToolSpecs(o.permittedTools(req)), nil)
+}
+
+// promptBuilder returns the prompt builder adapted to the provider's capabilities.
+// Without native tool calls, tools are described in the system prompt so the model can
+// answer with calls the output parser reads. A known context window bounds the prompt
+// when the builder has no budget of its own, reserving the completion's token limit.
+func (o *HarnessOrchestrator) promptBuilder(req *Request) *PromptBuilder {
+	caps := ports.CapabilitiesOf(o.provider)
+	inlineTools := !caps.NativeToolCalls &&
+		(o.builder.ToolFormatter == nil || o.builder.ToolFormatter.Placement() != ToolSchemaInline)
+	budget := o.builder.ContextSize == 0 && caps.ContextWindow > 0
+	if !inlineTools && !budget {
+		return o.builder
+	}
+
+	adapted := *o.builder
+	if inlineTools {
+		adapted.ToolFormatter = InlineToolSchemaFormatter{}
+	}
+	if budget {
+		adapted.ContextSize = caps.ContextWindow
+		if adapted.ReservedTokens == 0 {
+			adapted.ReservedTokens = o.providerOptions(req, 0).MaxNewTokens
+		}
+	}
+	return &adapted
+}
+
 // streamingAggregator accumulates streaming chunks and detects early tool calls.
+// Each detected call is reported exactly once through getEarlyToolCalls.
 type streamingAggregator struct {
 	text          strings.Builder
 	toolCalls     []ports.ToolCall
 	usage         *ports.Usage
-	parser        *OutputParser
+	scanner       *toolCallScanner
 	earlyCalls    []ports.ToolCall
-	partialBuffer strings.Builder // Buffer for incomplete JSON that might span chunks
+	providerCalls bool     // provider emits structured calls; skip text parsing
+	stops         []string // stop sequences; text from the first match on is dropped
+	maxStop       int      // length of the longest stop sequence
+	stopped       bool     // a stop sequence was seen, later text is ignored
+	done          bool
+}
+
+func newStreamingAggregator(stops ...string) *streamingAggregator {
+	a := &streamingAggregator{}
+	a.reset(stops...)
+	return a
 }
 
-func newStreamingAggregator() *streamingAggregator {
-	return &streamingAggregator{
-		parser: NewOutputParser(),
+// reset discards everything aggregated so far and prepares for a new provider call with
+// the given stop sequences. Text, tool calls and usage never carry over between calls.
+func (a *streamingAggregator) reset(stops ...string) {
+	maxStop := 0
+	for _, stop := range stops {
+		maxStop = max(maxStop, len(stop))
+	}
+	*a = streamingAggregator{
+		scanner: newToolCallScanner(NewOutputParser()),
+		stops:   stops,
+		maxStop: maxStop,
 	}
 }
 
 func (a *streamingAggregator) addChunk(chunk ports.CompletionChunk) {
-	// Accumulate text
-	a.text.WriteString(chunk.DeltaText)
-
-	// Also accumulate in partial buffer for JSON parsing
-	a.partialBuffer.WriteString(chunk.DeltaText)
+	// Accumulate text up to the first stop sequence, which may span chunks. Earlier text
+	// held no complete stop sequence, so only the tail that could complete one is searched.
+	if !a.stopped && chunk.DeltaText != "" {
+		from := max(0, a.text.Len()-a.maxStop+1)
+		a.text.WriteString(chunk.DeltaText)
+		text := a.text.String()
+		if tail, cut := truncateAtStop(text[from:], a.stops); cut {
+			a.text.Reset()
+			a.text.WriteString(text[:from+len(tail)])
+			a.stopped = true
+		}
+	}
 
 	// Use provider tool calls if available, otherwise parse from text
 	if len(chunk.ToolCalls) > 0 {
-		// Accumulate tool calls from provider
-		a.toolCalls = append(a.toolCalls, chunk.ToolCalls...)
-		// Store the latest batch for early emission
-		if len(a.earlyCalls) == 0 {
-			a.earlyCalls = chunk.ToolCalls
-		}
-	} else {
-		// Try to parse tool calls from both buffers
-		if calls := a.parser.ParseToolCalls(a.text.String()); len(calls) > 0 {
-			a.toolCalls = append(a.toolCalls, calls...)
-			if len(a.earlyCalls) == 0 {
-				a.earlyCalls = calls
-			}
-		} else if calls := a.parser.ParseToolCalls(a.partialBuffer.String()); len(calls) > 0 {
-			a.toolCalls = append(a.toolCalls, calls...)
-			if len(a.earlyCalls) == 0 {
-				a.earlyCalls = calls
-			}
-		}
+		a.providerCalls = true
+		a.record(chunk.ToolCalls)
+	} else if !a.providerCalls {
+		a.parseText()
 	}
 
 	// Update usage if provided (take the latest usage info)
@@ -318,6 +637,18 @@ func (a *streamingAggregator) addChunk(chunk ports.CompletionChunk) {
 	}
 }
 
+// parseText scans text received since the previous call and records the tool calls in
+// JSON values that completed. Each value is decoded once, so a repeated identical call
+// is recorded once per occurrence and never re-emitted.
+func (a *streamingAggregator) parseText() {
+	a.record(a.scanner.scan(a.text.String()))
+}
+
+func (a *streamingAggregator) record(calls []ports.ToolCall) {
+	a.toolCalls = append(a.toolCalls, calls...)
+	a.earlyCalls = append(a.earlyCalls, calls...)
+}
+
 func (a *streamingAggregator) getText() string {
 	return a.text.String()
 }
@@ -330,6 +661,7 @@ func (a *streamingAggregator) getUsage() *ports.Usage {
 	return a.usage
 }
 
+// getEarlyToolCalls returns calls detected since the previous call.
 func (a *streamingAggregator) getEarlyToolCalls() []ports.ToolCall {
 	calls := a.earlyCalls
 	a.earlyCalls = nil // Clear after getting
@@ -338,17 +670,10 @@ func (a *streamingAggregator) getEarlyToolCalls() []ports.ToolCall {
 
 func (a *streamingAggregator) finalize() ports.Completion {
 	// Final parse of accumulated text for any missed tool calls
-	if len(a.toolCalls) == 0 {
-		// Try parsing from main text buffer first
-		if calls := a.parser.ParseToolCalls(a.text.String()); len(calls) > 0 {
-			a.toolCalls = append(a.toolCalls, calls...)
-		}
-
-		// Also try parsing from partial buffer which might contain complete JSON
-		if calls := a.parser.ParseToolCalls(a.partialBuffer.String()); len(calls) > 0 {
-			a.toolCalls = append(a.toolCalls, calls...)
-		}
+	if !a.providerCalls {
+		a.parseText()
 	}
+	a.done = true
 
 	return ports.Completion{
 		Text:      a.text.String(),
@@ -357,41 +682,62 @@ func (a *streamingAggregator) finalize() ports.Completion {
 	}
 }
 
+// truncateAtStop cuts text at the earliest occurrence of any stop sequence and reports
+// whether one was found. The stop sequence itself is not kept.
+func truncateAtStop(text string, stops []string) (string, bool) {
+	cut := -1
+	for _, stop := range stops {
+		if stop == "" {
+			continue
+		}
+		if i := strings.Index(text, stop); i >= 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	if cut < 0 {
+		return text, false
+	}
+	return text[:cut], true
+}
+
 // runLoop executes the tool-calling loop until completion.
 func (o *HarnessOrchestrator) runLoop(ctx context.Context, req *Request, prompt ports.PromptInput) (*Response, error) {
 	currentPrompt := prompt
 	iteration := 0
 	depth := 0
+	var planned []ports.ToolCall // calls answered without execution in dry-run mode
 
 	for {
 		iteration++
 		if iteration > req.Policy.MaxIterations {
-			return nil, fmt.Errorf("max iterations exceeded: %d", req.Policy.MaxIterations)
+			return nil, fmt.Errorf("%w: %d", ErrMaxIterations, req.Policy.MaxIterations)
 		}
 
 		// Build provider options
-		opts := ports.Options{
-			MaxNewTokens: 1024,
-			Temperature:  0.7,
-			TopP:         0.9,
-			Seed:         0, // will be set if deterministic
-		}
-		if req.Policy.Deterministic && iteration == 1 {
-			opts.Seed = 42
-		}
+		opts := o.providerOptions(req, iteration)
 
 		// Call provider
-		ctx, spanFinish := o.tracer.StartSpan(ctx, "provider_call", map[string]any{
-			"iteration": iteration,
-			"depth":     depth,
+		callCtx, spanFinish := o.tracer.StartSpan(ctx, "provider_call", map[string]any{
+			"iteration":   iteration,
+			"depth":       depth,
+			"system":      currentPrompt.System,
+			"messages":    currentPrompt.Messages,
+			"prompt_hash": PromptHash(currentPrompt),
 		})
-		completion, err := o.provider.Complete(ctx, currentPrompt, opts)
-		spanFinish(err)
-
+		completion, err := o.provider.Complete(callCtx, currentPrompt, opts)
 		if err != nil {
-			return nil, fmt.Errorf("provider call failed: %w", err)
+			spanFinish(err)
+			return nil, fmt.Errorf("%w: %w", ErrProviderFailed, err)
 		}
 
+		// Enforce stop sequences even if the provider ignores them
+		completion.Text, _ = truncateAtStop(completion.Text, opts.Stop)
+		o.tracer.Event(callCtx, "provider_completion", map[string]any{
+			"text":       completion.Text,
+			"tool_calls": completion.ToolCalls,
+		})
+		spanFinish(nil)
+
 		// Merge tool calls from provider and parsed text
 		providerToolCalls := completion.ToolCalls
 		parsedToolCalls := o.parseToolCalls(completion.Text)
@@ -401,27 +747,40 @@ func (o *HarnessOrchestrator) runLoop(ctx context.Context, req *Request, prompt
 		if len(toolCalls) == 0 {
 			toolCalls = parsedToolCalls
 		}
+		toolCalls = o.selectToolCalls(callCtx, toolCalls, req.Policy.MaxToolCalls)
 
 		// Check stop conditions
 		if len(toolCalls) == 0 {
 			// No more tool calls - final response
 			return &Response{
 				Text:      completion.Text,
-				ToolCalls: nil,
+				ToolCalls: planned,
 				Usage:     completion.Usage,
 			}, nil
 		}
 
+		if req.Policy.DryRunTools {
+			planned = append(planned, toolCalls...)
+			if depth >= req.Policy.MaxToolDepth {
+				return &Response{Text: completion.Text, ToolCalls: planned, Usage: completion.Usage}, nil
+			}
+		}
+
 		// Validate tool depth only if we're going to execute tools
 		if depth >= req.Policy.MaxToolDepth {
-			return nil, fmt.Errorf("max tool depth exceeded: %d", req.Policy.MaxToolDepth)
+			return nil, fmt.Errorf("%w: %d", ErrMaxToolDepth, req.Policy.MaxToolDepth)
 		}
 		depth++
 
 		// Execute tools and append results
-		toolResults, err := o.executeTools(ctx, req.Tools, toolCalls)
-		if err != nil {
-			return nil, fmt.Errorf("tool execution failed: %w", err)
+		var toolResults []string
+		if req.Policy.DryRunTools {
+			toolResults = o.dryRunTools(ctx, toolCalls)
+		} else {
+			toolResults, err = o.executeTools(ctx, req, toolCalls)
+			if err != nil {
+				return nil, fmt.Errorf("tool execution failed: %w", err)
+			}
 		}
 
 		// Append tool results to conversation
@@ -433,85 +792,405 @@ func (o *HarnessOrchestrator) runLoop(ctx context.Context, req *Request, prompt
 				ports.PromptMessage{Role: "tool", Content: result},
 			)
 		}
+		o.compactConversation(ctx, req)
+		o.shedToolResults(ctx, req)
 
 		// Rebuild prompt for next iteration
-		currentPrompt = o.builder.Build(req.System, req.Conversation.Messages, req.Context, o.buildToolSpecs(req.Tools), nil)
+		currentPrompt = o.rebuildPrompt(req)
+	}
+}
+
+// summaryPrefix marks the synthetic message that carries rolled-over conversation history.
+const summaryPrefix = "Summary of earlier conversation:\n"
+
+// compactConversation rolls the oldest messages into one summary message once the
+// conversation exceeds Policy.MaxConversationMessages, keeping the most recent turns.
+// An earlier summary is among the oldest messages, so it is folded into the new one.
+// The summary is persisted as a system turn; summarizer and store failures leave the
+// conversation as-is and are only traced.
+func (o *HarnessOrchestrator) compactConversation(ctx context.Context, req *Request) {
+	limit := req.Policy.MaxConversationMessages
+	messages := req.Conversation.Messages
+	if o.summarizer == nil || limit <= 0 || len(messages) <= limit {
+		return
+	}
+
+	keep := max(limit-1, 1) // one slot is taken by the summary
+	oldest := messages[:len(messages)-keep]
+	summary, err := o.summarizer.Summarize(ctx, oldest)
+	if err != nil {
+		o.tracer.Event(ctx, "summarize_error", map[string]any{"error": err.Error(), "messages": len(oldest)})
+		return
+	}
+
+	compacted := make([]ports.PromptMessage, 0, keep+1)
+	compacted = append(compacted, ports.PromptMessage{Role: "system", Content: summaryPrefix + summary})
+	compacted = append(compacted, messages[len(messages)-keep:]...)
+	req.Conversation.Messages = compacted
+	o.tracer.Event(ctx, "conversation_compacted", map[string]any{"summarized": len(oldest), "kept": keep})
+
+	if err := o.store.SaveTurn(ctx, req.Conversation.ID, ports.Turn{
+		Role:      "system",
+		Content:   compacted[0].Content,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		o.tracer.Event(ctx, "store_error", map[string]any{"error": err.Error()})
 	}
 }
 
+// toolResultsSummaryPrefix marks the synthetic message that carries summarized tool results.
+const toolResultsSummaryPrefix = "Summary of earlier tool results:\n"
+
+// toolResultsNote stands in for tool results dropped to fit the context window.
+const toolResultsNote = "[%d earlier tool results omitted to fit the context window]"
+
+// shedToolResults keeps accumulated tool results within the context window of the prompt
+// builder. When the untrimmed prompt is over budget, the oldest tool results are removed
+// until it fits and replaced by one message: a summary from the Summarizer, or a note
+// counting them without one. The latest batch of results and every other message are
+// kept, so neither the system prompt nor the user's question is lost to the results;
+// what was removed is traced. Synthetic messages of earlier passes are folded into the
+// new one. Without a context budget this is a no-op.
+func (o *HarnessOrchestrator) shedToolResults(ctx context.Context, req *Request) {
+	builder := o.promptBuilder(req)
+	if builder.ContextSize <= 0 {
+		return
+	}
+	budget := builder.ContextSize - builder.ReservedTokens
+	untrimmed := *builder
+	untrimmed.ContextSize = 0
+	toolSpecs := o.buildToolSpecs(o.permittedTools(req))
+	estimate := func(messages []ports.PromptMessage) int {
+		return builder.EstimateTokens(untrimmed.Build(req.System, messages, req.Context, toolSpecs, nil))
+	}
+
+	messages := req.Conversation.Messages
+	tokensBefore := estimate(messages)
+	if tokensBefore <= budget {
+		return
+	}
+
+	// The latest batch of results follows the last non-tool message
+	latest := len(messages)
+	for latest > 0 && messages[latest-1].Role == "tool" {
+		latest--
+	}
+	var candidates []int
+	for i, msg := range messages[:latest] {
+		if _, synthetic := shedToolResultCount(msg); synthetic || msg.Role == "tool" {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	// Drop the oldest results until the prompt fits, or all of them
+	var shed []ports.PromptMessage
+	var rebuilt []ports.PromptMessage
+	results := 0
+	for n := 1; n <= len(candidates); n++ {
+		i := candidates[n-1]
+		shed = append(shed, messages[i])
+		if count, synthetic := shedToolResultCount(messages[i]); synthetic {
+			results += count
+		} else {
+			results++
+		}
+		rebuilt = replaceMessages(messages, candidates[:n], ports.PromptMessage{Role: "system", Content: fmt.Sprintf(toolResultsNote, results)})
+		if estimate(rebuilt) <= budget {
+			break
+		}
+	}
+
+	summarized := false
+	if o.summarizer != nil {
+		summary, err := o.summarizer.Summarize(ctx, shed)
+		if err != nil {
+			o.tracer.Event(ctx, "summarize_error", map[string]any{"error": err.Error(), "messages": len(shed)})
+		} else if summarizedMessages := replaceMessages(messages, candidates[:len(shed)], ports.PromptMessage{Role: "system", Content: toolResultsSummaryPrefix + summary}); estimate(summarizedMessages) <= budget {
+			// A summary too long to fit would push the user's question out instead
+			rebuilt = summarizedMessages
+			summarized = true
+		}
+	}
+	req.Conversation.Messages = rebuilt
+
+	o.tracer.Event(ctx, "tool_results_shed", map[string]any{
+		"tool_results":  results,
+		"messages":      len(shed),
+		"summarized":    summarized,
+		"budget":        budget,
+		"tokens_before": tokensBefore,
+		"tokens_after":  estimate(rebuilt),
+	})
+}
+
+// shedToolResultCount reports whether msg was added by shedToolResults and, for a note,
+// how many tool results it stands in for. A summary does not record its count.
+func shedToolResultCount(msg ports.PromptMessage) (int, bool) {
+	if msg.Role != "system" {
+		return 0, false
+	}
+	if strings.HasPrefix(msg.Content, toolResultsSummaryPrefix) {
+		return 0, true
+	}
+	var count int
+	if _, err := fmt.Sscanf(msg.Content, toolResultsNote, &count); err != nil {
+		return 0, false
+	}
+	return count, true
+}
+
+// replaceMessages returns a copy of messages with the messages at the ascending indices
+// removed and replacement placed where the first of them was.
+func replaceMessages(messages []ports.PromptMessage, indices []int, replacement ports.PromptMessage) []ports.PromptMessage {
+	out := make([]ports.PromptMessage, 0, len(messages)-len(indices)+1)
+	next := 0
+	for i, msg := range messages {
+		if next < len(indices) && indices[next] == i {
+			if next == 0 {
+				out = append(out, replacement)
+			}
+			next++
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
 // executeTools runs all tool calls in parallel with timeout.
-func (o *HarnessOrchestrator) executeTools(ctx context.Context, tools []ports.Tool, calls []ports.ToolCall) ([]string, error) {
+func (o *HarnessOrchestrator) executeTools(ctx context.Context, req *Request, calls []ports.ToolCall) ([]string, error) {
 	if len(calls) == 0 {
 		return nil, nil
 	}
 
-	// Build tool map for lookup
-	toolMap := make(map[string]ports.Tool)
-	for _, tool := range tools {
-		toolMap[tool.Name()] = tool
+	dispatcher := o.newToolDispatcher(ctx, req)
+	for _, call := range calls {
+		dispatcher.start(call)
 	}
+	return dispatcher.wait()
+}
 
-	type result struct {
-		content string
-		err     error
+// dryRunTools answers each call with a synthetic result instead of invoking the tool.
+func (o *HarnessOrchestrator) dryRunTools(ctx context.Context, calls []ports.ToolCall) []string {
+	results := make([]string, len(calls))
+	for i, call := range calls {
+		o.tracer.Event(ctx, "tool_dry_run", map[string]any{"tool": call.Name, "args": string(call.Args)})
+		results[i] = fmt.Sprintf("%s: not executed (dry-run)", call.Name)
 	}
+	return results
+}
 
-	results := make([]result, len(calls))
-	sem := make(chan struct{}, 5) // limit concurrency
+// maxConcurrentTools bounds how many tool invocations run at once per dispatcher.
+const maxConcurrentTools = 5
 
-	// Start all goroutines
-	for i, call := range calls {
-		go func(idx int, tc ports.ToolCall) {
-			sem <- struct{}{} // acquire semaphore
+// toolResult is the outcome of one tool invocation.
+type toolResult struct {
+	name         string
+	content      string
+	err          error
+	artifact     []byte // full output when content was truncated
+	artifactName string // name the full output is archived under
+}
 
-			tool, exists := toolMap[tc.Name]
-			if !exists {
-				results[idx] = result{content: "", err: fmt.Errorf("unknown tool: %s", tc.Name)}
-				<-sem // release semaphore
-				return
-			}
+// toolDispatcher starts tool calls as they arrive and joins their results in call order.
+// start and wait must be called from a single goroutine.
+type toolDispatcher struct {
+	ctx            context.Context
+	toolMap        map[string]ports.Tool
+	denied         map[string]bool // provided tools excluded by an allowlist
+	sem            chan struct{}
+	wg             sync.WaitGroup
+	results        []*toolResult
+	timeout        time.Duration                     // per-call budget unless the tool declares its own; zero means none
+	maxResultBytes int                               // zero means uncapped
+	archive        func(name string, payload []byte) // persists full output of truncated results
+	fetchable      bool                              // archived outputs can be read back with fetch_artifact
+	metrics        *ToolMetrics                      // optional, records each invocation of a known tool
+	resultFmt      ToolResultFormat                  // rendering of non-string outputs unless the tool overrides it
+	tracer         ports.Tracer                      // optional, traces each invocation with its args and result
+	auditor        *toolAuditor                      // optional, records each invocation in the audit log
+}
 
-			toolCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			defer cancel()
+func (o *HarnessOrchestrator) newToolDispatcher(ctx context.Context, req *Request) *toolDispatcher {
+	// Build tool map for lookup; calls to tools outside the allowlists are rejected
+	toolMap := make(map[string]ports.Tool)
+	denied := make(map[string]bool)
+	for _, tool := range req.Tools {
+		if o.toolAllowed(req, tool.Name()) {
+			toolMap[tool.Name()] = tool
+		} else {
+			denied[tool.Name()] = true
+		}
+	}
 
-			output, err := tool.Invoke(toolCtx, tc.Args)
-			if err != nil {
-				results[idx] = result{content: "", err: fmt.Errorf("tool %s failed: %w", tc.Name, err)}
-				<-sem // release semaphore
-				return
+	d := &toolDispatcher{
+		ctx:       ctx,
+		toolMap:   toolMap,
+		denied:    denied,
+		sem:       make(chan struct{}, maxConcurrentTools), // limit concurrency
+		timeout:   DefaultPolicy().ToolTimeout,
+		metrics:   o.metrics,
+		tracer:    o.tracer,
+		resultFmt: o.resultFmt,
+		auditor:   o.auditor,
+	}
+	if req.Policy != nil {
+		d.timeout = req.Policy.ToolTimeout
+		d.maxResultBytes = req.Policy.MaxToolResultBytes
+	}
+	if req.Conversation != nil {
+		// Tools such as fetch_artifact scope their lookups to the calling conversation
+		d.ctx = ports.WithConversationID(ctx, req.Conversation.ID)
+	}
+	if o.store != nil && req.Conversation != nil {
+		_, d.fetchable = o.store.(ports.ArtifactReader)
+		d.archive = func(name string, payload []byte) {
+			if err := o.store.AppendToolArtifact(ctx, req.Conversation.ID, name, payload); err != nil {
+				o.tracer.Event(ctx, "store_error", map[string]any{"error": err.Error(), "tool": name})
 			}
+		}
+	}
+	return d
+}
 
-			// Convert output to string
-			var content string
-			if str, ok := output.(string); ok {
-				content = str
-			} else {
-				jsonBytes, err := json.Marshal(output)
-				if err != nil {
-					content = fmt.Sprintf("Error marshaling tool output: %v", err)
-					results[idx] = result{content: content, err: fmt.Errorf("tool %s output marshaling failed: %w", tc.Name, err)}
-					<-sem // release semaphore
-					return
-				} else {
-					content = string(jsonBytes)
-				}
-			}
+// start runs call in the background once a concurrency slot is free.
+func (d *toolDispatcher) start(call ports.ToolCall) {
+	res := &toolResult{}
+	d.results = append(d.results, res)
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+		d.sem <- struct{}{}        // acquire semaphore
+		defer func() { <-d.sem }() // release semaphore
+		*res = d.invoke(call)
+		res.name = call.Name
+		d.truncate(res)
+	}()
+}
+
+func (d *toolDispatcher) invoke(tc ports.ToolCall) (res toolResult) {
+	ctx := d.ctx
+	if d.tracer != nil {
+		var spanFinish func(err error)
+		ctx, spanFinish = d.tracer.StartSpan(ctx, "tool_call", map[string]any{
+			"tool": tc.Name,
+			"args": string(tc.Args),
+		})
+		defer func() {
+			d.tracer.Event(ctx, "tool_result", map[string]any{"tool": tc.Name, "result": res.content})
+			spanFinish(res.err)
+		}()
+	}
+
+	if d.auditor != nil {
+		begin := time.Now()
+		defer func() { d.auditor.record(d.ctx, tc, begin, res) }()
+	}
 
-			results[idx] = result{content: content, err: nil}
-			<-sem // release semaphore
-		}(i, call)
+	tool, exists := d.toolMap[tc.Name]
+	if !exists {
+		if d.denied[tc.Name] {
+			return toolResult{err: &ErrToolFailed{Name: tc.Name, Err: ErrToolNotAllowed}}
+		}
+		return toolResult{err: &ErrToolFailed{Name: tc.Name, Err: ErrUnknownTool}}
+	}
+
+	// Unknown names are not recorded so model output cannot grow the metric set
+	if d.metrics != nil {
+		begin := time.Now()
+		defer func() { d.metrics.Observe(tc.Name, time.Since(begin), res.err) }()
 	}
 
-	// Wait for all goroutines to complete
-	for i := 0; i < len(calls); i++ {
-		sem <- struct{}{} // block until all goroutines release their semaphores
+	// Tools may declare their own budget in place of the policy default
+	timeout := d.timeout
+	if timed, ok := tool.(ports.TimeoutTool); ok && timed.Timeout() > 0 {
+		timeout = timed.Timeout()
+	}
+	toolCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		toolCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	output, err := tool.Invoke(toolCtx, tc.Args)
+	if err != nil {
+		return toolResult{err: &ErrToolFailed{Name: tc.Name, Err: err}}
+	}
+
+	// Convert output to string, in the tool's own format when it declares a valid one
+	format := d.resultFmt
+	if formatted, ok := tool.(ports.ResultFormatTool); ok {
+		if declared, ok := ToolResultFormatFor(formatted.ResultFormat()); ok {
+			format = declared
+		}
+	}
+	content, err := renderToolOutput(tc.Name, output, format)
+	if err != nil {
+		return toolResult{
+			content: fmt.Sprintf("Error marshaling tool output: %v", err),
+			err:     &ErrToolFailed{Name: tc.Name, Err: fmt.Errorf("output marshaling failed: %w", err)},
+		}
+	}
+	return toolResult{content: content}
+}
+
+// truncate caps an oversized result, keeping the full output for archiving.
+func (d *toolDispatcher) truncate(res *toolResult) {
+	// Tools may declare their own cap in place of the policy one
+	maxBytes := d.maxResultBytes
+	if limited, ok := d.toolMap[res.name].(ports.ResultLimitTool); ok && limited.MaxResultBytes() != 0 {
+		maxBytes = limited.MaxResultBytes()
+	}
+	if maxBytes <= 0 || res.err != nil || len(res.content) <= maxBytes {
+		return
+	}
+
+	// Cut on a rune boundary so the prompt stays valid UTF-8
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(res.content[cut]) {
+		cut--
+	}
+	res.artifact = []byte(res.content)
+	res.artifactName = newArtifactName(res.name)
+	res.content = res.content[:cut] + fmt.Sprintf("...[truncated %d bytes]", len(res.artifact)-cut)
+	if d.fetchable {
+		res.content += fmt.Sprintf(" (full output: fetch_artifact name=%q)", res.artifactName)
+	}
+}
+
+// newArtifactName returns a name for an archived output of tool that is unique within
+// its conversation.
+func newArtifactName(tool string) string {
+	var suffix [6]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return fmt.Sprintf("%s-%x", tool, time.Now().UnixNano())
+	}
+	return tool + "-" + hex.EncodeToString(suffix[:])
+}
+
+// wait blocks until every started call finishes and returns outputs in call order.
+// Full outputs of truncated results are archived here, from the caller's goroutine.
+func (d *toolDispatcher) wait() ([]string, error) {
+	d.wg.Wait()
+
+	if d.archive != nil {
+		for _, res := range d.results {
+			if res.artifact != nil {
+				d.archive(res.artifactName, res.artifact)
+			}
+		}
 	}
 
 	// Collect results - handle partial failures gracefully
 	var outputs []string
 	var errors []error
 
-	for _, res := range results {
+	for _, res := range d.results {
 		if res.err != nil {
 			errors = append(errors, res.err)
 		} else {
@@ -527,6 +1206,53 @@ func (o *HarnessOrchestrator) executeTools(ctx context.Context, tools []ports.To
 	return outputs, nil
 }
 
+// selectToolCalls keeps the best n calls, ranked by Confidence with ties in proposal order,
+// and traces the discarded ones. n <= 0 keeps every call.
+func (o *HarnessOrchestrator) selectToolCalls(ctx context.Context, calls []ports.ToolCall, n int) []ports.ToolCall {
+	if n <= 0 || len(calls) <= n {
+		return calls
+	}
+
+	ranked := make([]ports.ToolCall, len(calls))
+	copy(ranked, calls)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Confidence > ranked[j].Confidence
+	})
+
+	discarded := make([]string, 0, len(ranked)-n)
+	for _, call := range ranked[n:] {
+		discarded = append(discarded, call.Name)
+	}
+	o.tracer.Event(ctx, "tool_calls_discarded", map[string]any{"kept": n, "discarded": discarded})
+	return ranked[:n]
+}
+
+// permittedTools returns the tools of req that pass both the global allowlist and
+// req.AllowedTools, in their original order.
+func (o *HarnessOrchestrator) permittedTools(req *Request) []ports.Tool {
+	if len(o.allowlist) == 0 && len(req.AllowedTools) == 0 {
+		return req.Tools
+	}
+	permitted := make([]ports.Tool, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		if o.toolAllowed(req, tool.Name()) {
+			permitted = append(permitted, tool)
+		}
+	}
+	return permitted
+}
+
+// toolAllowed reports whether name passes the global allowlist and req.AllowedTools.
+func (o *HarnessOrchestrator) toolAllowed(req *Request, name string) bool {
+	if len(o.allowlist) > 0 && !o.allowlist[name] {
+		return false
+	}
+	if len(req.AllowedTools) > 0 && !slices.Contains(req.AllowedTools, name) {
+		return false
+	}
+	return true
+}
+
 // buildToolSpecs converts tools to provider-expected specs.
 func (o *HarnessOrchestrator) buildToolSpecs(tools []ports.Tool) []ports.ToolSpec {
 	specs := make([]ports.ToolSpec, len(tools))
@@ -548,10 +1274,27 @@ func (o *HarnessOrchestrator) buildCacheKey(req *Request) string {
 		req.Conversation.ID,
 		o.hashString(req.System),
 		o.hashString(strings.Join(req.Context, "|")),
-		len(req.Tools))
+		len(o.permittedTools(req)))
+	if len(req.AllowedTools) > 0 {
+		// Narrower allowlists hide tools from the prompt, so they must not share entries
+		allowed := slices.Clone(req.AllowedTools)
+		sort.Strings(allowed)
+		key += "|allowed:" + o.hashString(strings.Join(allowed, "\x00"))
+	}
 
 	if req.Policy != nil {
 		key += fmt.Sprintf("|policy:%d:%d", req.Policy.MaxToolDepth, req.Policy.MaxIterations)
+		if req.Policy.DryRunTools {
+			// Planned-only responses must never be served to a run that executes tools
+			key += "|dryrun"
+		}
+	}
+	if req.Options != nil {
+		// Sampling overrides change the output, so they must not share entries
+		key += "|opts:" + o.hashString(fmt.Sprintf("%+v", *req.Options))
+	}
+	if len(req.StopSequences) > 0 || req.MaxNewTokens > 0 {
+		key += fmt.Sprintf("|stop:%s|max:%d", o.hashString(strings.Join(req.StopSequences, "\x00")), req.MaxNewTokens)
 	}
 
 	return key
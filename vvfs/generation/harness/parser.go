@@ -31,6 +31,15 @@ func NewOutputParser() *OutputParser {
 
 // ParseToolCalls extracts tool calls from a model response text.
 func (p *OutputParser) ParseToolCalls(text string) []ports.ToolCall {
+	if calls := p.parseOpenAIToolCalls(text); len(calls) > 0 {
+		return calls
+	}
+	if data, ok := ExtractJSON(text); ok && data[0] == '[' {
+		if calls := decodeToolCallArray(data); len(calls) > 0 {
+			return calls
+		}
+	}
+
 	var calls []ports.ToolCall
 
 	// Try each pattern
@@ -66,9 +75,76 @@ func (p *OutputParser) ParseToolCalls(text string) []ports.ToolCall {
 	return calls
 }
 
+// parseOpenAIToolCalls decodes an OpenAI-style {"tool_calls": [...]} payload embedded in
+// text. Arguments are JSON-encoded strings in that format, which the regex patterns cannot
+// unescape, so the payload is extracted and decoded structurally instead.
+func (p *OutputParser) parseOpenAIToolCalls(text string) []ports.ToolCall {
+	if !strings.Contains(text, `"tool_calls"`) {
+		return nil
+	}
+	data, ok := ExtractJSON(text)
+	if !ok {
+		return nil
+	}
+
+	var payload struct {
+		ToolCalls []struct {
+			Function struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil
+	}
+
+	calls := make([]ports.ToolCall, 0, len(payload.ToolCalls))
+	for _, tc := range payload.ToolCalls {
+		name := strings.TrimSpace(tc.Function.Name)
+		if name == "" {
+			continue
+		}
+		args := json.RawMessage(tc.Function.Arguments)
+		if !json.Valid(args) {
+			args = json.RawMessage(p.fixJSON(tc.Function.Arguments))
+			if !json.Valid(args) {
+				continue
+			}
+		}
+		calls = append(calls, ports.ToolCall{Name: name, Args: args})
+	}
+	return calls
+}
+
+// decodeToolCallArray decodes a JSON array of {"name", "arguments", "confidence"} objects.
+// Entries without a name or arguments are skipped.
+func decodeToolCallArray(data []byte) []ports.ToolCall {
+	var entries []struct {
+		Name       string          `json:"name"`
+		Arguments  json.RawMessage `json:"arguments"`
+		Confidence float64         `json:"confidence"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil
+	}
+
+	var calls []ports.ToolCall
+	for _, entry := range entries {
+		if name := strings.TrimSpace(entry.Name); name != "" && len(entry.Arguments) > 0 {
+			calls = append(calls, ports.ToolCall{Name: name, Args: entry.Arguments, Confidence: entry.Confidence})
+		}
+	}
+	return calls
+}
+
 // ParseJSONOutput attempts to extract JSON from text when JSON mode is required.
 func (p *OutputParser) ParseJSONOutput(text string) (json.RawMessage, error) {
-	// Look for JSON objects or arrays in the text
+	if data, ok := ExtractJSON(text); ok {
+		return json.RawMessage(data), nil
+	}
+
+	// Fall back to a loose match that fixJSON may be able to repair
 	jsonPattern := regexp.MustCompile(`(\{.*\}|\[.*\])`)
 	match := jsonPattern.FindString(text)
 
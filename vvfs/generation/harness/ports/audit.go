@@ -0,0 +1,35 @@
+package harnessports
+
+import (
+	"context"
+	"time"
+)
+
+// ToolInvocation is the audit record of a single tool call.
+// Arguments, results and errors are redacted before they are recorded.
+type ToolInvocation struct {
+	ID             string
+	ConversationID string
+	Tool           string
+	Args           string        // redacted JSON arguments
+	Result         string        // redacted result; empty when only the hash is kept
+	ResultHash     string        // hex SHA-256 of the full, unredacted result
+	Error          string        // redacted failure, empty on success
+	StartedAt      time.Time     // when the call was dispatched
+	Duration       time.Duration // time spent in the tool
+}
+
+// AuditFilter selects tool invocations. Zero-valued fields match every record.
+type AuditFilter struct {
+	ConversationID string
+	Tool           string
+	Since          time.Time // inclusive lower bound on StartedAt
+	Until          time.Time // exclusive upper bound on StartedAt
+	Limit          int       // maximum records returned, newest first; 0 is unlimited
+}
+
+// AuditSink durably records tool invocations and lists them back for review.
+type AuditSink interface {
+	RecordToolInvocation(ctx context.Context, invocation ToolInvocation) error
+	ListToolInvocations(ctx context.Context, filter AuditFilter) ([]ToolInvocation, error)
+}
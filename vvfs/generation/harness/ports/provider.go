@@ -17,20 +17,28 @@ type PromptInput struct {
 	Context  []string          // retrieved RAG/context snippets
 	Tools    []ToolSpec        // tool declarations available to the model
 	Meta     map[string]string // lightweight metadata for tracing/caching keys
+
+	// ToolSchema holds Tools serialized in the provider's wire format (e.g. an OpenAI
+	// "tools" array) when the prompt builder has a wire-format ToolSchemaFormatter.
+	ToolSchema []byte
 }
 
 // Options controls sampling, limits, determinism, and tool preferences.
 type Options struct {
-	MaxNewTokens int
-	Temperature  float32
-	TopP         float32
-	MinP         float32
-	Seed         int
-	Stop         []string
+	MaxNewTokens      int
+	Temperature       float32
+	TopP              float32
+	MinP              float32
+	RepetitionPenalty float32 // zero leaves the provider default
+	Seed              int
+	Stop              []string
 	// ToolChoice: "auto" | "none" | specific tool name (if the provider supports it)
 	ToolChoice string
 	// TimeoutMs applies to the provider call only (not overall harness deadline)
 	TimeoutMs int
+	// JSONMode constrains the completion to valid JSON. It is only set for providers whose
+	// capabilities report JSONMode.
+	JSONMode bool
 }
 
 // Usage captures token accounting for cost/telemetry.
@@ -61,3 +69,35 @@ type Provider interface {
 	Complete(ctx context.Context, in PromptInput, opts Options) (Completion, error)
 	Stream(ctx context.Context, in PromptInput, opts Options) (<-chan CompletionChunk, error)
 }
+
+// ToolSchemaFormatProvider is implemented by providers that expect tool declarations in a
+// specific format, e.g. "openai" for function-calling APIs or "inline" for models that
+// read tool descriptions from the system prompt.
+type ToolSchemaFormatProvider interface {
+	ToolSchemaFormat() string
+}
+
+// ProviderCapabilities describes what a provider supports, so the orchestrator can adapt
+// its requests instead of relying on parsing fallbacks.
+type ProviderCapabilities struct {
+	NativeToolCalls bool // returns structured tool calls for tools declared in the request
+	Streaming       bool // Stream delivers incremental chunks
+	JSONMode        bool // honors Options.JSONMode
+	ContextWindow   int  // context size in tokens; zero when unknown
+}
+
+// CapabilitiesProvider is implemented by providers that describe their capabilities.
+// Providers without it are assumed to support native tool calls, streaming and JSON mode,
+// with an unknown context window.
+type CapabilitiesProvider interface {
+	Capabilities() ProviderCapabilities
+}
+
+// CapabilitiesOf returns p's declared capabilities, or the defaults described on
+// CapabilitiesProvider when it declares none.
+func CapabilitiesOf(p Provider) ProviderCapabilities {
+	if declared, ok := p.(CapabilitiesProvider); ok {
+		return declared.Capabilities()
+	}
+	return ProviderCapabilities{NativeToolCalls: true, Streaming: true, JSONMode: true}
+}
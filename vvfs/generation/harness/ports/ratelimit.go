@@ -6,3 +6,11 @@ import "context"
 type RateLimiter interface {
 	Acquire(ctx context.Context, key string) (release func(), err error)
 }
+
+// WaitingRateLimiter is implemented by limiters that can block until a permit is available
+// instead of failing immediately.
+type WaitingRateLimiter interface {
+	RateLimiter
+	// AcquireWait waits for a permit until one is available or ctx is done.
+	AcquireWait(ctx context.Context, key string) (release func(), err error)
+}
@@ -2,6 +2,7 @@ package harnessports
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -18,3 +19,25 @@ type ConversationStore interface {
 	LoadContext(ctx context.Context, conversationID string, k int) ([]Turn, error) // last-k turns
 	AppendToolArtifact(ctx context.Context, conversationID, name string, payload []byte) error
 }
+
+// ErrArtifactNotFound is returned when a conversation has no artifact with the requested name.
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// ArtifactReader is implemented by conversation stores that can read tool artifacts back.
+// Lookups are scoped to a single conversation.
+type ArtifactReader interface {
+	LoadToolArtifact(ctx context.Context, conversationID, name string) ([]byte, error)
+}
+
+type conversationIDKey struct{}
+
+// WithConversationID returns a context carrying the ID of the conversation a tool call serves.
+func WithConversationID(ctx context.Context, conversationID string) context.Context {
+	return context.WithValue(ctx, conversationIDKey{}, conversationID)
+}
+
+// ConversationIDFromContext returns the conversation ID set by WithConversationID.
+func ConversationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(conversationIDKey{}).(string)
+	return id, ok && id != ""
+}
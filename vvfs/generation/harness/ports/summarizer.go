@@ -0,0 +1,8 @@
+package harnessports
+
+import "context"
+
+// Summarizer condenses older conversation messages into a short summary.
+type Summarizer interface {
+	Summarize(ctx context.Context, messages []PromptMessage) (string, error)
+}
@@ -3,6 +3,7 @@ package harnessports
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // ToolSpec describes a callable tool exposed to the model.
@@ -16,6 +17,9 @@ type ToolSpec struct {
 type ToolCall struct {
 	Name string
 	Args json.RawMessage
+	// Confidence is an optional model-reported score used to rank alternative calls;
+	// zero when the model gives none.
+	Confidence float64
 }
 
 // Tool defines the runtime that executes a tool call.
@@ -24,3 +28,26 @@ type Tool interface {
 	Schema() []byte
 	Invoke(ctx context.Context, args json.RawMessage) (any, error)
 }
+
+// TimeoutTool is implemented by tools that declare their own execution budget.
+// A positive Timeout replaces Policy.ToolTimeout for every call to the tool.
+type TimeoutTool interface {
+	Tool
+	Timeout() time.Duration
+}
+
+// ResultLimitTool is implemented by tools that bound their own output. A non-zero
+// MaxResultBytes replaces Policy.MaxToolResultBytes for every result of the tool;
+// a negative value leaves results uncapped.
+type ResultLimitTool interface {
+	Tool
+	MaxResultBytes() int
+}
+
+// ResultFormatTool is implemented by tools whose output reads best in a particular
+// format. A non-empty ResultFormat ("json", "text" or "xml") replaces the orchestrator's
+// tool result format for every result of the tool.
+type ResultFormatTool interface {
+	Tool
+	ResultFormat() string
+}
@@ -1,17 +1,61 @@
 package harness
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
 )
 
+// messageOverheadTokens approximates per-message role/delimiter tokens added by chat templates.
+const messageOverheadTokens = 4
+
 // PromptBuilder assembles model-ready inputs from system text, messages, and tools.
-type PromptBuilder struct{}
+type PromptBuilder struct {
+	// ContextSize is the model context window in tokens; zero disables trimming.
+	ContextSize int
+	// ReservedTokens are held back from ContextSize for the completion.
+	ReservedTokens int
+	// TokenCounter estimates tokens for a string; defaults to ~4 chars per token.
+	TokenCounter func(s string) int
+	// ToolFormatter serializes tool specs into the provider's format; nil leaves them as specs.
+	ToolFormatter ToolSchemaFormatter
+}
 
 func NewPromptBuilder() *PromptBuilder { return &PromptBuilder{} }
 
+// NewBudgetedPromptBuilder creates a builder that trims prompts to fit contextSize,
+// keeping reservedTokens free for generation.
+func NewBudgetedPromptBuilder(contextSize, reservedTokens int, counter func(s string) int) *PromptBuilder {
+	return &PromptBuilder{
+		ContextSize:    contextSize,
+		ReservedTokens: reservedTokens,
+		TokenCounter:   counter,
+	}
+}
+
+// TrimReport describes what Fit removed to keep a prompt within budget.
+type TrimReport struct {
+	Budget          int  // tokens available for the prompt
+	TokensBefore    int  // estimated prompt tokens before trimming
+	TokensAfter     int  // estimated prompt tokens after trimming
+	DroppedMessages int  // oldest conversation turns removed
+	DroppedSnippets int  // lowest-priority context snippets removed
+	StillOverBudget bool // system prompt + latest turn alone exceed the budget
+}
+
+// Trimmed reports whether anything was removed.
+func (r TrimReport) Trimmed() bool {
+	return r.DroppedMessages > 0 || r.DroppedSnippets > 0
+}
+
 // Build flattens system + chat messages into a Provider PromptInput.
+// With a ToolFormatter the tool specs are serialized before fitting, so the budget covers
+// the formatted declarations; a formatting error is recorded in Meta under
+// "tool_schema_error" and the specs are left unformatted.
+// When a ContextSize is configured the result is trimmed to fit and the trim is
+// recorded in Meta under "trimmed_messages", "trimmed_snippets" and "trimmed_tokens".
 func (b *PromptBuilder) Build(system string, messages []ports.PromptMessage, contextSnippets []string, toolSpecs []ports.ToolSpec, meta map[string]string) ports.PromptInput {
 	// Normalize newlines and trim whitespace to reduce prompt diffs for caching
 	norm := func(s string) string { return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n")) }
@@ -23,11 +67,126 @@ func (b *PromptBuilder) Build(system string, messages []ports.PromptMessage, con
 		contextSnippets[i] = norm(contextSnippets[i])
 	}
 
-	return ports.PromptInput{
+	in := ports.PromptInput{
 		System:   norm(system),
 		Messages: messages,
 		Context:  contextSnippets,
 		Tools:    toolSpecs,
 		Meta:     meta,
 	}
+
+	if b.ToolFormatter != nil && len(in.Tools) > 0 {
+		formatted, err := applyToolSchema(in, b.ToolFormatter)
+		if err != nil {
+			if in.Meta == nil {
+				in.Meta = make(map[string]string)
+			}
+			in.Meta["tool_schema_error"] = err.Error()
+		} else {
+			in = formatted
+		}
+	}
+
+	in, report := b.Fit(in)
+	if report.Trimmed() {
+		if in.Meta == nil {
+			in.Meta = make(map[string]string)
+		}
+		in.Meta["trimmed_messages"] = strconv.Itoa(report.DroppedMessages)
+		in.Meta["trimmed_snippets"] = strconv.Itoa(report.DroppedSnippets)
+		in.Meta["trimmed_tokens"] = strconv.Itoa(report.TokensBefore - report.TokensAfter)
+	}
+
+	return in
+}
+
+// Fit trims a prompt to the configured context budget. The system prompt, tool specs
+// and the most recent message are always kept; the oldest turns are dropped first
+// (replaced by a short omission note), then the lowest-ranked context snippets.
+func (b *PromptBuilder) Fit(in ports.PromptInput) (ports.PromptInput, TrimReport) {
+	report := TrimReport{Budget: b.ContextSize - b.ReservedTokens}
+	report.TokensBefore = b.EstimateTokens(in)
+	report.TokensAfter = report.TokensBefore
+	if b.ContextSize <= 0 || report.TokensBefore <= report.Budget {
+		return in, report
+	}
+
+	messageTokens := make([]int, len(in.Messages))
+	for i, msg := range in.Messages {
+		messageTokens[i] = b.count(msg.Content) + messageOverheadTokens
+	}
+	snippetTokens := make([]int, len(in.Context))
+	for i, snippet := range in.Context {
+		snippetTokens[i] = b.count(snippet)
+	}
+	total := report.TokensBefore
+
+	// Drop oldest turns, keeping the latest message
+	start := 0
+	noteTokens := 0
+	for start < len(in.Messages)-1 && total+noteTokens > report.Budget {
+		total -= messageTokens[start]
+		start++
+		noteTokens = b.count(omissionNote(start)) + messageOverheadTokens
+	}
+
+	// Then drop lowest-priority snippets (assembler packs best-first)
+	end := len(in.Context)
+	for end > 0 && total+noteTokens > report.Budget {
+		end--
+		total -= snippetTokens[end]
+	}
+
+	out := in
+	if start > 0 {
+		kept := make([]ports.PromptMessage, 0, len(in.Messages)-start+1)
+		kept = append(kept, ports.PromptMessage{Role: "system", Content: omissionNote(start)})
+		kept = append(kept, in.Messages[start:]...)
+		out.Messages = kept
+	}
+	if end < len(in.Context) {
+		out.Context = in.Context[:end]
+	}
+
+	report.DroppedMessages = start
+	report.DroppedSnippets = len(in.Context) - end
+	report.TokensAfter = b.EstimateTokens(out)
+	report.StillOverBudget = report.TokensAfter > report.Budget
+
+	return out, report
+}
+
+// EstimateTokens approximates the token footprint of a prompt.
+func (b *PromptBuilder) EstimateTokens(in ports.PromptInput) int {
+	total := b.count(in.System)
+	for _, msg := range in.Messages {
+		total += b.count(msg.Content) + messageOverheadTokens
+	}
+	for _, snippet := range in.Context {
+		total += b.count(snippet)
+	}
+	if len(in.ToolSchema) > 0 {
+		// The serialized schema replaces the specs on the wire
+		return total + b.count(string(in.ToolSchema))
+	}
+	for _, spec := range in.Tools {
+		total += b.count(spec.Name) + b.count(spec.Description) + b.count(string(spec.JSONSchema))
+	}
+	return total
+}
+
+// count applies the configured token counter, falling back to ~4 chars per token.
+func (b *PromptBuilder) count(s string) int {
+	if b.TokenCounter != nil {
+		return b.TokenCounter(s)
+	}
+	if len(s) == 0 {
+		return 0
+	}
+	return (len(s) + 3) / 4
+}
+
+// omissionNote stands in for turns dropped from the start of the conversation.
+func omissionNote(dropped int) string {
+	return fmt.Sprintf("[%d earlier messages omitted to fit the context window]", dropped)
 }
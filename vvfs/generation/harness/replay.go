@@ -0,0 +1,209 @@
+package harness
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// ErrNoRecordedResponse is returned by ReplayProvider when a prompt was never seen in the
+// recorded run, which means the orchestration diverged from the recording.
+var ErrNoRecordedResponse = errors.New("no recorded response for prompt")
+
+// PromptHash returns a stable key for the parts of a prompt that determine a completion:
+// the system instructions, messages, context snippets and declared tool names. The
+// orchestrator records it on every provider_call span so runs can be replayed.
+func PromptHash(in ports.PromptInput) string {
+	tools := make([]string, len(in.Tools))
+	for i, tool := range in.Tools {
+		tools[i] = tool.Name
+	}
+	data, _ := json.Marshal(struct {
+		System   string
+		Messages []ports.PromptMessage
+		Context  []string
+		Tools    []string
+	}{in.System, in.Messages, in.Context, tools})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// recordedCompletion is one provider call captured in a timeline.
+type recordedCompletion struct {
+	completion ports.Completion
+	err        string // set when the recorded call failed
+}
+
+// ReplayProvider implements ports.Provider by returning the completions of a recorded run,
+// looked up by the PromptHash of each incoming prompt. It lets an orchestration be
+// re-executed deterministically without a live model to detect behavioral regressions.
+//
+// When the same prompt was recorded several times its completions are returned in order,
+// and the last one is repeated once they run out. Recorded payloads were redacted by the
+// RecordingTracer, so runs whose completions contained secrets replay the redacted text.
+// It is safe for concurrent use.
+type ReplayProvider struct {
+	mu        sync.Mutex
+	responses map[string][]recordedCompletion
+	served    map[string]int
+}
+
+// NewReplayProvider builds a replay provider from a timeline recorded by a RecordingTracer.
+func NewReplayProvider(timeline []TraceEntry) (*ReplayProvider, error) {
+	hashes := make(map[int]string) // provider_call span ID -> prompt hash
+	calls := make(map[int]*recordedCompletion)
+	var order []int
+
+	for _, entry := range timeline {
+		switch {
+		case entry.Kind == TraceSpanStart && entry.Name == "provider_call":
+			hash, _ := entry.Attrs["prompt_hash"].(string)
+			if hash == "" {
+				return nil, fmt.Errorf("provider call %d was recorded without a prompt hash", entry.SpanID)
+			}
+			hashes[entry.SpanID] = hash
+			calls[entry.SpanID] = &recordedCompletion{}
+			order = append(order, entry.SpanID)
+		case entry.Kind == TraceEvent && entry.Name == "provider_completion":
+			call, ok := calls[entry.SpanID]
+			if !ok {
+				continue
+			}
+			completion, err := decodeRecordedCompletion(entry.Attrs)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode completion of provider call %d: %w", entry.SpanID, err)
+			}
+			call.completion = completion
+		case entry.Kind == TraceSpanEnd && entry.Name == "provider_call" && entry.Error != "":
+			if call, ok := calls[entry.SpanID]; ok {
+				call.err = entry.Error
+			}
+		}
+	}
+
+	if len(order) == 0 {
+		return nil, fmt.Errorf("timeline has no recorded provider calls")
+	}
+
+	provider := &ReplayProvider{
+		responses: make(map[string][]recordedCompletion),
+		served:    make(map[string]int),
+	}
+	for _, id := range order {
+		hash := hashes[id]
+		provider.responses[hash] = append(provider.responses[hash], *calls[id])
+	}
+	return provider, nil
+}
+
+// LoadReplayProvider builds a replay provider from a timeline file written by
+// RecordingTracer.SaveJSON or PersistTo.
+func LoadReplayProvider(path string) (*ReplayProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open recorded run: %w", err)
+	}
+	defer file.Close()
+
+	timeline, err := ReadTraceTimeline(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayProvider(timeline)
+}
+
+// Complete returns the recorded completion for the prompt.
+func (p *ReplayProvider) Complete(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+	if err := ctx.Err(); err != nil {
+		return ports.Completion{}, err
+	}
+
+	recorded, err := p.next(in)
+	if err != nil {
+		return ports.Completion{}, err
+	}
+	if recorded.err != "" {
+		return ports.Completion{}, fmt.Errorf("recorded provider error: %s", recorded.err)
+	}
+	return recorded.completion, nil
+}
+
+// Stream returns the recorded completion for the prompt as a single final chunk.
+func (p *ReplayProvider) Stream(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+	completion, err := p.Complete(ctx, in, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan ports.CompletionChunk, 1)
+	ch <- ports.CompletionChunk{
+		DeltaText: completion.Text,
+		ToolCalls: completion.ToolCalls,
+		Done:      true,
+	}
+	close(ch)
+	return ch, nil
+}
+
+// Unserved returns how many recorded provider calls have not been replayed yet. A
+// non-zero count after a run means the replay made fewer calls than the recording.
+func (p *ReplayProvider) Unserved() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	unserved := 0
+	for hash, responses := range p.responses {
+		if remaining := len(responses) - p.served[hash]; remaining > 0 {
+			unserved += remaining
+		}
+	}
+	return unserved
+}
+
+// next pops the next recorded completion for the prompt, repeating the last one when the
+// recording holds no more.
+func (p *ReplayProvider) next(in ports.PromptInput) (recordedCompletion, error) {
+	hash := PromptHash(in)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	responses, ok := p.responses[hash]
+	if !ok {
+		return recordedCompletion{}, fmt.Errorf("%w: hash %s (%d messages)", ErrNoRecordedResponse, hash, len(in.Messages))
+	}
+
+	i := p.served[hash]
+	p.served[hash] = i + 1
+	if i >= len(responses) {
+		i = len(responses) - 1
+	}
+	return responses[i], nil
+}
+
+// decodeRecordedCompletion rebuilds a completion from provider_completion attributes.
+func decodeRecordedCompletion(attrs map[string]any) (ports.Completion, error) {
+	text, _ := attrs["text"].(string)
+	completion := ports.Completion{Text: text}
+
+	if raw, ok := attrs["tool_calls"]; ok && raw != nil {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return ports.Completion{}, fmt.Errorf("failed to encode recorded tool calls: %w", err)
+		}
+		if err := json.Unmarshal(data, &completion.ToolCalls); err != nil {
+			return ports.Completion{}, fmt.Errorf("failed to decode recorded tool calls: %w", err)
+		}
+	}
+	return completion, nil
+}
+
+var _ ports.Provider = (*ReplayProvider)(nil)
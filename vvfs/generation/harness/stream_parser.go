@@ -0,0 +1,143 @@
+package harness
+
+import (
+	"encoding/json"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// maxCallNameLookback bounds how far before a JSON object the scanner looks for a
+// function-call prefix such as `tool_name(`.
+const maxCallNameLookback = 128
+
+// toolCallScanner incrementally finds complete top-level JSON values in streamed text and
+// decodes tool calls from each one exactly once. It is a small state machine tracking
+// bracket nesting and string/escape state, so every byte is examined once and the work
+// per chunk is proportional to the chunk rather than to the accumulated text.
+// Brackets and quotes in prose outside a JSON value are ignored.
+type toolCallScanner struct {
+	parser   *OutputParser
+	pos      int    // bytes of the text already scanned
+	start    int    // offset of the current top-level value's opening bracket
+	stack    []byte // closing brackets expected by the open values; empty outside JSON
+	inString bool
+	escaped  bool
+	scanned  int // total bytes examined, for bounding work in tests
+}
+
+func newToolCallScanner(parser *OutputParser) *toolCallScanner {
+	return &toolCallScanner{parser: parser}
+}
+
+// scan consumes text beyond what was already scanned and returns the tool calls decoded
+// from top-level values that closed. text must extend the text passed previously; if it
+// was shortened (e.g. cut at a stop sequence) scanning resumes from its end.
+func (s *toolCallScanner) scan(text string) []ports.ToolCall {
+	if s.pos > len(text) {
+		s.pos = len(text)
+	}
+
+	var calls []ports.ToolCall
+	for ; s.pos < len(text); s.pos++ {
+		s.scanned++
+		c := text[s.pos]
+
+		if s.inString {
+			switch {
+			case s.escaped:
+				s.escaped = false
+			case c == '\\':
+				s.escaped = true
+			case c == '"':
+				s.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			if len(s.stack) > 0 {
+				s.inString = true
+			}
+		case '{', '[':
+			if len(s.stack) == 0 {
+				s.start = s.pos
+			}
+			if c == '{' {
+				s.stack = append(s.stack, '}')
+			} else {
+				s.stack = append(s.stack, ']')
+			}
+		case '}', ']':
+			if len(s.stack) == 0 {
+				continue // stray bracket in prose
+			}
+			if s.stack[len(s.stack)-1] != c {
+				// Malformed value; drop it and resume in prose
+				s.stack = s.stack[:0]
+				continue
+			}
+			s.stack = s.stack[:len(s.stack)-1]
+			if len(s.stack) == 0 {
+				calls = append(calls, s.decode(text, s.start, s.pos+1)...)
+			}
+		}
+	}
+	return calls
+}
+
+// decode extracts tool calls from the complete value text[start:end]. An object directly
+// preceded by `name(` is a function-style call; other values are decoded as a tool call
+// array or handed to the OutputParser.
+func (s *toolCallScanner) decode(text string, start, end int) []ports.ToolCall {
+	value := text[start:end]
+
+	if name := callNameBefore(text, start); name != "" && value[0] == '{' {
+		args := value
+		if !json.Valid([]byte(args)) {
+			args = s.parser.fixJSON(args)
+			if !json.Valid([]byte(args)) {
+				return nil
+			}
+		}
+		return []ports.ToolCall{{Name: name, Args: json.RawMessage(args)}}
+	}
+
+	if value[0] == '[' {
+		if calls := decodeToolCallArray([]byte(value)); len(calls) > 0 {
+			return calls
+		}
+	}
+
+	return s.parser.ParseToolCalls(value)
+}
+
+// callNameBefore returns the identifier in a `name(` prefix ending just before offset,
+// or "" when there is none.
+func callNameBefore(text string, offset int) string {
+	limit := max(0, offset-maxCallNameLookback)
+	i := offset - 1
+	for i >= limit && isSpaceByte(text[i]) {
+		i--
+	}
+	if i < limit || text[i] != '(' {
+		return ""
+	}
+	i--
+	for i >= limit && isSpaceByte(text[i]) {
+		i--
+	}
+	end := i + 1
+	for i >= limit && isIdentByte(text[i]) {
+		i--
+	}
+	return text[i+1 : end]
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,163 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToolResultFormat says how non-string tool outputs are rendered into the conversation.
+type ToolResultFormat string
+
+// Tool result formats accepted by ToolResultFormatFor.
+const (
+	// ToolResultJSON renders outputs as compact JSON. It is the default.
+	ToolResultJSON ToolResultFormat = "json"
+	// ToolResultText renders outputs as indented "key: value" lines.
+	ToolResultText ToolResultFormat = "text"
+	// ToolResultXML renders outputs as a <tool_result> block with one element per field.
+	ToolResultXML ToolResultFormat = "xml"
+)
+
+// ToolResultFormatFor returns the format for a name such as "json", "text" or "xml".
+func ToolResultFormatFor(name string) (ToolResultFormat, bool) {
+	switch format := ToolResultFormat(strings.ToLower(strings.TrimSpace(name))); format {
+	case ToolResultJSON, ToolResultText, ToolResultXML:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+// renderToolOutput converts a tool's output into the content of its conversation message.
+// Strings are used as-is, except in XML where they are escaped into the result block.
+// Other values go through their JSON encoding, so json struct tags name the fields in
+// every format.
+func renderToolOutput(tool string, output any, format ToolResultFormat) (string, error) {
+	if str, ok := output.(string); ok {
+		if format == ToolResultXML {
+			return xmlToolResult(tool, escapeXML(str)), nil
+		}
+		return str, nil
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return "", err
+	}
+	if format != ToolResultText && format != ToolResultXML {
+		return string(data), nil
+	}
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return "", err
+	}
+	if format == ToolResultText {
+		var b strings.Builder
+		writeTextValue(&b, value, 0)
+		return strings.TrimRight(b.String(), "\n"), nil
+	}
+	var b strings.Builder
+	writeXMLValue(&b, value)
+	return xmlToolResult(tool, b.String()), nil
+}
+
+// xmlToolResult wraps rendered content in the block that names the tool.
+func xmlToolResult(tool, content string) string {
+	return fmt.Sprintf("<tool_result name=\"%s\">%s</tool_result>", escapeXML(tool), content)
+}
+
+// writeTextValue writes value as YAML-like lines indented by depth.
+func writeTextValue(b *strings.Builder, value any, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			switch child := v[key].(type) {
+			case map[string]any, []any:
+				fmt.Fprintf(b, "%s%s:\n", indent, key)
+				writeTextValue(b, child, depth+1)
+			default:
+				fmt.Fprintf(b, "%s%s: %s\n", indent, key, scalarText(child))
+			}
+		}
+	case []any:
+		for _, item := range v {
+			switch child := item.(type) {
+			case map[string]any, []any:
+				fmt.Fprintf(b, "%s-\n", indent)
+				writeTextValue(b, child, depth+1)
+			default:
+				fmt.Fprintf(b, "%s- %s\n", indent, scalarText(child))
+			}
+		}
+	default:
+		fmt.Fprintf(b, "%s%s\n", indent, scalarText(v))
+	}
+}
+
+// xmlNamePattern matches keys that are usable as XML element names as-is.
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// writeXMLValue writes value as nested elements: object keys become element names and
+// array items become <item> elements. Keys that are not valid names use <entry key="...">.
+func writeXMLValue(b *strings.Builder, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(v) {
+			open, end := key, key
+			if !xmlNamePattern.MatchString(key) || strings.HasPrefix(strings.ToLower(key), "xml") {
+				open, end = fmt.Sprintf("entry key=\"%s\"", escapeXML(key)), "entry"
+			}
+			fmt.Fprintf(b, "<%s>", open)
+			writeXMLValue(b, v[key])
+			fmt.Fprintf(b, "</%s>", end)
+		}
+	case []any:
+		for _, item := range v {
+			b.WriteString("<item>")
+			writeXMLValue(b, item)
+			b.WriteString("</item>")
+		}
+	case nil:
+	default:
+		b.WriteString(escapeXML(scalarText(v)))
+	}
+}
+
+// scalarText formats a decoded JSON scalar; integral numbers print without a fraction.
+func scalarText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sortedKeys returns the keys of m in order so renderings are deterministic.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapeXML escapes the characters with special meaning in XML text and attributes.
+var escapeXML = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+).Replace
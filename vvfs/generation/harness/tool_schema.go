@@ -0,0 +1,155 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// ToolSchemaPlacement says where formatted tool declarations go in the prompt.
+type ToolSchemaPlacement int
+
+const (
+	// ToolSchemaWire places the output in PromptInput.ToolSchema for the provider's request payload.
+	ToolSchemaWire ToolSchemaPlacement = iota
+	// ToolSchemaInline appends the output to the system prompt and clears PromptInput.Tools.
+	ToolSchemaInline
+)
+
+// ToolSchemaFormatter serializes tool declarations into the format a provider expects.
+type ToolSchemaFormatter interface {
+	// Format serializes specs; it fails when a spec carries an invalid JSON schema.
+	Format(specs []ports.ToolSpec) ([]byte, error)
+	// Placement reports where the formatted output belongs in the prompt.
+	Placement() ToolSchemaPlacement
+}
+
+// Tool schema format names accepted by ToolSchemaFormatterFor.
+const (
+	ToolSchemaFormatOpenAI = "openai"
+	ToolSchemaFormatInline = "inline"
+)
+
+// ToolSchemaFormatterFor returns the formatter for a format name such as "openai" or "inline".
+func ToolSchemaFormatterFor(format string) (ToolSchemaFormatter, bool) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case ToolSchemaFormatOpenAI:
+		return OpenAIToolSchemaFormatter{}, true
+	case ToolSchemaFormatInline:
+		return InlineToolSchemaFormatter{}, true
+	default:
+		return nil, false
+	}
+}
+
+// emptyParametersSchema stands in for tools that declare no schema.
+var emptyParametersSchema = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// toolParameters returns the spec's schema in compact form, or the empty object schema.
+func toolParameters(spec ports.ToolSpec) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(spec.JSONSchema)) == 0 {
+		return emptyParametersSchema, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, spec.JSONSchema); err != nil {
+		return nil, fmt.Errorf("tool %q has an invalid JSON schema: %w", spec.Name, err)
+	}
+	return compact.Bytes(), nil
+}
+
+// OpenAIToolSchemaFormatter emits the OpenAI function-calling "tools" array:
+// [{"type":"function","function":{"name":...,"description":...,"parameters":{...}}}].
+type OpenAIToolSchemaFormatter struct{}
+
+type openAIFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+// Format implements ToolSchemaFormatter.
+func (OpenAIToolSchemaFormatter) Format(specs []ports.ToolSpec) ([]byte, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	tools := make([]openAITool, len(specs))
+	for i, spec := range specs {
+		params, err := toolParameters(spec)
+		if err != nil {
+			return nil, err
+		}
+		tools[i] = openAITool{
+			Type:     "function",
+			Function: openAIFunction{Name: spec.Name, Description: spec.Description, Parameters: params},
+		}
+	}
+	return json.Marshal(tools)
+}
+
+// Placement implements ToolSchemaFormatter.
+func (OpenAIToolSchemaFormatter) Placement() ToolSchemaPlacement { return ToolSchemaWire }
+
+// InlineToolSchemaFormatter describes tools in plain text for the system prompt, for models
+// without native tool support. It asks for calls in the JSON array form ParseToolCalls reads.
+type InlineToolSchemaFormatter struct{}
+
+// Format implements ToolSchemaFormatter.
+func (InlineToolSchemaFormatter) Format(specs []ports.ToolSpec) ([]byte, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	var b strings.Builder
+	b.WriteString("You can call the following tools:\n")
+	for _, spec := range specs {
+		params, err := toolParameters(spec)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString("\n- ")
+		b.WriteString(spec.Name)
+		if spec.Description != "" {
+			b.WriteString(": ")
+			b.WriteString(spec.Description)
+		}
+		b.WriteString("\n  Parameters: ")
+		b.Write(params)
+	}
+	b.WriteString("\n\nTo call tools, reply with only a JSON array of calls:\n")
+	b.WriteString(`[{"name": "<tool name>", "arguments": {<parameters>}}]`)
+	return []byte(b.String()), nil
+}
+
+// Placement implements ToolSchemaFormatter.
+func (InlineToolSchemaFormatter) Placement() ToolSchemaPlacement { return ToolSchemaInline }
+
+// applyToolSchema formats in.Tools with formatter and places the result in the prompt.
+func applyToolSchema(in ports.PromptInput, formatter ToolSchemaFormatter) (ports.PromptInput, error) {
+	formatted, err := formatter.Format(in.Tools)
+	if err != nil {
+		return in, fmt.Errorf("failed to format tool schema: %w", err)
+	}
+	if len(formatted) == 0 {
+		return in, nil
+	}
+
+	switch formatter.Placement() {
+	case ToolSchemaInline:
+		if in.System == "" {
+			in.System = string(formatted)
+		} else {
+			in.System += "\n\n" + string(formatted)
+		}
+		in.Tools = nil
+	default:
+		in.ToolSchema = formatted
+	}
+	return in, nil
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+const (
+	defaultArtifactPageBytes = 8 * 1024
+	maxArtifactPageBytes     = 64 * 1024
+)
+
+// FetchArtifactSchema defines the JSON schema for fetch_artifact tool parameters.
+const FetchArtifactSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {
+      "type": "string",
+      "description": "Artifact name given in a truncated tool result"
+    },
+    "offset": {
+      "type": "integer",
+      "description": "Byte offset to start reading from",
+      "minimum": 0,
+      "default": 0
+    },
+    "limit": {
+      "type": "integer",
+      "description": "Maximum number of bytes to return",
+      "minimum": 1,
+      "maximum": 65536,
+      "default": 8192
+    }
+  },
+  "required": ["name"]
+}`
+
+// ArtifactPage is a byte range of an archived tool output.
+type ArtifactPage struct {
+	Name       string `json:"name"`
+	Offset     int    `json:"offset"`
+	Content    string `json:"content"`
+	TotalBytes int    `json:"total_bytes"`
+	NextOffset int    `json:"next_offset,omitempty"` // set while bytes remain
+}
+
+// FetchArtifactTool reads back full tool outputs that were truncated in the prompt.
+// Only artifacts of the conversation the call belongs to are visible.
+type FetchArtifactTool struct {
+	store ports.ArtifactReader
+}
+
+// NewFetchArtifactTool creates a fetch_artifact tool reading from store.
+func NewFetchArtifactTool(store ports.ArtifactReader) *FetchArtifactTool {
+	return &FetchArtifactTool{store: store}
+}
+
+// Name returns the tool name.
+func (t *FetchArtifactTool) Name() string {
+	return "fetch_artifact"
+}
+
+// Schema returns the JSON schema for tool parameters.
+func (t *FetchArtifactTool) Schema() []byte {
+	return []byte(FetchArtifactSchema)
+}
+
+// MaxResultBytes exempts pages from the tool result cap; the limit argument already bounds
+// them, and truncating a page would archive a copy of the artifact.
+func (t *FetchArtifactTool) MaxResultBytes() int {
+	return -1
+}
+
+// Invoke returns one page of the named artifact from the calling conversation.
+func (t *FetchArtifactTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
+	var params struct {
+		Name   string `json:"name"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if params.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if params.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultArtifactPageBytes
+	}
+	params.Limit = min(params.Limit, maxArtifactPageBytes)
+
+	conversationID, ok := ports.ConversationIDFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("fetch_artifact requires a conversation")
+	}
+
+	payload, err := t.store.LoadToolArtifact(ctx, conversationID, params.Name)
+	if errors.Is(err, ports.ErrArtifactNotFound) {
+		return nil, fmt.Errorf("no artifact named %q in this conversation", params.Name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load artifact: %w", err)
+	}
+	if params.Offset > len(payload) {
+		return nil, fmt.Errorf("offset %d is past the end of the artifact (%d bytes)", params.Offset, len(payload))
+	}
+
+	// Keep page boundaries on runes so each page is valid UTF-8 text
+	start := params.Offset
+	for start < len(payload) && !utf8.RuneStart(payload[start]) {
+		start++
+	}
+	end := min(start+params.Limit, len(payload))
+	for end < len(payload) && end > start && !utf8.RuneStart(payload[end]) {
+		end--
+	}
+	if end == start && start < len(payload) {
+		// The limit is smaller than the rune at start; return the whole rune
+		for end++; end < len(payload) && !utf8.RuneStart(payload[end]); end++ {
+		}
+	}
+
+	page := ArtifactPage{
+		Name:       params.Name,
+		Offset:     start,
+		Content:    string(payload[start:end]),
+		TotalBytes: len(payload),
+	}
+	if end < len(payload) {
+		page.NextOffset = end
+	}
+	return page, nil
+}
+
+// Ensure FetchArtifactTool bounds its own results.
+var _ ports.ResultLimitTool = (*FetchArtifactTool)(nil)
@@ -25,9 +25,14 @@ const FSMetadataSchema = `{
       "description": "Include file contents in response (for text files only)",
       "default": false
     },
+    "include_preview": {
+      "type": "boolean",
+      "description": "Include a type-specific preview (text lines, PDF text, image dimensions and thumbnail, archive listing)",
+      "default": false
+    },
     "max_content_size": {
       "type": "integer",
-      "description": "Maximum content size to include (in bytes)",
+      "description": "Maximum content or preview size to include (in bytes)",
       "minimum": 1,
       "maximum": 1048576,
       "default": 8192
@@ -36,6 +41,20 @@ const FSMetadataSchema = `{
       "type": "boolean",
       "description": "For directories, include metadata for all nested files and directories",
       "default": false
+    },
+    "max_depth": {
+      "type": "integer",
+      "description": "For recursive listings, how many directory levels below the path to descend",
+      "minimum": 1,
+      "maximum": 32,
+      "default": 8
+    },
+    "max_entries": {
+      "type": "integer",
+      "description": "For recursive listings, the maximum number of nested entries to return; truncated listings are marked",
+      "minimum": 1,
+      "maximum": 10000,
+      "default": 1000
     }
   },
   "required": ["path"]
@@ -54,13 +73,23 @@ type FileMetadata struct {
 	Extension   string         `json:"extension,omitempty"`
 	MimeType    string         `json:"mime_type,omitempty"`
 	Contents    string         `json:"contents,omitempty"`
+	Preview     *FilePreview   `json:"preview,omitempty"`
 	Children    []FileMetadata `json:"children,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"` // children were omitted to respect max_entries
 	Error       string         `json:"error,omitempty"`
 }
 
+// Recursive listing bounds applied when the arguments omit or exceed them.
+const (
+	defaultMaxDepth   = 8
+	maxMaxDepth       = 32
+	defaultMaxEntries = 1000
+	maxMaxEntries     = 10000
+)
+
 // FSMetadataTool implements a tool for retrieving filesystem metadata.
 type FSMetadataTool struct {
-	basePath string // Optional base path for security
+	basePath string // Optional sandbox root; paths are resolved with SandboxedPath
 }
 
 // NewFSMetadataTool creates a new filesystem metadata tool.
@@ -86,8 +115,11 @@ func (t *FSMetadataTool) Invoke(ctx context.Context, args json.RawMessage) (any,
 	var params struct {
 		Path            string `json:"path"`
 		IncludeContents bool   `json:"include_contents"`
+		IncludePreview  bool   `json:"include_preview"`
 		MaxContentSize  int    `json:"max_content_size"`
 		Recursive       bool   `json:"recursive"`
+		MaxDepth        int    `json:"max_depth"`
+		MaxEntries      int    `json:"max_entries"`
 	}
 
 	if err := json.Unmarshal(args, &params); err != nil {
@@ -106,33 +138,82 @@ func (t *FSMetadataTool) Invoke(ctx context.Context, args json.RawMessage) (any,
 	if params.MaxContentSize > 1048576 {
 		params.MaxContentSize = 1048576
 	}
-
-	// Validate path for security (prevent directory traversal)
-	cleanPath := filepath.Clean(params.Path)
-	if strings.Contains(cleanPath, "..") {
-		return nil, fmt.Errorf("path contains directory traversal: %s", params.Path)
+	if params.MaxDepth <= 0 {
+		params.MaxDepth = defaultMaxDepth
+	}
+	if params.MaxDepth > maxMaxDepth {
+		params.MaxDepth = maxMaxDepth
+	}
+	if params.MaxEntries <= 0 {
+		params.MaxEntries = defaultMaxEntries
+	}
+	if params.MaxEntries > maxMaxEntries {
+		params.MaxEntries = maxMaxEntries
 	}
 
-	// Resolve path (add base path if configured)
-	fullPath := cleanPath
-	if t.basePath != "" {
-		fullPath = filepath.Join(t.basePath, cleanPath)
+	// Resolve path, confined to the base path when one is configured
+	fullPath, err := t.resolvePath(params.Path)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get metadata
-	metadata, err := t.getMetadata(fullPath, params.IncludeContents, params.MaxContentSize, params.Recursive)
+	opts := metadataOptions{
+		includeContents: params.IncludeContents,
+		includePreview:  params.IncludePreview,
+		maxContentSize:  params.MaxContentSize,
+	}
+	if params.Recursive {
+		opts.maxDepth = params.MaxDepth
+		opts.remaining = params.MaxEntries
+	}
+	metadata, err := t.getMetadata(fullPath, &opts, 0)
 	if err != nil {
 		return FileMetadata{
 			Path:  params.Path,
 			Error: err.Error(),
 		}, nil
 	}
+	// Flag the root too, so a cut deep in the tree is visible at the top
+	if opts.truncated {
+		metadata.Truncated = true
+	}
 
 	return metadata, nil
 }
 
-// getMetadata retrieves metadata for a file or directory.
-func (t *FSMetadataTool) getMetadata(path string, includeContents bool, maxContentSize int, recursive bool) (FileMetadata, error) {
+// resolvePath validates a requested path. With a base path it must resolve inside it;
+// without one only directory traversal is rejected.
+func (t *FSMetadataTool) resolvePath(path string) (string, error) {
+	if t.basePath != "" {
+		resolved, err := SandboxedPath(t.basePath, path)
+		if err != nil {
+			return "", fmt.Errorf("invalid path %s: %w", path, err)
+		}
+		return resolved, nil
+	}
+
+	cleanPath := filepath.Clean(path)
+	if strings.Contains(cleanPath, "..") {
+		return "", fmt.Errorf("path contains directory traversal: %s", path)
+	}
+	return cleanPath, nil
+}
+
+// metadataOptions controls which optional content is gathered per file and bounds a
+// recursive walk. A zero maxDepth lists no children.
+type metadataOptions struct {
+	includeContents bool
+	includePreview  bool
+	maxContentSize  int
+	maxDepth        int  // directory levels below the requested path to list
+	remaining       int  // nested entries still allowed in the response
+	truncated       bool // some entries were omitted because remaining ran out
+}
+
+// getMetadata retrieves metadata for a file or directory at depth levels below the
+// requested path, listing directory children while depth is under opts.maxDepth.
+func (t *FSMetadataTool) getMetadata(path string, opts *metadataOptions, depth int) (FileMetadata, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return FileMetadata{}, fmt.Errorf("failed to stat path: %w", err)
@@ -151,16 +232,30 @@ func (t *FSMetadataTool) getMetadata(path string, includeContents bool, maxConte
 	if info.IsDir() {
 		metadata.Type = "directory"
 
-		if recursive {
+		if depth < opts.maxDepth {
 			entries, err := os.ReadDir(path)
 			if err != nil {
 				return metadata, fmt.Errorf("failed to read directory: %w", err)
 			}
 
-			children := make([]FileMetadata, 0, len(entries))
+			children := make([]FileMetadata, 0, min(len(entries), opts.remaining))
 			for _, entry := range entries {
+				if opts.remaining <= 0 {
+					metadata.Truncated = true
+					opts.truncated = true
+					break
+				}
+				opts.remaining--
+
 				childPath := filepath.Join(path, entry.Name())
-				childMetadata, err := t.getMetadata(childPath, includeContents, maxContentSize, false)
+				if t.basePath != "" {
+					// Skip entries such as symlinks that point outside the sandbox
+					if _, err := SandboxedPath(t.basePath, childPath); err != nil {
+						children = append(children, FileMetadata{Path: childPath, Name: entry.Name(), Error: err.Error()})
+						continue
+					}
+				}
+				childMetadata, err := t.getMetadata(childPath, opts, depth+1)
 				if err != nil {
 					childMetadata = FileMetadata{
 						Path:  childPath,
@@ -182,14 +277,23 @@ func (t *FSMetadataTool) getMetadata(path string, includeContents bool, maxConte
 		}
 
 		// Include contents if requested and it's a text file
-		if includeContents && t.isTextFile(path) {
-			content, err := t.readFileContent(path, maxContentSize)
+		if opts.includeContents && t.isTextFile(path) {
+			content, err := t.readFileContent(path, opts.maxContentSize)
 			if err != nil {
 				metadata.Error = fmt.Sprintf("failed to read contents: %v", err)
 			} else {
 				metadata.Contents = content
 			}
 		}
+
+		// Include a preview selected by the sniffed content type
+		if opts.includePreview {
+			preview, err := t.generatePreview(path, opts.maxContentSize)
+			if err != nil && metadata.Error == "" {
+				metadata.Error = fmt.Sprintf("failed to generate preview: %v", err)
+			}
+			metadata.Preview = preview
+		}
 	}
 
 	return metadata, nil
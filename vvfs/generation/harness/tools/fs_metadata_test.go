@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// invokePreview runs the tool with previews enabled and returns the file metadata
+func invokePreview(t *testing.T, tool *FSMetadataTool, path string, maxSize int) FileMetadata {
+	args, err := json.Marshal(map[string]any{
+		"path":             path,
+		"include_preview":  true,
+		"max_content_size": maxSize,
+	})
+	require.NoError(t, err)
+
+	result, err := tool.Invoke(context.Background(), args)
+	require.NoError(t, err)
+
+	metadata, ok := result.(FileMetadata)
+	require.True(t, ok)
+	require.Empty(t, metadata.Error)
+	require.NotNil(t, metadata.Preview)
+	return metadata
+}
+
+func TestFSMetadataTool_PreviewText(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.log"), []byte(strings.Join(lines, "\n")), 0o644))
+
+	tool := NewFSMetadataTool(dir)
+	metadata := invokePreview(t, tool, "notes.log", 8192)
+
+	assert.Equal(t, "text", metadata.Preview.Kind)
+	assert.True(t, strings.HasPrefix(metadata.Preview.Text, "line 0\nline 1\n"))
+	assert.Len(t, strings.Split(strings.TrimSuffix(metadata.Preview.Text, "\n"), "\n"), previewTextLines)
+	assert.True(t, metadata.Preview.Truncated)
+
+	// The byte budget bounds the preview too
+	metadata = invokePreview(t, tool, "notes.log", 16)
+	assert.LessOrEqual(t, len(metadata.Preview.Text), 16)
+}
+
+func TestFSMetadataTool_PreviewImage(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for x := 0; x < 200; x++ {
+		img.Set(x, x%100, color.RGBA{R: 255, A: 255})
+	}
+	f, err := os.Create(filepath.Join(dir, "photo.png"))
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, img))
+	require.NoError(t, f.Close())
+
+	metadata := invokePreview(t, NewFSMetadataTool(dir), "photo.png", 65536)
+
+	assert.Equal(t, "image", metadata.Preview.Kind)
+	assert.Equal(t, 200, metadata.Preview.Width)
+	assert.Equal(t, 100, metadata.Preview.Height)
+	assert.True(t, strings.HasPrefix(metadata.Preview.Thumbnail, "data:image/png;base64,"))
+}
+
+func TestFSMetadataTool_PreviewZip(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "bundle.zip"))
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"README.md", "src/main.go"} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte("content"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	metadata := invokePreview(t, NewFSMetadataTool(dir), "bundle.zip", 8192)
+
+	assert.Equal(t, "archive", metadata.Preview.Kind)
+	assert.Equal(t, []string{"README.md", "src/main.go"}, metadata.Preview.Entries)
+	assert.False(t, metadata.Preview.Truncated)
+
+	// The listing is bounded by max_content_size rather than an entry count
+	metadata = invokePreview(t, NewFSMetadataTool(dir), "bundle.zip", 12)
+	assert.Equal(t, []string{"README.md"}, metadata.Preview.Entries)
+	assert.True(t, metadata.Preview.Truncated)
+}
+
+// writeTar writes a tar stream with one small file per name to w
+func writeTar(t *testing.T, w io.Writer, names []string) {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 7}))
+		_, err := tw.Write([]byte("content"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func TestFSMetadataTool_PreviewTar(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"README.md", "src/main.go", "src/util.go"}
+
+	var buf bytes.Buffer
+	writeTar(t, &buf, names)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.tar"), buf.Bytes(), 0o644))
+
+	metadata := invokePreview(t, NewFSMetadataTool(dir), "bundle.tar", 8192)
+
+	assert.Equal(t, "archive", metadata.Preview.Kind)
+	assert.Equal(t, "application/x-tar", metadata.Preview.MimeType)
+	assert.Equal(t, names, metadata.Preview.Entries)
+	assert.False(t, metadata.Preview.Truncated)
+
+	metadata = invokePreview(t, NewFSMetadataTool(dir), "bundle.tar", 24)
+	assert.Equal(t, names[:2], metadata.Preview.Entries)
+	assert.True(t, metadata.Preview.Truncated)
+}
+
+func TestFSMetadataTool_PreviewTarGz(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"README.md", "src/main.go"}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	writeTar(t, gz, names)
+	require.NoError(t, gz.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bundle.tar.gz"), buf.Bytes(), 0o644))
+
+	metadata := invokePreview(t, NewFSMetadataTool(dir), "bundle.tar.gz", 8192)
+
+	assert.Equal(t, "archive", metadata.Preview.Kind)
+	assert.Equal(t, "application/x-tar+gzip", metadata.Preview.MimeType)
+	assert.Equal(t, names, metadata.Preview.Entries)
+}
+
+// writePDF writes a single-page PDF whose content stream is stream
+func writePDF(t *testing.T, path string, stream []byte, filter string) {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n1 0 obj\n<< /Length ")
+	b.WriteString(fmt.Sprint(len(stream)))
+	b.WriteString(filter)
+	b.WriteString(" >>\nstream\n")
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\n%%EOF\n")
+	require.NoError(t, os.WriteFile(path, b.Bytes(), 0o644))
+}
+
+func TestFSMetadataTool_PreviewPDF(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte(`BT /F1 12 Tf (Quarterly \(Q4\) report) Tj [(Revenue ) -250 (grew)] TJ ET`)
+	writePDF(t, filepath.Join(dir, "plain.pdf"), content, "")
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, err := zw.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	writePDF(t, filepath.Join(dir, "flate.pdf"), compressed.Bytes(), " /Filter /FlateDecode")
+
+	tool := NewFSMetadataTool(dir)
+	for _, name := range []string{"plain.pdf", "flate.pdf"} {
+		metadata := invokePreview(t, tool, name, 8192)
+		assert.Equal(t, "pdf", metadata.Preview.Kind, name)
+		assert.Equal(t, "application/pdf", metadata.Preview.MimeType, name)
+		assert.Equal(t, "Quarterly (Q4) report Revenue grew", metadata.Preview.Text, name)
+		assert.False(t, metadata.Preview.Truncated, name)
+	}
+
+	// The byte budget bounds the extracted text
+	metadata := invokePreview(t, tool, "plain.pdf", 24)
+	assert.Equal(t, "Quarterly (Q4) report Re", metadata.Preview.Text)
+	assert.True(t, metadata.Preview.Truncated)
+}
+
+// writeTree creates a/b/c three levels deep with one file per level
+func writeTree(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a", "b", "c"), 0o755))
+	for _, file := range []string{"top.txt", "a/a.txt", "a/b/b.txt", "a/b/c/c.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(file), 0o644))
+	}
+	return dir
+}
+
+// invokeRecursive runs a recursive listing of the tool's root with extra arguments
+func invokeRecursive(t *testing.T, tool *FSMetadataTool, extra map[string]any) FileMetadata {
+	args := map[string]any{"path": ".", "recursive": true}
+	for key, value := range extra {
+		args[key] = value
+	}
+	raw, err := json.Marshal(args)
+	require.NoError(t, err)
+
+	result, err := tool.Invoke(context.Background(), raw)
+	require.NoError(t, err)
+	metadata, ok := result.(FileMetadata)
+	require.True(t, ok)
+	require.Empty(t, metadata.Error)
+	return metadata
+}
+
+// childNamed returns the child of metadata with the given name
+func childNamed(t *testing.T, metadata FileMetadata, name string) FileMetadata {
+	t.Helper()
+	for _, child := range metadata.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	t.Fatalf("%s has no child %s", metadata.Name, name)
+	return FileMetadata{}
+}
+
+// countEntries counts the nested entries below metadata
+func countEntries(metadata FileMetadata) int {
+	count := len(metadata.Children)
+	for _, child := range metadata.Children {
+		count += countEntries(child)
+	}
+	return count
+}
+
+func TestFSMetadataTool_RecursiveDepth(t *testing.T) {
+	tool := NewFSMetadataTool(writeTree(t))
+
+	// The default depth walks the whole tree
+	metadata := invokeRecursive(t, tool, nil)
+	c := childNamed(t, childNamed(t, childNamed(t, metadata, "a"), "b"), "c")
+	assert.Equal(t, "c.txt", childNamed(t, c, "c.txt").Name)
+	assert.Equal(t, 7, countEntries(metadata))
+	assert.False(t, metadata.Truncated)
+
+	// max_depth stops the walk after that many levels
+	metadata = invokeRecursive(t, tool, map[string]any{"max_depth": 2})
+	b := childNamed(t, childNamed(t, metadata, "a"), "b")
+	assert.Equal(t, "directory", b.Type)
+	assert.Empty(t, b.Children)
+	assert.Equal(t, 4, countEntries(metadata))
+	assert.False(t, metadata.Truncated)
+
+	// Without recursive only the path itself is described
+	raw, err := json.Marshal(map[string]any{"path": ".", "max_depth": 2})
+	require.NoError(t, err)
+	result, err := tool.Invoke(context.Background(), raw)
+	require.NoError(t, err)
+	assert.Empty(t, result.(FileMetadata).Children)
+}
+
+func TestFSMetadataTool_RecursiveEntryCap(t *testing.T) {
+	tool := NewFSMetadataTool(writeTree(t))
+
+	metadata := invokeRecursive(t, tool, map[string]any{"max_entries": 3})
+	assert.Equal(t, 3, countEntries(metadata))
+	assert.True(t, metadata.Truncated, "the root reports the cut")
+
+	// a/b ran out of budget before listing anything; top.txt was never reached
+	a := childNamed(t, metadata, "a")
+	b := childNamed(t, a, "b")
+	assert.True(t, b.Truncated)
+	assert.Empty(t, b.Children)
+	assert.Len(t, metadata.Children, 1)
+}
@@ -0,0 +1,309 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register GIF decoder for DecodeConfig
+	_ "image/jpeg" // register JPEG decoder for DecodeConfig
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	previewTextLines     = 20       // lines returned for text previews
+	previewThumbnailSize = 64       // longest thumbnail edge in pixels
+	previewMaxPixels     = 16 << 20 // refuse to decode images larger than this
+	previewPDFScanFactor = 8        // PDF bytes scanned per byte of output budget
+)
+
+// FilePreview is a bounded, type-specific summary of a file's contents.
+type FilePreview struct {
+	Kind      string   `json:"kind"` // "text", "pdf", "image", "archive", "binary"
+	MimeType  string   `json:"mime_type"`
+	Text      string   `json:"text,omitempty"`
+	Width     int      `json:"width,omitempty"`
+	Height    int      `json:"height,omitempty"`
+	Thumbnail string   `json:"thumbnail,omitempty"` // data URI (image/png)
+	Entries   []string `json:"entries,omitempty"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// generatePreview sniffs the file type and builds a preview bounded by maxSize bytes.
+// Files are only parsed, never executed.
+func (t *FSMetadataTool) generatePreview(path string, maxSize int) (*FilePreview, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	mimeType := http.DetectContentType(head)
+	preview := &FilePreview{MimeType: mimeType}
+
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		preview.Kind = "image"
+		err = t.previewImage(file, maxSize, preview)
+	case mimeType == "application/pdf":
+		preview.Kind = "pdf"
+		err = t.previewPDF(file, maxSize, preview)
+	case mimeType == "application/zip":
+		preview.Kind = "archive"
+		err = t.previewZip(path, maxSize, preview)
+	case mimeType == "application/x-gzip" && isTarName(path):
+		preview.Kind = "archive"
+		err = t.previewTarGz(file, maxSize, preview)
+	case isTarHeader(head):
+		preview.Kind = "archive"
+		preview.MimeType = "application/x-tar"
+		err = t.previewTar(file, maxSize, preview)
+	case strings.HasPrefix(mimeType, "text/") || utf8.Valid(head):
+		preview.Kind = "text"
+		err = t.previewText(file, maxSize, preview)
+	default:
+		preview.Kind = "binary"
+	}
+	if err != nil {
+		return preview, err
+	}
+
+	return preview, nil
+}
+
+// previewText returns the first lines of a text file within maxSize bytes.
+func (t *FSMetadataTool) previewText(r io.Reader, maxSize int, preview *FilePreview) error {
+	scanner := bufio.NewScanner(io.LimitReader(r, int64(maxSize)+1))
+	scanner.Buffer(make([]byte, 0, 4096), maxSize+1)
+
+	var b strings.Builder
+	lines := 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		if lines >= previewTextLines || b.Len()+len(line)+1 > maxSize {
+			preview.Truncated = true
+			break
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+		lines++
+	}
+	if err := scanner.Err(); err != nil && err != bufio.ErrTooLong {
+		return err
+	}
+
+	preview.Text = b.String()
+	return nil
+}
+
+// previewImage reports dimensions and, when within budget, a PNG thumbnail data URI.
+func (t *FSMetadataTool) previewImage(r io.ReadSeeker, maxSize int, preview *FilePreview) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("failed to decode image header: %w", err)
+	}
+	preview.Width = cfg.Width
+	preview.Height = cfg.Height
+
+	if cfg.Width*cfg.Height > previewMaxPixels {
+		preview.Truncated = true
+		return nil
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		// Dimensions are still useful without a thumbnail
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, thumbnail(img, previewThumbnailSize)); err != nil {
+		return nil
+	}
+	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	if len(uri) > maxSize {
+		preview.Truncated = true
+		return nil
+	}
+	preview.Thumbnail = uri
+	return nil
+}
+
+// thumbnail downsamples img so its longest edge is at most size (nearest neighbour).
+func thumbnail(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	tw, th := size, size
+	if w > h {
+		th = max(1, h*size/w)
+	} else {
+		tw = max(1, w*size/h)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		for x := 0; x < tw; x++ {
+			c := img.At(bounds.Min.X+x*w/tw, bounds.Min.Y+y*h/th)
+			dst.Set(x, y, color.RGBAModel.Convert(c))
+		}
+	}
+	return dst
+}
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\nendstream`)
+	pdfTextPattern   = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)\s*(?:Tj|'|")|\[((?:\\.|[^\]])*)\]\s*TJ`)
+	pdfArrayString   = regexp.MustCompile(`\(((?:\\.|[^\\)])*)\)`)
+)
+
+// previewPDF extracts a text snippet from the page content streams.
+// Only plain and Flate-compressed streams are inspected.
+func (t *FSMetadataTool) previewPDF(r io.Reader, maxSize int, preview *FilePreview) error {
+	data, err := io.ReadAll(io.LimitReader(r, int64(maxSize)*previewPDFScanFactor))
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for _, match := range pdfStreamPattern.FindAllSubmatch(data, -1) {
+		content := match[1]
+		if zr, err := zlib.NewReader(bytes.NewReader(content)); err == nil {
+			if inflated, err := io.ReadAll(io.LimitReader(zr, int64(maxSize)*previewPDFScanFactor)); err == nil {
+				content = inflated
+			}
+			zr.Close()
+		}
+
+		for _, text := range pdfTextPattern.FindAllSubmatch(content, -1) {
+			if len(text[1]) > 0 {
+				b.WriteString(unescapePDFString(text[1]))
+			} else {
+				for _, part := range pdfArrayString.FindAllSubmatch(text[2], -1) {
+					b.WriteString(unescapePDFString(part[1]))
+				}
+			}
+			b.WriteByte(' ')
+			if b.Len() >= maxSize {
+				break
+			}
+		}
+		if b.Len() >= maxSize {
+			preview.Truncated = true
+			break
+		}
+	}
+
+	snippet := strings.Join(strings.Fields(b.String()), " ")
+	if len(snippet) > maxSize {
+		snippet = snippet[:maxSize]
+		preview.Truncated = true
+	}
+	preview.Text = snippet
+	return nil
+}
+
+// unescapePDFString resolves the common backslash escapes of PDF literal strings.
+func unescapePDFString(s []byte) string {
+	replacer := strings.NewReplacer(`\(`, "(", `\)`, ")", `\\`, `\`, `\n`, "\n", `\r`, "", `\t`, " ")
+	return replacer.Replace(string(s))
+}
+
+// previewZip lists the entries of a zip archive within maxSize bytes of names.
+func (t *FSMetadataTool) previewZip(path string, maxSize int, preview *FilePreview) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer zr.Close()
+
+	used := 0
+	for _, f := range zr.File {
+		if !addArchiveEntry(preview, f.Name, &used, maxSize) {
+			break
+		}
+	}
+	return nil
+}
+
+// previewTarGz lists the entries of a gzip-compressed tar archive.
+func (t *FSMetadataTool) previewTarGz(r io.Reader, maxSize int, preview *FilePreview) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to open gzip: %w", err)
+	}
+	defer gz.Close()
+	preview.MimeType = "application/x-tar+gzip"
+	return t.previewTar(gz, maxSize, preview)
+}
+
+// previewTar lists the entries of a tar archive within maxSize bytes of names.
+func (t *FSMetadataTool) previewTar(r io.Reader, maxSize int, preview *FilePreview) error {
+	tr := tar.NewReader(r)
+	used := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+		if !addArchiveEntry(preview, hdr.Name, &used, maxSize) {
+			return nil
+		}
+	}
+}
+
+// addArchiveEntry appends name to the listing while the names, one per line,
+// fit in maxSize bytes. It marks the preview truncated and reports false once
+// the budget is exhausted.
+func addArchiveEntry(preview *FilePreview, name string, used *int, maxSize int) bool {
+	if *used+len(name) > maxSize {
+		preview.Truncated = true
+		return false
+	}
+	*used += len(name) + 1
+	preview.Entries = append(preview.Entries, name)
+	return true
+}
+
+// isTarHeader reports whether head starts with a POSIX/GNU tar header.
+func isTarHeader(head []byte) bool {
+	return len(head) >= 262 && bytes.HasPrefix(head[257:], []byte("ustar"))
+}
+
+// isTarName reports whether a gzip file name denotes a tarball.
+func isTarName(path string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
@@ -2,8 +2,12 @@ package tools
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"sort"
 	"strings"
 
 	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
@@ -36,6 +40,32 @@ const KGSchema = `{
       "type": "boolean",
       "description": "Include related entities in results",
       "default": true
+    },
+    "min_score": {
+      "type": "number",
+      "description": "Drop results scoring below this similarity",
+      "minimum": 0,
+      "maximum": 1,
+      "default": 0
+    },
+    "format": {
+      "type": "string",
+      "enum": ["compact", "verbose"],
+      "description": "compact omits relations and metadata; verbose includes every field",
+      "default": "compact"
+    },
+    "fields": {
+      "type": "array",
+      "items": {
+        "type": "string",
+        "enum": ["entity_id", "entity_type", "name", "description", "score", "relations", "metadata"]
+      },
+      "description": "Fields to include for each entity; overrides format"
+    },
+    "dedup": {
+      "type": "boolean",
+      "description": "Collapse entities with the same name, keeping the highest scoring one",
+      "default": true
     }
   },
   "required": ["query"]
@@ -59,15 +89,60 @@ type KGRelation struct {
 	TargetName   string `json:"target_name"`
 }
 
+// DefaultKGMaxEntities bounds how many entities a kg_search result surfaces.
+const DefaultKGMaxEntities = 20
+
+// kgCompactFields are the entity fields kept by the compact format.
+var kgCompactFields = []string{"entity_id", "entity_type", "name", "description", "score"}
+
+// kgVerboseFields are the entity fields kept by the verbose format.
+var kgVerboseFields = []string{"entity_id", "entity_type", "name", "description", "score", "relations", "metadata"}
+
+// kgSearchParams are the arguments of a kg_search call.
+type kgSearchParams struct {
+	Query            string   `json:"query"`
+	Limit            int      `json:"limit"`
+	EntityTypes      []string `json:"entity_types"`
+	IncludeRelations bool     `json:"include_relations"`
+	MinScore         float32  `json:"min_score"`
+	Format           string   `json:"format"`
+	Fields           []string `json:"fields"`
+	Dedup            *bool    `json:"dedup"`
+}
+
 // KGSearchTool implements a tool for searching the knowledge graph.
+// Results are formatted for the prompt: duplicates are collapsed, the entity count is
+// capped and, unless verbose output is requested, relations and metadata are omitted.
+// With an artifact store set, the unformatted results are archived for fetch_artifact
+// whenever formatting dropped detail.
 type KGSearchTool struct {
 	// In a real implementation, this would have access to the memory service
 	// For now, we'll implement a stub that demonstrates the interface
+	search      func(ctx context.Context, params kgSearchParams) ([]KGSearchResult, error)
+	maxEntities int
+	store       ports.ConversationStore
 }
 
 // NewKGSearchTool creates a new KG search tool.
 func NewKGSearchTool() *KGSearchTool {
-	return &KGSearchTool{}
+	t := &KGSearchTool{maxEntities: DefaultKGMaxEntities}
+	t.search = t.performMockSearch
+	return t
+}
+
+// SetMaxEntities caps how many entities a result surfaces; non-positive values restore
+// DefaultKGMaxEntities.
+func (t *KGSearchTool) SetMaxEntities(n int) {
+	if n <= 0 {
+		n = DefaultKGMaxEntities
+	}
+	t.maxEntities = n
+}
+
+// SetArtifactStore archives full results in store when formatting drops detail, so they
+// can be read back with fetch_artifact. Archiving needs the conversation of the call.
+func (t *KGSearchTool) SetArtifactStore(store ports.ConversationStore) {
+	t.store = store
 }
 
 // Name returns the tool name.
@@ -83,13 +158,7 @@ func (t *KGSearchTool) Schema() []byte {
 // Invoke executes the KG search tool.
 func (t *KGSearchTool) Invoke(ctx context.Context, args json.RawMessage) (any, error) {
 	// Parse arguments with validation
-	var params struct {
-		Query            string   `json:"query"`
-		Limit            int      `json:"limit"`
-		EntityTypes      []string `json:"entity_types"`
-		IncludeRelations bool     `json:"include_relations"`
-	}
-
+	var params kgSearchParams
 	if err := json.Unmarshal(args, &params); err != nil {
 		return nil, fmt.Errorf("invalid arguments: %w", err)
 	}
@@ -119,25 +188,151 @@ func (t *KGSearchTool) Invoke(ctx context.Context, args json.RawMessage) (any, e
 		}
 	}
 
+	fields, err := kgResultFields(params)
+	if err != nil {
+		return nil, err
+	}
+
 	// In a real implementation, this would query the memory service
 	// For demonstration, we'll return mock results
-	results := t.performMockSearch(params)
+	results, err := t.search(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search knowledge graph: %w", err)
+	}
 
-	return map[string]any{
+	surfaced, omitted := t.selectResults(results, params)
+	formatted := make([]map[string]any, len(surfaced))
+	for i, r := range surfaced {
+		formatted[i] = formatKGResult(r, fields)
+	}
+
+	output := map[string]any{
 		"query":   params.Query,
-		"results": results,
-		"total":   len(results),
-	}, nil
+		"results": formatted,
+		"total":   len(formatted),
+	}
+	if omitted > 0 {
+		output["omitted"] = omitted
+	}
+
+	// Keep the unformatted results reachable when the prompt copy lost any of them
+	if omitted > 0 || len(fields) < len(kgVerboseFields) {
+		if name, ok := t.archive(ctx, params.Query, results); ok {
+			output["full_results"] = fmt.Sprintf("fetch_artifact name=%q", name)
+		}
+	}
+	return output, nil
+}
+
+// kgResultFields resolves the entity fields to serialize from the format and field selection.
+func kgResultFields(params kgSearchParams) ([]string, error) {
+	if len(params.Fields) > 0 {
+		for _, field := range params.Fields {
+			if !slices.Contains(kgVerboseFields, field) {
+				return nil, fmt.Errorf("invalid field: %s", field)
+			}
+		}
+		return params.Fields, nil
+	}
+
+	switch params.Format {
+	case "", "compact":
+		return kgCompactFields, nil
+	case "verbose":
+		return kgVerboseFields, nil
+	default:
+		return nil, fmt.Errorf("invalid format: %s", params.Format)
+	}
+}
+
+// selectResults filters results by score, collapses duplicate names and applies the entity
+// cap. It returns the results to surface, best first, and how many were dropped.
+func (t *KGSearchTool) selectResults(results []KGSearchResult, params kgSearchParams) ([]KGSearchResult, int) {
+	ranked := make([]KGSearchResult, 0, len(results))
+	for _, r := range results {
+		if r.Score >= params.MinScore {
+			ranked = append(ranked, r)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+
+	if params.Dedup == nil || *params.Dedup {
+		seen := make(map[string]bool, len(ranked))
+		unique := ranked[:0]
+		for _, r := range ranked {
+			key := strings.ToLower(strings.TrimSpace(r.Name))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			unique = append(unique, r)
+		}
+		ranked = unique
+	}
+
+	limit := min(params.Limit, t.maxEntities)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked, len(results) - len(ranked)
+}
+
+// formatKGResult serializes the selected fields of r, leaving out empty relations and metadata.
+func formatKGResult(r KGSearchResult, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "entity_id":
+			out[field] = r.EntityID
+		case "entity_type":
+			out[field] = r.EntityType
+		case "name":
+			out[field] = r.Name
+		case "description":
+			out[field] = r.Description
+		case "score":
+			out[field] = r.Score
+		case "relations":
+			if len(r.Relations) > 0 {
+				out[field] = r.Relations
+			}
+		case "metadata":
+			if len(r.Metadata) > 0 {
+				out[field] = r.Metadata
+			}
+		}
+	}
+	return out
+}
+
+// archive stores the unformatted results in the calling conversation and returns the
+// artifact name. Failures are not fatal; the formatted result is still returned.
+func (t *KGSearchTool) archive(ctx context.Context, query string, results []KGSearchResult) (string, bool) {
+	if t.store == nil {
+		return "", false
+	}
+	conversationID, ok := ports.ConversationIDFromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	payload, err := json.Marshal(KGSearchToolResult{Query: query, Results: results, Total: len(results)})
+	if err != nil {
+		return "", false
+	}
+	var suffix [6]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return "", false
+	}
+	name := t.Name() + "-" + hex.EncodeToString(suffix[:])
+	if err := t.store.AppendToolArtifact(ctx, conversationID, name, payload); err != nil {
+		return "", false
+	}
+	return name, true
 }
 
 // performMockSearch simulates KG search for demonstration.
-func (t *KGSearchTool) performMockSearch(params struct {
-	Query            string   `json:"query"`
-	Limit            int      `json:"limit"`
-	EntityTypes      []string `json:"entity_types"`
-	IncludeRelations bool     `json:"include_relations"`
-},
-) []KGSearchResult {
+func (t *KGSearchTool) performMockSearch(_ context.Context, params kgSearchParams) ([]KGSearchResult, error) {
 	// Mock results based on query
 	var results []KGSearchResult
 
@@ -191,7 +386,7 @@ func (t *KGSearchTool) performMockSearch(params struct {
 		results = results[:params.Limit]
 	}
 
-	return results
+	return results, nil
 }
 
 // KGSearchToolResult represents the complete tool response.
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// artifactRecorder is a conversation store that only records archived artifacts
+type artifactRecorder struct {
+	artifacts map[string][]byte
+}
+
+func (s *artifactRecorder) SaveTurn(context.Context, string, ports.Turn) error { return nil }
+
+func (s *artifactRecorder) LoadContext(context.Context, string, int) ([]ports.Turn, error) {
+	return nil, nil
+}
+
+func (s *artifactRecorder) AppendToolArtifact(_ context.Context, conversationID, name string, payload []byte) error {
+	s.artifacts[conversationID+"/"+name] = payload
+	return nil
+}
+
+// newStaticKGSearchTool returns a kg_search tool whose backend always answers with results
+func newStaticKGSearchTool(results ...KGSearchResult) *KGSearchTool {
+	tool := NewKGSearchTool()
+	tool.search = func(context.Context, kgSearchParams) ([]KGSearchResult, error) {
+		return results, nil
+	}
+	return tool
+}
+
+// invokeKGSearch runs the tool with args and returns the formatted result
+func invokeKGSearch(t *testing.T, ctx context.Context, tool *KGSearchTool, args map[string]any) map[string]any {
+	raw, err := json.Marshal(args)
+	require.NoError(t, err)
+
+	result, err := tool.Invoke(ctx, raw)
+	require.NoError(t, err)
+
+	output, ok := result.(map[string]any)
+	require.True(t, ok)
+	return output
+}
+
+func kgEntity(name string, score float32) KGSearchResult {
+	return KGSearchResult{
+		EntityID:    "id-" + name,
+		EntityType:  "Concept",
+		Name:        name,
+		Description: "about " + name,
+		Score:       score,
+		Relations:   []KGRelation{{RelationType: "relates_to", TargetID: "other", TargetName: "Other"}},
+		Metadata:    map[string]string{"source": "test"},
+	}
+}
+
+func TestKGSearchTool_CompactOmitsAttributes(t *testing.T) {
+	tool := newStaticKGSearchTool(kgEntity("alpha", 0.9))
+
+	output := invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha"})
+	results := output["results"].([]map[string]any)
+	require.Len(t, results, 1)
+	assert.Equal(t, "alpha", results[0]["name"])
+	assert.NotContains(t, results[0], "metadata")
+	assert.NotContains(t, results[0], "relations")
+
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha", "format": "verbose"})
+	results = output["results"].([]map[string]any)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]string{"source": "test"}, results[0]["metadata"])
+	assert.Contains(t, results[0], "relations")
+
+	// Field selection overrides the format
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha", "format": "verbose", "fields": []string{"name", "score"}})
+	results = output["results"].([]map[string]any)
+	require.Len(t, results, 1)
+	assert.Equal(t, map[string]any{"name": "alpha", "score": float32(0.9)}, results[0])
+
+	_, err := tool.Invoke(context.Background(), json.RawMessage(`{"query": "alpha", "fields": ["embedding"]}`))
+	assert.Error(t, err)
+	_, err = tool.Invoke(context.Background(), json.RawMessage(`{"query": "alpha", "format": "terse"}`))
+	assert.Error(t, err)
+}
+
+func TestKGSearchTool_DedupKeepsBestScoringEntity(t *testing.T) {
+	low := kgEntity("alpha", 0.4)
+	low.EntityID = "alpha-low"
+	tool := newStaticKGSearchTool(low, kgEntity("beta", 0.6), kgEntity("Alpha ", 0.8))
+
+	output := invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha"})
+	results := output["results"].([]map[string]any)
+	require.Len(t, results, 2)
+	assert.Equal(t, "id-Alpha ", results[0]["entity_id"])
+	assert.Equal(t, "beta", results[1]["name"])
+	assert.Equal(t, 1, output["omitted"])
+
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha", "dedup": false})
+	assert.Len(t, output["results"], 3)
+	assert.NotContains(t, output, "omitted")
+}
+
+func TestKGSearchTool_CapsEntityCount(t *testing.T) {
+	var results []KGSearchResult
+	for i := 0; i < 30; i++ {
+		results = append(results, kgEntity(fmt.Sprintf("entity-%d", i), float32(i)/30))
+	}
+	tool := newStaticKGSearchTool(results...)
+
+	output := invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "entity", "limit": 50})
+	assert.Len(t, output["results"], DefaultKGMaxEntities)
+	assert.Equal(t, 30-DefaultKGMaxEntities, output["omitted"])
+
+	tool.SetMaxEntities(5)
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "entity", "limit": 50})
+	surfaced := output["results"].([]map[string]any)
+	require.Len(t, surfaced, 5)
+	assert.Equal(t, "entity-29", surfaced[0]["name"])
+
+	// The per-call limit and score threshold narrow the result further
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "entity", "limit": 3})
+	assert.Len(t, output["results"], 3)
+	output = invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "entity", "min_score": 0.85})
+	assert.Len(t, output["results"], 4)
+}
+
+func TestKGSearchTool_ArchivesFullResults(t *testing.T) {
+	tool := newStaticKGSearchTool(kgEntity("alpha", 0.9), kgEntity("alpha", 0.5))
+	store := &artifactRecorder{artifacts: make(map[string][]byte)}
+	tool.SetArtifactStore(store)
+
+	// Without a conversation there is nowhere to archive
+	output := invokeKGSearch(t, context.Background(), tool, map[string]any{"query": "alpha"})
+	assert.NotContains(t, output, "full_results")
+	assert.Empty(t, store.artifacts)
+
+	ctx := ports.WithConversationID(context.Background(), "conv-1")
+	output = invokeKGSearch(t, ctx, tool, map[string]any{"query": "alpha"})
+	assert.Contains(t, output["full_results"], "fetch_artifact name=")
+	require.Len(t, store.artifacts, 1)
+	for key, payload := range store.artifacts {
+		assert.Contains(t, key, "conv-1/kg_search-")
+
+		var full KGSearchToolResult
+		require.NoError(t, json.Unmarshal(payload, &full))
+		require.Len(t, full.Results, 2)
+		assert.Equal(t, map[string]string{"source": "test"}, full.Results[0].Metadata)
+	}
+
+	// Verbose output of unique entities loses nothing and is not archived
+	unique := newStaticKGSearchTool(kgEntity("alpha", 0.9))
+	unique.SetArtifactStore(store)
+	output = invokeKGSearch(t, ctx, unique, map[string]any{"query": "alpha", "format": "verbose"})
+	assert.NotContains(t, output, "full_results")
+	assert.Len(t, store.artifacts, 1)
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathEscapesSandbox is returned (wrapped) when a path resolves outside its sandbox root.
+var ErrPathEscapesSandbox = errors.New("path escapes sandbox")
+
+// SandboxedPath resolves a user-supplied path inside base and returns the resulting
+// absolute, symlink-free path. Relative paths are joined to base; absolute paths are
+// accepted only when they already lie within base. Symlinks are resolved for every
+// existing component, so a link pointing outside base is rejected, and components that
+// do not exist yet (e.g. a file about to be written) are appended lexically.
+//
+// The check reflects the filesystem at call time; a symlink swapped in afterwards is
+// not detected, so callers should use the result promptly.
+func SandboxedPath(base, user string) (string, error) {
+	if base == "" {
+		return "", fmt.Errorf("sandbox base path is required")
+	}
+
+	absBase, err := filepath.Abs(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sandbox base: %w", err)
+	}
+	root, err := filepath.EvalSymlinks(absBase)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve sandbox base: %w", err)
+	}
+
+	var joined string
+	if filepath.IsAbs(user) {
+		// Accept absolute paths spelled against either the given or the resolved base
+		rel, ok := relativeTo(absBase, filepath.Clean(user))
+		if !ok {
+			if rel, ok = relativeTo(root, filepath.Clean(user)); !ok {
+				return "", fmt.Errorf("%w: %s", ErrPathEscapesSandbox, user)
+			}
+		}
+		joined = filepath.Join(root, rel)
+	} else {
+		joined = filepath.Join(root, user)
+		if _, ok := relativeTo(root, joined); !ok {
+			return "", fmt.Errorf("%w: %s", ErrPathEscapesSandbox, user)
+		}
+	}
+
+	resolved, err := resolveExisting(joined)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := relativeTo(root, resolved); !ok {
+		return "", fmt.Errorf("%w: %s resolves to %s", ErrPathEscapesSandbox, user, resolved)
+	}
+
+	return resolved, nil
+}
+
+// resolveExisting evaluates symlinks in the longest existing prefix of path and
+// re-appends the components that do not exist yet.
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to resolve path: %w", err)
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("failed to resolve path: %w", err)
+		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
+	}
+}
+
+// relativeTo reports path relative to root and whether it stays inside root.
+func relativeTo(root, path string) (string, bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", false
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandboxedPath(t *testing.T) {
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+
+	base := t.TempDir()
+	root, err := filepath.EvalSymlinks(base)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "docs", "nested"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "docs", "nested", "a.txt"), []byte("a"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(base, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(base, "docs", "secret-link")))
+	require.NoError(t, os.Symlink(filepath.Join(base, "docs"), filepath.Join(base, "docs-link")))
+
+	tests := []struct {
+		name    string
+		user    string
+		want    string // relative to the resolved base; empty when an error is expected
+		wantErr bool
+	}{
+		{name: "nested file", user: "docs/nested/a.txt", want: "docs/nested/a.txt"},
+		{name: "base itself", user: ".", want: "."},
+		{name: "redundant elements", user: "./docs//nested/../nested/a.txt", want: "docs/nested/a.txt"},
+		{name: "not yet created", user: "docs/new/file.txt", want: "docs/new/file.txt"},
+		{name: "dotted file name", user: "docs/a..b.txt", want: "docs/a..b.txt"},
+		{name: "symlink within sandbox", user: "docs-link/nested/a.txt", want: "docs/nested/a.txt"},
+		{name: "absolute inside base", user: filepath.Join(base, "docs", "nested"), want: "docs/nested"},
+		{name: "parent traversal", user: "../secret.txt", wantErr: true},
+		{name: "deep traversal", user: "docs/../../" + filepath.Base(outside) + "/secret.txt", wantErr: true},
+		{name: "absolute outside base", user: filepath.Join(outside, "secret.txt"), wantErr: true},
+		{name: "absolute system path", user: "/etc/passwd", wantErr: true},
+		{name: "symlinked directory escape", user: "escape/secret.txt", wantErr: true},
+		{name: "symlinked file escape", user: "docs/secret-link", wantErr: true},
+		{name: "missing path under escaping symlink", user: "escape/new.txt", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SandboxedPath(base, tt.user)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrPathEscapesSandbox)
+				assert.Empty(t, got)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(root, filepath.FromSlash(tt.want)), got)
+		})
+	}
+
+	_, err = SandboxedPath("", "docs")
+	assert.Error(t, err)
+}
+
+func TestFSMetadataTool_Sandbox(t *testing.T) {
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+
+	base := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(base, "notes.txt"), []byte("notes"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(base, "link.txt")))
+	tool := NewFSMetadataTool(base)
+
+	invoke := func(args map[string]any) (any, error) {
+		raw, err := json.Marshal(args)
+		require.NoError(t, err)
+		return tool.Invoke(context.Background(), raw)
+	}
+
+	_, err := invoke(map[string]any{"path": "link.txt", "include_contents": true})
+	assert.ErrorIs(t, err, ErrPathEscapesSandbox)
+
+	// Recursive listings do not follow links out of the sandbox
+	result, err := invoke(map[string]any{"path": ".", "recursive": true})
+	require.NoError(t, err)
+	metadata := result.(FileMetadata)
+	require.Len(t, metadata.Children, 2)
+	for _, child := range metadata.Children {
+		if child.Name == "link.txt" {
+			assert.Contains(t, child.Error, ErrPathEscapesSandbox.Error())
+		} else {
+			assert.Empty(t, child.Error)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package harness
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// TraceEntryKind identifies what a timeline entry records.
+type TraceEntryKind string
+
+const (
+	TraceSpanStart TraceEntryKind = "span_start"
+	TraceSpanEnd   TraceEntryKind = "span_end"
+	TraceEvent     TraceEntryKind = "event"
+)
+
+// TraceEntry is one step of a recorded orchestration timeline.
+type TraceEntry struct {
+	Seq      int            `json:"seq"`
+	Kind     TraceEntryKind `json:"kind"`
+	Name     string         `json:"name"`
+	SpanID   int            `json:"span_id,omitempty"`   // span started/ended by this entry, or enclosing an event
+	ParentID int            `json:"parent_id,omitempty"` // enclosing span of a span_start
+	Time     time.Time      `json:"time"`
+	Duration time.Duration  `json:"duration,omitempty"` // set on span_end
+	Attrs    map[string]any `json:"attrs,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// recordingSpanKey is the context key holding the ID of the current recorded span.
+type recordingSpanKey struct{}
+
+// RecordingTracer implements ports.Tracer by capturing every span and event into an
+// ordered in-memory timeline that can be inspected or persisted after a run.
+// Attribute payloads are normalized to JSON values and string content is redacted
+// with the guardrails before it is stored. It is safe for concurrent use.
+type RecordingTracer struct {
+	mu         sync.Mutex
+	guardrails *Guardrails
+	entries    []TraceEntry
+	nextSpan   int
+	persist    string // when set, the timeline is written here each time a root span ends
+}
+
+// NewRecordingTracer creates a recorder that redacts payloads with guardrails.
+// A nil guardrails uses NewGuardrails.
+func NewRecordingTracer(guardrails *Guardrails) *RecordingTracer {
+	if guardrails == nil {
+		guardrails = NewGuardrails()
+	}
+	return &RecordingTracer{guardrails: guardrails}
+}
+
+// PersistTo makes the recorder write the timeline as JSON to path whenever a root
+// span (one without a recorded parent) finishes. An empty path disables persistence.
+func (r *RecordingTracer) PersistTo(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.persist = path
+}
+
+// StartSpan records a span_start entry and returns a finish function recording its end.
+func (r *RecordingTracer) StartSpan(ctx context.Context, name string, attrs map[string]any) (context.Context, func(err error)) {
+	parent, _ := ctx.Value(recordingSpanKey{}).(int)
+	start := time.Now()
+
+	r.mu.Lock()
+	r.nextSpan++
+	id := r.nextSpan
+	r.appendLocked(TraceEntry{
+		Kind:     TraceSpanStart,
+		Name:     name,
+		SpanID:   id,
+		ParentID: parent,
+		Time:     start,
+		Attrs:    r.redactAttrs(attrs),
+	})
+	r.mu.Unlock()
+
+	finish := func(err error) {
+		entry := TraceEntry{
+			Kind:     TraceSpanEnd,
+			Name:     name,
+			SpanID:   id,
+			ParentID: parent,
+			Time:     time.Now(),
+			Duration: time.Since(start),
+		}
+		if err != nil {
+			entry.Error = r.guardrails.SanitizeOutput(err.Error())
+		}
+
+		r.mu.Lock()
+		r.appendLocked(entry)
+		path := r.persist
+		r.mu.Unlock()
+
+		// Persistence is best effort; the in-memory timeline stays authoritative
+		if parent == 0 && path != "" {
+			_ = r.SaveJSON(path)
+		}
+	}
+
+	return context.WithValue(ctx, recordingSpanKey{}, id), finish
+}
+
+// Event records an event within the current span.
+func (r *RecordingTracer) Event(ctx context.Context, name string, attrs map[string]any) {
+	span, _ := ctx.Value(recordingSpanKey{}).(int)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.appendLocked(TraceEntry{
+		Kind:   TraceEvent,
+		Name:   name,
+		SpanID: span,
+		Time:   time.Now(),
+		Attrs:  r.redactAttrs(attrs),
+	})
+}
+
+// Timeline returns a copy of the recorded entries in the order they occurred.
+func (r *RecordingTracer) Timeline() []TraceEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]TraceEntry(nil), r.entries...)
+}
+
+// Reset discards the recorded timeline.
+func (r *RecordingTracer) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+	r.nextSpan = 0
+}
+
+// WriteJSON writes the timeline to w as an indented JSON array.
+func (r *RecordingTracer) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(r.Timeline()); err != nil {
+		return fmt.Errorf("failed to encode trace timeline: %w", err)
+	}
+	return nil
+}
+
+// SaveJSON writes the timeline to the file at path, replacing any previous content.
+func (r *RecordingTracer) SaveJSON(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create trace file: %w", err)
+	}
+	if err := r.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close trace file: %w", err)
+	}
+	return nil
+}
+
+// ReadTraceTimeline decodes a timeline previously written by WriteJSON, for replay.
+func ReadTraceTimeline(rd io.Reader) ([]TraceEntry, error) {
+	var entries []TraceEntry
+	if err := json.NewDecoder(rd).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode trace timeline: %w", err)
+	}
+	return entries, nil
+}
+
+func (r *RecordingTracer) appendLocked(entry TraceEntry) {
+	entry.Seq = len(r.entries) + 1
+	r.entries = append(r.entries, entry)
+}
+
+// redactAttrs converts attrs to plain JSON values so the timeline is serializable and
+// isolated from later mutation, then masks sensitive strings.
+func (r *RecordingTracer) redactAttrs(attrs map[string]any) map[string]any {
+	if len(attrs) == 0 {
+		return nil
+	}
+	redacted := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		redacted[k] = r.redact(normalizeTraceValue(v))
+	}
+	return redacted
+}
+
+func (r *RecordingTracer) redact(v any) any {
+	switch val := v.(type) {
+	case string:
+		return r.guardrails.SanitizeOutput(val)
+	case map[string]any:
+		for k, item := range val {
+			val[k] = r.redact(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = r.redact(item)
+		}
+		return val
+	default:
+		return val
+	}
+}
+
+// normalizeTraceValue round-trips v through JSON; values that cannot be encoded are
+// recorded as their fmt representation.
+func normalizeTraceValue(v any) any {
+	switch val := v.(type) {
+	case nil, string, bool, int, int64, float64:
+		return val
+	case error:
+		return val.Error()
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return normalized
+}
+
+var _ ports.Tracer = (*RecordingTracer)(nil)
@@ -2,7 +2,10 @@ package generation
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness"
@@ -26,18 +29,15 @@ func NewHarnessGenerator(orchestrator *harness.HarnessOrchestrator, conversation
 	}
 }
 
+// ErrSchemaMismatch is returned (wrapped) when a json_schema response does not satisfy the requested schema.
+var ErrSchemaMismatch = errors.New("response does not match JSON schema")
+
 // Generate implements the Generator interface using the harness.
 func (g *HarnessGenerator) Generate(ctx context.Context, req *GenerationRequest) (*GenerationResponse, error) {
 	// Convert GenerationRequest to harness Request
-	harnessReq := &harness.Request{
-		Conversation: &harness.Conversation{
-			ID:       g.conversationID,
-			Messages: g.convertMessages(req.Messages),
-		},
-		System:  "", // System message should be part of conversation messages
-		Context: nil,
-		Tools:   nil, // Tools not part of the original Generator interface
-		Policy:  harness.DefaultPolicy(),
+	harnessReq, err := g.buildRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute orchestration
@@ -46,26 +46,27 @@ func (g *HarnessGenerator) Generate(ctx context.Context, req *GenerationRequest)
 		return nil, fmt.Errorf("harness orchestration failed: %w", err)
 	}
 
+	text, err := g.formatOutput(req, resp.Text)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert harness Response back to GenerationResponse
 	return &GenerationResponse{
-		Text:     resp.Text,
-		Messages: g.convertBackToMessages(req.Messages, resp.Text),
+		Text:     text,
+		Messages: g.convertBackToMessages(req.Messages, text),
 		Usage:    g.convertUsage(resp.Usage),
 	}, nil
 }
 
 // StreamGenerate implements streaming using the harness.
+// A failed generation, including a json_schema response that does not match its schema,
+// is delivered as a response with Err set.
 func (g *HarnessGenerator) StreamGenerate(ctx context.Context, req *GenerationRequest) (<-chan *GenerationResponse, error) {
 	// Convert request
-	harnessReq := &harness.Request{
-		Conversation: &harness.Conversation{
-			ID:       g.conversationID,
-			Messages: g.convertMessages(req.Messages),
-		},
-		System:  "",
-		Context: nil,
-		Tools:   nil,
-		Policy:  harness.DefaultPolicy(),
+	harnessReq, err := g.buildRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get streaming channel
@@ -79,18 +80,20 @@ func (g *HarnessGenerator) StreamGenerate(ctx context.Context, req *GenerationRe
 		select {
 		case resp := <-respCh:
 			if resp != nil {
+				text, err := g.formatOutput(req, resp.Text)
+				if err != nil {
+					resultCh <- &GenerationResponse{Err: err}
+					return
+				}
 				resultCh <- &GenerationResponse{
-					Text:     resp.Text,
-					Messages: g.convertBackToMessages(req.Messages, resp.Text),
+					Text:     text,
+					Messages: g.convertBackToMessages(req.Messages, text),
 					Usage:    g.convertUsage(resp.Usage),
 				}
 			}
 		case err := <-errCh:
-			// For now, we can't easily return errors from the channel
-			// FIXME: In a real implementation, we might want to use a different pattern
 			if err != nil {
-				// Log the error or handle it appropriately
-				fmt.Printf("Streaming error: %v\n", err)
+				resultCh <- &GenerationResponse{Err: fmt.Errorf("harness orchestration failed: %w", err)}
 			}
 		}
 	}()
@@ -98,6 +101,84 @@ func (g *HarnessGenerator) StreamGenerate(ctx context.Context, req *GenerationRe
 	return resultCh, nil
 }
 
+// buildRequest converts a GenerationRequest to a harness Request.
+// A json_schema request forces JSON mode and carries the schema in the system prompt.
+func (g *HarnessGenerator) buildRequest(req *GenerationRequest) (*harness.Request, error) {
+	harnessReq := &harness.Request{
+		Conversation: &harness.Conversation{
+			ID:       g.conversationID,
+			Messages: g.convertMessages(req.Messages),
+		},
+		System:  "", // System message should be part of conversation messages
+		Context: nil,
+		Tools:   nil, // Tools not part of the original Generator interface
+		Policy:  harness.DefaultPolicy(),
+		Options: g.convertOptions(req),
+	}
+
+	switch req.ResponseFormat {
+	case "", ResponseFormatText:
+	case ResponseFormatJSONSchema:
+		if len(req.JSONSchema) == 0 || !json.Valid(req.JSONSchema) {
+			return nil, fmt.Errorf("response format %q requires a valid JSON schema", req.ResponseFormat)
+		}
+		harnessReq.Policy.RequireJSONOutput = true
+		harnessReq.System = "Respond with a single JSON value and no other text. " +
+			"The JSON must conform to this JSON schema:\n" + string(req.JSONSchema)
+	default:
+		return nil, fmt.Errorf("unsupported response format: %q", req.ResponseFormat)
+	}
+
+	return harnessReq, nil
+}
+
+// formatOutput returns text unchanged for text requests. For json_schema requests it extracts
+// the JSON value from the model output and validates it against the requested schema.
+func (g *HarnessGenerator) formatOutput(req *GenerationRequest, text string) (string, error) {
+	if req.ResponseFormat != ResponseFormatJSONSchema {
+		return text, nil
+	}
+
+	data := json.RawMessage(stripCodeFence(text))
+	if !json.Valid(data) {
+		parsed, err := harness.NewOutputParser().ParseJSONOutput(text)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrSchemaMismatch, err)
+		}
+		data = parsed
+	}
+
+	if err := harness.NewJSONValidator().Validate(data, req.JSONSchema); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSchemaMismatch, err)
+	}
+
+	return string(data), nil
+}
+
+// stripCodeFence removes a surrounding markdown code fence such as ```json ... ```.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") || len(text) < 6 {
+		return text
+	}
+	text = strings.TrimSuffix(text[3:], "```")
+	if newline := strings.IndexByte(text, '\n'); newline >= 0 {
+		text = text[newline+1:]
+	}
+	return strings.TrimSpace(text)
+}
+
+// convertOptions maps request sampling parameters to provider options; zero values keep the harness defaults.
+func (g *HarnessGenerator) convertOptions(req *GenerationRequest) *ports.Options {
+	return &ports.Options{
+		MaxNewTokens:      req.MaxTokens,
+		Temperature:       req.Temperature,
+		TopP:              req.TopP,
+		MinP:              req.MinP,
+		RepetitionPenalty: req.RepetitionPenalty,
+	}
+}
+
 // convertMessages converts from the old Message format to harness PromptMessage.
 func (g *HarnessGenerator) convertMessages(messages []Message) []ports.PromptMessage {
 	result := make([]ports.PromptMessage, len(messages))
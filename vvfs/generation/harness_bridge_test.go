@@ -0,0 +1,136 @@
+package generation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/adapters"
+	ports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
+)
+
+// cannedProvider returns a fixed completion and records the last prompt it saw.
+type cannedProvider struct {
+	text       string
+	lastPrompt ports.PromptInput
+}
+
+func (p *cannedProvider) Complete(ctx context.Context, in ports.PromptInput, opts ports.Options) (ports.Completion, error) {
+	p.lastPrompt = in
+	return ports.Completion{Text: p.text}, nil
+}
+
+func (p *cannedProvider) Stream(ctx context.Context, in ports.PromptInput, opts ports.Options) (<-chan ports.CompletionChunk, error) {
+	p.lastPrompt = in
+	ch := make(chan ports.CompletionChunk, 1)
+	ch <- ports.CompletionChunk{DeltaText: p.text, Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func newTestHarnessGenerator(provider ports.Provider) *HarnessGenerator {
+	orchestrator := harness.NewHarnessOrchestrator(
+		provider,
+		harness.NewPromptBuilder(),
+		harness.NewContextAssembler(harness.Budget{MaxContextTokens: 4000, MaxSnippets: 10}, nil),
+		&stubConversationStoreForBridge{},
+		adapters.NewLRUCache(100),
+		adapters.NewTokenBucket(100, 0),
+		adapters.NewZerologTracer(zerolog.Nop()),
+	)
+	return NewHarnessGenerator(orchestrator, "test-conversation")
+}
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer"}
+	},
+	"required": ["name", "age"]
+}`
+
+func jsonSchemaRequest() *GenerationRequest {
+	return &GenerationRequest{
+		Messages:       []Message{{Role: "user", Content: "Describe Ada"}},
+		ResponseFormat: ResponseFormatJSONSchema,
+		JSONSchema:     json.RawMessage(personSchema),
+	}
+}
+
+func TestHarnessGenerator_JSONSchemaConforming(t *testing.T) {
+	provider := &cannedProvider{text: "```json\n{\"name\": \"Ada\", \"age\": 36}\n```"}
+	gen := newTestHarnessGenerator(provider)
+
+	resp, err := gen.Generate(context.Background(), jsonSchemaRequest())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Ada", "age": 36}`, resp.Text)
+	assert.Equal(t, resp.Text, resp.Messages[len(resp.Messages)-1].Content)
+
+	// The schema is injected into the system prompt
+	assert.Contains(t, provider.lastPrompt.System, `"required"`)
+}
+
+func TestHarnessGenerator_JSONSchemaMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"missing field", `{"name": "Ada"}`},
+		{"wrong type", `{"name": "Ada", "age": "thirty-six"}`},
+		{"not json", "Ada was a mathematician."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := newTestHarnessGenerator(&cannedProvider{text: tt.text})
+
+			resp, err := gen.Generate(context.Background(), jsonSchemaRequest())
+			assert.Nil(t, resp)
+			assert.True(t, errors.Is(err, ErrSchemaMismatch), "expected ErrSchemaMismatch, got %v", err)
+		})
+	}
+}
+
+func TestHarnessGenerator_StreamGenerateReportsSchemaMismatch(t *testing.T) {
+	gen := newTestHarnessGenerator(&cannedProvider{text: `{"name": "Ada"}`})
+
+	ch, err := gen.StreamGenerate(context.Background(), jsonSchemaRequest())
+	assert.NoError(t, err)
+	resp, ok := <-ch
+	assert.True(t, ok, "the caller receives the failure rather than a closed channel")
+	assert.True(t, errors.Is(resp.Err, ErrSchemaMismatch), "expected ErrSchemaMismatch, got %v", resp.Err)
+	assert.Empty(t, resp.Text)
+
+	gen = newTestHarnessGenerator(&cannedProvider{text: `{"name": "Ada", "age": 36}`})
+	ch, err = gen.StreamGenerate(context.Background(), jsonSchemaRequest())
+	assert.NoError(t, err)
+	resp = <-ch
+	assert.NoError(t, resp.Err)
+	assert.JSONEq(t, `{"name": "Ada", "age": 36}`, resp.Text)
+}
+
+func TestHarnessGenerator_ResponseFormatValidation(t *testing.T) {
+	gen := newTestHarnessGenerator(&cannedProvider{text: "plain text"})
+
+	// Text responses pass through untouched
+	resp, err := gen.Generate(context.Background(), &GenerationRequest{
+		Messages:       []Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: ResponseFormatText,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "plain text", resp.Text)
+
+	_, err = gen.Generate(context.Background(), &GenerationRequest{ResponseFormat: ResponseFormatJSONSchema})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "requires a valid JSON schema"))
+
+	_, err = gen.Generate(context.Background(), &GenerationRequest{ResponseFormat: "yaml"})
+	assert.Error(t, err)
+}
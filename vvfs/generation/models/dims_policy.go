@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DimsPolicy selects how an embedding whose length differs from the Matryoshka dimension
+// is fitted to it.
+//
+//   - strict: reject the embedding. Safest default, since a silently resized vector
+//     no longer matches the index it is compared against.
+//   - truncate: keep the leading dimensions and re-normalize to unit length. Only
+//     meaningful for Matryoshka-trained models, whose prefixes are embeddings themselves.
+//   - pad: append zeros up to the target dimension.
+type DimsPolicy string
+
+const (
+	DimsPolicyStrict   DimsPolicy = "strict"
+	DimsPolicyTruncate DimsPolicy = "truncate"
+	DimsPolicyPad      DimsPolicy = "pad"
+)
+
+// ErrDimsMismatch is returned when an embedding cannot be fitted to the target dimension
+var ErrDimsMismatch = errors.New("embedding dimension mismatch")
+
+// ParseDimsPolicy validates a configured dimension policy name; empty selects strict
+func ParseDimsPolicy(name string) (DimsPolicy, error) {
+	switch policy := DimsPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return DimsPolicyStrict, nil
+	case DimsPolicyStrict, DimsPolicyTruncate, DimsPolicyPad:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unsupported dims policy %q (expected strict, truncate or pad)", name)
+	}
+}
+
+// SetDimsPolicy selects how embeddings are fitted to the Matryoshka dimension
+func (p *OpenEmbedProvider) SetDimsPolicy(name string) error {
+	policy, err := ParseDimsPolicy(name)
+	if err != nil {
+		return err
+	}
+	p.GGUFProvider.config.DimsPolicy = policy
+	return nil
+}
+
+// GetDimsPolicy returns the configured dimension policy
+func (p *OpenEmbedProvider) GetDimsPolicy() DimsPolicy {
+	if p.GGUFProvider.config.DimsPolicy == "" {
+		return DimsPolicyStrict
+	}
+	return p.GGUFProvider.config.DimsPolicy
+}
+
+// adjustToDims fits the embedding vector to the target dimensions according to the dims policy
+func (p *OpenEmbedProvider) adjustToDims(vec []float32, target int) ([]float32, error) {
+	if len(vec) == target {
+		return vec, nil
+	}
+
+	policy := p.GetDimsPolicy()
+	switch {
+	case policy == DimsPolicyTruncate && len(vec) > target:
+		return truncateNormalized(vec, target), nil
+	case policy == DimsPolicyPad && len(vec) < target:
+		padded := make([]float32, target)
+		copy(padded, vec)
+		return padded, nil
+	default:
+		return nil, fmt.Errorf("%w: model produced %d dims, expected %d (dims policy %s)", ErrDimsMismatch, len(vec), target, policy)
+	}
+}
+
+// truncateNormalized keeps the first target dimensions of vec and rescales them to unit length
+func truncateNormalized(vec []float32, target int) []float32 {
+	out := append([]float32(nil), vec[:target]...)
+
+	var sum float64
+	for _, v := range out {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return out
+	}
+	norm := math.Sqrt(sum)
+	for i := range out {
+		out[i] = float32(float64(out[i]) / norm)
+	}
+	return out
+}
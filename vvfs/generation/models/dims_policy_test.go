@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// newDimsTestProvider returns a provider whose backend produces vector, fitted to target dims
+func newDimsTestProvider(t *testing.T, policy string, target int, vector []float32) *OpenEmbedProvider {
+	t.Helper()
+
+	provider := &OpenEmbedProvider{
+		GGUFProvider:   &GGUFProvider{config: DefaultGGUFConfig("model.gguf", ModelTypeEmbedding)},
+		matryoshkaDims: target,
+	}
+	provider.SetTokenEmbedder(stubTokenEmbedder{tokens: TokenEmbeddings{Vectors: [][]float32{vector}}})
+	if err := provider.SetDimsPolicy(policy); err != nil {
+		t.Fatalf("SetDimsPolicy(%s) failed: %v", policy, err)
+	}
+	return provider
+}
+
+// TestParseDimsPolicy tests policy name validation and the strict default
+func TestParseDimsPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		want DimsPolicy
+	}{
+		{"", DimsPolicyStrict},
+		{"strict", DimsPolicyStrict},
+		{" Truncate ", DimsPolicyTruncate},
+		{"PAD", DimsPolicyPad},
+	}
+	for _, tt := range tests {
+		got, err := ParseDimsPolicy(tt.name)
+		if err != nil {
+			t.Fatalf("ParseDimsPolicy(%q) failed: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseDimsPolicy(%q): expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := ParseDimsPolicy("crop"); err == nil {
+		t.Error("expected error for unsupported dims policy")
+	}
+	if DefaultGGUFConfig("model.gguf", ModelTypeEmbedding).DimsPolicy != DimsPolicyStrict {
+		t.Error("expected strict dims policy by default")
+	}
+}
+
+// TestOpenEmbedProvider_DimsPolicyStrict tests that strict rejects both longer and shorter vectors
+func TestOpenEmbedProvider_DimsPolicyStrict(t *testing.T) {
+	ctx := context.Background()
+
+	for _, vector := range [][]float32{{3, 4, 12}, {1}} {
+		provider := newDimsTestProvider(t, "strict", 2, vector)
+		if _, err := provider.EmbedText(ctx, "hello"); !errors.Is(err, ErrDimsMismatch) {
+			t.Errorf("%d dims: expected ErrDimsMismatch, got %v", len(vector), err)
+		}
+	}
+
+	provider := newDimsTestProvider(t, "strict", 2, []float32{3, 4})
+	got, err := provider.EmbedText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "strict", got, []float32{3, 4})
+}
+
+// TestOpenEmbedProvider_DimsPolicyTruncate tests Matryoshka truncation with re-normalization
+func TestOpenEmbedProvider_DimsPolicyTruncate(t *testing.T) {
+	ctx := context.Background()
+
+	provider := newDimsTestProvider(t, "truncate", 2, []float32{3, 4, 12})
+	got, err := provider.EmbedText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "truncate", got, []float32{0.6, 0.8})
+
+	// A zero prefix has no direction to normalize and is kept as is
+	provider = newDimsTestProvider(t, "truncate", 2, []float32{0, 0, 1})
+	got, err = provider.EmbedText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "zero prefix", got, []float32{0, 0})
+
+	provider = newDimsTestProvider(t, "truncate", 4, []float32{3, 4})
+	if _, err := provider.EmbedText(ctx, "hello"); !errors.Is(err, ErrDimsMismatch) {
+		t.Errorf("expected ErrDimsMismatch when truncate meets a shorter vector, got %v", err)
+	}
+}
+
+// TestOpenEmbedProvider_DimsPolicyPad tests zero padding up to the target dimension
+func TestOpenEmbedProvider_DimsPolicyPad(t *testing.T) {
+	ctx := context.Background()
+
+	provider := newDimsTestProvider(t, "pad", 4, []float32{3, 4})
+	got, err := provider.EmbedText(ctx, "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "pad", got, []float32{3, 4, 0, 0})
+
+	provider = newDimsTestProvider(t, "pad", 2, []float32{3, 4, 12})
+	if _, err := provider.EmbedText(ctx, "hello"); !errors.Is(err, ErrDimsMismatch) {
+		t.Errorf("expected ErrDimsMismatch when pad meets a longer vector, got %v", err)
+	}
+}
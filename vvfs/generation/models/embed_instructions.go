@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// EmbedInstructions holds the prefixes an embedding model expects before queries and
+// documents. Asymmetric retrieval models are trained with them; embedding both sides
+// the same way degrades retrieval
+type EmbedInstructions struct {
+	Query    string
+	Document string
+}
+
+// qwen3QueryInstruction is the retrieval instruction from the Qwen3-Embedding model card
+const qwen3QueryInstruction = "Instruct: Given a web search query, retrieve relevant passages that answer the query\nQuery: "
+
+// DefaultEmbedInstructions returns the prefixes for the model family named by modelPath.
+// Unrecognized models get none, embedding text as-is
+func DefaultEmbedInstructions(modelPath string) EmbedInstructions {
+	name := strings.ToLower(filepath.Base(modelPath))
+	switch {
+	case strings.Contains(name, "qwen3") && strings.Contains(name, "embed"):
+		return EmbedInstructions{Query: qwen3QueryInstruction}
+	case strings.Contains(name, "nomic-embed"):
+		return EmbedInstructions{Query: "search_query: ", Document: "search_document: "}
+	case strings.HasPrefix(name, "e5-") || strings.Contains(name, "-e5-"):
+		return EmbedInstructions{Query: "query: ", Document: "passage: "}
+	case strings.HasPrefix(name, "bge-"):
+		return EmbedInstructions{Query: "Represent this sentence for searching relevant passages: "}
+	default:
+		return EmbedInstructions{}
+	}
+}
+
+// SetEmbedInstructions overrides the query and document prefixes
+func (p *OpenEmbedProvider) SetEmbedInstructions(instructions EmbedInstructions) {
+	p.GGUFProvider.config.QueryInstruction = instructions.Query
+	p.GGUFProvider.config.DocumentInstruction = instructions.Document
+}
+
+// GetEmbedInstructions returns the configured query and document prefixes
+func (p *OpenEmbedProvider) GetEmbedInstructions() EmbedInstructions {
+	return EmbedInstructions{
+		Query:    p.GGUFProvider.config.QueryInstruction,
+		Document: p.GGUFProvider.config.DocumentInstruction,
+	}
+}
+
+// EmbedQuery embeds a search query, prefixed with the model's query instruction
+func (p *OpenEmbedProvider) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return p.embedDims(ctx, p.GGUFProvider.config.QueryInstruction+text)
+}
+
+// EmbedDocument embeds content to be stored and searched, prefixed with the model's
+// document instruction
+func (p *OpenEmbedProvider) EmbedDocument(ctx context.Context, text string) ([]float32, error) {
+	return p.embedDims(ctx, p.GGUFProvider.config.DocumentInstruction+text)
+}
+
+// embedDims embeds text as given and fits the vector to the Matryoshka dimension
+func (p *OpenEmbedProvider) embedDims(ctx context.Context, text string) ([]float32, error) {
+	embedding, err := p.embedPooled(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(embedding) != p.matryoshkaDims {
+		embedding, err = p.adjustToDims(embedding, p.matryoshkaDims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return embedding, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+// recordingTokenEmbedder records the text of each call and returns a fixed token output
+type recordingTokenEmbedder struct {
+	texts []string
+}
+
+func (r *recordingTokenEmbedder) EmbedTokens(ctx context.Context, text string) (TokenEmbeddings, error) {
+	r.texts = append(r.texts, text)
+	return TokenEmbeddings{Vectors: [][]float32{{1, 0}}}, nil
+}
+
+// TestDefaultEmbedInstructions tests that known model families get their prefixes
+func TestDefaultEmbedInstructions(t *testing.T) {
+	tests := []struct {
+		path     string
+		query    string
+		document string
+	}{
+		{"/models/Qwen3-Embedding-0.6B-Q8_0.gguf", qwen3QueryInstruction, ""},
+		{"nomic-embed-text-v1.5.f16.gguf", "search_query: ", "search_document: "},
+		{"multilingual-e5-large.gguf", "query: ", "passage: "},
+		{"bge-small-en-v1.5.gguf", "Represent this sentence for searching relevant passages: ", ""},
+		{"all-MiniLM-L6-v2.gguf", "", ""},
+	}
+
+	for _, tt := range tests {
+		got := DefaultEmbedInstructions(tt.path)
+		if got.Query != tt.query || got.Document != tt.document {
+			t.Errorf("%s: expected (%q, %q), got (%q, %q)", tt.path, tt.query, tt.document, got.Query, got.Document)
+		}
+	}
+}
+
+// TestOpenEmbedProvider_EmbedQueryAndDocument tests that each mode applies its own prefix
+func TestOpenEmbedProvider_EmbedQueryAndDocument(t *testing.T) {
+	provider := &OpenEmbedProvider{
+		GGUFProvider:   &GGUFProvider{config: DefaultGGUFConfig("Qwen3-Embedding-0.6B.gguf", ModelTypeEmbedding)},
+		matryoshkaDims: 2,
+	}
+	recorder := &recordingTokenEmbedder{}
+	provider.SetTokenEmbedder(recorder)
+	ctx := context.Background()
+
+	if _, err := provider.EmbedQuery(ctx, "where is the invoice"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.EmbedDocument(ctx, "invoice.pdf"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.EmbedText(ctx, "notes.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	provider.SetEmbedInstructions(EmbedInstructions{Query: "q: ", Document: "d: "})
+	if _, err := provider.EmbedQuery(ctx, "where"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := provider.EmbedText(ctx, "notes.txt"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		qwen3QueryInstruction + "where is the invoice",
+		"invoice.pdf",
+		"notes.txt", // EmbedText is document mode
+		"q: where",
+		"d: notes.txt",
+	}
+	if len(recorder.texts) != len(want) {
+		t.Fatalf("expected %d embed calls, got %d", len(want), len(recorder.texts))
+	}
+	for i := range want {
+		if recorder.texts[i] != want[i] {
+			t.Errorf("call %d: expected %q, got %q", i, want[i], recorder.texts[i])
+		}
+	}
+}
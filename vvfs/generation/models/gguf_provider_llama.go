@@ -27,6 +27,7 @@ type GGUFProvider struct {
 	// Pooling
 	pool   chan *llama.LLama
 	poolMu sync.Mutex
+	closed bool
 
 	// Circuit breaker
 	failureCount    int64
@@ -34,7 +35,8 @@ type GGUFProvider struct {
 	breakerMu       sync.Mutex
 
 	// Logger
-	logger *slog.Logger
+	logger      *slog.Logger
+	minPWarning sync.Once // min-p is reported as unsupported once per provider
 }
 
 // NewGGUFProvider creates a new GGUF model provider (llama-specific)
@@ -81,9 +83,18 @@ func (p *GGUFProvider) loadModel() (*llama.LLama, error) {
 		modelPath = p.config.ModelPath
 	}
 
+	// Check free VRAM before offloading so a short device fails cleanly instead of crashing in CUDA
+	gpuLayers, err := p.config.planGPULayers(queryFreeVRAM)
+	if err != nil {
+		return nil, err
+	}
+	if gpuLayers != p.config.GPULayers {
+		p.logger.Warn("Free VRAM below threshold, loading model on CPU", "requested_gpu_layers", p.config.GPULayers)
+	}
+
 	options := []llama.ModelOption{
 		llama.SetContext(p.config.ContextSize),
-		llama.SetGPULayers(p.config.GPULayers),
+		llama.SetGPULayers(gpuLayers),
 	}
 
 	model, err := llama.New(modelPath, options...)
@@ -116,6 +127,10 @@ func (p *GGUFProvider) Borrow(ctx context.Context) (*llama.LLama, error) {
 	p.poolMu.Lock()
 	defer p.poolMu.Unlock()
 
+	if p.closed {
+		return nil, ErrProviderClosed
+	}
+
 	if p.isBreakerOpen() {
 		return nil, fmt.Errorf("circuit breaker is open")
 	}
@@ -137,6 +152,11 @@ func (p *GGUFProvider) Return(model *llama.LLama) {
 	p.poolMu.Lock()
 	defer p.poolMu.Unlock()
 
+	if p.closed {
+		model.Free()
+		return
+	}
+
 	if len(p.pool) >= p.config.PoolSize {
 		p.logger.Warn("Attempted to return model to full pool", "pool_size", len(p.pool))
 		model.Free()
@@ -215,7 +235,7 @@ func (p *GGUFProvider) GenerateText(ctx context.Context, prompt string, options
 		return "", fmt.Errorf("prompt cannot be empty")
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, p.generateTimeout())
 	defer cancel()
 
 	model, err := p.Borrow(reqCtx)
@@ -228,11 +248,19 @@ func (p *GGUFProvider) GenerateText(ctx context.Context, prompt string, options
 	start := time.Now()
 	p.logger.Debug("Starting text generation", "prompt_length", len(prompt))
 
+	settings := p.predictSettings()
 	defaultOptions := []llama.PredictOption{
-		llama.SetTemperature(p.config.Temperature),
-		llama.SetTopP(p.config.TopP),
-		llama.SetTokens(p.config.MaxTokens),
-		llama.SetRepeat(1),
+		llama.SetTemperature(settings.Temperature),
+		llama.SetTopP(settings.TopP),
+		llama.SetTokens(settings.Tokens),
+		llama.SetPenalty(settings.RepetitionPenalty),
+		llama.SetRepeat(settings.RepeatLastN),
+	}
+	if settings.MinP > 0 {
+		// The go-llama.cpp bindings expose no min-p sampler, so the cutoff cannot be applied
+		p.minPWarning.Do(func() {
+			p.logger.Warn("min-p sampling is not supported by the llama bindings; ignoring", "min_p", settings.MinP)
+		})
 	}
 
 	allOptions := append(defaultOptions, options...)
@@ -256,7 +284,7 @@ func (p *GGUFProvider) EmbedText(ctx context.Context, text string) ([]float32, e
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, p.embedTimeout())
 	defer cancel()
 
 	model, err := p.Borrow(reqCtx)
@@ -313,6 +341,7 @@ func (p *GGUFProvider) GetHealth() *ModelHealth {
 	defer p.mu.RUnlock()
 
 	health := *p.health
+	health.BreakerOpen, health.BreakerFailures = p.breakerState()
 	return &health
 }
 
@@ -322,11 +351,16 @@ func (p *GGUFProvider) Close() error {
 	defer p.mu.Unlock()
 
 	p.poolMu.Lock()
+	if p.closed {
+		p.poolMu.Unlock()
+		return nil
+	}
 	for len(p.pool) > 0 {
 		model := <-p.pool
 		model.Free()
 	}
 	close(p.pool)
+	p.closed = true
 	p.poolMu.Unlock()
 
 	if p.tempFilePath != "" {
@@ -25,6 +25,7 @@ type GGUFProvider struct {
 	// Pooling
 	pool   chan interface{}
 	poolMu sync.Mutex
+	closed bool
 
 	// Circuit breaker
 	failureCount    int64
@@ -82,6 +83,10 @@ func (p *GGUFProvider) Borrow(ctx context.Context) (interface{}, error) {
 	p.poolMu.Lock()
 	defer p.poolMu.Unlock()
 
+	if p.closed {
+		return nil, ErrProviderClosed
+	}
+
 	if p.isBreakerOpen() {
 		return nil, fmt.Errorf("circuit breaker is open")
 	}
@@ -103,6 +108,10 @@ func (p *GGUFProvider) Return(model interface{}) {
 	p.poolMu.Lock()
 	defer p.poolMu.Unlock()
 
+	if p.closed {
+		return
+	}
+
 	if len(p.pool) >= p.config.PoolSize {
 		p.logger.Warn("Attempted to return model to full pool", "pool_size", len(p.pool))
 		return
@@ -139,7 +148,7 @@ func (p *GGUFProvider) GenerateText(ctx context.Context, prompt string, options
 		return "", fmt.Errorf("prompt cannot be empty")
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, p.generateTimeout())
 	defer cancel()
 
 	model, err := p.Borrow(reqCtx)
@@ -160,7 +169,7 @@ func (p *GGUFProvider) EmbedText(ctx context.Context, text string) ([]float32, e
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
-	reqCtx, cancel := context.WithTimeout(ctx, p.config.RequestTimeout)
+	reqCtx, cancel := context.WithTimeout(ctx, p.embedTimeout())
 	defer cancel()
 
 	model, err := p.Borrow(reqCtx)
@@ -186,6 +195,7 @@ func (p *GGUFProvider) GetHealth() *ModelHealth {
 	defer p.mu.RUnlock()
 
 	health := *p.health
+	health.BreakerOpen, health.BreakerFailures = p.breakerState()
 	return &health
 }
 
@@ -195,10 +205,15 @@ func (p *GGUFProvider) Close() error {
 	defer p.mu.Unlock()
 
 	p.poolMu.Lock()
+	if p.closed {
+		p.poolMu.Unlock()
+		return nil
+	}
 	for len(p.pool) > 0 {
 		<-p.pool
 	}
 	close(p.pool)
+	p.closed = true
 	p.poolMu.Unlock()
 
 	p.health.IsHealthy = false
@@ -1,10 +1,15 @@
 package models
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 )
 
+// ErrProviderClosed is returned when a model is borrowed from a provider after Close
+var ErrProviderClosed = errors.New("provider is closed")
+
 // GGUFModelConfig holds configuration for GGUF model loading
 type GGUFModelConfig struct {
 	ModelPath       string
@@ -19,17 +24,34 @@ type GGUFModelConfig struct {
 	MaxTokens       int
 	Temperature     float32
 	TopP            float32
+	Pooling         PoolingStrategy // reduction of token-level outputs for embedding models
+	DimsPolicy      DimsPolicy      // fitting of embeddings whose length differs from the target dimension
+	// Sampling applied by GenerateText on top of temperature and top-p
+	MinP              float32 // min-p cutoff relative to the top token's probability; 0 disables it
+	RepetitionPenalty float32 // penalty on recently generated tokens; 0 or 1 disables it
+	// Prefixes applied by EmbedQuery and EmbedDocument; defaulted from the model name for embedding models
+	QueryInstruction    string
+	DocumentInstruction string
 	// Pooling and resilience settings
 	PoolSize         int
 	BorrowTimeout    time.Duration
-	RequestTimeout   time.Duration
+	RequestTimeout   time.Duration // fallback for GenerateTimeout and EmbedTimeout when they are zero
+	GenerateTimeout  time.Duration // bound on a single GenerateText call
+	EmbedTimeout     time.Duration // bound on a single EmbedText call
 	BreakerThreshold int
 	BreakerCooldown  time.Duration
+	// GPU memory guard checked before each model instance loads (zero MinFreeVRAMBytes disables it)
+	MinFreeVRAMBytes uint64 // refuse GPU loads when less VRAM than this is free
+	VRAMFallbackCPU  bool   // load on the CPU instead of refusing when VRAM is short
 }
 
+// DefaultEmbedTimeout bounds an embedding call by default; embeddings are a single forward
+// pass, so they get a much shorter budget than text generation
+const DefaultEmbedTimeout = 10 * time.Second
+
 // DefaultGGUFConfig returns default configuration for a GGUF model
 func DefaultGGUFConfig(modelPath string, modelType ModelType) *GGUFModelConfig {
-	return &GGUFModelConfig{
+	config := &GGUFModelConfig{
 		ModelPath:        modelPath,
 		ModelType:        modelType,
 		ContextSize:      2048,
@@ -42,12 +64,23 @@ func DefaultGGUFConfig(modelPath string, modelType ModelType) *GGUFModelConfig {
 		MaxTokens:        256,
 		Temperature:      0.7,
 		TopP:             0.9,
+		Pooling:          PoolingMean,
+		DimsPolicy:       DimsPolicyStrict,
 		PoolSize:         2,
 		BorrowTimeout:    5 * time.Second,
 		RequestTimeout:   30 * time.Second,
+		GenerateTimeout:  30 * time.Second,
+		EmbedTimeout:     DefaultEmbedTimeout,
 		BreakerThreshold: 5,
 		BreakerCooldown:  60 * time.Second,
+		MinFreeVRAMBytes: minFreeVRAMFromEnv(),
+	}
+	if modelType == ModelTypeEmbedding {
+		instructions := DefaultEmbedInstructions(modelPath)
+		config.QueryInstruction = instructions.Query
+		config.DocumentInstruction = instructions.Document
 	}
+	return config
 }
 
 // ValidateConfig validates the GGUF model configuration
@@ -88,6 +121,18 @@ func ValidateConfig(config *GGUFModelConfig) error {
 		return fmt.Errorf("top_p must be between 0 and 1, got %f", config.TopP)
 	}
 
+	if err := ValidateSampling(config.MinP, config.RepetitionPenalty); err != nil {
+		return err
+	}
+
+	if _, err := ParsePoolingStrategy(string(config.Pooling)); err != nil {
+		return err
+	}
+
+	if _, err := ParseDimsPolicy(string(config.DimsPolicy)); err != nil {
+		return err
+	}
+
 	if config.PoolSize <= 0 {
 		return fmt.Errorf("pool size must be positive, got %d", config.PoolSize)
 	}
@@ -96,19 +141,65 @@ func ValidateConfig(config *GGUFModelConfig) error {
 		return fmt.Errorf("borrow timeout must be positive, got %v", config.BorrowTimeout)
 	}
 
-	if config.RequestTimeout <= 0 {
+	if config.GenerateTimeout < 0 {
+		return fmt.Errorf("generate timeout cannot be negative, got %v", config.GenerateTimeout)
+	}
+
+	if config.EmbedTimeout < 0 {
+		return fmt.Errorf("embed timeout cannot be negative, got %v", config.EmbedTimeout)
+	}
+
+	if config.generateTimeout() <= 0 || config.embedTimeout() <= 0 {
 		return fmt.Errorf("request timeout must be positive, got %v", config.RequestTimeout)
 	}
 
-	if config.BreakerThreshold <= 0 {
-		return fmt.Errorf("breaker threshold must be positive, got %d", config.BreakerThreshold)
+	return ValidateBreakerPolicy(config.BreakerThreshold, config.BreakerCooldown)
+}
+
+// generateTimeout returns the bound on a GenerateText call, falling back to RequestTimeout
+func (c *GGUFModelConfig) generateTimeout() time.Duration {
+	if c.GenerateTimeout > 0 {
+		return c.GenerateTimeout
 	}
+	return c.RequestTimeout
+}
 
-	if config.BreakerCooldown <= 0 {
-		return fmt.Errorf("breaker cooldown must be positive, got %v", config.BreakerCooldown)
+// embedTimeout returns the bound on an EmbedText call, falling back to RequestTimeout
+func (c *GGUFModelConfig) embedTimeout() time.Duration {
+	if c.EmbedTimeout > 0 {
+		return c.EmbedTimeout
 	}
+	return c.RequestTimeout
+}
 
-	return nil
+// defaultRepeatLastN is how many recent tokens the repetition penalty covers (llama.cpp's default)
+const defaultRepeatLastN = 64
+
+// predictSettings are the sampling parameters GenerateText passes to the model by default
+type predictSettings struct {
+	Temperature       float32
+	TopP              float32
+	MinP              float32 // 0 disables min-p sampling
+	Tokens            int
+	RepetitionPenalty float32 // 1 disables the penalty
+	RepeatLastN       int     // recent tokens the penalty applies to
+}
+
+// predictSettings resolves the default sampling parameters for a generation call
+func (c *GGUFModelConfig) predictSettings() predictSettings {
+	settings := predictSettings{
+		Temperature:       c.Temperature,
+		TopP:              c.TopP,
+		MinP:              c.MinP,
+		Tokens:            c.MaxTokens,
+		RepetitionPenalty: 1,
+		RepeatLastN:       1,
+	}
+	if c.RepetitionPenalty > 0 && c.RepetitionPenalty != 1 {
+		settings.RepetitionPenalty = c.RepetitionPenalty
+		settings.RepeatLastN = defaultRepeatLastN
+	}
+	return settings
 }
 
 // ModelHealth tracks the health status of a model
@@ -123,4 +214,114 @@ type ModelHealth struct {
 	LastError       error
 	ErrorMessages   []string
 	LastHealthCheck time.Time
+
+	// Circuit breaker state
+	BreakerOpen     bool  // true while calls are rejected until the cooldown elapses
+	BreakerFailures int64 // failures counted towards the breaker threshold
+}
+
+// ValidateBreakerPolicy checks a circuit breaker threshold and cooldown
+func ValidateBreakerPolicy(threshold int, cooldown time.Duration) error {
+	if threshold <= 0 {
+		return fmt.Errorf("breaker threshold must be positive, got %d", threshold)
+	}
+
+	if cooldown <= 0 {
+		return fmt.Errorf("breaker cooldown must be positive, got %v", cooldown)
+	}
+
+	return nil
+}
+
+// ValidateSampling checks a min-p cutoff and repetition penalty; zero disables either
+func ValidateSampling(minP, repetitionPenalty float32) error {
+	if minP < 0 || minP > 1 {
+		return fmt.Errorf("min_p must be between 0 and 1, got %f", minP)
+	}
+
+	if repetitionPenalty < 0 || repetitionPenalty > 2 {
+		return fmt.Errorf("repetition penalty must be between 0 and 2, got %f", repetitionPenalty)
+	}
+
+	return nil
+}
+
+// SetBreakerPolicy replaces the circuit breaker threshold and cooldown
+func (p *GGUFProvider) SetBreakerPolicy(threshold int, cooldown time.Duration) error {
+	if err := ValidateBreakerPolicy(threshold, cooldown); err != nil {
+		return err
+	}
+
+	p.breakerMu.Lock()
+	defer p.breakerMu.Unlock()
+	p.config.BreakerThreshold = threshold
+	p.config.BreakerCooldown = cooldown
+	return nil
+}
+
+// SetRequestTimeouts replaces the generation and embedding request timeouts. A zero value
+// keeps the current timeout
+func (p *GGUFProvider) SetRequestTimeouts(generate, embed time.Duration) error {
+	if generate < 0 || embed < 0 {
+		return fmt.Errorf("request timeouts cannot be negative, got generate=%v embed=%v", generate, embed)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if generate > 0 {
+		p.config.GenerateTimeout = generate
+	}
+	if embed > 0 {
+		p.config.EmbedTimeout = embed
+	}
+	return nil
+}
+
+// SetSampling replaces the min-p cutoff and repetition penalty used by GenerateText. A zero
+// value keeps the current setting
+func (p *GGUFProvider) SetSampling(minP, repetitionPenalty float32) error {
+	if err := ValidateSampling(minP, repetitionPenalty); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if minP > 0 {
+		p.config.MinP = minP
+	}
+	if repetitionPenalty > 0 {
+		p.config.RepetitionPenalty = repetitionPenalty
+	}
+	return nil
+}
+
+// predictSettings returns the provider's current default sampling parameters
+func (p *GGUFProvider) predictSettings() predictSettings {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config.predictSettings()
+}
+
+// generateTimeout returns the provider's current GenerateText bound
+func (p *GGUFProvider) generateTimeout() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config.generateTimeout()
+}
+
+// embedTimeout returns the provider's current EmbedText bound
+func (p *GGUFProvider) embedTimeout() time.Duration {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.config.embedTimeout()
+}
+
+// breakerState reports whether the breaker is open and the failures counted towards it
+func (p *GGUFProvider) breakerState() (bool, int64) {
+	p.breakerMu.Lock()
+	defer p.breakerMu.Unlock()
+
+	failures := atomic.LoadInt64(&p.failureCount)
+	open := failures >= int64(p.config.BreakerThreshold) && time.Since(p.lastFailureTime) <= p.config.BreakerCooldown
+	return open, failures
 }
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+)
+
+// ImageAnalysis is the structured result of analyzing an image
+type ImageAnalysis struct {
+	Caption string           `json:"caption"`
+	Tags    []string         `json:"tags"`
+	Objects []DetectedObject `json:"objects"`
+	Width   int              `json:"width"`  // pixels; zero when the format is not recognized
+	Height  int              `json:"height"` // pixels; zero when the format is not recognized
+	Format  string           `json:"format"` // e.g. "png", "jpeg"
+}
+
+// DetectedObject is an object located in an image
+type DetectedObject struct {
+	Label      string          `json:"label"`
+	Confidence float64         `json:"confidence"`
+	Bounds     image.Rectangle `json:"bounds"` // pixel bounding box
+}
+
+// ImageAnalyzer analyzes encoded image bytes; OpenVisionProvider implements it
+type ImageAnalyzer interface {
+	AnalyzeImage(ctx context.Context, imageData []byte) (*ImageAnalysis, error)
+}
+
+// AnalyzeImage captions the image with the vision model
+func (p *OpenVisionProvider) AnalyzeImage(ctx context.Context, imageData []byte) (*ImageAnalysis, error) {
+	caption, err := p.DescribeImage(ctx, imageData)
+	if err != nil {
+		return nil, err
+	}
+	return &ImageAnalysis{Caption: caption}, nil
+}
+
+// fillImageDimensions sets the size and format from the image header when the analyzer left them unset
+func fillImageDimensions(analysis *ImageAnalysis, imageData []byte) {
+	if analysis.Width > 0 && analysis.Height > 0 && analysis.Format != "" {
+		return
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		return
+	}
+	if analysis.Width == 0 && analysis.Height == 0 {
+		analysis.Width, analysis.Height = cfg.Width, cfg.Height
+	}
+	if analysis.Format == "" {
+		analysis.Format = format
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
 
+// DefaultEmbeddingModel names the embedding provider loaded from ModelManagerConfig.EmbeddingModelPath
+const DefaultEmbeddingModel = "default"
+
 // ModelManager coordinates all AI model providers and manages the cascade system
 type ModelManager struct {
 	// Model providers (now using Open providers wrapping GGUF)
@@ -17,6 +22,16 @@ type ModelManager struct {
 	visionProvider    *OpenVisionProvider
 	cascadeManager    *CascadeManager
 
+	// Optional: analyzes images in place of the vision provider
+	imageAnalyzer ImageAnalyzer
+
+	// Additional embedding models addressed by name, alongside the default embeddingProvider
+	namedEmbedders map[string]*OpenEmbedProvider
+
+	// In-flight call accounting so reloads can defer closing a replaced provider
+	inflight map[*GGUFProvider]*sync.WaitGroup
+	retiring sync.WaitGroup
+
 	// Configuration
 	config *ModelManagerConfig
 
@@ -35,6 +50,9 @@ type ModelManagerConfig struct {
 	ChatModelPath      string
 	VisionModelPath    string
 
+	// Embedding pooling: "mean", "cls", "last_token" or "weighted_mean" (see PoolingStrategy)
+	EmbeddingPooling string
+
 	// Performance settings
 	EmbeddingDims int
 	ContextSize   int
@@ -50,6 +68,35 @@ type ModelManagerConfig struct {
 	// Cascade settings
 	ConfidenceThreshold float64
 	EnableCascade       bool
+
+	// Circuit breaker applied to every provider (zero selects the GGUF defaults)
+	BreakerThreshold int           // failures before calls are rejected
+	BreakerCooldown  time.Duration // how long calls are rejected once the breaker opens
+
+	// Per-call request timeouts applied to every provider (zero keeps the GGUF defaults)
+	GenerateTimeout time.Duration // bound on a text generation call
+	EmbedTimeout    time.Duration // bound on an embedding call
+
+	// Sampling applied to the chat and vision providers (zero keeps the GGUF defaults)
+	MinP              float32 // min-p cutoff relative to the top token's probability
+	RepetitionPenalty float32 // penalty on recently generated tokens
+
+	// Provider types that must be healthy for IsHealthy; the others are optional.
+	// Nil selects DefaultRequiredModelTypes
+	RequiredModelTypes []ModelType
+}
+
+// DefaultRequiredModelTypes returns the providers IsHealthy requires by default: embedding and chat
+func DefaultRequiredModelTypes() []ModelType {
+	return []ModelType{ModelTypeEmbedding, ModelTypeChat}
+}
+
+// requiredModelTypes resolves RequiredModelTypes, falling back to the defaults
+func (c *ModelManagerConfig) requiredModelTypes() []ModelType {
+	if c.RequiredModelTypes == nil {
+		return DefaultRequiredModelTypes()
+	}
+	return c.RequiredModelTypes
 }
 
 // DefaultModelManagerConfig returns default model manager config with open-source defaults
@@ -60,6 +107,9 @@ func DefaultModelManagerConfig() *ModelManagerConfig {
 		ChatModelPath:      "vvfs/generation/models/gguf/open-chat-qwen3-1_7b.gguf",
 		VisionModelPath:    "vvfs/generation/models/gguf/open-vision.gguf",
 
+		// Qwen3-Embedding is trained with last-token pooling
+		EmbeddingPooling: string(PoolingLastToken),
+
 		// Open-source defaults
 		EmbeddingDims: 768,  // Qwen3-Embedding-0.6B default
 		ContextSize:   4096, // Qwen3-1.7B default
@@ -73,6 +123,9 @@ func DefaultModelManagerConfig() *ModelManagerConfig {
 		EnableHealthMonitoring: true,
 		ConfidenceThreshold:    0.90, // threshold tuned for open models
 		EnableCascade:          true, // enable cascade across open providers
+
+		// Vision is optional: its absence or failure leaves the manager healthy
+		RequiredModelTypes: DefaultRequiredModelTypes(),
 	}
 }
 
@@ -99,6 +152,29 @@ func (c *ModelManagerConfig) applyEnvOverrides() {
 	}
 }
 
+// applyBreakerDefaults fills unset circuit breaker settings from the GGUF defaults
+func (c *ModelManagerConfig) applyBreakerDefaults() {
+	defaults := DefaultGGUFConfig("", ModelTypeChat)
+	if c.BreakerThreshold == 0 {
+		c.BreakerThreshold = defaults.BreakerThreshold
+	}
+	if c.BreakerCooldown == 0 {
+		c.BreakerCooldown = defaults.BreakerCooldown
+	}
+}
+
+// validateModelTypes rejects types the manager has no provider for
+func validateModelTypes(types []ModelType) error {
+	for _, modelType := range types {
+		switch modelType {
+		case ModelTypeEmbedding, ModelTypeChat, ModelTypeVision:
+		default:
+			return fmt.Errorf("unknown model type %q", modelType)
+		}
+	}
+	return nil
+}
+
 // NewModelManager creates a new model manager with all providers and env overrides
 func NewModelManager(config *ModelManagerConfig) (*ModelManager, error) {
 	if config == nil {
@@ -108,9 +184,26 @@ func NewModelManager(config *ModelManagerConfig) (*ModelManager, error) {
 	// Apply environment overrides
 	config.applyEnvOverrides()
 
+	if _, err := ParsePoolingStrategy(config.EmbeddingPooling); err != nil {
+		return nil, fmt.Errorf("invalid embedding pooling: %w", err)
+	}
+
+	config.applyBreakerDefaults()
+	if err := ValidateBreakerPolicy(config.BreakerThreshold, config.BreakerCooldown); err != nil {
+		return nil, fmt.Errorf("invalid circuit breaker policy: %w", err)
+	}
+	if err := validateModelTypes(config.RequiredModelTypes); err != nil {
+		return nil, fmt.Errorf("invalid required model types: %w", err)
+	}
+	if err := ValidateSampling(config.MinP, config.RepetitionPenalty); err != nil {
+		return nil, fmt.Errorf("invalid sampling settings: %w", err)
+	}
+
 	manager := &ModelManager{
 		config:              config,
 		cascadeManager:      NewCascadeManager(),
+		inflight:            make(map[*GGUFProvider]*sync.WaitGroup),
+		namedEmbedders:      make(map[string]*OpenEmbedProvider),
 		healthCheckInterval: config.HealthCheckInterval,
 		stopHealthCheck:     make(chan bool),
 	}
@@ -136,7 +229,17 @@ func (m *ModelManager) initializeProviders() error {
 		log.Printf("Warning: Failed to initialize embedding provider: %v", err)
 		// Continue without embedding provider for now
 	} else {
+		if err := embeddingProvider.SetPooling(m.config.EmbeddingPooling); err != nil {
+			return fmt.Errorf("failed to configure embedding pooling: %w", err)
+		}
+		if err := embeddingProvider.SetBreakerPolicy(m.config.BreakerThreshold, m.config.BreakerCooldown); err != nil {
+			return fmt.Errorf("failed to configure embedding circuit breaker: %w", err)
+		}
+		if err := embeddingProvider.SetRequestTimeouts(m.config.GenerateTimeout, m.config.EmbedTimeout); err != nil {
+			return fmt.Errorf("failed to configure embedding request timeouts: %w", err)
+		}
 		m.embeddingProvider = embeddingProvider
+		m.track(embeddingProvider.GGUFProvider)
 		m.cascadeManager.AddProvider("open-embed", embeddingProvider.GGUFProvider)
 	}
 
@@ -145,7 +248,17 @@ func (m *ModelManager) initializeProviders() error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize chat provider: %w", err)
 	}
+	if err := chatProvider.SetBreakerPolicy(m.config.BreakerThreshold, m.config.BreakerCooldown); err != nil {
+		return fmt.Errorf("failed to configure chat circuit breaker: %w", err)
+	}
+	if err := chatProvider.SetRequestTimeouts(m.config.GenerateTimeout, m.config.EmbedTimeout); err != nil {
+		return fmt.Errorf("failed to configure chat request timeouts: %w", err)
+	}
+	if err := chatProvider.SetSampling(m.config.MinP, m.config.RepetitionPenalty); err != nil {
+		return fmt.Errorf("failed to configure chat sampling: %w", err)
+	}
 	m.chatProvider = chatProvider
+	m.track(chatProvider.GGUFProvider)
 	m.cascadeManager.AddProvider("open-chat", chatProvider.GGUFProvider)
 
 	// Initialize vision provider (optional)
@@ -155,7 +268,17 @@ func (m *ModelManager) initializeProviders() error {
 			log.Printf("Warning: Failed to initialize vision provider: %v", err)
 			// Continue without vision provider
 		} else {
+			if err := visionProvider.SetBreakerPolicy(m.config.BreakerThreshold, m.config.BreakerCooldown); err != nil {
+				return fmt.Errorf("failed to configure vision circuit breaker: %w", err)
+			}
+			if err := visionProvider.SetRequestTimeouts(m.config.GenerateTimeout, m.config.EmbedTimeout); err != nil {
+				return fmt.Errorf("failed to configure vision request timeouts: %w", err)
+			}
+			if err := visionProvider.SetSampling(m.config.MinP, m.config.RepetitionPenalty); err != nil {
+				return fmt.Errorf("failed to configure vision sampling: %w", err)
+			}
 			m.visionProvider = visionProvider
+			m.track(visionProvider.GGUFProvider)
 			m.cascadeManager.AddProvider("open-vision", visionProvider.GGUFProvider)
 		}
 	}
@@ -163,6 +286,59 @@ func (m *ModelManager) initializeProviders() error {
 	return nil
 }
 
+// track registers a provider for in-flight call accounting; callers must hold m.mu
+func (m *ModelManager) track(provider *GGUFProvider) {
+	m.inflight[provider] = &sync.WaitGroup{}
+}
+
+// acquire marks a call in flight on provider and returns the func that ends it.
+// Callers must hold m.mu (read or write) so the call is counted before any reload swaps the provider out
+func (m *ModelManager) acquire(provider *GGUFProvider) func() {
+	wg, ok := m.inflight[provider]
+	if !ok {
+		return func() {}
+	}
+	wg.Add(1)
+	return wg.Done
+}
+
+// retire stops tracking provider and closes it once its in-flight calls return; callers must hold m.mu
+func (m *ModelManager) retire(name string, provider *GGUFProvider) {
+	wg := m.inflight[provider]
+	delete(m.inflight, provider)
+
+	m.retiring.Add(1)
+	go func() {
+		defer m.retiring.Done()
+		if wg != nil {
+			wg.Wait()
+		}
+		if err := provider.Close(); err != nil {
+			log.Printf("Warning: Error closing old %s provider: %v", name, err)
+		}
+	}()
+}
+
+// providers returns the loaded providers; callers must hold m.mu
+func (m *ModelManager) providers() []*GGUFProvider {
+	providers := make([]*GGUFProvider, 0, 3)
+	if m.embeddingProvider != nil {
+		providers = append(providers, m.embeddingProvider.GGUFProvider)
+	}
+	if m.chatProvider != nil {
+		providers = append(providers, m.chatProvider.GGUFProvider)
+	}
+	if m.visionProvider != nil {
+		providers = append(providers, m.visionProvider.GGUFProvider)
+	}
+	for _, name := range m.embeddingModelNames() {
+		if name != DefaultEmbeddingModel {
+			providers = append(providers, m.namedEmbedders[name].GGUFProvider)
+		}
+	}
+	return providers
+}
+
 // startHealthMonitoring starts the background health monitoring
 func (m *ModelManager) startHealthMonitoring() {
 	m.healthTicker = time.NewTicker(m.healthCheckInterval)
@@ -203,23 +379,40 @@ func (m *ModelManager) GetVisionProvider() *OpenVisionProvider {
 
 // SetEmbeddingPath updates the embedding model path and reloads the provider
 func (m *ModelManager) SetEmbeddingPath(path string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Close existing provider
-	if m.embeddingProvider != nil {
-		if err := m.embeddingProvider.Close(); err != nil {
-			log.Printf("Warning: Error closing old embedding provider: %v", err)
-		}
-	}
+	m.mu.RLock()
+	pooling := m.config.EmbeddingPooling
+	threshold, cooldown := m.config.BreakerThreshold, m.config.BreakerCooldown
+	generateTimeout, embedTimeout := m.config.GenerateTimeout, m.config.EmbedTimeout
+	m.mu.RUnlock()
 
-	// Create new provider
+	// Create new provider before touching the current one so a failed load leaves it in service
 	newProvider, err := NewOpenEmbedProvider(path)
 	if err != nil {
 		return fmt.Errorf("failed to create new embedding provider: %w", err)
 	}
+	if err := newProvider.SetPooling(pooling); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure embedding pooling: %w", err)
+	}
+	if err := newProvider.SetBreakerPolicy(threshold, cooldown); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure embedding circuit breaker: %w", err)
+	}
+	if err := newProvider.SetRequestTimeouts(generateTimeout, embedTimeout); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure embedding request timeouts: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Close existing provider once its in-flight calls complete
+	if m.embeddingProvider != nil {
+		m.retire("embedding", m.embeddingProvider.GGUFProvider)
+	}
 
 	m.embeddingProvider = newProvider
+	m.track(newProvider.GGUFProvider)
 	m.config.EmbeddingModelPath = path
 
 	// Update cascade manager
@@ -232,23 +425,40 @@ func (m *ModelManager) SetEmbeddingPath(path string) error {
 
 // SetChatPath updates the chat model path and reloads the provider
 func (m *ModelManager) SetChatPath(path string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Close existing provider
-	if m.chatProvider != nil {
-		if err := m.chatProvider.Close(); err != nil {
-			log.Printf("Warning: Error closing old chat provider: %v", err)
-		}
-	}
+	m.mu.RLock()
+	threshold, cooldown := m.config.BreakerThreshold, m.config.BreakerCooldown
+	generateTimeout, embedTimeout := m.config.GenerateTimeout, m.config.EmbedTimeout
+	minP, repetitionPenalty := m.config.MinP, m.config.RepetitionPenalty
+	m.mu.RUnlock()
 
-	// Create new provider
+	// Create new provider before touching the current one so a failed load leaves it in service
 	newProvider, err := NewOpenChatProvider(path)
 	if err != nil {
 		return fmt.Errorf("failed to create new chat provider: %w", err)
 	}
+	if err := newProvider.SetBreakerPolicy(threshold, cooldown); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure chat circuit breaker: %w", err)
+	}
+	if err := newProvider.SetRequestTimeouts(generateTimeout, embedTimeout); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure chat request timeouts: %w", err)
+	}
+	if err := newProvider.SetSampling(minP, repetitionPenalty); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure chat sampling: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Close existing provider once its in-flight calls complete
+	if m.chatProvider != nil {
+		m.retire("chat", m.chatProvider.GGUFProvider)
+	}
 
 	m.chatProvider = newProvider
+	m.track(newProvider.GGUFProvider)
 	m.config.ChatModelPath = path
 
 	// Update cascade manager
@@ -261,23 +471,40 @@ func (m *ModelManager) SetChatPath(path string) error {
 
 // SetVisionPath updates the vision model path and reloads the provider
 func (m *ModelManager) SetVisionPath(path string) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	// Close existing provider
-	if m.visionProvider != nil {
-		if err := m.visionProvider.Close(); err != nil {
-			log.Printf("Warning: Error closing old vision provider: %v", err)
-		}
-	}
+	m.mu.RLock()
+	threshold, cooldown := m.config.BreakerThreshold, m.config.BreakerCooldown
+	generateTimeout, embedTimeout := m.config.GenerateTimeout, m.config.EmbedTimeout
+	minP, repetitionPenalty := m.config.MinP, m.config.RepetitionPenalty
+	m.mu.RUnlock()
 
-	// Create new provider
+	// Create new provider before touching the current one so a failed load leaves it in service
 	newProvider, err := NewOpenVisionProvider(path)
 	if err != nil {
 		return fmt.Errorf("failed to create new vision provider: %w", err)
 	}
+	if err := newProvider.SetBreakerPolicy(threshold, cooldown); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure vision circuit breaker: %w", err)
+	}
+	if err := newProvider.SetRequestTimeouts(generateTimeout, embedTimeout); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure vision request timeouts: %w", err)
+	}
+	if err := newProvider.SetSampling(minP, repetitionPenalty); err != nil {
+		newProvider.Close()
+		return fmt.Errorf("failed to configure vision sampling: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Close existing provider once its in-flight calls complete
+	if m.visionProvider != nil {
+		m.retire("vision", m.visionProvider.GGUFProvider)
+	}
 
 	m.visionProvider = newProvider
+	m.track(newProvider.GGUFProvider)
 	m.config.VisionModelPath = path
 
 	// Update cascade manager
@@ -295,26 +522,33 @@ func (m *ModelManager) GetCascadeManager() *CascadeManager {
 	return m.cascadeManager
 }
 
-// GetBestProvider returns the best available provider for a given task type
+// GetBestProvider returns the best available provider for a given task type.
+// The provider may be closed by a concurrent reload; prefer the Generate methods, which keep it alive for the call
 func (m *ModelManager) GetBestProvider(ctx context.Context, taskType ModelType) (*GGUFProvider, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return m.bestProvider(ctx, taskType)
+}
+
+// borrowProvider returns the best provider for taskType along with the func that releases it.
+// A reload defers closing the provider until release is called
+func (m *ModelManager) borrowProvider(ctx context.Context, taskType ModelType) (*GGUFProvider, func(), error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	provider, err := m.bestProvider(ctx, taskType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return provider, m.acquire(provider), nil
+}
 
+// bestProvider selects a provider for taskType; callers must hold m.mu
+func (m *ModelManager) bestProvider(ctx context.Context, taskType ModelType) (*GGUFProvider, error) {
 	if !m.config.EnableCascade {
 		// Return specific provider based on task type
-		switch taskType {
-		case ModelTypeEmbedding:
-			if m.embeddingProvider != nil {
-				return m.embeddingProvider.GGUFProvider, nil
-			}
-		case ModelTypeChat:
-			if m.chatProvider != nil {
-				return m.chatProvider.GGUFProvider, nil
-			}
-		case ModelTypeVision:
-			if m.visionProvider != nil {
-				return m.visionProvider.GGUFProvider, nil
-			}
+		if provider := m.providerOf(taskType); provider != nil {
+			return provider, nil
 		}
 		return nil, fmt.Errorf("no provider available for task type: %s", taskType)
 	}
@@ -323,12 +557,32 @@ func (m *ModelManager) GetBestProvider(ctx context.Context, taskType ModelType)
 	return m.cascadeManager.GetBestProvider(ctx)
 }
 
+// providerOf returns the loaded provider for modelType, or nil; callers must hold m.mu
+func (m *ModelManager) providerOf(modelType ModelType) *GGUFProvider {
+	switch modelType {
+	case ModelTypeEmbedding:
+		if m.embeddingProvider != nil {
+			return m.embeddingProvider.GGUFProvider
+		}
+	case ModelTypeChat:
+		if m.chatProvider != nil {
+			return m.chatProvider.GGUFProvider
+		}
+	case ModelTypeVision:
+		if m.visionProvider != nil {
+			return m.visionProvider.GGUFProvider
+		}
+	}
+	return nil
+}
+
 // GenerateText generates text using the best available chat provider
 func (m *ModelManager) GenerateText(ctx context.Context, prompt string, options ...interface{}) (string, error) {
-	provider, err := m.GetBestProvider(ctx, ModelTypeChat)
+	provider, release, err := m.borrowProvider(ctx, ModelTypeChat)
 	if err != nil {
 		return "", err
 	}
+	defer release()
 
 	// Pass options directly to provider (type handling is provider-specific)
 	return provider.GenerateText(ctx, prompt, options...)
@@ -336,21 +590,202 @@ func (m *ModelManager) GenerateText(ctx context.Context, prompt string, options
 
 // GenerateEmbedding generates embeddings using the embedding provider
 func (m *ModelManager) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	if m.embeddingProvider == nil {
+	return m.GenerateEmbeddingWith(ctx, DefaultEmbeddingModel, text)
+}
+
+// GenerateEmbeddingWith generates embeddings using the named embedding model.
+// An empty name or DefaultEmbeddingModel selects the default embedding provider
+func (m *ModelManager) GenerateEmbeddingWith(ctx context.Context, modelName, text string) ([]float32, error) {
+	m.mu.RLock()
+	provider, err := m.embedder(modelName)
+	if err != nil {
+		m.mu.RUnlock()
+		return nil, err
+	}
+	release := m.acquire(provider.GGUFProvider)
+	m.mu.RUnlock()
+	defer release()
+
+	return provider.EmbedText(ctx, text)
+}
+
+// MultiEmbed embeds text with each named model so callers can fuse several vector spaces.
+// With no names it uses every loaded embedding model; any failure fails the whole call
+func (m *ModelManager) MultiEmbed(ctx context.Context, text string, modelNames ...string) (map[string][]float32, error) {
+	m.mu.RLock()
+	if len(modelNames) == 0 {
+		modelNames = m.embeddingModelNames()
+	}
+	providers := make(map[string]*OpenEmbedProvider, len(modelNames))
+	var releases []func()
+	for _, name := range modelNames {
+		provider, err := m.embedder(name)
+		if err != nil {
+			m.mu.RUnlock()
+			for _, release := range releases {
+				release()
+			}
+			return nil, err
+		}
+		providers[name] = provider
+		releases = append(releases, m.acquire(provider.GGUFProvider))
+	}
+	m.mu.RUnlock()
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("embedding provider not available")
+	}
+
+	vectors := make(map[string][]float32, len(providers))
+	for name, provider := range providers {
+		vector, err := provider.EmbedText(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed with model %s: %w", name, err)
+		}
+		vectors[name] = vector
+	}
+	return vectors, nil
+}
+
+// RegisterEmbeddingProvider makes provider available as the named embedding model.
+// The manager's circuit breaker policy and request timeouts are applied; a provider already
+// registered under name is closed once its in-flight calls complete
+func (m *ModelManager) RegisterEmbeddingProvider(name string, provider *OpenEmbedProvider) error {
+	if name == "" || name == DefaultEmbeddingModel {
+		return fmt.Errorf("invalid embedding model name: %q", name)
+	}
+	if provider == nil {
+		return fmt.Errorf("embedding provider for %s is nil", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := provider.SetBreakerPolicy(m.config.BreakerThreshold, m.config.BreakerCooldown); err != nil {
+		return fmt.Errorf("failed to configure embedding circuit breaker: %w", err)
+	}
+	if err := provider.SetRequestTimeouts(m.config.GenerateTimeout, m.config.EmbedTimeout); err != nil {
+		return fmt.Errorf("failed to configure embedding request timeouts: %w", err)
+	}
+	if old, ok := m.namedEmbedders[name]; ok && old != provider {
+		m.retire("embedding "+name, old.GGUFProvider)
+	}
+
+	m.namedEmbedders[name] = provider
+	m.track(provider.GGUFProvider)
+	return nil
+}
+
+// LoadEmbeddingModel loads the GGUF model at path and registers it as the named embedding model
+func (m *ModelManager) LoadEmbeddingModel(name, path string) error {
+	m.mu.RLock()
+	pooling := m.config.EmbeddingPooling
+	m.mu.RUnlock()
+
+	provider, err := NewOpenEmbedProvider(path)
+	if err != nil {
+		return fmt.Errorf("failed to create embedding provider %s: %w", name, err)
+	}
+	if err := provider.SetPooling(pooling); err != nil {
+		provider.Close()
+		return fmt.Errorf("failed to configure embedding pooling: %w", err)
+	}
+	if err := m.RegisterEmbeddingProvider(name, provider); err != nil {
+		provider.Close()
+		return err
+	}
+	return nil
+}
+
+// EmbeddingModels lists the loaded embedding models, default first
+func (m *ModelManager) EmbeddingModels() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.embeddingModelNames()
+}
+
+// embedder resolves an embedding model by name; callers must hold m.mu
+func (m *ModelManager) embedder(name string) (*OpenEmbedProvider, error) {
+	if name == "" || name == DefaultEmbeddingModel {
+		if m.embeddingProvider == nil {
+			return nil, fmt.Errorf("embedding provider not available")
+		}
+		return m.embeddingProvider, nil
+	}
+	provider, ok := m.namedEmbedders[name]
+	if !ok {
+		return nil, fmt.Errorf("embedding model not registered: %s", name)
+	}
+	return provider, nil
+}
+
+// embeddingModelNames lists loaded embedding models, default first then sorted; callers must hold m.mu
+func (m *ModelManager) embeddingModelNames() []string {
+	names := make([]string, 0, len(m.namedEmbedders)+1)
+	if m.embeddingProvider != nil {
+		names = append(names, DefaultEmbeddingModel)
+	}
+	named := slices.Sorted(maps.Keys(m.namedEmbedders))
+	return append(names, named...)
+}
+
+// GenerateEmbeddings embeds a batch of texts with a single hold on the embedding provider
+func (m *ModelManager) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	m.mu.RLock()
+	provider := m.embeddingProvider
+	if provider == nil {
+		m.mu.RUnlock()
 		return nil, fmt.Errorf("embedding provider not available")
 	}
+	release := m.acquire(provider.GGUFProvider)
+	m.mu.RUnlock()
+	defer release()
 
-	return m.embeddingProvider.EmbedText(ctx, text)
+	return provider.EmbedBatch(ctx, texts)
+}
+
+// SetImageAnalyzer routes AnalyzeImage to analyzer instead of the vision provider; nil restores the provider
+func (m *ModelManager) SetImageAnalyzer(analyzer ImageAnalyzer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.imageAnalyzer = analyzer
 }
 
-// AnalyzeImage analyzes an image using the vision provider (placeholder)
-func (m *ModelManager) AnalyzeImage(ctx context.Context, imagePath string) (string, error) {
-	if m.visionProvider == nil {
-		return "", fmt.Errorf("vision provider not available")
+// AnalyzeImage analyzes the image at imagePath with the vision provider.
+// Dimensions and format are read from the image header when the analyzer does not report them
+func (m *ModelManager) AnalyzeImage(ctx context.Context, imagePath string) (*ImageAnalysis, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
 	}
 
-	// Placeholder: for now, return an error as multimodal is not implemented
-	return "", fmt.Errorf("image analysis not yet implemented")
+	m.mu.RLock()
+	analyzer := m.imageAnalyzer
+	release := func() {}
+	if analyzer == nil && m.visionProvider != nil {
+		analyzer = m.visionProvider
+		release = m.acquire(m.visionProvider.GGUFProvider)
+	}
+	m.mu.RUnlock()
+	defer release()
+	if analyzer == nil {
+		return nil, fmt.Errorf("vision provider not available")
+	}
+
+	analysis, err := analyzer.AnalyzeImage(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze image: %w", err)
+	}
+	if analysis == nil {
+		analysis = &ImageAnalysis{}
+	}
+	fillImageDimensions(analysis, data)
+	return analysis, nil
 }
 
 // GetHealthSummary returns health status of all providers
@@ -368,6 +803,17 @@ func (m *ModelManager) Close() error {
 		}
 	}
 
+	// Wait for replaced providers to finish closing
+	m.retiring.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Let in-flight calls on the current providers return before closing them
+	for _, wg := range m.inflight {
+		wg.Wait()
+	}
+
 	// Close providers
 	var errors []error
 
@@ -389,6 +835,12 @@ func (m *ModelManager) Close() error {
 		}
 	}
 
+	for name, provider := range m.namedEmbedders {
+		if err := provider.Close(); err != nil {
+			errors = append(errors, fmt.Errorf("embedding provider %s: %w", name, err))
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("errors closing providers: %v", errors)
 	}
@@ -396,12 +848,16 @@ func (m *ModelManager) Close() error {
 	return nil
 }
 
-// IsHealthy returns whether the model manager is healthy
+// IsHealthy reports whether every required provider (see ModelManagerConfig.RequiredModelTypes)
+// is loaded and healthy. Optional providers such as vision do not affect the result;
+// GetHealthSummary still reports them
 func (m *ModelManager) IsHealthy() bool {
-	healthSummary := m.GetHealthSummary()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	for _, health := range healthSummary {
-		if !health.IsHealthy {
+	for _, modelType := range m.config.requiredModelTypes() {
+		provider := m.providerOf(modelType)
+		if provider == nil || !provider.IsHealthy() {
 			return false
 		}
 	}
@@ -442,6 +898,18 @@ func (m *ModelManager) GetModelInfo() map[string]interface{} {
 		}
 	}
 
+	embeddingModels := make(map[string]interface{})
+	for _, name := range m.embeddingModelNames() {
+		provider, _ := m.embedder(name)
+		embeddingModels[name] = map[string]interface{}{
+			"type":       "embedding",
+			"dimensions": provider.GetMatryoshkaDims(),
+			"health":     provider.GetHealth(),
+			"model_path": provider.config.ModelPath,
+		}
+	}
+	info["embedding_models"] = embeddingModels
+
 	info["cascade_enabled"] = m.config.EnableCascade
 	info["health_monitoring"] = m.config.EnableHealthMonitoring
 
@@ -462,6 +930,19 @@ func (m *ModelManager) UpdateConfig(newConfig *ModelManagerConfig) error {
 		return fmt.Errorf("invalid context size: %d", newConfig.ContextSize)
 	}
 
+	newConfig.applyBreakerDefaults()
+	if err := ValidateBreakerPolicy(newConfig.BreakerThreshold, newConfig.BreakerCooldown); err != nil {
+		return fmt.Errorf("invalid circuit breaker policy: %w", err)
+	}
+	if err := validateModelTypes(newConfig.RequiredModelTypes); err != nil {
+		return fmt.Errorf("invalid required model types: %w", err)
+	}
+	for _, provider := range m.providers() {
+		if err := provider.SetBreakerPolicy(newConfig.BreakerThreshold, newConfig.BreakerCooldown); err != nil {
+			return fmt.Errorf("failed to update circuit breaker: %w", err)
+		}
+	}
+
 	// Update config
 	oldConfig := m.config
 	m.config = newConfig
@@ -511,24 +992,37 @@ func (m *ModelManager) ValidateModels() error {
 func (m *ModelManager) PreloadModels(ctx context.Context) error {
 	log.Println("Preloading AI models...")
 
+	m.mu.RLock()
+	chatProvider, embeddingProvider, visionProvider := m.chatProvider, m.embeddingProvider, m.visionProvider
+	releaseChat, releaseEmbedding := func() {}, func() {}
+	if chatProvider != nil {
+		releaseChat = m.acquire(chatProvider.GGUFProvider)
+	}
+	if embeddingProvider != nil {
+		releaseEmbedding = m.acquire(embeddingProvider.GGUFProvider)
+	}
+	m.mu.RUnlock()
+	defer releaseChat()
+	defer releaseEmbedding()
+
 	// Preload chat model with a simple prompt (no options needed for warmup)
-	if m.chatProvider != nil {
-		_, err := m.chatProvider.GenerateText(ctx, "Hello")
+	if chatProvider != nil {
+		_, err := chatProvider.GenerateText(ctx, "Hello")
 		if err != nil {
 			return fmt.Errorf("failed to preload chat model: %w", err)
 		}
 	}
 
 	// Preload embedding model with a simple text
-	if m.embeddingProvider != nil {
-		_, err := m.embeddingProvider.EmbedText(ctx, "test")
+	if embeddingProvider != nil {
+		_, err := embeddingProvider.EmbedText(ctx, "test")
 		if err != nil {
 			return fmt.Errorf("failed to preload embedding model: %w", err)
 		}
 	}
 
 	// Preload vision model if available
-	if m.visionProvider != nil {
+	if visionProvider != nil {
 		// FIXME: Vision preloading would require a test image
 		log.Println("Vision model preloaded (skipped - requires test image)")
 	}
@@ -0,0 +1,564 @@
+//go:build !llama
+
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestModelManager builds a manager over placeholder GGUF files with cascade and health monitoring off
+func newTestModelManager(t *testing.T) (*ModelManager, string) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	ggufData := []byte("GGUF" + string(make([]byte, 100)))
+	for _, name := range []string{"embed.gguf", "chat.gguf"} {
+		if err := os.WriteFile(filepath.Join(tempDir, name), ggufData, 0o644); err != nil {
+			t.Fatalf("Failed to create test GGUF file: %v", err)
+		}
+	}
+
+	config := DefaultModelManagerConfig()
+	config.EmbeddingModelPath = filepath.Join(tempDir, "embed.gguf")
+	config.ChatModelPath = filepath.Join(tempDir, "chat.gguf")
+	config.VisionModelPath = ""
+	config.EnableCascade = false
+	config.EnableHealthMonitoring = false
+
+	manager, err := NewModelManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create ModelManager: %v", err)
+	}
+	return manager, tempDir
+}
+
+// TestModelManager_ReloadDuringGenerate hammers GenerateText while the chat provider is reloaded
+func TestModelManager_ReloadDuringGenerate(t *testing.T) {
+	manager, tempDir := newTestModelManager(t)
+	defer manager.Close()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("GenerateText panicked during reload: %v", r)
+				}
+			}()
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				// The no-op provider has no model instances, so calls end in a borrow timeout
+				ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+				_, err := manager.GenerateText(ctx, "Hello")
+				cancel()
+				if errors.Is(err, ErrProviderClosed) {
+					t.Errorf("GenerateText used a closed provider: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	ggufData := []byte("GGUF" + string(make([]byte, 100)))
+	for i := 0; i < 50; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("chat-%d.gguf", i))
+		if err := os.WriteFile(path, ggufData, 0o644); err != nil {
+			t.Fatalf("Failed to create test GGUF file: %v", err)
+		}
+		if err := manager.SetChatPath(path); err != nil {
+			t.Fatalf("SetChatPath failed: %v", err)
+		}
+	}
+
+	close(stop)
+	wg.Wait()
+
+	if got := manager.GetModelInfo()["chat"].(map[string]interface{})["model_path"]; got != filepath.Join(tempDir, "chat-49.gguf") {
+		t.Errorf("Expected chat model path to follow the last reload, got %v", got)
+	}
+}
+
+// TestModelManager_ReloadDefersClose checks that a replaced provider stays open until its borrow is released
+func TestModelManager_ReloadDefersClose(t *testing.T) {
+	manager, tempDir := newTestModelManager(t)
+	defer manager.Close()
+
+	old, release, err := manager.borrowProvider(context.Background(), ModelTypeChat)
+	if err != nil {
+		t.Fatalf("borrowProvider failed: %v", err)
+	}
+
+	if err := manager.SetChatPath(filepath.Join(tempDir, "chat.gguf")); err != nil {
+		t.Fatalf("SetChatPath failed: %v", err)
+	}
+	if manager.GetChatProvider().GGUFProvider == old {
+		t.Fatal("Expected SetChatPath to replace the chat provider")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+	defer cancel()
+	if _, err := old.Borrow(ctx); errors.Is(err, ErrProviderClosed) {
+		t.Fatal("Replaced provider was closed while a call was still in flight")
+	}
+
+	release()
+	manager.retiring.Wait()
+
+	if _, err := old.Borrow(context.Background()); !errors.Is(err, ErrProviderClosed) {
+		t.Errorf("Expected ErrProviderClosed after release, got %v", err)
+	}
+}
+
+// TestModelManager_BreakerPolicy checks the configured breaker threshold opens the breaker and shows in health
+func TestModelManager_BreakerPolicy(t *testing.T) {
+	tempDir := t.TempDir()
+	chatPath := filepath.Join(tempDir, "chat.gguf")
+	if err := os.WriteFile(chatPath, []byte("GGUF"+string(make([]byte, 100))), 0o644); err != nil {
+		t.Fatalf("Failed to create test GGUF file: %v", err)
+	}
+
+	config := DefaultModelManagerConfig()
+	config.EmbeddingModelPath = chatPath
+	config.ChatModelPath = chatPath
+	config.VisionModelPath = ""
+	config.EnableCascade = false
+	config.EnableHealthMonitoring = false
+	config.BreakerThreshold = 2
+	config.BreakerCooldown = time.Minute
+
+	manager, err := NewModelManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create ModelManager: %v", err)
+	}
+	defer manager.Close()
+
+	chat := manager.GetChatProvider()
+	if got := chat.GetConfig().BreakerThreshold; got != 2 {
+		t.Fatalf("Expected breaker threshold 2 on the chat provider, got %d", got)
+	}
+	if health := chat.GetHealth(); health.BreakerOpen {
+		t.Fatal("Expected breaker to start closed")
+	}
+
+	// The no-op provider has no model instances, so each call fails with a borrow timeout
+	for i := 0; i < 2; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Millisecond)
+		_, err := manager.GenerateText(ctx, "Hello")
+		cancel()
+		if err == nil {
+			t.Fatalf("Expected call %d to fail", i)
+		}
+	}
+
+	health := chat.GetHealth()
+	if !health.BreakerOpen {
+		t.Error("Expected breaker to open after the configured number of failures")
+	}
+	if health.BreakerFailures != 2 {
+		t.Errorf("Expected 2 breaker failures, got %d", health.BreakerFailures)
+	}
+
+	_, err = manager.GenerateText(context.Background(), "Hello")
+	if err == nil || !strings.Contains(err.Error(), "circuit breaker is open") {
+		t.Errorf("Expected open breaker to reject the call, got %v", err)
+	}
+
+	// Reloads keep the configured policy
+	if err := manager.SetChatPath(chatPath); err != nil {
+		t.Fatalf("SetChatPath failed: %v", err)
+	}
+	if got := manager.GetChatProvider().GetConfig().BreakerCooldown; got != time.Minute {
+		t.Errorf("Expected reloaded provider to keep a 1m cooldown, got %v", got)
+	}
+}
+
+// sleepingTokenEmbedder stands in for a slow model: it only returns once its context is done
+type sleepingTokenEmbedder struct{}
+
+func (sleepingTokenEmbedder) EmbedTokens(ctx context.Context, text string) (TokenEmbeddings, error) {
+	select {
+	case <-ctx.Done():
+		return TokenEmbeddings{}, ctx.Err()
+	case <-time.After(5 * time.Second):
+		return TokenEmbeddings{Vectors: [][]float32{{1}}}, nil
+	}
+}
+
+// TestModelManager_RequestTimeouts checks embeddings are bounded by the shorter embed timeout and
+// generation by the longer generate timeout
+func TestModelManager_RequestTimeouts(t *testing.T) {
+	tempDir := t.TempDir()
+	modelPath := filepath.Join(tempDir, "model.gguf")
+	if err := os.WriteFile(modelPath, []byte("GGUF"+string(make([]byte, 100))), 0o644); err != nil {
+		t.Fatalf("Failed to create test GGUF file: %v", err)
+	}
+
+	config := DefaultModelManagerConfig()
+	config.EmbeddingModelPath = modelPath
+	config.ChatModelPath = modelPath
+	config.VisionModelPath = ""
+	config.EnableCascade = false
+	config.EnableHealthMonitoring = false
+	config.GenerateTimeout = 300 * time.Millisecond
+	config.EmbedTimeout = 30 * time.Millisecond
+
+	manager, err := NewModelManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create ModelManager: %v", err)
+	}
+	defer manager.Close()
+
+	// The no-op provider has no model instances, so each call waits out its request timeout
+	start := time.Now()
+	if _, err := manager.GenerateEmbedding(context.Background(), "hello"); err == nil {
+		t.Fatal("Expected embedding to time out")
+	}
+	if elapsed := time.Since(start); elapsed >= config.GenerateTimeout {
+		t.Errorf("Expected embedding to give up after the embed timeout, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if _, err := manager.GenerateText(context.Background(), "Hello"); err == nil {
+		t.Fatal("Expected generation to time out")
+	}
+	if elapsed := time.Since(start); elapsed < config.GenerateTimeout {
+		t.Errorf("Expected generation to run for the generate timeout, gave up after %v", elapsed)
+	}
+
+	// A token embedder that sleeps is cut off by the same embed timeout
+	manager.GetEmbeddingProvider().SetTokenEmbedder(sleepingTokenEmbedder{})
+	start = time.Now()
+	_, err = manager.GenerateEmbedding(context.Background(), "hello")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded from the sleeping embedder, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= config.GenerateTimeout {
+		t.Errorf("Expected the sleeping embedder to be cut off by the embed timeout, took %v", elapsed)
+	}
+
+	// Unset timeouts fall back to RequestTimeout
+	gguf := DefaultGGUFConfig("model.gguf", ModelTypeChat)
+	gguf.RequestTimeout = time.Minute
+	gguf.GenerateTimeout = 0
+	gguf.EmbedTimeout = 0
+	if gguf.generateTimeout() != time.Minute || gguf.embedTimeout() != time.Minute {
+		t.Errorf("Expected both timeouts to fall back to 1m, got generate=%v embed=%v", gguf.generateTimeout(), gguf.embedTimeout())
+	}
+}
+
+// TestModelManager_SamplingSettings checks the configured min-p and repetition penalty reach the
+// chat provider's predict settings, and that generation stays neutral without them
+func TestModelManager_SamplingSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	modelPath := filepath.Join(tempDir, "model.gguf")
+	if err := os.WriteFile(modelPath, []byte("GGUF"+string(make([]byte, 100))), 0o644); err != nil {
+		t.Fatalf("Failed to create test GGUF file: %v", err)
+	}
+
+	neutral := DefaultGGUFConfig(modelPath, ModelTypeChat).predictSettings()
+	if neutral.MinP != 0 || neutral.RepetitionPenalty != 1 || neutral.RepeatLastN != 1 {
+		t.Errorf("Expected neutral sampling by default, got %+v", neutral)
+	}
+
+	config := DefaultModelManagerConfig()
+	config.EmbeddingModelPath = modelPath
+	config.ChatModelPath = modelPath
+	config.VisionModelPath = ""
+	config.EnableCascade = false
+	config.EnableHealthMonitoring = false
+	config.MinP = 0.05
+	config.RepetitionPenalty = 1.1
+
+	manager, err := NewModelManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	defer manager.Close()
+
+	settings := manager.chatProvider.predictSettings()
+	if settings.MinP != 0.05 || settings.RepetitionPenalty != 1.1 {
+		t.Errorf("Expected min_p=0.05 and repetition penalty=1.1, got %+v", settings)
+	}
+	if settings.RepeatLastN != defaultRepeatLastN {
+		t.Errorf("Expected the penalty to cover %d tokens, got %d", defaultRepeatLastN, settings.RepeatLastN)
+	}
+	if settings.Temperature != 0.7 || settings.TopP != 0.9 || settings.Tokens != 512 {
+		t.Errorf("Expected the chat defaults to be kept, got %+v", settings)
+	}
+
+	if err := manager.chatProvider.SetSampling(1.5, 0); err == nil {
+		t.Error("Expected min_p above 1 to be rejected")
+	}
+
+	config = DefaultModelManagerConfig()
+	config.EnableHealthMonitoring = false
+	config.RepetitionPenalty = 3
+	if _, err := NewModelManager(config); err == nil {
+		t.Error("Expected an out-of-range repetition penalty to be rejected")
+	}
+}
+
+// TestNewModelManager_InvalidBreakerPolicy rejects negative breaker settings
+func TestNewModelManager_InvalidBreakerPolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		cooldown  time.Duration
+	}{
+		{"negative threshold", -1, time.Minute},
+		{"negative cooldown", 3, -time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := DefaultModelManagerConfig()
+			config.EnableHealthMonitoring = false
+			config.BreakerThreshold = test.threshold
+			config.BreakerCooldown = test.cooldown
+
+			if _, err := NewModelManager(config); err == nil {
+				t.Error("Expected invalid breaker policy to be rejected")
+			}
+		})
+	}
+
+	if err := ValidateBreakerPolicy(0, time.Minute); err == nil {
+		t.Error("Expected zero threshold to be rejected")
+	}
+	if err := ValidateBreakerPolicy(1, 0); err == nil {
+		t.Error("Expected zero cooldown to be rejected")
+	}
+}
+
+// TestModelManager_IsHealthyRequiredProviders checks only required providers decide IsHealthy
+func TestModelManager_IsHealthyRequiredProviders(t *testing.T) {
+	newManager := func(t *testing.T, required []ModelType) *ModelManager {
+		tempDir := t.TempDir()
+		ggufData := []byte("GGUF" + string(make([]byte, 100)))
+		for _, name := range []string{"embed.gguf", "chat.gguf", "vision.gguf"} {
+			if err := os.WriteFile(filepath.Join(tempDir, name), ggufData, 0o644); err != nil {
+				t.Fatalf("Failed to create test GGUF file: %v", err)
+			}
+		}
+
+		config := DefaultModelManagerConfig()
+		config.EmbeddingModelPath = filepath.Join(tempDir, "embed.gguf")
+		config.ChatModelPath = filepath.Join(tempDir, "chat.gguf")
+		config.VisionModelPath = filepath.Join(tempDir, "vision.gguf")
+		config.EnableCascade = false
+		config.EnableHealthMonitoring = false
+		config.RequiredModelTypes = required
+
+		manager, err := NewModelManager(config)
+		if err != nil {
+			t.Fatalf("Failed to create ModelManager: %v", err)
+		}
+		t.Cleanup(func() { manager.Close() })
+		return manager
+	}
+
+	t.Run("optional vision down", func(t *testing.T) {
+		manager := newManager(t, nil)
+		if !manager.IsHealthy() {
+			t.Fatal("Expected a freshly loaded manager to be healthy")
+		}
+
+		manager.GetVisionProvider().recordFailure("vision model offline")
+		if !manager.IsHealthy() {
+			t.Error("Expected an unhealthy optional vision provider to leave the manager healthy")
+		}
+		manager.cascadeManager.UpdateHealth()
+		if health := manager.GetHealthSummary()["open-vision"]; health == nil || health.IsHealthy {
+			t.Errorf("Expected the health summary to report vision as unhealthy, got %+v", health)
+		}
+	})
+
+	t.Run("required chat down", func(t *testing.T) {
+		manager := newManager(t, nil)
+		manager.GetChatProvider().recordFailure("chat model offline")
+		if manager.IsHealthy() {
+			t.Error("Expected an unhealthy chat provider to mark the manager unhealthy")
+		}
+	})
+
+	t.Run("vision made required", func(t *testing.T) {
+		manager := newManager(t, []ModelType{ModelTypeChat, ModelTypeVision})
+		manager.GetVisionProvider().recordFailure("vision model offline")
+		if manager.IsHealthy() {
+			t.Error("Expected a required vision provider to count toward health")
+		}
+	})
+
+	t.Run("unknown type rejected", func(t *testing.T) {
+		config := DefaultModelManagerConfig()
+		config.EnableHealthMonitoring = false
+		config.RequiredModelTypes = []ModelType{"audio"}
+		if _, err := NewModelManager(config); err == nil {
+			t.Error("Expected an unknown required model type to be rejected")
+		}
+	})
+}
+
+// newTestEmbedProvider loads a placeholder embedding model whose output is a fixed 2-dim vector
+func newTestEmbedProvider(t *testing.T, dir, name string, vector []float32) *OpenEmbedProvider {
+	t.Helper()
+
+	path := filepath.Join(dir, name+".gguf")
+	if err := os.WriteFile(path, []byte("GGUF"+string(make([]byte, 100))), 0o644); err != nil {
+		t.Fatalf("Failed to create test GGUF file: %v", err)
+	}
+	provider, err := NewOpenEmbedProvider(path)
+	if err != nil {
+		t.Fatalf("Failed to create embedding provider %s: %v", name, err)
+	}
+	provider.SetMatryoshkaDims(2)
+	provider.SetTokenEmbedder(stubTokenEmbedder{tokens: TokenEmbeddings{Vectors: [][]float32{vector}}})
+	return provider
+}
+
+// TestModelManager_NamedEmbeddingModels registers two extra embedding models and addresses each by name
+func TestModelManager_NamedEmbeddingModels(t *testing.T) {
+	manager, tempDir := newTestModelManager(t)
+	defer manager.Close()
+	ctx := context.Background()
+
+	manager.GetEmbeddingProvider().SetMatryoshkaDims(2)
+	manager.GetEmbeddingProvider().SetTokenEmbedder(stubTokenEmbedder{tokens: TokenEmbeddings{Vectors: [][]float32{{1, 1}}}})
+	for name, vector := range map[string][]float32{"code": {2, 0}, "legal": {0, 3}} {
+		if err := manager.RegisterEmbeddingProvider(name, newTestEmbedProvider(t, tempDir, name, vector)); err != nil {
+			t.Fatalf("RegisterEmbeddingProvider(%s) failed: %v", name, err)
+		}
+	}
+
+	got, err := manager.GenerateEmbeddingWith(ctx, "code", "func main()")
+	if err != nil {
+		t.Fatalf("GenerateEmbeddingWith(code) failed: %v", err)
+	}
+	assertVector(t, "code", got, []float32{2, 0})
+
+	got, err = manager.GenerateEmbeddingWith(ctx, "legal", "whereas")
+	if err != nil {
+		t.Fatalf("GenerateEmbeddingWith(legal) failed: %v", err)
+	}
+	assertVector(t, "legal", got, []float32{0, 3})
+
+	// The default model is unaffected by registration
+	got, err = manager.GenerateEmbedding(ctx, "hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	assertVector(t, "default", got, []float32{1, 1})
+
+	vectors, err := manager.MultiEmbed(ctx, "hello")
+	if err != nil {
+		t.Fatalf("MultiEmbed failed: %v", err)
+	}
+	if len(vectors) != 3 {
+		t.Fatalf("Expected vectors from 3 models, got %d", len(vectors))
+	}
+	assertVector(t, "multi default", vectors[DefaultEmbeddingModel], []float32{1, 1})
+	assertVector(t, "multi legal", vectors["legal"], []float32{0, 3})
+
+	vectors, err = manager.MultiEmbed(ctx, "hello", "code")
+	if err != nil || len(vectors) != 1 {
+		t.Fatalf("Expected one vector from MultiEmbed(code), got %v (err %v)", vectors, err)
+	}
+
+	if _, err := manager.GenerateEmbeddingWith(ctx, "missing", "hello"); err == nil {
+		t.Error("Expected an unregistered model name to be rejected")
+	}
+	if _, err := manager.MultiEmbed(ctx, "hello", "code", "missing"); err == nil {
+		t.Error("Expected MultiEmbed to fail on an unregistered model")
+	}
+	if err := manager.RegisterEmbeddingProvider(DefaultEmbeddingModel, newTestEmbedProvider(t, tempDir, "other", []float32{9, 9})); err == nil {
+		t.Error("Expected registering over the default name to be rejected")
+	}
+
+	if names := manager.EmbeddingModels(); strings.Join(names, ",") != "default,code,legal" {
+		t.Errorf("Expected default,code,legal, got %v", names)
+	}
+	models, ok := manager.GetModelInfo()["embedding_models"].(map[string]interface{})
+	if !ok || len(models) != 3 {
+		t.Errorf("Expected 3 embedding models in GetModelInfo, got %v", manager.GetModelInfo()["embedding_models"])
+	}
+}
+
+// stubImageAnalyzer returns a fixed analysis and records the bytes it was given
+type stubImageAnalyzer struct {
+	analysis *ImageAnalysis
+	received []byte
+}
+
+func (a *stubImageAnalyzer) AnalyzeImage(ctx context.Context, imageData []byte) (*ImageAnalysis, error) {
+	a.received = imageData
+	return a.analysis, nil
+}
+
+// TestModelManager_AnalyzeImage checks image analysis is delegated to the analyzer and the
+// dimensions are filled in from the image header
+func TestModelManager_AnalyzeImage(t *testing.T) {
+	manager, tempDir := newTestModelManager(t)
+	defer manager.Close()
+	ctx := context.Background()
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewRGBA(image.Rect(0, 0, 64, 48))); err != nil {
+		t.Fatalf("Failed to encode test image: %v", err)
+	}
+	imagePath := filepath.Join(tempDir, "photo.png")
+	if err := os.WriteFile(imagePath, encoded.Bytes(), 0o644); err != nil {
+		t.Fatalf("Failed to write test image: %v", err)
+	}
+
+	if _, err := manager.AnalyzeImage(ctx, imagePath); err == nil {
+		t.Error("Expected an error without a vision provider")
+	}
+
+	analyzer := &stubImageAnalyzer{analysis: &ImageAnalysis{
+		Caption: "a cat on a sofa",
+		Tags:    []string{"cat", "sofa"},
+		Objects: []DetectedObject{{Label: "cat", Confidence: 0.9, Bounds: image.Rect(4, 4, 40, 30)}},
+	}}
+	manager.SetImageAnalyzer(analyzer)
+
+	analysis, err := manager.AnalyzeImage(ctx, imagePath)
+	if err != nil {
+		t.Fatalf("AnalyzeImage failed: %v", err)
+	}
+	if !bytes.Equal(analyzer.received, encoded.Bytes()) {
+		t.Error("Expected the analyzer to receive the image file contents")
+	}
+	if analysis.Caption != "a cat on a sofa" || strings.Join(analysis.Tags, ",") != "cat,sofa" {
+		t.Errorf("Expected the analyzer's caption and tags, got %+v", analysis)
+	}
+	if len(analysis.Objects) != 1 || analysis.Objects[0].Label != "cat" {
+		t.Errorf("Expected one detected cat, got %+v", analysis.Objects)
+	}
+	if analysis.Width != 64 || analysis.Height != 48 || analysis.Format != "png" {
+		t.Errorf("Expected a 64x48 png, got %dx%d %q", analysis.Width, analysis.Height, analysis.Format)
+	}
+
+	if _, err := manager.AnalyzeImage(ctx, filepath.Join(tempDir, "missing.png")); err == nil {
+		t.Error("Expected an error for a missing image")
+	}
+}
@@ -14,6 +14,7 @@ import (
 type OpenEmbedProvider struct {
 	*GGUFProvider
 	matryoshkaDims int
+	tokenEmbedder  TokenEmbedder // optional per-token backend, pooled with config.Pooling
 }
 
 // NewOpenEmbedProvider creates a new OpenEmbedProvider with Qwen3-Embedding-0.6B defaults
@@ -40,18 +41,23 @@ func NewOpenEmbedProvider(modelPath string) (*OpenEmbedProvider, error) {
 	return provider, nil
 }
 
-// EmbedText generates embeddings with Matryoshka support
+// EmbedText generates document embeddings with Matryoshka support; it is EmbedDocument
 func (p *OpenEmbedProvider) EmbedText(ctx context.Context, text string) ([]float32, error) {
-	embedding, err := p.GGUFProvider.EmbedText(ctx, text)
-	if err != nil {
-		return nil, err
-	}
+	return p.EmbedDocument(ctx, text)
+}
 
-	if len(embedding) != p.matryoshkaDims {
-		embedding = p.adjustToDims(embedding, p.matryoshkaDims)
+// EmbedBatch embeds texts in order, stopping at the first failure
+func (p *OpenEmbedProvider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := p.EmbedText(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed text %d: %w", i, err)
+		}
+		embeddings[i] = embedding
 	}
 
-	return embedding, nil
+	return embeddings, nil
 }
 
 // SetMatryoshkaDims sets the target dimension for Matryoshka embeddings
@@ -64,21 +70,6 @@ func (p *OpenEmbedProvider) GetMatryoshkaDims() int {
 	return p.matryoshkaDims
 }
 
-// adjustToDims truncates or pads the embedding vector to the target dimensions
-func (p *OpenEmbedProvider) adjustToDims(vec []float32, target int) []float32 {
-	if len(vec) == target {
-		return vec
-	}
-
-	if len(vec) > target {
-		return vec[:target]
-	}
-
-	padded := make([]float32, target)
-	copy(padded, vec)
-	return padded
-}
-
 // OpenChatProvider wraps GGUFProvider for chat tasks with Qwen3-1.7B defaults
 type OpenChatProvider struct {
 	*GGUFProvider
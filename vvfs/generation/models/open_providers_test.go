@@ -92,17 +92,24 @@ func TestOpenEmbedProvider_SetMatryoshkaDims(t *testing.T) {
 
 	// Test adjustToDims logic
 	tests := []struct {
+		policy   string
 		input    []float32
 		target   int
 		expected int
 	}{
-		{[]float32{1, 2, 3}, 3, 3},
-		{[]float32{1, 2, 3, 4}, 2, 2}, // Truncate
-		{[]float32{1, 2}, 4, 4},       // Pad
+		{"strict", []float32{1, 2, 3}, 3, 3},
+		{"truncate", []float32{1, 2, 3, 4}, 2, 2}, // Truncate
+		{"pad", []float32{1, 2}, 4, 4},            // Pad
 	}
 
 	for _, test := range tests {
-		result := provider.adjustToDims(test.input, test.target)
+		if err := provider.SetDimsPolicy(test.policy); err != nil {
+			t.Fatalf("SetDimsPolicy(%s) failed: %v", test.policy, err)
+		}
+		result, err := provider.adjustToDims(test.input, test.target)
+		if err != nil {
+			t.Fatalf("adjustToDims(%v, %d) failed: %v", test.input, test.target, err)
+		}
 		if len(result) != test.expected {
 			t.Errorf("adjustToDims(%v, %d) = %d, expected %d", test.input, test.target, len(result), test.expected)
 		}
@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PoolingStrategy selects how token-level hidden states are reduced to one embedding.
+//
+//   - mean: average of all non-padding tokens. Suits encoder models trained with mean
+//     pooling (sentence-transformers, e5, gte, nomic-embed).
+//   - cls: the first token's state. Suits BERT-style models trained on the [CLS] token
+//     (bge, original BERT/RoBERTa classifiers).
+//   - last_token: the final non-padding token. Suits decoder-only embedders that attend
+//     causally (Qwen3-Embedding, e5-mistral, gte-Qwen2, SFR-Embedding).
+//   - weighted_mean: position-weighted mean where token i has weight i+1, so later tokens
+//     that have seen more context count more. Suits decoder models used without
+//     last-token training (SGPT-style).
+type PoolingStrategy string
+
+const (
+	PoolingMean         PoolingStrategy = "mean"
+	PoolingCLS          PoolingStrategy = "cls"
+	PoolingLastToken    PoolingStrategy = "last_token"
+	PoolingWeightedMean PoolingStrategy = "weighted_mean"
+)
+
+// ParsePoolingStrategy validates a configured pooling name; empty selects mean
+func ParsePoolingStrategy(name string) (PoolingStrategy, error) {
+	switch strategy := PoolingStrategy(strings.ToLower(strings.TrimSpace(name))); strategy {
+	case "":
+		return PoolingMean, nil
+	case PoolingMean, PoolingCLS, PoolingLastToken, PoolingWeightedMean:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unsupported pooling strategy %q (expected mean, cls, last_token or weighted_mean)", name)
+	}
+}
+
+// TokenEmbeddings holds per-token hidden states for one input
+type TokenEmbeddings struct {
+	Vectors [][]float32 // one vector per token, in sequence order
+	Mask    []int       // attention mask (1 = real token, 0 = padding); nil means all real
+}
+
+// TokenEmbedder is implemented by backends that expose token-level outputs
+type TokenEmbedder interface {
+	EmbedTokens(ctx context.Context, text string) (TokenEmbeddings, error)
+}
+
+// PoolTokenEmbeddings reduces token-level outputs to a single vector using strategy
+func PoolTokenEmbeddings(tokens TokenEmbeddings, strategy PoolingStrategy) ([]float32, error) {
+	if tokens.Mask != nil && len(tokens.Mask) != len(tokens.Vectors) {
+		return nil, fmt.Errorf("attention mask has %d entries for %d tokens", len(tokens.Mask), len(tokens.Vectors))
+	}
+
+	// Positions of real tokens, preserving order
+	positions := make([]int, 0, len(tokens.Vectors))
+	for i := range tokens.Vectors {
+		if tokens.Mask == nil || tokens.Mask[i] != 0 {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("no unmasked tokens to pool")
+	}
+
+	dims := len(tokens.Vectors[positions[0]])
+	for _, pos := range positions {
+		if len(tokens.Vectors[pos]) != dims {
+			return nil, fmt.Errorf("token %d has dimension %d, expected %d", pos, len(tokens.Vectors[pos]), dims)
+		}
+	}
+
+	switch strategy {
+	case PoolingCLS:
+		return append([]float32(nil), tokens.Vectors[positions[0]]...), nil
+	case PoolingLastToken:
+		return append([]float32(nil), tokens.Vectors[positions[len(positions)-1]]...), nil
+	case PoolingMean, PoolingWeightedMean:
+		pooled := make([]float64, dims)
+		var total float64
+		for rank, pos := range positions {
+			weight := 1.0
+			if strategy == PoolingWeightedMean {
+				weight = float64(rank + 1)
+			}
+			for d, v := range tokens.Vectors[pos] {
+				pooled[d] += weight * float64(v)
+			}
+			total += weight
+		}
+		out := make([]float32, dims)
+		for d := range pooled {
+			out[d] = float32(pooled[d] / total)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported pooling strategy %q", strategy)
+	}
+}
+
+// SetPooling selects the pooling applied to token-level outputs
+func (p *OpenEmbedProvider) SetPooling(name string) error {
+	strategy, err := ParsePoolingStrategy(name)
+	if err != nil {
+		return err
+	}
+	p.GGUFProvider.config.Pooling = strategy
+	return nil
+}
+
+// GetPooling returns the configured pooling strategy
+func (p *OpenEmbedProvider) GetPooling() PoolingStrategy {
+	return p.GGUFProvider.config.Pooling
+}
+
+// SetTokenEmbedder routes embedding through a backend exposing per-token outputs,
+// which are then pooled with the configured strategy. Nil restores the backend's own pooling.
+func (p *OpenEmbedProvider) SetTokenEmbedder(embedder TokenEmbedder) {
+	p.tokenEmbedder = embedder
+}
+
+// embedPooled pools token-level outputs when available, otherwise uses the backend embedding
+func (p *OpenEmbedProvider) embedPooled(ctx context.Context, text string) ([]float32, error) {
+	if p.tokenEmbedder == nil {
+		return p.GGUFProvider.EmbedText(ctx, text)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.embedTimeout())
+	defer cancel()
+
+	tokens, err := p.tokenEmbedder.EmbedTokens(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed tokens: %w", err)
+	}
+	return PoolTokenEmbeddings(tokens, p.GetPooling())
+}
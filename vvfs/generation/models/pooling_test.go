@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func assertVector(t *testing.T, name string, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: expected %d dims, got %d", name, len(want), len(got))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-6 {
+			t.Errorf("%s: dim %d expected %f, got %f", name, i, want[i], got[i])
+		}
+	}
+}
+
+// TestPoolTokenEmbeddings tests each pooling strategy on synthetic token outputs
+func TestPoolTokenEmbeddings(t *testing.T) {
+	tokens := TokenEmbeddings{
+		Vectors: [][]float32{
+			{1, 0},
+			{0, 2},
+			{3, 4},
+			{9, 9}, // padding
+		},
+		Mask: []int{1, 1, 1, 0},
+	}
+
+	tests := []struct {
+		strategy PoolingStrategy
+		want     []float32
+	}{
+		{PoolingMean, []float32{4.0 / 3, 2}},
+		{PoolingCLS, []float32{1, 0}},
+		{PoolingLastToken, []float32{3, 4}},
+		// weights 1, 2, 3 over the unmasked tokens
+		{PoolingWeightedMean, []float32{(1*1 + 3*3) / 6.0, (2*2 + 3*4) / 6.0}},
+	}
+
+	for _, tt := range tests {
+		got, err := PoolTokenEmbeddings(tokens, tt.strategy)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.strategy, err)
+		}
+		assertVector(t, string(tt.strategy), got, tt.want)
+	}
+
+	// Without a mask every token counts
+	got, err := PoolTokenEmbeddings(TokenEmbeddings{Vectors: tokens.Vectors}, PoolingLastToken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "unmasked last_token", got, []float32{9, 9})
+
+	if _, err := PoolTokenEmbeddings(TokenEmbeddings{Vectors: tokens.Vectors, Mask: []int{0, 0, 0, 0}}, PoolingMean); err == nil {
+		t.Error("expected error when every token is masked")
+	}
+	if _, err := PoolTokenEmbeddings(TokenEmbeddings{Vectors: [][]float32{{1, 2}, {3}}}, PoolingMean); err == nil {
+		t.Error("expected error for ragged token vectors")
+	}
+}
+
+// TestParsePoolingStrategy tests validation of configured pooling names
+func TestParsePoolingStrategy(t *testing.T) {
+	for name, want := range map[string]PoolingStrategy{
+		"":              PoolingMean,
+		"mean":          PoolingMean,
+		"CLS":           PoolingCLS,
+		"last_token":    PoolingLastToken,
+		"weighted_mean": PoolingWeightedMean,
+	} {
+		got, err := ParsePoolingStrategy(name)
+		if err != nil || got != want {
+			t.Errorf("ParsePoolingStrategy(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParsePoolingStrategy("max"); err == nil {
+		t.Error("expected error for unsupported strategy")
+	}
+
+	config := DefaultGGUFConfig("model.gguf", ModelTypeEmbedding)
+	config.Pooling = "attention"
+	if err := ValidateConfig(config); err == nil {
+		t.Error("expected ValidateConfig to reject unsupported pooling")
+	}
+}
+
+type stubTokenEmbedder struct{ tokens TokenEmbeddings }
+
+func (s stubTokenEmbedder) EmbedTokens(ctx context.Context, text string) (TokenEmbeddings, error) {
+	return s.tokens, nil
+}
+
+// TestOpenEmbedProvider_PoolsTokenOutputs tests that the provider applies the configured pooling
+func TestOpenEmbedProvider_PoolsTokenOutputs(t *testing.T) {
+	provider := &OpenEmbedProvider{
+		GGUFProvider:   &GGUFProvider{config: DefaultGGUFConfig("model.gguf", ModelTypeEmbedding)},
+		matryoshkaDims: 2,
+	}
+	provider.SetTokenEmbedder(stubTokenEmbedder{tokens: TokenEmbeddings{Vectors: [][]float32{{1, 1}, {3, 5}}}})
+
+	if err := provider.SetPooling("last_token"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := provider.EmbedText(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "last_token", got, []float32{3, 5})
+
+	if err := provider.SetPooling("mean"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err = provider.EmbedText(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertVector(t, "mean", got, []float32{2, 3})
+
+	if err := provider.SetPooling("max"); err == nil {
+		t.Error("expected error for unsupported pooling")
+	}
+	if provider.GetPooling() != PoolingMean {
+		t.Errorf("invalid pooling should leave the strategy unchanged, got %q", provider.GetPooling())
+	}
+}
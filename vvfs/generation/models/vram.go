@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrInsufficientVRAM is returned when free GPU memory is below GGUFModelConfig.MinFreeVRAMBytes
+var ErrInsufficientVRAM = errors.New("insufficient free VRAM")
+
+// VRAMQuery reports the free memory in bytes of the GPU models are loaded onto
+type VRAMQuery func() (uint64, error)
+
+// queryFreeVRAM is the VRAM query used by the load path; tests replace it
+var queryFreeVRAM VRAMQuery = nvidiaSMIFreeVRAM
+
+// minFreeVRAMFromEnv reads VVFS_MIN_FREE_VRAM_MB, returning 0 (guard disabled) when unset or invalid
+func minFreeVRAMFromEnv() uint64 {
+	mb, err := strconv.ParseUint(strings.TrimSpace(os.Getenv("VVFS_MIN_FREE_VRAM_MB")), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return mb << 20
+}
+
+// nvidiaSMIFreeVRAM asks nvidia-smi for the free memory of the first GPU, which llama.cpp
+// offloads to by default
+func nvidiaSMIFreeVRAM() (uint64, error) {
+	out, err := exec.Command("nvidia-smi", "--query-gpu=memory.free", "--format=csv,noheader,nounits").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to run nvidia-smi: %w", err)
+	}
+
+	first, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	mb, err := strconv.ParseUint(strings.TrimSpace(first), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse nvidia-smi output %q: %w", first, err)
+	}
+	return mb << 20, nil
+}
+
+// planGPULayers applies the free-VRAM guard and returns the GPU layers to load with.
+// The guard is skipped for CPU-only loads or when MinFreeVRAMBytes is zero. When free VRAM is
+// below the threshold, or cannot be queried, the load is refused with ErrInsufficientVRAM
+// unless VRAMFallbackCPU is set, in which case the model is loaded on the CPU
+func (c *GGUFModelConfig) planGPULayers(query VRAMQuery) (int, error) {
+	if c.GPULayers == 0 || c.MinFreeVRAMBytes == 0 {
+		return c.GPULayers, nil
+	}
+
+	free, err := query()
+	if err != nil {
+		if c.VRAMFallbackCPU {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: failed to query free VRAM: %w", ErrInsufficientVRAM, err)
+	}
+
+	if free < c.MinFreeVRAMBytes {
+		if c.VRAMFallbackCPU {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %d MiB free, %d MiB required to load %s with %d GPU layers",
+			ErrInsufficientVRAM, free>>20, c.MinFreeVRAMBytes>>20, c.ModelPath, c.GPULayers)
+	}
+
+	return c.GPULayers, nil
+}
@@ -0,0 +1,77 @@
+//go:build !llama
+
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPlanGPULayers checks the free-VRAM guard declines short devices and passes adequate ones
+func TestPlanGPULayers(t *testing.T) {
+	const gib = uint64(1) << 30
+	freeVRAM := func(free uint64) VRAMQuery {
+		return func() (uint64, error) { return free, nil }
+	}
+
+	config := DefaultGGUFConfig("model.gguf", ModelTypeChat)
+	config.GPULayers = -1
+	config.MinFreeVRAMBytes = 4 * gib
+
+	// Adequate VRAM keeps the requested offload
+	layers, err := config.planGPULayers(freeVRAM(6 * gib))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if layers != -1 {
+		t.Errorf("Expected all GPU layers with enough VRAM, got %d", layers)
+	}
+
+	// Insufficient VRAM refuses the load
+	if _, err := config.planGPULayers(freeVRAM(2 * gib)); !errors.Is(err, ErrInsufficientVRAM) {
+		t.Errorf("Expected ErrInsufficientVRAM with 2 GiB free, got %v", err)
+	}
+
+	// A failed query cannot prove the device has room
+	failing := func() (uint64, error) { return 0, errors.New("no GPU") }
+	if _, err := config.planGPULayers(failing); !errors.Is(err, ErrInsufficientVRAM) {
+		t.Errorf("Expected ErrInsufficientVRAM when VRAM cannot be queried, got %v", err)
+	}
+
+	// With the CPU fallback the model loads without offloading instead
+	config.VRAMFallbackCPU = true
+	layers, err = config.planGPULayers(freeVRAM(2 * gib))
+	if err != nil || layers != 0 {
+		t.Errorf("Expected CPU fallback, got %d layers, %v", layers, err)
+	}
+
+	// CPU-only loads and a disabled guard never query the device
+	queried := false
+	query := func() (uint64, error) { queried = true; return 0, nil }
+	config.VRAMFallbackCPU = false
+	config.GPULayers = 0
+	if layers, err := config.planGPULayers(query); err != nil || layers != 0 {
+		t.Errorf("Expected CPU-only load to pass, got %d layers, %v", layers, err)
+	}
+	config.GPULayers = 20
+	config.MinFreeVRAMBytes = 0
+	if layers, err := config.planGPULayers(query); err != nil || layers != 20 {
+		t.Errorf("Expected disabled guard to keep 20 layers, got %d, %v", layers, err)
+	}
+	if queried {
+		t.Error("Expected no VRAM query when the guard does not apply")
+	}
+}
+
+// TestMinFreeVRAMFromEnv checks the guard threshold is read in MiB from the environment
+func TestMinFreeVRAMFromEnv(t *testing.T) {
+	t.Setenv("VVFS_MIN_FREE_VRAM_MB", "2048")
+	if got := DefaultGGUFConfig("model.gguf", ModelTypeChat).MinFreeVRAMBytes; got != 2<<30 {
+		t.Errorf("Expected 2 GiB threshold, got %d", got)
+	}
+
+	t.Setenv("VVFS_MIN_FREE_VRAM_MB", "lots")
+	if got := minFreeVRAMFromEnv(); got != 0 {
+		t.Errorf("Expected invalid value to disable the guard, got %d", got)
+	}
+}
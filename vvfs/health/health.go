@@ -0,0 +1,221 @@
+// Package health aggregates component health into a single readiness report
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/models"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/service"
+)
+
+// DefaultCheckTimeout bounds a single check when none is given
+const DefaultCheckTimeout = 2 * time.Second
+
+// CheckFunc probes one component; details are included in the report as-is
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+// ModelHealthSource reports per-provider model health (satisfied by models.ModelManager)
+type ModelHealthSource interface {
+	GetHealthSummary() map[string]*models.ModelHealth
+}
+
+// DatabaseHealthSource pings open project databases (satisfied by database.DBManager)
+type DatabaseHealthSource interface {
+	ActiveProjects() []string
+	Ping(ctx context.Context, projectName string) error
+}
+
+// MemoryHealthSource exposes memory subsystem metrics (satisfied by service.MemorySystem)
+type MemoryHealthSource interface {
+	GetMetrics() service.MetricsSummary
+}
+
+// ComponentHealth is the result of one check
+type ComponentHealth struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Details  any           `json:"details,omitempty"`
+}
+
+// HealthReport is the aggregate readiness view; Healthy is false if any component is unhealthy
+type HealthReport struct {
+	Healthy    bool              `json:"healthy"`
+	CheckedAt  time.Time         `json:"checked_at"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// ModelStatus is the JSON-friendly view of a models.ModelHealth
+type ModelStatus struct {
+	Healthy     bool    `json:"healthy"`
+	SuccessRate float64 `json:"success_rate"`
+	TotalCalls  int64   `json:"total_calls"`
+	LastError   string  `json:"last_error,omitempty"`
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Monitor runs registered checks concurrently, each under its own timeout
+type Monitor struct {
+	mu     sync.RWMutex
+	checks []check
+	// dynamic checks expand into several components at probe time (e.g. one per DB project)
+	expanders []func() []check
+}
+
+// NewMonitor creates an empty health monitor
+func NewMonitor() *Monitor {
+	return &Monitor{}
+}
+
+// AddCheck registers a named check; timeout <= 0 uses DefaultCheckTimeout
+func (m *Monitor) AddCheck(name string, timeout time.Duration, fn CheckFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checks = append(m.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// AddModels registers a check that fails when any model provider is unhealthy
+func (m *Monitor) AddModels(source ModelHealthSource, timeout time.Duration) {
+	m.AddCheck("models", timeout, func(ctx context.Context) (any, error) {
+		statuses := make(map[string]ModelStatus)
+		var unhealthy []string
+		for name, h := range source.GetHealthSummary() {
+			if h == nil {
+				continue
+			}
+			status := ModelStatus{Healthy: h.IsHealthy, SuccessRate: h.SuccessRate, TotalCalls: h.TotalCalls}
+			if h.LastError != nil {
+				status.LastError = h.LastError.Error()
+			}
+			statuses[name] = status
+			if !h.IsHealthy {
+				unhealthy = append(unhealthy, name)
+			}
+		}
+		if len(unhealthy) > 0 {
+			sort.Strings(unhealthy)
+			return statuses, fmt.Errorf("unhealthy models: %v", unhealthy)
+		}
+		return statuses, nil
+	})
+}
+
+// AddDatabase registers one ping check per active project, named "database:<project>"
+func (m *Monitor) AddDatabase(source DatabaseHealthSource, timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expanders = append(m.expanders, func() []check {
+		projects := source.ActiveProjects()
+		checks := make([]check, 0, len(projects))
+		for _, project := range projects {
+			checks = append(checks, check{
+				name:    "database:" + project,
+				timeout: timeout,
+				fn: func(ctx context.Context) (any, error) {
+					return nil, source.Ping(ctx, project)
+				},
+			})
+		}
+		return checks
+	})
+}
+
+// AddMemory registers a check reporting memory subsystem metrics
+func (m *Monitor) AddMemory(source MemoryHealthSource, timeout time.Duration) {
+	m.AddCheck("memory", timeout, func(ctx context.Context) (any, error) {
+		return source.GetMetrics(), nil
+	})
+}
+
+// OverallHealth runs every check concurrently and aggregates the results.
+// A check exceeding its timeout is reported unhealthy without waiting for it to return.
+func (m *Monitor) OverallHealth(ctx context.Context) (*HealthReport, error) {
+	m.mu.RLock()
+	checks := append([]check(nil), m.checks...)
+	for _, expand := range m.expanders {
+		checks = append(checks, expand()...)
+	}
+	m.mu.RUnlock()
+
+	report := &HealthReport{
+		Healthy:    true,
+		CheckedAt:  time.Now(),
+		Components: make([]ComponentHealth, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Components[i] = runCheck(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if !component.Healthy {
+			report.Healthy = false
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("health probe cancelled: %w", err)
+	}
+	return report, nil
+}
+
+// runCheck executes c under its timeout, recovering from panics
+func runCheck(ctx context.Context, c check) ComponentHealth {
+	timeout := c.timeout
+	if timeout <= 0 {
+		timeout = DefaultCheckTimeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		details any
+		err     error
+	}
+	done := make(chan outcome, 1) // buffered so a late check never blocks
+	start := time.Now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("check panicked: %v", r)}
+			}
+		}()
+		details, err := c.fn(checkCtx)
+		done <- outcome{details: details, err: err}
+	}()
+
+	result := ComponentHealth{Name: c.name, Healthy: true}
+	select {
+	case out := <-done:
+		result.Details = out.details
+		if out.err != nil {
+			result.Healthy = false
+			result.Error = out.err.Error()
+		}
+	case <-checkCtx.Done():
+		result.Healthy = false
+		if ctx.Err() != nil {
+			result.Error = fmt.Sprintf("check cancelled: %v", ctx.Err())
+		} else {
+			result.Error = fmt.Sprintf("check timed out after %s", timeout)
+		}
+	}
+	result.Duration = time.Since(start)
+	return result
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/models"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubModels map[string]*models.ModelHealth
+
+func (s stubModels) GetHealthSummary() map[string]*models.ModelHealth { return s }
+
+type stubDatabase struct {
+	failing map[string]error
+	delay   map[string]time.Duration
+}
+
+func (s *stubDatabase) ActiveProjects() []string { return []string{"alpha", "beta", "slow"} }
+
+func (s *stubDatabase) Ping(ctx context.Context, project string) error {
+	if d := s.delay[project]; d > 0 {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return s.failing[project]
+}
+
+type stubMemory struct{}
+
+func (stubMemory) GetMetrics() service.MetricsSummary {
+	return service.MetricsSummary{IngestCount: 7}
+}
+
+func componentsByName(report *HealthReport) map[string]ComponentHealth {
+	byName := make(map[string]ComponentHealth)
+	for _, c := range report.Components {
+		byName[c.Name] = c
+	}
+	return byName
+}
+
+func TestOverallHealth_AllHealthy(t *testing.T) {
+	monitor := NewMonitor()
+	monitor.AddModels(stubModels{"chat": {IsHealthy: true, SuccessRate: 1}}, 0)
+	monitor.AddDatabase(&stubDatabase{}, 0)
+	monitor.AddMemory(stubMemory{}, 0)
+
+	report, err := monitor.OverallHealth(context.Background())
+	require.NoError(t, err)
+	assert.True(t, report.Healthy)
+	assert.Len(t, report.Components, 5)
+
+	memory := componentsByName(report)["memory"]
+	assert.Equal(t, int64(7), memory.Details.(service.MetricsSummary).IngestCount)
+
+	_, err = json.Marshal(report)
+	assert.NoError(t, err)
+}
+
+func TestOverallHealth_ReflectsWorstComponent(t *testing.T) {
+	monitor := NewMonitor()
+	monitor.AddModels(stubModels{
+		"chat":  {IsHealthy: true},
+		"embed": {IsHealthy: false, LastError: errors.New("model not loaded")},
+	}, 0)
+	monitor.AddDatabase(&stubDatabase{
+		failing: map[string]error{"beta": errors.New("disk I/O error")},
+		delay:   map[string]time.Duration{"slow": time.Minute},
+	}, 50*time.Millisecond)
+	monitor.AddMemory(stubMemory{}, 0)
+
+	start := time.Now()
+	report, err := monitor.OverallHealth(context.Background())
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "a slow check must not block the probe")
+
+	assert.False(t, report.Healthy)
+	byName := componentsByName(report)
+	assert.False(t, byName["models"].Healthy)
+	assert.Contains(t, byName["models"].Error, "embed")
+	assert.Equal(t, "model not loaded", byName["models"].Details.(map[string]ModelStatus)["embed"].LastError)
+	assert.True(t, byName["database:alpha"].Healthy)
+	assert.False(t, byName["database:beta"].Healthy)
+	assert.Contains(t, byName["database:beta"].Error, "disk I/O error")
+	assert.False(t, byName["database:slow"].Healthy)
+	assert.True(t, byName["memory"].Healthy)
+}
+
+func TestOverallHealth_CheckTimeout(t *testing.T) {
+	monitor := NewMonitor()
+	block := make(chan struct{})
+	defer close(block)
+	monitor.AddCheck("stuck", 20*time.Millisecond, func(ctx context.Context) (any, error) {
+		<-block // ignores ctx, like a wedged dependency
+		return nil, nil
+	})
+	monitor.AddCheck("ok", 0, func(ctx context.Context) (any, error) { return "fine", nil })
+
+	report, err := monitor.OverallHealth(context.Background())
+	require.NoError(t, err)
+	assert.False(t, report.Healthy)
+	byName := componentsByName(report)
+	assert.Contains(t, byName["stuck"].Error, "timed out")
+	assert.True(t, byName["ok"].Healthy)
+}
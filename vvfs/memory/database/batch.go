@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// BatchStmt is a single statement executed by ExecBatch
+type BatchStmt struct {
+	Query string
+	Args  []any
+}
+
+// ExecBatch executes stmts in order within one transaction, rolling back all of them if any fails.
+// Each distinct query is prepared once and reused for every statement that repeats it.
+func (dm *DBManager) ExecBatch(ctx context.Context, projectName string, stmts []BatchStmt) error {
+	if len(stmts) == 0 {
+		return nil
+	}
+
+	db, err := dm.getDB(projectName)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	prepared := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range prepared {
+			_ = stmt.Close()
+		}
+	}()
+
+	for i, batchStmt := range stmts {
+		stmt, ok := prepared[batchStmt.Query]
+		if !ok {
+			stmt, err = tx.PrepareContext(ctx, batchStmt.Query)
+			if err != nil {
+				return rollbackBatch(tx, fmt.Errorf("failed to prepare batch statement %d: %w", i, err))
+			}
+			prepared[batchStmt.Query] = stmt
+		}
+
+		if _, err := stmt.ExecContext(ctx, batchStmt.Args...); err != nil {
+			return rollbackBatch(tx, fmt.Errorf("failed to execute batch statement %d: %w", i, err))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	return nil
+}
+
+// rollbackBatch rolls back tx and returns err, noting a failed rollback
+func rollbackBatch(tx *sql.Tx, err error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("batch failed and rollback failed: %v (original error: %w)", rollbackErr, err)
+	}
+	return err
+}
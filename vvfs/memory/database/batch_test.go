@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const batchInsertQuery = `INSERT INTO batch_items (id, name) VALUES (?, ?)`
+
+// newBatchTestManager opens a fresh database with an empty batch_items table
+func newBatchTestManager(tb testing.TB) *DBManager {
+	tb.Helper()
+	dm, err := NewDBManager(&Config{
+		URL:           "file:" + filepath.Join(tb.TempDir(), "libsql.db"),
+		EmbeddingDims: 4,
+	})
+	require.NoError(tb, err)
+	tb.Cleanup(func() { dm.Close() })
+
+	db, err := dm.getDB(defaultProject)
+	require.NoError(tb, err)
+	_, err = db.ExecContext(context.Background(), `CREATE TABLE batch_items (id INTEGER PRIMARY KEY, name TEXT NOT NULL)`)
+	require.NoError(tb, err)
+	return dm
+}
+
+func countBatchItems(t *testing.T, dm *DBManager) int {
+	db, err := dm.getDB(defaultProject)
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRowContext(context.Background(), `SELECT COUNT(*) FROM batch_items`).Scan(&count))
+	return count
+}
+
+func batchInserts(start, n int) []BatchStmt {
+	stmts := make([]BatchStmt, n)
+	for i := range stmts {
+		stmts[i] = BatchStmt{Query: batchInsertQuery, Args: []any{start + i, fmt.Sprintf("item-%d", start+i)}}
+	}
+	return stmts
+}
+
+func TestExecBatch(t *testing.T) {
+	ctx := context.Background()
+	dm := newBatchTestManager(t)
+
+	require.NoError(t, dm.ExecBatch(ctx, defaultProject, batchInserts(0, 1000)))
+	assert.Equal(t, 1000, countBatchItems(t, dm))
+
+	// Mixed queries run in order within the same transaction
+	require.NoError(t, dm.ExecBatch(ctx, defaultProject, []BatchStmt{
+		{Query: `UPDATE batch_items SET name = ? WHERE id = ?`, Args: []any{"renamed", 0}},
+		{Query: `DELETE FROM batch_items WHERE id = ?`, Args: []any{1}},
+	}))
+	assert.Equal(t, 999, countBatchItems(t, dm))
+
+	assert.NoError(t, dm.ExecBatch(ctx, defaultProject, nil))
+}
+
+func TestExecBatch_RollsBackOnFailure(t *testing.T) {
+	ctx := context.Background()
+	dm := newBatchTestManager(t)
+	require.NoError(t, dm.ExecBatch(ctx, defaultProject, batchInserts(0, 10)))
+
+	// A duplicate key halfway through aborts the whole batch
+	stmts := batchInserts(100, 500)
+	stmts = append(stmts, BatchStmt{Query: batchInsertQuery, Args: []any{5, "duplicate"}})
+	stmts = append(stmts, batchInserts(600, 500)...)
+
+	err := dm.ExecBatch(ctx, defaultProject, stmts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "batch statement 500")
+	assert.Equal(t, 10, countBatchItems(t, dm))
+
+	// An invalid query fails at prepare time and also rolls back
+	err = dm.ExecBatch(ctx, defaultProject, append(batchInserts(200, 5), BatchStmt{Query: `INSERT INTO missing_table VALUES (?)`, Args: []any{1}}))
+	require.Error(t, err)
+	assert.Equal(t, 10, countBatchItems(t, dm))
+}
+
+func BenchmarkExecBatch(b *testing.B) {
+	const rows = 500
+	ctx := context.Background()
+
+	b.Run("batch", func(b *testing.B) {
+		dm := newBatchTestManager(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := dm.ExecBatch(ctx, defaultProject, batchInserts(i*rows, rows)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("individual", func(b *testing.B) {
+		dm := newBatchTestManager(b)
+		db, err := dm.getDB(defaultProject)
+		require.NoError(b, err)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, stmt := range batchInserts(i*rows, rows) {
+				if _, err := db.ExecContext(ctx, stmt.Query, stmt.Args...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
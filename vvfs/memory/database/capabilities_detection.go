@@ -78,8 +78,6 @@ func (dm *DBManager) detectCapabilitiesForProject(ctx context.Context, projectNa
 	if _, err := db.ExecContext(ctx3, "CREATE VIRTUAL TABLE IF NOT EXISTS temp._fts5_probe USING fts5(content)"); err == nil {
 		// If we can create the table, FTS5 is available
 		caps.fts5 = true
-		// Ensure FTS schema is created when FTS5 is detected
-		_ = dm.ensureFTSSchema(context.Background(), db)
 		// Clean up
 		_, _ = db.ExecContext(ctx3, "DROP TABLE IF EXISTS temp._fts5_probe")
 	} else {
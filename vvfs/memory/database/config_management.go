@@ -3,6 +3,9 @@ package database
 import (
 	"os"
 	"strconv"
+	"time"
+
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
 
 // Config holds the database configuration
@@ -16,22 +19,37 @@ type Config struct {
 	MaxIdleConns     int
 	ConnMaxIdleSec   int
 	ConnMaxLifeSec   int
+	// QueryTimeout bounds each transaction and query run through the manager (0 uses DefaultQueryTimeout, negative disables)
+	QueryTimeout time.Duration
 	// PRAGMA settings
 	EnableWAL   bool
 	SyncMode    string // NORMAL, FULL, OFF
 	CacheSize   int    // pages, negative for KB
 	TempStore   string // MEMORY, FILE, DEFAULT
 	JournalMode string // WAL, DELETE, TRUNCATE, PERSIST, MEMORY, OFF
+	// MmapSize is the memory-mapped I/O limit in bytes (0 uses the default, negative disables)
+	MmapSize           int64
+	WALAutocheckpoint  int  // pages between automatic WAL checkpoints
+	BusyTimeoutMs      int  // lock wait in milliseconds
+	DisableForeignKeys bool // foreign key enforcement is on unless disabled
+	// Pragmas holds extra PRAGMA overrides applied to every project; names must be allowlisted
+	Pragmas map[string]string
+	// ProjectPragmas holds per-project PRAGMA overrides keyed by project name
+	ProjectPragmas map[string]map[string]string
 }
 
-// NewConfig creates a new Config from environment variables
-func NewConfig() *Config {
+// NewConfig creates a new Config from the given database settings, with environment
+// variables overriding them
+func NewConfig(dbConfig appconfig.DatabaseConfig) *Config {
 	url := os.Getenv("LIBSQL_URL")
 	if url == "" {
 		url = "file:./libsql.db"
 	}
 
-	authToken := os.Getenv("LIBSQL_AUTH_TOKEN")
+	authToken := dbConfig.AuthToken
+	if v := os.Getenv("LIBSQL_AUTH_TOKEN"); v != "" {
+		authToken = v
+	}
 	dims := 4
 	if v := os.Getenv("EMBEDDING_DIMS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
@@ -64,30 +82,49 @@ func NewConfig() *Config {
 		}
 	}
 
-	// PRAGMA settings
+	queryTimeout := dbConfig.QueryTimeout
+	if v := os.Getenv("DB_QUERY_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			queryTimeout = d
+		}
+	}
+
+	// PRAGMA settings come from dbConfig; the DB_* variables override them
 	enableWAL := false
 	if v := os.Getenv("DB_ENABLE_WAL"); v != "" {
 		enableWAL = v == "true" || v == "1"
 	}
 
-	syncMode := "NORMAL"
+	syncMode := dbConfig.SyncMode
+	if syncMode == "" {
+		syncMode = "NORMAL"
+	}
 	if v := os.Getenv("DB_SYNC_MODE"); v != "" {
 		syncMode = v
 	}
 
-	cacheSize := -64000 // 64MB default
+	cacheSize := dbConfig.CacheSize
+	if cacheSize == 0 {
+		cacheSize = -64000 // 64MB default
+	}
 	if v := os.Getenv("DB_CACHE_SIZE"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
 			cacheSize = n
 		}
 	}
 
-	tempStore := "MEMORY"
+	tempStore := dbConfig.TempStore
+	if tempStore == "" {
+		tempStore = "MEMORY"
+	}
 	if v := os.Getenv("DB_TEMP_STORE"); v != "" {
 		tempStore = v
 	}
 
-	journalMode := "WAL"
+	journalMode := dbConfig.JournalMode
+	if journalMode == "" {
+		journalMode = "WAL"
+	}
 	if v := os.Getenv("DB_JOURNAL_MODE"); v != "" {
 		journalMode = v
 	}
@@ -100,11 +137,18 @@ func NewConfig() *Config {
 		MaxIdleConns:   maxIdle,
 		ConnMaxIdleSec: idleSec,
 		ConnMaxLifeSec: lifeSec,
+		QueryTimeout:   queryTimeout,
 		// PRAGMA settings
-		EnableWAL:   enableWAL,
-		SyncMode:    syncMode,
-		CacheSize:   cacheSize,
-		TempStore:   tempStore,
-		JournalMode: journalMode,
+		EnableWAL:          enableWAL,
+		SyncMode:           syncMode,
+		CacheSize:          cacheSize,
+		TempStore:          tempStore,
+		JournalMode:        journalMode,
+		MmapSize:           dbConfig.MmapSize,
+		WALAutocheckpoint:  dbConfig.WALAutocheckpoint,
+		BusyTimeoutMs:      dbConfig.BusyTimeoutMs,
+		DisableForeignKeys: dbConfig.DisableForeignKeys,
+		Pragmas:            dbConfig.Pragmas,
+		ProjectPragmas:     dbConfig.ProjectPragmas,
 	}
 }
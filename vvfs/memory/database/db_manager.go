@@ -4,16 +4,16 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 	"github.com/pressly/goose/v3"
 	_ "github.com/tursodatabase/go-libsql"
 )
@@ -28,6 +28,8 @@ type DBManager struct {
 	capsByProject map[string]capFlags
 	capMu         sync.RWMutex        // mutex for capabilities
 	queries       map[string]*Queries // sqlc generated queriers
+	projectLocks  map[string]*sync.Mutex
+	projectLockMu sync.Mutex // guards projectLocks
 }
 
 // NewDBManager creates a new database manager with sqlc integration
@@ -41,6 +43,7 @@ func NewDBManager(config *Config) (*DBManager, error) {
 		dbs:           make(map[string]*sql.DB),
 		capsByProject: make(map[string]capFlags),
 		queries:       make(map[string]*Queries),
+		projectLocks:  make(map[string]*sync.Mutex),
 	}
 
 	// initialize default DB in single-project mode
@@ -53,17 +56,28 @@ func NewDBManager(config *Config) (*DBManager, error) {
 	return manager, nil
 }
 
-// Close closes all database connections.
+// Close closes all prepared queriers and database connections.
+// Closed handles are removed, so calling Close again is a no-op.
 func (dm *DBManager) Close() error {
-	// close dbs
 	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	// close prepared statements before the connections they were prepared on
+	var errs []error
+	for name, querier := range dm.queries {
+		if err := querier.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close querier for project %s: %w", name, err))
+		}
+		delete(dm.queries, name)
+	}
+
+	// close dbs
 	for name, db := range dm.dbs {
 		_ = db.Close()
 		delete(dm.dbs, name)
 	}
-	dm.mu.Unlock()
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // getDB retrieves or creates a DB connection for a project
@@ -75,71 +89,77 @@ func (dm *DBManager) getDB(projectName string) (*sql.DB, error) {
 		return db, nil
 	}
 
-	dm.mu.Lock()
-	defer dm.mu.Unlock()
-	if db, ok = dm.dbs[projectName]; ok {
+	// Open and set up each project once: concurrent callers for the same project wait
+	// here, while other projects are set up in parallel
+	lock := dm.projectLock(projectName)
+	lock.Lock()
+	defer lock.Unlock()
+	dm.mu.RLock()
+	db, ok = dm.dbs[projectName]
+	dm.mu.RUnlock()
+	if ok {
 		return db, nil
 	}
 
-	var dbURL string
+	dsnConfig := appconfig.DatabaseConfig{
+		DSN:           dm.config.URL,
+		AuthToken:     dm.config.AuthToken,
+		LibSQLDataDir: dm.config.ProjectsDir,
+	}
+	dsnProject := ""
 	if dm.config.MultiProjectMode {
 		if projectName == "" {
 			return nil, fmt.Errorf("project name cannot be empty in multi-project mode")
 		}
-		dbPath := filepath.Join(dm.config.ProjectsDir, projectName, "libsql.db")
+		dbPath, err := dsnConfig.ProjectDBPath(projectName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve database path for project %s: %w", projectName, err)
+		}
 		if err := os.MkdirAll(filepath.Dir(dbPath), 0o755); err != nil {
 			return nil, fmt.Errorf("failed to create project directory for %s: %w", projectName, err)
 		}
-		dbURL = fmt.Sprintf("file:%s", dbPath)
-	} else {
-		dbURL = dm.config.URL
-	}
-
-	var newDb *sql.DB
-	var err error
-	if strings.HasPrefix(dbURL, "file:") {
-		newDb, err = sql.Open("libsql", dbURL)
-	} else {
-		authURL := dbURL
-		if dm.config.AuthToken != "" {
-			if u, perr := url.Parse(dbURL); perr == nil {
-				q := u.Query()
-				q.Set("authToken", dm.config.AuthToken)
-				u.RawQuery = q.Encode()
-				authURL = u.String()
-			} else {
-				if strings.Contains(dbURL, "?") {
-					authURL = dbURL + "&authToken=" + url.QueryEscape(dm.config.AuthToken)
-				} else {
-					authURL = dbURL + "?authToken=" + url.QueryEscape(dm.config.AuthToken)
-				}
-			}
-		}
-		newDb, err = sql.Open("libsql", authURL)
+		dsnProject = projectName
+	}
+
+	dbURL, err := dsnConfig.BuildDSN(dsnProject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build DSN for project %s: %w", projectName, err)
 	}
+
+	newDb, err := sql.Open("libsql", dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database connector for project %s: %w", projectName, err)
 	}
 
-	if err := dm.initialize(newDb); err != nil {
+	// Size the pool before migrating so a private in-memory database keeps its schema
+	// on the one connection every later query uses
+	dm.configureConnectionPooling(newDb, dbURL, projectName)
+
+	if err := dm.initialize(newDb, projectName); err != nil {
 		newDb.Close()
 		return nil, fmt.Errorf("failed to initialize database for project %s: %w", projectName, err)
 	}
 
-	// Configure connection pooling for optimal performance
-	dm.configureConnectionPooling(newDb)
-
 	// reconcile embedding dims with DB if needed
-	if dbDims := detectDBEmbeddingDims(newDb); dbDims > 0 && dbDims != dm.config.EmbeddingDims {
-		log.Printf("Embedding dims mismatch: DB=%d, Config=%d. Adopting DB dims.", dbDims, dm.config.EmbeddingDims)
-		dm.config.EmbeddingDims = dbDims
+	if dbDims := detectDBEmbeddingDims(newDb); dbDims > 0 {
+		dm.mu.Lock()
+		if dbDims != dm.config.EmbeddingDims {
+			log.Printf("Embedding dims mismatch: DB=%d, Config=%d. Adopting DB dims.", dbDims, dm.config.EmbeddingDims)
+			dm.config.EmbeddingDims = dbDims
+		}
+		dm.mu.Unlock()
 	}
 
-	dm.dbs[projectName] = newDb
-
 	// detect caps
 	dm.detectCapabilitiesForProject(context.Background(), projectName, newDb)
 
+	// Full-text search schema; set up once the capabilities are known so a missing FTS5
+	// module is told apart from a real failure, and under the caller's project lock
+	if err := dm.ensureFTSSchema(context.Background(), newDb, projectName); err != nil {
+		newDb.Close()
+		return nil, fmt.Errorf("failed to set up FTS schema for project %s: %w", projectName, err)
+	}
+
 	// prepare sqlc querier with prepared statements
 	ctx := context.Background()
 	querier, err := Prepare(ctx, newDb)
@@ -147,12 +167,29 @@ func (dm *DBManager) getDB(projectName string) (*sql.DB, error) {
 		newDb.Close()
 		return nil, fmt.Errorf("failed to prepare sqlc querier: %w", err)
 	}
+
+	dm.mu.Lock()
+	dm.dbs[projectName] = newDb
 	dm.queries[projectName] = querier
+	dm.mu.Unlock()
 
 	_ = newDb.Stats() // touch stats (future metrics)
 	return newDb, nil
 }
 
+// projectLock returns the lock serializing setup of projectName's database
+func (dm *DBManager) projectLock(projectName string) *sync.Mutex {
+	dm.projectLockMu.Lock()
+	defer dm.projectLockMu.Unlock()
+
+	lock, ok := dm.projectLocks[projectName]
+	if !ok {
+		lock = &sync.Mutex{}
+		dm.projectLocks[projectName] = lock
+	}
+	return lock
+}
+
 // detectDBEmbeddingDims introspects F32_BLOB size for entities.embedding
 func detectDBEmbeddingDims(db *sql.DB) int {
 	var sqlText string
@@ -179,15 +216,15 @@ func detectDBEmbeddingDims(db *sql.DB) int {
 	return 0
 }
 
-// initialize creates schema using goose and prepares sqlc querier
-func (dm *DBManager) initialize(db *sql.DB) error {
+// initialize creates schema using goose and applies PRAGMAs
+func (dm *DBManager) initialize(db *sql.DB, projectName string) error {
 	// Run goose migrations to ensure schema is up to date
 	if err := dm.runGooseMigrations(db); err != nil {
 		return fmt.Errorf("failed to run goose migrations: %w", err)
 	}
 
 	// Configure PRAGMA settings for optimal performance
-	if err := dm.configurePragmaSettings(db); err != nil {
+	if err := dm.configurePragmaSettings(db, projectName); err != nil {
 		return fmt.Errorf("failed to configure PRAGMA settings: %w", err)
 	}
 
@@ -196,24 +233,9 @@ func (dm *DBManager) initialize(db *sql.DB) error {
 
 // runGooseMigrations runs goose migrations on the provided database
 func (dm *DBManager) runGooseMigrations(db *sql.DB) error {
-	// Use absolute path to migrations directory
-	// Get the current working directory and construct the path
-	wd, err := os.Getwd()
+	migrationsPath, err := resolveMigrationsDir()
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
-	}
-
-	// Check if we're in a subdirectory and need to go up to project root
-	migrationsPath := filepath.Join(wd, "vvfs", "memory", "migrations")
-
-	// If the path doesn't exist, try going up levels (for test execution from subdirs)
-	if _, err := os.Stat(migrationsPath); os.IsNotExist(err) {
-		// Try going up one level
-		migrationsPath = filepath.Join(wd, "..", "vvfs", "memory", "migrations")
-		if _, err := os.Stat(migrationsPath); os.IsNotExist(err) {
-			// Try going up two levels
-			migrationsPath = filepath.Join(wd, "..", "..", "vvfs", "memory", "migrations")
-		}
+		return err
 	}
 
 	// Set goose dialect to SQLite (required for proper migration execution)
@@ -228,98 +250,38 @@ func (dm *DBManager) runGooseMigrations(db *sql.DB) error {
 	return nil
 }
 
-// configurePragmaSettings applies PRAGMA settings to the database
-func (dm *DBManager) configurePragmaSettings(db *sql.DB) error {
-	// Journal mode (WAL, DELETE, etc.)
-	if dm.config.JournalMode != "" {
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA journal_mode = %s", dm.config.JournalMode)); err != nil {
-			return fmt.Errorf("failed to set journal_mode: %w", err)
-		}
-	}
-
-	// Synchronous mode (NORMAL, FULL, OFF)
-	if dm.config.SyncMode != "" {
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA synchronous = %s", dm.config.SyncMode)); err != nil {
-			return fmt.Errorf("failed to set synchronous: %w", err)
-		}
-	}
-
-	// Cache size (negative values in KB, positive in pages)
-	if dm.config.CacheSize != 0 {
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA cache_size = %d", dm.config.CacheSize)); err != nil {
-			return fmt.Errorf("failed to set cache_size: %w", err)
-		}
-	}
-
-	// Temporary storage location
-	if dm.config.TempStore != "" {
-		if _, err := db.Exec(fmt.Sprintf("PRAGMA temp_store = %s", dm.config.TempStore)); err != nil {
-			return fmt.Errorf("failed to set temp_store: %w", err)
-		}
-	}
-
-	// Additional performance PRAGMAs
-	pragmaSettings := []struct {
-		name  string
-		value string
-	}{
-		{"mmap_size", "268435456"},     // 256MB memory map
-		{"wal_autocheckpoint", "1000"}, // Checkpoint every 1000 pages
-		{"busy_timeout", "5000"},       // 5 second timeout
-		{"foreign_keys", "ON"},         // Enable foreign key constraints
+// configurePragmaSettings applies configured PRAGMA settings to a project database.
+// Every name and value is validated against the allowlist before anything is executed.
+func (dm *DBManager) configurePragmaSettings(db *sql.DB, projectName string) error {
+	settings, err := dm.pragmaSettings(projectName)
+	if err != nil {
+		return err
 	}
 
-	for _, setting := range pragmaSettings {
+	for _, setting := range settings {
 		// Some PRAGMA statements return values, so we need to handle them differently
 		query := fmt.Sprintf("PRAGMA %s = %s", setting.name, setting.value)
 		if _, err := db.Exec(query); err != nil {
 			// If Exec fails due to returning rows, try Query instead
-			if strings.Contains(err.Error(), "returned rows") {
-				if _, err := db.Query(query); err != nil {
-					return fmt.Errorf("failed to set %s: %w", setting.name, err)
-				}
-			} else {
+			if !strings.Contains(err.Error(), "returned rows") {
+				return fmt.Errorf("failed to set %s: %w", setting.name, err)
+			}
+			rows, err := db.Query(query)
+			if err != nil {
 				return fmt.Errorf("failed to set %s: %w", setting.name, err)
 			}
+			rows.Close()
 		}
 	}
 
 	return nil
 }
 
-// configureConnectionPooling sets up optimal connection pooling parameters
-func (dm *DBManager) configureConnectionPooling(db *sql.DB) {
-	// Set max open connections (default: 25 for SQLite)
-	maxOpen := dm.config.MaxOpenConns
-	if maxOpen <= 0 {
-		maxOpen = 25 // SQLite default
-	}
-	db.SetMaxOpenConns(maxOpen)
-
-	// Set max idle connections (default: 25 for SQLite)
-	maxIdle := dm.config.MaxIdleConns
-	if maxIdle <= 0 {
-		maxIdle = 25 // SQLite default
-	}
-	db.SetMaxIdleConns(maxIdle)
-
-	// Set connection max idle time (default: 5 minutes)
-	idleTime := time.Duration(dm.config.ConnMaxIdleSec) * time.Second
-	if idleTime <= 0 {
-		idleTime = 5 * time.Minute
-	}
-	db.SetConnMaxIdleTime(idleTime)
-
-	// Set connection max lifetime (default: 1 hour)
-	lifeTime := time.Duration(dm.config.ConnMaxLifeSec) * time.Second
-	if lifeTime <= 0 {
-		lifeTime = time.Hour
-	}
-	db.SetConnMaxLifetime(lifeTime)
-
-	// Log connection pool configuration
-	log.Printf("Connection pool configured: max_open=%d, max_idle=%d, max_idle_time=%v, max_lifetime=%v",
-		maxOpen, maxIdle, idleTime, lifeTime)
+// configureConnectionPooling sizes the pool for the kind of database dsn names
+func (dm *DBManager) configureConnectionPooling(db *sql.DB, dsn, projectName string) {
+	plan := dm.planConnectionPool(dsn, projectName)
+	plan.apply(db)
+	logPoolPlan(projectName, plan)
 }
 
 // GetQuerier returns the sqlc querier for a project
@@ -340,21 +302,22 @@ func (dm *DBManager) GetQuerier(projectName string) (*Queries, error) {
 	return querier, nil
 }
 
+// Conn returns the connection the queries run on; inside WithTx it is the transaction, so
+// hand-written SQL issued through it commits or rolls back with the sqlc queries
+func (q *Queries) Conn() DBTX {
+	return q.db
+}
+
 // WithTx executes a function within a database transaction
 func (dm *DBManager) WithTx(ctx context.Context, projectName string, fn func(*Queries) error) error {
-	// Get the base querier
-	querier, err := dm.GetQuerier(projectName)
+	db, err := dm.getDB(projectName)
 	if err != nil {
-		return fmt.Errorf("failed to get querier: %w", err)
+		return fmt.Errorf("failed to get database: %w", err)
 	}
 
-	// Get the underlying database connection
-	dm.mu.RLock()
-	db, ok := dm.dbs[projectName]
-	dm.mu.RUnlock()
-	if !ok {
-		return fmt.Errorf("database connection not found for project: %s", projectName)
-	}
+	// Bound the whole transaction, including every statement fn runs
+	ctx, cancel := dm.WithQueryTimeout(ctx)
+	defer cancel()
 
 	// Begin transaction
 	tx, err := db.BeginTx(ctx, nil)
@@ -362,13 +325,14 @@ func (dm *DBManager) WithTx(ctx context.Context, projectName string, fn func(*Qu
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	// Create querier with transaction
-	txQuerier := querier.WithTx(tx)
+	// Create a querier whose statements share the transaction's deadline. The prepared
+	// statements are bypassed since they run under the caller's context
+	txQuerier := New(boundedDBTX{db: tx, bound: ctx})
 
 	// Execute the function
 	if err := fn(txQuerier); err != nil {
 		// Rollback on error
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
 			return fmt.Errorf("transaction failed and rollback failed: %v (original error: %w)", rollbackErr, err)
 		}
 		return err
@@ -384,33 +348,33 @@ func (dm *DBManager) WithTx(ctx context.Context, projectName string, fn func(*Qu
 
 // WithTxReadOnly executes a read-only function within a transaction
 func (dm *DBManager) WithTxReadOnly(ctx context.Context, projectName string, fn func(*Queries) error) error {
-	// Get the base querier
-	querier, err := dm.GetQuerier(projectName)
+	db, err := dm.getDB(projectName)
 	if err != nil {
-		return fmt.Errorf("failed to get querier: %w", err)
+		return fmt.Errorf("failed to get database: %w", err)
 	}
 
-	// Get the underlying database connection
-	dm.mu.RLock()
-	db, ok := dm.dbs[projectName]
-	dm.mu.RUnlock()
-	if !ok {
-		return fmt.Errorf("database connection not found for project: %s", projectName)
-	}
+	// Bound the whole transaction, including every statement fn runs
+	ctx, cancel := dm.WithQueryTimeout(ctx)
+	defer cancel()
 
-	// Begin read-only transaction
+	// Begin read-only transaction. go-libsql rejects the ReadOnly option, so fall back to a
+	// plain transaction there; fn is still expected to only read
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil && ctx.Err() == nil {
+		tx, err = db.BeginTx(ctx, nil)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to begin read-only transaction: %w", err)
 	}
 
-	// Create querier with transaction
-	txQuerier := querier.WithTx(tx)
+	// Create a querier whose statements share the transaction's deadline. The prepared
+	// statements are bypassed since they run under the caller's context
+	txQuerier := New(boundedDBTX{db: tx, bound: ctx})
 
 	// Execute the function
 	if err := fn(txQuerier); err != nil {
 		// Rollback on error
-		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
 			return fmt.Errorf("read-only transaction failed and rollback failed: %v (original error: %w)", rollbackErr, err)
 		}
 		return err
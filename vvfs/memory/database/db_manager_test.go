@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBManager_CloseReleasesPreparedQueries(t *testing.T) {
+	ctx := context.Background()
+	dm, err := NewDBManager(&Config{
+		URL:           "file:" + filepath.Join(t.TempDir(), "libsql.db"),
+		EmbeddingDims: 4,
+	})
+	require.NoError(t, err)
+
+	querier, err := dm.GetQuerier(defaultProject)
+	require.NoError(t, err)
+	db := dm.dbs[defaultProject]
+	_, err = querier.CountGraphEntities(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, dm.Close())
+	assert.Empty(t, dm.queries)
+	assert.Empty(t, dm.dbs)
+	assert.Equal(t, 0, db.Stats().OpenConnections)
+
+	// The prepared statements were closed with the querier
+	_, err = querier.CountGraphEntities(ctx)
+	assert.ErrorContains(t, err, "statement is closed")
+
+	// Closing twice is harmless
+	assert.NotPanics(t, func() { assert.NoError(t, dm.Close()) })
+}
+
+func TestDBManager_ConcurrentOpenSetsUpFTSOnce(t *testing.T) {
+	ctx := context.Background()
+	dm, err := NewDBManager(&Config{
+		ProjectsDir:      t.TempDir(),
+		MultiProjectMode: true,
+		EmbeddingDims:    4,
+	})
+	require.NoError(t, err)
+	defer dm.Close()
+
+	const openers = 8
+	dbs := make([]*sql.DB, openers)
+	errs := make([]error, openers)
+	var wg sync.WaitGroup
+	for i := range openers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dbs[i], errs[i] = dm.getDB("notes")
+		}()
+	}
+	wg.Wait()
+
+	for i := range openers {
+		require.NoError(t, errs[i])
+		assert.Same(t, dbs[0], dbs[i], "every caller must share one set-up connection")
+	}
+
+	countSchema := func(kind, pattern string) int {
+		var n int
+		require.NoError(t, dbs[0].QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM sqlite_master WHERE type = ? AND name GLOB ?", kind, pattern).Scan(&n))
+		return n
+	}
+	assert.Equal(t, 1, countSchema("table", "fts_observations"))
+	assert.Equal(t, 3, countSchema("trigger", "trg_obs_a[diu]"))
+
+	// Replaying the setup leaves the schema as it was
+	require.NoError(t, dm.ensureFTSSchema(ctx, dbs[0], "notes"))
+	assert.Equal(t, 1, countSchema("table", "fts_observations"))
+	assert.Equal(t, 3, countSchema("trigger", "trg_obs_a[diu]"))
+}
+
+func TestDBManager_OpensEscapedProjectPath(t *testing.T) {
+	ctx := context.Background()
+	projectsDir := filepath.Join(t.TempDir(), "vvfs data")
+	dm, err := NewDBManager(&Config{
+		ProjectsDir:      projectsDir,
+		MultiProjectMode: true,
+		EmbeddingDims:    4,
+	})
+	require.NoError(t, err)
+	defer dm.Close()
+
+	// The DSN escapes the spaces and '#'; the file must still land at the literal path
+	querier, err := dm.GetQuerier("notes #1")
+	require.NoError(t, err)
+	_, err = querier.CountGraphEntities(ctx)
+	require.NoError(t, err)
+
+	_, err = os.Stat(filepath.Join(projectsDir, "notes #1", "libsql.db"))
+	require.NoError(t, err)
+	entries, err := os.ReadDir(projectsDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "notes #1", entries[0].Name())
+}
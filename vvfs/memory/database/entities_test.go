@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -13,7 +14,7 @@ import (
 	_ "github.com/tursodatabase/go-libsql"
 )
 
-func TestEntitiesCRUD(t *testing.T) {
+func TestGraphEntitiesCRUD(t *testing.T) {
 	// Create a test database
 	db := createTestDB(t)
 	defer db.Close()
@@ -24,89 +25,47 @@ func TestEntitiesCRUD(t *testing.T) {
 	require.NoError(t, err)
 	defer queries.Close()
 
-	// Prepare an embedding matching F32_BLOB(728) => 728 float32 => 728*4 bytes
-	embed := make([]byte, 728*4)
-
 	// Test Create Entity
-	entityName := "test-entity"
-	entityType := "person"
-	metadata := `{"tags": ["test"], "created_at": "2024-01-01"}`
-	createdAt := time.Now().Unix()
-	updatedAt := time.Now().Unix()
-
-	entity, err := queries.CreateEntity(ctx, CreateEntityParams{
-		Name:       entityName,
-		EntityType: entityType,
-		Embedding:  embed,
-		Metadata:   metadata,
-		CreatedAt:  createdAt,
-		UpdatedAt:  updatedAt,
+	entity, err := queries.CreateGraphEntity(ctx, CreateGraphEntityParams{
+		ID:        "entity-1",
+		Kind:      "person",
+		Name:      "test-entity",
+		Summary:   "A test entity",
+		AttrsJson: `{"tags": ["test"]}`,
 	})
 	require.NoError(t, err)
-	assert.Equal(t, entityName, entity.Name)
-	t.Logf("Created entity: %s, Type: %s", entity.Name, entity.EntityType)
+	assert.Equal(t, "test-entity", entity.Name)
 
 	// Test Get Entity
-	entity, err = queries.GetEntity(ctx, entityName)
+	entity, err = queries.GetGraphEntity(ctx, "entity-1")
 	require.NoError(t, err)
-	assert.Equal(t, entityName, entity.Name)
-	assert.Equal(t, entityType, entity.EntityType)
-	assert.Equal(t, metadata, entity.Metadata)
-	t.Logf("Retrieved entity: %s, Type: %s", entity.Name, entity.EntityType)
+	assert.Equal(t, "person", entity.Kind)
+	assert.Equal(t, `{"tags": ["test"]}`, entity.AttrsJson)
 
-	// Test Get Entities By Type
-	entities, err := queries.GetEntitiesByType(ctx, entityType)
-	require.NoError(t, err)
-	assert.Len(t, entities, 1)
-	assert.Equal(t, entityName, entities[0].Name)
-	t.Logf("GetEntitiesByType found %d entities for type '%s'", len(entities), entityType)
-
-	// Test Search Entities by type
-	searchResults, err := queries.SearchEntities(ctx, SearchEntitiesParams{
-		Column1: sql.NullString{String: "person", Valid: true}, // entity_type filter (person)
-		Column2: sql.NullString{String: "", Valid: false},      // name pattern (empty)
-		Limit:   10,                                            // limit
-		Offset:  0,
-	})
-	require.NoError(t, err)
-	t.Logf("Search by type 'person' returned %d results", len(searchResults))
-	assert.Len(t, searchResults, 1)
-
-	// Test Search Entities by name
-	nameSearchResults, err := queries.SearchEntities(ctx, SearchEntitiesParams{
-		Column1: sql.NullString{String: "", Valid: false},        // entity_type filter (empty)
-		Column2: sql.NullString{String: entityName, Valid: true}, // name pattern (test-entity)
-		Limit:   10,                                              // limit
-		Offset:  0,
-	})
+	// Test Get Entities By Kind
+	entities, err := queries.GetGraphEntitiesByKind(ctx, GetGraphEntitiesByKindParams{Kind: "person", Limit: 10})
 	require.NoError(t, err)
-	assert.Len(t, nameSearchResults, 1)
+	require.Len(t, entities, 1)
+	assert.Equal(t, "entity-1", entities[0].ID)
 
 	// Test Update Entity
-	newMetadata := `{"tags": ["test", "updated"], "created_at": "2024-01-01"}`
-	updatedEntity, err := queries.UpdateEntity(ctx, UpdateEntityParams{
-		Name:      entityName,
-		Metadata:  newMetadata,
-		UpdatedAt: time.Now().Unix(),
+	updated, err := queries.UpdateGraphEntity(ctx, UpdateGraphEntityParams{
+		ID:        "entity-1",
+		Kind:      "person",
+		Name:      "test-entity",
+		Summary:   "An updated test entity",
+		AttrsJson: `{"tags": ["test", "updated"]}`,
 	})
 	require.NoError(t, err)
-	assert.Equal(t, newMetadata, updatedEntity.Metadata)
-
-	// Verify update
-	updatedEntity, err = queries.GetEntity(ctx, entityName)
-	require.NoError(t, err)
-	assert.Equal(t, newMetadata, updatedEntity.Metadata)
+	assert.Equal(t, `{"tags": ["test", "updated"]}`, updated.AttrsJson)
 
 	// Test Delete Entity
-	err = queries.DeleteEntity(ctx, entityName)
-	require.NoError(t, err)
-
-	// Verify deletion
-	_, err = queries.GetEntity(ctx, entityName)
-	assert.Error(t, err) // Should return error for not found
+	require.NoError(t, queries.DeleteGraphEntity(ctx, "entity-1"))
+	_, err = queries.GetGraphEntity(ctx, "entity-1")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
 }
 
-func TestEntitiesSearch(t *testing.T) {
+func TestGraphEntitiesSearch(t *testing.T) {
 	// Create a test database
 	db := createTestDB(t)
 	defer db.Close()
@@ -114,50 +73,42 @@ func TestEntitiesSearch(t *testing.T) {
 	ctx := context.Background()
 	queries := New(db)
 
-	embed := make([]byte, 728*4)
-
 	// Create test entities
 	testEntities := []struct {
-		name       string
-		entityType string
-		metadata   string
+		id, name, kind string
 	}{
-		{"person1", "person", `{"tags": ["developer"]}`},
-		{"person2", "person", `{"tags": ["designer"]}`},
-		{"company1", "company", `{"tags": ["tech"]}`},
-		{"project1", "project", `{"tags": ["open-source"]}`},
+		{"1", "person1", "person"},
+		{"2", "person2", "person"},
+		{"3", "company1", "company"},
+		{"4", "project1", "project"},
 	}
-
 	for _, entity := range testEntities {
-		_, err := queries.CreateEntity(ctx, CreateEntityParams{
-			Name:       entity.name,
-			EntityType: entity.entityType,
-			Embedding:  embed,
-			Metadata:   entity.metadata,
-			CreatedAt:  time.Now().Unix(),
-			UpdatedAt:  time.Now().Unix(),
+		_, err := queries.CreateGraphEntity(ctx, CreateGraphEntityParams{
+			ID:        entity.id,
+			Kind:      entity.kind,
+			Name:      entity.name,
+			AttrsJson: `{}`,
 		})
 		require.NoError(t, err)
 	}
 
-	// Test search by type
-	persons, err := queries.GetEntitiesByType(ctx, "person")
+	// Test search by kind
+	persons, err := queries.GetGraphEntitiesByKind(ctx, GetGraphEntitiesByKindParams{Kind: "person", Limit: 10})
 	require.NoError(t, err)
 	assert.Len(t, persons, 2)
 
-	companies, err := queries.GetEntitiesByType(ctx, "company")
+	companies, err := queries.GetGraphEntitiesByKind(ctx, GetGraphEntitiesByKindParams{Kind: "company", Limit: 10})
 	require.NoError(t, err)
 	assert.Len(t, companies, 1)
 
 	// Test search with partial name match
-	partialNameResults, err := queries.SearchEntities(ctx, SearchEntitiesParams{
-		Column1: sql.NullString{String: "", Valid: false},      // no type filter
-		Column2: sql.NullString{String: "person", Valid: true}, // name contains "person"
-		Limit:   10,                                            // limit
-		Offset:  0,
+	results, err := queries.SearchGraphEntitiesFTS(ctx, SearchGraphEntitiesFTSParams{
+		Column1: sql.NullString{String: "person", Valid: true}, // name contains "person"
+		Column2: sql.NullString{String: "person", Valid: true}, // or summary does
+		Limit:   10,
 	})
 	require.NoError(t, err)
-	assert.GreaterOrEqual(t, len(partialNameResults), 2) // at least person1 and person2
+	assert.Len(t, results, 2)
 }
 
 func TestEntityWithObservations(t *testing.T) {
@@ -168,18 +119,12 @@ func TestEntityWithObservations(t *testing.T) {
 	ctx := context.Background()
 	queries := New(db)
 
-	embed := make([]byte, 728*4)
-
-	// Create an entity
+	// Observations reference the entities table, which has no generated queries
 	entityName := "test-entity-with-obs"
-	_, err := queries.CreateEntity(ctx, CreateEntityParams{
-		Name:       entityName,
-		EntityType: "person",
-		Embedding:  embed,
-		Metadata:   `{"tags": ["test"]}`,
-		CreatedAt:  time.Now().Unix(),
-		UpdatedAt:  time.Now().Unix(),
-	})
+	now := time.Now().Unix()
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO entities (name, entity_type, metadata, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		entityName, "person", `{"tags": ["test"]}`, now, now)
 	require.NoError(t, err)
 
 	// Create observations for the entity
@@ -187,32 +132,28 @@ func TestEntityWithObservations(t *testing.T) {
 	_, err = queries.CreateObservation(ctx, CreateObservationParams{
 		EntityName: entityName,
 		Content:    observationContent,
-		Embedding:  embed,
-		CreatedAt:  time.Now().Unix(),
+		CreatedAt:  now,
 	})
 	require.NoError(t, err)
 
-	// Test GetEntityWithObservations
-	result, err := queries.GetEntityWithObservations(ctx, GetEntityWithObservationsParams{
-		Name:       entityName,
-		EntityName: entityName,
-		Limit:      10,
-	})
+	observations, err := queries.GetEntityObservations(ctx, entityName)
+	require.NoError(t, err)
+	require.Len(t, observations, 1)
+	assert.Equal(t, observationContent, observations[0].Content)
+
+	stats, err := queries.GetEntityObservationStats(ctx, entityName)
 	require.NoError(t, err)
-	assert.Equal(t, entityName, result.Name)
-	assert.Greater(t, result.ObservationCount, int64(0))
-	assert.Contains(t, result.ObservationsContent, observationContent)
+	assert.Equal(t, int64(1), stats.TotalObservations)
 }
 
-// Helper function to create test database
+// createTestDB opens a file-backed libSQL database with every migration applied
 func createTestDB(t *testing.T) *sql.DB {
-	// Use in-memory libSQL for testing
-	db, err := sql.Open("libsql", "file::memory:?cache=shared")
+	db, err := sql.Open("libsql", "file:"+filepath.Join(t.TempDir(), "test.db"))
 	require.NoError(t, err)
 
 	// Run migrations with Turso/libSQL dialect using Provider API
 	migrationsDir := "../migrations"
-	provider, err := goose.NewProvider(goose.DialectTurso, db, os.DirFS(migrationsDir), goose.WithVerbose(true))
+	provider, err := goose.NewProvider(goose.DialectTurso, db, os.DirFS(migrationsDir))
 	if err != nil {
 		t.Fatalf("Failed to create goose provider: %v", err)
 	}
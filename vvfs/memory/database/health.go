@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// ActiveProjects returns the names of projects with an open connection
+func (dm *DBManager) ActiveProjects() []string {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+
+	projects := make([]string, 0, len(dm.dbs))
+	for name := range dm.dbs {
+		projects = append(projects, name)
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// Ping checks the connection of an already-open project without opening a new one
+func (dm *DBManager) Ping(ctx context.Context, projectName string) error {
+	dm.mu.RLock()
+	db, ok := dm.dbs[projectName]
+	dm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("project %s has no open connection", projectName)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database for project %s: %w", projectName, err)
+	}
+	return nil
+}
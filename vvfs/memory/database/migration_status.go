@@ -0,0 +1,84 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pressly/goose/v3"
+)
+
+// resolveMigrationsDir locates vvfs/memory/migrations by walking up from the
+// working directory, so binaries and tests run from subdirectories share it.
+func resolveMigrationsDir() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	for dir := wd; ; {
+		candidate := filepath.Join(dir, "vvfs", "memory", "migrations")
+		if info, err := os.Stat(candidate); err == nil && info.IsDir() {
+			return candidate, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	// Fall back to the conventional location so goose reports a clear error
+	return filepath.Join(wd, "vvfs", "memory", "migrations"), nil
+}
+
+// SchemaVersion returns the goose migration version applied to a project's DB
+func (dm *DBManager) SchemaVersion(ctx context.Context, projectName string) (int64, error) {
+	db, err := dm.getDB(projectName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := goose.SetDialect("sqlite3"); err != nil {
+		return 0, fmt.Errorf("failed to set goose dialect: %w", err)
+	}
+
+	version, err := goose.GetDBVersionContext(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version for project %s: %w", projectName, err)
+	}
+	return version, nil
+}
+
+// PendingMigrations lists migration files newer than the project's schema
+// version without applying them. An empty result means the DB is up to date.
+func (dm *DBManager) PendingMigrations(ctx context.Context, projectName string) ([]string, error) {
+	current, err := dm.SchemaVersion(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	migrationsPath, err := resolveMigrationsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := goose.CollectMigrations(migrationsPath, current, goose.MaxVersion)
+	if errors.Is(err, goose.ErrNoMigrationFiles) {
+		// Nothing newer than the current version
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	pending := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, filepath.Base(m.Source))
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersionAndPendingMigrations(t *testing.T) {
+	ctx := context.Background()
+	cfg := &Config{
+		URL:           "file:" + filepath.Join(t.TempDir(), "libsql.db"),
+		EmbeddingDims: 4,
+	}
+
+	dm, err := NewDBManager(cfg)
+	require.NoError(t, err)
+	defer dm.Close()
+
+	migrationsPath, err := resolveMigrationsDir()
+	require.NoError(t, err)
+	all, err := goose.CollectMigrations(migrationsPath, 0, goose.MaxVersion)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+	latest := all[len(all)-1].Version
+
+	version, err := dm.SchemaVersion(ctx, defaultProject)
+	require.NoError(t, err)
+	assert.Equal(t, latest, version)
+
+	pending, err := dm.PendingMigrations(ctx, defaultProject)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+)
+
+// poolStrategy names how a database's connection pool is sized
+type poolStrategy string
+
+const (
+	poolPrivateMemory poolStrategy = "private-memory" // each connection opens its own empty database
+	poolSharedMemory  poolStrategy = "shared-memory"  // connections share one in-memory database via cache=shared
+	poolFileWAL       poolStrategy = "file-wal"       // one writer alongside many concurrent readers
+	poolFileRollback  poolStrategy = "file-rollback"  // readers and the writer block each other
+	poolRemote        poolStrategy = "remote"
+)
+
+// Pool defaults when the config leaves the limits unset
+const (
+	defaultMaxOpenConns         = 25
+	defaultRollbackMaxOpenConns = 4
+	defaultConnMaxIdleTime      = 5 * time.Minute
+	defaultConnMaxLifetime      = time.Hour
+)
+
+// poolPlan is the connection pool sizing chosen for a DSN
+type poolPlan struct {
+	strategy    poolStrategy
+	reason      string
+	maxOpen     int
+	maxIdle     int
+	maxIdleTime time.Duration // zero keeps idle connections open
+	maxLifetime time.Duration // zero never recycles connections
+}
+
+// planConnectionPool picks pool limits for dsn. Private in-memory databases are pinned to a
+// single connection that is never recycled, since any other connection would see a different,
+// empty database. Configured limits apply to every other kind
+func (dm *DBManager) planConnectionPool(dsn, projectName string) poolPlan {
+	maxOpen := dm.config.MaxOpenConns
+	maxIdle := dm.config.MaxIdleConns
+	idleTime := time.Duration(dm.config.ConnMaxIdleSec) * time.Second
+	if idleTime <= 0 {
+		idleTime = defaultConnMaxIdleTime
+	}
+	lifeTime := time.Duration(dm.config.ConnMaxLifeSec) * time.Second
+	if lifeTime <= 0 {
+		lifeTime = defaultConnMaxLifetime
+	}
+
+	plan := poolPlan{maxIdleTime: idleTime, maxLifetime: lifeTime}
+	memory, shared := inMemoryDSN(dsn)
+	switch {
+	case memory && !shared:
+		return poolPlan{
+			strategy: poolPrivateMemory,
+			reason:   "in-memory database without cache=shared is private to one connection",
+			maxOpen:  1,
+			maxIdle:  1,
+		}
+	case memory:
+		// The database lives only while a connection is open, so connections are never recycled
+		plan.strategy = poolSharedMemory
+		plan.reason = "shared-cache in-memory database; connections are kept open to keep it alive"
+		plan.maxIdleTime, plan.maxLifetime = 0, 0
+		if maxOpen <= 0 {
+			maxOpen = defaultMaxOpenConns
+		}
+		if maxIdle <= 0 {
+			maxIdle = maxOpen
+		}
+	case isFileDSN(dsn) && dm.journalMode(projectName) == "WAL":
+		plan.strategy = poolFileWAL
+		plan.reason = "WAL lets readers proceed alongside the single writer"
+		if maxOpen <= 0 {
+			maxOpen = defaultMaxOpenConns
+		}
+	case isFileDSN(dsn):
+		plan.strategy = poolFileRollback
+		plan.reason = "rollback journal blocks readers during writes; few connections limit busy retries"
+		if maxOpen <= 0 {
+			maxOpen = defaultRollbackMaxOpenConns
+		}
+	default:
+		plan.strategy = poolRemote
+		plan.reason = "remote database; the server coordinates writers"
+		if maxOpen <= 0 {
+			maxOpen = defaultMaxOpenConns
+		}
+	}
+
+	if maxIdle <= 0 || maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	plan.maxOpen, plan.maxIdle = maxOpen, maxIdle
+	return plan
+}
+
+// apply sets the plan's limits on db
+func (p poolPlan) apply(db *sql.DB) {
+	db.SetMaxOpenConns(p.maxOpen)
+	db.SetMaxIdleConns(p.maxIdle)
+	db.SetConnMaxIdleTime(p.maxIdleTime)
+	db.SetConnMaxLifetime(p.maxLifetime)
+}
+
+// journalMode returns the configured journal mode for a project in upper case, or "" if unset
+func (dm *DBManager) journalMode(projectName string) string {
+	settings, err := dm.pragmaSettings(projectName)
+	if err != nil {
+		return ""
+	}
+	for _, setting := range settings {
+		if setting.name == "journal_mode" {
+			return strings.ToUpper(setting.value)
+		}
+	}
+	return ""
+}
+
+// inMemoryDSN reports whether dsn names an in-memory database and whether it uses a shared cache
+func inMemoryDSN(dsn string) (memory, shared bool) {
+	path, query, ok := appconfig.ParseFileDSN(dsn)
+	if !ok {
+		return false, false
+	}
+	memory = path == ":memory:" || query.Get("mode") == "memory"
+	shared = query.Get("cache") == "shared"
+	return memory, shared
+}
+
+// isFileDSN reports whether dsn names a local database file
+func isFileDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "file:")
+}
+
+// logPoolPlan records the chosen pool strategy and why
+func logPoolPlan(projectName string, plan poolPlan) {
+	log.Printf("Connection pool for project %s: strategy=%s (%s), max_open=%d, max_idle=%d, max_idle_time=%v, max_lifetime=%v",
+		projectName, plan.strategy, plan.reason, plan.maxOpen, plan.maxIdle, plan.maxIdleTime, plan.maxLifetime)
+}
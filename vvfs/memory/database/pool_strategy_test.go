@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanConnectionPool(t *testing.T) {
+	fileDSN := "file:" + filepath.Join(t.TempDir(), "libsql.db")
+
+	tests := []struct {
+		name        string
+		config      Config
+		dsn         string
+		strategy    poolStrategy
+		maxOpen     int
+		keepForever bool
+	}{
+		{name: "plain memory", config: Config{MaxOpenConns: 10}, dsn: ":memory:", strategy: poolPrivateMemory, maxOpen: 1, keepForever: true},
+		{name: "file memory", dsn: "file::memory:", strategy: poolPrivateMemory, maxOpen: 1, keepForever: true},
+		{name: "memory mode", dsn: "file:vvfs?mode=memory", strategy: poolPrivateMemory, maxOpen: 1, keepForever: true},
+		{name: "shared memory", dsn: "file::memory:?cache=shared", strategy: poolSharedMemory, maxOpen: 25, keepForever: true},
+		{name: "file wal", config: Config{JournalMode: "wal"}, dsn: fileDSN, strategy: poolFileWAL, maxOpen: 25},
+		{name: "file wal configured", config: Config{JournalMode: "WAL", MaxOpenConns: 8}, dsn: fileDSN, strategy: poolFileWAL, maxOpen: 8},
+		{name: "file rollback", config: Config{JournalMode: "DELETE"}, dsn: fileDSN, strategy: poolFileRollback, maxOpen: 4},
+		{name: "remote", dsn: "libsql://example.turso.io", strategy: poolRemote, maxOpen: 25},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := &DBManager{config: &tt.config}
+			plan := dm.planConnectionPool(tt.dsn, defaultProject)
+
+			assert.Equal(t, tt.strategy, plan.strategy)
+			assert.Equal(t, tt.maxOpen, plan.maxOpen)
+			assert.LessOrEqual(t, plan.maxIdle, plan.maxOpen)
+			assert.NotEmpty(t, plan.reason)
+			if tt.keepForever {
+				assert.Zero(t, plan.maxIdleTime)
+				assert.Zero(t, plan.maxLifetime)
+			} else {
+				assert.Positive(t, plan.maxLifetime)
+			}
+		})
+	}
+}
+
+func TestDBManager_InMemoryWritesVisibleToLaterReads(t *testing.T) {
+	ctx := context.Background()
+	dm, err := NewDBManager(&Config{URL: ":memory:", EmbeddingDims: 4, MaxOpenConns: 10})
+	require.NoError(t, err)
+	defer dm.Close()
+
+	db, err := dm.getDB(defaultProject)
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.Stats().MaxOpenConnections)
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT)`)
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, `INSERT INTO notes (body) VALUES ('remember me')`)
+	require.NoError(t, err)
+
+	// Concurrent readers would each open a fresh, empty database if the pool grew
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var body string
+			errs <- db.QueryRowContext(ctx, `SELECT body FROM notes`).Scan(&body)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	// Migrated tables are visible through the querier too
+	querier, err := dm.GetQuerier(defaultProject)
+	require.NoError(t, err)
+	_, err = querier.CountGraphEntities(ctx)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default values for PRAGMAs that are not set explicitly
+const (
+	defaultMmapSize          = 268435456 // 256MB memory map
+	defaultWALAutocheckpoint = 1000      // Checkpoint every 1000 pages
+	defaultBusyTimeoutMs     = 5000      // 5 second timeout
+)
+
+// pragmaSetting is a validated PRAGMA assignment
+type pragmaSetting struct {
+	name  string
+	value string
+}
+
+// pragmaValidator normalizes a PRAGMA value or rejects it
+type pragmaValidator func(value string) (string, error)
+
+// allowedPragmas lists the PRAGMAs that may be configured and how their values are validated.
+// Names outside this list are rejected so config cannot inject arbitrary SQL.
+var allowedPragmas = map[string]pragmaValidator{
+	"journal_mode":       enumPragma("DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"),
+	"synchronous":        enumPragma("OFF", "NORMAL", "FULL", "EXTRA", "0", "1", "2", "3"),
+	"temp_store":         enumPragma("DEFAULT", "FILE", "MEMORY", "0", "1", "2"),
+	"foreign_keys":       enumPragma("ON", "OFF", "TRUE", "FALSE", "1", "0"),
+	"automatic_index":    enumPragma("ON", "OFF", "TRUE", "FALSE", "1", "0"),
+	"cache_spill":        enumPragma("ON", "OFF", "TRUE", "FALSE", "1", "0"),
+	"secure_delete":      enumPragma("ON", "OFF", "FAST", "TRUE", "FALSE", "1", "0"),
+	"cache_size":         intPragma,
+	"mmap_size":          nonNegativeIntPragma,
+	"wal_autocheckpoint": nonNegativeIntPragma,
+	"busy_timeout":       nonNegativeIntPragma,
+	"journal_size_limit": intPragma,
+	"threads":            nonNegativeIntPragma,
+}
+
+// enumPragma accepts one of the given keywords (case-insensitive)
+func enumPragma(allowed ...string) pragmaValidator {
+	return func(value string) (string, error) {
+		upper := strings.ToUpper(strings.TrimSpace(value))
+		for _, a := range allowed {
+			if upper == a {
+				return upper, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+// intPragma accepts any integer
+func intPragma(value string) (string, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("must be an integer")
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+// nonNegativeIntPragma accepts integers >= 0
+func nonNegativeIntPragma(value string) (string, error) {
+	normalized, err := intPragma(value)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(normalized, "-") {
+		return "", fmt.Errorf("must not be negative")
+	}
+	return normalized, nil
+}
+
+// validatePragma checks a PRAGMA name against the allowlist and normalizes its value
+func validatePragma(name, value string) (pragmaSetting, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	validate, ok := allowedPragmas[name]
+	if !ok {
+		return pragmaSetting{}, fmt.Errorf("unsupported PRAGMA %q", name)
+	}
+	normalized, err := validate(value)
+	if err != nil {
+		return pragmaSetting{}, fmt.Errorf("invalid value %q for PRAGMA %s: %w", value, name, err)
+	}
+	return pragmaSetting{name: name, value: normalized}, nil
+}
+
+// pragmaSettings resolves the ordered PRAGMA list for a project: typed config fields first,
+// then global overrides, then project overrides (later entries win).
+func (dm *DBManager) pragmaSettings(projectName string) ([]pragmaSetting, error) {
+	cfg := dm.config
+	values := make(map[string]string)
+	order := make([]string, 0, len(allowedPragmas))
+	set := func(name, value string) error {
+		setting, err := validatePragma(name, value)
+		if err != nil {
+			return err
+		}
+		if _, seen := values[setting.name]; !seen {
+			order = append(order, setting.name)
+		}
+		values[setting.name] = setting.value
+		return nil
+	}
+
+	mmapSize := cfg.MmapSize
+	switch {
+	case mmapSize == 0:
+		mmapSize = defaultMmapSize
+	case mmapSize < 0:
+		mmapSize = 0
+	}
+	walAutocheckpoint := cfg.WALAutocheckpoint
+	if walAutocheckpoint <= 0 {
+		walAutocheckpoint = defaultWALAutocheckpoint
+	}
+	busyTimeout := cfg.BusyTimeoutMs
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeoutMs
+	}
+	foreignKeys := "ON"
+	if cfg.DisableForeignKeys {
+		foreignKeys = "OFF"
+	}
+
+	base := []pragmaSetting{
+		{"journal_mode", cfg.JournalMode},
+		{"synchronous", cfg.SyncMode},
+		{"temp_store", cfg.TempStore},
+		{"mmap_size", strconv.FormatInt(mmapSize, 10)},
+		{"wal_autocheckpoint", strconv.Itoa(walAutocheckpoint)},
+		{"busy_timeout", strconv.Itoa(busyTimeout)},
+		{"foreign_keys", foreignKeys},
+	}
+	if cfg.CacheSize != 0 {
+		base = append(base, pragmaSetting{"cache_size", strconv.Itoa(cfg.CacheSize)})
+	}
+	for _, s := range base {
+		if s.value == "" {
+			continue
+		}
+		if err := set(s.name, s.value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, overrides := range []map[string]string{cfg.Pragmas, cfg.ProjectPragmas[projectName]} {
+		names := make([]string, 0, len(overrides))
+		for name := range overrides {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := set(name, overrides[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	settings := make([]pragmaSetting, 0, len(order))
+	for _, name := range order {
+		settings = append(settings, pragmaSetting{name: name, value: values[name]})
+	}
+	return settings, nil
+}
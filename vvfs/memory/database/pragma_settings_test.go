@@ -0,0 +1,109 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appconfig "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+)
+
+func TestConfigurePragmaSettings_AppliesConfiguredValues(t *testing.T) {
+	cfg := &Config{
+		URL:               "file:" + filepath.Join(t.TempDir(), "libsql.db"),
+		EmbeddingDims:     4,
+		MaxOpenConns:      1, // PRAGMAs are per connection; keep readback on the configured one
+		MaxIdleConns:      1,
+		JournalMode:       "WAL",
+		CacheSize:         -2000,
+		WALAutocheckpoint: 500,
+		BusyTimeoutMs:     1234,
+		Pragmas:           map[string]string{"busy_timeout": "2500"},
+		ProjectPragmas: map[string]map[string]string{
+			defaultProject: {"cache_size": "-4000"},
+		},
+	}
+
+	dm, err := NewDBManager(cfg)
+	require.NoError(t, err)
+	defer dm.Close()
+
+	db, err := dm.getDB(defaultProject)
+	require.NoError(t, err)
+
+	readback := func(name string) string {
+		var value string
+		require.NoError(t, db.QueryRow("PRAGMA "+name).Scan(&value))
+		return value
+	}
+
+	assert.Equal(t, "wal", readback("journal_mode"))
+	assert.Equal(t, "-4000", readback("cache_size"))
+	assert.Equal(t, "500", readback("wal_autocheckpoint"))
+	assert.Equal(t, "2500", readback("busy_timeout"))
+	assert.Equal(t, "1", readback("foreign_keys"))
+}
+
+func TestConfigurePragmaSettings_RejectsUnknownPragma(t *testing.T) {
+	cfg := &Config{
+		URL:           "file:" + filepath.Join(t.TempDir(), "libsql.db"),
+		EmbeddingDims: 4,
+		Pragmas:       map[string]string{"writable_schema": "ON"},
+	}
+
+	_, err := NewDBManager(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported PRAGMA "writable_schema"`)
+}
+
+func TestValidatePragma(t *testing.T) {
+	setting, err := validatePragma("Journal_Mode", "wal")
+	require.NoError(t, err)
+	assert.Equal(t, pragmaSetting{name: "journal_mode", value: "WAL"}, setting)
+
+	_, err = validatePragma("cache_size", "1; DROP TABLE entities")
+	assert.Error(t, err)
+
+	_, err = validatePragma("busy_timeout", "-1")
+	assert.Error(t, err)
+
+	_, err = validatePragma("foreign_keys = ON; PRAGMA writable_schema", "ON")
+	assert.Error(t, err)
+}
+
+func TestNewConfig_ReadsPragmasFromDatabaseConfig(t *testing.T) {
+	for _, env := range []string{"LIBSQL_AUTH_TOKEN", "DB_SYNC_MODE", "DB_CACHE_SIZE", "DB_TEMP_STORE", "DB_JOURNAL_MODE"} {
+		t.Setenv(env, "")
+	}
+
+	dbConfig := appconfig.DatabaseConfig{
+		AuthToken:          "secret",
+		Pragmas:            map[string]string{"threads": "2"},
+		SyncMode:           "FULL",
+		CacheSize:          -2000,
+		JournalMode:        "DELETE",
+		MmapSize:           -1,
+		WALAutocheckpoint:  500,
+		BusyTimeoutMs:      2500,
+		DisableForeignKeys: true,
+		ProjectPragmas:     map[string]map[string]string{defaultProject: {"cache_size": "-4000"}},
+	}
+
+	cfg := NewConfig(dbConfig)
+	assert.Equal(t, "FULL", cfg.SyncMode)
+	assert.Equal(t, -2000, cfg.CacheSize)
+	assert.Equal(t, "MEMORY", cfg.TempStore) // unset values keep their defaults
+	assert.Equal(t, "DELETE", cfg.JournalMode)
+	assert.Equal(t, int64(-1), cfg.MmapSize)
+	assert.Equal(t, 500, cfg.WALAutocheckpoint)
+	assert.Equal(t, 2500, cfg.BusyTimeoutMs)
+	assert.True(t, cfg.DisableForeignKeys)
+	assert.Equal(t, "secret", cfg.AuthToken)
+	assert.Equal(t, map[string]string{"threads": "2"}, cfg.Pragmas)
+	assert.Equal(t, "-4000", cfg.ProjectPragmas[defaultProject]["cache_size"])
+
+	t.Setenv("LIBSQL_AUTH_TOKEN", "from-env")
+	assert.Equal(t, "from-env", NewConfig(dbConfig).AuthToken)
+}
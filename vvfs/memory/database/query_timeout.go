@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DefaultQueryTimeout bounds queries when Config.QueryTimeout is zero
+const DefaultQueryTimeout = 30 * time.Second
+
+// queryTimeout returns the configured bound; zero or less means unbounded
+func (dm *DBManager) queryTimeout() time.Duration {
+	switch {
+	case dm.config.QueryTimeout > 0:
+		return dm.config.QueryTimeout
+	case dm.config.QueryTimeout < 0:
+		return 0
+	default:
+		return DefaultQueryTimeout
+	}
+}
+
+// WithQueryTimeout bounds ctx by the configured query timeout. WithTx and WithTxReadOnly apply it
+// themselves; callers using GetQuerier directly wrap their queries with it.
+// An earlier deadline already on ctx is kept
+func (dm *DBManager) WithQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := dm.queryTimeout()
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// boundedDBTX runs every statement under both the caller's context and bound, so a statement
+// inside a transaction cannot outlive the transaction's timeout even when the caller passes
+// an unbounded context. Statement contexts are released when bound ends
+type boundedDBTX struct {
+	db    DBTX
+	bound context.Context
+}
+
+// statementContext derives the context a statement runs under
+func (b boundedDBTX) statementContext(ctx context.Context) context.Context {
+	var cancel context.CancelFunc
+	if deadline, ok := b.bound.Deadline(); ok {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	// Rows and Row outlive the call, so the context is cancelled with bound rather than on return
+	context.AfterFunc(b.bound, cancel)
+	return ctx
+}
+
+func (b boundedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return b.db.ExecContext(b.statementContext(ctx), query, args...)
+}
+
+func (b boundedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return b.db.PrepareContext(b.statementContext(ctx), query)
+}
+
+func (b boundedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return b.db.QueryContext(b.statementContext(ctx), query, args...)
+}
+
+func (b boundedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return b.db.QueryRowContext(b.statementContext(ctx), query, args...)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBManager_WithTxReadOnlyCancelsSlowQuery(t *testing.T) {
+	timeout := 200 * time.Millisecond
+	dm, err := NewDBManager(&Config{
+		URL:           "file:" + filepath.Join(t.TempDir(), "libsql.db"),
+		EmbeddingDims: 4,
+		QueryTimeout:  timeout,
+	})
+	require.NoError(t, err)
+	defer dm.Close()
+
+	start := time.Now()
+	err = dm.WithTxReadOnly(context.Background(), defaultProject, func(q *Queries) error {
+		// An endless recursive CTE only stops when its context ends
+		rows, err := q.db.QueryContext(context.Background(),
+			`WITH RECURSIVE counter(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM counter) SELECT n FROM counter`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows.Err()
+	})
+	elapsed := time.Since(start)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, elapsed, timeout)
+	assert.Less(t, elapsed, 10*timeout, "query should stop at the timeout rather than hang")
+
+	// The connection is usable afterwards
+	err = dm.WithTxReadOnly(context.Background(), defaultProject, func(q *Queries) error {
+		_, err := q.CountGraphEntities(context.Background())
+		return err
+	})
+	assert.NoError(t, err)
+}
+
+func TestDBManager_WithQueryTimeout(t *testing.T) {
+	dm := &DBManager{config: &Config{QueryTimeout: time.Minute}}
+
+	ctx, cancel := dm.WithQueryTimeout(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// An earlier caller deadline wins
+	short, cancelShort := context.WithTimeout(context.Background(), time.Second)
+	defer cancelShort()
+	ctx, cancel = dm.WithQueryTimeout(short)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	shortDeadline, _ := short.Deadline()
+	assert.Equal(t, shortDeadline, deadline)
+
+	// Zero uses the default; negative disables the bound
+	assert.Equal(t, DefaultQueryTimeout, (&DBManager{config: &Config{}}).queryTimeout())
+	dm.config.QueryTimeout = -1
+	ctx, cancel = dm.WithQueryTimeout(context.Background())
+	defer cancel()
+	_, ok = ctx.Deadline()
+	assert.False(t, ok)
+}
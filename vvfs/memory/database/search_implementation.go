@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log"
 	"math"
 	"sort"
 
@@ -247,18 +248,32 @@ func (dm *DBManager) fuseResultsWithRRF(textResults, vectorResults []apptype.Sea
 	return results[start:end], nil
 }
 
-// ensureFTSSchema creates FTS5 virtual table and triggers if supported (best-effort)
-func (dm *DBManager) ensureFTSSchema(ctx context.Context, db *sql.DB) error {
-	stmts := []string{
-		`DROP TRIGGER IF EXISTS trg_obs_ai`,
-		`DROP TRIGGER IF EXISTS trg_obs_ad`,
-		`DROP TRIGGER IF EXISTS trg_obs_au`,
-		`CREATE VIRTUAL TABLE IF NOT EXISTS fts_observations USING fts5(
+// ensureFTSSchema creates the FTS5 table over observations and its sync triggers.
+// It only creates what is missing, in one transaction, so it is safe to replay and never
+// leaves the triggers half-installed. When the project's detected capabilities lack FTS5
+// nothing is created and search falls back to LIKE matching.
+func (dm *DBManager) ensureFTSSchema(ctx context.Context, db *sql.DB, projectName string) error {
+	if !dm.HasCapability(projectName, "fts5") {
+		log.Printf("FTS5 unavailable for %s; skipping FTS schema", projectName)
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin FTS schema transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE VIRTUAL TABLE IF NOT EXISTS fts_observations USING fts5(
             entity_name,
             content,
-            tokenize = 'unicode61 tokenchars=:-_@./',
+            tokenize = "unicode61 tokenchars ':-_@./'",
             prefix = '2 3 4 5 6 7'
-        )`,
+        )`); err != nil {
+		return fmt.Errorf("failed to create FTS table: %w", err)
+	}
+
+	triggers := []string{
 		`CREATE TRIGGER IF NOT EXISTS trg_obs_ai AFTER INSERT ON observations BEGIN
             INSERT INTO fts_observations(rowid, entity_name, content) VALUES (new.id, new.entity_name, new.content);
         END;`,
@@ -270,10 +285,14 @@ func (dm *DBManager) ensureFTSSchema(ctx context.Context, db *sql.DB) error {
             INSERT INTO fts_observations(rowid, entity_name, content) VALUES (new.id, new.entity_name, new.content);
         END;`,
 	}
-	for _, s := range stmts {
-		if _, err := db.ExecContext(ctx, s); err != nil {
-			return nil
+	for _, stmt := range triggers {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to create FTS trigger: %w", err)
 		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit FTS schema: %w", err)
+	}
 	return nil
 }
@@ -1,3 +1,4 @@
+-- +goose Up
 -- Migration: Graph entities and edges for bi-temporal knowledge graph
 -- Adds support for graph_entities (nodes) and graph_edges (relationships) with temporal validity
 -- FTS5 for entity search, indexes for performance, views for temporal queries
@@ -24,26 +25,34 @@ CREATE VIRTUAL TABLE graph_entities_fts USING fts5(
     content_rowid='rowid'
 );
 
--- Trigger to keep FTS5 in sync on insert/update
+-- Triggers to keep FTS5 in sync; an external-content table is only changed through
+-- inserts, with the 'delete' command removing the old row's terms. The update trigger
+-- ignores the nested updated_at write from graph_entities_updated_at
+-- +goose StatementBegin
 CREATE TRIGGER graph_entities_fts_insert AFTER INSERT ON graph_entities
 BEGIN
     INSERT INTO graph_entities_fts (rowid, id, kind, name, summary)
     VALUES (new.rowid, new.id, new.kind, new.name, new.summary);
 END;
+-- +goose StatementEnd
 
+-- +goose StatementBegin
 CREATE TRIGGER graph_entities_fts_delete AFTER DELETE ON graph_entities
 BEGIN
-    DELETE FROM graph_entities_fts WHERE rowid = old.rowid;
+    INSERT INTO graph_entities_fts (graph_entities_fts, rowid, id, kind, name, summary)
+    VALUES ('delete', old.rowid, old.id, old.kind, old.name, old.summary);
 END;
+-- +goose StatementEnd
 
-CREATE TRIGGER graph_entities_fts_update AFTER UPDATE ON graph_entities
+-- +goose StatementBegin
+CREATE TRIGGER graph_entities_fts_update AFTER UPDATE OF id, kind, name, summary ON graph_entities
 BEGIN
-    UPDATE graph_entities_fts SET
-        kind = new.kind,
-        name = new.name,
-        summary = new.summary
-    WHERE rowid = new.rowid;
+    INSERT INTO graph_entities_fts (graph_entities_fts, rowid, id, kind, name, summary)
+    VALUES ('delete', old.rowid, old.id, old.kind, old.name, old.summary);
+    INSERT INTO graph_entities_fts (rowid, id, kind, name, summary)
+    VALUES (new.rowid, new.id, new.kind, new.name, new.summary);
 END;
+-- +goose StatementEnd
 
 -- Graph edges table: Represents relationships between graph entities with bi-temporal validity
 -- valid_from: When the fact became true (event time)
@@ -90,15 +99,31 @@ WHERE valid_from <= timepoint
   AND (invalidated_at IS NULL OR invalidated_at > timepoint);
 
 -- Trigger to update updated_at on graph entity changes
+-- +goose StatementBegin
 CREATE TRIGGER graph_entities_updated_at AFTER UPDATE ON graph_entities
 BEGIN
     UPDATE graph_entities SET updated_at = CURRENT_TIMESTAMP WHERE id = new.id;
 END;
+-- +goose StatementEnd
 
 -- Trigger to prevent invalidating already invalidated graph edges (optional, for data integrity)
 -- This is a no-op trigger for now, but can be extended for business logic
+-- +goose StatementBegin
 CREATE TRIGGER graph_edges_invalidation_check BEFORE UPDATE OF invalidated_at ON graph_edges
 WHEN new.invalidated_at IS NOT NULL AND old.invalidated_at IS NOT NULL
 BEGIN
     SELECT RAISE(ABORT, 'Edge already invalidated');
 END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER IF EXISTS graph_edges_invalidation_check;
+DROP TRIGGER IF EXISTS graph_entities_updated_at;
+DROP VIEW IF EXISTS graph_edges_asof;
+DROP VIEW IF EXISTS graph_edges_current;
+DROP TABLE IF EXISTS graph_edges;
+DROP TRIGGER IF EXISTS graph_entities_fts_update;
+DROP TRIGGER IF EXISTS graph_entities_fts_delete;
+DROP TRIGGER IF EXISTS graph_entities_fts_insert;
+DROP TABLE IF EXISTS graph_entities_fts;
+DROP TABLE IF EXISTS graph_entities;
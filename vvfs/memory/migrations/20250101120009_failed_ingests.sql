@@ -0,0 +1,21 @@
+-- +goose Up
+-- Dead-letter queue for memory items whose ingestion failed
+-- Rows are retried on a backoff schedule until they succeed (row deleted)
+-- or exhaust their attempts (status = 'failed')
+CREATE TABLE failed_ingests (
+    item_id TEXT PRIMARY KEY,
+    item_json TEXT NOT NULL, -- Serialized MemoryItem
+    episode_json TEXT, -- Serialized Episode for graph extraction, if any
+    last_error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    status TEXT NOT NULL DEFAULT 'pending', -- 'pending' or 'failed'
+    next_attempt_unix INTEGER NOT NULL, -- Unix seconds after which a retry is due
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_failed_ingests_due ON failed_ingests(status, next_attempt_unix);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_failed_ingests_due;
+DROP TABLE IF EXISTS failed_ingests;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Provenance of stored vectors: the embedding model version each item was embedded with,
+-- so vectors from different models are never compared against each other
+CREATE TABLE embedding_versions (
+    item_id TEXT PRIMARY KEY,
+    model_version TEXT NOT NULL,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_embedding_versions_model ON embedding_versions(model_version);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_embedding_versions_model;
+DROP TABLE IF EXISTS embedding_versions;
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Audit log of harness tool invocations; arguments, results and errors are redacted before insert.
+-- Timestamps and durations are stored in nanoseconds so range filters compare integers
+CREATE TABLE tool_invocations (
+    id TEXT PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    tool TEXT NOT NULL,
+    args TEXT NOT NULL,
+    result TEXT NOT NULL, -- Empty when only the hash is kept
+    result_hash TEXT NOT NULL, -- Hex SHA-256 of the unredacted result
+    error TEXT NOT NULL,
+    started_at INTEGER NOT NULL, -- Unix nanoseconds
+    duration_ns INTEGER NOT NULL
+);
+
+CREATE INDEX idx_tool_invocations_conversation ON tool_invocations(conversation_id, started_at);
+CREATE INDEX idx_tool_invocations_started ON tool_invocations(started_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tool_invocations_started;
+DROP INDEX IF EXISTS idx_tool_invocations_conversation;
+DROP TABLE IF EXISTS tool_invocations;
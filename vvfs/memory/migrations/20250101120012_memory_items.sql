@@ -0,0 +1,28 @@
+-- +goose Up
+-- Memory items and conversation sessions stored by the memory service
+CREATE TABLE memory_items (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    text TEXT NOT NULL,
+    metadata_json TEXT,
+    embedding BLOB, -- float32 vector; NULL until the item is indexed
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME,
+    source_ref TEXT
+);
+
+CREATE INDEX idx_memory_items_type ON memory_items(type);
+CREATE INDEX idx_memory_items_expires_at ON memory_items(expires_at);
+
+CREATE TABLE sessions (
+    id TEXT PRIMARY KEY,
+    messages_json TEXT NOT NULL, -- Serialized conversation messages
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS sessions;
+DROP INDEX IF EXISTS idx_memory_items_expires_at;
+DROP INDEX IF EXISTS idx_memory_items_type;
+DROP TABLE IF EXISTS memory_items;
@@ -0,0 +1,44 @@
+-- +goose Up
+-- External-content FTS5 index over memory_items for BM25 lexical search
+CREATE VIRTUAL TABLE memory_items_fts USING fts5(
+    text,
+    content = 'memory_items',
+    content_rowid = 'rowid'
+);
+
+-- Triggers to keep FTS5 in sync; an external-content table is only changed through
+-- inserts, with the 'delete' command removing the old row's terms
+-- +goose StatementBegin
+CREATE TRIGGER memory_items_fts_insert
+AFTER
+INSERT ON memory_items BEGIN
+INSERT INTO memory_items_fts (rowid, text)
+VALUES (new.rowid, new.text);
+END;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TRIGGER memory_items_fts_delete
+AFTER DELETE ON memory_items BEGIN
+INSERT INTO memory_items_fts (memory_items_fts, rowid, text)
+VALUES ('delete', old.rowid, old.text);
+END;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TRIGGER memory_items_fts_update
+AFTER
+UPDATE OF text ON memory_items BEGIN
+INSERT INTO memory_items_fts (memory_items_fts, rowid, text)
+VALUES ('delete', old.rowid, old.text);
+INSERT INTO memory_items_fts (rowid, text)
+VALUES (new.rowid, new.text);
+END;
+-- +goose StatementEnd
+
+-- A new external-content table starts empty, so index rows stored before it existed
+INSERT INTO memory_items_fts (memory_items_fts) VALUES ('rebuild');
+
+-- +goose Down
+DROP TRIGGER IF EXISTS memory_items_fts_update;
+DROP TRIGGER IF EXISTS memory_items_fts_delete;
+DROP TRIGGER IF EXISTS memory_items_fts_insert;
+DROP TABLE IF EXISTS memory_items_fts;
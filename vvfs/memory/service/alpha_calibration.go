@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// QueryJudgment is a labeled query used by CalibrateAlpha
+type QueryJudgment struct {
+	Query     string             `json:"query"`
+	Embedding []float64          `json:"embedding"` // Query vector; vector candidates are skipped when nil
+	Relevance map[string]float64 `json:"relevance"` // Graded relevance per item ID; unlisted IDs are irrelevant
+}
+
+// AlphaCalibrationConfig bounds the alpha sweep
+type AlphaCalibrationConfig struct {
+	Steps       int           // grid points over [0,1] (default 11, capped at 101)
+	K           int           // rank cutoff for nDCG and MRR (default 10)
+	Metric      string        // "ndcg" or "mrr" (default "ndcg"); the other metric breaks ties
+	MaxDuration time.Duration // cap on total calibration time (default 10s)
+	Apply       bool          // write the calibrated alpha to MemoryConfig.Alpha
+}
+
+const maxAlphaCalibrationSteps = 101
+
+// withDefaults fills unset bounds
+func (c AlphaCalibrationConfig) withDefaults() AlphaCalibrationConfig {
+	if c.Steps < 2 {
+		c.Steps = 11
+	}
+	if c.Steps > maxAlphaCalibrationSteps {
+		c.Steps = maxAlphaCalibrationSteps
+	}
+	if c.K <= 0 {
+		c.K = 10
+	}
+	if c.Metric != "mrr" {
+		c.Metric = "ndcg"
+	}
+	if c.MaxDuration <= 0 {
+		c.MaxDuration = 10 * time.Second
+	}
+	return c
+}
+
+// alphaTrial is the mean quality of fused rankings at one alpha
+type alphaTrial struct {
+	alpha float64
+	ndcg  float64
+	mrr   float64
+}
+
+// SetAlphaCalibration configures how CalibrateAlpha sweeps alpha
+func (ret *RetrieverImpl) SetAlphaCalibration(cfg AlphaCalibrationConfig) {
+	ret.calibration = cfg
+}
+
+// CalibrateAlpha sweeps the fusion alpha over an evenly spaced grid on [0,1], scoring the
+// fused rankings of the labeled queries by nDCG@K and MRR, and returns the best alpha.
+// Candidates are fetched once per query and re-fused for every alpha. Ties prefer the
+// alpha closest to the configured one. When the time budget runs out the best alpha
+// evaluated so far is returned
+func (ret *RetrieverImpl) CalibrateAlpha(ctx context.Context, labeled []QueryJudgment) (float64, error) {
+	cfg := ret.calibration.withDefaults()
+	deadline := time.Now().Add(cfg.MaxDuration)
+
+	type candidates struct {
+		lexical, vector []SearchResult
+		relevance       map[string]float64
+	}
+	var queries []candidates
+	for _, judgment := range labeled {
+		if !hasRelevant(judgment.Relevance) {
+			continue // nDCG and MRR are undefined without a relevant item
+		}
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("alpha calibration timed out while fetching candidates")
+		}
+
+		c := candidates{relevance: judgment.Relevance}
+		var err error
+		if ret.lexicalIndex != nil {
+			c.lexical, err = ret.lexicalIndex.Query(ctx, judgment.Query, cfg.K*2)
+			if err != nil {
+				return 0, fmt.Errorf("lexical search failed: %w", err)
+			}
+		}
+		if ret.vectorIndex != nil && judgment.Embedding != nil {
+			c.vector, err = ret.vectorIndex.Query(ctx, judgment.Embedding, cfg.K*2)
+			if err != nil {
+				return 0, fmt.Errorf("vector search failed: %w", err)
+			}
+		}
+		queries = append(queries, c)
+	}
+	if len(queries) == 0 {
+		return 0, fmt.Errorf("no labeled queries with relevant items")
+	}
+
+	var best *alphaTrial
+	for step := 0; step < cfg.Steps; step++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+
+		trial := alphaTrial{alpha: float64(step) / float64(cfg.Steps-1)}
+		for _, q := range queries {
+			// fuseResults breaks score ties by ID, so trials are comparable
+			ranked := ret.fuseResults(q.lexical, q.vector, trial.alpha, false)
+			trial.ndcg += ndcgAtK(ranked, q.relevance, cfg.K)
+			trial.mrr += reciprocalRank(ranked, q.relevance, cfg.K)
+		}
+		trial.ndcg /= float64(len(queries))
+		trial.mrr /= float64(len(queries))
+
+		if best == nil || ret.betterAlphaTrial(trial, *best, cfg.Metric) {
+			best = &trial
+		}
+	}
+	if best == nil {
+		return 0, fmt.Errorf("alpha calibration timed out before evaluating any alpha")
+	}
+
+	if cfg.Apply && ret.config != nil {
+		ret.config.Alpha = best.alpha
+	}
+	return best.alpha, nil
+}
+
+// betterAlphaTrial reports whether a should be preferred over b
+func (ret *RetrieverImpl) betterAlphaTrial(a, b alphaTrial, metric string) bool {
+	const epsilon = 1e-9
+	primaryA, primaryB, secondaryA, secondaryB := a.ndcg, b.ndcg, a.mrr, b.mrr
+	if metric == "mrr" {
+		primaryA, primaryB, secondaryA, secondaryB = a.mrr, b.mrr, a.ndcg, b.ndcg
+	}
+	switch {
+	case math.Abs(primaryA-primaryB) > epsilon:
+		return primaryA > primaryB
+	case math.Abs(secondaryA-secondaryB) > epsilon:
+		return secondaryA > secondaryB
+	}
+
+	current := 0.0
+	if ret.config != nil {
+		current = ret.config.Alpha
+	}
+	return math.Abs(a.alpha-current) < math.Abs(b.alpha-current)
+}
+
+// ndcgAtK computes normalized discounted cumulative gain over the top k results
+func ndcgAtK(ranked []SearchResult, relevance map[string]float64, k int) float64 {
+	dcg := 0.0
+	for i, result := range ranked {
+		if i >= k {
+			break
+		}
+		dcg += (math.Pow(2, relevance[result.ID]) - 1) / math.Log2(float64(i+2))
+	}
+
+	ideal := make([]float64, 0, len(relevance))
+	for _, grade := range relevance {
+		ideal = append(ideal, grade)
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(ideal)))
+	idcg := 0.0
+	for i, grade := range ideal {
+		if i >= k {
+			break
+		}
+		idcg += (math.Pow(2, grade) - 1) / math.Log2(float64(i+2))
+	}
+	if idcg == 0 {
+		return 0
+	}
+	return dcg / idcg
+}
+
+// reciprocalRank returns 1/rank of the first relevant result within the top k, or 0
+func reciprocalRank(ranked []SearchResult, relevance map[string]float64, k int) float64 {
+	for i, result := range ranked {
+		if i >= k {
+			break
+		}
+		if relevance[result.ID] > 0 {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// hasRelevant reports whether any judged item is relevant
+func hasRelevant(relevance map[string]float64) bool {
+	for _, grade := range relevance {
+		if grade > 0 {
+			return true
+		}
+	}
+	return false
+}
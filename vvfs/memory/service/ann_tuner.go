@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+)
+
+// ANNParams is one HNSW parameter set
+type ANNParams struct {
+	M              int `json:"m"`
+	EFConstruction int `json:"ef_construction"`
+	EFSearch       int `json:"ef_search"`
+}
+
+// ANNTrial records the measured quality of one parameter set
+type ANNTrial struct {
+	Params      ANNParams     `json:"params"`
+	Recall      float64       `json:"recall"`
+	MeanLatency time.Duration `json:"mean_latency"`
+}
+
+// ANNTuningResult is the recommendation produced by ANNTuner.Tune
+type ANNTuningResult struct {
+	Best        ANNParams     `json:"best"`
+	Recall      float64       `json:"recall"`
+	MeanLatency time.Duration `json:"mean_latency"`
+	MetTarget   bool          `json:"met_target"` // false when no trial reached the target recall
+	Trials      []ANNTrial    `json:"trials"`
+	TimedOut    bool          `json:"timed_out"` // sweep stopped early at MaxDuration
+}
+
+// ANNSample is a labeled corpus and query set used for tuning
+type ANNSample struct {
+	IDs         []string
+	Vectors     [][]float64
+	Queries     [][]float64
+	GroundTruth [][]string // exact top-K IDs per query; computed by brute force when nil
+}
+
+// ANNIndexFactory builds an empty index configured with the given parameters
+type ANNIndexFactory func(ctx context.Context, params ANNParams) (VectorIndex, error)
+
+// ANNTunerConfig bounds the parameter sweep
+type ANNTunerConfig struct {
+	TargetRecall         float64       // recall@K to reach (default 0.95)
+	K                    int           // neighbours per query (default 10)
+	MValues              []int         // candidate M values
+	EFConstructionValues []int         // candidate efConstruction values
+	EFSearchValues       []int         // candidate efSearch values
+	MaxTrials            int           // cap on evaluated parameter sets (default 48)
+	MaxDuration          time.Duration // cap on total sweep time (default 60s)
+}
+
+// ANNTuner sweeps HNSW parameters and recommends the fastest set meeting a recall target
+type ANNTuner struct {
+	config  ANNTunerConfig
+	factory ANNIndexFactory
+}
+
+// NewANNTuner creates a tuner, filling unset bounds with defaults
+func NewANNTuner(cfg ANNTunerConfig, factory ANNIndexFactory) *ANNTuner {
+	if cfg.TargetRecall <= 0 || cfg.TargetRecall > 1 {
+		cfg.TargetRecall = 0.95
+	}
+	if cfg.K <= 0 {
+		cfg.K = 10
+	}
+	if len(cfg.MValues) == 0 {
+		cfg.MValues = []int{8, 16, 32}
+	}
+	if len(cfg.EFConstructionValues) == 0 {
+		cfg.EFConstructionValues = []int{64, 128, 256}
+	}
+	if len(cfg.EFSearchValues) == 0 {
+		cfg.EFSearchValues = []int{16, 32, 64, 128, 256}
+	}
+	if cfg.MaxTrials <= 0 {
+		cfg.MaxTrials = 48
+	}
+	if cfg.MaxDuration <= 0 {
+		cfg.MaxDuration = 60 * time.Second
+	}
+
+	// Sweep cheapest settings first so time-bounded runs still cover the low end
+	cfg.MValues = sortedCopy(cfg.MValues)
+	cfg.EFConstructionValues = sortedCopy(cfg.EFConstructionValues)
+	cfg.EFSearchValues = sortedCopy(cfg.EFSearchValues)
+
+	return &ANNTuner{config: cfg, factory: factory}
+}
+
+// Tune evaluates candidate parameters against the sample and returns the recommendation.
+// Among trials meeting TargetRecall the lowest mean latency wins; if none do, the highest recall wins.
+func (t *ANNTuner) Tune(ctx context.Context, sample ANNSample) (*ANNTuningResult, error) {
+	if t.factory == nil {
+		return nil, fmt.Errorf("index factory cannot be nil")
+	}
+	if len(sample.Vectors) == 0 || len(sample.Vectors) != len(sample.IDs) {
+		return nil, fmt.Errorf("sample must contain one ID per vector")
+	}
+	if len(sample.Queries) == 0 {
+		return nil, fmt.Errorf("sample must contain at least one query")
+	}
+
+	groundTruth := sample.GroundTruth
+	if groundTruth == nil {
+		groundTruth = exactNeighbors(sample, t.config.K)
+	}
+	if len(groundTruth) != len(sample.Queries) {
+		return nil, fmt.Errorf("ground truth has %d entries for %d queries", len(groundTruth), len(sample.Queries))
+	}
+
+	deadline := time.Now().Add(t.config.MaxDuration)
+	result := &ANNTuningResult{}
+
+sweep:
+	for _, m := range t.config.MValues {
+		for _, efc := range t.config.EFConstructionValues {
+			for _, efs := range t.config.EFSearchValues {
+				if len(result.Trials) >= t.config.MaxTrials {
+					break sweep
+				}
+				if time.Now().After(deadline) {
+					result.TimedOut = true
+					break sweep
+				}
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
+				trial, err := t.evaluate(ctx, ANNParams{M: m, EFConstruction: efc, EFSearch: efs}, sample, groundTruth)
+				if err != nil {
+					return nil, err
+				}
+				result.Trials = append(result.Trials, trial)
+			}
+		}
+	}
+
+	if len(result.Trials) == 0 {
+		return nil, fmt.Errorf("no parameter sets evaluated within bounds")
+	}
+
+	best := result.Trials[0]
+	for _, trial := range result.Trials[1:] {
+		if betterTrial(trial, best, t.config.TargetRecall) {
+			best = trial
+		}
+	}
+	result.Best = best.Params
+	result.Recall = best.Recall
+	result.MeanLatency = best.MeanLatency
+	result.MetTarget = best.Recall >= t.config.TargetRecall
+
+	return result, nil
+}
+
+// evaluate builds an index for params, loads the sample and measures recall@K and latency
+func (t *ANNTuner) evaluate(ctx context.Context, params ANNParams, sample ANNSample, groundTruth [][]string) (ANNTrial, error) {
+	index, err := t.factory(ctx, params)
+	if err != nil {
+		return ANNTrial{}, fmt.Errorf("failed to build index for %+v: %w", params, err)
+	}
+	defer index.Close()
+
+	for i, vector := range sample.Vectors {
+		if err := index.Upsert(ctx, sample.IDs[i], vector); err != nil {
+			return ANNTrial{}, fmt.Errorf("failed to load vector %s: %w", sample.IDs[i], err)
+		}
+	}
+
+	var hits, total int
+	var elapsed time.Duration
+	for i, query := range sample.Queries {
+		start := time.Now()
+		results, err := index.Query(ctx, query, t.config.K)
+		elapsed += time.Since(start)
+		if err != nil {
+			return ANNTrial{}, fmt.Errorf("failed to query index for %+v: %w", params, err)
+		}
+
+		expected := groundTruth[i]
+		if len(expected) > t.config.K {
+			expected = expected[:t.config.K]
+		}
+		want := make(map[string]bool, len(expected))
+		for _, id := range expected {
+			want[id] = true
+		}
+		for _, r := range results {
+			if want[r.ID] {
+				hits++
+				delete(want, r.ID)
+			}
+		}
+		total += len(expected)
+	}
+
+	trial := ANNTrial{
+		Params:      params,
+		MeanLatency: elapsed / time.Duration(len(sample.Queries)),
+	}
+	if total > 0 {
+		trial.Recall = float64(hits) / float64(total)
+	}
+	return trial, nil
+}
+
+// betterTrial reports whether a should be preferred over b
+func betterTrial(a, b ANNTrial, target float64) bool {
+	aMet, bMet := a.Recall >= target, b.Recall >= target
+	switch {
+	case aMet != bMet:
+		return aMet
+	case !aMet:
+		return a.Recall > b.Recall
+	case a.MeanLatency != b.MeanLatency:
+		return a.MeanLatency < b.MeanLatency
+	default:
+		// Equal latency: prefer cheaper search, then a smaller graph
+		if a.Params.EFSearch != b.Params.EFSearch {
+			return a.Params.EFSearch < b.Params.EFSearch
+		}
+		return a.Params.M < b.Params.M
+	}
+}
+
+// exactNeighbors computes brute-force cosine top-k IDs for every query
+func exactNeighbors(sample ANNSample, k int) [][]string {
+	truth := make([][]string, len(sample.Queries))
+	for qi, query := range sample.Queries {
+		order := make([]int, len(sample.Vectors))
+		scores := make([]float64, len(sample.Vectors))
+		for i, vector := range sample.Vectors {
+			order[i] = i
+			scores[i] = cosineSimilarity(query, vector)
+		}
+		sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+		n := min(k, len(order))
+		ids := make([]string, n)
+		for i := 0; i < n; i++ {
+			ids[i] = sample.IDs[order[i]]
+		}
+		truth[qi] = ids
+	}
+	return truth
+}
+
+// Apply writes the recommended parameters back to the memory config
+func (r *ANNTuningResult) Apply(cfg *config.MemoryConfig) {
+	if r == nil || cfg == nil {
+		return
+	}
+	cfg.HNSWM = r.Best.M
+	cfg.HNSWEFConstruction = r.Best.EFConstruction
+	cfg.HNSWEFSearch = r.Best.EFSearch
+}
+
+// sortedCopy returns an ascending copy so caller slices are left untouched
+func sortedCopy(values []int) []int {
+	out := append([]int(nil), values...)
+	sort.Ints(out)
+	return out
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probeIndex is an approximate in-memory index whose recall grows with efSearch:
+// each query scores only the first efSearch vectors of a fixed probe order.
+type probeIndex struct {
+	ids     []string
+	vectors [][]float64
+	params  ANNParams
+}
+
+func (p *probeIndex) Upsert(ctx context.Context, id string, vector []float64) error {
+	p.ids = append(p.ids, id)
+	p.vectors = append(p.vectors, vector)
+	return nil
+}
+
+func (p *probeIndex) Query(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	budget := min(len(p.ids), p.params.EFSearch*p.params.M/8)
+	results := make([]SearchResult, 0, budget)
+	for i := 0; i < budget; i++ {
+		results = append(results, SearchResult{ID: p.ids[i], Score: cosineSimilarity(query, p.vectors[i])})
+	}
+	sort.Slice(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+func (p *probeIndex) Delete(ctx context.Context, id string) error { return nil }
+func (p *probeIndex) Clear(ctx context.Context) error             { p.ids, p.vectors = nil, nil; return nil }
+func (p *probeIndex) Close() error                                { return nil }
+
+// syntheticSample builds a random unit-vector corpus and queries
+func syntheticSample(n, queries, dim int) ANNSample {
+	rng := rand.New(rand.NewSource(42))
+	randomUnit := func() []float64 {
+		v := make([]float64, dim)
+		var norm float64
+		for i := range v {
+			v[i] = rng.NormFloat64()
+			norm += v[i] * v[i]
+		}
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] /= norm
+		}
+		return v
+	}
+
+	sample := ANNSample{}
+	for i := 0; i < n; i++ {
+		sample.IDs = append(sample.IDs, fmt.Sprintf("vec-%d", i))
+		sample.Vectors = append(sample.Vectors, randomUnit())
+	}
+	for i := 0; i < queries; i++ {
+		sample.Queries = append(sample.Queries, randomUnit())
+	}
+	return sample
+}
+
+func TestANNTuner_MeetsTargetRecall(t *testing.T) {
+	sample := syntheticSample(200, 20, 16)
+	factory := func(ctx context.Context, params ANNParams) (VectorIndex, error) {
+		return &probeIndex{params: params}, nil
+	}
+
+	tuner := NewANNTuner(ANNTunerConfig{
+		TargetRecall:         0.9,
+		K:                    5,
+		MValues:              []int{16, 8},
+		EFConstructionValues: []int{64},
+		EFSearchValues:       []int{256, 16, 64, 128},
+		MaxDuration:          10 * time.Second,
+	}, factory)
+
+	result, err := tuner.Tune(context.Background(), sample)
+	require.NoError(t, err)
+
+	assert.True(t, result.MetTarget)
+	assert.GreaterOrEqual(t, result.Recall, 0.9)
+	assert.Len(t, result.Trials, 8)
+	assert.False(t, result.TimedOut)
+
+	// Low-ef trials scan too little of the corpus to reach the target
+	for _, trial := range result.Trials {
+		if trial.Params.EFSearch*trial.Params.M/8 < 100 {
+			assert.Less(t, trial.Recall, 0.9, "params %+v", trial.Params)
+		}
+	}
+
+	cfg := &config.MemoryConfig{}
+	result.Apply(cfg)
+	assert.Equal(t, result.Best.M, cfg.HNSWM)
+	assert.Equal(t, result.Best.EFConstruction, cfg.HNSWEFConstruction)
+	assert.Equal(t, result.Best.EFSearch, cfg.HNSWEFSearch)
+}
+
+func TestANNTuner_RespectsMaxTrials(t *testing.T) {
+	sample := syntheticSample(50, 5, 8)
+	built := 0
+	factory := func(ctx context.Context, params ANNParams) (VectorIndex, error) {
+		built++
+		return &probeIndex{params: params}, nil
+	}
+
+	result, err := NewANNTuner(ANNTunerConfig{MaxTrials: 3}, factory).Tune(context.Background(), sample)
+	require.NoError(t, err)
+	assert.Len(t, result.Trials, 3)
+	assert.Equal(t, 3, built)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// defaultChunkSize is the target chunk size in tokens when none is configured
+const defaultChunkSize = 256
+
+// paragraphBreak matches blank lines separating paragraphs
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// ChunkOptions controls how ChunkText splits text
+type ChunkOptions struct {
+	Size         int              // Target tokens per chunk; defaults to 256
+	Overlap      int              // Tokens repeated from the end of the previous chunk; must be below Size
+	TokenCounter func(string) int // Estimates tokens for a string; defaults to ~4 chars per token
+}
+
+// chunkUnit is a sentence, or a piece of an oversized sentence
+type chunkUnit struct {
+	text           string
+	paragraphStart bool
+}
+
+// ChunkText splits text into chunks of at most opts.Size tokens on paragraph and
+// sentence boundaries. Consecutive chunks share trailing sentences totalling at
+// most opts.Overlap tokens. Sentences longer than a chunk are split on words.
+func ChunkText(text string, opts ChunkOptions) []string {
+	opts = opts.withDefaults()
+
+	var chunks []string
+	var current []chunkUnit
+	fresh := false // current holds more than the overlap carried from the previous chunk
+
+	for _, unit := range splitChunkUnits(text, opts) {
+		for len(current) > 0 && opts.TokenCounter(joinChunkUnits(append(current[:len(current):len(current)], unit))) > opts.Size {
+			if fresh {
+				chunks = append(chunks, joinChunkUnits(current))
+				current = overlapTail(current, opts)
+				fresh = false
+				continue
+			}
+			// The carried overlap alone leaves no room; drop it from the front
+			current = current[1:]
+		}
+		current = append(current, unit)
+		fresh = true
+	}
+	if fresh {
+		chunks = append(chunks, joinChunkUnits(current))
+	}
+
+	return chunks
+}
+
+// withDefaults fills unset options and keeps the overlap below the chunk size
+func (o ChunkOptions) withDefaults() ChunkOptions {
+	if o.Size <= 0 {
+		o.Size = defaultChunkSize
+	}
+	if o.Overlap < 0 {
+		o.Overlap = 0
+	}
+	if o.Overlap >= o.Size {
+		o.Overlap = o.Size / 2
+	}
+	if o.TokenCounter == nil {
+		o.TokenCounter = estimateTokens
+	}
+	return o
+}
+
+// estimateTokens approximates tokens at ~4 chars per token
+func estimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// splitChunkUnits breaks text into sentence units no larger than opts.Size tokens
+func splitChunkUnits(text string, opts ChunkOptions) []chunkUnit {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var units []chunkUnit
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		paragraphStart := true
+		for _, sentence := range splitSentences(paragraph) {
+			for _, piece := range splitOversized(sentence, opts) {
+				units = append(units, chunkUnit{text: piece, paragraphStart: paragraphStart})
+				paragraphStart = false
+			}
+		}
+	}
+	return units
+}
+
+// splitSentences splits a paragraph after '.', '!' or '?' followed by whitespace,
+// collapsing runs of whitespace inside each sentence
+func splitSentences(paragraph string) []string {
+	var sentences []string
+	runes := []rune(paragraph)
+	start := 0
+	for i, r := range runes {
+		if (r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])) {
+			if sentence := strings.Join(strings.Fields(string(runes[start:i+1])), " "); sentence != "" {
+				sentences = append(sentences, sentence)
+			}
+			start = i + 1
+		}
+	}
+	if sentence := strings.Join(strings.Fields(string(runes[start:])), " "); sentence != "" {
+		sentences = append(sentences, sentence)
+	}
+	return sentences
+}
+
+// splitOversized splits a sentence exceeding opts.Size tokens into word runs that fit;
+// a single word larger than a chunk is kept whole
+func splitOversized(sentence string, opts ChunkOptions) []string {
+	if opts.TokenCounter(sentence) <= opts.Size {
+		return []string{sentence}
+	}
+
+	var pieces []string
+	var current []string
+	for _, word := range strings.Fields(sentence) {
+		if len(current) > 0 && opts.TokenCounter(strings.Join(append(current, word), " ")) > opts.Size {
+			pieces = append(pieces, strings.Join(current, " "))
+			current = current[:0]
+		}
+		current = append(current, word)
+	}
+	if len(current) > 0 {
+		pieces = append(pieces, strings.Join(current, " "))
+	}
+	return pieces
+}
+
+// overlapTail returns the trailing units of a chunk totalling at most opts.Overlap tokens
+func overlapTail(units []chunkUnit, opts ChunkOptions) []chunkUnit {
+	start := len(units)
+	for start > 0 && opts.TokenCounter(joinChunkUnits(units[start-1:])) <= opts.Overlap {
+		start--
+	}
+	return append([]chunkUnit(nil), units[start:]...)
+}
+
+// joinChunkUnits joins sentences with spaces and paragraphs with blank lines
+func joinChunkUnits(units []chunkUnit) string {
+	var b strings.Builder
+	for i, unit := range units {
+		if i > 0 {
+			if unit.paragraphStart {
+				b.WriteString("\n\n")
+			} else {
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(unit.text)
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longDocument builds paragraphs of numbered sentences
+func longDocument(paragraphs, sentencesPerParagraph int) string {
+	var b strings.Builder
+	n := 0
+	for p := 0; p < paragraphs; p++ {
+		if p > 0 {
+			b.WriteString("\n\n")
+		}
+		for s := 0; s < sentencesPerParagraph; s++ {
+			if s > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "Sentence %02d covers topic %02d.", n, n)
+			n++
+		}
+	}
+	return b.String()
+}
+
+func TestChunkText_RespectsSizeAndOverlap(t *testing.T) {
+	opts := ChunkOptions{Size: 24, Overlap: 8}
+	chunks := ChunkText(longDocument(6, 5), opts)
+	require.Greater(t, len(chunks), 3)
+
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, estimateTokens(chunk), opts.Size, "chunk %d exceeds size", i)
+		assert.False(t, strings.HasPrefix(chunk, " ") || strings.HasSuffix(chunk, " "), "chunk %d has untrimmed edges", i)
+	}
+
+	// Each chunk opens with the last sentence of the previous chunk, which fits the overlap
+	for i := 1; i < len(chunks); i++ {
+		prev := splitSentences(strings.ReplaceAll(chunks[i-1], "\n\n", " "))
+		carried := prev[len(prev)-1]
+		assert.LessOrEqual(t, estimateTokens(carried), opts.Overlap)
+		assert.True(t, strings.HasPrefix(chunks[i], carried), "chunk %d should start with %q", i, carried)
+	}
+
+	// Every sentence survives in order
+	joined := strings.Join(chunks, " ")
+	last := -1
+	for n := 0; n < 30; n++ {
+		idx := strings.Index(joined, fmt.Sprintf("Sentence %02d ", n))
+		require.GreaterOrEqual(t, idx, 0, "sentence %d missing", n)
+		assert.Greater(t, idx, last)
+		last = idx
+	}
+}
+
+func TestChunkText_KeepsParagraphBreaks(t *testing.T) {
+	chunks := ChunkText("First para one. First para two.\n\nSecond para.", ChunkOptions{Size: 100})
+	require.Len(t, chunks, 1)
+	assert.Equal(t, "First para one. First para two.\n\nSecond para.", chunks[0])
+}
+
+func TestChunkText_SplitsOversizedSentences(t *testing.T) {
+	sentence := strings.TrimSpace(strings.Repeat("word ", 60)) + "."
+	chunks := ChunkText(sentence, ChunkOptions{Size: 10})
+	require.Greater(t, len(chunks), 1)
+	for _, chunk := range chunks {
+		assert.LessOrEqual(t, estimateTokens(chunk), 10)
+	}
+	assert.Equal(t, 60, len(strings.Fields(strings.Join(chunks, " "))))
+}
+
+func TestChunkText_EmptyAndDefaults(t *testing.T) {
+	assert.Empty(t, ChunkText("  \n\n  ", ChunkOptions{}))
+
+	opts := ChunkOptions{Overlap: 500}.withDefaults()
+	assert.Equal(t, defaultChunkSize, opts.Size)
+	assert.Less(t, opts.Overlap, opts.Size)
+}
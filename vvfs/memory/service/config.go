@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/database"
 )
 
@@ -15,6 +17,7 @@ type Config struct {
 	MaxIdleConns     int
 	ConnMaxIdleSec   int
 	ConnMaxLifeSec   int
+	QueryTimeout     time.Duration
 }
 
 // ToInternal converts to internal database config
@@ -29,5 +32,6 @@ func (c *Config) ToInternal() *database.Config {
 		MaxIdleConns:     c.MaxIdleConns,
 		ConnMaxIdleSec:   c.ConnMaxIdleSec,
 		ConnMaxLifeSec:   c.ConnMaxLifeSec,
+		QueryTimeout:     c.QueryTimeout,
 	}
 }
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CrossEncoderReranker reorders results by pairwise relevance scores, caching each
+// (query, candidate) score so repeated or paginated searches do not re-score
+type CrossEncoderReranker struct {
+	scorer RelevanceScorer
+	cache  *rerankCache
+}
+
+// NewCrossEncoderReranker creates a reranker with an LRU score cache.
+// cacheSize <= 0 defaults to 10000 entries; ttl <= 0 means entries never expire.
+func NewCrossEncoderReranker(scorer RelevanceScorer, cacheSize int, ttl time.Duration) *CrossEncoderReranker {
+	if cacheSize <= 0 {
+		cacheSize = 10000
+	}
+	return &CrossEncoderReranker{
+		scorer: scorer,
+		cache:  newRerankCache(cacheSize, ttl),
+	}
+}
+
+// Rerank scores results against the query, serving cached pairs and scoring the rest in one call
+func (r *CrossEncoderReranker) Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	reranked := make([]SearchResult, len(results))
+	copy(reranked, results)
+
+	keys := make([]string, len(reranked))
+	var missIdx []int
+	for i, result := range reranked {
+		keys[i] = rerankKey(query, result)
+		if score, ok := r.cache.get(keys[i]); ok {
+			reranked[i].Score = score
+		} else {
+			missIdx = append(missIdx, i)
+		}
+	}
+
+	if len(missIdx) > 0 {
+		misses := make([]SearchResult, len(missIdx))
+		for j, i := range missIdx {
+			misses[j] = reranked[i]
+		}
+
+		scores, err := r.scorer.ScorePairs(ctx, query, misses)
+		if err != nil {
+			return nil, fmt.Errorf("failed to score candidates: %w", err)
+		}
+		if len(scores) != len(misses) {
+			return nil, fmt.Errorf("scorer returned %d scores for %d candidates", len(scores), len(misses))
+		}
+
+		for j, i := range missIdx {
+			reranked[i].Score = scores[j]
+			r.cache.put(keys[i], reranked[i].ID, scores[j])
+		}
+	}
+
+	sortResultsStable(reranked)
+	return reranked, nil
+}
+
+// InvalidateCandidate drops every cached score for a candidate whose content changed
+func (r *CrossEncoderReranker) InvalidateCandidate(id string) {
+	r.cache.invalidate(id)
+}
+
+// rerankKey hashes the query with the candidate ID and text so edited candidates miss the cache
+func rerankKey(query string, result SearchResult) string {
+	text, _ := result.Metadata["text"].(string)
+	h := sha256.New()
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write([]byte(result.ID))
+	h.Write([]byte{0})
+	h.Write([]byte(text))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// rerankCache is a TTL-bounded LRU of relevance scores with a per-candidate key index
+type rerankCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+	order    *list.List // front = most recently used
+	entries  map[string]*list.Element
+	byID     map[string]map[string]struct{}
+}
+
+type rerankEntry struct {
+	key       string
+	id        string
+	score     float64
+	expiresAt time.Time
+}
+
+func newRerankCache(capacity int, ttl time.Duration) *rerankCache {
+	return &rerankCache{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		byID:     make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *rerankCache) get(key string) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return 0, false
+	}
+	entry := elem.Value.(*rerankEntry)
+	if c.ttl > 0 && c.now().After(entry.expiresAt) {
+		c.remove(elem)
+		return 0, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.score, true
+}
+
+func (c *rerankCache) put(key, id string, score float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*rerankEntry)
+		entry.score = score
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&rerankEntry{key: key, id: id, score: score, expiresAt: expiresAt})
+	if c.byID[id] == nil {
+		c.byID[id] = make(map[string]struct{})
+	}
+	c.byID[id][key] = struct{}{}
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *rerankCache) invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.byID[id] {
+		if elem, ok := c.entries[key]; ok {
+			c.remove(elem)
+		}
+	}
+	delete(c.byID, id)
+}
+
+// remove unlinks an entry; caller holds mu
+func (c *rerankCache) remove(elem *list.Element) {
+	entry := elem.Value.(*rerankEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.key)
+	if keys := c.byID[entry.id]; keys != nil {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.byID, entry.id)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingScorer scores by substring overlap and counts scored pairs
+type countingScorer struct {
+	calls int
+	pairs int
+}
+
+func (s *countingScorer) ScorePairs(ctx context.Context, query string, candidates []SearchResult) ([]float64, error) {
+	s.calls++
+	s.pairs += len(candidates)
+	scores := make([]float64, len(candidates))
+	for i, c := range candidates {
+		text, _ := c.Metadata["text"].(string)
+		if strings.Contains(text, query) {
+			scores[i] = 1
+		}
+	}
+	return scores, nil
+}
+
+func rerankCandidates() []SearchResult {
+	return []SearchResult{
+		{ID: "a", Score: 0.9, Metadata: map[string]interface{}{"text": "unrelated"}},
+		{ID: "b", Score: 0.1, Metadata: map[string]interface{}{"text": "golang channels"}},
+	}
+}
+
+// TestCrossEncoderReranker_CachesPairs tests that repeated pairs skip scoring and new queries do not
+func TestCrossEncoderReranker_CachesPairs(t *testing.T) {
+	ctx := context.Background()
+	scorer := &countingScorer{}
+	reranker := NewCrossEncoderReranker(scorer, 100, time.Hour)
+
+	results, err := reranker.Rerank(ctx, "golang", rerankCandidates())
+	require.NoError(t, err)
+	assert.Equal(t, "b", results[0].ID)
+	assert.Equal(t, 1, scorer.calls)
+
+	// Identical pairs are served from cache
+	results, err = reranker.Rerank(ctx, "golang", rerankCandidates())
+	require.NoError(t, err)
+	assert.Equal(t, "b", results[0].ID)
+	assert.Equal(t, 1, scorer.calls)
+
+	// A different query busts the cache
+	_, err = reranker.Rerank(ctx, "channels", rerankCandidates())
+	require.NoError(t, err)
+	assert.Equal(t, 2, scorer.calls)
+
+	// Only the invalidated candidate is re-scored
+	reranker.InvalidateCandidate("b")
+	_, err = reranker.Rerank(ctx, "golang", rerankCandidates())
+	require.NoError(t, err)
+	assert.Equal(t, 3, scorer.calls)
+	assert.Equal(t, 5, scorer.pairs)
+}
+
+// TestCrossEncoderReranker_ExpiresAndEvicts tests TTL expiry and LRU capacity
+func TestCrossEncoderReranker_ExpiresAndEvicts(t *testing.T) {
+	ctx := context.Background()
+	scorer := &countingScorer{}
+	reranker := NewCrossEncoderReranker(scorer, 1, time.Minute)
+	now := time.Now()
+	reranker.cache.now = func() time.Time { return now }
+
+	candidate := rerankCandidates()[:1]
+	_, err := reranker.Rerank(ctx, "golang", candidate)
+	require.NoError(t, err)
+	_, err = reranker.Rerank(ctx, "golang", candidate)
+	require.NoError(t, err)
+	assert.Equal(t, 1, scorer.calls)
+
+	now = now.Add(2 * time.Minute)
+	_, err = reranker.Rerank(ctx, "golang", candidate)
+	require.NoError(t, err)
+	assert.Equal(t, 2, scorer.calls)
+
+	// Capacity 1: scoring a second candidate evicts the first
+	_, err = reranker.Rerank(ctx, "golang", rerankCandidates()[1:])
+	require.NoError(t, err)
+	_, err = reranker.Rerank(ctx, "golang", candidate)
+	require.NoError(t, err)
+	assert.Equal(t, 4, scorer.calls)
+}
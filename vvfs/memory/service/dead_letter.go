@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Dead-letter statuses
+const (
+	FailedIngestPending = "pending" // will be retried once due
+	FailedIngestFailed  = "failed"  // exhausted its attempts
+)
+
+// Retry defaults for failed ingests
+const (
+	defaultIngestMaxAttempts  = 5
+	defaultIngestRetryBackoff = 30 * time.Second
+	maxIngestRetryBackoff     = time.Hour
+)
+
+// FailedIngest is a dead-lettered ingestion task
+type FailedIngest struct {
+	Item          *MemoryItem
+	Episode       *Episode
+	LastError     string
+	Attempts      int
+	Status        string
+	NextAttemptAt time.Time
+}
+
+// DeadLetterStore persists failed ingestion tasks in the failed_ingests table
+type DeadLetterStore struct {
+	db          *sql.DB
+	maxAttempts int
+	backoff     time.Duration
+	now         func() time.Time
+}
+
+// NewDeadLetterStore creates a dead-letter store; zero limits use the defaults
+func NewDeadLetterStore(db *sql.DB, maxAttempts int, backoff time.Duration) *DeadLetterStore {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultIngestMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultIngestRetryBackoff
+	}
+	return &DeadLetterStore{db: db, maxAttempts: maxAttempts, backoff: backoff, now: time.Now}
+}
+
+// Record stores a failed attempt, scheduling the next retry or marking the item
+// permanently failed once maxAttempts is reached
+func (s *DeadLetterStore) Record(ctx context.Context, task *IngestionTask, cause error) error {
+	itemJSON, err := json.Marshal(task.Item)
+	if err != nil {
+		return fmt.Errorf("failed to marshal item: %w", err)
+	}
+	var episodeJSON sql.NullString
+	if task.Episode != nil {
+		data, err := json.Marshal(task.Episode)
+		if err != nil {
+			return fmt.Errorf("failed to marshal episode: %w", err)
+		}
+		episodeJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	attempts := 1
+	err = s.db.QueryRowContext(ctx, `SELECT attempts + 1 FROM failed_ingests WHERE item_id = ?`, task.Item.ID).Scan(&attempts)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read failed ingest: %w", err)
+	}
+
+	status := FailedIngestPending
+	if attempts >= s.maxAttempts {
+		status = FailedIngestFailed
+	}
+	next := s.now().Add(s.retryDelay(attempts))
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO failed_ingests (item_id, item_json, episode_json, last_error, attempts, status, next_attempt_unix)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(item_id) DO UPDATE SET
+			item_json = excluded.item_json,
+			episode_json = excluded.episode_json,
+			last_error = excluded.last_error,
+			attempts = excluded.attempts,
+			status = excluded.status,
+			next_attempt_unix = excluded.next_attempt_unix,
+			updated_at = CURRENT_TIMESTAMP
+	`, task.Item.ID, string(itemJSON), episodeJSON, cause.Error(), attempts, status, next.Unix())
+	if err != nil {
+		return fmt.Errorf("failed to record failed ingest: %w", err)
+	}
+	return nil
+}
+
+// retryDelay doubles the base backoff per attempt, capped at an hour
+func (s *DeadLetterStore) retryDelay(attempts int) time.Duration {
+	delay := s.backoff
+	for i := 1; i < attempts && delay < maxIngestRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxIngestRetryBackoff)
+}
+
+// Due returns pending entries whose retry time has passed, oldest first
+func (s *DeadLetterStore) Due(ctx context.Context, limit int) ([]*FailedIngest, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT item_json, episode_json, last_error, attempts, status, next_attempt_unix
+		FROM failed_ingests
+		WHERE status = ? AND next_attempt_unix <= ?
+		ORDER BY next_attempt_unix
+		LIMIT ?
+	`, FailedIngestPending, s.now().Unix(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed ingests: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*FailedIngest
+	for rows.Next() {
+		entry, err := scanFailedIngest(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Get returns the dead-letter entry for an item, or nil if there is none
+func (s *DeadLetterStore) Get(ctx context.Context, itemID string) (*FailedIngest, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT item_json, episode_json, last_error, attempts, status, next_attempt_unix
+		FROM failed_ingests WHERE item_id = ?
+	`, itemID)
+	entry, err := scanFailedIngest(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return entry, err
+}
+
+// Resolve removes an item after it was ingested successfully
+func (s *DeadLetterStore) Resolve(ctx context.Context, itemID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM failed_ingests WHERE item_id = ?`, itemID); err != nil {
+		return fmt.Errorf("failed to resolve failed ingest: %w", err)
+	}
+	return nil
+}
+
+// scanFailedIngest decodes one failed_ingests row
+func scanFailedIngest(row interface{ Scan(dest ...any) error }) (*FailedIngest, error) {
+	var itemJSON string
+	var episodeJSON sql.NullString
+	var nextUnix int64
+	entry := &FailedIngest{}
+	if err := row.Scan(&itemJSON, &episodeJSON, &entry.LastError, &entry.Attempts, &entry.Status, &nextUnix); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan failed ingest: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(itemJSON), &entry.Item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+	}
+	if episodeJSON.Valid {
+		if err := json.Unmarshal([]byte(episodeJSON.String), &entry.Episode); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal episode: %w", err)
+		}
+	}
+	entry.NextAttemptAt = time.Unix(nextUnix, 0)
+	return entry, nil
+}
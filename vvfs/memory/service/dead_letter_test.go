@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "github.com/tursodatabase/go-libsql"
+)
+
+// flakyEmbedder fails its first failures calls
+type flakyEmbedder struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (e *flakyEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, fmt.Errorf("embedder unavailable (call %d)", e.calls)
+	}
+	result := make([][]float64, len(texts))
+	for i := range texts {
+		result[i] = []float64{1, 0, 0}
+	}
+	return result, nil
+}
+
+func (e *flakyEmbedder) Dimension() int { return 3 }
+
+// recordingIndex keeps upserted vectors in memory
+type recordingIndex struct {
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+func (r *recordingIndex) Upsert(ctx context.Context, id string, vector []float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.vectors[id] = vector
+	return nil
+}
+
+// Query ranks stored vectors by cosine similarity, reported as the score
+func (r *recordingIndex) Query(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	results := make([]SearchResult, 0, len(r.vectors))
+	for id, vector := range r.vectors {
+		results = append(results, SearchResult{ID: id, Score: cosineSimilarity(query, vector)})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results[:min(k, len(results))], nil
+}
+func (r *recordingIndex) Delete(ctx context.Context, id string) error { return nil }
+func (r *recordingIndex) Clear(ctx context.Context) error             { return nil }
+func (r *recordingIndex) Close() error                                { return nil }
+
+func (r *recordingIndex) has(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.vectors[id]
+	return ok
+}
+
+// openTestDeadLetterDB opens an in-memory libSQL database with the failed_ingests table
+func openTestDeadLetterDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("libsql", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // each in-memory connection is a separate database
+
+	_, err = db.Exec(`CREATE TABLE failed_ingests (
+		item_id TEXT PRIMARY KEY,
+		item_json TEXT NOT NULL,
+		episode_json TEXT,
+		last_error TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 1,
+		status TEXT NOT NULL DEFAULT 'pending',
+		next_attempt_unix INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// newDeadLetterIngester wires an ingester to a dead-letter store driven by a fake clock
+func newDeadLetterIngester(t *testing.T, embedder Embedder, maxAttempts int) (*Ingester, *recordingIndex, *DeadLetterStore, *time.Time) {
+	index := &recordingIndex{vectors: make(map[string][]float64)}
+	ing := NewIngester(&config.MemoryConfig{IngestBatchSize: 1}, index, nil, nil, nil, NewMetricsCollector())
+	t.Cleanup(func() { ing.Stop() })
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewDeadLetterStore(openTestDeadLetterDB(t), maxAttempts, time.Minute)
+	store.now = func() time.Time { return now }
+
+	ing.SetEmbedder(embedder)
+	ing.SetDeadLetterStore(store)
+	return ing, index, store, &now
+}
+
+func TestIngester_DeadLettersAndRetriesFailedEmbedding(t *testing.T) {
+	ctx := context.Background()
+	ing, index, store, now := newDeadLetterIngester(t, &flakyEmbedder{failures: 2}, 5)
+
+	require.NoError(t, ing.IngestMemoryItem(ctx, &MemoryItem{ID: "item-1", Type: "note", Text: "hello"}))
+	require.NoError(t, ing.Drain(ctx))
+
+	entry, err := store.Get(ctx, "item-1")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, 1, entry.Attempts)
+	assert.Equal(t, FailedIngestPending, entry.Status)
+	assert.Contains(t, entry.LastError, "embedding failed")
+	assert.Equal(t, "hello", entry.Item.Text)
+	assert.False(t, index.has("item-1"))
+
+	// Not due until the backoff elapses
+	recovered, err := ing.RetryFailedIngests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+
+	// Second failure reschedules with a doubled backoff
+	*now = now.Add(time.Minute)
+	recovered, err = ing.RetryFailedIngests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+	entry, err = store.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Equal(t, now.Add(2*time.Minute).Unix(), entry.NextAttemptAt.Unix())
+
+	*now = now.Add(2 * time.Minute)
+	recovered, err = ing.RetryFailedIngests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+	assert.True(t, index.has("item-1"))
+
+	entry, err = store.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+}
+
+func TestIngester_MarksPermanentlyFailedAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	ing, index, store, now := newDeadLetterIngester(t, &flakyEmbedder{failures: 100}, 2)
+
+	require.NoError(t, ing.IngestMemoryItem(ctx, &MemoryItem{ID: "item-1", Type: "note", Text: "hello"}))
+	require.NoError(t, ing.Drain(ctx))
+
+	*now = now.Add(time.Hour)
+	recovered, err := ing.RetryFailedIngests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+
+	entry, err := store.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.Equal(t, FailedIngestFailed, entry.Status)
+
+	// Permanently failed items are no longer retried
+	*now = now.Add(24 * time.Hour)
+	recovered, err = ing.RetryFailedIngests(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, recovered)
+	entry, err = store.Get(ctx, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Attempts)
+	assert.False(t, index.has("item-1"))
+}
+
+func TestIngester_CountsTasksThatCannotBeDeadLettered(t *testing.T) {
+	ctx := context.Background()
+	ing, _, store, _ := newDeadLetterIngester(t, &flakyEmbedder{failures: 100}, 5)
+
+	// Without its table the store cannot record the failure
+	_, err := store.db.Exec(`DROP TABLE failed_ingests`)
+	require.NoError(t, err)
+
+	require.NoError(t, ing.IngestMemoryItem(ctx, &MemoryItem{ID: "item-1", Type: "note", Text: "hello"}))
+	require.NoError(t, ing.Drain(ctx))
+
+	summary := ing.metrics.GetSummary()
+	assert.Equal(t, int64(1), summary.IngestErrors)
+	assert.Equal(t, int64(1), summary.DeadLetterFailures)
+}
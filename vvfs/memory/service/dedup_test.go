@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngester_SkipsNearDuplicates(t *testing.T) {
+	ctx := context.Background()
+	index := &recordingIndex{vectors: make(map[string][]float64)}
+	metrics := NewMetricsCollector()
+	ing := NewIngester(&config.MemoryConfig{IngestBatchSize: 1, DedupThreshold: 0.95}, index, nil, nil, nil, metrics)
+	defer ing.Stop()
+
+	original := &MemoryItem{ID: "original", Text: "The meeting moved to Tuesday", Embedding: []float64{1, 0, 0}}
+	reworded := &MemoryItem{ID: "reworded", Text: "The meeting was moved to Tuesday", Embedding: []float64{0.99, 0.05, 0}}
+	distinct := &MemoryItem{ID: "distinct", Text: "Buy more coffee", Embedding: []float64{0, 1, 0}}
+
+	for _, item := range []*MemoryItem{original, reworded, distinct} {
+		require.NoError(t, ing.IngestMemoryItem(ctx, item))
+		require.NoError(t, ing.Drain(ctx))
+	}
+
+	assert.True(t, index.has("original"))
+	assert.False(t, index.has("reworded"))
+	assert.True(t, index.has("distinct"))
+	assert.Nil(t, reworded.Metadata) // the caller's item is never written by workers
+
+	events := metrics.DedupEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, "reworded", events[0].ItemID)
+	assert.Equal(t, "original", events[0].CanonicalID)
+	assert.GreaterOrEqual(t, events[0].Similarity, 0.95)
+	assert.Equal(t, int64(1), metrics.GetSummary().DedupCount)
+}
+
+func TestIngester_DedupKeepsOneStoredRow(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	store := NewMemoryStoreImpl(db)
+	metrics := NewMetricsCollector()
+	ing := NewIngester(&config.MemoryConfig{IngestBatchSize: 1, DedupThreshold: 0.95}, NewFlatIndexImpl(db, 3), nil, nil, nil, metrics)
+	defer ing.Stop()
+	ing.SetMemoryStore(store)
+
+	original := &MemoryItem{ID: "original", Type: "note", Text: "The meeting moved to Tuesday", Embedding: []float64{1, 0, 0}}
+	reworded := &MemoryItem{ID: "reworded", Type: "note", Text: "The meeting was moved to Tuesday", Embedding: []float64{0.99, 0.05, 0}}
+
+	// Callers such as IngestDocument store the row before queueing the item
+	for _, item := range []*MemoryItem{original, reworded} {
+		require.NoError(t, store.PutItem(ctx, item))
+		require.NoError(t, ing.IngestMemoryItem(ctx, item))
+		require.NoError(t, ing.Drain(ctx))
+	}
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM memory_items`).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	_, err := store.GetItem(ctx, "reworded")
+	assert.Error(t, err)
+	canonical, err := store.GetItem(ctx, "original")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"reworded"}, canonical.Metadata["duplicates"])
+	assert.Nil(t, reworded.Metadata)
+
+	events := metrics.DedupEvents()
+	require.Len(t, events, 1)
+	assert.Equal(t, "reworded", events[0].ItemID)
+	assert.Equal(t, "original", events[0].CanonicalID)
+}
+
+func TestIngester_DedupOffByDefault(t *testing.T) {
+	ctx := context.Background()
+	index := &recordingIndex{vectors: make(map[string][]float64)}
+	metrics := NewMetricsCollector()
+	ing := NewIngester(&config.MemoryConfig{IngestBatchSize: 1}, index, nil, nil, nil, metrics)
+	defer ing.Stop()
+
+	for _, id := range []string{"a", "b"} {
+		require.NoError(t, ing.IngestMemoryItem(ctx, &MemoryItem{ID: id, Text: "same text", Embedding: []float64{1, 0, 0}}))
+		require.NoError(t, ing.Drain(ctx))
+	}
+
+	assert.True(t, index.has("a"))
+	assert.True(t, index.has("b"))
+	assert.Empty(t, metrics.DedupEvents())
+}
+
+func TestIngester_UpsertBySourceRef(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	store := NewMemoryStoreImpl(db)
+	index := &recordingIndex{vectors: make(map[string][]float64)}
+	embedder := &flakyEmbedder{}
+	ing := NewIngester(&config.MemoryConfig{IngestBatchSize: 1}, index, nil, nil, nil, NewMetricsCollector())
+	defer ing.Stop()
+	ing.SetEmbedder(embedder)
+	ing.SetMemoryStore(store)
+
+	countRows := func() int {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM memory_items WHERE source_ref = ?`, "notes/todo.md").Scan(&count))
+		return count
+	}
+	scan := func(id, text string) *MemoryItem {
+		return &MemoryItem{ID: id, Type: "file", Text: text, SourceRef: "notes/todo.md"}
+	}
+
+	first := scan("scan-1", "buy milk")
+	outcome, err := ing.UpsertBySourceRef(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, UpsertCreated, outcome)
+	assert.True(t, index.has("scan-1"))
+
+	// Re-ingesting the unchanged file writes nothing and is not re-embedded
+	unchanged := scan("scan-2", "buy milk")
+	outcome, err = ing.UpsertBySourceRef(ctx, unchanged)
+	require.NoError(t, err)
+	assert.Equal(t, UpsertUnchanged, outcome)
+	assert.Equal(t, "scan-1", unchanged.ID)
+	assert.Equal(t, 1, countRows())
+	assert.Equal(t, 1, embedder.calls)
+	assert.False(t, index.has("scan-2"))
+
+	// A changed file replaces the existing item instead of adding another
+	changed := scan("scan-3", "buy milk and eggs")
+	outcome, err = ing.UpsertBySourceRef(ctx, changed)
+	require.NoError(t, err)
+	assert.Equal(t, UpsertUpdated, outcome)
+	assert.Equal(t, "scan-1", changed.ID)
+	assert.Equal(t, 1, countRows())
+	assert.Equal(t, 2, embedder.calls)
+	assert.False(t, index.has("scan-3"))
+
+	stored, err := store.GetItem(ctx, "scan-1")
+	require.NoError(t, err)
+	assert.Equal(t, "buy milk and eggs", stored.Text)
+	assert.Equal(t, first.CreatedAt.Unix(), stored.CreatedAt.Unix())
+
+	_, err = ing.UpsertBySourceRef(ctx, &MemoryItem{ID: "loose", Text: "no source"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/models"
+)
+
+// DefaultEmbedderProvider explicitly selects the zero-vector DefaultEmbedder
+const DefaultEmbedderProvider = "default"
+
+// EmbedderConstructor builds an embedder from the embedding config
+type EmbedderConstructor func(cfg config.EmbeddingConfig) (Embedder, error)
+
+var (
+	embedderProvidersMu sync.RWMutex
+	embedderProviders   = map[string]EmbedderConstructor{
+		DefaultEmbedderProvider: func(cfg config.EmbeddingConfig) (Embedder, error) {
+			return NewDefaultEmbedder(), nil
+		},
+		"gguf":  newGGUFEmbedder,
+		"llama": newGGUFEmbedder,
+	}
+)
+
+// RegisterEmbedderProvider makes a provider selectable by EmbeddingConfig.Provider,
+// replacing any existing registration under the same name
+func RegisterEmbedderProvider(name string, constructor EmbedderConstructor) {
+	embedderProvidersMu.Lock()
+	defer embedderProvidersMu.Unlock()
+	embedderProviders[strings.ToLower(name)] = constructor
+}
+
+// NewEmbedder constructs the provider named by cfg.Provider.
+// Unknown providers and construction failures are errors; zero vectors are never substituted.
+func NewEmbedder(cfg config.EmbeddingConfig) (Embedder, error) {
+	name := strings.ToLower(strings.TrimSpace(cfg.Provider))
+	if name == "" {
+		return nil, fmt.Errorf("embedding provider cannot be empty")
+	}
+
+	embedderProvidersMu.RLock()
+	constructor, ok := embedderProviders[name]
+	embedderProvidersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("embedding provider %q is not registered (available: %s)", cfg.Provider, strings.Join(registeredEmbedderProviders(), ", "))
+	}
+
+	embedder, err := constructor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s embedder: %w", name, err)
+	}
+	return embedder, nil
+}
+
+// registeredEmbedderProviders lists provider names in sorted order
+func registeredEmbedderProviders() []string {
+	embedderProvidersMu.RLock()
+	defer embedderProvidersMu.RUnlock()
+	names := make([]string, 0, len(embedderProviders))
+	for name := range embedderProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ggufEmbedder adapts models.OpenEmbedProvider to the Embedder interface
+type ggufEmbedder struct {
+	provider     *models.OpenEmbedProvider
+	modelVersion string
+}
+
+// newGGUFEmbedder loads the GGUF embedding model at cfg.ModelPath
+func newGGUFEmbedder(cfg config.EmbeddingConfig) (Embedder, error) {
+	if cfg.ModelPath == "" {
+		return nil, fmt.Errorf("embedding model path cannot be empty")
+	}
+
+	provider, err := models.NewOpenEmbedProvider(cfg.ModelPath)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Pooling != "" {
+		if err := provider.SetPooling(cfg.Pooling); err != nil {
+			provider.Close()
+			return nil, err
+		}
+	}
+	if cfg.Dims > 0 {
+		provider.SetMatryoshkaDims(cfg.Dims)
+	}
+	if err := provider.SetDimsPolicy(cfg.DimsPolicy); err != nil {
+		provider.Close()
+		return nil, err
+	}
+	instructions := provider.GetEmbedInstructions()
+	if cfg.QueryInstruction != "" {
+		instructions.Query = cfg.QueryInstruction
+	}
+	if cfg.DocumentInstruction != "" {
+		instructions.Document = cfg.DocumentInstruction
+	}
+	provider.SetEmbedInstructions(instructions)
+
+	modelVersion := cfg.ModelVersion
+	if modelVersion == "" {
+		modelVersion = ggufModelVersion(cfg, provider.GetMatryoshkaDims())
+	}
+
+	return &ggufEmbedder{provider: provider, modelVersion: modelVersion}, nil
+}
+
+// ggufModelVersion derives a version tag from the model file, pooling and output dimension,
+// all of which change the vectors produced
+func ggufModelVersion(cfg config.EmbeddingConfig, dims int) string {
+	version := filepath.Base(cfg.ModelPath)
+	if cfg.Pooling != "" {
+		version += ":" + strings.ToLower(cfg.Pooling)
+	}
+	return fmt.Sprintf("%s@%d", version, dims)
+}
+
+// Embed embeds each text as a document with the underlying provider
+func (e *ggufEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return e.embed(ctx, texts, e.provider.EmbedDocument)
+}
+
+// EmbedQuery embeds each text as a search query with the underlying provider
+func (e *ggufEmbedder) EmbedQuery(ctx context.Context, texts []string) ([][]float64, error) {
+	return e.embed(ctx, texts, e.provider.EmbedQuery)
+}
+
+// embed converts each text's float32 embedding from embedFn to float64
+func (e *ggufEmbedder) embed(ctx context.Context, texts []string, embedFn func(context.Context, string) ([]float32, error)) ([][]float64, error) {
+	result := make([][]float64, len(texts))
+	for i, text := range texts {
+		vec, err := embedFn(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed text %d: %w", i, err)
+		}
+		out := make([]float64, len(vec))
+		for j, v := range vec {
+			out[j] = float64(v)
+		}
+		result[i] = out
+	}
+	return result, nil
+}
+
+// Dimension returns the configured output dimension
+func (e *ggufEmbedder) Dimension() int {
+	return e.provider.GetMatryoshkaDims()
+}
+
+// ModelVersion identifies the model and settings producing the vectors
+func (e *ggufEmbedder) ModelVersion() string {
+	return e.modelVersion
+}
+
+// Close releases the model pool
+func (e *ggufEmbedder) Close() error {
+	return e.provider.Close()
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEmbedder records the config it was built from
+type fakeEmbedder struct {
+	cfg    config.EmbeddingConfig
+	closed bool
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	result := make([][]float64, len(texts))
+	for i := range texts {
+		result[i] = make([]float64, e.cfg.Dims)
+		result[i][0] = 1
+	}
+	return result, nil
+}
+
+func (e *fakeEmbedder) Dimension() int { return e.cfg.Dims }
+
+func (e *fakeEmbedder) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestNewEmbedder_SelectsConfiguredProvider(t *testing.T) {
+	var built *fakeEmbedder
+	RegisterEmbedderProvider("fake", func(cfg config.EmbeddingConfig) (Embedder, error) {
+		built = &fakeEmbedder{cfg: cfg}
+		return built, nil
+	})
+
+	embedder, err := NewEmbedder(config.EmbeddingConfig{Provider: "Fake", ModelPath: "models/fake.gguf", Dims: 8, Pooling: "cls"})
+	require.NoError(t, err)
+	assert.Same(t, built, embedder)
+	assert.Equal(t, 8, embedder.Dimension())
+	assert.Equal(t, "models/fake.gguf", built.cfg.ModelPath)
+	assert.Equal(t, "cls", built.cfg.Pooling)
+}
+
+func TestNewEmbedder_DefaultOnlyWhenExplicit(t *testing.T) {
+	embedder, err := NewEmbedder(config.EmbeddingConfig{Provider: DefaultEmbedderProvider})
+	require.NoError(t, err)
+	assert.IsType(t, &DefaultEmbedder{}, embedder)
+
+	_, err = NewEmbedder(config.EmbeddingConfig{Provider: "does-not-exist"})
+	assert.Error(t, err)
+
+	_, err = NewEmbedder(config.EmbeddingConfig{})
+	assert.Error(t, err)
+}
+
+func TestMemorySystem_UsesEmbeddingConfig(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	var built *fakeEmbedder
+	RegisterEmbedderProvider("fake-system", func(cfg config.EmbeddingConfig) (Embedder, error) {
+		built = &fakeEmbedder{cfg: cfg}
+		return built, nil
+	})
+
+	memCfg := &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4}
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:    memCfg,
+		DB:        db,
+		Embedding: &config.EmbeddingConfig{Provider: "fake-system", Dims: 16},
+	})
+	require.NoError(t, err)
+	assert.Same(t, built, ms.embedder)
+	require.NoError(t, ms.Close())
+	assert.True(t, built.closed)
+
+	// A misconfigured provider fails instead of degrading to zero vectors
+	_, err = NewMemorySystem(ctx, MemorySystemConfig{
+		Config:    memCfg,
+		DB:        db,
+		Embedding: &config.EmbeddingConfig{Provider: "does-not-exist"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// ModelVersioner is implemented by embedders that can identify the model producing their vectors.
+// Vectors are only comparable when they share a model version
+type ModelVersioner interface {
+	ModelVersion() string
+}
+
+// EmbedderModelVersion returns the embedder's model version, or "" when it does not report one
+func EmbedderModelVersion(embedder Embedder) string {
+	if versioner, ok := embedder.(ModelVersioner); ok {
+		return versioner.ModelVersion()
+	}
+	return ""
+}
+
+// EmbeddingVersionLookup reports the model version each stored vector was embedded with
+type EmbeddingVersionLookup interface {
+	Versions(ctx context.Context, ids []string) (map[string]string, error)
+}
+
+// EmbeddingVersionStore records embedding provenance in the embedding_versions table
+type EmbeddingVersionStore struct {
+	db *sql.DB
+}
+
+// NewEmbeddingVersionStore creates an embedding version store
+func NewEmbeddingVersionStore(db *sql.DB) *EmbeddingVersionStore {
+	return &EmbeddingVersionStore{db: db}
+}
+
+// SetVersion records that id's vector was embedded with modelVersion
+func (s *EmbeddingVersionStore) SetVersion(ctx context.Context, id, modelVersion string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO embedding_versions (item_id, model_version)
+		VALUES (?, ?)
+		ON CONFLICT(item_id) DO UPDATE SET
+			model_version = excluded.model_version,
+			updated_at = CURRENT_TIMESTAMP
+	`, id, modelVersion)
+	if err != nil {
+		return fmt.Errorf("failed to record embedding version: %w", err)
+	}
+	return nil
+}
+
+// Versions returns the recorded model version per ID; untagged IDs are left out
+func (s *EmbeddingVersionStore) Versions(ctx context.Context, ids []string) (map[string]string, error) {
+	versions := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return versions, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT item_id, model_version FROM embedding_versions WHERE item_id IN (`+sqlPlaceholders(len(ids))+`)`,
+		stringArgs(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding version: %w", err)
+		}
+		versions[id] = version
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate embedding versions: %w", err)
+	}
+	return versions, nil
+}
+
+// Delete forgets the versions of ids
+func (s *EmbeddingVersionStore) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM embedding_versions WHERE item_id IN (`+sqlPlaceholders(len(ids))+`)`,
+		stringArgs(ids)...)
+	if err != nil {
+		return fmt.Errorf("failed to delete embedding versions: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every recorded version
+func (s *EmbeddingVersionStore) Clear(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM embedding_versions`); err != nil {
+		return fmt.Errorf("failed to clear embedding versions: %w", err)
+	}
+	return nil
+}
+
+// Counts returns the number of tagged vectors per model version
+func (s *EmbeddingVersionStore) Counts(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT model_version, COUNT(*) FROM embedding_versions GROUP BY model_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count embedding versions: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var version string
+		var count int
+		if err := rows.Scan(&version, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding version count: %w", err)
+		}
+		counts[version] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate embedding version counts: %w", err)
+	}
+	return counts, nil
+}
+
+// EmbeddingConsistencyReport describes which embedding model versions are present in the vector index
+type EmbeddingConsistencyReport struct {
+	CurrentVersion string         `json:"current_version"`
+	Versions       map[string]int `json:"versions"`   // Tagged vectors per model version
+	Mismatched     int            `json:"mismatched"` // Vectors tagged with a version other than CurrentVersion
+	Consistent     bool           `json:"consistent"`
+}
+
+// StaleVersions returns the recorded versions other than the current one, sorted
+func (r EmbeddingConsistencyReport) StaleVersions() []string {
+	var stale []string
+	for version := range r.Versions {
+		if version != r.CurrentVersion {
+			stale = append(stale, version)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// buildConsistencyReport summarizes per-version counts against the current model version
+func buildConsistencyReport(current string, counts map[string]int) EmbeddingConsistencyReport {
+	report := EmbeddingConsistencyReport{CurrentVersion: current, Versions: counts}
+	for version, count := range counts {
+		if version != current {
+			report.Mismatched += count
+		}
+	}
+	report.Consistent = report.Mismatched == 0
+	return report
+}
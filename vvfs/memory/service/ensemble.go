@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"sync"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"golang.org/x/sync/errgroup"
 )
 
 // IndexEnsembleImpl implements IndexEnsemble for coordinating multiple indexes
@@ -13,9 +15,11 @@ type IndexEnsembleImpl struct {
 	bm25   LexicalIndex
 	vector VectorIndex
 	graph  GraphSearch
-	config *config.MemoryConfig
-	router QueryRouter
-	fusion FusionRanker
+	// Optional: embeds queries for the vector source, which is skipped without one
+	embedder Embedder
+	config   *config.MemoryConfig
+	router   QueryRouter
+	fusion   FusionRanker
 }
 
 // NewIndexEnsemble creates a new index ensemble
@@ -30,7 +34,14 @@ func NewIndexEnsemble(bm25 LexicalIndex, vector VectorIndex, graph GraphSearch,
 	}
 }
 
-// Search performs ensemble search across multiple indexes
+// SetEmbedder sets the embedder used to embed queries for the vector source
+func (ie *IndexEnsembleImpl) SetEmbedder(embedder Embedder) {
+	ie.embedder = embedder
+}
+
+// Search queries the routed indexes concurrently within the MaxLatency budget.
+// Sources still running when the budget expires are cancelled and listed under the
+// "timed_out" metadata key of the results that did arrive
 func (ie *IndexEnsembleImpl) Search(ctx context.Context, query string, opts EnsembleSearchOptions) ([]EnsembleResult, error) {
 	// Route to determine which indexes to query and with what parameters
 	decision, err := ie.router.Route(ctx, query, RoutingOptions{
@@ -45,79 +56,132 @@ func (ie *IndexEnsembleImpl) Search(ctx context.Context, query string, opts Ense
 		return nil, fmt.Errorf("failed to route query: %w", err)
 	}
 
-	// Fan out to selected indexes in parallel
-	var wg sync.WaitGroup
-	resultsChan := make(chan EnsembleResult, len(decision.Indexes))
+	// Fan out to selected indexes in parallel, bounded by the latency budget
+	searchCtx := ctx
+	if ie.config.MaxLatency > 0 {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, ie.config.MaxLatency)
+		defer cancel()
+	}
+	g, gctx := errgroup.WithContext(searchCtx)
+
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	var ensembleResults []EnsembleResult
+	var pending []string
 
 	for _, indexConfig := range decision.Indexes {
 		if !indexConfig.Enabled {
 			continue
 		}
+		pending = append(pending, indexConfig.Name)
 
-		wg.Add(1)
-		go func(config IndexConfig) {
-			defer wg.Done()
-
-			var searchResults []SearchResult
-			var err error
-
-			switch config.Name {
-			case "bm25":
-				searchResults, err = ie.bm25.Query(ctx, query, opts.K)
-			case "vector":
-				// For vector search, we need an embedding of the query
-				// This is a placeholder - in practice, embed the query first
-				// For now, assume we have a query vector or embed it here
-				// searchResults, err = ie.vector.Query(ctx, queryVector, opts.K)
-				searchResults = []SearchResult{} // Placeholder
-			case "graph":
-				// Graph search requires a center entity
-				// This is a placeholder implementation
-				graphResults, err := ie.graph.SearchWithPathBoost(ctx, query, GraphSearchOptions{
-					Query: query,
-					K:     opts.K,
-				})
-				if err != nil {
-					return
-				}
-				// Convert GraphSearchResult to SearchResult
-				for _, gr := range graphResults {
-					searchResults = append(searchResults, SearchResult{
-						ID:         gr.EntityID,
-						Score:      gr.Score,
-						Provenance: "graph",
-					})
-				}
-			default:
-				// Handle other indexes (e.g., external ANN)
-			}
+		g.Go(func() error {
+			searchResults, err := ie.querySource(gctx, indexConfig.Name, query, opts.K)
 
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				// Log error but don't fail the entire ensemble
-				return
+				// A source cut off by the budget stays unfinished and is reported as timed out
+				if gctx.Err() == nil {
+					// Log error but don't fail the entire ensemble
+					log.Printf("Warning: ensemble %s search failed, continuing with remaining sources: %v", indexConfig.Name, err)
+					finished[indexConfig.Name] = true
+				}
+				return nil
 			}
-
-			resultsChan <- EnsembleResult{
-				Source:  config.Name,
+			finished[indexConfig.Name] = true
+			ensembleResults = append(ensembleResults, EnsembleResult{
+				Source:  indexConfig.Name,
 				Results: searchResults,
 				Metadata: map[string]interface{}{
-					"config": config,
+					"config":  indexConfig,
+					"routing": decision.Notes,
 				},
-			}
-		}(indexConfig)
+			})
+			return nil
+		})
 	}
 
-	// Wait for all goroutines to complete
+	// Sources that ignore cancellation must not hold the search past its budget
+	done := make(chan struct{})
 	go func() {
-		wg.Wait()
-		close(resultsChan)
+		_ = g.Wait()
+		close(done)
 	}()
+	select {
+	case <-done:
+	case <-searchCtx.Done():
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// Collect results
-	var ensembleResults []EnsembleResult
-	for result := range resultsChan {
-		ensembleResults = append(ensembleResults, result)
+	mu.Lock()
+	defer mu.Unlock()
+
+	var timedOut []string
+	for _, name := range pending {
+		if !finished[name] {
+			timedOut = append(timedOut, name)
+		}
+	}
+	if len(timedOut) == 0 {
+		return ensembleResults, nil
+	}
+	if len(ensembleResults) == 0 {
+		return nil, fmt.Errorf("ensemble search exceeded latency budget of %v before any source answered: %w", ie.config.MaxLatency, context.DeadlineExceeded)
 	}
 
-	return ensembleResults, nil
+	// Return what answered in time, noting which sources were cut off
+	results := make([]EnsembleResult, len(ensembleResults))
+	for i, result := range ensembleResults {
+		metadata := make(map[string]interface{}, len(result.Metadata)+1)
+		for key, value := range result.Metadata {
+			metadata[key] = value
+		}
+		metadata["timed_out"] = timedOut
+		result.Metadata = metadata
+		results[i] = result
+	}
+	return results, nil
+}
+
+// querySource searches one index of the ensemble
+func (ie *IndexEnsembleImpl) querySource(ctx context.Context, name string, query string, k int) ([]SearchResult, error) {
+	switch name {
+	case "bm25":
+		return ie.bm25.Query(ctx, query, k)
+	case "vector":
+		// Without an embedder there is no query vector to search with
+		if ie.embedder == nil {
+			return []SearchResult{}, nil
+		}
+		queryVec, err := embedSearchQuery(ctx, ie.embedder, query)
+		if err != nil {
+			return nil, err
+		}
+		return ie.vector.Query(ctx, queryVec, k)
+	case "graph":
+		graphResults, err := ie.graph.SearchWithPathBoost(ctx, query, GraphSearchOptions{
+			Query: query,
+			K:     k,
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Convert GraphSearchResult to SearchResult
+		searchResults := make([]SearchResult, 0, len(graphResults))
+		for _, gr := range graphResults {
+			searchResults = append(searchResults, SearchResult{
+				ID:         gr.EntityID,
+				Score:      gr.Score,
+				Provenance: "graph",
+			})
+		}
+		return searchResults, nil
+	default:
+		// Handle other indexes (e.g., external ANN)
+		return nil, nil
+	}
 }
@@ -3,23 +3,27 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockLexicalIndex for testing
 type MockLexicalIndex struct {
 	Results []SearchResult
+	Err     error
 }
 
 func (m *MockLexicalIndex) Query(ctx context.Context, query string, k int) ([]SearchResult, error) {
-	return m.Results, nil
+	return m.Results, m.Err
 }
 
 // MockVectorIndex for testing
 type MockVectorIndex struct {
 	Results []SearchResult
+	Err     error
 }
 
 func (m *MockVectorIndex) Upsert(ctx context.Context, id string, vector []float64) error {
@@ -27,13 +31,18 @@ func (m *MockVectorIndex) Upsert(ctx context.Context, id string, vector []float6
 }
 
 func (m *MockVectorIndex) Query(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
-	return m.Results, nil
+	return m.Results, m.Err
 }
 
 func (m *MockVectorIndex) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *MockVectorIndex) Clear(ctx context.Context) error {
+	m.Results = nil
+	return nil
+}
+
 func (m *MockVectorIndex) Close() error {
 	return nil
 }
@@ -74,11 +83,14 @@ func TestIndexEnsembleImpl_Search(t *testing.T) {
 	mockBM25 := &MockLexicalIndex{Results: []SearchResult{{ID: "doc1", Score: 0.8}}}
 	mockVector := &MockVectorIndex{Results: []SearchResult{{ID: "doc2", Score: 0.9}}}
 	mockGraph := &MockGraphSearch{Results: []GraphSearchResult{{EntityID: "entity1", Score: 0.7}}}
-	mockRouter := &MockQueryRouter{Decision: &RoutingDecision{}}
+	mockRouter := &MockQueryRouter{Decision: &RoutingDecision{
+		Indexes: []IndexConfig{{Name: "bm25", Enabled: true}, {Name: "vector", Enabled: true}, {Name: "graph", Enabled: false}},
+	}}
 	mockFusion := &MockFusionRanker{Results: []SearchResult{{ID: "doc1", Score: 1.0}, {ID: "doc2", Score: 0.9}}}
 
 	config := &config.MemoryConfig{}
 	ensemble := NewIndexEnsemble(mockBM25, mockVector, mockGraph, config, mockRouter, mockFusion)
+	ensemble.SetEmbedder(&modalEmbedder{})
 
 	results, err := ensemble.Search(context.Background(), "test query", EnsembleSearchOptions{
 		Query:    "test query",
@@ -90,6 +102,95 @@ func TestIndexEnsembleImpl_Search(t *testing.T) {
 	assert.Len(t, results, 2)
 }
 
+// TestIndexEnsembleImpl_SearchSkipsGraphForKeywordQuery tests that the routing decision skips
+// graph search without entity mentions and is recorded on each source's results
+func TestIndexEnsembleImpl_SearchSkipsGraphForKeywordQuery(t *testing.T) {
+	mockBM25 := &MockLexicalIndex{Results: []SearchResult{{ID: "doc1", Score: 0.8}}}
+	mockGraph := &MockGraphSearch{Results: []GraphSearchResult{{EntityID: "entity1", Score: 0.7}}}
+	config := &config.MemoryConfig{GraphEnabled: true, WeightsBM25: 0.5, WeightsGraph: 0.1}
+	ensemble := NewIndexEnsemble(mockBM25, &MockVectorIndex{}, mockGraph, config, NewQueryRouter(config), &MockFusionRanker{})
+
+	sources := func(results []EnsembleResult) []string {
+		var names []string
+		for _, result := range results {
+			names = append(names, result.Source)
+		}
+		return names
+	}
+
+	results, err := ensemble.Search(context.Background(), "tax receipts", EnsembleSearchOptions{K: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bm25"}, sources(results))
+	if assert.Len(t, results, 1) {
+		routing, ok := results[0].Metadata["routing"].(map[string]interface{})
+		if assert.True(t, ok) {
+			assert.Equal(t, 0, routing["entity_mentions"])
+			assert.Contains(t, routing["graph"], "skipped")
+		}
+	}
+
+	results, err = ensemble.Search(context.Background(), "receipts from Acme", EnsembleSearchOptions{K: 5})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bm25", "graph"}, sources(results))
+	for _, result := range results {
+		routing := result.Metadata["routing"].(map[string]interface{})
+		assert.Equal(t, "included", routing["graph"])
+	}
+}
+
+// slowGraphSearch answers only after delay, or fails once its context is cancelled
+type slowGraphSearch struct {
+	MockGraphSearch
+	delay time.Duration
+}
+
+func (s *slowGraphSearch) SearchWithPathBoost(ctx context.Context, query string, opts GraphSearchOptions) ([]GraphSearchResult, error) {
+	select {
+	case <-time.After(s.delay):
+		return s.Results, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TestIndexEnsembleImpl_SearchReturnsWithinLatencyBudget tests that a slow source is cut off
+// at MaxLatency while the fast source's results are returned
+func TestIndexEnsembleImpl_SearchReturnsWithinLatencyBudget(t *testing.T) {
+	vector := &MockVectorIndex{Results: []SearchResult{{ID: "doc2", Score: 0.9}}}
+	graph := &slowGraphSearch{
+		MockGraphSearch: MockGraphSearch{Results: []GraphSearchResult{{EntityID: "entity1", Score: 0.7}}},
+		delay:           5 * time.Second,
+	}
+	router := &MockQueryRouter{Decision: &RoutingDecision{
+		Indexes: []IndexConfig{{Name: "vector", Enabled: true}, {Name: "graph", Enabled: true}},
+		Notes:   map[string]interface{}{},
+	}}
+	config := &config.MemoryConfig{MaxLatency: 50 * time.Millisecond}
+	ensemble := NewIndexEnsemble(&MockLexicalIndex{}, vector, graph, config, router, &MockFusionRanker{})
+	ensemble.SetEmbedder(&modalEmbedder{})
+
+	start := time.Now()
+	results, err := ensemble.Search(context.Background(), "invoices", EnsembleSearchOptions{K: 5})
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.Less(t, elapsed, time.Second, "ensemble must not wait for the slow source")
+	require.Len(t, results, 1)
+	assert.Equal(t, "vector", results[0].Source)
+	assert.Equal(t, vector.Results, results[0].Results)
+	assert.Equal(t, []string{"graph"}, results[0].Metadata["timed_out"])
+
+	// Without a budget the slow source is awaited and nothing is marked as timed out
+	graph.delay = 10 * time.Millisecond
+	config.MaxLatency = 0
+	results, err = ensemble.Search(context.Background(), "invoices", EnsembleSearchOptions{K: 5})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		assert.NotContains(t, result.Metadata, "timed_out")
+	}
+}
+
 // TestFusionRankerImpl_Fuse tests fusion strategies
 func TestFusionRankerImpl_Fuse(t *testing.T) {
 	config := &config.MemoryConfig{
@@ -9,6 +9,16 @@ import (
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
 
+// exampleEmbeddingConfig selects a real embedding model for the examples; without one
+// NewMemorySystem fails rather than indexing zero vectors
+func exampleEmbeddingConfig() *config.EmbeddingConfig {
+	return &config.EmbeddingConfig{
+		Provider:  "gguf",
+		ModelPath: "vvfs/generation/models/gguf/open-embed.gguf",
+		Dims:      768,
+	}
+}
+
 // ExampleBasicMemoryUsage demonstrates basic memory system usage
 func ExampleBasicMemoryUsage() {
 	// Step 1: Open database connection
@@ -33,8 +43,9 @@ func ExampleBasicMemoryUsage() {
 	// Step 3: Initialize memory system
 	ctx := context.Background()
 	memSys, err := NewMemorySystem(ctx, MemorySystemConfig{
-		Config: memCfg,
-		DB:     db,
+		Config:    memCfg,
+		DB:        db,
+		Embedding: exampleEmbeddingConfig(),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -86,8 +97,9 @@ func ExampleGraphKnowledgeExtraction() {
 
 	ctx := context.Background()
 	memSys, err := NewMemorySystem(ctx, MemorySystemConfig{
-		Config: memCfg,
-		DB:     db,
+		Config:    memCfg,
+		DB:        db,
+		Embedding: exampleEmbeddingConfig(),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -142,8 +154,9 @@ func ExampleEnsembleSearch() {
 
 	ctx := context.Background()
 	memSys, err := NewMemorySystem(ctx, MemorySystemConfig{
-		Config: memCfg,
-		DB:     db,
+		Config:    memCfg,
+		DB:        db,
+		Embedding: exampleEmbeddingConfig(),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -182,8 +195,9 @@ func ExampleWorkingMemorySummarization() {
 
 	ctx := context.Background()
 	memSys, err := NewMemorySystem(ctx, MemorySystemConfig{
-		Config: memCfg,
-		DB:     db,
+		Config:    memCfg,
+		DB:        db,
+		Embedding: exampleEmbeddingConfig(),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+)
+
+// Retry defaults for remote extractor calls
+const (
+	defaultExtractorMaxRetries  = 3
+	defaultExtractorBaseBackoff = 500 * time.Millisecond
+	defaultExtractorMaxBackoff  = 30 * time.Second
+	defaultExtractorTimeout     = 30 * time.Second
+)
+
+// extractorClient is the shared HTTP client for remote extractor providers (openai, gemini, ollama).
+// A token bucket sized by ExtractorConcurrency gates in-flight requests; each attempt gets its own
+// ExtractorTimeout; 429 and 5xx responses are retried with exponential backoff honoring Retry-After.
+type extractorClient struct {
+	http        *http.Client
+	tokens      chan struct{} // one token per allowed in-flight request
+	timeout     time.Duration
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+// newExtractorClient creates a client from the memory config; httpClient may be nil
+func newExtractorClient(cfg *config.MemoryConfig, httpClient *http.Client) *extractorClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	concurrency := 1
+	timeout := defaultExtractorTimeout
+	if cfg != nil {
+		if cfg.ExtractorConcurrency > 0 {
+			concurrency = cfg.ExtractorConcurrency
+		}
+		if cfg.ExtractorTimeout > 0 {
+			timeout = cfg.ExtractorTimeout
+		}
+	}
+
+	tokens := make(chan struct{}, concurrency)
+	for i := 0; i < concurrency; i++ {
+		tokens <- struct{}{}
+	}
+
+	return &extractorClient{
+		http:        httpClient,
+		tokens:      tokens,
+		timeout:     timeout,
+		maxRetries:  defaultExtractorMaxRetries,
+		baseBackoff: defaultExtractorBaseBackoff,
+		maxBackoff:  defaultExtractorMaxBackoff,
+	}
+}
+
+// extractorStatusError is returned when the provider answers with a non-2xx status
+type extractorStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *extractorStatusError) Error() string {
+	return fmt.Sprintf("extractor provider returned %d: %s", e.StatusCode, e.Body)
+}
+
+// retryable reports whether the status is worth retrying
+func (e *extractorStatusError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// do sends the request built by newRequest, retrying transient failures, and returns the response body.
+// newRequest is called once per attempt so request bodies can be replayed.
+func (c *extractorClient) do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		body, retryAfter, err := c.attempt(ctx, newRequest)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		statusErr, ok := err.(*extractorStatusError)
+		if ctx.Err() != nil || (ok && !statusErr.retryable()) || attempt == c.maxRetries {
+			break
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > 0 {
+			wait = min(retryAfter, c.maxBackoff)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("extractor request cancelled: %w", ctx.Err())
+		}
+	}
+
+	return nil, fmt.Errorf("extractor request failed: %w", lastErr)
+}
+
+// attempt performs one request while holding a concurrency token
+func (c *extractorClient) attempt(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error)) ([]byte, time.Duration, error) {
+	select {
+	case <-c.tokens:
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+	defer func() { c.tokens <- struct{}{} }()
+
+	attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := newRequest(attemptCtx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), &extractorStatusError{
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+	return body, 0, nil
+}
+
+// backoff returns the exponential delay before retry attempt+1
+func (c *extractorClient) backoff(attempt int) time.Duration {
+	wait := c.baseBackoff << attempt
+	if wait <= 0 || wait > c.maxBackoff {
+		return c.maxBackoff
+	}
+	return wait
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// defaultExtractorBaseURL returns the OpenAI-compatible endpoint for a provider
+func defaultExtractorBaseURL(provider string) (string, error) {
+	switch provider {
+	case "openai", "":
+		return "https://api.openai.com/v1", nil
+	case "gemini":
+		return "https://generativelanguage.googleapis.com/v1beta/openai", nil
+	case "ollama":
+		return "http://localhost:11434/v1", nil
+	default:
+		return "", fmt.Errorf("unsupported extractor provider: %s", provider)
+	}
+}
+
+// ChatExtractorLLM implements LLMClient against an OpenAI-compatible chat completions API,
+// which openai, gemini and ollama all expose
+type ChatExtractorLLM struct {
+	client  *extractorClient
+	baseURL string
+	apiKey  string
+	model   string
+}
+
+// NewChatExtractorLLM creates an extractor LLM for cfg.ExtractorProvider.
+// An empty baseURL selects the provider's default endpoint.
+func NewChatExtractorLLM(cfg *config.MemoryConfig, baseURL, apiKey, model string) (*ChatExtractorLLM, error) {
+	if baseURL == "" {
+		provider := ""
+		if cfg != nil {
+			provider = cfg.ExtractorProvider
+		}
+		var err error
+		if baseURL, err = defaultExtractorBaseURL(provider); err != nil {
+			return nil, err
+		}
+	}
+	if model == "" {
+		return nil, fmt.Errorf("extractor model cannot be empty")
+	}
+
+	return &ChatExtractorLLM{
+		client:  newExtractorClient(cfg, nil),
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+	}, nil
+}
+
+// GenerateStructured asks the model for a JSON object and decodes it
+func (l *ChatExtractorLLM) GenerateStructured(ctx context.Context, prompt string, schema interface{}) (map[string]interface{}, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model": l.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	body, err := l.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+"/chat/completions", bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if l.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+l.apiKey)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return nil, fmt.Errorf("failed to decode completion: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("completion contained no choices")
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), &result); err != nil {
+		return nil, fmt.Errorf("failed to decode structured output: %w", err)
+	}
+	return result, nil
+}
@@ -75,6 +75,24 @@ func (f *FlatIndexImpl) Upsert(ctx context.Context, id string, vector []float64)
 
 // Query performs k-NN search using brute force
 func (f *FlatIndexImpl) Query(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	return f.scan(ctx, query, k, "", nil)
+}
+
+// QueryFiltered is Query over only the items whose metadata matches every filter entry,
+// applied as a SQL prefilter so non-matching vectors are never decoded or scored
+func (f *FlatIndexImpl) QueryFiltered(ctx context.Context, query []float64, k int, filters map[string]interface{}) ([]SearchResult, error) {
+	if len(filters) == 0 {
+		return f.Query(ctx, query, k)
+	}
+	where, args, err := metadataFilterClause(filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPrefilterUnsupported, err)
+	}
+	return f.scan(ctx, query, k, where, args)
+}
+
+// scan scores the stored vectors, restricted by an optional WHERE condition, against query
+func (f *FlatIndexImpl) scan(ctx context.Context, query []float64, k int, where string, args []interface{}) ([]SearchResult, error) {
 	if len(query) != f.dimension {
 		return nil, fmt.Errorf("query dimension mismatch: expected %d, got %d", f.dimension, len(query))
 	}
@@ -88,8 +106,11 @@ func (f *FlatIndexImpl) Query(ctx context.Context, query []float64, k int) ([]Se
 		FROM memory_items
 		WHERE embedding IS NOT NULL
 	`
+	if where != "" {
+		sqlQuery += " AND " + where
+	}
 
-	rows, err := f.db.QueryContext(ctx, sqlQuery)
+	rows, err := f.db.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch vectors: %w", err)
 	}
@@ -140,9 +161,12 @@ func (f *FlatIndexImpl) Query(ctx context.Context, query []float64, k int) ([]Se
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	// Sort by distance (ascending)
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].distance < candidates[j].distance
+	// Sort by distance (ascending), breaking ties by ID
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].id < candidates[j].id
 	})
 
 	// Return top-k
@@ -164,6 +188,31 @@ func (f *FlatIndexImpl) Query(ctx context.Context, query []float64, k int) ([]Se
 	return results, nil
 }
 
+// Vector returns the stored vector for id, or nil if it has none
+func (f *FlatIndexImpl) Vector(ctx context.Context, id string) ([]float64, error) {
+	f.mu.RLock()
+	cached, ok := f.cache[id]
+	f.mu.RUnlock()
+	if ok {
+		return cached, nil
+	}
+
+	var embeddingBlob []byte
+	err := f.db.QueryRowContext(ctx, `SELECT embedding FROM memory_items WHERE id = ?`, id).Scan(&embeddingBlob)
+	if err == sql.ErrNoRows || (err == nil && embeddingBlob == nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vector: %w", err)
+	}
+
+	var vector []float64
+	if err := json.Unmarshal(embeddingBlob, &vector); err != nil {
+		return nil, fmt.Errorf("failed to decode vector: %w", err)
+	}
+	return vector, nil
+}
+
 // Delete removes a vector from the index
 func (f *FlatIndexImpl) Delete(ctx context.Context, id string) error {
 	query := `
@@ -185,6 +234,45 @@ func (f *FlatIndexImpl) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteBatch removes many vectors with a single statement
+func (f *FlatIndexImpl) DeleteBatch(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE memory_items SET embedding = NULL WHERE id IN (` + sqlPlaceholders(len(ids)) + `)`
+	if _, err := f.db.ExecContext(ctx, query, stringArgs(ids)...); err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
+	}
+
+	f.mu.Lock()
+	for _, id := range ids {
+		delete(f.cache, id)
+	}
+	f.mu.Unlock()
+
+	return nil
+}
+
+// Clear removes every stored vector and empties the cache
+func (f *FlatIndexImpl) Clear(ctx context.Context) error {
+	query := `
+		UPDATE memory_items
+		SET embedding = NULL
+		WHERE embedding IS NOT NULL
+	`
+
+	if _, err := f.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to clear vectors: %w", err)
+	}
+
+	f.mu.Lock()
+	f.cache = make(map[string][]float64)
+	f.mu.Unlock()
+
+	return nil
+}
+
 // Close cleans up resources
 func (f *FlatIndexImpl) Close() error {
 	f.mu.Lock()
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatIndex_QueryFilteredScansOnlyMatchingWorkspace(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	store := NewMemoryStoreImpl(db)
+	index := NewFlatIndexImpl(db, 3)
+	items := []struct {
+		id        string
+		workspace string
+		vector    []float64
+	}{
+		{"a-near", "alpha", []float64{0.9, 0.1, 0}},
+		{"a-far", "alpha", []float64{0, 1, 0}},
+		{"b-exact", "beta", []float64{1, 0, 0}},
+	}
+	for _, item := range items {
+		require.NoError(t, store.PutItem(ctx, &MemoryItem{
+			ID:       item.id,
+			Type:     "note",
+			Text:     item.id,
+			Metadata: map[string]interface{}{"workspace": item.workspace},
+		}))
+		require.NoError(t, index.Upsert(ctx, item.id, item.vector))
+	}
+
+	query := []float64{1, 0, 0}
+	all, err := index.Query(ctx, query, 10)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, "b-exact", all[0].ID)
+
+	results, err := index.QueryFiltered(ctx, query, 10, map[string]interface{}{"workspace": "alpha"})
+	require.NoError(t, err)
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	assert.Equal(t, []string{"a-near", "a-far"}, ids, "only the workspace's vectors are scored")
+
+	_, err = index.QueryFiltered(ctx, query, 10, map[string]interface{}{"workspace": []string{"alpha"}})
+	assert.ErrorIs(t, err, ErrPrefilterUnsupported)
+}
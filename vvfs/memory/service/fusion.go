@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"sort"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
@@ -62,9 +61,7 @@ func (fr *FusionRankerImpl) fuseRRF(results []EnsembleResult) ([]SearchResult, e
 		})
 	}
 
-	sort.Slice(fusedResults, func(i, j int) bool {
-		return fusedResults[i].Score > fusedResults[j].Score
-	})
+	sortResultsStable(fusedResults)
 
 	return fusedResults, nil
 }
@@ -107,9 +104,7 @@ func (fr *FusionRankerImpl) fuseWeightedRRF(results []EnsembleResult) ([]SearchR
 		})
 	}
 
-	sort.Slice(fusedResults, func(i, j int) bool {
-		return fusedResults[i].Score > fusedResults[j].Score
-	})
+	sortResultsStable(fusedResults)
 
 	return fusedResults, nil
 }
@@ -161,9 +156,7 @@ func (fr *FusionRankerImpl) fuseRelativeScore(results []EnsembleResult) ([]Searc
 		})
 	}
 
-	sort.Slice(fusedResults, func(i, j int) bool {
-		return fusedResults[i].Score > fusedResults[j].Score
-	})
+	sortResultsStable(fusedResults)
 
 	return fusedResults, nil
 }
@@ -84,3 +84,37 @@ func TestFusionRankerImpl_fuseRelativeScore(t *testing.T) {
 	// Scores should be normalized and combined
 	assert.Greater(t, results[0].Score, results[1].Score)
 }
+
+// TestFusionRankerImpl_TiesBreakDeterministically tests that equal fused scores always rank in ID order
+func TestFusionRankerImpl_TiesBreakDeterministically(t *testing.T) {
+	fusion := NewFusionRanker(&config.MemoryConfig{})
+
+	// Each document is first in exactly one source, so every fused score ties
+	ids := []string{"doc-e", "doc-b", "doc-d", "doc-a", "doc-c"}
+	var ensembleResults []EnsembleResult
+	for _, id := range ids {
+		ensembleResults = append(ensembleResults, EnsembleResult{
+			Source:  "bm25",
+			Results: []SearchResult{{ID: id, Score: 0.5}},
+		})
+	}
+
+	for _, method := range []FusionStrategy{FusionRRF, FusionWeightedRRF, FusionRelativeScore} {
+		var first []string
+		for run := 0; run < 20; run++ {
+			results, err := fusion.Fuse(context.Background(), ensembleResults, method)
+			assert.NoError(t, err)
+
+			order := make([]string, len(results))
+			for i, result := range results {
+				order[i] = result.ID
+			}
+			if run == 0 {
+				first = order
+				assert.Equal(t, []string{"doc-a", "doc-b", "doc-c", "doc-d", "doc-e"}, order, "method %s", method)
+				continue
+			}
+			assert.Equal(t, first, order, "method %s run %d", method, run)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// CachedGraphStore decorates a GraphStore with a read-through LRU over GetEntity and GetEdge.
+// Writes go to the wrapped store first and then drop the cached copy, so a read after a
+// write always sees the stored row. List, iterate and temporal queries pass through.
+type CachedGraphStore struct {
+	GraphStore
+	cache *graphCache
+}
+
+// NewCachedGraphStore wraps store with an LRU read cache.
+// capacity <= 0 defaults to 10000 entries; ttl <= 0 means entries never expire.
+func NewCachedGraphStore(store GraphStore, capacity int, ttl time.Duration) *CachedGraphStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &CachedGraphStore{
+		GraphStore: store,
+		cache:      newGraphCache(capacity, ttl),
+	}
+}
+
+// GetEntity returns a cached entity or loads it from the wrapped store
+func (s *CachedGraphStore) GetEntity(ctx context.Context, id string) (*Entity, error) {
+	key := entityCacheKey(id)
+	if cached, ok := s.cache.get(key); ok {
+		return copyEntity(cached.(*Entity)), nil
+	}
+
+	epoch := s.cache.currentEpoch()
+	entity, err := s.GraphStore.GetEntity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, copyEntity(entity), epoch)
+	return entity, nil
+}
+
+// GetEntities serves cached entities and loads the rest in one batch from the wrapped store
+func (s *CachedGraphStore) GetEntities(ctx context.Context, ids []string) (map[string]*Entity, error) {
+	entities := make(map[string]*Entity, len(ids))
+	var misses []string
+	for _, id := range ids {
+		if cached, ok := s.cache.get(entityCacheKey(id)); ok {
+			entities[id] = copyEntity(cached.(*Entity))
+			continue
+		}
+		misses = append(misses, id)
+	}
+	if len(misses) == 0 {
+		return entities, nil
+	}
+
+	epoch := s.cache.currentEpoch()
+	loaded, err := s.GraphStore.GetEntities(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	for id, entity := range loaded {
+		s.cache.put(entityCacheKey(id), copyEntity(entity), epoch)
+		entities[id] = entity
+	}
+	return entities, nil
+}
+
+// UpsertEntity writes through to the wrapped store and drops the cached entity
+func (s *CachedGraphStore) UpsertEntity(ctx context.Context, entity *Entity) error {
+	defer s.cache.invalidate(entityCacheKey(entity.ID))
+	return s.GraphStore.UpsertEntity(ctx, entity)
+}
+
+// DeleteEntity deletes from the wrapped store and drops the cached entity
+func (s *CachedGraphStore) DeleteEntity(ctx context.Context, id string) error {
+	defer s.cache.invalidate(entityCacheKey(id))
+	return s.GraphStore.DeleteEntity(ctx, id)
+}
+
+// GetEdge returns a cached edge or loads it from the wrapped store
+func (s *CachedGraphStore) GetEdge(ctx context.Context, id string) (*Edge, error) {
+	key := edgeCacheKey(id)
+	if cached, ok := s.cache.get(key); ok {
+		return copyEdge(cached.(*Edge)), nil
+	}
+
+	epoch := s.cache.currentEpoch()
+	edge, err := s.GraphStore.GetEdge(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, copyEdge(edge), epoch)
+	return edge, nil
+}
+
+// UpsertEdge writes through to the wrapped store and drops the cached edge
+func (s *CachedGraphStore) UpsertEdge(ctx context.Context, edge *Edge) error {
+	defer s.cache.invalidate(edgeCacheKey(edge.ID))
+	return s.GraphStore.UpsertEdge(ctx, edge)
+}
+
+// InvalidateEdge marks the edge invalid in the wrapped store and drops the cached edge
+func (s *CachedGraphStore) InvalidateEdge(ctx context.Context, id string, reason string) error {
+	defer s.cache.invalidate(edgeCacheKey(id))
+	return s.GraphStore.InvalidateEdge(ctx, id, reason)
+}
+
+func entityCacheKey(id string) string { return "entity:" + id }
+func edgeCacheKey(id string) string   { return "edge:" + id }
+
+// copyEntity detaches a cached entity from callers that mutate what they read
+func copyEntity(entity *Entity) *Entity {
+	c := *entity
+	c.Attrs = maps.Clone(entity.Attrs)
+	return &c
+}
+
+// copyEdge detaches a cached edge from callers that mutate what they read
+func copyEdge(edge *Edge) *Edge {
+	c := *edge
+	c.Attrs = maps.Clone(edge.Attrs)
+	c.Provenance = maps.Clone(edge.Provenance)
+	return &c
+}
+
+// graphCache is a TTL-bounded LRU of graph rows.
+// The epoch advances on every invalidation so a read that raced a write is not cached.
+type graphCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	now      func() time.Time
+	order    *list.List // front = most recently used
+	entries  map[string]*list.Element
+	epoch    uint64
+}
+
+type graphCacheEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+func newGraphCache(capacity int, ttl time.Duration) *graphCache {
+	return &graphCache{
+		capacity: capacity,
+		ttl:      ttl,
+		now:      time.Now,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *graphCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*graphCacheEntry)
+	if c.ttl > 0 && c.now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+func (c *graphCache) currentEpoch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch
+}
+
+// put stores value unless an invalidation happened since epoch was read
+func (c *graphCache) put(key string, value interface{}, epoch uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.epoch != epoch {
+		return
+	}
+
+	expiresAt := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*graphCacheEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&graphCacheEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*graphCacheEntry).key)
+	}
+}
+
+func (c *graphCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.epoch++
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedGraphStore_GetEntityHitsCache(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	entity := &Entity{ID: "e1", Kind: "person", Name: "Ada", Attrs: map[string]interface{}{"role": "engineer"}}
+	store.On("GetEntity", mock.Anything, "e1").Return(entity, nil).Once()
+
+	cached := NewCachedGraphStore(store, 10, time.Minute)
+
+	first, err := cached.GetEntity(ctx, "e1")
+	require.NoError(t, err)
+	first.Attrs["role"] = "mutated by caller"
+
+	second, err := cached.GetEntity(ctx, "e1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", second.Name)
+	assert.Equal(t, "engineer", second.Attrs["role"], "cached copy must not share caller mutations")
+
+	store.AssertExpectations(t)
+	store.AssertNumberOfCalls(t, "GetEntity", 1)
+}
+
+func TestCachedGraphStore_UpsertInvalidatesEntity(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	before := &Entity{ID: "e1", Name: "Ada"}
+	after := &Entity{ID: "e1", Name: "Ada Lovelace"}
+	store.On("GetEntity", mock.Anything, "e1").Return(before, nil).Once()
+	store.On("UpsertEntity", mock.Anything, after).Return(nil).Once()
+	store.On("GetEntity", mock.Anything, "e1").Return(after, nil).Once()
+
+	cached := NewCachedGraphStore(store, 10, time.Minute)
+
+	got, err := cached.GetEntity(ctx, "e1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", got.Name)
+
+	require.NoError(t, cached.UpsertEntity(ctx, after))
+
+	got, err = cached.GetEntity(ctx, "e1")
+	require.NoError(t, err)
+	assert.Equal(t, "Ada Lovelace", got.Name)
+
+	store.AssertExpectations(t)
+	store.AssertNumberOfCalls(t, "GetEntity", 2)
+}
+
+func TestCachedGraphStore_EdgeInvalidationAndErrors(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	edge := &Edge{ID: "r1", SourceID: "e1", TargetID: "e2", Relation: "knows"}
+	store.On("GetEdge", mock.Anything, "r1").Return(edge, nil).Times(2)
+	store.On("InvalidateEdge", mock.Anything, "r1", "superseded").Return(nil).Once()
+	store.On("GetEdge", mock.Anything, "missing").Return((*Edge)(nil), errors.New("edge not found: missing")).Times(2)
+
+	cached := NewCachedGraphStore(store, 10, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, err := cached.GetEdge(ctx, "r1")
+		require.NoError(t, err)
+	}
+	store.AssertNumberOfCalls(t, "GetEdge", 1)
+
+	require.NoError(t, cached.InvalidateEdge(ctx, "r1", "superseded"))
+	_, err := cached.GetEdge(ctx, "r1")
+	require.NoError(t, err)
+	store.AssertNumberOfCalls(t, "GetEdge", 2)
+
+	// Misses are not cached
+	for i := 0; i < 2; i++ {
+		_, err := cached.GetEdge(ctx, "missing")
+		assert.Error(t, err)
+	}
+	store.AssertExpectations(t)
+}
+
+func TestCachedGraphStore_TTLAndCapacity(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	store.On("GetEntity", mock.Anything, "a").Return(&Entity{ID: "a"}, nil).Times(3)
+	store.On("GetEntity", mock.Anything, "b").Return(&Entity{ID: "b"}, nil).Once()
+
+	cached := NewCachedGraphStore(store, 1, time.Minute)
+	now := time.Now()
+	cached.cache.now = func() time.Time { return now }
+
+	_, _ = cached.GetEntity(ctx, "a")
+	_, _ = cached.GetEntity(ctx, "a") // hit
+
+	// Expired entries are reloaded
+	now = now.Add(2 * time.Minute)
+	_, _ = cached.GetEntity(ctx, "a")
+
+	// Loading b evicts a from a one-entry cache
+	_, _ = cached.GetEntity(ctx, "b")
+	_, _ = cached.GetEntity(ctx, "a")
+
+	store.AssertExpectations(t)
+	store.AssertNumberOfCalls(t, "GetEntity", 4)
+}
+
+func TestCachedGraphStore_GetEntitiesFetchesOnlyMisses(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	store.On("GetEntity", mock.Anything, "a").Return(&Entity{ID: "a"}, nil).Once()
+	store.On("GetEntities", mock.Anything, []string{"b", "missing"}).Return(map[string]*Entity{"b": {ID: "b"}}, nil).Once()
+	store.On("GetEntities", mock.Anything, []string{"missing"}).Return(map[string]*Entity{}, nil).Once()
+
+	cached := NewCachedGraphStore(store, 10, time.Minute)
+	_, err := cached.GetEntity(ctx, "a")
+	require.NoError(t, err)
+
+	entities, err := cached.GetEntities(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	// b is now cached too; only the missing ID goes back to the store
+	entities, err = cached.GetEntities(ctx, []string{"a", "b", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, entities, 2)
+	store.AssertExpectations(t)
+}
@@ -2,12 +2,44 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
 
+// EdgeWeightAttr is the edge attribute holding the relationship strength
+const EdgeWeightAttr = "weight"
+
+// Weight returns the edge strength from Attrs[EdgeWeightAttr], defaulting to 1.0 when unset or invalid
+func (e *Edge) Weight() float64 {
+	var weight float64
+	switch v := e.Attrs[EdgeWeightAttr].(type) {
+	case float64:
+		weight = v
+	case float32:
+		weight = float64(v)
+	case int:
+		weight = float64(v)
+	case int64:
+		weight = float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return 1.0
+		}
+		weight = f
+	default:
+		return 1.0
+	}
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return 1.0
+	}
+	return weight
+}
+
 // GraphSearchImpl implements GraphSearch for graph-based retrieval and reranking
 type GraphSearchImpl struct {
 	store  GraphStore
@@ -22,8 +54,14 @@ func NewGraphSearch(store GraphStore, config *config.MemoryConfig) *GraphSearchI
 	}
 }
 
-// SearchFromCenter performs search centered around a specific entity
+// SearchFromCenter performs search centered around a specific entity.
+// Result paths are populated when GraphExplainPaths is enabled in the config.
 func (gs *GraphSearchImpl) SearchFromCenter(ctx context.Context, centerID string, query string, depth int, k int) ([]GraphSearchResult, error) {
+	return gs.searchFromCenter(ctx, centerID, depth, k, gs.config.GraphExplainPaths)
+}
+
+// searchFromCenter runs the BFS, recording the traversed path per result when includePaths is set
+func (gs *GraphSearchImpl) searchFromCenter(ctx context.Context, centerID string, depth int, k int, includePaths bool) ([]GraphSearchResult, error) {
 	if depth <= 0 {
 		depth = gs.config.GraphDepth
 	}
@@ -43,6 +81,15 @@ func (gs *GraphSearchImpl) SearchFromCenter(ctx context.Context, centerID string
 	distances := map[string]int{centerID: 0}
 	results := []GraphSearchResult{}
 
+	// strengths holds the product of edge weights along the first path that reached each entity
+	strengths := map[string]float64{centerID: 1.0}
+
+	// paths holds the first path that reached each entity: [center, relation, entity, ...]
+	var paths map[string][]string
+	if includePaths {
+		paths = map[string][]string{centerID: {centerID}}
+	}
+
 	for len(queue) > 0 && len(results) < k {
 		currentID := queue[0]
 		queue = queue[1:]
@@ -75,13 +122,27 @@ func (gs *GraphSearchImpl) SearchFromCenter(ctx context.Context, centerID string
 					distances[targetID] = newDist
 					queue = append(queue, targetID)
 
+					strength := strengths[currentID] * edge.Weight()
+					if _, seen := strengths[targetID]; !seen {
+						strengths[targetID] = strength
+					}
+
 					// Create result
 					result := GraphSearchResult{
 						EntityID:   targetID,
-						Score:      gs.calculateGraphScore(edge, newDist),
+						Score:      gs.calculateGraphScore(edge, newDist) * gs.calculateStrengthWeight(strength),
 						PathLength: newDist,
 						Relation:   edge.Relation,
 					}
+					if includePaths {
+						parent := paths[currentID]
+						path := make([]string, len(parent), len(parent)+2)
+						copy(path, parent)
+						result.Path = append(path, edge.Relation, targetID)
+						if _, seen := paths[targetID]; !seen {
+							paths[targetID] = result.Path
+						}
+					}
 					results = append(results, result)
 				}
 			}
@@ -119,7 +180,8 @@ func (gs *GraphSearchImpl) SearchWithPathBoost(ctx context.Context, query string
 	}
 
 	// Perform BFS from center
-	results, err := gs.SearchFromCenter(ctx, centerID, query, depth, k*2) // Overfetch for reranking
+	includePaths := opts.IncludePaths || gs.config.GraphExplainPaths
+	results, err := gs.searchFromCenter(ctx, centerID, depth, k*2, includePaths) // Overfetch for reranking
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +219,14 @@ func (gs *GraphSearchImpl) calculateGraphScore(edge *Edge, distance int) float64
 	return baseScore * distancePenalty
 }
 
+// calculateStrengthWeight scales a score by the path's edge-weight product, raised to GraphEdgeWeightFactor
+func (gs *GraphSearchImpl) calculateStrengthWeight(strength float64) float64 {
+	if gs.config.GraphEdgeWeightFactor <= 0 {
+		return 1.0
+	}
+	return math.Pow(strength, gs.config.GraphEdgeWeightFactor)
+}
+
 // calculatePathWeight computes weight for path length
 func (gs *GraphSearchImpl) calculatePathWeight(pathLength int) float64 {
 	// Exponential decay with path length
@@ -183,14 +253,12 @@ func (gs *GraphSearchImpl) getRelationBoost(relation string) (float64, bool) {
 	return boost, ok
 }
 
-// sortGraphResults sorts results by score descending
+// sortGraphResults sorts results by score descending, breaking ties by entity ID
 func (gs *GraphSearchImpl) sortGraphResults(results []GraphSearchResult) {
-	// Simple bubble sort for small lists (can be optimized)
-	for i := 0; i < len(results)-1; i++ {
-		for j := 0; j < len(results)-i-1; j++ {
-			if results[j].Score < results[j+1].Score {
-				results[j], results[j+1] = results[j+1], results[j]
-			}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
 		}
-	}
+		return results[i].EntityID < results[j].EntityID
+	})
 }
@@ -57,3 +57,120 @@ func TestGraphSearchImpl_calculatePathWeight(t *testing.T) {
 	assert.Greater(t, weight, 0.0)
 	assert.Less(t, weight, 1.0) // Should decay with distance
 }
+
+// edgesFrom matches ListEdges calls for one source entity
+func edgesFrom(src string) interface{} {
+	return mock.MatchedBy(func(opts ListOptions) bool { return opts.Filter["src_id"] == src })
+}
+
+// TestGraphSearchImpl_SearchFromCenterPaths verifies multi-hop paths list the traversed entities and relations
+func TestGraphSearchImpl_SearchFromCenterPaths(t *testing.T) {
+	mockStore := new(MockGraphStore)
+	search := NewGraphSearch(mockStore, &config.MemoryConfig{GraphDepth: 3, K: 10, GraphExplainPaths: true})
+
+	// alice -works_on-> vvfs -depends_on-> libsql -maintained_by-> turso
+	mockStore.On("GetEntity", mock.Anything, "alice").Return(&Entity{ID: "alice"}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("alice")).Return([]*Edge{
+		{ID: "e1", SourceID: "alice", TargetID: "vvfs", Relation: "works_on"},
+	}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("vvfs")).Return([]*Edge{
+		{ID: "e2", SourceID: "vvfs", TargetID: "libsql", Relation: "depends_on"},
+	}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("libsql")).Return([]*Edge{
+		{ID: "e3", SourceID: "libsql", TargetID: "turso", Relation: "maintained_by"},
+	}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("turso")).Return([]*Edge{}, nil)
+
+	results, err := search.SearchFromCenter(context.Background(), "alice", "", 3, 10)
+	assert.NoError(t, err)
+
+	paths := make(map[string][]string)
+	for _, r := range results {
+		paths[r.EntityID] = r.Path
+		assert.Len(t, r.Path, 2*r.PathLength+1)
+	}
+	assert.Equal(t, []string{"alice", "works_on", "vvfs"}, paths["vvfs"])
+	assert.Equal(t, []string{"alice", "works_on", "vvfs", "depends_on", "libsql"}, paths["libsql"])
+	assert.Equal(t, []string{"alice", "works_on", "vvfs", "depends_on", "libsql", "maintained_by", "turso"}, paths["turso"])
+
+	// Depth caps the path length
+	results, err = search.SearchFromCenter(context.Background(), "alice", "", 2, 10)
+	assert.NoError(t, err)
+	for _, r := range results {
+		assert.LessOrEqual(t, r.PathLength, 2)
+		assert.NotEqual(t, "turso", r.EntityID)
+	}
+}
+
+// TestGraphSearchImpl_PathsOffByDefault verifies paths are only built on request
+func TestGraphSearchImpl_PathsOffByDefault(t *testing.T) {
+	mockStore := new(MockGraphStore)
+	search := NewGraphSearch(mockStore, &config.MemoryConfig{GraphDepth: 2, K: 5})
+
+	mockStore.On("GetEntity", mock.Anything, "center1").Return(&Entity{ID: "center1"}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("center1")).Return([]*Edge{
+		{ID: "edge1", SourceID: "center1", TargetID: "entity2", Relation: "works_with"},
+	}, nil)
+	mockStore.On("ListEdges", mock.Anything, edgesFrom("entity2")).Return([]*Edge{}, nil)
+
+	results, err := search.SearchFromCenter(context.Background(), "center1", "", 2, 5)
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Nil(t, results[0].Path)
+
+	results, err = search.SearchWithPathBoost(context.Background(), "", GraphSearchOptions{CenterID: "center1", IncludePaths: true})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"center1", "works_with", "entity2"}, results[0].Path)
+}
+
+// TestGraphSearchImpl_EdgeWeights verifies stronger paths outrank shorter but weaker ones
+func TestGraphSearchImpl_EdgeWeights(t *testing.T) {
+	// hub -cites(0.1)-> weak, hub -cites(1.0)-> bridge -cites(0.9)-> strong
+	newStore := func() *MockGraphStore {
+		mockStore := new(MockGraphStore)
+		mockStore.On("GetEntity", mock.Anything, "hub").Return(&Entity{ID: "hub"}, nil)
+		mockStore.On("ListEdges", mock.Anything, edgesFrom("hub")).Return([]*Edge{
+			{ID: "e1", SourceID: "hub", TargetID: "weak", Relation: "cites", Attrs: map[string]interface{}{"weight": 0.1}},
+			{ID: "e2", SourceID: "hub", TargetID: "bridge", Relation: "cites", Attrs: map[string]interface{}{"weight": 1.0}},
+		}, nil)
+		mockStore.On("ListEdges", mock.Anything, edgesFrom("weak")).Return([]*Edge{}, nil)
+		mockStore.On("ListEdges", mock.Anything, edgesFrom("bridge")).Return([]*Edge{
+			{ID: "e3", SourceID: "bridge", TargetID: "strong", Relation: "cites", Attrs: map[string]interface{}{"weight": 0.9}},
+		}, nil)
+		mockStore.On("ListEdges", mock.Anything, edgesFrom("strong")).Return([]*Edge{}, nil)
+		return mockStore
+	}
+	rank := func(results []GraphSearchResult, id string) int {
+		for i, r := range results {
+			if r.EntityID == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	search := NewGraphSearch(newStore(), &config.MemoryConfig{GraphDepth: 2, K: 10, GraphEdgeWeightFactor: 1})
+	results, err := search.SearchFromCenter(context.Background(), "hub", "", 2, 10)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Less(t, rank(results, "strong"), rank(results, "weak"))
+	assert.Equal(t, "weak", results[len(results)-1].EntityID)
+
+	// Path boosting keeps the weighted order
+	results, err = search.SearchWithPathBoost(context.Background(), "", GraphSearchOptions{CenterID: "hub", Depth: 2, K: 10, PathWeights: true})
+	assert.NoError(t, err)
+	assert.Less(t, rank(results, "strong"), rank(results, "weak"))
+
+	// With weights ignored, the shorter path wins again
+	search = NewGraphSearch(newStore(), &config.MemoryConfig{GraphDepth: 2, K: 10})
+	results, err = search.SearchFromCenter(context.Background(), "hub", "", 2, 10)
+	assert.NoError(t, err)
+	assert.Less(t, rank(results, "weak"), rank(results, "strong"))
+
+	// Missing or invalid weights default to 1.0
+	assert.Equal(t, 1.0, (&Edge{}).Weight())
+	assert.Equal(t, 1.0, (&Edge{Attrs: map[string]interface{}{"weight": "heavy"}}).Weight())
+	assert.Equal(t, 1.0, (&Edge{Attrs: map[string]interface{}{"weight": -2.0}}).Weight())
+	assert.Equal(t, 3.0, (&Edge{Attrs: map[string]interface{}{"weight": 3}}).Weight())
+}
@@ -5,12 +5,18 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/database"
 )
 
+// getEntitiesChunkSize keeps IN lists well under SQLite's bound parameter limit
+const getEntitiesChunkSize = 500
+
 // GraphStoreImpl implements GraphStore using SQL database
 type GraphStoreImpl struct {
-	db *sql.DB
+	db database.DBTX
 }
 
 // NewGraphStore creates a new graph store
@@ -18,6 +24,12 @@ func NewGraphStore(db *sql.DB) *GraphStoreImpl {
 	return &GraphStoreImpl{db: db}
 }
 
+// NewGraphStoreTx creates a graph store whose statements run in tx, e.g. the Conn of the
+// Queries passed to DBManager.WithTx, so its writes commit or roll back with the transaction
+func NewGraphStoreTx(tx database.DBTX) *GraphStoreImpl {
+	return &GraphStoreImpl{db: tx}
+}
+
 // GetEntity retrieves an entity by ID
 func (gs *GraphStoreImpl) GetEntity(ctx context.Context, id string) (*Entity, error) {
 	query := `
@@ -46,36 +58,81 @@ func (gs *GraphStoreImpl) GetEntity(ctx context.Context, id string) (*Entity, er
 	return entity, nil
 }
 
-// UpsertEntity inserts or updates an entity
-func (gs *GraphStoreImpl) UpsertEntity(ctx context.Context, entity *Entity) error {
-	// Check if entity exists
-	existing, err := gs.GetEntity(ctx, entity.ID)
-	if err != nil && err.Error() != "entity not found: "+entity.ID {
-		return err
+// GetEntities retrieves entities by ID in one query per chunk, keyed by ID.
+// IDs with no stored entity are left out of the result.
+func (gs *GraphStoreImpl) GetEntities(ctx context.Context, ids []string) (map[string]*Entity, error) {
+	entities := make(map[string]*Entity, len(ids))
+
+	for start := 0; start < len(ids); start += getEntitiesChunkSize {
+		chunk := ids[start:min(start+getEntitiesChunkSize, len(ids))]
+
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = id
+		}
+
+		query := `
+			SELECT id, kind, name, summary, attrs_json, created_at, updated_at
+			FROM entities
+			WHERE id IN (` + strings.Join(placeholders, ", ") + `)
+		`
+
+		rows, err := gs.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entities: %w", err)
+		}
+
+		for rows.Next() {
+			entity := &Entity{}
+			var attrsJSON string
+			err := rows.Scan(
+				&entity.ID, &entity.Kind, &entity.Name, &entity.Summary,
+				&attrsJSON, &entity.CreatedAt, &entity.UpdatedAt,
+			)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan entity: %w", err)
+			}
+
+			if err := json.Unmarshal([]byte(attrsJSON), &entity.Attrs); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to unmarshal attrs: %w", err)
+			}
+
+			entities[entity.ID] = entity
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate entities: %w", err)
+		}
 	}
 
+	return entities, nil
+}
+
+// UpsertEntity inserts or updates an entity in one atomic statement, so concurrent upserts of the
+// same ID cannot both insert. created_at is kept from the first insert while updated_at is bumped
+func (gs *GraphStoreImpl) UpsertEntity(ctx context.Context, entity *Entity) error {
 	attrsJSON, err := json.Marshal(entity.Attrs)
 	if err != nil {
 		return fmt.Errorf("failed to marshal attrs: %w", err)
 	}
 
-	if existing != nil {
-		// Update existing entity
-		query := `
-			UPDATE entities
-			SET kind = $2, name = $3, summary = $4, attrs_json = $5, updated_at = CURRENT_TIMESTAMP
-			WHERE id = $1
-		`
-		_, err = gs.db.ExecContext(ctx, query, entity.ID, entity.Kind, entity.Name, entity.Summary, string(attrsJSON))
-	} else {
-		// Insert new entity
-		query := `
-			INSERT INTO entities (id, kind, name, summary, attrs_json, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-		`
-		_, err = gs.db.ExecContext(ctx, query, entity.ID, entity.Kind, entity.Name, entity.Summary, string(attrsJSON))
-	}
-
+	query := `
+		INSERT INTO entities (id, kind, name, summary, attrs_json, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			name = EXCLUDED.name,
+			summary = EXCLUDED.summary,
+			attrs_json = EXCLUDED.attrs_json,
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err = gs.db.ExecContext(ctx, query, entity.ID, entity.Kind, entity.Name, entity.Summary, string(attrsJSON))
 	if err != nil {
 		return fmt.Errorf("failed to upsert entity: %w", err)
 	}
@@ -95,42 +152,43 @@ func (gs *GraphStoreImpl) DeleteEntity(ctx context.Context, id string) error {
 
 // ListEntities retrieves entities with pagination and filtering
 func (gs *GraphStoreImpl) ListEntities(ctx context.Context, opts ListOptions) ([]*Entity, error) {
+	var entities []*Entity
+	err := gs.IterateEntities(ctx, opts, func(entity *Entity) error {
+		entities = append(entities, entity)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// IterateEntities streams entities matching opts through fn without materializing the result set.
+// Iteration stops at the first error returned by fn, which is passed through unchanged.
+func (gs *GraphStoreImpl) IterateEntities(ctx context.Context, opts ListOptions, fn func(*Entity) error) error {
 	query := `
 		SELECT id, kind, name, summary, attrs_json, created_at, updated_at
 		FROM entities
 	`
+	var args []interface{}
 
 	// FIXME: Apply filters (simplified - can be extended)
 	if filter := opts.Filter; filter != nil {
 		// FIXME: Example: filter by kind
-		if _, ok := filter["kind"]; ok {
+		if kind, ok := filter["kind"]; ok {
 			query += " WHERE kind = $1"
-			// FIXME: This is a simplified example; in practice, use proper query building
+			args = append(args, kind)
 		}
 	}
 
-	// FIXME: Apply sorting
-	if opts.Sort != "" {
-		query += " ORDER BY " + opts.Sort
-	} else {
-		query += " ORDER BY created_at DESC"
-	}
-
-	// FIXME: Apply pagination
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-	}
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
-	}
+	query += listOrderAndPage(opts, "created_at DESC")
 
-	rows, err := gs.db.QueryContext(ctx, query)
+	rows, err := gs.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list entities: %w", err)
+		return fmt.Errorf("failed to list entities: %w", err)
 	}
 	defer rows.Close()
 
-	var entities []*Entity
 	for rows.Next() {
 		entity := &Entity{}
 		var attrsJSON string
@@ -139,17 +197,46 @@ func (gs *GraphStoreImpl) ListEntities(ctx context.Context, opts ListOptions) ([
 			&attrsJSON, &entity.CreatedAt, &entity.UpdatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan entity: %w", err)
+			return fmt.Errorf("failed to scan entity: %w", err)
 		}
 
 		if err := json.Unmarshal([]byte(attrsJSON), &entity.Attrs); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal attrs: %w", err)
+			return fmt.Errorf("failed to unmarshal attrs: %w", err)
 		}
 
-		entities = append(entities, entity)
+		if err := fn(entity); err != nil {
+			return err
+		}
 	}
 
-	return entities, nil
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate entities: %w", err)
+	}
+	return nil
+}
+
+// listOrderAndPage renders the ORDER BY / LIMIT / OFFSET suffix for list queries
+func listOrderAndPage(opts ListOptions, defaultSort string) string {
+	var clause string
+
+	// FIXME: Apply sorting
+	if opts.Sort != "" {
+		clause += " ORDER BY " + opts.Sort
+	} else {
+		clause += " ORDER BY " + defaultSort
+	}
+
+	// SQLite only accepts OFFSET after a LIMIT; -1 means unbounded
+	if opts.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	} else if opts.Offset > 0 {
+		clause += " LIMIT -1"
+	}
+	if opts.Offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", opts.Offset)
+	}
+
+	return clause
 }
 
 // GetEdge retrieves an edge by ID
@@ -261,41 +348,48 @@ func (gs *GraphStoreImpl) InvalidateEdge(ctx context.Context, id string, reason
 
 // ListEdges retrieves edges with pagination and filtering
 func (gs *GraphStoreImpl) ListEdges(ctx context.Context, opts ListOptions) ([]*Edge, error) {
+	var edges []*Edge
+	err := gs.IterateEdges(ctx, opts, func(edge *Edge) error {
+		edges = append(edges, edge)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edges, nil
+}
+
+// IterateEdges streams edges matching opts through fn without materializing the result set.
+// Iteration stops at the first error returned by fn, which is passed through unchanged.
+func (gs *GraphStoreImpl) IterateEdges(ctx context.Context, opts ListOptions, fn func(*Edge) error) error {
 	query := `
 		SELECT id, src_id, dst_id, rel, attrs_json, valid_from, valid_to, ingested_at, invalidated_at, provenance_json
 		FROM edges
 	`
+	var args []interface{}
 
 	// FIXME: Apply filters (simplified)
 	if filter := opts.Filter; filter != nil {
 		// Example: filter by source entity
-		if _, ok := filter["src_id"]; ok {
+		if srcID, ok := filter["src_id"]; ok {
 			query += " WHERE src_id = $1"
+			args = append(args, srcID)
 		}
 	}
 
-	// FIXME: Apply sorting
-	if opts.Sort != "" {
-		query += " ORDER BY " + opts.Sort
-	} else {
-		query += " ORDER BY ingested_at DESC"
-	}
-
-	// FIXME: Apply pagination
-	if opts.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
-	}
-	if opts.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
-	}
+	query += listOrderAndPage(opts, "ingested_at DESC")
 
-	rows, err := gs.db.QueryContext(ctx, query)
+	rows, err := gs.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list edges: %w", err)
+		return fmt.Errorf("failed to list edges: %w", err)
 	}
 	defer rows.Close()
 
-	var edges []*Edge
+	return scanEdgeRows(rows, fn)
+}
+
+// scanEdgeRows decodes edge rows selected in the standard column order and passes each to fn
+func scanEdgeRows(rows *sql.Rows, fn func(*Edge) error) error {
 	for rows.Next() {
 		edge := &Edge{}
 		var attrsJSON, provenanceJSON string
@@ -306,7 +400,7 @@ func (gs *GraphStoreImpl) ListEdges(ctx context.Context, opts ListOptions) ([]*E
 			&attrsJSON, &edge.ValidFrom, &validTo, &edge.IngestedAt, &invalidatedAt, &provenanceJSON,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan edge: %w", err)
+			return fmt.Errorf("failed to scan edge: %w", err)
 		}
 
 		if validTo.Valid {
@@ -317,15 +411,73 @@ func (gs *GraphStoreImpl) ListEdges(ctx context.Context, opts ListOptions) ([]*E
 		}
 
 		if err := json.Unmarshal([]byte(attrsJSON), &edge.Attrs); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal attrs: %w", err)
+			return fmt.Errorf("failed to unmarshal attrs: %w", err)
 		}
 		if err := json.Unmarshal([]byte(provenanceJSON), &edge.Provenance); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal provenance: %w", err)
+			return fmt.Errorf("failed to unmarshal provenance: %w", err)
 		}
 
-		edges = append(edges, edge)
+		if err := fn(edge); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate edges: %w", err)
 	}
+	return nil
+}
+
+// Direction selects which edges touching an entity EdgesForEntity returns
+type Direction string
+
+const (
+	DirectionOut  Direction = "out"  // edges whose source is the entity
+	DirectionIn   Direction = "in"   // edges whose target is the entity
+	DirectionBoth Direction = "both" // edges on either side of the entity
+)
+
+// EdgesForEntity returns edges touching entityID in the given direction, ordered by ingestion
+// time (newest first) and paginated by opts.Limit and opts.Offset. Only current edges are
+// returned unless opts.Filter["include_invalidated"] is true
+func (gs *GraphStoreImpl) EdgesForEntity(ctx context.Context, entityID string, direction Direction, opts ListOptions) ([]*Edge, error) {
+	var where string
+	args := []interface{}{entityID}
+	switch direction {
+	case DirectionOut:
+		where = "src_id = $1"
+	case DirectionIn:
+		where = "dst_id = $1"
+	case DirectionBoth:
+		where = "(src_id = $1 OR dst_id = $2)"
+		args = append(args, entityID)
+	default:
+		return nil, fmt.Errorf("invalid edge direction: %q", direction)
+	}
+
+	if include, _ := opts.Filter["include_invalidated"].(bool); !include {
+		where += " AND valid_to IS NULL AND invalidated_at IS NULL"
+	}
+
+	query := `
+		SELECT id, src_id, dst_id, rel, attrs_json, valid_from, valid_to, ingested_at, invalidated_at, provenance_json
+		FROM edges
+		WHERE ` + where + listOrderAndPage(ListOptions{Limit: opts.Limit, Offset: opts.Offset}, "ingested_at DESC, id")
+
+	rows, err := gs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edges for entity: %w", err)
+	}
+	defer rows.Close()
 
+	var edges []*Edge
+	err = scanEdgeRows(rows, func(edge *Edge) error {
+		edges = append(edges, edge)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return edges, nil
 }
 
@@ -410,3 +562,87 @@ func (gs *GraphStoreImpl) GetCurrentEdges(ctx context.Context, opts ListOptions)
 
 	return edges, nil
 }
+
+// DanglingEdge is an edge whose source or target entity no longer exists
+type DanglingEdge struct {
+	EdgeID        string `json:"edge_id"`
+	SourceID      string `json:"source_id"`
+	TargetID      string `json:"target_id"`
+	MissingSource bool   `json:"missing_source"`
+	MissingTarget bool   `json:"missing_target"`
+}
+
+// GraphReport summarizes a graph consistency check
+type GraphReport struct {
+	EdgesChecked  int            `json:"edges_checked"`  // edges still valid at check time
+	DanglingEdges []DanglingEdge `json:"dangling_edges"` // valid edges with a missing endpoint, ordered by edge ID
+}
+
+// Consistent reports whether the check found no dangling edges
+func (r *GraphReport) Consistent() bool {
+	return len(r.DanglingEdges) == 0
+}
+
+// ValidateGraph finds valid edges whose src_id or dst_id has no matching entity.
+// Foreign keys are not guaranteed to be enforced, so deleting an entity can leave such edges behind
+func (gs *GraphStoreImpl) ValidateGraph(ctx context.Context) (*GraphReport, error) {
+	report := &GraphReport{}
+
+	err := gs.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM edges WHERE invalidated_at IS NULL`).Scan(&report.EdgesChecked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count edges: %w", err)
+	}
+
+	query := `
+		SELECT e.id, e.src_id, e.dst_id, src.id IS NULL, dst.id IS NULL
+		FROM edges e
+		LEFT JOIN entities src ON src.id = e.src_id
+		LEFT JOIN entities dst ON dst.id = e.dst_id
+		WHERE e.invalidated_at IS NULL AND (src.id IS NULL OR dst.id IS NULL)
+		ORDER BY e.id
+	`
+
+	rows, err := gs.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find dangling edges: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dangling DanglingEdge
+		if err := rows.Scan(&dangling.EdgeID, &dangling.SourceID, &dangling.TargetID, &dangling.MissingSource, &dangling.MissingTarget); err != nil {
+			return nil, fmt.Errorf("failed to scan dangling edge: %w", err)
+		}
+		report.DanglingEdges = append(report.DanglingEdges, dangling)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate dangling edges: %w", err)
+	}
+
+	return report, nil
+}
+
+// PruneOrphanEdges invalidates every valid edge with a missing endpoint and returns how many
+// were invalidated. Edges are invalidated rather than deleted so temporal queries keep their
+// history; the single UPDATE makes the prune atomic
+func (gs *GraphStoreImpl) PruneOrphanEdges(ctx context.Context) (int, error) {
+	query := `
+		UPDATE edges
+		SET invalidated_at = CURRENT_TIMESTAMP, valid_to = CURRENT_TIMESTAMP
+		WHERE invalidated_at IS NULL
+			AND (NOT EXISTS (SELECT 1 FROM entities WHERE entities.id = edges.src_id)
+				OR NOT EXISTS (SELECT 1 FROM entities WHERE entities.id = edges.dst_id))
+	`
+
+	result, err := gs.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune orphan edges: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -2,10 +2,17 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockGraphStore for testing
@@ -18,6 +25,11 @@ func (m *MockGraphStore) GetEntity(ctx context.Context, id string) (*Entity, err
 	return args.Get(0).(*Entity), args.Error(1)
 }
 
+func (m *MockGraphStore) GetEntities(ctx context.Context, ids []string) (map[string]*Entity, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(map[string]*Entity), args.Error(1)
+}
+
 func (m *MockGraphStore) UpsertEntity(ctx context.Context, entity *Entity) error {
 	args := m.Called(ctx, entity)
 	return args.Error(0)
@@ -33,6 +45,11 @@ func (m *MockGraphStore) ListEntities(ctx context.Context, opts ListOptions) ([]
 	return args.Get(0).([]*Entity), args.Error(1)
 }
 
+func (m *MockGraphStore) IterateEntities(ctx context.Context, opts ListOptions, fn func(*Entity) error) error {
+	args := m.Called(ctx, opts, fn)
+	return args.Error(0)
+}
+
 func (m *MockGraphStore) GetEdge(ctx context.Context, id string) (*Edge, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*Edge), args.Error(1)
@@ -53,6 +70,16 @@ func (m *MockGraphStore) ListEdges(ctx context.Context, opts ListOptions) ([]*Ed
 	return args.Get(0).([]*Edge), args.Error(1)
 }
 
+func (m *MockGraphStore) IterateEdges(ctx context.Context, opts ListOptions, fn func(*Edge) error) error {
+	args := m.Called(ctx, opts, fn)
+	return args.Error(0)
+}
+
+func (m *MockGraphStore) EdgesForEntity(ctx context.Context, entityID string, direction Direction, opts ListOptions) ([]*Edge, error) {
+	args := m.Called(ctx, entityID, direction, opts)
+	return args.Get(0).([]*Edge), args.Error(1)
+}
+
 func (m *MockGraphStore) GetEdgesAsOf(ctx context.Context, timepoint time.Time, opts ListOptions) ([]*Edge, error) {
 	args := m.Called(ctx, timepoint, opts)
 	return args.Get(0).([]*Edge), args.Error(1)
@@ -70,16 +97,369 @@ func TestGraphStoreImpl_GetEntity(t *testing.T) {
 	t.Skip("Requires database setup")
 }
 
-// TestGraphStoreImpl_UpsertEntity tests entity upsert
-func TestGraphStoreImpl_UpsertEntity(t *testing.T) {
-	// This would require a real database connection
-	// For now, placeholder test structure
-	t.Skip("Requires database setup")
-}
-
 // TestGraphStoreImpl_TemporalQueries tests as-of and current edge queries
 func TestGraphStoreImpl_TemporalQueries(t *testing.T) {
 	// This would require a real database connection
 	// For now, placeholder test structure
 	t.Skip("Requires database setup")
 }
+
+// testGraphSchema creates the entities and edges tables, in that order
+var testGraphSchema = []string{
+	`CREATE TABLE entities (
+		id TEXT PRIMARY KEY,
+		kind TEXT NOT NULL,
+		name TEXT NOT NULL,
+		summary TEXT,
+		attrs_json TEXT,
+		created_at DATETIME,
+		updated_at DATETIME
+	)`,
+	`CREATE TABLE edges (
+		id TEXT PRIMARY KEY,
+		src_id TEXT NOT NULL,
+		dst_id TEXT NOT NULL,
+		rel TEXT NOT NULL,
+		attrs_json TEXT,
+		valid_from DATETIME NOT NULL,
+		valid_to DATETIME,
+		ingested_at DATETIME NOT NULL,
+		invalidated_at DATETIME,
+		provenance_json TEXT
+	)`,
+}
+
+// openTestGraphDB opens a file-backed libSQL database with the graph store tables
+func openTestGraphDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("libsql", "file:"+filepath.Join(t.TempDir(), "graph.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	for _, stmt := range testGraphSchema {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+// seedTestGraph inserts n entities chained by n-1 edges
+func seedTestGraph(t *testing.T, store *GraphStoreImpl, n int) {
+	ctx := context.Background()
+	now := time.Now()
+	for i := 0; i < n; i++ {
+		require.NoError(t, store.UpsertEntity(ctx, &Entity{
+			ID:    fmt.Sprintf("entity-%d", i),
+			Kind:  "concept",
+			Name:  fmt.Sprintf("Concept %d", i),
+			Attrs: map[string]interface{}{"index": i},
+		}))
+		if i > 0 {
+			require.NoError(t, store.UpsertEdge(ctx, &Edge{
+				ID:         fmt.Sprintf("edge-%d", i),
+				SourceID:   fmt.Sprintf("entity-%d", i-1),
+				TargetID:   fmt.Sprintf("entity-%d", i),
+				Relation:   "related_to",
+				Attrs:      map[string]interface{}{},
+				ValidFrom:  now,
+				IngestedAt: now,
+				Provenance: map[string]interface{}{"source": "test"},
+			}))
+		}
+	}
+}
+
+// TestGraphStoreImpl_IterateEntities tests streaming entity iteration
+func TestGraphStoreImpl_IterateEntities(t *testing.T) {
+	ctx := context.Background()
+	store := NewGraphStore(openTestGraphDB(t))
+	seedTestGraph(t, store, 10)
+
+	// Visits every row
+	seen := make(map[string]bool)
+	err := store.IterateEntities(ctx, ListOptions{}, func(entity *Entity) error {
+		seen[entity.ID] = true
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, seen, 10)
+
+	// Respects Limit
+	count := 0
+	err = store.IterateEntities(ctx, ListOptions{Limit: 3}, func(entity *Entity) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// Stops on the first callback error and returns it unchanged
+	errStop := errors.New("stop")
+	count = 0
+	err = store.IterateEntities(ctx, ListOptions{}, func(entity *Entity) error {
+		count++
+		if count == 2 {
+			return errStop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 2, count)
+
+	// The slice variant sees the same rows
+	entities, err := store.ListEntities(ctx, ListOptions{Filter: map[string]interface{}{"kind": "concept"}})
+	require.NoError(t, err)
+	assert.Len(t, entities, 10)
+}
+
+// TestGraphStoreImpl_UpsertEntity tests that updates keep created_at and bump updated_at
+func TestGraphStoreImpl_UpsertEntity(t *testing.T) {
+	ctx := context.Background()
+	db := openTestGraphDB(t)
+	store := NewGraphStore(db)
+
+	_, err := db.Exec(`INSERT INTO entities (id, kind, name, summary, attrs_json, created_at, updated_at)
+		VALUES ('entity-1', 'concept', 'Old', '', '{}', '2020-01-01 00:00:00', '2020-01-01 00:00:00')`)
+	require.NoError(t, err)
+
+	require.NoError(t, store.UpsertEntity(ctx, &Entity{
+		ID: "entity-1", Kind: "person", Name: "New", Summary: "updated",
+		Attrs: map[string]interface{}{"role": "author"},
+	}))
+
+	entity, err := store.GetEntity(ctx, "entity-1")
+	require.NoError(t, err)
+	assert.Equal(t, "person", entity.Kind)
+	assert.Equal(t, "New", entity.Name)
+	assert.Equal(t, "updated", entity.Summary)
+	assert.Equal(t, "author", entity.Attrs["role"])
+	assert.Equal(t, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), entity.CreatedAt.UTC())
+	assert.True(t, entity.UpdatedAt.After(entity.CreatedAt), "updated_at is bumped")
+}
+
+// TestGraphStoreImpl_UpsertEntityConcurrent tests concurrent upserts of one ID
+func TestGraphStoreImpl_UpsertEntityConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db := openTestGraphDB(t)
+	// One connection avoids SQLITE_BUSY; statements from different goroutines still interleave
+	db.SetMaxOpenConns(1)
+	store := NewGraphStore(db)
+
+	const writers = 32
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- store.UpsertEntity(ctx, &Entity{
+				ID:    "shared",
+				Kind:  "concept",
+				Name:  fmt.Sprintf("writer %d", i),
+				Attrs: map[string]interface{}{"writer": float64(i)},
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM entities WHERE id = 'shared'`).Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// The surviving row is one writer's complete data
+	entity, err := store.GetEntity(ctx, "shared")
+	require.NoError(t, err)
+	writer, ok := entity.Attrs["writer"].(float64)
+	require.True(t, ok)
+	assert.Equal(t, fmt.Sprintf("writer %d", int(writer)), entity.Name)
+}
+
+// TestGraphStoreImpl_IterateEdges tests streaming edge iteration
+func TestGraphStoreImpl_IterateEdges(t *testing.T) {
+	ctx := context.Background()
+	store := NewGraphStore(openTestGraphDB(t))
+	seedTestGraph(t, store, 10)
+
+	count := 0
+	err := store.IterateEdges(ctx, ListOptions{}, func(edge *Edge) error {
+		count++
+		assert.Equal(t, "related_to", edge.Relation)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 9, count)
+
+	count = 0
+	err = store.IterateEdges(ctx, ListOptions{Limit: 4, Offset: 2}, func(edge *Edge) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	errStop := errors.New("stop")
+	count = 0
+	err = store.IterateEdges(ctx, ListOptions{}, func(edge *Edge) error {
+		count++
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+	assert.Equal(t, 1, count)
+
+	edges, err := store.ListEdges(ctx, ListOptions{Filter: map[string]interface{}{"src_id": "entity-0"}})
+	require.NoError(t, err)
+	require.Len(t, edges, 1)
+	assert.Equal(t, "entity-1", edges[0].TargetID)
+}
+
+// TestGraphStoreImpl_GetEntities tests batch lookup with present and missing IDs
+func TestGraphStoreImpl_GetEntities(t *testing.T) {
+	ctx := context.Background()
+	store := NewGraphStore(openTestGraphDB(t))
+	seedTestGraph(t, store, 5)
+
+	// All present
+	entities, err := store.GetEntities(ctx, []string{"entity-0", "entity-2", "entity-4"})
+	require.NoError(t, err)
+	require.Len(t, entities, 3)
+	assert.Equal(t, "Concept 2", entities["entity-2"].Name)
+	assert.Equal(t, float64(4), entities["entity-4"].Attrs["index"])
+
+	// Missing IDs are omitted, duplicates collapse
+	entities, err = store.GetEntities(ctx, []string{"entity-1", "missing", "entity-1", "entity-3", "also-missing"})
+	require.NoError(t, err)
+	assert.Len(t, entities, 2)
+	assert.Contains(t, entities, "entity-1")
+	assert.Contains(t, entities, "entity-3")
+	assert.NotContains(t, entities, "missing")
+
+	// Empty input needs no query
+	entities, err = store.GetEntities(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, entities)
+
+	// Lookups larger than one IN chunk are split
+	ids := make([]string, getEntitiesChunkSize+10)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("entity-%d", i%7)
+	}
+	entities, err = store.GetEntities(ctx, ids)
+	require.NoError(t, err)
+	assert.Len(t, entities, 5)
+}
+
+// TestGraphStoreImpl_ValidateAndPruneOrphanEdges tests that edges left dangling by a deleted entity are found and invalidated
+func TestGraphStoreImpl_ValidateAndPruneOrphanEdges(t *testing.T) {
+	ctx := context.Background()
+	db := openTestGraphDB(t)
+	store := NewGraphStore(db)
+	seedTestGraph(t, store, 3)
+
+	report, err := store.ValidateGraph(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 2, report.EdgesChecked)
+
+	// Deleting entity-2 leaves edge-2 (entity-1 -> entity-2) without a target
+	require.NoError(t, store.DeleteEntity(ctx, "entity-2"))
+
+	report, err = store.ValidateGraph(ctx)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	require.Len(t, report.DanglingEdges, 1)
+	assert.Equal(t, DanglingEdge{
+		EdgeID:        "edge-2",
+		SourceID:      "entity-1",
+		TargetID:      "entity-2",
+		MissingTarget: true,
+	}, report.DanglingEdges[0])
+
+	pruned, err := store.PruneOrphanEdges(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+
+	var invalidated bool
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT invalidated_at IS NOT NULL FROM edges WHERE id = 'edge-2'`).Scan(&invalidated))
+	assert.True(t, invalidated)
+	require.NoError(t, db.QueryRowContext(ctx, `SELECT invalidated_at IS NOT NULL FROM edges WHERE id = 'edge-1'`).Scan(&invalidated))
+	assert.False(t, invalidated)
+
+	// The graph is consistent again and a second prune has nothing to do
+	report, err = store.ValidateGraph(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+	assert.Equal(t, 1, report.EdgesChecked)
+
+	pruned, err = store.PruneOrphanEdges(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, pruned)
+}
+
+// TestGraphStoreImpl_EdgesForEntity tests direction, pagination and validity filtering of an entity's edges
+func TestGraphStoreImpl_EdgesForEntity(t *testing.T) {
+	ctx := context.Background()
+	store := NewGraphStore(openTestGraphDB(t))
+
+	// hub -> a, hub -> b, c -> hub, a -> b; ingested one second apart so ordering is stable
+	base := time.Now().Add(-time.Hour)
+	for _, id := range []string{"hub", "a", "b", "c"} {
+		require.NoError(t, store.UpsertEntity(ctx, &Entity{ID: id, Kind: "concept", Name: id, Attrs: map[string]interface{}{}}))
+	}
+	for i, pair := range [][2]string{{"hub", "a"}, {"hub", "b"}, {"c", "hub"}, {"a", "b"}} {
+		at := base.Add(time.Duration(i) * time.Second)
+		require.NoError(t, store.UpsertEdge(ctx, &Edge{
+			ID:         pair[0] + "->" + pair[1],
+			SourceID:   pair[0],
+			TargetID:   pair[1],
+			Relation:   "related_to",
+			Attrs:      map[string]interface{}{},
+			ValidFrom:  at,
+			IngestedAt: at,
+			Provenance: map[string]interface{}{},
+		}))
+	}
+
+	ids := func(edges []*Edge) []string {
+		out := make([]string, len(edges))
+		for i, edge := range edges {
+			out[i] = edge.ID
+		}
+		return out
+	}
+
+	out, err := store.EdgesForEntity(ctx, "hub", DirectionOut, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hub->b", "hub->a"}, ids(out))
+
+	in, err := store.EdgesForEntity(ctx, "hub", DirectionIn, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c->hub"}, ids(in))
+
+	both, err := store.EdgesForEntity(ctx, "hub", DirectionBoth, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c->hub", "hub->b", "hub->a"}, ids(both))
+
+	// Pages partition the result
+	page1, err := store.EdgesForEntity(ctx, "hub", DirectionBoth, ListOptions{Limit: 2})
+	require.NoError(t, err)
+	page2, err := store.EdgesForEntity(ctx, "hub", DirectionBoth, ListOptions{Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c->hub", "hub->b"}, ids(page1))
+	assert.Equal(t, []string{"hub->a"}, ids(page2))
+
+	// Invalidated edges are hidden unless requested
+	require.NoError(t, store.InvalidateEdge(ctx, "hub->b", "superseded"))
+	out, err = store.EdgesForEntity(ctx, "hub", DirectionOut, ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hub->a"}, ids(out))
+	out, err = store.EdgesForEntity(ctx, "hub", DirectionOut, ListOptions{Filter: map[string]interface{}{"include_invalidated": true}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hub->b", "hub->a"}, ids(out))
+
+	_, err = store.EdgesForEntity(ctx, "hub", Direction("sideways"), ListOptions{})
+	assert.Error(t, err)
+}
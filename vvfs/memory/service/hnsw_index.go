@@ -2,17 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
 
+// ErrIndexPersistenceUnsupported is returned when an index cannot yet write
+// its state to disk
+var ErrIndexPersistenceUnsupported = errors.New("index persistence not implemented")
+
 // HNSWIndexImpl implements VectorIndex using HNSW algorithm
 type HNSWIndexImpl struct {
 	dimension int
 	metric    string      // "cosine" or "l2"
 	index     interface{} // Placeholder for HNSW index (use hnswlib or similar)
+	path      string      // Persistence path used by Flush (empty disables)
 	mu        sync.RWMutex
 	config    *config.MemoryConfig
 }
@@ -35,6 +41,7 @@ func NewHNSWIndex(config *config.MemoryConfig) (*HNSWIndexImpl, error) {
 		dimension: dimension,
 		metric:    metric,
 		// index:     index,
+		path:   config.IndexPath,
 		config: config,
 	}, nil
 }
@@ -107,6 +114,22 @@ func (hi *HNSWIndexImpl) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// Clear resets the graph, dropping every vector
+func (hi *HNSWIndexImpl) Clear(ctx context.Context) error {
+	hi.mu.Lock()
+	defer hi.mu.Unlock()
+
+	// Placeholder: in real implementation, rebuild an empty graph
+	// index, err := hnswlib.NewHNSW(hi.dimension, hi.metric, hi.config.HNSWM, hi.config.HNSWEFConstruction)
+	// if err != nil {
+	//     return fmt.Errorf("failed to reset HNSW index: %w", err)
+	// }
+	// hi.index = index
+	hi.index = nil
+
+	return nil
+}
+
 // Close closes the index and releases resources
 func (hi *HNSWIndexImpl) Close() error {
 	hi.mu.Lock()
@@ -136,6 +159,22 @@ func (hi *HNSWIndexImpl) Save(path string) error {
 	// Placeholder: in real implementation, call index.Save(path)
 	// return hi.index.Save(path)
 
+	return fmt.Errorf("failed to save HNSW index to %s: %w", path, ErrIndexPersistenceUnsupported)
+}
+
+// Flush persists the in-memory graph to the configured index path. Until
+// Save is implemented this reports ErrIndexPersistenceUnsupported rather
+// than claiming durability when an index path is configured.
+func (hi *HNSWIndexImpl) Flush(ctx context.Context) error {
+	if hi.path == "" {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := hi.Save(hi.path); err != nil {
+		return fmt.Errorf("failed to persist HNSW index: %w", err)
+	}
 	return nil
 }
 
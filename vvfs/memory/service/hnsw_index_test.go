@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+)
+
+func TestHNSWIndex_FlushReportsMissingPersistence(t *testing.T) {
+	ctx := context.Background()
+
+	// Without an index path there is nothing to persist
+	index, err := NewHNSWIndex(&config.MemoryConfig{})
+	require.NoError(t, err)
+	assert.NoError(t, index.Flush(ctx))
+
+	// With a path configured, Flush must not claim durability it cannot provide
+	index, err = NewHNSWIndex(&config.MemoryConfig{IndexPath: filepath.Join(t.TempDir(), "hnsw.idx")})
+	require.NoError(t, err)
+	assert.ErrorIs(t, index.Flush(ctx), ErrIndexPersistenceUnsupported)
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"hash/fnv"
+	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/database"
 )
 
 // Ingester handles parallel ingestion of memory items
@@ -17,8 +20,15 @@ type Ingester struct {
 	lexicalIndex LexicalIndex
 	graphStore   GraphStore
 	extractor    KnowledgeExtractor
+	embedder     Embedder               // Optional: embeds items queued without an embedding
+	store        MemoryStore            // Optional: required by UpsertBySourceRef
+	deadLetters  *DeadLetterStore       // Optional: failed tasks are retried from here
+	versions     *EmbeddingVersionStore // Optional: records the model version of each vector
+	modelVersion string
 	metrics      *MetricsCollector
 	queue        chan *IngestionTask
+	pending      atomic.Int64 // Tasks enqueued but not yet processed
+	dedupMu      sync.Mutex   // Serializes read-modify-write of canonical items in mergeDuplicate
 	wg           sync.WaitGroup
 	mu           sync.RWMutex
 	stopping     bool
@@ -54,6 +64,27 @@ func NewIngester(config *config.MemoryConfig, vectorIndex VectorIndex, lexicalIn
 	return ingester
 }
 
+// SetEmbedder embeds items that arrive without an embedding
+func (ing *Ingester) SetEmbedder(embedder Embedder) {
+	ing.embedder = embedder
+}
+
+// SetMemoryStore sets the store UpsertBySourceRef reads and writes items through
+func (ing *Ingester) SetMemoryStore(store MemoryStore) {
+	ing.store = store
+}
+
+// SetDeadLetterStore records failed tasks for RetryFailedIngests instead of dropping them
+func (ing *Ingester) SetDeadLetterStore(store *DeadLetterStore) {
+	ing.deadLetters = store
+}
+
+// SetEmbeddingVersions tags every vector written with modelVersion in store
+func (ing *Ingester) SetEmbeddingVersions(store *EmbeddingVersionStore, modelVersion string) {
+	ing.versions = store
+	ing.modelVersion = modelVersion
+}
+
 // IngestMemoryItem ingests a memory item with idempotence and backpressure
 func (ing *Ingester) IngestMemoryItem(ctx context.Context, item *MemoryItem) error {
 	return ing.IngestWithPriority(ctx, item, nil, 0)
@@ -61,6 +92,10 @@ func (ing *Ingester) IngestMemoryItem(ctx context.Context, item *MemoryItem) err
 
 // IngestWithPriority ingests an item with specified priority
 func (ing *Ingester) IngestWithPriority(ctx context.Context, item *MemoryItem, episode *Episode, priority int) error {
+	// Count the task before it becomes visible to workers so Drain never
+	// observes an empty pipeline while a task is in flight
+	ing.pending.Add(1)
+
 	// Check for backpressure
 	select {
 	case ing.queue <- &IngestionTask{
@@ -72,8 +107,10 @@ func (ing *Ingester) IngestWithPriority(ctx context.Context, item *MemoryItem, e
 	}:
 		return nil
 	case <-ctx.Done():
+		ing.pending.Add(-1)
 		return ctx.Err()
 	default:
+		ing.pending.Add(-1)
 		return fmt.Errorf("ingestion queue full, backpressure applied")
 	}
 }
@@ -88,6 +125,21 @@ func (ing *Ingester) IngestBatch(ctx context.Context, items []*MemoryItem) error
 	return nil
 }
 
+// Drain blocks until every queued and in-flight task has been processed
+func (ing *Ingester) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for ing.pending.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to drain ingestion queue (%d pending): %w", ing.pending.Load(), ctx.Err())
+		}
+	}
+	return nil
+}
+
 // Stop gracefully stops the ingester
 func (ing *Ingester) Stop() error {
 	ing.mu.Lock()
@@ -113,8 +165,16 @@ func (ing *Ingester) worker(id int) {
 
 		if err != nil {
 			// Log error but continue processing other tasks
-			fmt.Printf("Worker %d failed to process task %s: %v\n", id, task.ID, err)
+			log.Printf("Worker %d failed to process task %s: %v", id, task.ID, err)
+			// Dead-letter before the task stops counting as pending so Drain covers it
+			if ing.deadLetters != nil {
+				if dlErr := ing.deadLetters.Record(context.Background(), task, err); dlErr != nil {
+					log.Printf("Worker %d failed to dead-letter task %s: %v", id, task.ID, dlErr)
+					ing.metrics.RecordDeadLetterFailure()
+				}
+			}
 		}
+		ing.pending.Add(-1)
 	}
 }
 
@@ -126,6 +186,31 @@ func (ing *Ingester) processTask(ctx context.Context, task *IngestionTask) error
 		return nil // Already processed
 	}
 
+	// Embed before indexing so a failing embedder dead-letters the whole task
+	if err := ing.embedItem(ctx, task.Item); err != nil {
+		return err
+	}
+
+	// Skip near-duplicates of items already in the index
+	if ing.config.DedupThreshold > 0 && task.Item.Embedding != nil {
+		canonicalID, similarity, err := ing.findDuplicate(ctx, task.Item)
+		if err != nil {
+			return fmt.Errorf("dedup lookup failed: %w", err)
+		}
+		if canonicalID != "" {
+			if err := ing.mergeDuplicate(ctx, task.Item.ID, canonicalID); err != nil {
+				return fmt.Errorf("failed to merge duplicate %s into %s: %w", task.Item.ID, canonicalID, err)
+			}
+			ing.metrics.RecordDedup(DedupEvent{
+				ItemID:      task.Item.ID,
+				CanonicalID: canonicalID,
+				Similarity:  similarity,
+				At:          time.Now(),
+			})
+			return nil
+		}
+	}
+
 	// Parallel processing of vector, lexical, and graph ingestion
 	var wg sync.WaitGroup
 	var mu sync.Mutex
@@ -136,9 +221,9 @@ func (ing *Ingester) processTask(ctx context.Context, task *IngestionTask) error
 	go func() {
 		defer wg.Done()
 		if task.Item.Embedding != nil {
-			if err := ing.vectorIndex.Upsert(ctx, task.Item.ID, task.Item.Embedding); err != nil {
+			if err := ing.indexVector(ctx, task.Item); err != nil {
 				mu.Lock()
-				errors = append(errors, fmt.Errorf("vector upsert failed: %w", err))
+				errors = append(errors, err)
 				mu.Unlock()
 			}
 		}
@@ -174,6 +259,258 @@ func (ing *Ingester) processTask(ctx context.Context, task *IngestionTask) error
 	return nil
 }
 
+// embedItem embeds item when it was queued without an embedding and an embedder is set
+func (ing *Ingester) embedItem(ctx context.Context, item *MemoryItem) error {
+	if item.Embedding != nil || ing.embedder == nil {
+		return nil
+	}
+	embeddings, err := ing.embedder.Embed(ctx, []string{item.Text})
+	if err != nil {
+		return fmt.Errorf("embedding failed: %w", err)
+	}
+	if len(embeddings) != 1 {
+		return fmt.Errorf("embedding failed: expected 1 vector, got %d", len(embeddings))
+	}
+	item.Embedding = embeddings[0]
+	return nil
+}
+
+// indexVector upserts item's embedding and records the model version it came from
+func (ing *Ingester) indexVector(ctx context.Context, item *MemoryItem) error {
+	if err := ing.vectorIndex.Upsert(ctx, item.ID, item.Embedding); err != nil {
+		return fmt.Errorf("vector upsert failed: %w", err)
+	}
+	if ing.versions != nil {
+		return ing.versions.SetVersion(ctx, item.ID, ing.modelVersion)
+	}
+	return nil
+}
+
+// IngestAtomic ingests item synchronously, writing the item row with its embedding and the
+// graph extracted from episode in one transaction run by runTx, so a failure in any of them
+// rolls back all. Embedding and extraction run before the transaction; the vector index and
+// embedding versions are refreshed after it commits
+func (ing *Ingester) IngestAtomic(ctx context.Context, runTx TxRunner, item *MemoryItem, episode *Episode) error {
+	if err := ing.embedItem(ctx, item); err != nil {
+		return err
+	}
+
+	var extracted *ExtractionResult
+	if episode != nil && ing.extractor != nil {
+		result, err := ing.extractor.Extract(ctx, *episode)
+		if err != nil {
+			return fmt.Errorf("extraction failed: %w", err)
+		}
+		extracted = result
+	}
+
+	err := runTx(ctx, func(tx database.DBTX) error {
+		if err := NewMemoryStoreTx(tx).PutItem(ctx, item); err != nil {
+			return fmt.Errorf("failed to store memory item: %w", err)
+		}
+		if extracted == nil {
+			return nil
+		}
+		if err := storeExtraction(ctx, NewGraphStoreTx(tx), extracted); err != nil {
+			return fmt.Errorf("graph ingestion failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if item.Embedding != nil {
+		return ing.indexVector(ctx, item)
+	}
+	return nil
+}
+
+// UpsertOutcome reports what UpsertBySourceRef did with an item
+type UpsertOutcome string
+
+const (
+	UpsertCreated   UpsertOutcome = "created"   // no item had the source ref; item was stored
+	UpsertUpdated   UpsertOutcome = "updated"   // the stored item changed and was replaced in place
+	UpsertUnchanged UpsertOutcome = "unchanged" // the stored item has the same content; nothing was written
+)
+
+// UpsertBySourceRef ingests item synchronously as the stored item for its SourceRef, so
+// re-scanning a file does not add a row per scan. An existing item with the same content hash
+// is left alone; a changed one is overwritten under its ID, re-embedded and re-indexed.
+// item.ID is set to the stored item's ID. Chunked documents share one SourceRef across
+// chunks and are re-ingested with IngestDocument instead
+func (ing *Ingester) UpsertBySourceRef(ctx context.Context, item *MemoryItem) (UpsertOutcome, error) {
+	if item.SourceRef == "" {
+		return "", fmt.Errorf("source ref is required")
+	}
+	lookup, ok := ing.store.(SourceRefLookup)
+	if !ok {
+		return "", fmt.Errorf("memory store does not support source ref lookups")
+	}
+
+	existing, err := lookup.GetItemBySourceRef(ctx, item.SourceRef)
+	if err != nil {
+		return "", err
+	}
+
+	outcome := UpsertCreated
+	if existing != nil {
+		item.ID = existing.ID
+		if ing.hashContent(existing.Text) == ing.hashContent(item.Text) {
+			return UpsertUnchanged, nil
+		}
+		item.CreatedAt = existing.CreatedAt
+		outcome = UpsertUpdated
+	}
+
+	if err := ing.embedItem(ctx, item); err != nil {
+		return "", err
+	}
+	// The row goes first so the lexical triggers and flat index see the new text
+	if err := ing.store.PutItem(ctx, item); err != nil {
+		return "", fmt.Errorf("failed to store memory item: %w", err)
+	}
+	if item.Embedding != nil {
+		if err := ing.indexVector(ctx, item); err != nil {
+			return "", err
+		}
+	} else if outcome == UpsertUpdated {
+		// The old vector no longer describes the item
+		if err := ing.vectorIndex.Delete(ctx, item.ID); err != nil {
+			return "", fmt.Errorf("vector delete failed: %w", err)
+		}
+	}
+	return outcome, nil
+}
+
+// findDuplicate returns the nearest other indexed item whose cosine similarity to
+// item reaches DedupThreshold. Indexes implementing VectorLookup are compared
+// exactly; otherwise the index score is taken as the similarity.
+func (ing *Ingester) findDuplicate(ctx context.Context, item *MemoryItem) (string, float64, error) {
+	// Two neighbours so a re-ingest of the same ID does not match itself
+	results, err := ing.vectorIndex.Query(ctx, item.Embedding, 2)
+	if err != nil {
+		return "", 0, err
+	}
+
+	lookup, exact := ing.vectorIndex.(VectorLookup)
+	for _, result := range results {
+		if result.ID == item.ID {
+			continue
+		}
+
+		similarity := result.Score
+		if exact {
+			vector, err := lookup.Vector(ctx, result.ID)
+			if err != nil {
+				return "", 0, err
+			}
+			if vector == nil {
+				continue
+			}
+			similarity = cosineSimilarity(item.Embedding, vector)
+		}
+
+		if similarity >= ing.config.DedupThreshold {
+			return result.ID, similarity, nil
+		}
+		// Results are ordered, so the nearest other item decides
+		break
+	}
+	return "", 0, nil
+}
+
+// mergeDuplicate folds a near-duplicate into its canonical item. The canonical row, when
+// stored, lists the duplicate's ID under "duplicates", and a row the caller already stored
+// for the duplicate is deleted so only the canonical item remains. The queued item itself
+// is left untouched since the caller may still hold it
+func (ing *Ingester) mergeDuplicate(ctx context.Context, duplicateID, canonicalID string) error {
+	if ing.store == nil {
+		return nil
+	}
+
+	ing.dedupMu.Lock()
+	defer ing.dedupMu.Unlock()
+
+	// The canonical item may only live in the vector index when it was ingested without a row
+	if canonical, err := ing.store.GetItem(ctx, canonicalID); err == nil {
+		merged := *canonical
+		merged.Metadata = make(map[string]interface{}, len(canonical.Metadata)+1)
+		for k, v := range canonical.Metadata {
+			merged.Metadata[k] = v
+		}
+		duplicates, _ := merged.Metadata["duplicates"].([]interface{})
+		if !containsValue(duplicates, duplicateID) {
+			merged.Metadata["duplicates"] = append(duplicates, duplicateID)
+			if err := ing.store.PutItem(ctx, &merged); err != nil {
+				return fmt.Errorf("failed to record duplicate on canonical item: %w", err)
+			}
+		}
+	}
+
+	if _, err := ing.store.GetItem(ctx, duplicateID); err == nil {
+		if err := ing.store.DeleteItem(ctx, duplicateID); err != nil {
+			return fmt.Errorf("failed to delete duplicate item: %w", err)
+		}
+	}
+	return nil
+}
+
+// containsValue reports whether values holds id
+func containsValue(values []interface{}, id string) bool {
+	for _, v := range values {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryFailedIngests re-runs dead-lettered tasks whose backoff has elapsed and
+// returns how many were ingested successfully. Tasks that fail again are
+// rescheduled, or marked permanently failed once they exhaust their attempts.
+func (ing *Ingester) RetryFailedIngests(ctx context.Context) (int, error) {
+	if ing.deadLetters == nil {
+		return 0, nil
+	}
+
+	due, err := ing.deadLetters.Due(ctx, max(ing.config.IngestBatchSize, 1)*2)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, entry := range due {
+		if err := ctx.Err(); err != nil {
+			return recovered, err
+		}
+
+		task := &IngestionTask{
+			ID:        entry.Item.ID,
+			Item:      entry.Item,
+			Episode:   entry.Episode,
+			CreatedAt: time.Now(),
+		}
+		start := time.Now()
+		err := ing.processTask(ctx, task)
+		ing.metrics.RecordIngest(time.Since(start), err)
+
+		if err != nil {
+			if err := ing.deadLetters.Record(ctx, task, err); err != nil {
+				return recovered, err
+			}
+			continue
+		}
+		if err := ing.deadLetters.Resolve(ctx, task.ID); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
 // processGraphIngestion handles entity and edge extraction and storage
 func (ing *Ingester) processGraphIngestion(ctx context.Context, episode *Episode) error {
 	if ing.extractor == nil {
@@ -186,16 +523,19 @@ func (ing *Ingester) processGraphIngestion(ctx context.Context, episode *Episode
 		return fmt.Errorf("extraction failed: %w", err)
 	}
 
-	// Process entities
+	return storeExtraction(ctx, ing.graphStore, result)
+}
+
+// storeExtraction upserts extracted entities, then the edges between them
+func storeExtraction(ctx context.Context, store GraphStore, result *ExtractionResult) error {
 	for _, entity := range result.Entities {
-		if err := ing.graphStore.UpsertEntity(ctx, &entity); err != nil {
+		if err := store.UpsertEntity(ctx, &entity); err != nil {
 			return fmt.Errorf("entity upsert failed: %w", err)
 		}
 	}
 
-	// Process edges
 	for _, edge := range result.Edges {
-		if err := ing.graphStore.UpsertEdge(ctx, &edge); err != nil {
+		if err := store.UpsertEdge(ctx, &edge); err != nil {
 			return fmt.Errorf("edge upsert failed: %w", err)
 		}
 	}
@@ -40,11 +40,11 @@ func (l *LexicalIndexImpl) Query(ctx context.Context, query string, k int) ([]Se
 			mi.text,
 			mi.metadata_json,
 			mi.created_at,
-			bm25(mif) as bm25_score
-		FROM memory_items_fts mif
-		JOIN memory_items mi ON mif.rowid = mi.rowid
-		WHERE mif MATCH ?
-		ORDER BY bm25_score DESC
+			bm25(memory_items_fts) as bm25_score
+		FROM memory_items_fts
+		JOIN memory_items mi ON memory_items_fts.rowid = mi.rowid
+		WHERE memory_items_fts MATCH ?
+		ORDER BY bm25_score
 		LIMIT ?
 	`
 
@@ -77,7 +77,7 @@ func (l *LexicalIndexImpl) Query(ctx context.Context, query string, k int) ([]Se
 			return nil, fmt.Errorf("failed to scan FTS5 result: %w", err)
 		}
 
-		r.Score = bm25Score
+		r.Score = -bm25Score // bm25() is lower-is-better; flip so higher scores rank first
 		r.Provenance = "bm25_fts5"
 
 		// Store metadata as JSON
@@ -99,6 +99,30 @@ func (l *LexicalIndexImpl) Query(ctx context.Context, query string, k int) ([]Se
 	return results, nil
 }
 
+// Optimize merges the FTS5 index b-trees, which fragment after bulk writes
+func (l *LexicalIndexImpl) Optimize(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `INSERT INTO memory_items_fts (memory_items_fts) VALUES ('optimize')`); err != nil {
+		return fmt.Errorf("failed to optimize FTS5 index: %w", err)
+	}
+	return nil
+}
+
+// Reindex rebuilds the FTS5 index from memory_items, repairing any drift
+func (l *LexicalIndexImpl) Reindex(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `INSERT INTO memory_items_fts (memory_items_fts) VALUES ('rebuild')`); err != nil {
+		return fmt.Errorf("failed to rebuild FTS5 index: %w", err)
+	}
+	return nil
+}
+
+// CheckIntegrity verifies the FTS5 index matches the memory_items content
+func (l *LexicalIndexImpl) CheckIntegrity(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `INSERT INTO memory_items_fts (memory_items_fts, rank) VALUES ('integrity-check', 1)`); err != nil {
+		return fmt.Errorf("FTS5 index is inconsistent: %w", err)
+	}
+	return nil
+}
+
 // Close cleans up resources
 func (l *LexicalIndexImpl) Close() error {
 	// No specific cleanup needed for FTS5
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "github.com/tursodatabase/go-libsql"
+)
+
+// openTestLexicalDB opens a libSQL database with the memory item and FTS5 migrations applied
+func openTestLexicalDB(t *testing.T) *sql.DB {
+	db := openTestMigratedDB(t, filepath.Join(t.TempDir(), "lexical.db"), "*_memory_items*.sql")
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func insertLexicalItem(t *testing.T, db *sql.DB, id, text string) {
+	_, err := db.Exec(`INSERT INTO memory_items (id, type, text, metadata_json, created_at) VALUES (?, 'note', ?, '{}', ?)`, id, text, time.Now())
+	require.NoError(t, err)
+}
+
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestLexicalIndex_MigrationCreatesSyncTriggers(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMigratedDB(t, filepath.Join(t.TempDir(), "lexical.db"), "*_memory_items.sql")
+	defer db.Close()
+
+	// Rows written before the FTS table exists are picked up on creation
+	insertLexicalItem(t, db, "early", "coffee grinder settings")
+	applyTestMigrations(t, db, "*_memory_items*.sql")
+
+	var triggers int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name GLOB 'memory_items_fts_*'`).Scan(&triggers))
+	assert.Equal(t, 3, triggers)
+
+	index := NewLexicalIndexImpl(db, &config.MemoryConfig{})
+	results, err := index.Query(ctx, "grinder", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"early"}, resultIDs(results))
+
+	// Triggers follow inserts, updates and deletes
+	insertLexicalItem(t, db, "late", "espresso grinder burrs")
+	_, err = db.Exec(`UPDATE memory_items SET text = 'pour over kettle' WHERE id = 'early'`)
+	require.NoError(t, err)
+
+	results, err = index.Query(ctx, "grinder", 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"late"}, resultIDs(results))
+
+	_, err = db.Exec(`DELETE FROM memory_items WHERE id = 'late'`)
+	require.NoError(t, err)
+	results, err = index.Query(ctx, "grinder", 10)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	require.NoError(t, index.CheckIntegrity(ctx))
+}
+
+func TestLexicalIndex_OptimizeAndReindexKeepResults(t *testing.T) {
+	ctx := context.Background()
+	db := openTestLexicalDB(t)
+	index := NewLexicalIndexImpl(db, &config.MemoryConfig{})
+
+	// Bulk writes with churn fragment the index
+	for i := 0; i < 200; i++ {
+		insertLexicalItem(t, db, fmt.Sprintf("item-%03d", i), fmt.Sprintf("note %d about topic%d", i, i%5))
+	}
+	_, err := db.Exec(`DELETE FROM memory_items WHERE id LIKE 'item-0%'`)
+	require.NoError(t, err)
+
+	before, err := index.Query(ctx, "topic3", 100)
+	require.NoError(t, err)
+	require.Len(t, before, 20)
+
+	require.NoError(t, index.Optimize(ctx))
+	require.NoError(t, index.CheckIntegrity(ctx))
+	afterOptimize, err := index.Query(ctx, "topic3", 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, resultIDs(before), resultIDs(afterOptimize))
+
+	require.NoError(t, index.Reindex(ctx))
+	require.NoError(t, index.CheckIntegrity(ctx))
+	afterReindex, err := index.Query(ctx, "topic3", 100)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, resultIDs(before), resultIDs(afterReindex))
+
+	stats, err := index.GetStatistics(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, stats["total_items"])
+}
+
+func TestLexicalIndex_QueryRanksByBM25(t *testing.T) {
+	ctx := context.Background()
+	db := openTestLexicalDB(t)
+	index := NewLexicalIndexImpl(db, &config.MemoryConfig{})
+
+	insertLexicalItem(t, db, "once", "a long note that mentions tea only once among many other words")
+	insertLexicalItem(t, db, "often", "tea tea tea")
+	insertLexicalItem(t, db, "twice", "tea and more tea in a note")
+	insertLexicalItem(t, db, "none", "coffee")
+
+	results, err := index.Query(ctx, "tea", 10)
+	require.NoError(t, err)
+	// bm25() is lower-is-better, so ascending order puts the best match first
+	assert.Equal(t, []string{"often", "twice", "once"}, resultIDs(results))
+	for i := 1; i < len(results); i++ {
+		assert.Greater(t, results[i-1].Score, results[i].Score)
+	}
+	assert.Positive(t, results[0].Score)
+}
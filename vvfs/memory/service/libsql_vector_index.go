@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CapabilityChecker reports libSQL feature support per project (satisfied by database.DBManager)
+type CapabilityChecker interface {
+	HasCapability(project, capability string) bool
+}
+
+// LibSQLVectorIndex implements VectorIndex over the entities table using libSQL native ANN.
+// Queries go through vector_top_k on idx_entities_embedding when the project supports it,
+// and fall back to a brute-force cosine scan otherwise.
+type LibSQLVectorIndex struct {
+	db        *sql.DB
+	dimension int
+	caps      CapabilityChecker
+	project   string
+}
+
+// NewLibSQLVectorIndex creates a vector index backed by entities.embedding
+func NewLibSQLVectorIndex(db *sql.DB, dimension int, caps CapabilityChecker, project string) *LibSQLVectorIndex {
+	return &LibSQLVectorIndex{
+		db:        db,
+		dimension: dimension,
+		caps:      caps,
+		project:   project,
+	}
+}
+
+// Upsert stores the embedding for an existing entity
+func (l *LibSQLVectorIndex) Upsert(ctx context.Context, id string, vector []float64) error {
+	if len(vector) != l.dimension {
+		return fmt.Errorf("vector dimension mismatch: expected %d, got %d", l.dimension, len(vector))
+	}
+
+	result, err := l.db.ExecContext(ctx,
+		`UPDATE entities SET embedding = vector32(?), updated_at = unixepoch() WHERE name = ?`,
+		encodeVector32(vector), id)
+	if err != nil {
+		return fmt.Errorf("failed to upsert vector: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("entity not found: %s", id)
+	}
+
+	return nil
+}
+
+// Query returns the k nearest entities by cosine similarity
+func (l *LibSQLVectorIndex) Query(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	if len(query) != l.dimension {
+		return nil, fmt.Errorf("query dimension mismatch: expected %d, got %d", l.dimension, len(query))
+	}
+	if k <= 0 {
+		return nil, nil
+	}
+
+	if l.nativeANN() {
+		results, err := l.queryTopK(ctx, query, k)
+		if err == nil {
+			return results, nil
+		}
+		// Index missing or unusable on this connection; the flat scan is always correct
+	}
+
+	return l.queryFlat(ctx, query, k)
+}
+
+// nativeANN reports whether vector_top_k can be used for this project
+func (l *LibSQLVectorIndex) nativeANN() bool {
+	return l.caps != nil && l.caps.HasCapability(l.project, "vectorTopK")
+}
+
+// queryTopK uses the libSQL vector index
+func (l *LibSQLVectorIndex) queryTopK(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	encoded := encodeVector32(query)
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT e.name, vector_distance_cos(e.embedding, vector32(?)) AS distance
+		FROM vector_top_k('idx_entities_embedding', vector32(?), ?) AS vt
+		JOIN entities e ON e.rowid = vt.id
+		ORDER BY distance ASC
+	`, encoded, encoded, k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector index: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]SearchResult, 0, k)
+	for rows.Next() {
+		var id string
+		var distance float64
+		if err := rows.Scan(&id, &distance); err != nil {
+			return nil, fmt.Errorf("failed to scan vector result: %w", err)
+		}
+		results = append(results, SearchResult{ID: id, Score: 1 - distance, Provenance: "vector_libsql"})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate vector results: %w", err)
+	}
+
+	return results, nil
+}
+
+// queryFlat scans every stored embedding and ranks by cosine similarity
+func (l *LibSQLVectorIndex) queryFlat(ctx context.Context, query []float64, k int) ([]SearchResult, error) {
+	rows, err := l.db.QueryContext(ctx, `SELECT name, embedding FROM entities WHERE embedding IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var id string
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		vector := decodeVector32(blob, l.dimension)
+		if len(vector) != len(query) {
+			continue // Skip malformed or mismatched rows
+		}
+		results = append(results, SearchResult{ID: id, Score: cosineSimilarity(query, vector), Provenance: "vector_flat"})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate embeddings: %w", err)
+	}
+
+	sortResultsStable(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+
+	return results, nil
+}
+
+// Delete clears the embedding for an entity
+func (l *LibSQLVectorIndex) Delete(ctx context.Context, id string) error {
+	if _, err := l.db.ExecContext(ctx, `UPDATE entities SET embedding = NULL WHERE name = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete vector: %w", err)
+	}
+	return nil
+}
+
+// DeleteBatch clears the embeddings of many entities with a single statement
+func (l *LibSQLVectorIndex) DeleteBatch(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `UPDATE entities SET embedding = NULL WHERE name IN (` + sqlPlaceholders(len(ids)) + `)`
+	if _, err := l.db.ExecContext(ctx, query, stringArgs(ids)...); err != nil {
+		return fmt.Errorf("failed to delete vectors: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all embeddings while keeping the entities
+func (l *LibSQLVectorIndex) Clear(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, `UPDATE entities SET embedding = NULL WHERE embedding IS NOT NULL`); err != nil {
+		return fmt.Errorf("failed to clear vectors: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; the database connection is owned by the caller
+func (l *LibSQLVectorIndex) Close() error {
+	return nil
+}
+
+// sqlPlaceholders returns n comma-separated "?" placeholders
+func sqlPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// stringArgs converts ids to query arguments
+func stringArgs(ids []string) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// encodeVector32 formats a vector as the "[a,b,...]" text accepted by vector32()
+func encodeVector32(vector []float64) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, v := range vector {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(v, 'f', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// decodeVector32 reads a little-endian F32_BLOB, ignoring any trailing type metadata
+func decodeVector32(blob []byte, dimension int) []float64 {
+	n := len(blob) / 4
+	if dimension > 0 && n > dimension {
+		n = dimension
+	}
+	vector := make([]float64, n)
+	for i := 0; i < n; i++ {
+		vector[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(blob[i*4:])))
+	}
+	return vector
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "github.com/tursodatabase/go-libsql"
+)
+
+// staticCapabilities reports a fixed vectorTopK capability
+type staticCapabilities bool
+
+func (c staticCapabilities) HasCapability(_, capability string) bool {
+	return capability == "vectorTopK" && bool(c)
+}
+
+// openTestVectorDB opens an in-memory libSQL database with the entities vector schema
+func openTestVectorDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("libsql", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1) // each in-memory connection is a separate database
+
+	schema := []string{
+		`CREATE TABLE entities (
+			name TEXT PRIMARY KEY,
+			entity_type TEXT NOT NULL,
+			embedding F32_BLOB(3),
+			metadata TEXT,
+			created_at INTEGER NOT NULL DEFAULT (unixepoch()),
+			updated_at INTEGER NOT NULL DEFAULT (unixepoch())
+		)`,
+		`CREATE INDEX idx_entities_embedding ON entities(libsql_vector_idx(embedding))`,
+	}
+	for _, stmt := range schema {
+		_, err := db.Exec(stmt)
+		require.NoError(t, err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, name := range []string{"east", "north", "northeast", "west"} {
+		_, err := db.Exec(`INSERT INTO entities (name, entity_type) VALUES (?, 'direction')`, name)
+		require.NoError(t, err)
+	}
+	return db
+}
+
+func TestLibSQLVectorIndex_Query(t *testing.T) {
+	ctx := context.Background()
+	vectors := map[string][]float64{
+		"east":      {1, 0, 0},
+		"north":     {0, 1, 0},
+		"northeast": {0.7, 0.7, 0},
+		"west":      {-1, 0, 0},
+	}
+
+	for name, caps := range map[string]staticCapabilities{"vector_top_k": true, "flat_fallback": false} {
+		t.Run(name, func(t *testing.T) {
+			index := NewLibSQLVectorIndex(openTestVectorDB(t), 3, caps, "default")
+			for id, vector := range vectors {
+				require.NoError(t, index.Upsert(ctx, id, vector))
+			}
+
+			results, err := index.Query(ctx, []float64{0.9, 0.1, 0}, 2)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			assert.Equal(t, "east", results[0].ID)
+			assert.Equal(t, "northeast", results[1].ID)
+			assert.InDelta(t, cosineSimilarity([]float64{0.9, 0.1, 0}, vectors["east"]), results[0].Score, 1e-4)
+
+			require.NoError(t, index.Delete(ctx, "east"))
+			results, err = index.Query(ctx, []float64{0.9, 0.1, 0}, 1)
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			assert.Equal(t, "northeast", results[0].ID)
+		})
+	}
+}
+
+func TestLibSQLVectorIndex_Validation(t *testing.T) {
+	ctx := context.Background()
+	index := NewLibSQLVectorIndex(openTestVectorDB(t), 3, staticCapabilities(false), "default")
+
+	assert.Error(t, index.Upsert(ctx, "east", []float64{1, 0}))
+	assert.Error(t, index.Upsert(ctx, "missing", []float64{1, 0, 0}))
+
+	_, err := index.Query(ctx, []float64{1, 0}, 1)
+	assert.Error(t, err)
+}
@@ -3,7 +3,12 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
@@ -40,15 +45,49 @@ type MemorySystem struct {
 	ingester *Ingester
 	metrics  *MetricsCollector
 
-	// Database connection
+	// Embedding provenance; vectors are only tagged when the embedder reports a model version
+	embeddingVersions *EmbeddingVersionStore
+	modelVersion      string
+
+	// Database connection; owned by the caller, never closed by Close
 	db *sql.DB
+
+	// Set when the embedder was built from config and must be closed with the system
+	ownsEmbedder bool
+
+	// Serializes Flush calls; reads are never blocked by a flush
+	flushMu sync.Mutex
+	// Held exclusively by Reindex so searches never see a half-built index
+	indexMu sync.RWMutex
+
+	// Readiness gate; ready is closed once warm-up finishes, readyErr holds its outcome
+	ready        chan struct{}
+	readyErr     error
+	cancelWarmup context.CancelFunc
+
+	// Close runs its shutdown once; later calls return closeErr
+	closeOnce sync.Once
+	closeErr  error
 }
 
+// DefaultReadyTimeout bounds warm-up when MemorySystemConfig.ReadyTimeout is unset
+const DefaultReadyTimeout = 2 * time.Minute
+
+// ErrNoEmbedder is returned by NewMemorySystem when neither Embedder nor Embedding is set
+// and AllowNullEmbedder is not either
+var ErrNoEmbedder = errors.New("no embedder configured: set Embedder or Embedding, or AllowNullEmbedder to use zero vectors")
+
 // MemorySystemConfig holds all configuration for initializing the memory system
 type MemorySystemConfig struct {
 	Config   *config.MemoryConfig
-	DB       *sql.DB
-	Embedder Embedder // Optional: if nil, will use default
+	DB       *sql.DB  // Owned by the caller; Close leaves it open
+	Embedder Embedder // Optional: takes precedence over Embedding
+	// Optional: selects the embedder when Embedder is nil; one of the two is required
+	// unless AllowNullEmbedder is set
+	Embedding *config.EmbeddingConfig
+	// Opt-in for tests: falls back to the zero-vector DefaultEmbedder when neither Embedder
+	// nor Embedding is set. Vector search over zero vectors returns meaningless scores
+	AllowNullEmbedder bool
 
 	// Optional overrides for testing/customization
 	VectorIndex  VectorIndex
@@ -56,6 +95,12 @@ type MemorySystemConfig struct {
 	GraphStore   GraphStore
 	MemoryStore  MemoryStore
 	SessionStore SessionStore
+	Reranker     Reranker
+
+	// Optional query run once the subsystems are ready, priming caches before the system reports ready
+	WarmupQuery string
+	// Bounds warm-up; defaults to DefaultReadyTimeout
+	ReadyTimeout time.Duration
 }
 
 // NewMemorySystem creates a fully configured memory system
@@ -66,19 +111,31 @@ func NewMemorySystem(ctx context.Context, cfg MemorySystemConfig) (*MemorySystem
 	if cfg.DB == nil {
 		return nil, fmt.Errorf("database connection is required")
 	}
+	if _, err := ParseTimeDecayFunc(cfg.Config.TimeDecayFunc); err != nil {
+		return nil, err
+	}
 
 	ms := &MemorySystem{
-		config:  cfg.Config,
-		db:      cfg.DB,
-		metrics: NewMetricsCollector(),
+		config:   cfg.Config,
+		db:       cfg.DB,
+		metrics:  NewMetricsCollector(),
+		reranker: cfg.Reranker,
 	}
 
 	// Initialize embedder
 	if cfg.Embedder != nil {
 		ms.embedder = cfg.Embedder
-	} else {
-		// FIXME: Use default embedder (placeholder - to be implemented)
+	} else if cfg.Embedding != nil {
+		embedder, err := NewEmbedder(*cfg.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embedder: %w", err)
+		}
+		ms.embedder = embedder
+		ms.ownsEmbedder = true
+	} else if cfg.AllowNullEmbedder {
 		ms.embedder = NewDefaultEmbedder()
+	} else {
+		return nil, ErrNoEmbedder
 	}
 
 	// Initialize vector index based on config
@@ -151,10 +208,104 @@ func NewMemorySystem(ctx context.Context, cfg MemorySystemConfig) (*MemorySystem
 		ms.extractor,
 		ms.metrics,
 	)
+	// Zero vectors from the placeholder embedder would only pollute the index and match
+	// every query equally
+	if _, placeholder := ms.embedder.(*DefaultEmbedder); !placeholder {
+		ms.ingester.SetEmbedder(ms.embedder)
+		if retrieverImpl, ok := ms.retriever.(*RetrieverImpl); ok {
+			retrieverImpl.SetEmbedder(ms.embedder)
+		}
+	}
+	ms.ingester.SetMemoryStore(ms.memoryStore)
+	ms.ingester.SetDeadLetterStore(NewDeadLetterStore(cfg.DB, cfg.Config.IngestMaxAttempts, cfg.Config.IngestRetryBackoff))
+
+	// Tag vectors with the embedder's model version so a model change cannot silently mix vector spaces
+	ms.embeddingVersions = NewEmbeddingVersionStore(cfg.DB)
+	ms.modelVersion = EmbedderModelVersion(ms.embedder)
+	if ms.modelVersion != "" {
+		ms.ingester.SetEmbeddingVersions(ms.embeddingVersions, ms.modelVersion)
+		if retrieverImpl, ok := ms.retriever.(*RetrieverImpl); ok {
+			retrieverImpl.SetEmbeddingVersionCheck(ms.embeddingVersions, ms.modelVersion)
+		}
+	}
+
+	ms.startWarmup(cfg)
 
 	return ms, nil
 }
 
+// startWarmup waits for the subsystems in the background and closes ms.ready when done
+func (ms *MemorySystem) startWarmup(cfg MemorySystemConfig) {
+	timeout := cfg.ReadyTimeout
+	if timeout <= 0 {
+		timeout = DefaultReadyTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ms.ready = make(chan struct{})
+	ms.cancelWarmup = cancel
+
+	go func() {
+		defer cancel()
+		ms.readyErr = ms.warmup(ctx, cfg.WarmupQuery)
+		close(ms.ready)
+	}()
+}
+
+// warmup blocks until the embedder and indexes report ready, then runs the optional warm-up query
+func (ms *MemorySystem) warmup(ctx context.Context, query string) error {
+	components := []struct {
+		name      string
+		component interface{}
+	}{
+		{"embedder", ms.embedder},
+		{"vector index", ms.vectorIndex},
+		{"lexical index", ms.lexical},
+	}
+	for _, c := range components {
+		checker, ok := c.component.(ReadinessChecker)
+		if !ok {
+			continue
+		}
+		if err := checker.WaitReady(ctx); err != nil {
+			return fmt.Errorf("failed to wait for %s: %w", c.name, err)
+		}
+	}
+
+	// The flat index searches the stored embeddings directly; other indexes start empty
+	if _, flat := ms.vectorIndex.(*FlatIndexImpl); ms.config.LoadExistingVectors && !flat {
+		if _, err := ms.LoadExistingVectors(ctx); err != nil {
+			return err
+		}
+	}
+
+	if query != "" {
+		if _, err := ms.Search(ctx, query, SearchOptions{K: 1}); err != nil {
+			return fmt.Errorf("failed to run warm-up query: %w", err)
+		}
+	}
+	return nil
+}
+
+// Ready reports whether warm-up completed successfully; suitable for readiness probes
+func (ms *MemorySystem) Ready() bool {
+	select {
+	case <-ms.ready:
+		return ms.readyErr == nil
+	default:
+		return false
+	}
+}
+
+// WaitReady blocks until warm-up finishes or ctx is done, returning the warm-up error if any
+func (ms *MemorySystem) WaitReady(ctx context.Context) error {
+	select {
+	case <-ms.ready:
+		return ms.readyErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // createVectorIndex creates the appropriate vector index based on config
 func (ms *MemorySystem) createVectorIndex() (VectorIndex, error) {
 	switch ms.config.VectorIndex {
@@ -198,6 +349,7 @@ func (ms *MemorySystem) initializeGraphComponents(cfg MemorySystemConfig) error
 	// Update retriever with graph search
 	if retrieverImpl, ok := ms.retriever.(*RetrieverImpl); ok {
 		retrieverImpl.graphSearch = ms.graphSearch
+		retrieverImpl.graphStore = ms.graphStore
 	}
 
 	return nil
@@ -217,17 +369,20 @@ func (ms *MemorySystem) initializeEnsembleComponents() error {
 
 	// Initialize ensemble
 	ms.ensemble = &IndexEnsembleImpl{
-		bm25:   ms.lexical,
-		vector: ms.vectorIndex,
-		graph:  ms.graphSearch,
-		config: ms.config,
-		router: ms.router,
-		fusion: ms.fusionRanker,
+		bm25:     ms.lexical,
+		vector:   ms.vectorIndex,
+		graph:    ms.graphSearch,
+		embedder: ms.embedder,
+		config:   ms.config,
+		router:   ms.router,
+		fusion:   ms.fusionRanker,
 	}
 
-	// Initialize reranker if configured
-	ms.reranker = &LTRankerImpl{
-		config: ms.config,
+	// Initialize reranker unless one was injected
+	if ms.reranker == nil {
+		ms.reranker = &LTRankerImpl{
+			config: ms.config,
+		}
 	}
 
 	return nil
@@ -235,16 +390,390 @@ func (ms *MemorySystem) initializeEnsembleComponents() error {
 
 // Ingest adds a memory item to the system
 func (ms *MemorySystem) Ingest(ctx context.Context, item *MemoryItem) error {
-	return ms.ingester.IngestMemoryItem(ctx, item)
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+	if err := ms.ingester.IngestMemoryItem(ctx, item); err != nil {
+		return err
+	}
+	ms.invalidateReranker(item)
+	return nil
 }
 
 // IngestWithEpisode ingests a memory item along with graph extraction
 func (ms *MemorySystem) IngestWithEpisode(ctx context.Context, item *MemoryItem, episode *Episode) error {
-	return ms.ingester.IngestWithPriority(ctx, item, episode, 0)
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+	if err := ms.ingester.IngestWithPriority(ctx, item, episode, 0); err != nil {
+		return err
+	}
+	ms.invalidateReranker(item)
+	return nil
+}
+
+// IngestAtomic ingests item synchronously, committing the item row and the graph extracted
+// from episode in one transaction on the system database
+func (ms *MemorySystem) IngestAtomic(ctx context.Context, item *MemoryItem, episode *Episode) error {
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+	if err := ms.ingester.IngestAtomic(ctx, SQLTx(ms.db), item, episode); err != nil {
+		return err
+	}
+	ms.invalidateReranker(item)
+	return nil
+}
+
+// IngestBySourceRef stores item as the single item for its SourceRef, skipping unchanged
+// content and updating changed content in place; see Ingester.UpsertBySourceRef
+func (ms *MemorySystem) IngestBySourceRef(ctx context.Context, item *MemoryItem) (UpsertOutcome, error) {
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+	outcome, err := ms.ingester.UpsertBySourceRef(ctx, item)
+	if err != nil {
+		return "", err
+	}
+	if outcome == UpsertUpdated {
+		ms.invalidateReranker(item)
+	}
+	return outcome, nil
+}
+
+// IngestDocument splits a long document with ChunkText using the configured chunk
+// size and overlap, embeds the chunks in one batch, and stores each as a memory item
+// whose SourceRef is the document ID. Returns the chunk IDs in document order
+func (ms *MemorySystem) IngestDocument(ctx context.Context, doc *Document) ([]string, error) {
+	if doc == nil || doc.ID == "" {
+		return nil, fmt.Errorf("document ID is required")
+	}
+
+	chunks := ChunkText(doc.Text, ChunkOptions{Size: ms.config.ChunkSize, Overlap: ms.config.ChunkOverlap})
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("document %s has no text to ingest", doc.ID)
+	}
+
+	// Zero vectors from the placeholder embedder would only pollute the index
+	var embeddings [][]float64
+	if _, placeholder := ms.embedder.(*DefaultEmbedder); !placeholder {
+		var err error
+		embeddings, err = ms.embedder.Embed(ctx, chunks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed document chunks: %w", err)
+		}
+		if len(embeddings) != len(chunks) {
+			return nil, fmt.Errorf("failed to embed document chunks: expected %d vectors, got %d", len(chunks), len(embeddings))
+		}
+	}
+
+	itemType := doc.Type
+	if itemType == "" {
+		itemType = "document"
+	}
+
+	items := make([]*MemoryItem, len(chunks))
+	for i, chunk := range chunks {
+		metadata := make(map[string]interface{}, len(doc.Metadata)+3)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata["parent_id"] = doc.ID
+		metadata["chunk_index"] = i
+		metadata["chunk_count"] = len(chunks)
+
+		item := &MemoryItem{
+			ID:        fmt.Sprintf("%s#chunk-%d", doc.ID, i),
+			Type:      itemType,
+			Text:      chunk,
+			Metadata:  metadata,
+			ExpiresAt: doc.ExpiresAt,
+			SourceRef: doc.ID,
+		}
+		if embeddings != nil {
+			item.Embedding = embeddings[i]
+		}
+		items[i] = item
+	}
+
+	// Store every row before queueing any chunk, so the lexical triggers and flat index
+	// can see them and the rows are not written while ingest workers update embeddings
+	for i, item := range items {
+		if err := ms.memoryStore.PutItem(ctx, item); err != nil {
+			return nil, fmt.Errorf("failed to store chunk %d of document %s: %w", i, doc.ID, err)
+		}
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		if err := ms.Ingest(ctx, item); err != nil {
+			return ids[:i], fmt.Errorf("failed to ingest chunk %d of document %s: %w", i, doc.ID, err)
+		}
+		ids[i] = item.ID
+	}
+
+	return ids, nil
+}
+
+// RetryFailedIngests re-ingests dead-lettered items whose retry is due and
+// returns how many succeeded
+func (ms *MemorySystem) RetryFailedIngests(ctx context.Context) (int, error) {
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+	return ms.ingester.RetryFailedIngests(ctx)
+}
+
+// invalidateReranker drops cached rerank scores for a re-ingested item
+func (ms *MemorySystem) invalidateReranker(item *MemoryItem) {
+	if invalidator, ok := ms.reranker.(CandidateInvalidator); ok && item != nil {
+		invalidator.InvalidateCandidate(item.ID)
+	}
+}
+
+// ReembedFunc produces embeddings for a batch of source texts during Reindex
+type ReembedFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Reindex rebuilds the vector index from the stored item texts, then rebuilds the
+// lexical index when it supports maintenance.
+// If reembed is nil the system embedder is used; either way the rebuilt vectors are
+// tagged with the system embedder's model version. Every item is re-embedded before the
+// index is touched, and if writing the new vectors fails the previous ones are restored,
+// so a failed rebuild does not leave the index empty. Searches and ingests block
+// until the rebuild completes.
+func (ms *MemorySystem) Reindex(ctx context.Context, reembed ReembedFunc) error {
+	if reembed == nil {
+		if ms.embedder == nil {
+			return fmt.Errorf("cannot reindex: %w", ErrNoEmbedder)
+		}
+		reembed = ms.embedder.Embed
+	}
+
+	ms.indexMu.Lock()
+	defer ms.indexMu.Unlock()
+
+	// Let already-queued items land before rebuilding the index
+	if ms.ingester != nil {
+		if err := ms.ingester.Drain(ctx); err != nil {
+			return err
+		}
+	}
+
+	batchSize := ms.config.IngestBatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+
+	// Build the new vectors aside; the stored items keep the current ones for a restore
+	var items []*MemoryItem
+	var vectors [][]float64
+	for offset := 0; ; offset += batchSize {
+		batch, err := ms.memoryStore.ListItems(ctx, ListOptions{Limit: batchSize, Offset: offset})
+		if err != nil {
+			return fmt.Errorf("failed to list items at offset %d: %w", offset, err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		texts := make([]string, len(batch))
+		for i, item := range batch {
+			texts[i] = item.Text
+		}
+
+		batchVectors, err := reembed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to re-embed items at offset %d: %w", offset, err)
+		}
+		if len(batchVectors) != len(batch) {
+			return fmt.Errorf("re-embed returned %d vectors for %d items", len(batchVectors), len(batch))
+		}
+		items = append(items, batch...)
+		vectors = append(vectors, batchVectors...)
+
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	var previousVersions map[string]string
+	if ms.modelVersion != "" {
+		ids := make([]string, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		var err error
+		if previousVersions, err = ms.embeddingVersions.Versions(ctx, ids); err != nil {
+			return err
+		}
+	}
+
+	if err := ms.replaceVectors(ctx, items, vectors, nil); err != nil {
+		previous := make([][]float64, len(items))
+		for i, item := range items {
+			previous[i] = item.Embedding
+		}
+		if restoreErr := ms.replaceVectors(ctx, items, previous, previousVersions); restoreErr != nil {
+			return fmt.Errorf("reindex failed: %w; restoring the previous vectors also failed: %v", err, restoreErr)
+		}
+		return fmt.Errorf("reindex failed, previous vectors restored: %w", err)
+	}
+
+	if maintainer, ok := ms.lexical.(LexicalMaintainer); ok {
+		if err := maintainer.Reindex(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceVectors clears the vector index and upserts vectors[i] for items[i], skipping empty
+// vectors. Each vector is tagged with versions[id], or the system model version when versions is nil
+func (ms *MemorySystem) replaceVectors(ctx context.Context, items []*MemoryItem, vectors [][]float64, versions map[string]string) error {
+	if err := ms.vectorIndex.Clear(ctx); err != nil {
+		return fmt.Errorf("failed to clear vector index: %w", err)
+	}
+	if ms.modelVersion != "" {
+		if err := ms.embeddingVersions.Clear(ctx); err != nil {
+			return err
+		}
+	}
+
+	for i, item := range items {
+		if len(vectors[i]) == 0 {
+			continue
+		}
+		if err := ms.vectorIndex.Upsert(ctx, item.ID, vectors[i]); err != nil {
+			return fmt.Errorf("failed to reindex item %s: %w", item.ID, err)
+		}
+		if ms.modelVersion == "" {
+			continue
+		}
+		version := ms.modelVersion
+		if versions != nil {
+			if version = versions[item.ID]; version == "" {
+				continue
+			}
+		}
+		if err := ms.embeddingVersions.SetVersion(ctx, item.ID, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadExistingVectors loads the embeddings stored with memory items into the vector index in
+// batches, so items ingested before the index was enabled become searchable without
+// re-ingesting. Items without a stored embedding, or with one of another dimension, are
+// skipped. Returns the number of vectors loaded
+func (ms *MemorySystem) LoadExistingVectors(ctx context.Context) (int, error) {
+	ms.indexMu.Lock()
+	defer ms.indexMu.Unlock()
+
+	batchSize := ms.config.IngestBatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	dim := ms.embedder.Dimension()
+
+	loaded := 0
+	for offset := 0; ; offset += batchSize {
+		items, err := ms.memoryStore.ListItems(ctx, ListOptions{Limit: batchSize, Offset: offset})
+		if err != nil {
+			return loaded, fmt.Errorf("failed to list items at offset %d: %w", offset, err)
+		}
+
+		for _, item := range items {
+			if len(item.Embedding) == 0 || len(item.Embedding) != dim {
+				continue
+			}
+			if err := ms.vectorIndex.Upsert(ctx, item.ID, item.Embedding); err != nil {
+				return loaded, fmt.Errorf("failed to load vector for item %s: %w", item.ID, err)
+			}
+			loaded++
+		}
+
+		if len(items) < batchSize {
+			break
+		}
+	}
+
+	return loaded, nil
+}
+
+// VerifyEmbeddingConsistency reports the embedding model versions recorded for stored vectors.
+// The report is inconsistent when any vector was embedded with a model other than the
+// current embedder's; Reindex re-embeds them with the current model
+func (ms *MemorySystem) VerifyEmbeddingConsistency(ctx context.Context) (EmbeddingConsistencyReport, error) {
+	counts, err := ms.embeddingVersions.Counts(ctx)
+	if err != nil {
+		return EmbeddingConsistencyReport{}, err
+	}
+	return buildConsistencyReport(ms.modelVersion, counts), nil
+}
+
+// memoryItemFilterDeleter is implemented by memory stores that report the IDs removed by a filtered delete
+type memoryItemFilterDeleter interface {
+	DeleteMemoryItemsByFilter(ctx context.Context, filter map[string]interface{}) ([]string, error)
+}
+
+// DeleteByFilter deletes the memory items whose metadata matches filter and removes their vectors.
+// The lexical index follows the deletion through its delete trigger. Returns the number of deleted items
+func (ms *MemorySystem) DeleteByFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	deleter, ok := ms.memoryStore.(memoryItemFilterDeleter)
+	if !ok {
+		return 0, fmt.Errorf("memory store %T cannot report deleted items to keep indexes in sync", ms.memoryStore)
+	}
+
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+
+	// Let queued ingests land first so they cannot re-add vectors for deleted items
+	if ms.ingester != nil {
+		if err := ms.ingester.Drain(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	ids, err := deleter.DeleteMemoryItemsByFilter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	if err := deleteVectors(ctx, ms.vectorIndex, ids); err != nil {
+		return len(ids), err
+	}
+	if ms.modelVersion != "" {
+		if err := ms.embeddingVersions.Delete(ctx, ids); err != nil {
+			return len(ids), err
+		}
+	}
+
+	if invalidator, ok := ms.reranker.(CandidateInvalidator); ok {
+		for _, id := range ids {
+			invalidator.InvalidateCandidate(id)
+		}
+	}
+
+	return len(ids), nil
+}
+
+// deleteVectors removes ids from index, in one call when the index supports bulk deletes
+func deleteVectors(ctx context.Context, index VectorIndex, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if bulk, ok := index.(VectorBulkDeleter); ok {
+		return bulk.DeleteBatch(ctx, ids)
+	}
+	for _, id := range ids {
+		if err := index.Delete(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Search performs hybrid retrieval
 func (ms *MemorySystem) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	ms.indexMu.RLock()
+	defer ms.indexMu.RUnlock()
+
 	if ms.config.EnsembleEnabled && ms.ensemble != nil {
 		// Use ensemble search
 		ensembleOpts := EnsembleSearchOptions{
@@ -260,13 +789,76 @@ func (ms *MemorySystem) Search(ctx context.Context, query string, opts SearchOpt
 		// Fuse ensemble results
 		var allResults []EnsembleResult
 		allResults = append(allResults, ensembleResults...)
-		return ms.fusionRanker.Fuse(ctx, allResults, ensembleOpts.Strategy)
+		fused, err := ms.fusionRanker.Fuse(ctx, allResults, ensembleOpts.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		return projectMetadata(fused, opts.MetadataFields), nil
 	}
 
 	// Use basic hybrid retrieval
 	return ms.retriever.Search(ctx, query, opts)
 }
 
+// Flush forces durability of everything ingested so far: it drains the
+// ingestion queue, checkpoints the WAL, and persists any in-memory vector
+// index state. It is safe to call while searches are in progress.
+func (ms *MemorySystem) Flush(ctx context.Context) error {
+	ms.flushMu.Lock()
+	defer ms.flushMu.Unlock()
+
+	// Wait for queued items to reach the indexes
+	if ms.ingester != nil {
+		if err := ms.ingester.Drain(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Persist in-memory index state (HNSW/LEANN); flat indexes live in the DB
+	if flusher, ok := ms.vectorIndex.(IndexFlusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			return fmt.Errorf("failed to flush vector index: %w", err)
+		}
+	}
+
+	// Move WAL pages into the main database file and truncate the log. A reader or
+	// writer on another connection makes the checkpoint busy, so retry briefly
+	var busy, logFrames, checkpointed int
+	for attempt := 1; ; attempt++ {
+		err := ms.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed)
+		if err != nil && !isBusyError(err) {
+			return fmt.Errorf("failed to checkpoint WAL: %w", err)
+		}
+		if err == nil && busy == 0 {
+			return nil
+		}
+		if attempt == checkpointAttempts {
+			if err != nil {
+				return fmt.Errorf("failed to checkpoint WAL: %w", err)
+			}
+			return fmt.Errorf("WAL checkpoint incomplete: database busy (%d/%d frames checkpointed)", checkpointed, logFrames)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * checkpointBackoff):
+		}
+	}
+}
+
+// Checkpoint retries while the database is busy
+const (
+	checkpointAttempts = 5
+	checkpointBackoff  = 50 * time.Millisecond
+)
+
+// isBusyError reports whether err is SQLite's SQLITE_BUSY, which libSQL only surfaces as text
+func isBusyError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "SQLITE_BUSY")
+}
+
 // Summarize creates a structured summary of conversation messages
 func (ms *MemorySystem) Summarize(ctx context.Context, messages []ConversationMessage) (Summary, error) {
 	return ms.summarizer.Summarize(ctx, messages)
@@ -277,23 +869,70 @@ func (ms *MemorySystem) GetMetrics() MetricsSummary {
 	return ms.metrics.GetSummary()
 }
 
-// Close gracefully shuts down the memory system
+// Close shuts the memory system down in dependency order: the warm-up and ingester stop
+// first, then the components that read through the indexes, then the indexes and stores
+// themselves, and finally a config-built embedder. Every closable subsystem is closed even
+// when an earlier one fails, and all errors are joined. The MemorySystemConfig.DB handle is
+// owned by the caller and left open; close it after Close returns. Later calls return the
+// first call's result
 func (ms *MemorySystem) Close() error {
-	// Stop ingester
+	ms.closeOnce.Do(func() {
+		ms.closeErr = ms.close()
+	})
+	return ms.closeErr
+}
+
+func (ms *MemorySystem) close() error {
+	// Abandon a warm-up still in progress and let it return before its components close
+	if ms.cancelWarmup != nil {
+		ms.cancelWarmup()
+		if ms.ready != nil {
+			<-ms.ready
+		}
+	}
+
+	var errs []error
+
+	// Stop the ingester so nothing writes to the indexes or stores below
 	if ms.ingester != nil {
 		if err := ms.ingester.Stop(); err != nil {
-			return fmt.Errorf("failed to stop ingester: %w", err)
+			errs = append(errs, fmt.Errorf("failed to stop ingester: %w", err))
 		}
 	}
 
-	// Close vector index
-	if ms.vectorIndex != nil {
-		if err := ms.vectorIndex.Close(); err != nil {
-			return fmt.Errorf("failed to close vector index: %w", err)
+	type component struct {
+		name  string
+		value interface{}
+	}
+	components := []component{
+		{"retriever", ms.retriever},
+		{"ensemble", ms.ensemble},
+		{"graph search", ms.graphSearch},
+		{"reranker", ms.reranker},
+		{"knowledge extractor", ms.extractor},
+		{"summarizer", ms.summarizer},
+		{"lexical index", ms.lexical},
+		{"vector index", ms.vectorIndex},
+		{"graph store", ms.graphStore},
+		{"memory store", ms.memoryStore},
+		{"session store", ms.sessionStore},
+	}
+	// Caller-supplied embedders may be shared, so only config-built ones are released
+	if ms.ownsEmbedder {
+		components = append(components, component{"embedder", ms.embedder})
+	}
+
+	for _, c := range components {
+		closer, ok := c.value.(io.Closer)
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %w", c.name, err))
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // GetMemoryStore returns the memory store for direct access
@@ -311,7 +950,9 @@ func (ms *MemorySystem) GetGraphStore() GraphStore {
 	return ms.graphStore
 }
 
-// DefaultEmbedder is a placeholder embedder implementation
+// DefaultEmbedder is a placeholder embedder producing zero vectors.
+// It is only used when selected explicitly with DefaultEmbedderProvider, or when no embedder
+// or embedding config is supplied and MemorySystemConfig.AllowNullEmbedder is set.
 type DefaultEmbedder struct {
 	dimension int
 }
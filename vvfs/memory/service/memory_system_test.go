@@ -0,0 +1,738 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	_ "github.com/tursodatabase/go-libsql"
+)
+
+// openTestMemoryDB opens a file-backed libSQL database with the memory migrations applied
+func openTestMemoryDB(t *testing.T, path string) *sql.DB {
+	return openTestMigratedDB(t, path, "*.sql")
+}
+
+// openTestMigratedDB opens a file-backed libSQL database with the migrations matching pattern
+// applied. A single connection with a busy timeout serializes the ingest workers' writes
+func openTestMigratedDB(t *testing.T, path, pattern string) *sql.DB {
+	db, err := sql.Open("libsql", "file:"+path)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{`PRAGMA journal_mode = WAL`, `PRAGMA busy_timeout = 5000`} {
+		rows, err := db.Query(pragma)
+		require.NoError(t, err)
+		rows.Close()
+	}
+
+	applyTestMigrations(t, db, pattern)
+	return db
+}
+
+// applyTestMigrations applies the pending migrations matching pattern
+func applyTestMigrations(t *testing.T, db *sql.DB, pattern string) {
+	migrations := fstest.MapFS{}
+	names, err := fs.Glob(os.DirFS("../migrations"), pattern)
+	require.NoError(t, err)
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join("../migrations", name))
+		require.NoError(t, err)
+		migrations[name] = &fstest.MapFile{Data: data}
+	}
+
+	provider, err := goose.NewProvider(goose.DialectTurso, db, migrations)
+	require.NoError(t, err)
+	_, err = provider.Up(context.Background())
+	require.NoError(t, err)
+}
+
+// testVector returns a deterministic embedding of the given dimension
+func testVector(dim int, seed float64) []float64 {
+	v := make([]float64, dim)
+	for i := range v {
+		v[i] = seed + float64(i)/float64(dim)
+	}
+	return v
+}
+
+// TestNewMemorySystem_RequiresEmbedder verifies the zero-vector fallback needs an explicit opt-in
+func TestNewMemorySystem_RequiresEmbedder(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+	memCfg := &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4}
+
+	_, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db})
+	assert.ErrorIs(t, err, ErrNoEmbedder)
+
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db, AllowNullEmbedder: true})
+	require.NoError(t, err)
+	defer ms.Close()
+	assert.IsType(t, &DefaultEmbedder{}, ms.embedder)
+}
+
+// gatedEmbedder reports ready only once its gate is closed, like a model still loading
+type gatedEmbedder struct {
+	*DefaultEmbedder
+	gate chan struct{}
+}
+
+func (e *gatedEmbedder) WaitReady(ctx context.Context) error {
+	select {
+	case <-e.gate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countingLexicalIndex counts the queries it serves
+type countingLexicalIndex struct {
+	MockLexicalIndex
+	queries int32
+}
+
+func (l *countingLexicalIndex) Query(ctx context.Context, query string, k int) ([]SearchResult, error) {
+	atomic.AddInt32(&l.queries, 1)
+	return l.MockLexicalIndex.Query(ctx, query, k)
+}
+
+// TestMemorySystem_WaitReady verifies the system reports ready only after its subsystems and warm-up query
+func TestMemorySystem_WaitReady(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	embedder := &gatedEmbedder{DefaultEmbedder: NewDefaultEmbedder(), gate: make(chan struct{})}
+	lexical := &countingLexicalIndex{}
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:       &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4},
+		DB:           db,
+		Embedder:     embedder,
+		LexicalIndex: lexical,
+		WarmupQuery:  "warm up",
+	})
+	require.NoError(t, err)
+	defer ms.Close()
+
+	assert.False(t, ms.Ready())
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ms.WaitReady(waitCtx), context.DeadlineExceeded)
+	assert.False(t, ms.Ready())
+	assert.Zero(t, atomic.LoadInt32(&lexical.queries), "warm-up query must wait for the embedder")
+
+	close(embedder.gate)
+	waitCtx, cancel = context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, ms.WaitReady(waitCtx))
+	assert.True(t, ms.Ready())
+	assert.Positive(t, atomic.LoadInt32(&lexical.queries), "warm-up query should have run")
+}
+
+// closeProbe counts Close calls and records the order they arrive in
+type closeProbe struct {
+	name  string
+	err   error
+	calls int
+	order *[]string
+}
+
+func (p *closeProbe) Close() error {
+	p.calls++
+	*p.order = append(*p.order, p.name)
+	return p.err
+}
+
+// Closable stand-ins for each subsystem; only Close is ever called
+type closingVectorIndex struct {
+	VectorIndex
+	probe *closeProbe
+}
+
+func (c *closingVectorIndex) Close() error { return c.probe.Close() }
+
+type closingLexicalIndex struct {
+	LexicalIndex
+	*closeProbe
+}
+
+type closingGraphStore struct {
+	GraphStore
+	*closeProbe
+}
+
+type closingMemoryStore struct {
+	MemoryStore
+	*closeProbe
+}
+
+type closingSessionStore struct {
+	SessionStore
+	*closeProbe
+}
+
+type closingReranker struct {
+	Reranker
+	*closeProbe
+}
+
+type closingExtractor struct {
+	KnowledgeExtractor
+	*closeProbe
+}
+
+type closingEmbedder struct {
+	Embedder
+	*closeProbe
+}
+
+// TestMemorySystem_CloseClosesEverySubsystemOnce verifies Close reaches every subsystem once,
+// in dependency order, and joins their errors
+func TestMemorySystem_CloseClosesEverySubsystemOnce(t *testing.T) {
+	var order []string
+	probe := func(name string, err error) *closeProbe {
+		return &closeProbe{name: name, err: err, order: &order}
+	}
+	errLexical := errors.New("fts busy")
+	errMemory := errors.New("disk full")
+	vector := probe("vector index", nil)
+	lexical := probe("lexical index", errLexical)
+	graph := probe("graph store", nil)
+	memory := probe("memory store", errMemory)
+	session := probe("session store", nil)
+	reranker := probe("reranker", nil)
+	extractor := probe("extractor", nil)
+	embedder := probe("embedder", nil)
+
+	memCfg := &config.MemoryConfig{IngestBatchSize: 1}
+	vectorIndex := &closingVectorIndex{probe: vector}
+	ms := &MemorySystem{
+		config:       memCfg,
+		vectorIndex:  vectorIndex,
+		lexical:      closingLexicalIndex{closeProbe: lexical},
+		graphStore:   closingGraphStore{closeProbe: graph},
+		memoryStore:  closingMemoryStore{closeProbe: memory},
+		sessionStore: closingSessionStore{closeProbe: session},
+		reranker:     closingReranker{closeProbe: reranker},
+		extractor:    closingExtractor{closeProbe: extractor},
+		embedder:     closingEmbedder{closeProbe: embedder},
+		ownsEmbedder: true,
+		ingester:     NewIngester(memCfg, vectorIndex, nil, nil, nil, NewMetricsCollector()),
+	}
+
+	err := ms.Close()
+	require.Error(t, err)
+	// A failing subsystem does not stop the rest from closing
+	assert.ErrorIs(t, err, errLexical)
+	assert.ErrorIs(t, err, errMemory)
+	assert.Contains(t, err.Error(), "failed to close lexical index")
+	assert.Contains(t, err.Error(), "failed to close memory store")
+
+	assert.Equal(t, []string{
+		"reranker", "extractor", "lexical index", "vector index",
+		"graph store", "memory store", "session store", "embedder",
+	}, order)
+
+	// Closing again is a no-op that reports the same outcome
+	assert.Equal(t, err, ms.Close())
+	for _, p := range []*closeProbe{vector, lexical, graph, memory, session, reranker, extractor, embedder} {
+		assert.Equal(t, 1, p.calls, p.name)
+	}
+
+	// Caller-supplied embedders are left open
+	order = nil
+	shared := probe("shared embedder", nil)
+	borrowed := &MemorySystem{embedder: closingEmbedder{closeProbe: shared}}
+	require.NoError(t, borrowed.Close())
+	assert.Zero(t, shared.calls)
+}
+
+// TestIngester_IngestAtomicRollsBackOnGraphFailure verifies a graph write failing mid-way
+// also rolls back the memory item written in the same transaction
+func TestIngester_IngestAtomicRollsBackOnGraphFailure(t *testing.T) {
+	ctx := context.Background()
+	// The graph store's entities table differs from the migrated one, so only the memory
+	// item migration is applied
+	db := openTestMigratedDB(t, filepath.Join(t.TempDir(), "memory.db"), "*_memory_items.sql")
+	defer db.Close()
+	// Only the entities table exists, so the edge upsert fails after an entity was written
+	_, err := db.Exec(testGraphSchema[0])
+	require.NoError(t, err)
+
+	extractor := new(MockKnowledgeExtractor)
+	extractor.On("Extract", mock.Anything, mock.Anything).Return(&ExtractionResult{
+		Entities: []Entity{{ID: "ada", Kind: "person", Name: "Ada"}, {ID: "bob", Kind: "person", Name: "Bob"}},
+		Edges:    []Edge{{ID: "ada-knows-bob", SourceID: "ada", TargetID: "bob", Relation: "knows", ValidFrom: time.Now()}},
+	}, nil)
+	ingester := NewIngester(&config.MemoryConfig{IngestBatchSize: 1}, NewFlatIndexImpl(db, 3), nil, nil, extractor, NewMetricsCollector())
+	defer ingester.Stop()
+
+	item := &MemoryItem{ID: "item-1", Type: "note", Text: "Ada knows Bob", Embedding: []float64{1, 0, 0}}
+	countRows := func(table string) int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM "+table).Scan(&n))
+		return n
+	}
+
+	err = ingester.IngestAtomic(ctx, SQLTx(db), item, &Episode{ID: "episode-1", Content: item.Text})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "edge upsert failed")
+	assert.Zero(t, countRows("memory_items"), "memory item must roll back with the graph")
+	assert.Zero(t, countRows("entities"))
+
+	// Once the graph can be written, the same ingest commits everything
+	_, err = db.Exec(testGraphSchema[1])
+	require.NoError(t, err)
+	require.NoError(t, ingester.IngestAtomic(ctx, SQLTx(db), item, &Episode{ID: "episode-1", Content: item.Text}))
+	assert.Equal(t, 1, countRows("memory_items"))
+	assert.Equal(t, 2, countRows("entities"))
+	assert.Equal(t, 1, countRows("edges"))
+}
+
+// TestMemorySystem_FlushPersistsAcrossReopen ingests, flushes, reopens and verifies durability
+func TestMemorySystem_FlushPersistsAcrossReopen(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	memCfg := &config.MemoryConfig{
+		VectorIndex:     "flat",
+		IngestBatchSize: 4,
+	}
+
+	db := openTestMemoryDB(t, dbPath)
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db, AllowNullEmbedder: true})
+	require.NoError(t, err)
+
+	dim := ms.embedder.Dimension()
+	const count = 20
+	for i := 0; i < count; i++ {
+		item := &MemoryItem{
+			ID:   fmt.Sprintf("item-%d", i),
+			Type: "document",
+			Text: fmt.Sprintf("document number %d", i),
+		}
+		require.NoError(t, ms.GetMemoryStore().PutItem(ctx, item))
+
+		item.Embedding = testVector(dim, float64(i))
+		require.NoError(t, ms.Ingest(ctx, item))
+	}
+
+	require.NoError(t, ms.Flush(ctx))
+	assert.Equal(t, 0, ms.ingester.GetQueueSize())
+	require.NoError(t, ms.Close())
+	require.NoError(t, db.Close())
+
+	// Reopen against the same database file
+	db = openTestMemoryDB(t, dbPath)
+	defer db.Close()
+	reopened, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db, AllowNullEmbedder: true})
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	for i := 0; i < count; i++ {
+		item, err := reopened.GetMemoryStore().GetItem(ctx, fmt.Sprintf("item-%d", i))
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("document number %d", i), item.Text)
+		assert.Len(t, item.Embedding, dim)
+	}
+
+	results, err := reopened.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Len(t, results, count)
+}
+
+// TestMemorySystem_ClearAndReindex verifies Clear empties the index and Reindex repopulates it
+func TestMemorySystem_ClearAndReindex(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:            &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4},
+		DB:                db,
+		AllowNullEmbedder: true,
+	})
+	require.NoError(t, err)
+	defer ms.Close()
+
+	dim := ms.embedder.Dimension()
+	const count = 10
+	for i := 0; i < count; i++ {
+		require.NoError(t, ms.GetMemoryStore().PutItem(ctx, &MemoryItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Type:      "document",
+			Text:      fmt.Sprintf("text %d", i),
+			Embedding: testVector(dim, float64(i)),
+		}))
+	}
+
+	results, err := ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	require.Len(t, results, count)
+
+	// Clear drops every vector
+	require.NoError(t, ms.vectorIndex.Clear(ctx))
+	results, err = ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Reindex with a "new model" that encodes the text length
+	var embedded []string
+	reembed := func(ctx context.Context, texts []string) ([][]float64, error) {
+		vectors := make([][]float64, len(texts))
+		for i, text := range texts {
+			embedded = append(embedded, text)
+			vectors[i] = testVector(dim, float64(len(text)))
+		}
+		return vectors, nil
+	}
+	require.NoError(t, ms.Reindex(ctx, reembed))
+	assert.Len(t, embedded, count)
+
+	results, err = ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Len(t, results, count)
+
+	item, err := ms.GetMemoryStore().GetItem(ctx, "item-3")
+	require.NoError(t, err)
+	assert.Equal(t, testVector(dim, float64(len("text 3"))), item.Embedding)
+}
+
+// failOnceIndex fails the failAt-th upsert it sees, once
+type failOnceIndex struct {
+	VectorIndex
+	failAt  int
+	upserts int
+}
+
+func (f *failOnceIndex) Upsert(ctx context.Context, id string, vector []float64) error {
+	f.upserts++
+	if f.upserts == f.failAt {
+		return errors.New("disk full")
+	}
+	return f.VectorIndex.Upsert(ctx, id, vector)
+}
+
+// TestMemorySystem_ReindexFailureKeepsVectors verifies a failed rebuild restores the previous
+// vectors instead of leaving the index empty, and a missing embedder is an error
+func TestMemorySystem_ReindexFailureKeepsVectors(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	const dim, count = 4, 6
+	index := &failOnceIndex{VectorIndex: NewFlatIndexImpl(db, dim), failAt: 3}
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:            &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4},
+		DB:                db,
+		VectorIndex:       index,
+		AllowNullEmbedder: true,
+	})
+	require.NoError(t, err)
+	defer ms.Close()
+
+	for i := 0; i < count; i++ {
+		require.NoError(t, ms.GetMemoryStore().PutItem(ctx, &MemoryItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Type:      "document",
+			Text:      fmt.Sprintf("text %d", i),
+			Embedding: testVector(dim, float64(i)),
+		}))
+	}
+
+	reembed := func(ctx context.Context, texts []string) ([][]float64, error) {
+		vectors := make([][]float64, len(texts))
+		for i := range texts {
+			vectors[i] = testVector(dim, 100)
+		}
+		return vectors, nil
+	}
+	err = ms.Reindex(ctx, reembed)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "previous vectors restored")
+
+	results, err := ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Len(t, results, count)
+	item, err := ms.GetMemoryStore().GetItem(ctx, "item-4")
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, testVector(dim, 4), item.Embedding, 1e-6)
+
+	// A re-embed failure leaves the index untouched
+	upserts := index.upserts
+	err = ms.Reindex(ctx, func(ctx context.Context, texts []string) ([][]float64, error) {
+		return nil, errors.New("model offline")
+	})
+	assert.ErrorContains(t, err, "model offline")
+	assert.Equal(t, upserts, index.upserts)
+
+	ms.embedder = nil
+	assert.ErrorIs(t, ms.Reindex(ctx, nil), ErrNoEmbedder)
+}
+
+// TestMemorySystem_LoadExistingVectors verifies items ingested under the flat index become
+// searchable after switching to an in-memory index and backfilling
+func TestMemorySystem_LoadExistingVectors(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "memory.db")
+	memCfg := &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 3}
+
+	db := openTestMemoryDB(t, dbPath)
+	defer db.Close()
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db, AllowNullEmbedder: true})
+	require.NoError(t, err)
+
+	dim := ms.embedder.Dimension()
+	const count = 7
+	for i := 0; i < count; i++ {
+		require.NoError(t, ms.GetMemoryStore().PutItem(ctx, &MemoryItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Type:      "document",
+			Text:      fmt.Sprintf("text %d", i),
+			Embedding: testVector(dim, float64(i)),
+		}))
+	}
+	// Items without a stored embedding are skipped
+	require.NoError(t, ms.GetMemoryStore().PutItem(ctx, &MemoryItem{ID: "no-vector", Type: "note", Text: "unembedded"}))
+	require.NoError(t, ms.Close())
+
+	// Switch to an in-memory index; it starts empty
+	index := &recordingIndex{vectors: make(map[string][]float64)}
+	switched, err := NewMemorySystem(ctx, MemorySystemConfig{Config: memCfg, DB: db, AllowNullEmbedder: true, VectorIndex: index})
+	require.NoError(t, err)
+	defer switched.Close()
+	require.NoError(t, switched.WaitReady(ctx))
+
+	results, err := switched.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	loaded, err := switched.LoadExistingVectors(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, count, loaded)
+	assert.False(t, index.has("no-vector"))
+
+	results, err = switched.vectorIndex.Query(ctx, testVector(dim, 3), count)
+	require.NoError(t, err)
+	require.Len(t, results, count)
+	assert.Equal(t, testVector(dim, 3), index.vectors["item-3"])
+
+	// With the option set, warm-up backfills on its own
+	autoIndex := &recordingIndex{vectors: make(map[string][]float64)}
+	autoCfg := &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 3, LoadExistingVectors: true}
+	auto, err := NewMemorySystem(ctx, MemorySystemConfig{Config: autoCfg, DB: db, AllowNullEmbedder: true, VectorIndex: autoIndex})
+	require.NoError(t, err)
+	defer auto.Close()
+	require.NoError(t, auto.WaitReady(ctx))
+	assert.True(t, autoIndex.has("item-0"))
+	assert.True(t, autoIndex.has("item-6"))
+}
+
+// TestMemorySystem_DeleteByFilter verifies only matching items and their vectors are removed
+func TestMemorySystem_DeleteByFilter(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:            &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4},
+		DB:                db,
+		AllowNullEmbedder: true,
+	})
+	require.NoError(t, err)
+	defer ms.Close()
+
+	dim := ms.embedder.Dimension()
+	const count = 9
+	for i := 0; i < count; i++ {
+		source := "manual"
+		if i%3 == 0 {
+			source = "import-2024"
+		}
+		require.NoError(t, ms.GetMemoryStore().PutItem(ctx, &MemoryItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			Type:      "document",
+			Text:      fmt.Sprintf("quarterly report %d", i),
+			Metadata:  map[string]interface{}{"source": source, "pinned": i == 3, "year": 2024},
+			Embedding: testVector(dim, float64(i)),
+		}))
+	}
+
+	// Prime the vector cache so stale entries would be visible
+	results, err := ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	require.Len(t, results, count)
+
+	deleted, err := ms.DeleteByFilter(ctx, map[string]interface{}{"source": "import-2024", "pinned": false})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted) // item-0 and item-6; item-3 is pinned
+
+	for i := 0; i < count; i++ {
+		_, err := ms.GetMemoryStore().GetItem(ctx, fmt.Sprintf("item-%d", i))
+		if i == 0 || i == 6 {
+			assert.Error(t, err, "item-%d should be deleted", i)
+		} else {
+			assert.NoError(t, err, "item-%d should remain", i)
+		}
+	}
+
+	results, err = ms.vectorIndex.Query(ctx, testVector(dim, 0), count)
+	require.NoError(t, err)
+	assert.Len(t, results, count-2)
+	for _, result := range results {
+		assert.NotContains(t, []string{"item-0", "item-6"}, result.ID)
+	}
+
+	lexicalResults, err := ms.lexical.Query(ctx, "quarterly", count)
+	require.NoError(t, err)
+	assert.Len(t, lexicalResults, count-2)
+	assert.NotContains(t, resultIDs(lexicalResults), "item-0")
+	assert.NotContains(t, resultIDs(lexicalResults), "item-6")
+	require.NoError(t, ms.lexical.(*LexicalIndexImpl).CheckIntegrity(ctx), "the FTS5 index follows the deletes")
+
+	// Numeric filters match regardless of JSON number formatting; nothing left to delete
+	deleted, err = ms.GetMemoryStore().DeleteByFilter(ctx, map[string]interface{}{"source": "import-2024", "year": 2024.0, "pinned": false})
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	_, err = ms.DeleteByFilter(ctx, map[string]interface{}{})
+	assert.Error(t, err)
+	_, err = ms.DeleteByFilter(ctx, map[string]interface{}{"source": []string{"a"}})
+	assert.Error(t, err)
+}
+
+// TestMemorySystem_IngestDocument verifies a long document is stored as linked, retrievable chunks
+func TestMemorySystem_IngestDocument(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+
+	ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+		Config:   &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4, ChunkSize: 24, ChunkOverlap: 8},
+		DB:       db,
+		Embedder: &fakeEmbedder{cfg: config.EmbeddingConfig{Dims: 8}},
+	})
+	require.NoError(t, err)
+	defer ms.Close()
+
+	doc := &Document{
+		ID:       "handbook",
+		Text:     longDocument(4, 5) + "\n\nThe zeppelin policy closes the handbook.",
+		Metadata: map[string]interface{}{"source": "upload"},
+	}
+	ids, err := ms.IngestDocument(ctx, doc)
+	require.NoError(t, err)
+	require.Greater(t, len(ids), 2)
+	require.NoError(t, ms.Flush(ctx))
+
+	for i, id := range ids {
+		item, err := ms.GetMemoryStore().GetItem(ctx, id)
+		require.NoError(t, err, "chunk %d should be retrievable", i)
+		assert.Equal(t, "handbook", item.SourceRef)
+		assert.Equal(t, "document", item.Type)
+		assert.Equal(t, "upload", item.Metadata["source"])
+		assert.EqualValues(t, i, item.Metadata["chunk_index"])
+		assert.EqualValues(t, len(ids), item.Metadata["chunk_count"])
+		assert.LessOrEqual(t, estimateTokens(item.Text), 24)
+		assert.Len(t, item.Embedding, 8)
+	}
+
+	vectorResults, err := ms.vectorIndex.Query(ctx, testVector(8, 1), len(ids)+1)
+	require.NoError(t, err)
+	assert.Len(t, vectorResults, len(ids))
+
+	lexicalResults, err := ms.lexical.Query(ctx, "zeppelin", 5)
+	require.NoError(t, err)
+	require.Len(t, lexicalResults, 1)
+	assert.Equal(t, ids[len(ids)-1], lexicalResults[0].ID)
+
+	_, err = ms.IngestDocument(ctx, &Document{ID: "empty", Text: "   "})
+	assert.Error(t, err)
+}
+
+// versionedEmbedder is a fakeEmbedder reporting a model version
+type versionedEmbedder struct {
+	fakeEmbedder
+	version string
+}
+
+func (e *versionedEmbedder) ModelVersion() string { return e.version }
+
+// TestMemorySystem_VerifyEmbeddingConsistency ingests under two model versions and checks the
+// mismatch is reported until Reindex re-embeds everything with the current model
+func TestMemorySystem_VerifyEmbeddingConsistency(t *testing.T) {
+	ctx := context.Background()
+	db := openTestMemoryDB(t, filepath.Join(t.TempDir(), "memory.db"))
+	defer db.Close()
+	memCfg := &config.MemoryConfig{VectorIndex: "flat", IngestBatchSize: 4, ChunkSize: 24, ChunkOverlap: 8}
+
+	open := func(version string) *MemorySystem {
+		ms, err := NewMemorySystem(ctx, MemorySystemConfig{
+			Config:   memCfg,
+			DB:       db,
+			Embedder: &versionedEmbedder{fakeEmbedder: fakeEmbedder{cfg: config.EmbeddingConfig{Dims: 8}}, version: version},
+		})
+		require.NoError(t, err)
+		return ms
+	}
+
+	old := open("model-a@8")
+	// A chunk whose write failed would be dead-lettered and skew the version counts
+	assertNoFailedIngests := func() {
+		var failed int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM failed_ingests`).Scan(&failed))
+		require.Zero(t, failed)
+	}
+
+	oldIDs, err := old.IngestDocument(ctx, &Document{ID: "old", Text: longDocument(2, 4)})
+	require.NoError(t, err)
+	require.NoError(t, old.Flush(ctx))
+	assertNoFailedIngests()
+
+	report, err := old.VerifyEmbeddingConsistency(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+	assert.Equal(t, map[string]int{"model-a@8": len(oldIDs)}, report.Versions)
+	require.NoError(t, old.Close())
+
+	// The embedder is upgraded without reindexing
+	ms := open("model-b@8")
+	defer ms.Close()
+	newIDs, err := ms.IngestDocument(ctx, &Document{ID: "new", Text: longDocument(2, 4)})
+	require.NoError(t, err)
+	require.NoError(t, ms.Flush(ctx))
+	assertNoFailedIngests()
+
+	report, err = ms.VerifyEmbeddingConsistency(ctx)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent)
+	assert.Equal(t, "model-b@8", report.CurrentVersion)
+	assert.Equal(t, map[string]int{"model-a@8": len(oldIDs), "model-b@8": len(newIDs)}, report.Versions)
+	assert.Equal(t, len(oldIDs), report.Mismatched)
+	assert.Equal(t, []string{"model-a@8"}, report.StaleVersions())
+
+	versions, err := ms.embeddingVersions.Versions(ctx, []string{oldIDs[0], newIDs[0]})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{oldIDs[0]: "model-a@8", newIDs[0]: "model-b@8"}, versions)
+
+	// Reindexing re-embeds the stale vectors with the current model
+	require.NoError(t, ms.Reindex(ctx, nil))
+	report, err = ms.VerifyEmbeddingConsistency(ctx)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent)
+	assert.Equal(t, map[string]int{"model-b@8": len(oldIDs) + len(newIDs)}, report.Versions)
+}
@@ -24,12 +24,36 @@ type MetricsCollector struct {
 	retrievalErrors int64
 	graphErrors     int64
 
+	// Source errors skipped by lenient searches
+	sourceFailures int64
+
+	// Vector hits dropped for coming from a different embedding model
+	staleVectors int64
+
+	// Failed ingests that could not be recorded for retry
+	deadLetterFailures int64
+
 	// Index-specific metrics
 	indexStats map[string]IndexStats
 
 	// Graph metrics
 	entityCount int64
 	edgeCount   int64
+
+	// Ingest-time dedup
+	dedupCount  int64
+	dedupEvents []DedupEvent // most recent maxDedupEvents
+}
+
+// maxDedupEvents bounds the dedup events kept for inspection
+const maxDedupEvents = 100
+
+// DedupEvent records an ingested item skipped as a near-duplicate
+type DedupEvent struct {
+	ItemID      string    `json:"item_id"`
+	CanonicalID string    `json:"canonical_id"`
+	Similarity  float64   `json:"similarity"`
+	At          time.Time `json:"at"`
 }
 
 // IndexStats tracks metrics for individual indexes
@@ -80,6 +104,33 @@ func (mc *MetricsCollector) RecordRetrieval(indexName string, duration time.Dura
 	mc.indexStats[indexName] = stats
 }
 
+// RecordSourceFailure records an error from one retrieval source during a lenient search
+func (mc *MetricsCollector) RecordSourceFailure(indexName string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.sourceFailures++
+	stats := mc.indexStats[indexName]
+	stats.ErrorCount++
+	mc.indexStats[indexName] = stats
+}
+
+// RecordStaleVectors counts vector hits skipped because their embedding model version is outdated
+func (mc *MetricsCollector) RecordStaleVectors(n int) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.staleVectors += int64(n)
+}
+
+// RecordDeadLetterFailure counts a failed ingest that could not be dead-lettered and is lost
+func (mc *MetricsCollector) RecordDeadLetterFailure() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.deadLetterFailures++
+}
+
 // RecordGraphIngest records a graph ingestion operation
 func (mc *MetricsCollector) RecordGraphIngest(duration time.Duration, err error) {
 	mc.mu.Lock()
@@ -92,6 +143,25 @@ func (mc *MetricsCollector) RecordGraphIngest(duration time.Duration, err error)
 	}
 }
 
+// RecordDedup records an item skipped as a duplicate of an existing one
+func (mc *MetricsCollector) RecordDedup(event DedupEvent) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.dedupCount++
+	mc.dedupEvents = append(mc.dedupEvents, event)
+	if len(mc.dedupEvents) > maxDedupEvents {
+		mc.dedupEvents = mc.dedupEvents[len(mc.dedupEvents)-maxDedupEvents:]
+	}
+}
+
+// DedupEvents returns the most recent dedup events, oldest first
+func (mc *MetricsCollector) DedupEvents() []DedupEvent {
+	mc.mu.RLock()
+	defer mc.mu.RUnlock()
+	return append([]DedupEvent(nil), mc.dedupEvents...)
+}
+
 // UpdateEntityCount updates the entity count
 func (mc *MetricsCollector) UpdateEntityCount(count int64) {
 	mc.mu.Lock()
@@ -122,18 +192,22 @@ func (mc *MetricsCollector) GetSummary() MetricsSummary {
 	defer mc.mu.RUnlock()
 
 	return MetricsSummary{
-		IngestCount:      mc.ingestCount,
-		RetrievalCount:   mc.retrievalCount,
-		GraphIngestCount: mc.graphIngestCount,
-		IngestErrors:     mc.ingestErrors,
-		RetrievalErrors:  mc.retrievalErrors,
-		GraphErrors:      mc.graphErrors,
-		EntityCount:      mc.entityCount,
-		EdgeCount:        mc.edgeCount,
-		IndexStats:       mc.indexStats,
-		IngestLatency:    mc.calculatePercentiles(mc.ingestLatency),
-		RetrievalLatency: mc.calculatePercentiles(mc.retrievalLatency),
-		GraphLatency:     mc.calculatePercentiles(mc.graphLatency),
+		IngestCount:        mc.ingestCount,
+		RetrievalCount:     mc.retrievalCount,
+		GraphIngestCount:   mc.graphIngestCount,
+		IngestErrors:       mc.ingestErrors,
+		RetrievalErrors:    mc.retrievalErrors,
+		GraphErrors:        mc.graphErrors,
+		SourceFailures:     mc.sourceFailures,
+		StaleVectors:       mc.staleVectors,
+		DeadLetterFailures: mc.deadLetterFailures,
+		EntityCount:        mc.entityCount,
+		EdgeCount:          mc.edgeCount,
+		DedupCount:         mc.dedupCount,
+		IndexStats:         mc.indexStats,
+		IngestLatency:      mc.calculatePercentiles(mc.ingestLatency),
+		RetrievalLatency:   mc.calculatePercentiles(mc.retrievalLatency),
+		GraphLatency:       mc.calculatePercentiles(mc.graphLatency),
 	}
 }
 
@@ -164,18 +238,22 @@ func (mc *MetricsCollector) calculatePercentiles(latencies []time.Duration) Late
 
 // MetricsSummary represents a summary of collected metrics
 type MetricsSummary struct {
-	IngestCount      int64                 `json:"ingest_count"`
-	RetrievalCount   int64                 `json:"retrieval_count"`
-	GraphIngestCount int64                 `json:"graph_ingest_count"`
-	IngestErrors     int64                 `json:"ingest_errors"`
-	RetrievalErrors  int64                 `json:"retrieval_errors"`
-	GraphErrors      int64                 `json:"graph_errors"`
-	EntityCount      int64                 `json:"entity_count"`
-	EdgeCount        int64                 `json:"edge_count"`
-	IndexStats       map[string]IndexStats `json:"index_stats"`
-	IngestLatency    LatencyPercentiles    `json:"ingest_latency"`
-	RetrievalLatency LatencyPercentiles    `json:"retrieval_latency"`
-	GraphLatency     LatencyPercentiles    `json:"graph_latency"`
+	IngestCount        int64                 `json:"ingest_count"`
+	RetrievalCount     int64                 `json:"retrieval_count"`
+	GraphIngestCount   int64                 `json:"graph_ingest_count"`
+	IngestErrors       int64                 `json:"ingest_errors"`
+	RetrievalErrors    int64                 `json:"retrieval_errors"`
+	GraphErrors        int64                 `json:"graph_errors"`
+	SourceFailures     int64                 `json:"source_failures"`
+	StaleVectors       int64                 `json:"stale_vectors"`
+	DeadLetterFailures int64                 `json:"dead_letter_failures"`
+	EntityCount        int64                 `json:"entity_count"`
+	EdgeCount          int64                 `json:"edge_count"`
+	DedupCount         int64                 `json:"dedup_count"`
+	IndexStats         map[string]IndexStats `json:"index_stats"`
+	IngestLatency      LatencyPercentiles    `json:"ingest_latency"`
+	RetrievalLatency   LatencyPercentiles    `json:"retrieval_latency"`
+	GraphLatency       LatencyPercentiles    `json:"graph_latency"`
 }
 
 // LatencyPercentiles represents latency percentiles
@@ -196,6 +274,9 @@ func (mc *MetricsCollector) Reset() {
 	mc.ingestErrors = 0
 	mc.retrievalErrors = 0
 	mc.graphErrors = 0
+	mc.sourceFailures = 0
+	mc.staleVectors = 0
+	mc.deadLetterFailures = 0
 	mc.entityCount = 0
 	mc.edgeCount = 0
 	mc.ingestLatency = mc.ingestLatency[:0]
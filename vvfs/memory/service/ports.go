@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -11,19 +12,65 @@ type Embedder interface {
 	Dimension() int
 }
 
+// QueryEmbedder is implemented by embedders whose models embed search queries differently
+// from stored documents. Embed is the document mode
+type QueryEmbedder interface {
+	EmbedQuery(ctx context.Context, texts []string) ([][]float64, error)
+}
+
 // VectorIndex manages vector storage and similarity search
 type VectorIndex interface {
 	Upsert(ctx context.Context, id string, vector []float64) error
 	Query(ctx context.Context, query []float64, k int) ([]SearchResult, error)
 	Delete(ctx context.Context, id string) error
+	Clear(ctx context.Context) error // Remove all vectors (e.g. before a re-index)
 	Close() error
 }
 
+// VectorBulkDeleter is implemented by vector indexes that can remove many vectors in one operation
+type VectorBulkDeleter interface {
+	DeleteBatch(ctx context.Context, ids []string) error
+}
+
+// IndexFlusher is implemented by vector indexes that buffer state in memory
+// and can persist it to durable storage on demand
+type IndexFlusher interface {
+	Flush(ctx context.Context) error
+}
+
+// FilteredVectorQuerier is implemented by vector indexes that can apply metadata equality
+// filters before scoring, so only matching vectors are scanned. Filters they cannot express
+// fail with ErrPrefilterUnsupported, and the caller falls back to post-filtering
+type FilteredVectorQuerier interface {
+	QueryFiltered(ctx context.Context, query []float64, k int, filters map[string]interface{}) ([]SearchResult, error)
+}
+
+// ErrPrefilterUnsupported is returned by QueryFiltered for filters the index cannot apply
+var ErrPrefilterUnsupported = errors.New("metadata prefilter unsupported")
+
+// VectorLookup is implemented by vector indexes that can return a stored vector,
+// letting callers compute exact similarities independent of the index's score scale
+type VectorLookup interface {
+	Vector(ctx context.Context, id string) ([]float64, error)
+}
+
+// ReadinessChecker is implemented by components that load or build state in the background,
+// e.g. an embedder loading model weights or an ANN index loading from disk
+type ReadinessChecker interface {
+	WaitReady(ctx context.Context) error // blocks until the component can serve requests
+}
+
 // LexicalIndex manages BM25/FTS5 search
 type LexicalIndex interface {
 	Query(ctx context.Context, query string, k int) ([]SearchResult, error)
 }
 
+// LexicalMaintainer is implemented by lexical indexes that support maintenance
+type LexicalMaintainer interface {
+	Optimize(ctx context.Context) error // compact the index after bulk writes
+	Reindex(ctx context.Context) error  // rebuild the index from the source table
+}
+
 // Retriever orchestrates hybrid retrieval
 type Retriever interface {
 	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
@@ -34,6 +81,17 @@ type Reranker interface {
 	Rerank(ctx context.Context, query string, results []SearchResult) ([]SearchResult, error)
 }
 
+// RelevanceScorer scores query/candidate pairs, e.g. a cross-encoder or LLM judge
+type RelevanceScorer interface {
+	ScorePairs(ctx context.Context, query string, candidates []SearchResult) ([]float64, error)
+}
+
+// CandidateInvalidator is implemented by rerankers that cache per-candidate state
+// which must be dropped when the candidate is re-ingested
+type CandidateInvalidator interface {
+	InvalidateCandidate(id string)
+}
+
 // Summarizer handles working memory summarization
 type Summarizer interface {
 	Summarize(ctx context.Context, messages []ConversationMessage) (Summary, error)
@@ -43,7 +101,7 @@ type Summarizer interface {
 type Scorer interface {
 	FuseScores(results []SearchResult, alpha float64) []SearchResult
 	ApplyThresholds(results []SearchResult, threshold float64) []SearchResult
-	ApplyAutocut(results []SearchResult) []SearchResult
+	ApplyAutocut(results []SearchResult, bounds AutocutBounds) []SearchResult
 	ApplyTimeDecay(results []SearchResult, lambda float64) []SearchResult
 	ApplySpatialBoost(results []SearchResult, center []float64, radius float64) []SearchResult
 }
@@ -53,9 +111,15 @@ type MemoryStore interface {
 	GetItem(ctx context.Context, id string) (*MemoryItem, error)
 	PutItem(ctx context.Context, item *MemoryItem) error
 	DeleteItem(ctx context.Context, id string) error
+	DeleteByFilter(ctx context.Context, filter map[string]interface{}) (int, error) // Deletes items whose metadata matches every filter entry
 	ListItems(ctx context.Context, opts ListOptions) ([]*MemoryItem, error)
 }
 
+// SourceRefLookup finds the item stored for a source such as a file path
+type SourceRefLookup interface {
+	GetItemBySourceRef(ctx context.Context, sourceRef string) (*MemoryItem, error) // nil when none is stored
+}
+
 // SessionStore manages conversation sessions
 type SessionStore interface {
 	GetSession(ctx context.Context, id string) (*Session, error)
@@ -71,14 +135,19 @@ type KnowledgeExtractor interface {
 // GraphStore manages entities and edges with temporal semantics
 type GraphStore interface {
 	GetEntity(ctx context.Context, id string) (*Entity, error)
+	GetEntities(ctx context.Context, ids []string) (map[string]*Entity, error) // Missing IDs are omitted
 	UpsertEntity(ctx context.Context, entity *Entity) error
 	DeleteEntity(ctx context.Context, id string) error
 	ListEntities(ctx context.Context, opts ListOptions) ([]*Entity, error)
+	IterateEntities(ctx context.Context, opts ListOptions, fn func(*Entity) error) error // Stream rows; stops on fn error
 
 	GetEdge(ctx context.Context, id string) (*Edge, error)
 	UpsertEdge(ctx context.Context, edge *Edge) error
 	InvalidateEdge(ctx context.Context, id string, reason string) error
 	ListEdges(ctx context.Context, opts ListOptions) ([]*Edge, error)
+	IterateEdges(ctx context.Context, opts ListOptions, fn func(*Edge) error) error // Stream rows; stops on fn error
+	// Edges touching an entity; current edges only unless Filter["include_invalidated"] is true
+	EdgesForEntity(ctx context.Context, entityID string, direction Direction, opts ListOptions) ([]*Edge, error)
 	// Temporal queries
 	GetEdgesAsOf(ctx context.Context, timepoint time.Time, opts ListOptions) ([]*Edge, error)
 	GetCurrentEdges(ctx context.Context, opts ListOptions) ([]*Edge, error)
@@ -118,6 +187,8 @@ type SearchResult struct {
 	Score      float64                `json:"score"`
 	Metadata   map[string]interface{} `json:"metadata"`
 	Provenance string                 `json:"provenance"` // Which index/source
+	// Per-source scores, ranks and fusion contributions; set only when SearchOptions.IncludeDebug is true
+	Debug map[string]interface{} `json:"debug,omitempty"`
 }
 
 // ConversationMessage represents a message in working memory
@@ -144,6 +215,15 @@ type MemoryItem struct {
 	SourceRef string                 `json:"source_ref"`
 }
 
+// Document is a long text ingested as chunked memory items linked by SourceRef
+type Document struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"` // Item type for the chunks; defaults to "document"
+	Text      string                 `json:"text"`
+	Metadata  map[string]interface{} `json:"metadata"` // Copied onto every chunk
+	ExpiresAt *time.Time             `json:"expires_at"`
+}
+
 // Session represents a conversation session
 type Session struct {
 	ID        string    `json:"id"`
@@ -193,10 +273,11 @@ type Edge struct {
 
 // GraphSearchResult represents a result from graph search
 type GraphSearchResult struct {
-	EntityID   string  `json:"entity_id"`
-	Score      float64 `json:"score"`
-	PathLength int     `json:"path_length"`
-	Relation   string  `json:"relation"`
+	EntityID   string   `json:"entity_id"`
+	Score      float64  `json:"score"`
+	PathLength int      `json:"path_length"`
+	Relation   string   `json:"relation"`
+	Path       []string `json:"path,omitempty"` // [center, relation, entity, ...]; set only when paths are requested
 }
 
 // EnsembleResult represents results from one index in the ensemble
@@ -215,10 +296,24 @@ type SearchOptions struct {
 	SpatialCenter   []float64              `json:"spatial_center"`
 	SpatialRadius   float64                `json:"spatial_radius"`
 	MetadataFilters map[string]interface{} `json:"metadata_filters"`
+	MetadataFields  []string               `json:"metadata_fields"` // Keys kept in each result's Metadata after filtering; empty keeps all
 	TimeDecay       bool                   `json:"time_decay"`
 	Autocut         bool                   `json:"autocut"`
+	AutocutBounds   AutocutBounds          `json:"autocut_bounds"` // Zero fields use MemoryConfig
 	Rerank          bool                   `json:"rerank"`
 	GraphDepth      int                    `json:"graph_depth"`
+	IncludeDebug    bool                   `json:"include_debug"`  // Populate SearchResult.Debug
+	StrictSources   bool                   `json:"strict_sources"` // Fail when any source errors instead of searching the rest
+
+	// Candidate overfetch; zero Overfetch uses MemoryConfig.Overfetch
+	Overfetch         int  `json:"overfetch"`          // Candidates fetched per source as a multiple of K
+	AdaptiveOverfetch bool `json:"adaptive_overfetch"` // Re-query with more candidates, up to MaxOverfetch, while results fall short of K
+}
+
+// AutocutBounds limits how far autocut may trim a result list
+type AutocutBounds struct {
+	MinResults     int     `json:"min_results"`      // Never cut below this many results
+	MaxCutFraction float64 `json:"max_cut_fraction"` // Never drop more than this share of results; 0 means no cap
 }
 
 // EnsembleSearchOptions for ensemble search
@@ -240,6 +335,8 @@ type RoutingOptions struct {
 type RoutingDecision struct {
 	Indexes []IndexConfig      `json:"indexes"`
 	Weights map[string]float64 `json:"weights"`
+	// Why indexes were included or skipped, copied into each ensemble result's metadata
+	Notes map[string]interface{} `json:"notes,omitempty"`
 }
 
 // IndexConfig for each index in the ensemble
@@ -267,11 +364,12 @@ const (
 
 // GraphSearchOptions for graph search
 type GraphSearchOptions struct {
-	CenterID    string `json:"center_id"`
-	Query       string `json:"query"`
-	Depth       int    `json:"depth"`
-	K           int    `json:"k"`
-	PathWeights bool   `json:"path_weights"`
+	CenterID     string `json:"center_id"`
+	Query        string `json:"query"`
+	Depth        int    `json:"depth"`
+	K            int    `json:"k"`
+	PathWeights  bool   `json:"path_weights"`
+	IncludePaths bool   `json:"include_paths"` // Populate GraphSearchResult.Path
 }
 
 // ListOptions for listing operations
@@ -2,9 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"math"
-	"sort"
 	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
@@ -15,9 +16,38 @@ type RetrieverImpl struct {
 	config       *config.MemoryConfig
 	lexicalIndex LexicalIndex
 	vectorIndex  VectorIndex
+	embedder     Embedder // Optional: embeds queries for vector search
 	graphSearch  GraphSearch
+	graphStore   GraphStore // validates center candidates when set
 	scorer       Scorer
 	metrics      *MetricsCollector
+	calibration  AlphaCalibrationConfig // bounds for CalibrateAlpha
+
+	// Optional: vector hits embedded with a model other than modelVersion are skipped
+	embeddingVersions EmbeddingVersionLookup
+	modelVersion      string
+
+	idNormalizer ResultIDNormalizer // fusion key per source result; nil merges on the raw ID
+}
+
+// ResultIDNormalizer maps a result from source ("lexical" or "vector") to the key fusion merges on.
+// Results from different sources are fused only when their keys match; the returned result keeps its raw ID
+type ResultIDNormalizer func(source string, result SearchResult) string
+
+// SourceScopedIDs keys results by source and ID, so results from different sources are never fused
+func SourceScopedIDs(source string, result SearchResult) string {
+	return source + ":" + result.ID
+}
+
+// IDSpaceNormalizer keys results by the ID space named in metadata[key] (e.g. "file" or "entity")
+// and their ID, so equal IDs fuse only within one space. Results without the key fall back to their source
+func IDSpaceNormalizer(key string) ResultIDNormalizer {
+	return func(source string, result SearchResult) string {
+		if space, ok := result.Metadata[key].(string); ok && space != "" {
+			return space + ":" + result.ID
+		}
+		return source + ":" + result.ID
+	}
 }
 
 // NewRetriever creates a new retriever
@@ -32,61 +62,238 @@ func NewRetriever(config *config.MemoryConfig, lexicalIndex LexicalIndex, vector
 	}
 }
 
-// Search performs hybrid retrieval with fusion and filtering
+// SetEmbedder embeds queries for vector search, in query mode when the embedder is a QueryEmbedder
+func (ret *RetrieverImpl) SetEmbedder(embedder Embedder) {
+	ret.embedder = embedder
+}
+
+// SetEmbeddingVersionCheck skips vector hits whose recorded model version differs from
+// modelVersion; hits with no recorded version are kept
+func (ret *RetrieverImpl) SetEmbeddingVersionCheck(lookup EmbeddingVersionLookup, modelVersion string) {
+	ret.embeddingVersions = lookup
+	ret.modelVersion = modelVersion
+}
+
+// SetResultIDNormalizer sets how fusion matches results across sources. Use it when sources
+// draw IDs from different spaces (e.g. file paths and entity UUIDs); nil merges on the raw ID
+func (ret *RetrieverImpl) SetResultIDNormalizer(normalizer ResultIDNormalizer) {
+	ret.idNormalizer = normalizer
+}
+
+// fusionKey returns the key result from source is merged on
+func (ret *RetrieverImpl) fusionKey(source string, result SearchResult) string {
+	if ret.idNormalizer == nil {
+		return result.ID
+	}
+	return ret.idNormalizer(source, result)
+}
+
+// Default candidate overfetch, as a multiple of K, when neither the query nor config sets one
+const (
+	defaultOverfetch    = 2
+	defaultMaxOverfetch = 8
+)
+
+// Search performs hybrid retrieval with fusion and filtering.
+// Each source is asked for K times the overfetch factor candidates; with AdaptiveOverfetch the
+// factor doubles and the sources are re-queried while filtering leaves fewer than K results
 func (ret *RetrieverImpl) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
 	start := time.Now()
 
-	// 1. Get candidate sets from lexical and vector indexes
-	var lexicalResults, vectorResults []SearchResult
+	factor, maxFactor := ret.overfetchFactors(opts)
+	// Embed once for every pass; a failure is charged to the vector source
+	queryVec, embedErr := ret.embedQuery(ctx, query)
+	var results []SearchResult
+	for {
+		pass, exhausted, err := ret.searchPass(ctx, query, queryVec, embedErr, opts, opts.K*factor)
+		if err != nil {
+			ret.metrics.RecordRetrieval("hybrid", time.Since(start), err)
+			return nil, err
+		}
+		results = pass
+		// Stop once K is filled, the sources have nothing more to give, or the cap is reached
+		if !opts.AdaptiveOverfetch || len(results) >= opts.K || exhausted || factor >= maxFactor {
+			break
+		}
+		factor = min(factor*2, maxFactor)
+	}
+
+	// 6. Truncate to final k and trim metadata to the requested fields
+	finalResults := projectMetadata(ret.truncateResults(results, opts.K), opts.MetadataFields)
+
+	duration := time.Since(start)
+	ret.metrics.RecordRetrieval("hybrid", duration, nil)
+
+	return finalResults, nil
+}
+
+// overfetchFactors resolves the initial overfetch factor and the adaptive cap from the query and config
+func (ret *RetrieverImpl) overfetchFactors(opts SearchOptions) (int, int) {
+	factor, maxFactor := opts.Overfetch, 0
+	if ret.config != nil {
+		if factor <= 0 {
+			factor = ret.config.Overfetch
+		}
+		maxFactor = ret.config.MaxOverfetch
+	}
+	if factor <= 0 {
+		factor = defaultOverfetch
+	}
+	if maxFactor <= 0 {
+		maxFactor = defaultMaxOverfetch
+	}
+	return min(factor, maxFactor), maxFactor
+}
+
+// embedQuery embeds query for vector search. Without an embedder the vector is empty
+func (ret *RetrieverImpl) embedQuery(ctx context.Context, query string) ([]float64, error) {
+	return embedSearchQuery(ctx, ret.embedder, query)
+}
+
+// embedSearchQuery embeds query with embedder, in query mode when it has one.
+// A nil embedder yields an empty vector
+func embedSearchQuery(ctx context.Context, embedder Embedder, query string) ([]float64, error) {
+	if embedder == nil {
+		return []float64{}, nil
+	}
+
+	var embeddings [][]float64
 	var err error
+	if queryEmbedder, ok := embedder.(QueryEmbedder); ok {
+		embeddings, err = queryEmbedder.EmbedQuery(ctx, []string{query})
+	} else {
+		embeddings, err = embedder.Embed(ctx, []string{query})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(embeddings) != 1 {
+		return nil, fmt.Errorf("failed to embed query: expected 1 embedding, got %d", len(embeddings))
+	}
+	return embeddings[0], nil
+}
+
+// searchPass fetches up to limit candidates per source and runs fusion, filters and reranking.
+// queryVec and embedErr are the query's embedding and its failure, if any.
+// exhausted reports that every source that answered returned fewer than limit candidates
+func (ret *RetrieverImpl) searchPass(ctx context.Context, query string, queryVec []float64, embedErr error, opts SearchOptions, limit int) ([]SearchResult, bool, error) {
+	// 1. Get candidate sets from lexical and vector indexes. A failing source is skipped
+	// unless opts.StrictSources is set; the search only fails when every source does.
+	var lexicalResults, vectorResults []SearchResult
+	var prefiltered map[string]bool
+	var sourceErrs []error
+	sources := 0
+	exhausted := true
 
 	// Lexical search (BM25/FTS5)
 	if ret.lexicalIndex != nil {
-		lexicalResults, err = ret.lexicalIndex.Query(ctx, query, opts.K*2) // Overfetch for fusion
+		sources++
+		var err error
+		lexicalResults, err = ret.lexicalIndex.Query(ctx, query, limit) // Overfetch for fusion
 		if err != nil {
-			return nil, fmt.Errorf("lexical search failed: %w", err)
+			if opts.StrictSources {
+				return nil, false, fmt.Errorf("lexical search failed: %w", err)
+			}
+			ret.recordSourceFailure("lexical", err)
+			sourceErrs = append(sourceErrs, fmt.Errorf("lexical search failed: %w", err))
+		} else if len(lexicalResults) >= limit {
+			exhausted = false
 		}
 	}
 
 	// Vector search (requires embedding - placeholder for now)
 	if ret.vectorIndex != nil {
-		// In a full implementation, embed the query first
-		// For now, use placeholder
-		vectorResults, err = ret.vectorIndex.Query(ctx, []float64{}, opts.K*2)
+		sources++
+		err := embedErr
+		if err == nil {
+			vectorResults, prefiltered, err = ret.queryVectors(ctx, queryVec, limit, opts.MetadataFilters)
+		}
+		if err == nil {
+			// Judge exhaustion before stale hits are dropped; a full page may hide current ones
+			if len(vectorResults) >= limit {
+				exhausted = false
+			}
+			vectorResults, err = ret.dropStaleVectors(ctx, vectorResults)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("vector search failed: %w", err)
+			if opts.StrictSources {
+				return nil, false, fmt.Errorf("vector search failed: %w", err)
+			}
+			ret.recordSourceFailure("vector", err)
+			sourceErrs = append(sourceErrs, fmt.Errorf("vector search failed: %w", err))
 		}
 	}
 
+	if sources > 0 && len(sourceErrs) == sources {
+		return nil, false, fmt.Errorf("all search sources failed: %w", errors.Join(sourceErrs...))
+	}
+
 	// 2. Fuse scores using alpha
-	fusedResults := ret.fuseResults(lexicalResults, vectorResults, opts.Alpha)
+	fusedResults := ret.fuseResults(lexicalResults, vectorResults, opts.Alpha, opts.IncludeDebug)
 
 	// 3. Apply filters and boosters
-	filteredResults := ret.applyFiltersAndBoosters(fusedResults, opts)
+	filteredResults := ret.applyFiltersAndBoosters(fusedResults, opts, prefiltered)
 
 	// 4. Apply thresholds and autocut
 	thresholdedResults := ret.scorer.ApplyThresholds(filteredResults, opts.Threshold)
-	autocutResults := ret.scorer.ApplyAutocut(thresholdedResults)
+	autocutResults := ret.scorer.ApplyAutocut(thresholdedResults, ret.autocutBounds(opts))
 
 	// 5. Optional reranking
 	if opts.Rerank && ret.graphSearch != nil {
+		var err error
 		autocutResults, err = ret.applyGraphReranking(ctx, query, autocutResults, opts)
 		if err != nil {
-			return nil, fmt.Errorf("reranking failed: %w", err)
+			return nil, false, fmt.Errorf("reranking failed: %w", err)
 		}
 	}
 
-	// 6. Truncate to final k
-	finalResults := ret.truncateResults(autocutResults, opts.K)
+	return autocutResults, exhausted, nil
+}
 
-	duration := time.Since(start)
-	ret.metrics.RecordRetrieval("hybrid", duration, nil)
+// recordSourceFailure logs and counts a source that failed during a lenient search
+func (ret *RetrieverImpl) recordSourceFailure(source string, err error) {
+	log.Printf("Warning: %s search failed, continuing with remaining sources: %v", source, err)
+	if ret.metrics != nil {
+		ret.metrics.RecordSourceFailure(source)
+	}
+}
 
-	return finalResults, nil
+// dropStaleVectors removes vector hits embedded with a different model version than the
+// current embedder, since their similarity scores are not comparable
+func (ret *RetrieverImpl) dropStaleVectors(ctx context.Context, results []SearchResult) ([]SearchResult, error) {
+	if ret.embeddingVersions == nil || len(results) == 0 {
+		return results, nil
+	}
+
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	versions, err := ret.embeddingVersions.Versions(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check embedding versions: %w", err)
+	}
+
+	kept := make([]SearchResult, 0, len(results))
+	stale := 0
+	for _, result := range results {
+		if version, ok := versions[result.ID]; ok && version != ret.modelVersion {
+			stale++
+			continue
+		}
+		kept = append(kept, result)
+	}
+	if stale > 0 {
+		log.Printf("Warning: skipped %d vector results embedded with a model other than %q; reindex to refresh them", stale, ret.modelVersion)
+		if ret.metrics != nil {
+			ret.metrics.RecordStaleVectors(stale)
+		}
+	}
+	return kept, nil
 }
 
-// fuseResults combines lexical and vector results using alpha fusion
-func (ret *RetrieverImpl) fuseResults(lexicalResults, vectorResults []SearchResult, alpha float64) []SearchResult {
+// fuseResults combines lexical and vector results using alpha fusion, recording per-source debug details when includeDebug is set
+func (ret *RetrieverImpl) fuseResults(lexicalResults, vectorResults []SearchResult, alpha float64, includeDebug bool) []SearchResult {
 	// Normalize scores per source
 	lexicalNorm := ret.normalizeScores(lexicalResults)
 	vectorNorm := ret.normalizeScores(vectorResults)
@@ -95,44 +302,70 @@ func (ret *RetrieverImpl) fuseResults(lexicalResults, vectorResults []SearchResu
 	resultMap := make(map[string]*SearchResult)
 
 	// Add lexical results
-	for _, result := range lexicalNorm {
-		if existing, exists := resultMap[result.ID]; exists {
+	for i, result := range lexicalNorm {
+		normalized := result.Score
+		key := ret.fusionKey("lexical", result)
+		if existing, exists := resultMap[key]; exists {
 			// Fuse scores: alpha * vector_score + (1-alpha) * lexical_score
 			existing.Score = alpha*existing.Score + (1-alpha)*result.Score
 			existing.Provenance += ",lexical"
 		} else {
 			result.Score = (1 - alpha) * result.Score
 			result.Provenance = "lexical"
-			resultMap[result.ID] = &result
+			resultMap[key] = &result
+		}
+		if includeDebug {
+			recordSourceDebug(resultMap[key], "lexical", lexicalResults[i].Score, normalized, i+1, (1-alpha)*normalized)
 		}
 	}
 
 	// Add vector results
-	for _, result := range vectorNorm {
-		if existing, exists := resultMap[result.ID]; exists {
-			existing.Score = alpha*result.Score + (1-alpha)*existing.Score
+	for i, result := range vectorNorm {
+		normalized := result.Score
+		key := ret.fusionKey("vector", result)
+		if existing, exists := resultMap[key]; exists {
+			// The lexical share is already weighted by (1-alpha)
+			existing.Score += alpha * result.Score
 			existing.Provenance += ",vector"
 		} else {
 			result.Score = alpha * result.Score
 			result.Provenance = "vector"
-			resultMap[result.ID] = &result
+			resultMap[key] = &result
+		}
+		if includeDebug {
+			recordSourceDebug(resultMap[key], "vector", vectorResults[i].Score, normalized, i+1, alpha*normalized)
 		}
 	}
 
 	// Convert back to slice
 	var fusedResults []SearchResult
 	for _, result := range resultMap {
+		if includeDebug {
+			result.Debug["alpha"] = alpha
+			result.Debug["fused_score"] = result.Score
+		}
 		fusedResults = append(fusedResults, *result)
 	}
 
-	// Sort by fused score
-	sort.Slice(fusedResults, func(i, j int) bool {
-		return fusedResults[i].Score > fusedResults[j].Score
-	})
+	// Sort by fused score; map iteration order must not decide ties
+	sortResultsStable(fusedResults)
 
 	return fusedResults
 }
 
+// recordSourceDebug stores one source's raw and normalized score, rank and fusion contribution in result.Debug
+func recordSourceDebug(result *SearchResult, source string, raw, normalized float64, rank int, contribution float64) {
+	if result.Debug == nil {
+		result.Debug = make(map[string]interface{})
+	}
+	result.Debug[source] = map[string]interface{}{
+		"raw_score":        raw,
+		"normalized_score": normalized,
+		"rank":             rank,
+		"contribution":     contribution,
+	}
+}
+
 // normalizeScores normalizes scores to [0,1] range per source
 func (ret *RetrieverImpl) normalizeScores(results []SearchResult) []SearchResult {
 	if len(results) == 0 {
@@ -171,10 +404,47 @@ func (ret *RetrieverImpl) normalizeScores(results []SearchResult) []SearchResult
 	return normalized
 }
 
-// applyFiltersAndBoosters applies metadata filters, time decay, and spatial boosters
-func (ret *RetrieverImpl) applyFiltersAndBoosters(results []SearchResult, opts SearchOptions) []SearchResult {
+// autocutBounds resolves the autocut bounds from the query, falling back to config per field
+func (ret *RetrieverImpl) autocutBounds(opts SearchOptions) AutocutBounds {
+	bounds := opts.AutocutBounds
+	if ret.config != nil {
+		if bounds.MinResults <= 0 {
+			bounds.MinResults = ret.config.AutocutMinResults
+		}
+		if bounds.MaxCutFraction <= 0 {
+			bounds.MaxCutFraction = ret.config.AutocutMaxCutFraction
+		}
+	}
+	return bounds
+}
+
+// queryVectors pushes metadata filters into the vector index when it supports prefiltering,
+// returning the IDs it matched so post-filtering keeps them. Otherwise, or when the index
+// cannot express the filters, it runs an unfiltered query left to post-filtering
+func (ret *RetrieverImpl) queryVectors(ctx context.Context, query []float64, k int, filters map[string]interface{}) ([]SearchResult, map[string]bool, error) {
+	if querier, ok := ret.vectorIndex.(FilteredVectorQuerier); ok && len(filters) > 0 {
+		results, err := querier.QueryFiltered(ctx, query, k, filters)
+		if err == nil {
+			matched := make(map[string]bool, len(results))
+			for _, result := range results {
+				matched[result.ID] = true
+			}
+			return results, matched, nil
+		}
+		if !errors.Is(err, ErrPrefilterUnsupported) {
+			return nil, nil, err
+		}
+	}
+
+	results, err := ret.vectorIndex.Query(ctx, query, k)
+	return results, nil, err
+}
+
+// applyFiltersAndBoosters applies metadata filters, time decay, and spatial boosters.
+// Results in prefiltered already matched the filters in the vector index
+func (ret *RetrieverImpl) applyFiltersAndBoosters(results []SearchResult, opts SearchOptions, prefiltered map[string]bool) []SearchResult {
 	// Apply metadata filters
-	filtered := ret.applyMetadataFilters(results, opts.MetadataFilters)
+	filtered := ret.applyMetadataFilters(results, opts.MetadataFilters, prefiltered)
 
 	// Apply time decay if enabled
 	if opts.TimeDecay {
@@ -190,10 +460,16 @@ func (ret *RetrieverImpl) applyFiltersAndBoosters(results []SearchResult, opts S
 }
 
 // applyMetadataFilters filters results based on metadata criteria
-func (ret *RetrieverImpl) applyMetadataFilters(results []SearchResult, filters map[string]interface{}) []SearchResult {
+func (ret *RetrieverImpl) applyMetadataFilters(results []SearchResult, filters map[string]interface{}, prefiltered map[string]bool) []SearchResult {
 	var filtered []SearchResult
 
 	for _, result := range results {
+		// Vector hits carry no metadata; the index already checked them
+		if prefiltered[result.ID] {
+			filtered = append(filtered, result)
+			continue
+		}
+
 		include := true
 
 		// Check each filter criterion
@@ -226,7 +502,10 @@ func (ret *RetrieverImpl) applyGraphReranking(ctx context.Context, query string,
 	}
 
 	// Find center entity for graph search
-	centerID := ret.findCenterEntity(results, opts)
+	centerID, err := ret.findCenterEntity(ctx, results, opts)
+	if err != nil {
+		return results, err // Continue without graph reranking
+	}
 
 	if centerID == "" {
 		return results, nil // No suitable center found
@@ -238,43 +517,64 @@ func (ret *RetrieverImpl) applyGraphReranking(ctx context.Context, query string,
 		return results, err // Continue without graph reranking
 	}
 
-	// Boost results that appear in graph search
-	resultMap := make(map[string]*SearchResult)
-	for _, result := range results {
-		resultMap[result.ID] = &result
+	// Boost results that appear in graph search. Results are boosted in place so ones that
+	// share an ID but were kept apart by fusion stay separate
+	boostedResults := make([]SearchResult, len(results))
+	copy(boostedResults, results)
+	positions := make(map[string][]int, len(boostedResults))
+	for i, result := range boostedResults {
+		positions[result.ID] = append(positions[result.ID], i)
 	}
 
 	for _, graphResult := range graphResults {
-		if existing, exists := resultMap[graphResult.EntityID]; exists {
+		for _, i := range positions[graphResult.EntityID] {
+			existing := &boostedResults[i]
 			// Boost score based on graph distance
 			boost := 1.0 / (1.0 + float64(graphResult.PathLength))
 			existing.Score *= (1.0 + boost)
+			if existing.Debug != nil {
+				existing.Debug["graph_boost"] = 1.0 + boost
+			}
 		}
 	}
 
 	// Re-sort after boosting
-	var boostedResults []SearchResult
-	for _, result := range resultMap {
-		boostedResults = append(boostedResults, *result)
-	}
-	sort.Slice(boostedResults, func(i, j int) bool {
-		return boostedResults[i].Score > boostedResults[j].Score
-	})
+	sortResultsStable(boostedResults)
 
 	return boostedResults, nil
 }
 
-// findCenterEntity selects a center entity for graph search
-func (ret *RetrieverImpl) findCenterEntity(results []SearchResult, opts SearchOptions) string {
-	// Simple heuristic: use the top result if it's an entity ID
-	if len(results) > 0 {
-		topResult := results[0]
-		// Check if ID looks like an entity ID (could be UUID or specific pattern)
-		if len(topResult.ID) > 10 { // Simple heuristic
-			return topResult.ID
+// findCenterEntity selects a center entity for graph search.
+// With a graph store the highest-ranked result that is a stored entity wins, resolved in one batch lookup.
+func (ret *RetrieverImpl) findCenterEntity(ctx context.Context, results []SearchResult, opts SearchOptions) (string, error) {
+	if len(results) == 0 {
+		return "", nil
+	}
+
+	if ret.graphStore != nil {
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = result.ID
 		}
+		entities, err := ret.graphStore.GetEntities(ctx, ids)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve center candidates: %w", err)
+		}
+		for _, result := range results {
+			if _, ok := entities[result.ID]; ok {
+				return result.ID, nil
+			}
+		}
+		return "", nil
 	}
-	return ""
+
+	// Simple heuristic: use the top result if it's an entity ID
+	topResult := results[0]
+	// Check if ID looks like an entity ID (could be UUID or specific pattern)
+	if len(topResult.ID) > 10 { // Simple heuristic
+		return topResult.ID, nil
+	}
+	return "", nil
 }
 
 // truncateResults limits results to k
@@ -284,3 +584,24 @@ func (ret *RetrieverImpl) truncateResults(results []SearchResult, k int) []Searc
 	}
 	return results[:k]
 }
+
+// projectMetadata keeps only fields in each result's Metadata; no fields keeps everything.
+// The maps are copied, so metadata shared with indexes is left intact
+func projectMetadata(results []SearchResult, fields []string) []SearchResult {
+	if len(fields) == 0 {
+		return results
+	}
+	for i := range results {
+		if results[i].Metadata == nil {
+			continue
+		}
+		projected := make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			if value, ok := results[i].Metadata[field]; ok {
+				projected[field] = value
+			}
+		}
+		results[i].Metadata = projected
+	}
+	return results
+}
@@ -0,0 +1,510 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetriever_FindCenterEntityBatchesLookups(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	store.On("GetEntities", mock.Anything, []string{"doc-1", "entity-a", "entity-b"}).
+		Return(map[string]*Entity{"entity-a": {ID: "entity-a"}, "entity-b": {ID: "entity-b"}}, nil).Once()
+
+	ret := NewRetriever(&config.MemoryConfig{}, nil, nil, nil, nil, nil)
+	ret.graphStore = store
+
+	results := []SearchResult{{ID: "doc-1"}, {ID: "entity-a"}, {ID: "entity-b"}}
+	centerID, err := ret.findCenterEntity(ctx, results, SearchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "entity-a", centerID, "highest-ranked stored entity is the center")
+	store.AssertExpectations(t)
+	store.AssertNumberOfCalls(t, "GetEntities", 1)
+}
+
+func TestRetriever_FindCenterEntityNoMatch(t *testing.T) {
+	ctx := context.Background()
+	store := &MockGraphStore{}
+	store.On("GetEntities", mock.Anything, []string{"doc-1"}).Return(map[string]*Entity{}, nil).Once()
+	store.On("GetEntities", mock.Anything, []string{"doc-2"}).Return(map[string]*Entity(nil), errors.New("db down")).Once()
+
+	ret := NewRetriever(&config.MemoryConfig{}, nil, nil, nil, nil, nil)
+	ret.graphStore = store
+
+	centerID, err := ret.findCenterEntity(ctx, []SearchResult{{ID: "doc-1"}}, SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, centerID)
+
+	_, err = ret.findCenterEntity(ctx, []SearchResult{{ID: "doc-2"}}, SearchOptions{})
+	assert.Error(t, err)
+	store.AssertExpectations(t)
+}
+
+func TestRetriever_SearchDebugPayload(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	lexical := &MockLexicalIndex{Results: []SearchResult{{ID: "doc-a", Score: 10}, {ID: "doc-b", Score: 5}, {ID: "doc-c", Score: 1}}}
+	vector := &MockVectorIndex{Results: []SearchResult{{ID: "doc-b", Score: 0.9}, {ID: "doc-a", Score: 0.5}, {ID: "doc-d", Score: 0.1}}}
+	ret := NewRetriever(cfg, lexical, vector, nil, NewScorer(cfg), NewMetricsCollector())
+
+	results, err := ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5, IncludeDebug: true})
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	top := results[0]
+	require.Equal(t, "doc-a", top.ID)
+	require.NotNil(t, top.Debug)
+
+	lexicalDebug, ok := top.Debug["lexical"].(map[string]interface{})
+	require.True(t, ok, "lexical debug entry")
+	assert.Equal(t, 10.0, lexicalDebug["raw_score"])
+	assert.Equal(t, 1.0, lexicalDebug["normalized_score"])
+	assert.Equal(t, 1, lexicalDebug["rank"])
+	assert.InDelta(t, 0.5, lexicalDebug["contribution"], 1e-9)
+
+	vectorDebug, ok := top.Debug["vector"].(map[string]interface{})
+	require.True(t, ok, "vector debug entry")
+	assert.Equal(t, 0.5, vectorDebug["raw_score"])
+	assert.InDelta(t, 0.5, vectorDebug["normalized_score"], 1e-9)
+	assert.Equal(t, 2, vectorDebug["rank"])
+	assert.InDelta(t, 0.25, vectorDebug["contribution"], 1e-9)
+
+	// The fused total is the sum of the source contributions
+	assert.InDelta(t, 0.75, top.Debug["fused_score"], 1e-9)
+	assert.InDelta(t, top.Score, top.Debug["fused_score"], 1e-9)
+	assert.Equal(t, 0.5, top.Debug["alpha"])
+
+	// Debug stays off by default
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Nil(t, result.Debug)
+	}
+}
+
+// TestRetriever_SearchNormalizesIDsAcrossSources verifies equal raw IDs from different ID
+// spaces are kept apart while genuinely shared documents still fuse
+func TestRetriever_SearchNormalizesIDsAcrossSources(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	fileSpace := map[string]interface{}{"id_space": "file"}
+	lexical := &MockLexicalIndex{Results: []SearchResult{
+		{ID: "42", Score: 10, Metadata: fileSpace},
+		{ID: "shared", Score: 5, Metadata: fileSpace},
+	}}
+	vector := &MockVectorIndex{Results: []SearchResult{
+		{ID: "42", Score: 0.9, Metadata: map[string]interface{}{"id_space": "entity"}},
+		{ID: "shared", Score: 0.5, Metadata: fileSpace},
+	}}
+	ret := NewRetriever(cfg, lexical, vector, nil, NewScorer(cfg), NewMetricsCollector())
+
+	// By default equal raw IDs are fused
+	results, err := ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	ret.SetResultIDNormalizer(IDSpaceNormalizer("id_space"))
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	provenance := make(map[string][]string)
+	for _, result := range results {
+		provenance[result.ID] = append(provenance[result.ID], result.Provenance)
+	}
+	// Returned IDs stay raw; the colliding documents are separate results from one source each
+	assert.ElementsMatch(t, []string{"lexical", "vector"}, provenance["42"])
+	assert.Equal(t, []string{"lexical,vector"}, provenance["shared"])
+
+	// Source scoping never fuses across sources
+	ret.SetResultIDNormalizer(SourceScopedIDs)
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	for _, result := range results {
+		assert.NotContains(t, result.Provenance, ",", result.ID)
+	}
+}
+
+// TestRetriever_FuseResultsBreaksTiesByID tests that equal fused scores rank identically on every run
+func TestRetriever_FuseResultsBreaksTiesByID(t *testing.T) {
+	cfg := &config.MemoryConfig{}
+	ret := NewRetriever(cfg, nil, nil, nil, NewScorer(cfg), NewMetricsCollector())
+
+	// Every result normalizes to the same score within its source
+	lexical := []SearchResult{{ID: "d", Score: 1}, {ID: "b", Score: 1}, {ID: "e", Score: 1}}
+	vector := []SearchResult{{ID: "c", Score: 0.5}, {ID: "a", Score: 0.5}}
+
+	var first []string
+	for run := 0; run < 20; run++ {
+		fused := ret.fuseResults(lexical, vector, 0.5, false)
+		order := make([]string, len(fused))
+		for i, result := range fused {
+			order[i] = result.ID
+		}
+		if run == 0 {
+			first = order
+			continue
+		}
+		require.Equal(t, first, order, "run %d", run)
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, first)
+}
+
+func TestRetriever_SearchDegradesWhenVectorIndexFails(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	lexical := &MockLexicalIndex{Results: []SearchResult{{ID: "doc-a", Score: 10}, {ID: "doc-b", Score: 5}}}
+	vector := &MockVectorIndex{Err: errors.New("vector index unavailable")}
+	metrics := NewMetricsCollector()
+	ret := NewRetriever(cfg, lexical, vector, nil, NewScorer(cfg), metrics)
+
+	// Lenient by default: lexical results still come back
+	results, err := ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "doc-a", results[0].ID)
+	for _, result := range results {
+		assert.Equal(t, "lexical", result.Provenance)
+	}
+
+	summary := metrics.GetSummary()
+	assert.Equal(t, int64(1), summary.SourceFailures)
+	assert.Equal(t, int64(1), summary.IndexStats["vector"].ErrorCount)
+	assert.Equal(t, int64(0), summary.RetrievalErrors)
+
+	// Strict mode surfaces the source failure
+	_, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5, StrictSources: true})
+	assert.ErrorContains(t, err, "vector search failed: vector index unavailable")
+
+	// With every source down the search fails even in lenient mode
+	lexical.Err = errors.New("fts unavailable")
+	_, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0.5})
+	assert.ErrorContains(t, err, "all search sources failed")
+	assert.ErrorContains(t, err, "fts unavailable")
+	assert.ErrorContains(t, err, "vector index unavailable")
+}
+
+// staticEmbeddingVersions serves recorded model versions from a map
+type staticEmbeddingVersions struct {
+	versions map[string]string
+	err      error
+}
+
+func (s staticEmbeddingVersions) Versions(ctx context.Context, ids []string) (map[string]string, error) {
+	return s.versions, s.err
+}
+
+// TestRetriever_SearchSkipsStaleEmbeddingVersions verifies vector hits from an older embedding
+// model are dropped and counted, while untagged hits are kept
+func TestRetriever_SearchSkipsStaleEmbeddingVersions(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	vector := &MockVectorIndex{Results: []SearchResult{
+		{ID: "old-a", Score: 0.9},
+		{ID: "new-a", Score: 0.8},
+		{ID: "old-b", Score: 0.7},
+		{ID: "untagged", Score: 0.6},
+	}}
+	metrics := NewMetricsCollector()
+	ret := NewRetriever(cfg, nil, vector, nil, NewScorer(cfg), metrics)
+	lookup := staticEmbeddingVersions{versions: map[string]string{
+		"old-a": "model-a@8",
+		"old-b": "model-a@8",
+		"new-a": "model-b@8",
+	}}
+	ret.SetEmbeddingVersionCheck(lookup, "model-b@8")
+
+	results, err := ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0})
+	require.NoError(t, err)
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.ID)
+	}
+	assert.ElementsMatch(t, []string{"new-a", "untagged"}, ids)
+	assert.Equal(t, int64(2), metrics.GetSummary().StaleVectors)
+
+	// A failing lookup is treated as a vector source failure
+	lookup.err = errors.New("versions table missing")
+	ret.SetEmbeddingVersionCheck(lookup, "model-b@8")
+	_, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0, StrictSources: true})
+	assert.ErrorContains(t, err, "failed to check embedding versions: versions table missing")
+}
+
+// limitedLexicalIndex serves the top limit results of a fixed pool and records each limit
+type limitedLexicalIndex struct {
+	pool   []SearchResult
+	limits []int
+}
+
+func (l *limitedLexicalIndex) Query(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	l.limits = append(l.limits, limit)
+	if limit > len(l.pool) {
+		limit = len(l.pool)
+	}
+	return l.pool[:limit], nil
+}
+
+// filteredPool has one "keep" result in four; the first five kept score far above the rest
+func filteredPool() []SearchResult {
+	pool := make([]SearchResult, 40)
+	for i := range pool {
+		score := 100 - float64(i)*0.1
+		if i >= 20 {
+			score = 10 - float64(i)*0.1
+		}
+		kind := "drop"
+		if i%4 == 0 {
+			kind = "keep"
+		}
+		pool[i] = SearchResult{ID: fmt.Sprintf("doc-%02d", i), Score: score, Metadata: map[string]interface{}{"kind": kind}}
+	}
+	return pool
+}
+
+func TestRetriever_SearchAdaptiveOverfetch(t *testing.T) {
+	ctx := context.Background()
+	opts := SearchOptions{K: 5, MetadataFilters: map[string]interface{}{"kind": "keep"}}
+
+	// The default overfetch under-fills K once filters prune three quarters of the candidates
+	cfg := &config.MemoryConfig{}
+	lexical := &limitedLexicalIndex{pool: filteredPool()}
+	ret := NewRetriever(cfg, lexical, nil, nil, NewScorer(cfg), NewMetricsCollector())
+	results, err := ret.Search(ctx, "query", opts)
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []int{10}, lexical.limits)
+
+	// Adaptive mode doubles the overfetch until K results survive
+	lexical.limits = nil
+	opts.AdaptiveOverfetch = true
+	results, err = ret.Search(ctx, "query", opts)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+	for i, result := range results {
+		assert.Equal(t, fmt.Sprintf("doc-%02d", i*4), result.ID)
+	}
+	assert.Equal(t, []int{10, 20, 40}, lexical.limits)
+
+	// Re-queries stop at the configured cap even when K is still unfilled
+	cfg.MaxOverfetch = 4
+	lexical.limits = nil
+	results, err = ret.Search(ctx, "query", opts)
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []int{10, 20}, lexical.limits)
+
+	// A per-query factor overrides config and is clamped to the cap
+	opts.AdaptiveOverfetch = false
+	opts.Overfetch = 16
+	lexical.limits = nil
+	_, err = ret.Search(ctx, "query", opts)
+	require.NoError(t, err)
+	assert.Equal(t, []int{20}, lexical.limits)
+
+	// Sources that run out of candidates end the re-queries early
+	cfg.MaxOverfetch = 0
+	opts = SearchOptions{K: 5, MetadataFilters: map[string]interface{}{"kind": "keep"}, AdaptiveOverfetch: true}
+	small := &limitedLexicalIndex{pool: filteredPool()[:12]}
+	ret = NewRetriever(cfg, small, nil, nil, NewScorer(cfg), NewMetricsCollector())
+	results, err = ret.Search(ctx, "query", opts)
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []int{10, 20}, small.limits)
+}
+
+// keyedLexicalIndex returns canned results per query text
+type keyedLexicalIndex struct {
+	byQuery map[string][]SearchResult
+}
+
+func (k *keyedLexicalIndex) Query(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return k.byQuery[query], nil
+}
+
+// keyedVectorIndex returns canned results keyed by the first query component
+type keyedVectorIndex struct {
+	MockVectorIndex
+	byKey map[float64][]SearchResult
+}
+
+func (k *keyedVectorIndex) Query(ctx context.Context, query []float64, limit int) ([]SearchResult, error) {
+	return k.byKey[query[0]], nil
+}
+
+func TestRetriever_CalibrateAlphaPrefersWinningSource(t *testing.T) {
+	ctx := context.Background()
+	lexical := &keyedLexicalIndex{byQuery: map[string][]SearchResult{}}
+	vector := &keyedVectorIndex{byKey: map[float64][]SearchResult{}}
+	var judgments []QueryJudgment
+
+	// Lexical ranks the relevant item first; vector ranks it last
+	for i := 0; i < 3; i++ {
+		query := fmt.Sprintf("query-%d", i)
+		relevant := fmt.Sprintf("rel-%d", i)
+		lexical.byQuery[query] = []SearchResult{{ID: relevant, Score: 10}, {ID: "noise-a", Score: 1}}
+		vector.byKey[float64(i)] = []SearchResult{{ID: "noise-b", Score: 0.9}, {ID: "noise-c", Score: 0.5}, {ID: relevant, Score: 0.1}}
+		judgments = append(judgments, QueryJudgment{
+			Query:     query,
+			Embedding: []float64{float64(i)},
+			Relevance: map[string]float64{relevant: 1},
+		})
+	}
+
+	cfg := &config.MemoryConfig{Alpha: 0.7}
+	ret := NewRetriever(cfg, lexical, vector, nil, NewScorer(cfg), NewMetricsCollector())
+
+	alpha, err := ret.CalibrateAlpha(ctx, judgments)
+	require.NoError(t, err)
+	assert.Less(t, alpha, 0.5)
+	// Every alpha below 0.5 ranks perfectly; ties resolve toward the configured alpha
+	assert.InDelta(t, 0.4, alpha, 1e-9)
+	assert.Equal(t, 0.7, cfg.Alpha, "config is untouched unless Apply is set")
+
+	ret.SetAlphaCalibration(AlphaCalibrationConfig{Steps: 5, Metric: "mrr", Apply: true})
+	alpha, err = ret.CalibrateAlpha(ctx, judgments)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, alpha, 1e-9)
+	assert.InDelta(t, 0.25, cfg.Alpha, 1e-9)
+
+	_, err = ret.CalibrateAlpha(ctx, []QueryJudgment{{Query: "query-0", Relevance: map[string]float64{"rel-0": 0}}})
+	assert.Error(t, err)
+}
+
+// prefilterVectorIndex serves per-workspace results from QueryFiltered and records the filters
+type prefilterVectorIndex struct {
+	MockVectorIndex
+	byWorkspace map[string][]SearchResult
+	unsupported bool
+	filtered    []map[string]interface{}
+}
+
+func (p *prefilterVectorIndex) QueryFiltered(ctx context.Context, query []float64, limit int, filters map[string]interface{}) ([]SearchResult, error) {
+	p.filtered = append(p.filtered, filters)
+	if p.unsupported {
+		return nil, ErrPrefilterUnsupported
+	}
+	workspace, _ := filters["workspace"].(string)
+	return p.byWorkspace[workspace], nil
+}
+
+func TestRetriever_SearchPrefiltersVectorIndex(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	vector := &prefilterVectorIndex{
+		MockVectorIndex: MockVectorIndex{Results: []SearchResult{{ID: "alpha-1", Score: 0.9}, {ID: "beta-1", Score: 0.8}}},
+		byWorkspace:     map[string][]SearchResult{"alpha": {{ID: "alpha-1", Score: 0.9}}},
+	}
+	ret := NewRetriever(cfg, nil, vector, nil, NewScorer(cfg), NewMetricsCollector())
+	filters := map[string]interface{}{"workspace": "alpha"}
+
+	// Prefiltered hits carry no metadata but still survive post-filtering
+	results, err := ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0, MetadataFilters: filters})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "alpha-1", results[0].ID)
+	require.Len(t, vector.filtered, 1)
+	assert.Equal(t, filters, vector.filtered[0])
+
+	// Without filters the plain query runs
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Len(t, vector.filtered, 1)
+
+	// Filters the index cannot apply fall back to post-filtering, which drops metadata-less hits
+	vector.unsupported = true
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 0, MetadataFilters: filters})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	assert.Len(t, vector.filtered, 2)
+}
+
+// modalEmbedder embeds documents as [0] and queries as [1], recording the texts of each mode
+type modalEmbedder struct {
+	queries   []string
+	documents []string
+}
+
+func (m *modalEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	m.documents = append(m.documents, texts...)
+	return [][]float64{{0}}, nil
+}
+
+func (m *modalEmbedder) EmbedQuery(ctx context.Context, texts []string) ([][]float64, error) {
+	m.queries = append(m.queries, texts...)
+	return [][]float64{{1}}, nil
+}
+
+func (m *modalEmbedder) Dimension() int {
+	return 1
+}
+
+// documentOnlyEmbedder hides EmbedQuery, leaving only the document mode
+type documentOnlyEmbedder struct {
+	Embedder
+}
+
+func TestRetriever_SearchEmbedsQueryInQueryMode(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	vector := &keyedVectorIndex{byKey: map[float64][]SearchResult{
+		0: {{ID: "document-mode", Score: 0.9}},
+		1: {{ID: "query-mode", Score: 0.9}},
+	}}
+	embedder := &modalEmbedder{}
+	ret := NewRetriever(cfg, nil, vector, nil, NewScorer(cfg), NewMetricsCollector())
+	ret.SetEmbedder(embedder)
+
+	results, err := ret.Search(ctx, "where is the invoice", SearchOptions{K: 10, Alpha: 0})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "query-mode", results[0].ID)
+	assert.Equal(t, []string{"where is the invoice"}, embedder.queries)
+	assert.Empty(t, embedder.documents)
+
+	// Embedders without a query mode fall back to Embed
+	ret.SetEmbedder(documentOnlyEmbedder{embedder})
+	results, err = ret.Search(ctx, "where is the invoice", SearchOptions{K: 10, Alpha: 0})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "document-mode", results[0].ID)
+}
+
+func TestRetriever_SearchProjectsMetadataFields(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.MemoryConfig{}
+	stored := []SearchResult{
+		{ID: "photo", Score: 0.9, Metadata: map[string]interface{}{"kind": "image", "path": "/a.jpg", "exif": "Canon"}},
+		{ID: "notes", Score: 0.8, Metadata: map[string]interface{}{"kind": "text", "path": "/b.txt", "exif": ""}},
+	}
+	lexical := &keyedLexicalIndex{byQuery: map[string][]SearchResult{"query": stored}}
+	ret := NewRetriever(cfg, lexical, nil, nil, NewScorer(cfg), NewMetricsCollector())
+
+	// The filter uses "kind", which the projection leaves out
+	results, err := ret.Search(ctx, "query", SearchOptions{
+		K:               10,
+		Alpha:           1,
+		MetadataFilters: map[string]interface{}{"kind": "image"},
+		MetadataFields:  []string{"path", "missing"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "photo", results[0].ID)
+	assert.Equal(t, map[string]interface{}{"path": "/a.jpg"}, results[0].Metadata)
+	assert.Len(t, stored[0].Metadata, 3, "the index's metadata must not be trimmed")
+
+	// No fields keeps the full metadata
+	results, err = ret.Search(ctx, "query", SearchOptions{K: 10, Alpha: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Len(t, result.Metadata, 3, result.ID)
+	}
+}
@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 )
@@ -23,6 +25,7 @@ func (qr *QueryRouterImpl) Route(ctx context.Context, query string, opts Routing
 	decision := &RoutingDecision{
 		Indexes: []IndexConfig{},
 		Weights: map[string]float64{},
+		Notes:   map[string]interface{}{},
 	}
 
 	// Simple rule-based routing (can be extended with ML)
@@ -54,17 +57,26 @@ func (qr *QueryRouterImpl) Route(ctx context.Context, query string, opts Routing
 		decision.Weights["vector"] = qr.config.WeightsVector
 	}
 
-	// Include graph search if graph is enabled and query suggests relationships
-	if qr.config.GraphEnabled && qr.isGraphQuery(queryLower) {
-		decision.Indexes = append(decision.Indexes, IndexConfig{
-			Name:    "graph",
-			Enabled: true,
-			Options: map[string]interface{}{
-				"depth":       qr.config.GraphDepth,
-				"rerank_only": qr.config.GraphRerankOnly,
-			},
-		})
-		decision.Weights["graph"] = qr.config.WeightsGraph
+	// Include graph search only when the query names entities to anchor a traversal;
+	// pure keyword lookups go to bm25 and vector alone
+	if qr.config.GraphEnabled {
+		mentions := countEntityMentions(query)
+		minEntities := qr.minGraphEntities()
+		decision.Notes["entity_mentions"] = mentions
+		if mentions >= minEntities || (mentions > 0 && qr.isGraphQuery(queryLower)) {
+			decision.Indexes = append(decision.Indexes, IndexConfig{
+				Name:    "graph",
+				Enabled: true,
+				Options: map[string]interface{}{
+					"depth":       qr.config.GraphDepth,
+					"rerank_only": qr.config.GraphRerankOnly,
+				},
+			})
+			decision.Weights["graph"] = qr.config.WeightsGraph
+			decision.Notes["graph"] = "included"
+		} else {
+			decision.Notes["graph"] = fmt.Sprintf("skipped: %d entity mentions, need %d", mentions, minEntities)
+		}
 	}
 
 	// Apply budget constraints
@@ -99,6 +111,68 @@ func (qr *QueryRouterImpl) isGraphQuery(query string) bool {
 	return false
 }
 
+// minGraphEntities returns the entity mentions a query needs before graph search runs
+func (qr *QueryRouterImpl) minGraphEntities() int {
+	if qr.config.GraphMinEntities > 0 {
+		return qr.config.GraphMinEntities
+	}
+	return 1
+}
+
+// nonEntityWords are often capitalized without naming anything
+var nonEntityWords = map[string]bool{
+	"a": true, "an": true, "the": true, "i": true, "is": true, "are": true, "do": true, "does": true,
+	"what": true, "who": true, "where": true, "when": true, "why": true, "how": true, "which": true,
+	"show": true, "find": true, "list": true, "get": true, "search": true,
+}
+
+// countEntityMentions counts tokens that look like named entities: capitalized words past
+// the first, acronyms, and mixed-case or alphanumeric identifiers
+func countEntityMentions(query string) int {
+	mentions := 0
+	for i, field := range strings.Fields(query) {
+		token := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if token == "" || nonEntityWords[strings.ToLower(token)] {
+			continue
+		}
+		if looksLikeEntity(token, i == 0) {
+			mentions++
+		}
+	}
+	return mentions
+}
+
+// looksLikeEntity reports whether token reads as a name rather than an ordinary word.
+// A capitalized first word is ambiguous, so it only counts when it has other markers
+func looksLikeEntity(token string, first bool) bool {
+	var upper, lower, digit int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		case unicode.IsDigit(r):
+			digit++
+		}
+	}
+	leadingUpper := unicode.IsUpper([]rune(token)[0])
+
+	switch {
+	case upper >= 2: // acronyms and inner capitals: NASA, GitHub
+		return true
+	case upper > 0 && !leadingUpper: // camelCase and brand casing: iPhone
+		return true
+	case digit > 0 && upper+lower > 0: // identifiers: GPT4, rfc9110
+		return true
+	case leadingUpper && !first: // proper noun mid-sentence
+		return true
+	}
+	return false
+}
+
 // applyLatencyBudget adjusts decision based on latency constraints
 func (qr *QueryRouterImpl) applyLatencyBudget(decision *RoutingDecision, maxLatency time.Duration) {
 	// If latency budget is tight, disable slower indexes
@@ -108,6 +182,7 @@ func (qr *QueryRouterImpl) applyLatencyBudget(decision *RoutingDecision, maxLate
 			if idx.Name == "graph" {
 				decision.Indexes[i].Enabled = false
 				delete(decision.Weights, "graph")
+				decision.Notes["graph"] = "skipped: latency budget"
 			}
 		}
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestQueryRouterImpl_Route tests query routing logic
@@ -48,3 +49,46 @@ func TestQueryRouterImpl_isGraphQuery(t *testing.T) {
 	assert.True(t, router.isGraphQuery("What projects is John working on?"))
 	assert.False(t, router.isGraphQuery("simple search"))
 }
+
+// TestQueryRouterImpl_RouteGraphByEntityMentions tests that graph search is only routed for entity queries
+func TestQueryRouterImpl_RouteGraphByEntityMentions(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		minEntities int
+		wantGraph   bool
+	}{
+		{name: "keyword lookup", query: "invoice pdf 2023", wantGraph: false},
+		{name: "capitalized first word only", query: "Quarterly budget spreadsheet", wantGraph: false},
+		{name: "proper nouns", query: "what did Alice send to Bob about Apollo", wantGraph: true},
+		{name: "acronym", query: "notes on the NASA launch", wantGraph: true},
+		{name: "identifier", query: "benchmarks for gpt4", wantGraph: true},
+		{name: "below threshold", query: "emails from Alice", minEntities: 2, wantGraph: false},
+		{name: "relationship phrasing", query: "who works with Alice", minEntities: 2, wantGraph: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := NewQueryRouter(&config.MemoryConfig{GraphEnabled: true, WeightsGraph: 0.1, GraphMinEntities: tc.minEntities})
+
+			decision, err := router.Route(context.Background(), tc.query, RoutingOptions{Query: tc.query})
+			require.NoError(t, err)
+
+			var hasGraph bool
+			for _, idx := range decision.Indexes {
+				if idx.Name == "graph" && idx.Enabled {
+					hasGraph = true
+				}
+			}
+			assert.Equal(t, tc.wantGraph, hasGraph)
+			assert.Contains(t, decision.Notes, "entity_mentions")
+			if tc.wantGraph {
+				assert.Equal(t, "included", decision.Notes["graph"])
+				assert.Contains(t, decision.Weights, "graph")
+			} else {
+				assert.Contains(t, decision.Notes["graph"], "skipped")
+				assert.NotContains(t, decision.Weights, "graph")
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
@@ -11,6 +13,7 @@ import (
 // ScorerImpl implements Scorer for fusion, thresholding, and boosters
 type ScorerImpl struct {
 	config *config.MemoryConfig
+	now    func() time.Time // clock for time decay; nil uses time.Now
 }
 
 // NewScorer creates a new scorer
@@ -37,8 +40,12 @@ func (sc *ScorerImpl) ApplyThresholds(results []SearchResult, threshold float64)
 	return filtered
 }
 
-// ApplyAutocut applies knee detection to cut off low-relevance results
-func (sc *ScorerImpl) ApplyAutocut(results []SearchResult) []SearchResult {
+// defaultAutocutMinResults is the autocut floor when neither the query nor config sets one
+const defaultAutocutMinResults = 3
+
+// ApplyAutocut applies knee detection to cut off low-relevance results. The cut keeps at
+// least bounds.MinResults results and drops at most bounds.MaxCutFraction of them
+func (sc *ScorerImpl) ApplyAutocut(results []SearchResult, bounds AutocutBounds) []SearchResult {
 	if len(results) < 3 {
 		return results // Need at least 3 points for knee detection
 	}
@@ -61,38 +68,50 @@ func (sc *ScorerImpl) ApplyAutocut(results []SearchResult) []SearchResult {
 	}
 
 	// Cut at knee point, but ensure we keep at least some results
-	minResults := 3
-	if kneeIndex < minResults {
-		kneeIndex = minResults
+	minResults := bounds.MinResults
+	if minResults <= 0 {
+		minResults = defaultAutocutMinResults
+	}
+	if bounds.MaxCutFraction > 0 && bounds.MaxCutFraction < 1 {
+		maxCut := int(math.Floor(float64(len(results)) * bounds.MaxCutFraction))
+		minResults = max(minResults, len(results)-maxCut)
 	}
+	kneeIndex = min(max(kneeIndex, minResults), len(results))
 
 	return results[:kneeIndex]
 }
 
-// ApplyTimeDecay applies exponential decay based on recency
+// ApplyTimeDecay multiplies each score by the configured decay of its age.
+// lambda is the per-day rate for exponential and linear decay; results without a
+// parseable created_at timestamp keep their score.
 func (sc *ScorerImpl) ApplyTimeDecay(results []SearchResult, lambda float64) []SearchResult {
+	fn := TimeDecayExponential
+	var halfLife, cutoff time.Duration
+	if sc.config != nil {
+		if parsed, err := ParseTimeDecayFunc(sc.config.TimeDecayFunc); err == nil {
+			fn = parsed
+		}
+		halfLife = sc.config.TimeDecayHalfLife
+		cutoff = sc.config.TimeDecayCutoff
+	}
+
 	now := time.Now()
+	if sc.now != nil {
+		now = sc.now()
+	}
 
 	for i := range results {
-		// Extract creation time from metadata (assuming it's stored there)
-		var createdAt time.Time
-		if createdAtVal, ok := results[i].Metadata["created_at"]; ok {
-			if t, ok := createdAtVal.(time.Time); ok {
-				createdAt = t
-			}
+		createdAt, ok := resultTimestamp(results[i].Metadata)
+		if !ok {
+			continue
 		}
 
-		// Calculate age in days
-		age := now.Sub(createdAt).Hours() / 24
-
-		// Apply exponential decay: score *= exp(-lambda * age)
-		decayFactor := math.Exp(-lambda * age)
+		// Calculate age in days; future timestamps count as brand new
+		age := math.Max(now.Sub(createdAt).Hours()/24, 0)
+		decayFactor := timeDecayFactor(fn, age, lambda, halfLife, cutoff)
 		results[i].Score *= decayFactor
 
 		// Update metadata to include decay info
-		if results[i].Metadata == nil {
-			results[i].Metadata = make(map[string]interface{})
-		}
 		results[i].Metadata["decay_factor"] = decayFactor
 		results[i].Metadata["age_days"] = age
 	}
@@ -100,6 +119,84 @@ func (sc *ScorerImpl) ApplyTimeDecay(results []SearchResult, lambda float64) []S
 	return results
 }
 
+// TimeDecayFunc selects the shape of recency decay
+type TimeDecayFunc string
+
+const (
+	// TimeDecayExponential scores exp(-lambda*age), or halves every TimeDecayHalfLife when set
+	TimeDecayExponential TimeDecayFunc = "exponential"
+	// TimeDecayLinear falls from 1 to 0 at TimeDecayCutoff, or at slope lambda per day without one
+	TimeDecayLinear TimeDecayFunc = "linear"
+	// TimeDecayStep keeps full score up to TimeDecayCutoff and zeroes older results
+	TimeDecayStep TimeDecayFunc = "step"
+)
+
+// ParseTimeDecayFunc validates a decay name; empty selects exponential
+func ParseTimeDecayFunc(name string) (TimeDecayFunc, error) {
+	switch fn := TimeDecayFunc(strings.ToLower(strings.TrimSpace(name))); fn {
+	case "":
+		return TimeDecayExponential, nil
+	case TimeDecayExponential, TimeDecayLinear, TimeDecayStep:
+		return fn, nil
+	default:
+		return "", fmt.Errorf("unsupported time decay function %q", name)
+	}
+}
+
+// timeDecayFactor returns the multiplier in [0, 1] for an item ageDays old
+func timeDecayFactor(fn TimeDecayFunc, ageDays, lambda float64, halfLife, cutoff time.Duration) float64 {
+	cutoffDays := cutoff.Hours() / 24
+
+	switch fn {
+	case TimeDecayLinear:
+		if cutoffDays > 0 {
+			return math.Max(1-ageDays/cutoffDays, 0)
+		}
+		return math.Max(1-lambda*ageDays, 0)
+	case TimeDecayStep:
+		if cutoffDays > 0 && ageDays > cutoffDays {
+			return 0
+		}
+		return 1
+	default:
+		if halfLifeDays := halfLife.Hours() / 24; halfLifeDays > 0 {
+			return math.Pow(0.5, ageDays/halfLifeDays)
+		}
+		return math.Exp(-lambda * ageDays)
+	}
+}
+
+// createdAtLayouts are the textual timestamp formats found in result metadata
+var createdAtLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700 MST", // time.Time.String, as written by the lexical index
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// resultTimestamp reads created_at from metadata as a time, string or unix seconds
+func resultTimestamp(metadata map[string]interface{}) (time.Time, bool) {
+	switch v := metadata["created_at"].(type) {
+	case time.Time:
+		return v, !v.IsZero()
+	case *time.Time:
+		if v != nil && !v.IsZero() {
+			return *v, true
+		}
+	case string:
+		for _, layout := range createdAtLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	case int64:
+		return time.Unix(v, 0), true
+	case float64:
+		return time.Unix(int64(v), 0), true
+	}
+	return time.Time{}, false
+}
+
 // ApplySpatialBoost applies spatial proximity boost
 func (sc *ScorerImpl) ApplySpatialBoost(results []SearchResult, center []float64, radius float64) []SearchResult {
 	// Simple implementation: boost based on Euclidean distance to center
@@ -184,9 +281,18 @@ func (sc *ScorerImpl) CalculateScoreDistribution(results []SearchResult) map[str
 	}
 }
 
-// SortByScore sorts results by score descending
+// SortByScore sorts results by score descending, breaking ties by ID
 func (sc *ScorerImpl) SortByScore(results []SearchResult) {
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+	sortResultsStable(results)
+}
+
+// sortResultsStable orders by score descending, breaking ties by ID so equal scores,
+// which often come out of map iteration, rank the same way on every run
+func sortResultsStable(results []SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
 	})
 }
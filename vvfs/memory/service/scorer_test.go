@@ -0,0 +1,135 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var decayNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// agedResults returns unit-score results created the given number of days before decayNow
+func agedResults(days ...int) []SearchResult {
+	results := make([]SearchResult, len(days))
+	for i, d := range days {
+		results[i] = SearchResult{
+			ID:       string(rune('a' + i)),
+			Score:    1,
+			Metadata: map[string]interface{}{"created_at": decayNow.Add(-time.Duration(d) * 24 * time.Hour)},
+		}
+	}
+	return results
+}
+
+func newDecayScorer(cfg *config.MemoryConfig) *ScorerImpl {
+	sc := NewScorer(cfg)
+	sc.now = func() time.Time { return decayNow }
+	return sc
+}
+
+func TestApplyTimeDecay_Exponential(t *testing.T) {
+	sc := newDecayScorer(&config.MemoryConfig{TimeDecayFunc: "exponential"})
+	results := sc.ApplyTimeDecay(agedResults(0, 1, 10), 0.1)
+
+	assert.InDelta(t, 1.0, results[0].Score, 1e-9)
+	assert.InDelta(t, math.Exp(-0.1), results[1].Score, 1e-9)
+	assert.InDelta(t, math.Exp(-1.0), results[2].Score, 1e-9)
+}
+
+func TestApplyTimeDecay_ExponentialHalfLife(t *testing.T) {
+	sc := newDecayScorer(&config.MemoryConfig{TimeDecayHalfLife: 7 * 24 * time.Hour})
+	results := sc.ApplyTimeDecay(agedResults(0, 7, 14), 0.1)
+
+	assert.InDelta(t, 1.0, results[0].Score, 1e-9)
+	assert.InDelta(t, 0.5, results[1].Score, 1e-9)
+	assert.InDelta(t, 0.25, results[2].Score, 1e-9)
+}
+
+func TestApplyTimeDecay_Linear(t *testing.T) {
+	sc := newDecayScorer(&config.MemoryConfig{TimeDecayFunc: "linear", TimeDecayCutoff: 10 * 24 * time.Hour})
+	results := sc.ApplyTimeDecay(agedResults(0, 5, 10, 20), 0.1)
+
+	assert.InDelta(t, 1.0, results[0].Score, 1e-9)
+	assert.InDelta(t, 0.5, results[1].Score, 1e-9)
+	assert.InDelta(t, 0.0, results[2].Score, 1e-9)
+	assert.InDelta(t, 0.0, results[3].Score, 1e-9, "decay never goes negative")
+}
+
+func TestApplyTimeDecay_Step(t *testing.T) {
+	sc := newDecayScorer(&config.MemoryConfig{TimeDecayFunc: "step", TimeDecayCutoff: 30 * 24 * time.Hour})
+	results := sc.ApplyTimeDecay(agedResults(1, 29, 31), 0.1)
+
+	assert.Equal(t, 1.0, results[0].Score)
+	assert.Equal(t, 1.0, results[1].Score)
+	assert.Equal(t, 0.0, results[2].Score)
+}
+
+func TestApplyTimeDecay_MissingTimestampUnchanged(t *testing.T) {
+	sc := newDecayScorer(&config.MemoryConfig{})
+	results := sc.ApplyTimeDecay([]SearchResult{
+		{ID: "none", Score: 0.8},
+		{ID: "bad", Score: 0.6, Metadata: map[string]interface{}{"created_at": "yesterday"}},
+		{ID: "text", Score: 0.6, Metadata: map[string]interface{}{"created_at": decayNow.Add(-48 * time.Hour).String()}},
+	}, 0.1)
+
+	assert.Equal(t, 0.8, results[0].Score)
+	assert.Equal(t, 0.6, results[1].Score)
+	assert.InDelta(t, 0.6*math.Exp(-0.2), results[2].Score, 1e-9)
+}
+
+func TestParseTimeDecayFunc(t *testing.T) {
+	fn, err := ParseTimeDecayFunc("")
+	require.NoError(t, err)
+	assert.Equal(t, TimeDecayExponential, fn)
+
+	fn, err = ParseTimeDecayFunc("Linear")
+	require.NoError(t, err)
+	assert.Equal(t, TimeDecayLinear, fn)
+
+	_, err = ParseTimeDecayFunc("cubic")
+	assert.Error(t, err)
+}
+
+// scoredResults returns results with the given scores in order
+func scoredResults(scores ...float64) []SearchResult {
+	results := make([]SearchResult, len(scores))
+	for i, score := range scores {
+		results[i] = SearchResult{ID: string(rune('a' + i)), Score: score}
+	}
+	return results
+}
+
+func TestApplyAutocut_MinResultsFloor(t *testing.T) {
+	sc := NewScorer(&config.MemoryConfig{})
+	// The sharpest drop follows the first result
+	results := scoredResults(0.95, 0.6, 0.58, 0.55, 0.52, 0.5, 0.48, 0.45, 0.42, 0.4)
+
+	assert.Len(t, sc.ApplyAutocut(results, AutocutBounds{}), defaultAutocutMinResults)
+	assert.Len(t, sc.ApplyAutocut(results, AutocutBounds{MinResults: 6}), 6)
+	assert.Len(t, sc.ApplyAutocut(results, AutocutBounds{MinResults: 20}), 10, "the floor never adds results")
+}
+
+func TestApplyAutocut_MaxCutFraction(t *testing.T) {
+	sc := NewScorer(&config.MemoryConfig{})
+	// Evenly spaced scores: the first gap counts as the knee, which would cut to the floor
+	results := scoredResults(0.9, 0.8, 0.7, 0.6, 0.5, 0.4, 0.3, 0.2, 0.1, 0.0)
+
+	assert.Len(t, sc.ApplyAutocut(results, AutocutBounds{}), defaultAutocutMinResults)
+	assert.Len(t, sc.ApplyAutocut(results, AutocutBounds{MaxCutFraction: 0.2}), 8)
+
+	// A knee inside the allowed share still cuts there
+	kneed := scoredResults(0.9, 0.89, 0.88, 0.87, 0.86, 0.85, 0.84, 0.83, 0.1, 0.05)
+	assert.Len(t, sc.ApplyAutocut(kneed, AutocutBounds{MaxCutFraction: 0.5}), 8)
+}
+
+func TestRetriever_AutocutBoundsFromConfig(t *testing.T) {
+	ret := NewRetriever(&config.MemoryConfig{AutocutMinResults: 5, AutocutMaxCutFraction: 0.25}, nil, nil, nil, nil, nil)
+
+	assert.Equal(t, AutocutBounds{MinResults: 5, MaxCutFraction: 0.25}, ret.autocutBounds(SearchOptions{}))
+	assert.Equal(t, AutocutBounds{MinResults: 10, MaxCutFraction: 0.25}, ret.autocutBounds(SearchOptions{AutocutBounds: AutocutBounds{MinResults: 10}}))
+	require.Equal(t, AutocutBounds{MinResults: 5, MaxCutFraction: 0.1}, ret.autocutBounds(SearchOptions{AutocutBounds: AutocutBounds{MaxCutFraction: 0.1}}))
+}
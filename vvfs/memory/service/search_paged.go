@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+)
+
+// Paging defaults
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+	// pagedCandidateLimit fixes the fused candidate set so every page is cut from the same ranking
+	pagedCandidateLimit = 1000
+)
+
+// PageOptions selects one page of search results
+type PageOptions struct {
+	Limit  int    `json:"limit"`  // Page size (default 10, max 100)
+	Cursor string `json:"cursor"` // Opaque cursor from a previous page; empty for the first page
+}
+
+// PagedResults is one page of search results
+type PagedResults struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor"` // Empty on the last page
+	Offset     int            `json:"offset"`      // Position of the first result in the full ranking
+	Total      int            `json:"total"`       // Estimated number of matches
+	TotalExact bool           `json:"total_exact"` // False when the candidate set was capped
+}
+
+// pageCursor is the decoded form of PagedResults.NextCursor
+type pageCursor struct {
+	Offset int    `json:"o"`
+	Query  uint64 `json:"q"` // fingerprint of the query the cursor belongs to
+}
+
+// SearchPaged returns one page of Search results with a cursor for the next page.
+// Pages are cut by offset from a fixed-size fused candidate set ordered by score,
+// then ID, so consecutive pages neither overlap nor skip results.
+func (ms *MemorySystem) SearchPaged(ctx context.Context, query string, opts SearchOptions, page PageOptions) (*PagedResults, error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	limit = min(limit, maxPageSize)
+
+	fingerprint := queryFingerprint(query, opts)
+	offset := 0
+	if page.Cursor != "" {
+		cursor, err := decodePageCursor(page.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Query != fingerprint {
+			return nil, fmt.Errorf("cursor does not belong to this query")
+		}
+		offset = cursor.Offset
+	}
+
+	opts.K = pagedCandidateLimit
+	candidates, err := ms.Search(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	sortResultsStable(candidates)
+
+	result := &PagedResults{
+		Offset:     offset,
+		Total:      len(candidates),
+		TotalExact: len(candidates) < pagedCandidateLimit,
+	}
+	if offset >= len(candidates) {
+		result.Results = []SearchResult{}
+		return result, nil
+	}
+
+	end := min(offset+limit, len(candidates))
+	result.Results = candidates[offset:end]
+	if end < len(candidates) {
+		result.NextCursor = encodePageCursor(pageCursor{Offset: end, Query: fingerprint})
+	}
+	return result, nil
+}
+
+// queryFingerprint identifies the query and options that shape the ranking
+func queryFingerprint(query string, opts SearchOptions) uint64 {
+	opts.K = 0 // page size does not change the ranking
+	encoded, _ := json.Marshal(opts)
+	h := fnv.New64a()
+	h.Write([]byte(query))
+	h.Write([]byte{0})
+	h.Write(encoded)
+	return h.Sum64()
+}
+
+func encodePageCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(encoded string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if cursor.Offset < 0 {
+		return cursor, fmt.Errorf("invalid cursor: negative offset")
+	}
+	return cursor, nil
+}
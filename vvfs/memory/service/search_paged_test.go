@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shuffledRetriever returns a fixed candidate set in a different order on every call
+type shuffledRetriever struct {
+	results []SearchResult
+	rng     *rand.Rand
+	lastK   int
+}
+
+func (r *shuffledRetriever) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	r.lastK = opts.K
+	out := append([]SearchResult(nil), r.results...)
+	r.rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+	return out, nil
+}
+
+func newPagedTestSystem(count int) (*MemorySystem, *shuffledRetriever) {
+	results := make([]SearchResult, count)
+	for i := range results {
+		// Groups of three share a score to exercise tie-breaking
+		results[i] = SearchResult{ID: fmt.Sprintf("item-%02d", i), Score: 1 - float64(i/3)*0.05}
+	}
+	retriever := &shuffledRetriever{results: results, rng: rand.New(rand.NewSource(1))}
+	return &MemorySystem{config: &config.MemoryConfig{}, retriever: retriever}, retriever
+}
+
+func TestSearchPaged_ConsecutivePagesDoNotOverlap(t *testing.T) {
+	ctx := context.Background()
+	ms, retriever := newPagedTestSystem(23)
+
+	var ids []string
+	seen := make(map[string]bool)
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 10, "pagination did not terminate")
+
+		page, err := ms.SearchPaged(ctx, "query", SearchOptions{}, PageOptions{Limit: 5, Cursor: cursor})
+		require.NoError(t, err)
+		assert.Equal(t, 23, page.Total)
+		assert.True(t, page.TotalExact)
+		assert.Equal(t, len(ids), page.Offset)
+		assert.Equal(t, pagedCandidateLimit, retriever.lastK)
+
+		for _, r := range page.Results {
+			assert.False(t, seen[r.ID], "duplicate %s across pages", r.ID)
+			seen[r.ID] = true
+			ids = append(ids, r.ID)
+		}
+		if page.NextCursor == "" {
+			assert.Len(t, page.Results, 3)
+			break
+		}
+		assert.Len(t, page.Results, 5)
+		cursor = page.NextCursor
+	}
+
+	require.Len(t, ids, 23)
+	for i, id := range ids {
+		assert.Equal(t, fmt.Sprintf("item-%02d", i), id, "stable order at position %d", i)
+	}
+}
+
+func TestSearchPaged_StableAcrossRepeatedCalls(t *testing.T) {
+	ctx := context.Background()
+	ms, _ := newPagedTestSystem(12)
+
+	first, err := ms.SearchPaged(ctx, "query", SearchOptions{}, PageOptions{Limit: 4})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		again, err := ms.SearchPaged(ctx, "query", SearchOptions{}, PageOptions{Limit: 4})
+		require.NoError(t, err)
+		assert.Equal(t, first.Results, again.Results)
+		assert.Equal(t, first.NextCursor, again.NextCursor)
+	}
+}
+
+func TestSearchPaged_RejectsForeignCursor(t *testing.T) {
+	ctx := context.Background()
+	ms, _ := newPagedTestSystem(12)
+
+	page, err := ms.SearchPaged(ctx, "query", SearchOptions{}, PageOptions{Limit: 4})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+
+	_, err = ms.SearchPaged(ctx, "other query", SearchOptions{}, PageOptions{Limit: 4, Cursor: page.NextCursor})
+	assert.Error(t, err)
+
+	_, err = ms.SearchPaged(ctx, "query", SearchOptions{}, PageOptions{Cursor: "not-a-cursor"})
+	assert.Error(t, err)
+}
@@ -5,14 +5,18 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/memory/database"
 	"github.com/google/uuid"
 )
 
 // MemoryStoreImpl implements MemoryStore interface
 type MemoryStoreImpl struct {
-	db *sql.DB
+	db database.DBTX
 }
 
 // NewMemoryStoreImpl creates a new memory store
@@ -20,6 +24,12 @@ func NewMemoryStoreImpl(db *sql.DB) *MemoryStoreImpl {
 	return &MemoryStoreImpl{db: db}
 }
 
+// NewMemoryStoreTx creates a memory store whose statements run in tx, so its writes commit
+// or roll back with the transaction
+func NewMemoryStoreTx(tx database.DBTX) *MemoryStoreImpl {
+	return &MemoryStoreImpl{db: tx}
+}
+
 // GetItem retrieves a memory item by ID (interface method)
 func (m *MemoryStoreImpl) GetItem(ctx context.Context, id string) (*MemoryItem, error) {
 	return m.GetMemoryItem(ctx, id)
@@ -40,6 +50,12 @@ func (m *MemoryStoreImpl) DeleteItem(ctx context.Context, id string) error {
 	return m.DeleteMemoryItem(ctx, id)
 }
 
+// DeleteByFilter deletes every memory item whose metadata matches filter (interface method)
+func (m *MemoryStoreImpl) DeleteByFilter(ctx context.Context, filter map[string]interface{}) (int, error) {
+	ids, err := m.DeleteMemoryItemsByFilter(ctx, filter)
+	return len(ids), err
+}
+
 // ListItems lists memory items with pagination (interface method)
 func (m *MemoryStoreImpl) ListItems(ctx context.Context, opts ListOptions) ([]*MemoryItem, error) {
 	return m.ListMemoryItems(ctx, opts)
@@ -135,6 +151,28 @@ func (m *MemoryStoreImpl) GetMemoryItem(ctx context.Context, id string) (*Memory
 	return &item, nil
 }
 
+// GetItemBySourceRef retrieves the earliest stored item with sourceRef, or nil when there is none
+func (m *MemoryStoreImpl) GetItemBySourceRef(ctx context.Context, sourceRef string) (*MemoryItem, error) {
+	query := `
+		SELECT id
+		FROM memory_items
+		WHERE source_ref = ?
+		ORDER BY created_at, id
+		LIMIT 1
+	`
+
+	var id string
+	err := m.db.QueryRowContext(ctx, query, sourceRef).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up source ref %s: %w", sourceRef, err)
+	}
+
+	return m.GetMemoryItem(ctx, id)
+}
+
 // UpdateMemoryItem updates an existing memory item
 func (m *MemoryStoreImpl) UpdateMemoryItem(ctx context.Context, item *MemoryItem) error {
 	metadataJSON, err := json.Marshal(item.Metadata)
@@ -199,6 +237,98 @@ func (m *MemoryStoreImpl) DeleteMemoryItem(ctx context.Context, id string) error
 	return nil
 }
 
+// DeleteMemoryItemsByFilter deletes the items whose metadata equals every filter entry
+// in one transaction and returns their IDs
+func (m *MemoryStoreImpl) DeleteMemoryItemsByFilter(ctx context.Context, filter map[string]interface{}) ([]string, error) {
+	where, args, err := metadataFilterClause(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// A store bound to a caller's transaction deletes within it
+	conn := m.db
+	var tx *sql.Tx
+	if db, ok := m.db.(*sql.DB); ok {
+		tx, err = db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+		conn = tx
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT id FROM memory_items WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select items by filter: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, "DELETE FROM memory_items WHERE "+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete items by filter: %w", err)
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit delete: %w", err)
+		}
+	}
+
+	return ids, nil
+}
+
+// metadataFilterClause builds a WHERE clause matching metadata_json entries by equality.
+// Supported values are strings, numbers, booleans and nil (a JSON null)
+func metadataFilterClause(filter map[string]interface{}) (string, []interface{}, error) {
+	if len(filter) == 0 {
+		return "", nil, fmt.Errorf("metadata filter cannot be empty")
+	}
+
+	keys := make([]string, 0, len(filter))
+	for key := range filter {
+		if key == "" || strings.ContainsAny(key, `"\`) {
+			return "", nil, fmt.Errorf("invalid metadata filter key: %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	var args []interface{}
+	for _, key := range keys {
+		path := `$."` + key + `"`
+		switch v := filter[key].(type) {
+		case nil:
+			conditions = append(conditions, "json_type(metadata_json, ?) = 'null'")
+			args = append(args, path)
+		case bool:
+			// json_extract reports booleans as 0/1, so compare the JSON type instead
+			conditions = append(conditions, "json_type(metadata_json, ?) = ?")
+			args = append(args, path, strconv.FormatBool(v))
+		case string, int, int32, int64, float32, float64:
+			conditions = append(conditions, "json_extract(metadata_json, ?) = ?")
+			args = append(args, path, v)
+		default:
+			return "", nil, fmt.Errorf("unsupported metadata filter value for %q: %T", key, v)
+		}
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
 // ListMemoryItems lists memory items with pagination
 func (m *MemoryStoreImpl) ListMemoryItems(ctx context.Context, opts ListOptions) ([]*MemoryItem, error) {
 	query := `
@@ -405,3 +535,33 @@ func decodeFloatSlice(data []byte) ([]float64, error) {
 	err := json.Unmarshal(data, &floats)
 	return floats, err
 }
+
+// TxRunner runs fn in one transaction, committing when fn returns nil and rolling back otherwise
+type TxRunner func(ctx context.Context, fn func(tx database.DBTX) error) error
+
+// SQLTx runs transactions directly on db
+func SQLTx(db *sql.DB) TxRunner {
+	return func(ctx context.Context, fn func(tx database.DBTX) error) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if err := fn(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+}
+
+// DBManagerTx runs transactions through DBManager.WithTx on the project's database
+func DBManagerTx(dm *database.DBManager, project string) TxRunner {
+	return func(ctx context.Context, fn func(tx database.DBTX) error) error {
+		return dm.WithTx(ctx, project, func(q *database.Queries) error {
+			return fn(q.Conn())
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/config"
+	harnessports "github.com/ZanzyTHEbar/virtual-vectorfs/vvfs/generation/harness/ports"
 )
 
 // SummarizerImpl implements Summarizer for working memory summarization
@@ -42,6 +43,30 @@ func (sum *SummarizerImpl) Summarize(ctx context.Context, messages []Conversatio
 	}, nil
 }
 
+// HarnessSummarizer adapts a Summarizer to the harness conversation rollover
+type HarnessSummarizer struct {
+	summarizer Summarizer
+}
+
+// NewHarnessSummarizer wraps summarizer for HarnessOrchestrator.SetSummarizer
+func NewHarnessSummarizer(summarizer Summarizer) *HarnessSummarizer {
+	return &HarnessSummarizer{summarizer: summarizer}
+}
+
+// Summarize summarizes prompt messages and returns the summary content
+func (h *HarnessSummarizer) Summarize(ctx context.Context, messages []harnessports.PromptMessage) (string, error) {
+	converted := make([]ConversationMessage, len(messages))
+	for i, msg := range messages {
+		converted[i] = ConversationMessage{Role: msg.Role, Content: msg.Content}
+	}
+
+	summary, err := h.summarizer.Summarize(ctx, converted)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	return summary.Content, nil
+}
+
 // validateMessages checks for basic message validity
 func (sum *SummarizerImpl) validateMessages(messages []ConversationMessage) error {
 	if len(messages) == 0 {